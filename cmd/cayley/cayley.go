@@ -22,6 +22,7 @@ import (
 	"os"
 	"runtime"
 	"runtime/pprof"
+	"strings"
 	"time"
 
 	"golang.org/x/net/context"
@@ -30,12 +31,16 @@ import (
 	_ "github.com/codelingo/cayley/clog/glog"
 
 	"github.com/codelingo/cayley/graph"
+	"github.com/codelingo/cayley/graph/algo"
 	"github.com/codelingo/cayley/internal"
 	"github.com/codelingo/cayley/internal/config"
 	"github.com/codelingo/cayley/internal/db"
 	"github.com/codelingo/cayley/internal/http"
+	"github.com/codelingo/cayley/quad"
+	"github.com/codelingo/cayley/quad/csv"
 
 	// Load all supported backends.
+	_ "github.com/codelingo/cayley/graph/badger"
 	_ "github.com/codelingo/cayley/graph/bolt"
 	_ "github.com/codelingo/cayley/graph/leveldb"
 	_ "github.com/codelingo/cayley/graph/memstore"
@@ -50,6 +55,8 @@ import (
 	_ "github.com/codelingo/cayley/quad/jsonld"
 	_ "github.com/codelingo/cayley/quad/nquads"
 	_ "github.com/codelingo/cayley/quad/pquads"
+	_ "github.com/codelingo/cayley/quad/rdfxml"
+	_ "github.com/codelingo/cayley/quad/turtle"
 
 	// Load writer registry
 	_ "github.com/codelingo/cayley/writer"
@@ -60,12 +67,17 @@ import (
 	_ "github.com/codelingo/cayley/query/gremlin"
 	_ "github.com/codelingo/cayley/query/mql"
 	_ "github.com/codelingo/cayley/query/sexp"
+	_ "github.com/codelingo/cayley/query/sparql"
 )
 
 var (
+	algoName           = flag.String("algo", "pagerank", `Graph algorithm to run ("pagerank", "wcc", "scc", "triangles", "degree", "indegree", "outdegree", "betweenness").`)
+	algoPreds          = flag.String("algo_preds", "", "Comma-separated predicates of edges to run a graph algorithm on (all quads by default).")
+	algoWrite          = flag.String("algo_write", "", "Predicate to write results of a graph algorithm to, instead of printing them.")
 	quadFile           = flag.String("quads", "", "Quad file to load before going to REPL.")
 	initOpt            = flag.Bool("init", false, "Initialize the database before using it. Equivalent to running `cayley init` followed by the given command.")
-	quadType           = flag.String("format", "cquad", `Quad format to use for loading ("cquad" or "nquad").`)
+	quadType           = flag.String("format", "cquad", `Quad format to use for loading ("cquad" or "nquad"). Tabular data can be loaded with "csv" or "tsv" formats and a -mapping file.`)
+	mappingFile        = flag.String("mapping", "", "Path to a JSON mapping specification for loading tabular data.")
 	cpuprofile         = flag.String("prof", "", "Output profiling file.")
 	queryLanguage      = flag.String("query_lang", "gremlin", "Use this parser as the query language.")
 	configFile         = flag.String("config", "", "Path to an explicit configuration file.")
@@ -74,10 +86,13 @@ var (
 	dumpFile           = flag.String("dump", "dbdump.nq", `Quad file to dump the database to (".gz" supported, "-" for stdout).`)
 	dumpType           = flag.String("dump_type", "quad", `Quad file format ("json", "quad", "gml", "graphml").`)
 	replicationBackend = flag.String("replication", "single", "Replication method.")
+	geoIndex           = flag.String("geo_index", "", `Spatial index to keep for the database ("memory").`)
 	host               = flag.String("host", "127.0.0.1", "Host to listen on (defaults to all).")
 	loadSize           = flag.Int("load_size", 10000, "Size of quadsets to load")
 	port               = flag.String("port", "64210", "Port to listen on.")
 	readOnly           = flag.Bool("read_only", false, "Disable writing via HTTP.")
+	reasoning          = flag.Bool("reasoning", false, "Expose quads entailed by RDFS and OWL schema statements.")
+	textIndex          = flag.String("text_index", "", `Full-text index to keep for the database ("memory").`)
 	timeout            = flag.Duration("timeout", 30*time.Second, "Elapsed time until an individual query times out.")
 )
 
@@ -98,6 +113,7 @@ Commands:
   http      Serve an HTTP endpoint on the given host and port.
   dump      Bulk-dump the database into a quad file.
   repl      Drop into a REPL of the given query language.
+  algo      Run a graph algorithm over the database.
   version   Version information.
 
 Flags:`)
@@ -159,10 +175,66 @@ func configFrom(file string) *config.Config {
 	}
 
 	cfg.ReadOnly = cfg.ReadOnly || *readOnly
+	cfg.Reasoning = cfg.Reasoning || *reasoning
+	if cfg.TextIndex == "" {
+		cfg.TextIndex = *textIndex
+	}
+	if cfg.GeoIndex == "" {
+		cfg.GeoIndex = *geoIndex
+	}
 
 	return cfg
 }
 
+// load loads quads from the given path. Tabular data is converted to quads according to the mapping file.
+func load(qw graph.QuadWriter, batch int, path, typ string) error {
+	if typ != "csv" && typ != "tsv" {
+		return internal.Load(qw, batch, path, typ)
+	}
+	if *mappingFile == "" {
+		return fmt.Errorf("mapping file is required to load %s data", typ)
+	}
+	f, err := os.Open(*mappingFile)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	m, err := csv.ReadMapping(f)
+	if err != nil {
+		return fmt.Errorf("cannot read mapping: %v", err)
+	}
+	return internal.LoadTable(qw, batch, path, typ, m)
+}
+
+// runAlgo runs a graph algorithm over the database and either prints results or writes them back to the database.
+func runAlgo(h *graph.Handle, cfg *config.Config) error {
+	var preds []quad.Value
+	for _, s := range strings.Split(*algoPreds, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			preds = append(preds, quad.StringToValue(s))
+		}
+	}
+	if *algoWrite != "" && cfg.ReadOnly {
+		return fmt.Errorf("database is read-only")
+	}
+	ctx := context.TODO()
+	g, err := algo.Load(ctx, h.QuadStore, preds...)
+	if err != nil {
+		return err
+	}
+	vals, err := algo.Run(ctx, g, *algoName)
+	if err != nil {
+		return err
+	}
+	if *algoWrite != "" {
+		return algo.Write(h.QuadWriter, g, vals, quad.StringToValue(*algoWrite), nil, cfg.LoadSize)
+	}
+	for i, v := range vals {
+		fmt.Printf("%v\t%v\n", g.NameOf(i), quad.NativeOf(v))
+	}
+	return nil
+}
+
 func main() {
 	// No command? It's time for usage.
 	if len(os.Args) == 1 {
@@ -222,7 +294,7 @@ func main() {
 			if err != nil {
 				break
 			}
-			err = internal.Load(handle.QuadWriter, cfg.LoadSize, *quadFile, *quadType)
+			err = load(handle.QuadWriter, cfg.LoadSize, *quadFile, *quadType)
 			if err != nil {
 				break
 			}
@@ -234,7 +306,7 @@ func main() {
 		if err != nil {
 			break
 		}
-		err = internal.Load(handle.QuadWriter, cfg.LoadSize, *quadFile, *quadType)
+		err = load(handle.QuadWriter, cfg.LoadSize, *quadFile, *quadType)
 		if err != nil {
 			break
 		}
@@ -247,7 +319,7 @@ func main() {
 			break
 		}
 		if !graph.IsPersistent(cfg.DatabaseType) {
-			err = internal.Load(handle.QuadWriter, cfg.LoadSize, *quadFile, *quadType)
+			err = load(handle.QuadWriter, cfg.LoadSize, *quadFile, *quadType)
 			if err != nil {
 				break
 			}
@@ -260,6 +332,22 @@ func main() {
 
 		handle.Close()
 
+	case "algo":
+		handle, err = db.Open(cfg)
+		if err != nil {
+			break
+		}
+		if !graph.IsPersistent(cfg.DatabaseType) {
+			err = load(handle.QuadWriter, cfg.LoadSize, *quadFile, *quadType)
+			if err != nil {
+				break
+			}
+		}
+
+		err = runAlgo(handle, cfg)
+
+		handle.Close()
+
 	case "repl":
 		if *initOpt {
 			err = db.Init(cfg)
@@ -272,7 +360,7 @@ func main() {
 			break
 		}
 		if !graph.IsPersistent(cfg.DatabaseType) {
-			err = internal.Load(handle.QuadWriter, cfg.LoadSize, cfg.DatabasePath, *quadType)
+			err = load(handle.QuadWriter, cfg.LoadSize, cfg.DatabasePath, *quadType)
 			if err != nil {
 				break
 			}
@@ -294,14 +382,25 @@ func main() {
 			break
 		}
 		if !graph.IsPersistent(cfg.DatabaseType) {
-			err = internal.Load(handle.QuadWriter, cfg.LoadSize, cfg.DatabasePath, *quadType)
+			err = load(handle.QuadWriter, cfg.LoadSize, cfg.DatabasePath, *quadType)
 			if err != nil {
 				break
 			}
 		}
 
-		http.Serve(handle, cfg)
+		dbs := db.NewDatabases(cfg)
+		dbs.Load = func(h *graph.Handle, cfg *config.Config) error {
+			return load(h.QuadWriter, cfg.LoadSize, cfg.DatabasePath, *quadType)
+		}
+		err = dbs.OpenAll(*initOpt)
+		if err != nil {
+			handle.Close()
+			break
+		}
+
+		http.Serve(handle, dbs, cfg)
 
+		dbs.Close()
 		handle.Close()
 
 	default:
@@ -28,6 +28,7 @@ import (
 
 	// Load all supported backends.
 
+	_ "github.com/codelingo/cayley/graph/badger"
 	_ "github.com/codelingo/cayley/graph/bolt"
 	_ "github.com/codelingo/cayley/graph/leveldb"
 	_ "github.com/codelingo/cayley/graph/memstore"
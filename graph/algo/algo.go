@@ -0,0 +1,110 @@
+// Copyright 2017 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package algo
+
+import (
+	"fmt"
+
+	"golang.org/x/net/context"
+
+	"github.com/codelingo/cayley/graph"
+	"github.com/codelingo/cayley/quad"
+)
+
+// Algorithms lists names of algorithms accepted by Run.
+var Algorithms = []string{
+	"pagerank", "wcc", "scc", "triangles",
+	"degree", "indegree", "outdegree", "betweenness",
+}
+
+// Run executes an algorithm by name with default options and returns its result for each node of the graph.
+func Run(ctx context.Context, g *Graph, name string) ([]quad.Value, error) {
+	switch name {
+	case "pagerank":
+		return floats(PageRank(ctx, g, PageRankOptions{}))
+	case "betweenness":
+		return floats(Betweenness(ctx, g))
+	case "wcc":
+		return ints(WeaklyConnected(g)), nil
+	case "scc":
+		return ints(StronglyConnected(g)), nil
+	case "triangles":
+		return ints(Triangles(g)), nil
+	case "degree":
+		return ints(Degree(g, quad.Any)), nil
+	case "indegree":
+		return ints(Degree(g, quad.Object)), nil
+	case "outdegree":
+		return ints(Degree(g, quad.Subject)), nil
+	}
+	return nil, fmt.Errorf("unknown algorithm: %q", name)
+}
+
+func floats(arr []float64, err error) ([]quad.Value, error) {
+	if err != nil {
+		return nil, err
+	}
+	out := make([]quad.Value, len(arr))
+	for i, v := range arr {
+		out[i] = quad.Float(v)
+	}
+	return out, nil
+}
+
+func ints(arr []int) []quad.Value {
+	out := make([]quad.Value, len(arr))
+	for i, v := range arr {
+		out[i] = quad.Int(v)
+	}
+	return out
+}
+
+// Write stores a result of an algorithm as quads from each node of the graph to its value, with
+// a given predicate and an optional label. Values written to these nodes with the same predicate
+// and label before are replaced. Changes are applied in transactions of at most batch nodes each.
+func Write(qw graph.QuadWriter, g *Graph, vals []quad.Value, pred, label quad.Value, batch int) error {
+	if len(vals) != g.Len() {
+		return fmt.Errorf("expected %d values, got %d", g.Len(), len(vals))
+	}
+	if batch <= 0 {
+		batch = len(vals)
+	}
+	tx := graph.NewTransaction()
+	for i, v := range vals {
+		if err := removeValues(tx, g.qs, g.nodes[i], pred, label); err != nil {
+			return err
+		}
+		tx.AddQuad(quad.Quad{Subject: g.NameOf(i), Predicate: pred, Object: v, Label: label})
+		if (i+1)%batch == 0 || i == len(vals)-1 {
+			if err := qw.ApplyTransaction(tx); err != nil {
+				return err
+			}
+			tx = graph.NewTransaction()
+		}
+	}
+	return nil
+}
+
+// removeValues adds all quads of the node with the given predicate and label to the transaction as removed.
+func removeValues(tx *graph.Transaction, qs graph.QuadStore, node graph.Value, pred, label quad.Value) error {
+	it := qs.QuadIterator(quad.Subject, node)
+	defer it.Close()
+	for it.Next(nil) {
+		if q := qs.Quad(it.Result()); q.Predicate == pred && q.Label == label {
+			tx.RemoveQuad(q)
+		}
+	}
+	return it.Err()
+}
@@ -0,0 +1,153 @@
+// Copyright 2017 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package algo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+
+	"github.com/codelingo/cayley/graph"
+	"github.com/codelingo/cayley/graph/memstore"
+	"github.com/codelingo/cayley/quad"
+	"github.com/codelingo/cayley/writer"
+)
+
+var testQuads = []quad.Quad{
+	quad.MakeIRI("a", "follows", "b", ""),
+	quad.MakeIRI("b", "follows", "c", ""),
+	quad.MakeIRI("c", "follows", "a", ""),
+	quad.MakeIRI("c", "follows", "d", ""),
+	quad.MakeIRI("d", "follows", "e", ""),
+	quad.MakeIRI("f", "follows", "g", ""),
+	quad.MakeIRI("e", "knows", "a", ""),
+}
+
+func newGraph(t testing.TB, preds ...quad.Value) (graph.QuadStore, *Graph) {
+	qs := memstore.New(testQuads...)
+	g, err := Load(context.TODO(), qs, preds...)
+	require.NoError(t, err)
+	return qs, g
+}
+
+// byName returns a result of an algorithm for each node.
+func byName(t testing.TB, g *Graph, name string) map[string]quad.Value {
+	vals, err := Run(context.TODO(), g, name)
+	require.NoError(t, err)
+	require.Equal(t, g.Len(), len(vals))
+	out := make(map[string]quad.Value, len(vals))
+	for i, v := range vals {
+		out[string(g.NameOf(i).(quad.IRI))] = v
+	}
+	return out
+}
+
+func TestLoad(t *testing.T) {
+	_, g := newGraph(t, quad.IRI("follows"), quad.IRI("unknown"))
+	require.Equal(t, 7, g.Len())
+	_, g = newGraph(t, quad.IRI("knows"))
+	require.Equal(t, 2, g.Len())
+}
+
+func TestDegree(t *testing.T) {
+	_, g := newGraph(t, quad.IRI("follows"))
+	require.Equal(t, quad.Int(3), byName(t, g, "degree")["c"])
+	require.Equal(t, quad.Int(1), byName(t, g, "indegree")["c"])
+	require.Equal(t, quad.Int(2), byName(t, g, "outdegree")["c"])
+}
+
+func TestComponents(t *testing.T) {
+	_, g := newGraph(t, quad.IRI("follows"))
+	wcc := byName(t, g, "wcc")
+	for _, n := range []string{"b", "c", "d", "e"} {
+		require.Equal(t, wcc["a"], wcc[n], n)
+	}
+	require.Equal(t, wcc["f"], wcc["g"])
+	require.NotEqual(t, wcc["a"], wcc["f"])
+
+	scc := byName(t, g, "scc")
+	require.Equal(t, scc["a"], scc["b"])
+	require.Equal(t, scc["a"], scc["c"])
+	for _, n := range []string{"d", "e", "f", "g"} {
+		require.NotEqual(t, scc["a"], scc[n], n)
+	}
+	require.NotEqual(t, scc["d"], scc["e"])
+	ids := make(map[quad.Value]bool)
+	for _, v := range scc {
+		ids[v] = true
+	}
+	for i := 0; i < len(ids); i++ {
+		require.True(t, ids[quad.Int(i)], "components should be numbered sequentially")
+	}
+
+	// with all predicates, e links back to a
+	_, g = newGraph(t)
+	scc = byName(t, g, "scc")
+	require.Equal(t, scc["a"], scc["e"])
+}
+
+func TestTriangles(t *testing.T) {
+	_, g := newGraph(t, quad.IRI("follows"))
+	require.Equal(t, map[string]quad.Value{
+		"a": quad.Int(1), "b": quad.Int(1), "c": quad.Int(1),
+		"d": quad.Int(0), "e": quad.Int(0), "f": quad.Int(0), "g": quad.Int(0),
+	}, byName(t, g, "triangles"))
+}
+
+func TestBetweenness(t *testing.T) {
+	_, g := newGraph(t, quad.IRI("follows"))
+	require.Equal(t, map[string]quad.Value{
+		"a": quad.Float(1), "b": quad.Float(3), "c": quad.Float(5),
+		"d": quad.Float(3), "e": quad.Float(0), "f": quad.Float(0), "g": quad.Float(0),
+	}, byName(t, g, "betweenness"))
+}
+
+func TestPageRank(t *testing.T) {
+	_, g := newGraph(t, quad.IRI("follows"))
+	rank, err := PageRank(context.TODO(), g, PageRankOptions{})
+	require.NoError(t, err)
+	sum := 0.0
+	for _, r := range rank {
+		sum += r
+	}
+	require.InDelta(t, 1, sum, 1e-9)
+	ranks := byName(t, g, "pagerank")
+	require.True(t, ranks["g"].(quad.Float) > ranks["f"].(quad.Float))
+	require.True(t, ranks["e"].(quad.Float) > ranks["d"].(quad.Float))
+}
+
+func TestWrite(t *testing.T) {
+	qs, g := newGraph(t, quad.IRI("follows"))
+	qw, err := writer.NewSingleReplication(qs, nil)
+	require.NoError(t, err)
+	pred := quad.IRI("score")
+	for _, name := range []string{"outdegree", "indegree"} {
+		vals, err := Run(context.TODO(), g, name)
+		require.NoError(t, err)
+		require.NoError(t, Write(qw, g, vals, pred, nil, 3))
+	}
+	got := make(map[string]quad.Value)
+	it := qs.QuadIterator(quad.Predicate, qs.ValueOf(pred))
+	defer it.Close()
+	for it.Next(nil) {
+		q := qs.Quad(it.Result())
+		s := string(q.Subject.(quad.IRI))
+		require.Nil(t, got[s], "old value of %s was not replaced", s)
+		got[s] = q.Object
+	}
+	require.NoError(t, it.Err())
+	require.Equal(t, byName(t, g, "indegree"), got)
+}
@@ -0,0 +1,113 @@
+// Copyright 2017 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package algo
+
+import (
+	"golang.org/x/net/context"
+
+	"github.com/codelingo/cayley/quad"
+)
+
+// Degree returns the number of edges of each node. Only outgoing edges are counted for quad.Subject,
+// only incoming edges for quad.Object, and both of them for quad.Any.
+func Degree(g *Graph, dir quad.Direction) []int {
+	deg := make([]int, g.Len())
+	for i := range deg {
+		if dir == quad.Subject || dir == quad.Any {
+			deg[i] += len(g.out[i])
+		}
+		if dir == quad.Object || dir == quad.Any {
+			deg[i] += len(g.in[i])
+		}
+	}
+	return deg
+}
+
+// Triangles returns the number of triangles that each node is a part of. Edge directions are ignored.
+func Triangles(g *Graph) []int {
+	nb := g.neighbors()
+	cnt := make([]int, g.Len())
+	for u, list := range nb {
+		for _, v := range list {
+			if v <= u {
+				continue
+			}
+			// count common neighbours w > v, so each triangle is seen once
+			a, b := list, nb[v]
+			for len(a) != 0 && len(b) != 0 {
+				switch {
+				case a[0] < b[0]:
+					a = a[1:]
+				case a[0] > b[0]:
+					b = b[1:]
+				default:
+					if w := a[0]; w > v {
+						cnt[u]++
+						cnt[v]++
+						cnt[w]++
+					}
+					a, b = a[1:], b[1:]
+				}
+			}
+		}
+	}
+	return cnt
+}
+
+// Betweenness computes a betweenness centrality of each node: the number of shortest directed paths
+// between other nodes that pass through it. When there are several shortest paths between two nodes,
+// each of them is counted with a weight of 1/N.
+func Betweenness(ctx context.Context, g *Graph) ([]float64, error) {
+	// Brandes' algorithm for unweighted graphs
+	n := g.Len()
+	cb := make([]float64, n)
+	sigma, delta := make([]float64, n), make([]float64, n)
+	dist := make([]int, n)
+	pred := make([][]int, n)
+	order := make([]int, 0, n)
+	for s := 0; s < n; s++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		for i := range dist {
+			sigma[i], delta[i], dist[i] = 0, 0, -1
+			pred[i] = pred[i][:0]
+		}
+		sigma[s], dist[s] = 1, 0
+		// order is both a BFS queue and a list of nodes by non-decreasing distance
+		order = append(order[:0], s)
+		for h := 0; h < len(order); h++ {
+			v := order[h]
+			for _, w := range g.out[v] {
+				if dist[w] < 0 {
+					dist[w] = dist[v] + 1
+					order = append(order, w)
+				}
+				if dist[w] == dist[v]+1 {
+					sigma[w] += sigma[v]
+					pred[w] = append(pred[w], v)
+				}
+			}
+		}
+		for i := len(order) - 1; i > 0; i-- {
+			w := order[i]
+			for _, v := range pred[w] {
+				delta[v] += sigma[v] / sigma[w] * (1 + delta[w])
+			}
+			cb[w] += delta[w]
+		}
+	}
+	return cb, nil
+}
@@ -0,0 +1,128 @@
+// Copyright 2017 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package algo
+
+// WeaklyConnected returns an ID of the weakly connected component of each node.
+// Components are numbered from 0 in order of their first node in the graph.
+func WeaklyConnected(g *Graph) []int {
+	nb := g.neighbors()
+	comp := make([]int, g.Len())
+	for i := range comp {
+		comp[i] = -1
+	}
+	n := 0
+	var queue []int
+	for s := range comp {
+		if comp[s] >= 0 {
+			continue
+		}
+		comp[s] = n
+		queue = append(queue[:0], s)
+		for len(queue) != 0 {
+			v := queue[len(queue)-1]
+			queue = queue[:len(queue)-1]
+			for _, w := range nb[v] {
+				if comp[w] < 0 {
+					comp[w] = n
+					queue = append(queue, w)
+				}
+			}
+		}
+		n++
+	}
+	return comp
+}
+
+// StronglyConnected returns an ID of the strongly connected component of each node.
+// Components are numbered from 0 in order of their first node in the graph.
+func StronglyConnected(g *Graph) []int {
+	// iterative version of Tarjan's algorithm
+	n := g.Len()
+	index, low, comp := make([]int, n), make([]int, n), make([]int, n)
+	for i := range index {
+		index[i] = -1
+	}
+	onStack := make([]bool, n)
+	type frame struct {
+		v, i int // node and the next edge to visit
+	}
+	var (
+		stack       []int
+		calls       []frame
+		next, ncomp int
+	)
+	visit := func(v int) {
+		index[v], low[v] = next, next
+		next++
+		stack = append(stack, v)
+		onStack[v] = true
+		calls = append(calls, frame{v: v})
+	}
+	for s := 0; s < n; s++ {
+		if index[s] >= 0 {
+			continue
+		}
+		visit(s)
+		for len(calls) != 0 {
+			f := &calls[len(calls)-1]
+			v := f.v
+			if f.i < len(g.out[v]) {
+				w := g.out[v][f.i]
+				f.i++
+				if index[w] < 0 {
+					visit(w)
+				} else if onStack[w] && index[w] < low[v] {
+					low[v] = index[w]
+				}
+				continue
+			}
+			calls = calls[:len(calls)-1]
+			if len(calls) != 0 {
+				if p := calls[len(calls)-1].v; low[v] < low[p] {
+					low[p] = low[v]
+				}
+			}
+			if low[v] != index[v] {
+				continue
+			}
+			for {
+				w := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[w] = false
+				comp[w] = ncomp
+				if w == v {
+					break
+				}
+			}
+			ncomp++
+		}
+	}
+	// renumber components in order of their first node
+	ids := make([]int, ncomp)
+	for i := range ids {
+		ids[i] = -1
+	}
+	k := 0
+	for _, c := range comp {
+		if ids[c] < 0 {
+			ids[c] = k
+			k++
+		}
+	}
+	for i, c := range comp {
+		comp[i] = ids[c]
+	}
+	return comp
+}
@@ -0,0 +1,153 @@
+// Copyright 2017 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package algo implements graph analytics algorithms over quad stores.
+//
+// Algorithms run on an in-memory Graph loaded from quads of any graph.QuadStore,
+// and return a result for each node of the graph. Results can be written back
+// to the store as quads with Write.
+package algo
+
+import (
+	"sort"
+
+	"golang.org/x/net/context"
+
+	"github.com/codelingo/cayley/graph"
+	"github.com/codelingo/cayley/quad"
+)
+
+// Graph is an in-memory directed graph built from quads of a store.
+// Nodes are subjects and objects of quads, and each quad is an edge from its subject to its object.
+type Graph struct {
+	qs    graph.QuadStore
+	nodes []graph.Value
+	index map[interface{}]int
+	out   [][]int
+	in    [][]int
+}
+
+// Load reads a graph from quads of the store that use one of the given predicates.
+// All quads of the store are used if no predicates are given. Parallel edges are merged.
+func Load(ctx context.Context, qs graph.QuadStore, preds ...quad.Value) (*Graph, error) {
+	g := &Graph{qs: qs, index: make(map[interface{}]int)}
+	if len(preds) == 0 {
+		if err := g.scan(ctx, qs.QuadsAllIterator()); err != nil {
+			return nil, err
+		}
+	}
+	for _, p := range preds {
+		v := qs.ValueOf(p)
+		if v == nil {
+			continue
+		}
+		if err := g.scan(ctx, qs.QuadIterator(quad.Predicate, v)); err != nil {
+			return nil, err
+		}
+	}
+	for i := range g.nodes {
+		g.out[i] = dedup(g.out[i])
+		g.in[i] = dedup(g.in[i])
+	}
+	return g, nil
+}
+
+// scan adds an edge for each quad of the iterator and closes it.
+func (g *Graph) scan(ctx context.Context, it graph.Iterator) error {
+	defer it.Close()
+	for it.Next(nil) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		q := it.Result()
+		s, o := g.node(g.qs.QuadDirection(q, quad.Subject)), g.node(g.qs.QuadDirection(q, quad.Object))
+		g.out[s] = append(g.out[s], o)
+		g.in[o] = append(g.in[o], s)
+	}
+	return it.Err()
+}
+
+// node returns an index of the node, adding it to the graph if necessary.
+func (g *Graph) node(v graph.Value) int {
+	k := graph.ToKey(v)
+	if i, ok := g.index[k]; ok {
+		return i
+	}
+	i := len(g.nodes)
+	g.index[k] = i
+	g.nodes = append(g.nodes, v)
+	g.out = append(g.out, nil)
+	g.in = append(g.in, nil)
+	return i
+}
+
+// dedup sorts the list of nodes and removes duplicates from it.
+func dedup(list []int) []int {
+	if len(list) < 2 {
+		return list
+	}
+	sort.Ints(list)
+	out := list[:1]
+	for _, v := range list[1:] {
+		if v != out[len(out)-1] {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// Len returns the number of nodes in the graph.
+func (g *Graph) Len() int {
+	return len(g.nodes)
+}
+
+// Node returns a value of the i-th node in the store.
+func (g *Graph) Node(i int) graph.Value {
+	return g.nodes[i]
+}
+
+// NameOf returns the i-th node.
+func (g *Graph) NameOf(i int) quad.Value {
+	return g.qs.NameOf(g.nodes[i])
+}
+
+// Out returns sorted indexes of nodes that the i-th node links to.
+func (g *Graph) Out(i int) []int {
+	return g.out[i]
+}
+
+// In returns sorted indexes of nodes that link to the i-th node.
+func (g *Graph) In(i int) []int {
+	return g.in[i]
+}
+
+// neighbors returns sorted adjacency lists of the graph with edge directions ignored and self-loops dropped.
+func (g *Graph) neighbors() [][]int {
+	nb := make([][]int, len(g.nodes))
+	for i := range g.nodes {
+		list := make([]int, 0, len(g.out[i])+len(g.in[i]))
+		for _, j := range g.out[i] {
+			if j != i {
+				list = append(list, j)
+			}
+		}
+		for _, j := range g.in[i] {
+			if j != i {
+				list = append(list, j)
+			}
+		}
+		nb[i] = dedup(list)
+	}
+	return nb
+}
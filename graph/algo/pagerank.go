@@ -0,0 +1,83 @@
+// Copyright 2017 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package algo
+
+import (
+	"math"
+
+	"golang.org/x/net/context"
+)
+
+// PageRankOptions are parameters of PageRank. Zero values are replaced with defaults.
+type PageRankOptions struct {
+	Damping    float64 // probability of following a link; 0.85 by default
+	Iterations int     // maximal number of iterations; 100 by default
+	Tolerance  float64 // stop when ranks change by less than this value in total; 1e-6 by default
+}
+
+// PageRank computes a PageRank of each node. Ranks sum up to 1.
+// The rank of nodes without outgoing edges is distributed evenly between all nodes.
+func PageRank(ctx context.Context, g *Graph, opts PageRankOptions) ([]float64, error) {
+	if opts.Damping == 0 {
+		opts.Damping = 0.85
+	}
+	if opts.Iterations == 0 {
+		opts.Iterations = 100
+	}
+	if opts.Tolerance == 0 {
+		opts.Tolerance = 1e-6
+	}
+	n := g.Len()
+	if n == 0 {
+		return nil, nil
+	}
+	d, fn := opts.Damping, float64(n)
+	rank, next := make([]float64, n), make([]float64, n)
+	for i := range rank {
+		rank[i] = 1 / fn
+	}
+	for iter := 0; iter < opts.Iterations; iter++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		dangling := 0.0
+		for i, r := range rank {
+			if len(g.out[i]) == 0 {
+				dangling += r
+			}
+		}
+		base := (1-d)/fn + d*dangling/fn
+		for i := range next {
+			next[i] = base
+		}
+		for i, r := range rank {
+			if out := g.out[i]; len(out) != 0 {
+				share := d * r / float64(len(out))
+				for _, j := range out {
+					next[j] += share
+				}
+			}
+		}
+		diff := 0.0
+		for i := range rank {
+			diff += math.Abs(next[i] - rank[i])
+		}
+		rank, next = next, rank
+		if diff < opts.Tolerance {
+			break
+		}
+	}
+	return rank, nil
+}
@@ -0,0 +1,206 @@
+// Copyright 2017 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.12
+
+package badger
+
+import (
+	"github.com/codelingo/cayley/clog"
+	"github.com/codelingo/cayley/graph"
+	"github.com/codelingo/cayley/graph/iterator"
+	"github.com/codelingo/cayley/graph/proto"
+	"github.com/codelingo/cayley/quad"
+)
+
+type AllIterator struct {
+	nodes  bool
+	uid    uint64
+	tags   graph.Tagger
+	bucket []byte
+	dir    quad.Direction
+	qs     *QuadStore
+	result *Token
+	err    error
+	buffer [][]byte
+	offset int
+	done   bool
+}
+
+func NewAllIterator(bucket []byte, d quad.Direction, qs *QuadStore) *AllIterator {
+	return &AllIterator{
+		nodes:  d == quad.Any,
+		uid:    iterator.NextUID(),
+		bucket: bucket,
+		dir:    d,
+		qs:     qs,
+	}
+}
+
+func (it *AllIterator) UID() uint64 {
+	return it.uid
+}
+
+func (it *AllIterator) Reset() {
+	it.buffer = nil
+	it.offset = 0
+	it.done = false
+}
+
+func (it *AllIterator) Tagger() *graph.Tagger {
+	return &it.tags
+}
+
+func (it *AllIterator) TagResults(dst map[string]graph.Value) {
+	for _, tag := range it.tags.Tags() {
+		dst[tag] = it.Result()
+	}
+
+	for tag, value := range it.tags.Fixed() {
+		dst[tag] = value
+	}
+}
+
+func (it *AllIterator) Clone() graph.Iterator {
+	out := NewAllIterator(it.bucket, it.dir, it.qs)
+	out.tags.CopyFrom(it)
+	return out
+}
+
+// isLiveNode checks if a node is used by any quad. Node sizes are only known for
+// the current state of the graph, thus views check the history of quads instead.
+func (qs *QuadStore) isLiveNode(key, val []byte) bool {
+	if qs.view {
+		return qs.hasLiveQuad(key)
+	}
+	var node proto.NodeData
+	node.Unmarshal(val)
+	return node.Size > 0
+}
+
+func (it *AllIterator) Next(ctx *graph.IterationContext) bool {
+	graph.NextLogIn(ctx, it)
+	return graph.NextLogOut(ctx, it, it.next(ctx))
+}
+
+func (it *AllIterator) next(ctx *graph.IterationContext) bool {
+	if it.done {
+		return false
+	}
+	if len(it.buffer) <= it.offset+1 {
+		var last []byte
+		if it.buffer != nil {
+			last = it.buffer[len(it.buffer)-1]
+			if last == nil {
+				it.done = true
+				return false
+			}
+		}
+		filter := it.qs.isLiveQuad
+		if it.nodes {
+			filter = it.qs.isLiveNode
+		}
+		it.offset = 0
+		it.buffer, it.err = it.qs.scan(it.bucket, nil, last, filter)
+		if it.err != nil {
+			clog.Errorf("Error nexting in database: %v", it.err)
+			it.done = true
+			return false
+		}
+	} else {
+		it.offset++
+	}
+	if it.Result() == nil {
+		it.done = true
+		return false
+	}
+	return true
+}
+
+func (it *AllIterator) Err() error {
+	return it.err
+}
+
+func (it *AllIterator) Result() graph.Value {
+	if it.done {
+		return nil
+	}
+	if it.result != nil {
+		return it.result
+	}
+	if it.offset >= len(it.buffer) || it.buffer[it.offset] == nil {
+		return nil
+	}
+	return &Token{nodes: it.nodes, bucket: it.bucket, key: it.buffer[it.offset]}
+}
+
+func (it *AllIterator) NextPath(ctx *graph.IterationContext) bool {
+	return false
+}
+
+// No subiterators.
+func (it *AllIterator) SubIterators() []graph.Iterator {
+	return nil
+}
+
+func (it *AllIterator) Contains(ctx *graph.IterationContext, v graph.Value) bool {
+	graph.ContainsLogIn(ctx, it, v)
+	return graph.ContainsLogOut(ctx, it, v, it.contains(ctx, v))
+}
+
+func (it *AllIterator) contains(ctx *graph.IterationContext, v graph.Value) bool {
+	it.result = v.(*Token)
+	return true
+}
+
+func (it *AllIterator) Close() error {
+	it.result = nil
+	it.buffer = nil
+	it.done = true
+	return nil
+}
+
+func (it *AllIterator) Size() (int64, bool) {
+	return it.qs.Size(), true
+}
+
+func (it *AllIterator) Describe() graph.Description {
+	size, _ := it.Size()
+	return graph.Description{
+		UID:       it.UID(),
+		Type:      it.Type(),
+		Tags:      it.tags.Tags(),
+		Size:      size,
+		Direction: it.dir,
+	}
+}
+
+func (it *AllIterator) Type() graph.Type { return graph.All }
+func (it *AllIterator) Sorted() bool     { return false }
+
+func (it *AllIterator) Optimize() (graph.Iterator, bool) {
+	return it, false
+}
+
+func (it *AllIterator) Stats() graph.IteratorStats {
+	s, exact := it.Size()
+	return graph.IteratorStats{
+		ContainsCost: 1,
+		NextCost:     2,
+		Size:         s,
+		ExactSize:    exact,
+	}
+}
+
+var _ graph.Iterator = &AllIterator{}
@@ -0,0 +1,251 @@
+// Copyright 2017 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.12
+
+package badger
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/dgraph-io/badger"
+
+	"github.com/codelingo/cayley/graph"
+	"github.com/codelingo/cayley/graph/graphtest"
+	"github.com/codelingo/cayley/graph/iterator"
+	"github.com/codelingo/cayley/graph/path/pathtest"
+	"github.com/codelingo/cayley/quad"
+	"github.com/codelingo/cayley/writer"
+)
+
+var _ graphtest.ValueSizer = (*QuadStore)(nil)
+
+func makeBadger(t testing.TB) (graph.QuadStore, graph.Options, func()) {
+	tmpDir, err := ioutil.TempDir(os.TempDir(), "cayley_test")
+	if err != nil {
+		t.Fatalf("Could not create working directory: %v", err)
+	}
+	err = createNewBadger(tmpDir, nil)
+	if err != nil {
+		os.RemoveAll(tmpDir)
+		t.Fatal("Failed to create Badger database.", err)
+	}
+	qs, err := newQuadStore(tmpDir, nil)
+	if qs == nil || err != nil {
+		os.RemoveAll(tmpDir)
+		t.Fatal("Failed to create Badger QuadStore.", err)
+	}
+	return qs, nil, func() {
+		qs.Close()
+		os.RemoveAll(tmpDir)
+	}
+}
+
+func TestBadgerAll(t *testing.T) {
+	graphtest.TestAll(t, makeBadger, nil)
+}
+
+func TestLoadDatabase(t *testing.T) {
+	tmpDir, err := ioutil.TempDir(os.TempDir(), "cayley_test")
+	if err != nil {
+		t.Fatalf("Could not create working directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	err = createNewBadger(tmpDir, nil)
+	if err != nil {
+		t.Fatal("Failed to create Badger database.", err)
+	}
+
+	qs, err := newQuadStore(tmpDir, nil)
+	if qs == nil || err != nil {
+		t.Fatal("Failed to create Badger QuadStore.", err)
+	}
+
+	w, _ := writer.NewSingleReplication(qs, nil)
+	w.AddQuad(quad.MakeRaw(
+		"Something",
+		"points_to",
+		"Something Else",
+		"context",
+	))
+	for _, pq := range []string{"Something", "points_to", "Something Else", "context"} {
+		if got := qs.NameOf(qs.ValueOf(quad.Raw(pq))).String(); got != pq {
+			t.Errorf("Failed to roundtrip %q, got:%q expect:%q", pq, got, pq)
+		}
+	}
+	if s := qs.Size(); s != 1 {
+		t.Errorf("Unexpected quadstore size, got:%d expect:1", s)
+	}
+	qs.Close()
+
+	err = createNewBadger(tmpDir, nil)
+	if err != graph.ErrDatabaseExists {
+		t.Fatal("Failed to create Badger database.", err)
+	}
+	qs, err = newQuadStore(tmpDir, nil)
+	if qs == nil || err != nil {
+		t.Fatal("Failed to open Badger QuadStore.", err)
+	}
+	defer qs.Close()
+	w, _ = writer.NewSingleReplication(qs, nil)
+
+	if h := qs.Horizon(); h.Int() != 1 {
+		t.Errorf("Unexpected horizon value, got:%d expect:1", h.Int())
+	}
+
+	w.AddQuadSet(graphtest.MakeQuadSet())
+	if s := qs.Size(); s != 12 {
+		t.Errorf("Unexpected quadstore size, got:%d expect:12", s)
+	}
+	if s := qs.(*QuadStore).SizeOf(qs.ValueOf(quad.Raw("B"))); s != 5 {
+		t.Errorf("Unexpected node size, got:%d expect:5", s)
+	}
+
+	w.RemoveQuad(quad.MakeRaw("A", "follows", "B", ""))
+	if s := qs.Size(); s != 11 {
+		t.Errorf("Unexpected quadstore size after RemoveQuad, got:%d expect:11", s)
+	}
+	if s := qs.(*QuadStore).SizeOf(qs.ValueOf(quad.Raw("B"))); s != 4 {
+		t.Errorf("Unexpected node size, got:%d expect:4", s)
+	}
+}
+
+func TestBulkLoad(t *testing.T) {
+	qs, opts, closer := makeBadger(t)
+	defer closer()
+
+	bl := qs.(graph.BulkLoader)
+	quads := graphtest.MakeQuadSet()
+	// duplicates must be skipped
+	quads = append(quads, quads[0], quads[len(quads)-1])
+
+	old := bulkBatchSize
+	bulkBatchSize = 5
+	defer func() { bulkBatchSize = old }()
+	if err := bl.BulkLoad(quad.NewReader(quads)); err != nil {
+		t.Fatal(err)
+	}
+	if s := qs.Size(); s != 11 {
+		t.Errorf("Unexpected quadstore size, got:%d expect:11", s)
+	}
+	if h := qs.Horizon(); h.Int() != 11 {
+		t.Errorf("Unexpected horizon value, got:%d expect:11", h.Int())
+	}
+	if s := qs.(*QuadStore).SizeOf(qs.ValueOf(quad.Raw("B"))); s != 5 {
+		t.Errorf("Unexpected node size, got:%d expect:5", s)
+	}
+	got := graphtest.IteratedQuads(t, qs, qs.QuadsAllIterator())
+	if len(got) != 11 {
+		t.Errorf("Unexpected number of quads, got:%d expect:11", len(got))
+	}
+	if err := bl.BulkLoad(quad.NewReader(quads)); err != graph.ErrCannotBulkLoad {
+		t.Errorf("Expected an error for non-empty store, got: %v", err)
+	}
+
+	// store must be writable after bulk load
+	w := graphtest.MakeWriter(t, qs, opts)
+	if err := w.RemoveQuad(quads[0]); err != nil {
+		t.Fatal(err)
+	}
+	if s := qs.Size(); s != 10 {
+		t.Errorf("Unexpected quadstore size, got:%d expect:10", s)
+	}
+}
+
+func TestApplyDeltasTxnTooBig(t *testing.T) {
+	tmpDir, err := ioutil.TempDir(os.TempDir(), "cayley_test")
+	if err != nil {
+		t.Fatalf("Could not create working directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	if err = createNewBadger(tmpDir, nil); err != nil {
+		t.Fatal("Failed to create Badger database.", err)
+	}
+	// small tables make Badger reject large transactions
+	db, err := badger.Open(badger.DefaultOptions(tmpDir).WithLogger(logger{}).WithMaxTableSize(1 << 20))
+	if err != nil {
+		t.Fatal("Failed to open Badger database.", err)
+	}
+	qs := &QuadStore{db: db, path: tmpDir}
+	defer qs.Close()
+	if err = qs.getMetadata(); err != nil {
+		t.Fatal(err)
+	}
+
+	const n = 1000
+	quads := make([]quad.Quad, 0, n)
+	for i := 0; i < n; i++ {
+		quads = append(quads, quad.MakeRaw(fmt.Sprint("s", i), "follows", fmt.Sprint("o", i), ""))
+	}
+	w := graphtest.MakeWriter(t, qs, nil)
+	if err = w.AddQuadSet(quads); err != nil {
+		t.Fatal(err)
+	}
+	if s := qs.Size(); s != n {
+		t.Errorf("Unexpected quadstore size, got:%d expect:%d", s, n)
+	}
+	if h := qs.Horizon(); h.Int() != n {
+		t.Errorf("Unexpected horizon value, got:%d expect:%d", h.Int(), n)
+	}
+	if s := qs.SizeOf(qs.ValueOf(quad.Raw("follows"))); s != n {
+		t.Errorf("Unexpected node size, got:%d expect:%d", s, n)
+	}
+}
+
+func TestOptimize(t *testing.T) {
+	qs, opts, closer := makeBadger(t)
+	defer closer()
+
+	graphtest.MakeWriter(t, qs, opts, graphtest.MakeQuadSet()...)
+
+	// With an linksto-fixed pair
+	fixed := qs.FixedIterator()
+	fixed.Add(qs.ValueOf(quad.Raw("F")))
+	fixed.Tagger().Add("internal")
+	lto := iterator.NewLinksTo(qs, fixed, quad.Object)
+
+	oldIt := lto.Clone()
+	newIt, ok := lto.Optimize()
+	if !ok {
+		t.Errorf("Failed to optimize iterator")
+	}
+	if newIt.Type() != Type() {
+		t.Errorf("Optimized iterator type does not match original, got:%v expect:%v", newIt.Type(), Type())
+	}
+
+	newQuads := graphtest.IteratedQuads(t, qs, newIt)
+	oldQuads := graphtest.IteratedQuads(t, qs, oldIt)
+	if !reflect.DeepEqual(newQuads, oldQuads) {
+		t.Errorf("Optimized iteration does not match original")
+	}
+
+	oldIt.Next(nil)
+	oldResults := make(map[string]graph.Value)
+	oldIt.TagResults(oldResults)
+	newIt.Next(nil)
+	newResults := make(map[string]graph.Value)
+	newIt.TagResults(newResults)
+	if !reflect.DeepEqual(newResults, oldResults) {
+		t.Errorf("Discordant tag results, new:%v old:%v", newResults, oldResults)
+	}
+}
+
+func TestBadgerPaths(t *testing.T) {
+	pathtest.RunTestMorphisms(t, makeBadger)
+}
@@ -0,0 +1,175 @@
+// Copyright 2017 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.12
+
+package badger
+
+import (
+	"io"
+	"time"
+
+	"github.com/dgraph-io/badger"
+
+	"github.com/codelingo/cayley/graph"
+	"github.com/codelingo/cayley/graph/proto"
+	"github.com/codelingo/cayley/quad"
+	"github.com/codelingo/cayley/quad/pquads"
+)
+
+var _ graph.BulkLoader = (*QuadStore)(nil)
+
+// bulkBatchSize is a number of quads written in a single batch by BulkLoad.
+var bulkBatchSize = 10000
+
+// BulkLoad loads quads into an empty quad store.
+//
+// Quads are written with Badger write batches, bypassing transactions and the size limits they have.
+// Duplicate quads are skipped. It returns graph.ErrCannotBulkLoad if the store is not empty.
+// If loading fails, quads from batches that were already written are kept in the store.
+func (qs *QuadStore) BulkLoad(r quad.Reader) (err error) {
+	if qs.view {
+		return graph.ErrReadOnlyView
+	}
+	qs.mu.Lock()
+	defer qs.mu.Unlock()
+	if qs.size != 0 || qs.horizon != 0 {
+		return graph.ErrCannotBulkLoad
+	}
+	defer func() {
+		if err2 := qs.db.Update(qs.writeHorizonAndSize); err == nil {
+			err = err2
+		}
+	}()
+	batch := make([]quad.Quad, 0, bulkBatchSize)
+	for {
+		q, err := r.ReadQuad()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		batch = append(batch, q)
+		if len(batch) == cap(batch) {
+			if err = qs.bulkWrite(batch); err != nil {
+				return err
+			}
+			batch = batch[:0]
+		}
+	}
+	return qs.bulkWrite(batch)
+}
+
+// bulkWrite writes a batch of quads, skipping quads that were already written.
+func (qs *QuadStore) bulkWrite(quads []quad.Quad) error {
+	if len(quads) == 0 {
+		return nil
+	}
+	type node struct {
+		val  quad.Value
+		size int64
+	}
+	var (
+		keys  = make(map[string]struct{}, len(quads))
+		nodes = make(map[string]*node)
+		fresh = make([]quad.Quad, 0, len(quads))
+	)
+	// find new quads and read current node sizes
+	err := qs.db.View(func(txn *badger.Txn) error {
+		for _, q := range quads {
+			k := keyFor(spoBucket, qs.createKeyFor(spo, q))
+			if _, ok := keys[string(k)]; ok {
+				continue
+			}
+			keys[string(k)] = struct{}{}
+			if data, err := get(txn, k); err != nil {
+				return err
+			} else if data != nil {
+				continue
+			}
+			fresh = append(fresh, q)
+			for _, d := range quad.Directions {
+				v := q.Get(d)
+				if v == nil {
+					continue
+				}
+				k := string(qs.createValueKeyFor(v))
+				n := nodes[k]
+				if n == nil {
+					var nd proto.NodeData
+					data, err := get(txn, keyFor(nodeBucket, []byte(k)))
+					if err != nil {
+						return err
+					} else if data != nil {
+						if err = nd.Unmarshal(data); err != nil {
+							return err
+						}
+					}
+					n = &node{val: v, size: nd.Size}
+					nodes[k] = n
+				}
+				n.size++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	wb := qs.db.NewWriteBatch()
+	defer wb.Cancel()
+	ts := time.Now().UnixNano()
+	horizon := qs.horizon
+	for _, q := range fresh {
+		horizon++
+		id := horizon
+		d := proto.LogDelta{
+			ID:        uint64(id),
+			Action:    int32(graph.Add),
+			Timestamp: ts,
+			Quad:      pquads.MakeQuad(q),
+		}
+		data, err := d.Marshal()
+		if err != nil {
+			return err
+		}
+		if err = wb.Set(qs.createDeltaKeyFor(id), data); err != nil {
+			return err
+		}
+		entry := proto.HistoryEntry{History: []uint64{uint64(id)}}
+		if data, err = entry.Marshal(); err != nil {
+			return err
+		}
+		if err = qs.writeIndexes(wb.Set, q, data); err != nil {
+			return err
+		}
+	}
+	for k, n := range nodes {
+		nd := proto.NodeData{Value: pquads.MakeValue(n.val), Size: n.size}
+		data, err := nd.Marshal()
+		if err != nil {
+			return err
+		}
+		if err = wb.Set(keyFor(nodeBucket, []byte(k)), data); err != nil {
+			return err
+		}
+	}
+	if err := wb.Flush(); err != nil {
+		return err
+	}
+	qs.horizon = horizon
+	qs.size += int64(len(fresh))
+	return nil
+}
@@ -0,0 +1,20 @@
+// Copyright 2017 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package badger implements a quad store backed by Badger, a pure-Go embedded key-value store.
+//
+// Keys use the same layout as the bolt quad store: each bolt bucket becomes a key prefix.
+//
+// Badger requires Go 1.12 or newer; with older versions of Go the package is empty and the backend is not registered.
+package badger
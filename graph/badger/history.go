@@ -0,0 +1,118 @@
+// Copyright 2017 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.12
+
+package badger
+
+import (
+	"sort"
+	"time"
+
+	"github.com/dgraph-io/badger"
+
+	"github.com/codelingo/cayley/clog"
+	"github.com/codelingo/cayley/graph"
+	"github.com/codelingo/cayley/graph/proto"
+)
+
+var _ graph.HistoryStore = (*QuadStore)(nil)
+
+// history returns the part of the quad history (a list of delta IDs) visible in this quad store.
+func (qs *QuadStore) history(h []uint64) []uint64 {
+	if !qs.view {
+		return h
+	}
+	n := sort.Search(len(h), func(i int) bool {
+		return int64(h[i]) > qs.horizon
+	})
+	return h[:n]
+}
+
+// hasLiveQuad checks if a node is used by any quad visible in this quad store.
+func (qs *QuadStore) hasLiveQuad(node []byte) bool {
+	live := false
+	err := qs.db.View(func(txn *badger.Txn) error {
+		for _, b := range [][]byte{spoBucket, posBucket, ospBucket, cpsBucket} {
+			opts := badger.DefaultIteratorOptions
+			opts.Prefix = keyFor(b, node)
+			it := txn.NewIterator(opts)
+			for it.Seek(opts.Prefix); !live && it.ValidForPrefix(opts.Prefix); it.Next() {
+				if err := it.Item().Value(func(v []byte) error {
+					live = qs.isLiveValue(v)
+					return nil
+				}); err != nil {
+					it.Close()
+					return err
+				}
+			}
+			it.Close()
+			if live {
+				return nil
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		clog.Errorf("Error checking node history: %v", err)
+		return false
+	}
+	return live
+}
+
+// AsOf returns a read-only view of the graph at a given horizon.
+//
+// Quads are checked against the history stored in the indexes, thus the view is
+// as fast as the store itself. Node and quad counts are estimated from the current state.
+func (qs *QuadStore) AsOf(horizon int64) (graph.QuadStore, error) {
+	qs.mu.RLock()
+	defer qs.mu.RUnlock()
+	if horizon > qs.horizon {
+		horizon = qs.horizon
+	}
+	return &QuadStore{
+		db:      qs.db,
+		path:    qs.path,
+		version: qs.version,
+		size:    qs.size,
+		horizon: horizon,
+		view:    true,
+	}, nil
+}
+
+// HorizonAt returns the ID of the last delta applied before or at a given time.
+func (qs *QuadStore) HorizonAt(t time.Time) (int64, error) {
+	ts := t.UnixNano()
+	var id int64
+	err := qs.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = logBucket
+		opts.Reverse = true
+		it := txn.NewIterator(opts)
+		defer it.Close()
+		// delta keys are ordered by ID; seek to the last one
+		for it.Seek(keyFor(logBucket, []byte{0xff})); it.ValidForPrefix(logBucket); it.Next() {
+			var d proto.LogDelta
+			if err := it.Item().Value(d.Unmarshal); err != nil {
+				return err
+			}
+			if d.Timestamp <= ts && (!qs.view || int64(d.ID) <= qs.horizon) {
+				id = int64(d.ID)
+				return nil
+			}
+		}
+		return nil
+	})
+	return id, err
+}
@@ -0,0 +1,286 @@
+// Copyright 2017 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.12
+
+package badger
+
+import (
+	"bytes"
+
+	"github.com/dgraph-io/badger"
+
+	"github.com/codelingo/cayley/clog"
+	"github.com/codelingo/cayley/graph"
+	"github.com/codelingo/cayley/graph/iterator"
+	"github.com/codelingo/cayley/quad"
+)
+
+var (
+	badgerType graph.Type
+	bufferSize = 50
+)
+
+func init() {
+	badgerType = graph.RegisterIterator("badger")
+}
+
+// scan reads up to bufferSize keys with a given prefix that go after the last key,
+// skipping values rejected by the filter. Keys are returned without the bucket prefix.
+// It returns a nil key at the end of the slice if there are no more keys.
+func (qs *QuadStore) scan(bucket, prefix, last []byte, filter func(k, v []byte) bool) ([][]byte, error) {
+	buf := make([][]byte, 0, bufferSize+1)
+	err := qs.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = keyFor(bucket, prefix)
+		opts.PrefetchValues = filter != nil
+		it := txn.NewIterator(opts)
+		defer it.Close()
+		if last == nil {
+			it.Seek(opts.Prefix)
+		} else {
+			lastKey := keyFor(bucket, last)
+			it.Seek(lastKey)
+			if it.ValidForPrefix(opts.Prefix) && bytes.Equal(it.Item().Key(), lastKey) {
+				it.Next()
+			}
+		}
+		for ; len(buf) < bufferSize; it.Next() {
+			if !it.ValidForPrefix(opts.Prefix) {
+				buf = append(buf, nil)
+				return nil
+			}
+			item := it.Item()
+			if filter != nil {
+				ok := false
+				if err := item.Value(func(v []byte) error {
+					ok = filter(item.Key()[len(bucket):], v)
+					return nil
+				}); err != nil {
+					return err
+				}
+				if !ok {
+					continue
+				}
+			}
+			buf = append(buf, item.KeyCopy(nil)[len(bucket):])
+		}
+		return nil
+	})
+	return buf, err
+}
+
+type Iterator struct {
+	uid     uint64
+	tags    graph.Tagger
+	bucket  []byte
+	checkID []byte
+	dir     quad.Direction
+	qs      *QuadStore
+	buffer  [][]byte
+	offset  int
+	done    bool
+	size    int64
+	err     error
+}
+
+func NewIterator(bucket []byte, d quad.Direction, value graph.Value, qs *QuadStore) *Iterator {
+	tok := value.(*Token)
+	if !bytes.Equal(tok.bucket, nodeBucket) {
+		clog.Errorf("creating an iterator from a non-node value")
+		return &Iterator{done: true}
+	}
+
+	it := Iterator{
+		uid:    iterator.NextUID(),
+		bucket: bucket,
+		dir:    d,
+		qs:     qs,
+		size:   qs.SizeOf(value),
+	}
+
+	it.checkID = make([]byte, len(tok.key))
+	copy(it.checkID, tok.key)
+
+	return &it
+}
+
+func Type() graph.Type { return badgerType }
+
+func (it *Iterator) UID() uint64 {
+	return it.uid
+}
+
+func (it *Iterator) Reset() {
+	it.buffer = nil
+	it.offset = 0
+	it.done = false
+}
+
+func (it *Iterator) Tagger() *graph.Tagger {
+	return &it.tags
+}
+
+func (it *Iterator) TagResults(dst map[string]graph.Value) {
+	for _, tag := range it.tags.Tags() {
+		dst[tag] = it.Result()
+	}
+
+	for tag, value := range it.tags.Fixed() {
+		dst[tag] = value
+	}
+}
+
+func (it *Iterator) Clone() graph.Iterator {
+	out := NewIterator(it.bucket, it.dir, &Token{true, nodeBucket, it.checkID}, it.qs)
+	out.Tagger().CopyFrom(it)
+	return out
+}
+
+func (it *Iterator) Close() error {
+	it.buffer = nil
+	it.done = true
+	return nil
+}
+
+func (it *Iterator) Next(ctx *graph.IterationContext) bool {
+	graph.NextLogIn(ctx, it)
+	return graph.NextLogOut(ctx, it, it.next(ctx))
+}
+
+func (it *Iterator) next(ctx *graph.IterationContext) bool {
+	if it.done {
+		return false
+	}
+	if len(it.buffer) <= it.offset+1 {
+		var last []byte
+		if it.buffer != nil {
+			last = it.buffer[len(it.buffer)-1]
+			if last == nil {
+				it.done = true
+				return false
+			}
+		}
+		it.offset = 0
+		it.buffer, it.err = it.qs.scan(it.bucket, it.checkID, last, it.qs.isLiveQuad)
+		if it.err != nil {
+			clog.Errorf("Error nexting in database: %v", it.err)
+			it.done = true
+			return false
+		}
+	} else {
+		it.offset++
+	}
+	if it.Result() == nil {
+		it.done = true
+		return false
+	}
+	return true
+}
+
+func (it *Iterator) Err() error {
+	return it.err
+}
+
+func (it *Iterator) Result() graph.Value {
+	if it.done || it.offset >= len(it.buffer) || it.buffer[it.offset] == nil {
+		return nil
+	}
+	return &Token{bucket: it.bucket, key: it.buffer[it.offset]}
+}
+
+func (it *Iterator) NextPath(ctx *graph.IterationContext) bool {
+	return false
+}
+
+// No subiterators.
+func (it *Iterator) SubIterators() []graph.Iterator {
+	return nil
+}
+
+func PositionOf(tok *Token, d quad.Direction, qs *QuadStore) int {
+	var index [4]quad.Direction
+	switch {
+	case bytes.Equal(tok.bucket, spoBucket):
+		index = spo
+	case bytes.Equal(tok.bucket, posBucket):
+		index = pos
+	case bytes.Equal(tok.bucket, ospBucket):
+		index = osp
+	case bytes.Equal(tok.bucket, cpsBucket):
+		index = cps
+	default:
+		panic("unreachable")
+	}
+	for i, id := range index {
+		if id == d {
+			return i * quad.HashSize
+		}
+	}
+	return -1
+}
+
+func (it *Iterator) Contains(ctx *graph.IterationContext, v graph.Value) bool {
+	graph.ContainsLogIn(ctx, it, v)
+	return graph.ContainsLogOut(ctx, it, v, it.contains(ctx, v))
+}
+
+func (it *Iterator) contains(ctx *graph.IterationContext, v graph.Value) bool {
+	val := v.(*Token)
+	if bytes.Equal(val.bucket, nodeBucket) {
+		return false
+	}
+	offset := PositionOf(val, it.dir, it.qs)
+	// tokens of quads come from Next, thus they are already checked to be live
+	return len(val.key) != 0 && bytes.HasPrefix(val.key[offset:], it.checkID)
+}
+
+func (it *Iterator) Size() (int64, bool) {
+	return it.size, true
+}
+
+func (it *Iterator) Describe() graph.Description {
+	nameOf := it.qs.NameOf(&Token{
+		nodes:  true,
+		bucket: nodeBucket,
+		key:    it.checkID,
+	})
+	return graph.Description{
+		UID:       it.UID(),
+		Name:      quad.StringOf(nameOf),
+		Type:      it.Type(),
+		Tags:      it.tags.Tags(),
+		Size:      it.size,
+		Direction: it.dir,
+	}
+}
+
+func (it *Iterator) Type() graph.Type { return badgerType }
+func (it *Iterator) Sorted() bool     { return false }
+
+func (it *Iterator) Optimize() (graph.Iterator, bool) {
+	return it, false
+}
+
+func (it *Iterator) Stats() graph.IteratorStats {
+	s, exact := it.Size()
+	return graph.IteratorStats{
+		ContainsCost: 1,
+		NextCost:     4,
+		Size:         s,
+		ExactSize:    exact,
+	}
+}
+
+var _ graph.Iterator = &Iterator{}
@@ -0,0 +1,641 @@
+// Copyright 2017 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build go1.12
+
+package badger
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/dgraph-io/badger"
+
+	"github.com/codelingo/cayley/clog"
+	"github.com/codelingo/cayley/graph"
+	"github.com/codelingo/cayley/graph/iterator"
+	"github.com/codelingo/cayley/graph/proto"
+	"github.com/codelingo/cayley/quad"
+	"github.com/codelingo/cayley/quad/pquads"
+)
+
+func init() {
+	graph.RegisterQuadStore(QuadStoreType, graph.QuadStoreRegistration{
+		NewFunc:           newQuadStore,
+		NewForRequestFunc: nil,
+		UpgradeFunc:       upgradeBadger,
+		InitFunc:          createNewBadger,
+		IsPersistent:      true,
+	})
+}
+
+const (
+	QuadStoreType = "badger"
+)
+
+const latestDataVersion = 1
+
+var (
+	errNoVersion = errors.New("badger: database version is missing")
+)
+
+var _ graph.Keyer = (*Token)(nil)
+
+type Token struct {
+	nodes  bool
+	bucket []byte
+	key    []byte
+}
+
+func (t *Token) IsNode() bool { return t.nodes }
+
+func (t *Token) Key() interface{} {
+	return fmt.Sprint(t.bucket, t.key)
+}
+
+// dbKey returns a full database key for a token.
+func (t *Token) dbKey() []byte {
+	return keyFor(t.bucket, t.key)
+}
+
+func keyFor(bucket, key []byte) []byte {
+	out := make([]byte, len(bucket)+len(key))
+	copy(out, bucket)
+	copy(out[len(bucket):], key)
+	return out
+}
+
+func clone(b []byte) []byte {
+	out := make([]byte, len(b))
+	copy(out, b)
+	return out
+}
+
+func (qs *QuadStore) isLiveValue(val []byte) bool {
+	var entry proto.HistoryEntry
+	entry.Unmarshal(val)
+	return len(qs.history(entry.History))%2 != 0
+}
+
+// isLiveQuad is a scan filter for quad indexes.
+func (qs *QuadStore) isLiveQuad(_, val []byte) bool {
+	return qs.isLiveValue(val)
+}
+
+type QuadStore struct {
+	db      *badger.DB
+	path    string
+	version int64
+
+	mu      sync.RWMutex
+	size    int64
+	horizon int64
+	view    bool // read-only view of the graph at the horizon; see AsOf
+}
+
+// logger redirects Badger logs to clog.
+type logger struct{}
+
+func (logger) Errorf(format string, args ...interface{})   { clog.Errorf("badger: "+format, args...) }
+func (logger) Warningf(format string, args ...interface{}) { clog.Warningf("badger: "+format, args...) }
+func (logger) Infof(format string, args ...interface{}) {
+	if clog.V(2) {
+		clog.Infof("badger: "+format, args...)
+	}
+}
+func (logger) Debugf(format string, args ...interface{}) {
+	if clog.V(4) {
+		clog.Infof("badger: "+format, args...)
+	}
+}
+
+func openDB(path string, opts graph.Options) (*badger.DB, error) {
+	bopts := badger.DefaultOptions(path).WithLogger(logger{})
+	// BoolKey returns false on non-existence. IE, Sync by default.
+	nosync, _, err := opts.BoolKey("nosync")
+	if err != nil {
+		return nil, err
+	}
+	bopts = bopts.WithSyncWrites(!nosync)
+	return badger.Open(bopts)
+}
+
+func createNewBadger(path string, opts graph.Options) error {
+	if err := os.MkdirAll(path, 0700); err != nil {
+		return err
+	}
+	db, err := openDB(path, opts)
+	if err != nil {
+		clog.Errorf("Error: couldn't create Badger database: %v", err)
+		return err
+	}
+	defer db.Close()
+	qs := &QuadStore{db: db}
+	err = qs.getMetadata()
+	if err != errNoVersion {
+		return graph.ErrDatabaseExists
+	}
+	return setVersion(db, latestDataVersion)
+}
+
+func newQuadStore(path string, options graph.Options) (graph.QuadStore, error) {
+	db, err := openDB(path, options)
+	if err != nil {
+		clog.Errorf("Error, couldn't open! %v", err)
+		return nil, err
+	}
+	qs := &QuadStore{db: db, path: path}
+	err = qs.getMetadata()
+	if err == errNoVersion {
+		db.Close()
+		return nil, errors.New("badger: quadstore has not been initialised")
+	} else if err != nil {
+		db.Close()
+		return nil, err
+	}
+	if qs.version != latestDataVersion {
+		db.Close()
+		return nil, errors.New("badger: data version is out of date. Run cayleyupgrade for your config to update the data.")
+	}
+	return qs, nil
+}
+
+func upgradeBadger(path string, opts graph.Options) error {
+	db, err := openDB(path, opts)
+	if err != nil {
+		clog.Errorf("Error, couldn't open! %v", err)
+		return err
+	}
+	defer db.Close()
+	qs := &QuadStore{db: db}
+	if err = qs.getMetadata(); err != nil {
+		return err
+	}
+	if qs.version > latestDataVersion {
+		err := fmt.Errorf("Unknown data version: %d -- upgrade this tool", qs.version)
+		clog.Errorf("error: %v", err)
+		return err
+	}
+	fmt.Printf("Already at latest version: %d\n", latestDataVersion)
+	return nil
+}
+
+func putInt64(txn *badger.Txn, key string, v int64) error {
+	buf := make([]byte, 8)
+	binary.LittleEndian.PutUint64(buf, uint64(v))
+	return txn.Set(keyFor(metaBucket, []byte(key)), buf)
+}
+
+func setVersion(db *badger.DB, version int64) error {
+	return db.Update(func(txn *badger.Txn) error {
+		return putInt64(txn, "version", version)
+	})
+}
+
+func (qs *QuadStore) Size() int64 {
+	qs.mu.RLock()
+	sz := qs.size
+	qs.mu.RUnlock()
+	return sz
+}
+
+func (qs *QuadStore) Horizon() graph.PrimaryKey {
+	qs.mu.RLock()
+	h := qs.horizon
+	qs.mu.RUnlock()
+	return graph.NewSequentialKey(h)
+}
+
+func (qs *QuadStore) createDeltaKeyFor(id int64) []byte {
+	return keyFor(logBucket, []byte(fmt.Sprintf("%018x", id)))
+}
+
+func bucketFor(d [4]quad.Direction) []byte {
+	return []byte{d[0].Prefix(), d[1].Prefix(), d[2].Prefix(), d[3].Prefix()}
+}
+
+func (qs *QuadStore) createKeyFor(d [4]quad.Direction, q quad.Quad) []byte {
+	key := make([]byte, quad.HashSize*4)
+	quad.HashTo(q.Get(d[0]), key[quad.HashSize*0:quad.HashSize*1])
+	quad.HashTo(q.Get(d[1]), key[quad.HashSize*1:quad.HashSize*2])
+	quad.HashTo(q.Get(d[2]), key[quad.HashSize*2:quad.HashSize*3])
+	quad.HashTo(q.Get(d[3]), key[quad.HashSize*3:quad.HashSize*4])
+	return key
+}
+
+func (qs *QuadStore) createValueKeyFor(s quad.Value) []byte {
+	return quad.HashOf(s)
+}
+
+var (
+	// Short hand for direction permutations.
+	spo = [4]quad.Direction{quad.Subject, quad.Predicate, quad.Object, quad.Label}
+	osp = [4]quad.Direction{quad.Object, quad.Subject, quad.Predicate, quad.Label}
+	pos = [4]quad.Direction{quad.Predicate, quad.Object, quad.Subject, quad.Label}
+	cps = [4]quad.Direction{quad.Label, quad.Predicate, quad.Subject, quad.Object}
+
+	// Key prefixes for each bucket.
+	spoBucket  = bucketFor(spo)
+	ospBucket  = bucketFor(osp)
+	posBucket  = bucketFor(pos)
+	cpsBucket  = bucketFor(cps)
+	logBucket  = []byte("log")
+	nodeBucket = []byte("node")
+	metaBucket = []byte("meta")
+)
+
+func deltaToProto(delta graph.Delta) proto.LogDelta {
+	var newd proto.LogDelta
+	newd.ID = uint64(delta.ID.Int())
+	newd.Action = int32(delta.Action)
+	newd.Timestamp = delta.Timestamp.UnixNano()
+	newd.Quad = pquads.MakeQuad(delta.Quad)
+	return newd
+}
+
+// get returns a copy of the value for a given key, or nil if the key does not exist.
+func get(txn *badger.Txn, key []byte) ([]byte, error) {
+	item, err := txn.Get(key)
+	if err == badger.ErrKeyNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return item.ValueCopy(nil)
+}
+
+// ApplyDeltas writes deltas to the store in a single transaction.
+//
+// Badger limits the size of a transaction, thus deltas that do not fit into one are split in halves
+// and written in separate transactions. If writing fails, deltas from transactions that were
+// already committed are kept in the store.
+func (qs *QuadStore) ApplyDeltas(deltas []graph.Delta, ignoreOpts graph.IgnoreOpts) error {
+	if qs.view {
+		return graph.ErrReadOnlyView
+	}
+	qs.mu.Lock()
+	defer qs.mu.Unlock()
+	err := qs.applyDeltas(deltas, ignoreOpts)
+	if err != nil {
+		clog.Errorf("Couldn't write to DB for Delta set. Error: %v", err)
+	}
+	return err
+}
+
+// isTxnTooBig checks if an error was caused by exceeding the transaction size limit.
+func isTxnTooBig(err error) bool {
+	if e, ok := err.(*graph.DeltaError); ok {
+		err = e.Err
+	}
+	return err == badger.ErrTxnTooBig
+}
+
+func (qs *QuadStore) applyDeltas(deltas []graph.Delta, ignoreOpts graph.IgnoreOpts) error {
+	oldSize := qs.size
+	oldHorizon := qs.horizon
+	err := qs.db.Update(func(txn *badger.Txn) error {
+		resizeMap := make(map[quad.Value]int64)
+		sizeChange := int64(0)
+		for _, d := range deltas {
+			if d.Action != graph.Add && d.Action != graph.Delete {
+				return &graph.DeltaError{Delta: d, Err: graph.ErrInvalidAction}
+			}
+			p := deltaToProto(d)
+			bytes, err := p.Marshal()
+			if err != nil {
+				return &graph.DeltaError{Delta: d, Err: err}
+			}
+			err = txn.Set(qs.createDeltaKeyFor(d.ID.Int()), bytes)
+			if err != nil {
+				return &graph.DeltaError{Delta: d, Err: err}
+			}
+		}
+		for _, d := range deltas {
+			err := qs.buildQuadWrite(txn, d.Quad, d.ID.Int(), d.Action == graph.Add)
+			if err != nil {
+				if err == graph.ErrQuadExists && ignoreOpts.IgnoreDup {
+					continue
+				}
+				if err == graph.ErrQuadNotExist && ignoreOpts.IgnoreMissing {
+					continue
+				}
+				return &graph.DeltaError{Delta: d, Err: err}
+			}
+			delta := int64(1)
+			if d.Action == graph.Delete {
+				delta = int64(-1)
+			}
+			resizeMap[d.Quad.Subject] += delta
+			resizeMap[d.Quad.Predicate] += delta
+			resizeMap[d.Quad.Object] += delta
+			if d.Quad.Label != nil {
+				resizeMap[d.Quad.Label] += delta
+			}
+			sizeChange += delta
+			qs.horizon = d.ID.Int()
+		}
+		for k, v := range resizeMap {
+			if v != 0 {
+				err := qs.UpdateValueKeyBy(k, v, txn)
+				if err != nil {
+					return err
+				}
+			}
+		}
+		qs.size += sizeChange
+		return qs.writeHorizonAndSize(txn)
+	})
+	if err == nil {
+		return nil
+	}
+	qs.horizon = oldHorizon
+	qs.size = oldSize
+	if n := len(deltas) / 2; n > 0 && isTxnTooBig(err) {
+		if err = qs.applyDeltas(deltas[:n], ignoreOpts); err != nil {
+			return err
+		}
+		return qs.applyDeltas(deltas[n:], ignoreOpts)
+	}
+	return err
+}
+
+func (qs *QuadStore) buildQuadWrite(txn *badger.Txn, q quad.Quad, id int64, isAdd bool) error {
+	var entry proto.HistoryEntry
+	data, err := get(txn, keyFor(spoBucket, qs.createKeyFor(spo, q)))
+	if err != nil {
+		return err
+	} else if data != nil {
+		// We got something.
+		err := entry.Unmarshal(data)
+		if err != nil {
+			return err
+		}
+	}
+
+	if isAdd && len(entry.History)%2 == 1 {
+		return graph.ErrQuadExists
+	}
+	if !isAdd && len(entry.History)%2 == 0 {
+		return graph.ErrQuadNotExist
+	}
+
+	entry.History = append(entry.History, uint64(id))
+
+	bytes, err := entry.Marshal()
+	if err != nil {
+		clog.Errorf("Couldn't write to buffer for entry %#v: %s", entry, err)
+		return err
+	}
+	return qs.writeIndexes(txn.Set, q, bytes)
+}
+
+// writeIndexes writes a history entry of the quad to all indexes.
+func (qs *QuadStore) writeIndexes(set func(k, v []byte) error, q quad.Quad, entry []byte) error {
+	for _, index := range [][4]quad.Direction{spo, osp, pos, cps} {
+		if index == cps && q.Get(quad.Label) == nil {
+			continue
+		}
+		err := set(keyFor(bucketFor(index), qs.createKeyFor(index, q)), entry)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (qs *QuadStore) UpdateValueKeyBy(name quad.Value, amount int64, txn *badger.Txn) error {
+	value := proto.NodeData{
+		Value: pquads.MakeValue(name),
+		Size:  amount,
+	}
+	key := keyFor(nodeBucket, qs.createValueKeyFor(name))
+	data, err := get(txn, key)
+	if err != nil {
+		return err
+	}
+
+	if data != nil {
+		// Node exists in the database -- unmarshal and update.
+		var oldvalue proto.NodeData
+		err := oldvalue.Unmarshal(data)
+		if err != nil {
+			clog.Errorf("Error: couldn't reconstruct value: %v", err)
+			return err
+		}
+		oldvalue.Size += amount
+		value = oldvalue
+	}
+
+	// Are we deleting something?
+	if value.Size <= 0 {
+		value.Size = 0
+	}
+
+	// Repackage and rewrite.
+	bytes, err := value.Marshal()
+	if err != nil {
+		clog.Errorf("Couldn't write to buffer for value %s: %s", name, err)
+		return err
+	}
+	return txn.Set(key, bytes)
+}
+
+func (qs *QuadStore) writeHorizonAndSize(txn *badger.Txn) error {
+	if err := putInt64(txn, "size", qs.size); err != nil {
+		clog.Errorf("Couldn't write size!")
+		return err
+	}
+	if err := putInt64(txn, "horizon", qs.horizon); err != nil {
+		clog.Errorf("Couldn't write horizon!")
+		return err
+	}
+	return nil
+}
+
+func (qs *QuadStore) Close() error {
+	if qs.view {
+		// database is owned by the parent quad store
+		return nil
+	}
+	qs.mu.Lock()
+	defer qs.mu.Unlock()
+	qs.db.Update(func(txn *badger.Txn) error {
+		return qs.writeHorizonAndSize(txn)
+	})
+	return qs.db.Close()
+}
+
+func (qs *QuadStore) Quad(k graph.Value) quad.Quad {
+	var d proto.LogDelta
+	tok := k.(*Token)
+	err := qs.db.View(func(txn *badger.Txn) error {
+		data, err := get(txn, tok.dbKey())
+		if err != nil || data == nil {
+			return err
+		}
+		var in proto.HistoryEntry
+		err = in.Unmarshal(data)
+		if err != nil {
+			return err
+		}
+		hist := qs.history(in.History)
+		if len(hist) == 0 {
+			return nil
+		}
+		data, err = get(txn, qs.createDeltaKeyFor(int64(hist[len(hist)-1])))
+		if err != nil || data == nil {
+			// No harm, no foul.
+			return err
+		}
+		return d.Unmarshal(data)
+	})
+	if err != nil {
+		clog.Errorf("Error getting quad: %v", err)
+		return quad.Quad{}
+	}
+	return d.Quad.ToNative()
+}
+
+func (qs *QuadStore) ValueOf(s quad.Value) graph.Value {
+	return &Token{
+		bucket: nodeBucket,
+		key:    qs.createValueKeyFor(s),
+	}
+}
+
+func (qs *QuadStore) valueData(t *Token) proto.NodeData {
+	var out proto.NodeData
+	if clog.V(4) {
+		clog.Infof("%s %v", string(t.bucket), t.key)
+	}
+	err := qs.db.View(func(txn *badger.Txn) error {
+		data, err := get(txn, t.dbKey())
+		if err != nil || data == nil {
+			return err
+		}
+		return out.Unmarshal(data)
+	})
+	if err != nil {
+		clog.Errorf("Error: couldn't get value")
+		return proto.NodeData{}
+	}
+	return out
+}
+
+func (qs *QuadStore) NameOf(k graph.Value) quad.Value {
+	if k == nil {
+		if clog.V(2) {
+			clog.Infof("k was nil")
+		}
+		return nil
+	} else if v, ok := k.(graph.PreFetchedValue); ok {
+		return v.NameOf()
+	}
+	v := qs.valueData(k.(*Token))
+	return v.GetNativeValue()
+}
+
+func (qs *QuadStore) SizeOf(k graph.Value) int64 {
+	if k == nil {
+		return -1
+	}
+	return int64(qs.valueData(k.(*Token)).Size)
+}
+
+func getInt64ForMetaKey(txn *badger.Txn, key string, empty int64) (int64, error) {
+	data, err := get(txn, keyFor(metaBucket, []byte(key)))
+	if err != nil {
+		return 0, err
+	} else if data == nil {
+		return empty, nil
+	} else if len(data) != 8 {
+		return 0, fmt.Errorf("badger: invalid value for %q", key)
+	}
+	return int64(binary.LittleEndian.Uint64(data)), nil
+}
+
+func (qs *QuadStore) getMetadata() error {
+	qs.mu.Lock()
+	defer qs.mu.Unlock()
+	return qs.db.View(func(txn *badger.Txn) error {
+		var err error
+		qs.version, err = getInt64ForMetaKey(txn, "version", 0)
+		if err != nil {
+			return err
+		} else if qs.version == 0 {
+			return errNoVersion
+		}
+		qs.size, err = getInt64ForMetaKey(txn, "size", 0)
+		if err != nil {
+			return err
+		}
+		qs.horizon, err = getInt64ForMetaKey(txn, "horizon", 0)
+		return err
+	})
+}
+
+func (qs *QuadStore) QuadIterator(d quad.Direction, val graph.Value) graph.Iterator {
+	var bucket []byte
+	switch d {
+	case quad.Subject:
+		bucket = spoBucket
+	case quad.Predicate:
+		bucket = posBucket
+	case quad.Object:
+		bucket = ospBucket
+	case quad.Label:
+		bucket = cpsBucket
+	default:
+		panic("unreachable " + d.String())
+	}
+	return NewIterator(bucket, d, val, qs)
+}
+
+func (qs *QuadStore) NodesAllIterator() graph.Iterator {
+	return NewAllIterator(nodeBucket, quad.Any, qs)
+}
+
+func (qs *QuadStore) QuadsAllIterator() graph.Iterator {
+	return NewAllIterator(posBucket, quad.Predicate, qs)
+}
+
+func (qs *QuadStore) QuadDirection(val graph.Value, d quad.Direction) graph.Value {
+	v := val.(*Token)
+	offset := PositionOf(v, d, qs)
+	if offset != -1 {
+		return &Token{
+			bucket: nodeBucket,
+			key:    v.key[offset : offset+quad.HashSize],
+		}
+	}
+	return qs.ValueOf(qs.Quad(v).Get(d))
+}
+
+func compareTokens(a, b graph.Value) bool {
+	atok := a.(*Token)
+	btok := b.(*Token)
+	return bytes.Equal(atok.key, btok.key) && bytes.Equal(atok.bucket, btok.bucket)
+}
+
+func (qs *QuadStore) FixedIterator() graph.FixedIterator {
+	return iterator.NewFixed(compareTokens)
+}
+
+func (qs *QuadStore) Type() string {
+	return QuadStoreType
+}
@@ -80,7 +80,20 @@ func (it *AllIterator) Clone() graph.Iterator {
 	return out
 }
 
+// isLive checks if a node or a quad is visible in the quad store.
+func (it *AllIterator) isLive(tx *bolt.Tx, k, v []byte) bool {
+	if !it.nodes {
+		return it.qs.isLiveValue(v)
+	}
+	return !it.qs.view || it.qs.hasLiveQuad(tx, k)
+}
+
 func (it *AllIterator) Next(ctx *graph.IterationContext) bool {
+	graph.NextLogIn(ctx, it)
+	return graph.NextLogOut(ctx, it, it.next(ctx))
+}
+
+func (it *AllIterator) next(ctx *graph.IterationContext) bool {
 	if it.done {
 		return false
 	}
@@ -97,7 +110,7 @@ func (it *AllIterator) Next(ctx *graph.IterationContext) bool {
 			cur := b.Cursor()
 			if last == nil {
 				k, v := cur.First()
-				if it.nodes || isLiveValue(v) {
+				if it.isLive(tx, k, v) {
 					it.buffer = append(it.buffer, clone(k))
 					i++
 				}
@@ -113,7 +126,7 @@ func (it *AllIterator) Next(ctx *graph.IterationContext) bool {
 					it.buffer = append(it.buffer, k)
 					break
 				}
-				if !it.nodes && !isLiveValue(v) {
+				if !it.isLive(tx, k, v) {
 					continue
 				}
 				it.buffer = append(it.buffer, clone(k))
@@ -167,6 +180,11 @@ func (it *AllIterator) SubIterators() []graph.Iterator {
 }
 
 func (it *AllIterator) Contains(ctx *graph.IterationContext, v graph.Value) bool {
+	graph.ContainsLogIn(ctx, it, v)
+	return graph.ContainsLogOut(ctx, it, v, it.contains(ctx, v))
+}
+
+func (it *AllIterator) contains(ctx *graph.IterationContext, v graph.Value) bool {
 	it.result = v.(*Token)
 	return true
 }
@@ -0,0 +1,94 @@
+// Copyright 2017 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bolt
+
+import (
+	"bytes"
+	"sort"
+	"time"
+
+	"github.com/boltdb/bolt"
+
+	"github.com/codelingo/cayley/graph"
+	"github.com/codelingo/cayley/graph/proto"
+)
+
+var _ graph.HistoryStore = (*QuadStore)(nil)
+
+// history returns the part of the quad history (a list of delta IDs) visible in this quad store.
+func (qs *QuadStore) history(h []uint64) []uint64 {
+	if !qs.view {
+		return h
+	}
+	n := sort.Search(len(h), func(i int) bool {
+		return int64(h[i]) > qs.horizon
+	})
+	return h[:n]
+}
+
+// hasLiveQuad checks if a node is used by any quad visible in this quad store.
+func (qs *QuadStore) hasLiveQuad(tx *bolt.Tx, node []byte) bool {
+	for _, b := range [][]byte{spoBucket, posBucket, ospBucket, cpsBucket} {
+		cur := tx.Bucket(b).Cursor()
+		for k, v := cur.Seek(node); k != nil && bytes.HasPrefix(k, node); k, v = cur.Next() {
+			if qs.isLiveValue(v) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// AsOf returns a read-only view of the graph at a given horizon.
+//
+// Quads are checked against the history stored in the indexes, thus the view is
+// as fast as the store itself. Node and quad counts are estimated from the current state.
+func (qs *QuadStore) AsOf(horizon int64) (graph.QuadStore, error) {
+	qs.mu.RLock()
+	defer qs.mu.RUnlock()
+	if horizon > qs.horizon {
+		horizon = qs.horizon
+	}
+	return &QuadStore{
+		db:      qs.db,
+		path:    qs.path,
+		version: qs.version,
+		size:    qs.size,
+		horizon: horizon,
+		view:    true,
+		vopts:   qs.vopts,
+	}, nil
+}
+
+// HorizonAt returns the ID of the last delta applied before or at a given time.
+func (qs *QuadStore) HorizonAt(t time.Time) (int64, error) {
+	ts := t.UnixNano()
+	var id int64
+	err := qs.db.View(func(tx *bolt.Tx) error {
+		cur := tx.Bucket(logBucket).Cursor()
+		for k, v := cur.Last(); k != nil; k, v = cur.Prev() {
+			var d proto.LogDelta
+			if err := d.Unmarshal(v); err != nil {
+				return err
+			}
+			if d.Timestamp <= ts && (!qs.view || int64(d.ID) <= qs.horizon) {
+				id = int64(d.ID)
+				return nil
+			}
+		}
+		return nil
+	})
+	return id, err
+}
@@ -113,6 +113,11 @@ func (it *Iterator) Close() error {
 }
 
 func (it *Iterator) Next(ctx *graph.IterationContext) bool {
+	graph.NextLogIn(ctx, it)
+	return graph.NextLogOut(ctx, it, it.next(ctx))
+}
+
+func (it *Iterator) next(ctx *graph.IterationContext) bool {
 	if it.done {
 		return false
 	}
@@ -130,7 +135,7 @@ func (it *Iterator) Next(ctx *graph.IterationContext) bool {
 			if last == nil {
 				k, v := cur.Seek(it.checkID)
 				if bytes.HasPrefix(k, it.checkID) {
-					if isLiveValue(v) {
+					if it.qs.isLiveValue(v) {
 						it.buffer = append(it.buffer, clone(k))
 						i++
 					}
@@ -150,7 +155,7 @@ func (it *Iterator) Next(ctx *graph.IterationContext) bool {
 					it.buffer = append(it.buffer, nil)
 					break
 				}
-				if !isLiveValue(v) {
+				if !it.qs.isLiveValue(v) {
 					continue
 				}
 				it.buffer = append(it.buffer, clone(k))
@@ -258,6 +263,11 @@ func PositionOf(tok *Token, d quad.Direction, qs *QuadStore) int {
 }
 
 func (it *Iterator) Contains(ctx *graph.IterationContext, v graph.Value) bool {
+	graph.ContainsLogIn(ctx, it, v)
+	return graph.ContainsLogOut(ctx, it, v, it.contains(ctx, v))
+}
+
+func (it *Iterator) contains(ctx *graph.IterationContext, v graph.Value) bool {
 	val := v.(*Token)
 	if bytes.Equal(val.bucket, nodeBucket) {
 		return false
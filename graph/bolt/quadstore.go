@@ -27,6 +27,7 @@ import (
 	"github.com/codelingo/cayley/graph"
 	"github.com/codelingo/cayley/graph/iterator"
 	"github.com/codelingo/cayley/graph/proto"
+	"github.com/codelingo/cayley/graph/valueindex"
 	"github.com/codelingo/cayley/quad"
 	"github.com/codelingo/cayley/quad/pquads"
 )
@@ -71,10 +72,10 @@ func clone(b []byte) []byte {
 	return out
 }
 
-func isLiveValue(val []byte) bool {
+func (qs *QuadStore) isLiveValue(val []byte) bool {
 	var entry proto.HistoryEntry
 	entry.Unmarshal(val)
-	return len(entry.History)%2 != 0
+	return len(qs.history(entry.History))%2 != 0
 }
 
 type QuadStore struct {
@@ -85,6 +86,9 @@ type QuadStore struct {
 	mu      sync.RWMutex
 	size    int64
 	horizon int64
+	view    bool // read-only view of the graph at the horizon; see AsOf
+
+	vopts valueindex.Options // value indexes kept by the store
 }
 
 func createNewBolt(path string, _ graph.Options) error {
@@ -136,6 +140,13 @@ func newQuadStore(path string, options graph.Options) (graph.QuadStore, error) {
 	if qs.version != latestDataVersion {
 		return nil, errors.New("bolt: data version is out of date. Run cayleyupgrade for your config to update the data.")
 	}
+	qs.vopts, err = valueindex.ParseOptions(options)
+	if err != nil {
+		return nil, err
+	}
+	if err = qs.initValueIndexes(); err != nil {
+		return nil, err
+	}
 	return &qs, nil
 }
 
@@ -241,6 +252,9 @@ func deltaToProto(delta graph.Delta) proto.LogDelta {
 }
 
 func (qs *QuadStore) ApplyDeltas(deltas []graph.Delta, ignoreOpts graph.IgnoreOpts) error {
+	if qs.view {
+		return graph.ErrReadOnlyView
+	}
 	qs.mu.Lock()
 	defer qs.mu.Unlock()
 	oldSize := qs.size
@@ -346,6 +360,9 @@ func (qs *QuadStore) buildQuadWrite(tx *bolt.Tx, q quad.Quad, id int64, isAdd bo
 			return err
 		}
 	}
+	if isAdd {
+		return qs.indexQuad(tx, q)
+	}
 	return nil
 }
 
@@ -417,6 +434,10 @@ func (qs *QuadStore) writeHorizonAndSize(tx *bolt.Tx) error {
 }
 
 func (qs *QuadStore) Close() error {
+	if qs.view {
+		// database is owned by the parent quad store
+		return nil
+	}
 	qs.mu.Lock()
 	defer qs.mu.Unlock()
 	qs.db.Update(func(tx *bolt.Tx) error {
@@ -439,11 +460,12 @@ func (qs *QuadStore) Quad(k graph.Value) quad.Quad {
 		if err != nil {
 			return err
 		}
-		if len(in.History) == 0 {
+		hist := qs.history(in.History)
+		if len(hist) == 0 {
 			return nil
 		}
 		b = tx.Bucket(logBucket)
-		data = b.Get(qs.createDeltaKeyFor(int64(in.History[len(in.History)-1])))
+		data = b.Get(qs.createDeltaKeyFor(int64(hist[len(hist)-1])))
 		if data == nil {
 			// No harm, no foul.
 			return nil
@@ -23,7 +23,8 @@ func (qs *QuadStore) OptimizeIterator(it graph.Iterator) (graph.Iterator, bool)
 	switch it.Type() {
 	case graph.LinksTo:
 		return qs.optimizeLinksTo(it.(*iterator.LinksTo))
-
+	case graph.Comparison:
+		return qs.optimizeComparison(it.(*iterator.Comparison))
 	}
 	return it, false
 }
@@ -0,0 +1,196 @@
+// Copyright 2017 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bolt
+
+import (
+	"bytes"
+
+	"github.com/boltdb/bolt"
+
+	"github.com/codelingo/cayley/clog"
+	"github.com/codelingo/cayley/graph"
+	"github.com/codelingo/cayley/graph/iterator"
+	"github.com/codelingo/cayley/graph/proto"
+	"github.com/codelingo/cayley/graph/valueindex"
+	"github.com/codelingo/cayley/quad"
+)
+
+var (
+	// valueBucket maps value keys followed by node hashes to nothing.
+	valueBucket = []byte("value")
+	// predValueBucket maps predicate hashes, value keys and object hashes to nothing.
+	predValueBucket = []byte("pvalue")
+)
+
+// initValueIndexes creates value index buckets that are enabled in options and drops the ones
+// that are not, since the latter are not updated on writes. New buckets are filled from existing data.
+func (qs *QuadStore) initValueIndexes() error {
+	return qs.db.Update(func(tx *bolt.Tx) error {
+		for _, b := range []struct {
+			name    []byte
+			enabled bool
+			fill    func(tx *bolt.Tx) error
+		}{
+			{valueBucket, qs.vopts.Global, qs.fillValueIndex},
+			{predValueBucket, qs.vopts.ByPredicate, qs.fillPredValueIndex},
+		} {
+			exists := tx.Bucket(b.name) != nil
+			if exists == b.enabled {
+				continue
+			} else if exists {
+				if err := tx.DeleteBucket(b.name); err != nil {
+					return err
+				}
+				continue
+			}
+			if _, err := tx.CreateBucket(b.name); err != nil {
+				return err
+			}
+			clog.Infof("bolt: building %s index", b.name)
+			if err := b.fill(tx); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (qs *QuadStore) fillValueIndex(tx *bolt.Tx) error {
+	return tx.Bucket(nodeBucket).ForEach(func(k, v []byte) error {
+		var nd proto.NodeData
+		if err := nd.Unmarshal(v); err != nil {
+			return err
+		}
+		return qs.indexValue(tx, nd.GetNativeValue(), k)
+	})
+}
+
+func (qs *QuadStore) fillPredValueIndex(tx *bolt.Tx) error {
+	nodes := tx.Bucket(nodeBucket)
+	return tx.Bucket(posBucket).ForEach(func(k, _ []byte) error {
+		p, o := k[:quad.HashSize], k[quad.HashSize:2*quad.HashSize]
+		var nd proto.NodeData
+		if data := nodes.Get(o); data == nil {
+			return nil
+		} else if err := nd.Unmarshal(data); err != nil {
+			return err
+		}
+		return qs.indexPredValue(tx, p, nd.GetNativeValue(), o)
+	})
+}
+
+func (qs *QuadStore) indexValue(tx *bolt.Tx, v quad.Value, node []byte) error {
+	k, ok := valueindex.Key(v)
+	if !ok {
+		return nil
+	}
+	b := tx.Bucket(valueBucket)
+	b.FillPercent = localFillPercent
+	return b.Put(append(k, node...), nil)
+}
+
+func (qs *QuadStore) indexPredValue(tx *bolt.Tx, pred []byte, v quad.Value, node []byte) error {
+	k, ok := valueindex.Key(v)
+	if !ok {
+		return nil
+	}
+	key := make([]byte, 0, len(pred)+len(k)+len(node))
+	key = append(key, pred...)
+	key = append(key, k...)
+	key = append(key, node...)
+	b := tx.Bucket(predValueBucket)
+	b.FillPercent = localFillPercent
+	return b.Put(key, nil)
+}
+
+// indexQuad adds values of an added quad to value indexes. Index entries are never removed,
+// the same way as nodes are never removed from the store.
+func (qs *QuadStore) indexQuad(tx *bolt.Tx, q quad.Quad) error {
+	if qs.vopts.Global {
+		for _, d := range quad.Directions {
+			v := q.Get(d)
+			if v == nil {
+				continue
+			}
+			if err := qs.indexValue(tx, v, qs.createValueKeyFor(v)); err != nil {
+				return err
+			}
+		}
+	}
+	if qs.vopts.ByPredicate {
+		p := qs.createValueKeyFor(q.Predicate)
+		if err := qs.indexPredValue(tx, p, q.Object, qs.createValueKeyFor(q.Object)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// scanValues returns a function that reads entries from a value index bucket.
+// Index keys end with node hashes.
+func (qs *QuadStore) scanValues(bucket []byte) valueindex.ScanFunc {
+	return func(from, to []byte, n int) ([][]byte, []graph.Value, error) {
+		var (
+			keys [][]byte
+			vals []graph.Value
+		)
+		err := qs.db.View(func(tx *bolt.Tx) error {
+			b := tx.Bucket(bucket)
+			if b == nil {
+				return errNoBucket
+			}
+			cur := b.Cursor()
+			for k, _ := cur.Seek(from); k != nil && bytes.Compare(k, to) < 0 && len(keys) < n; k, _ = cur.Next() {
+				keys = append(keys, clone(k))
+				vals = append(vals, &Token{
+					bucket: nodeBucket,
+					key:    clone(k[len(k)-quad.HashSize:]),
+				})
+			}
+			return nil
+		})
+		return keys, vals, err
+	}
+}
+
+// predicateOf checks if an iterator returns objects of quads with a given predicate.
+func (qs *QuadStore) predicateOf(it graph.Iterator) ([]byte, bool) {
+	hasa, ok := it.(*iterator.HasA)
+	if !ok || hasa.Direction() != quad.Object {
+		return nil, false
+	}
+	sub, ok := hasa.SubIterators()[0].(*Iterator)
+	if !ok || sub.dir != quad.Predicate {
+		return nil, false
+	}
+	return sub.checkID, true
+}
+
+func (qs *QuadStore) optimizeComparison(it *iterator.Comparison) (graph.Iterator, bool) {
+	if !valueindex.Indexable(it) {
+		return it, false
+	}
+	sub := it.SubIterators()[0]
+	if p, ok := qs.predicateOf(sub); ok && qs.vopts.ByPredicate {
+		rit := valueindex.NewIterator(qs, it.Operator(), it.Value(), p, qs.scanValues(predValueBucket))
+		return valueindex.Intersect(qs, it, rit, false), true
+	}
+	if !qs.vopts.Global {
+		return it, false
+	}
+	all, ok := sub.(*AllIterator)
+	rit := valueindex.NewIterator(qs, it.Operator(), it.Value(), nil, qs.scanValues(valueBucket))
+	return valueindex.Intersect(qs, it, rit, ok && all.nodes), true
+}
@@ -0,0 +1,255 @@
+// Copyright 2017 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graph
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// IteratorProfile is the actual cost of running an iterator.
+type IteratorProfile struct {
+	Next     int64         // number of Next calls
+	Contains int64         // number of Contains calls
+	Time     time.Duration // wall time spent in Next and Contains, including subiterators
+}
+
+// Explanation describes an optimized iterator tree with estimated costs of each iterator,
+// and with actual costs if the query was profiled.
+type Explanation struct {
+	UID     uint64
+	Type    Type
+	Name    string           `json:",omitempty"`
+	Tags    []string         `json:",omitempty"`
+	Stats   IteratorStats    // estimated before running the query
+	Profile *IteratorProfile `json:",omitempty"`
+	SubIts  []Explanation    `json:",omitempty"`
+}
+
+// Explainer collects iterator trees run by queries with its context (see WithExplainer).
+//
+// Without profiling, iterators are only described and not run, so queries return no results.
+// With profiling, queries run as usual and the number of calls and time spent in each iterator
+// are recorded for iterators that report calls with NextLogIn/NextLogOut and ContainsLogIn/ContainsLogOut,
+// as long as they are run with the iteration context of the explainer (see IterationContext).
+type Explainer struct {
+	profile bool
+
+	mu       sync.Mutex
+	trees    []Explanation
+	profiles map[uint64]*iteratorProfile // by iterator UID
+}
+
+// NewExplainer creates a new explainer. If profile is set, queries are run and profiled.
+func NewExplainer(profile bool) *Explainer {
+	return &Explainer{profile: profile}
+}
+
+type explainerKey struct{}
+
+// WithExplainer returns a context that makes iterators run with it to be collected by the explainer.
+func WithExplainer(ctx context.Context, e *Explainer) context.Context {
+	return context.WithValue(ctx, explainerKey{}, e)
+}
+
+// ExplainerFromContext returns an explainer set with WithExplainer, or nil.
+func ExplainerFromContext(ctx context.Context) *Explainer {
+	if ctx == nil {
+		return nil
+	}
+	e, _ := ctx.Value(explainerKey{}).(*Explainer)
+	return e
+}
+
+// Profiling checks if the explainer runs and profiles queries.
+func (e *Explainer) Profiling() bool {
+	return e.profile
+}
+
+// Track adds an optimized iterator tree to the explanation. It returns false if the iterator should not be run.
+func (e *Explainer) Track(it Iterator) bool {
+	var uids []uint64
+	tree := describeStats(it, &uids)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.trees = append(e.trees, tree)
+	if e.profile {
+		if e.profiles == nil {
+			e.profiles = make(map[uint64]*iteratorProfile, len(uids))
+		}
+		for _, uid := range uids {
+			if e.profiles[uid] == nil {
+				e.profiles[uid] = &iteratorProfile{}
+			}
+		}
+	}
+	return e.profile
+}
+
+// IterationContext returns a context to run tracked iterators with. If the explainer is profiling,
+// calls of iterators are only recorded if they are run with this context.
+func (e *Explainer) IterationContext() *IterationContext {
+	c := NewIterationContext()
+	if e.profile {
+		c.explainer = e
+	}
+	return c
+}
+
+// Explain returns descriptions of all tracked iterator trees.
+func (e *Explainer) Explain() []Explanation {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	out := make([]Explanation, 0, len(e.trees))
+	for _, t := range e.trees {
+		out = append(out, e.withProfile(t))
+	}
+	return out
+}
+
+func (e *Explainer) withProfile(t Explanation) Explanation {
+	if e.profiles != nil {
+		if p := e.profiles[t.UID]; p != nil {
+			prof := p.IteratorProfile
+			t.Profile = &prof
+		} else {
+			t.Profile = &IteratorProfile{}
+		}
+	}
+	if len(t.SubIts) != 0 {
+		subs := make([]Explanation, 0, len(t.SubIts))
+		for _, sub := range t.SubIts {
+			subs = append(subs, e.withProfile(sub))
+		}
+		t.SubIts = subs
+	}
+	return t
+}
+
+func describeStats(it Iterator, uids *[]uint64) Explanation {
+	d := it.Describe()
+	out := Explanation{
+		UID:   it.UID(),
+		Type:  it.Type(),
+		Name:  d.Name,
+		Tags:  d.Tags,
+		Stats: it.Stats(),
+	}
+	*uids = append(*uids, out.UID)
+	for _, sub := range it.SubIterators() {
+		if sub != nil {
+			out.SubIts = append(out.SubIts, describeStats(sub, uids))
+		}
+	}
+	return out
+}
+
+// WriteExplanation renders iterator trees as indented text.
+func WriteExplanation(w io.Writer, trees []Explanation) error {
+	for _, t := range trees {
+		if err := writeExplanation(w, t, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FormatExplanation renders iterator trees as indented text.
+func FormatExplanation(trees []Explanation) string {
+	buf := bytes.NewBuffer(nil)
+	WriteExplanation(buf, trees)
+	return buf.String()
+}
+
+func writeExplanation(w io.Writer, t Explanation, indent string) error {
+	line := fmt.Sprintf("%s%s #%d", indent, t.Type, t.UID)
+	if t.Name != "" {
+		line += " " + t.Name
+	}
+	if len(t.Tags) != 0 {
+		line += " [" + strings.Join(t.Tags, ", ") + "]"
+	}
+	size := "~"
+	if t.Stats.ExactSize {
+		size = ""
+	}
+	line += fmt.Sprintf(" (size: %s%d, next cost: %d, contains cost: %d)", size, t.Stats.Size, t.Stats.NextCost, t.Stats.ContainsCost)
+	if p := t.Profile; p != nil {
+		line += fmt.Sprintf(" next: %d, contains: %d, time: %v", p.Next, p.Contains, p.Time)
+	}
+	if _, err := fmt.Fprintln(w, line); err != nil {
+		return err
+	}
+	for _, sub := range t.SubIts {
+		if err := writeExplanation(w, sub, indent+"  "); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Iterators are profiled by UID, which are unique across all iterators. Profiles are kept by the explainer
+// of the query, so only iterations of the same profiled query contend for its lock.
+
+type iteratorProfile struct {
+	IteratorProfile
+	depth int // number of nested calls
+	start time.Time
+}
+
+func profileIn(c *IterationContext, it Iterator) {
+	if c == nil || c.explainer == nil {
+		return
+	}
+	e := c.explainer
+	uid := it.UID()
+	e.mu.Lock()
+	if p := e.profiles[uid]; p != nil {
+		if p.depth == 0 {
+			p.start = time.Now()
+		}
+		p.depth++
+	}
+	e.mu.Unlock()
+}
+
+func profileOut(c *IterationContext, it Iterator, contains bool) {
+	if c == nil || c.explainer == nil {
+		return
+	}
+	e := c.explainer
+	uid := it.UID()
+	e.mu.Lock()
+	if p := e.profiles[uid]; p != nil {
+		if contains {
+			p.Contains++
+		} else {
+			p.Next++
+		}
+		if p.depth > 0 {
+			p.depth--
+			if p.depth == 0 {
+				p.Time += time.Since(p.start)
+			}
+		}
+	}
+	e.mu.Unlock()
+}
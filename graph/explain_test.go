@@ -0,0 +1,78 @@
+// Copyright 2017 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graph_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+
+	. "github.com/codelingo/cayley/graph"
+	"github.com/codelingo/cayley/graph/memstore"
+	"github.com/codelingo/cayley/graph/path"
+	"github.com/codelingo/cayley/quad"
+	"github.com/codelingo/cayley/writer"
+)
+
+func TestExplain(t *testing.T) {
+	qs := memstore.New()
+	w, err := writer.NewSingleReplication(qs, nil)
+	require.NoError(t, err)
+	require.NoError(t, w.AddQuadSet([]quad.Quad{
+		quad.MakeIRI("alice", "follows", "bob", ""),
+		quad.MakeIRI("alice", "follows", "charlie", ""),
+		quad.MakeIRI("bob", "follows", "charlie", ""),
+	}))
+	p := path.StartPath(qs, quad.IRI("alice")).Out(quad.IRI("follows"))
+
+	e := NewExplainer(false)
+	ctx := WithExplainer(context.TODO(), e)
+	vals, err := Iterate(ctx, p.BuildIterator()).On(qs).All()
+	require.NoError(t, err)
+	require.Empty(t, vals, "iterator should not run without profiling")
+	trees := e.Explain()
+	require.Len(t, trees, 1)
+	require.Equal(t, HasA, trees[0].Type)
+	require.Nil(t, trees[0].Profile)
+	require.NotEmpty(t, trees[0].SubIts)
+	require.True(t, strings.HasPrefix(FormatExplanation(trees), "hasa #"))
+
+	e = NewExplainer(true)
+	ctx = WithExplainer(context.TODO(), e)
+	vals, err = Iterate(ctx, p.BuildIterator()).On(qs).All()
+	require.NoError(t, err)
+	require.Len(t, vals, 2)
+	trees = e.Explain()
+	require.Len(t, trees, 1)
+	root := trees[0]
+	require.NotNil(t, root.Profile)
+	require.Equal(t, int64(3), root.Profile.Next)
+	require.True(t, root.Profile.Time > 0)
+	var leafCalls int64
+	var walk func(Explanation)
+	walk = func(t Explanation) {
+		if len(t.SubIts) == 0 {
+			leafCalls += t.Profile.Next + t.Profile.Contains
+		}
+		for _, sub := range t.SubIts {
+			walk(sub)
+		}
+	}
+	walk(root)
+	require.True(t, leafCalls > 0, "calls of leaf iterators were not profiled")
+	require.Contains(t, FormatExplanation(trees), "next: 3")
+}
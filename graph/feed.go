@@ -0,0 +1,281 @@
+// Copyright 2017 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graph
+
+// Change feed of the quad store.
+//
+// Quad writers publish all deltas after they were successfully applied to the
+// store, and subscribers receive them in the same batches. A feed is created
+// with the first subscription to the quad store and is kept until the store is
+// closed. It keeps a number of recent batches, so subscribers can reconnect and
+// resume from the last delta they have seen.
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/codelingo/cayley/quad"
+)
+
+var (
+	// ErrFeedExpired is returned when subscription cannot be resumed, because
+	// deltas after the requested ID are no longer kept in the change feed.
+	ErrFeedExpired = errors.New("feed: requested deltas are no longer available")
+	// ErrFeedUnordered is returned on an attempt to resume a subscription for a quad store with non-sequential keys.
+	ErrFeedUnordered = errors.New("feed: cannot resume for a quad store without sequential keys")
+	// ErrFeedDropped is returned by the cancel function of a subscription that was dropped for falling behind the feed.
+	ErrFeedDropped = errors.New("feed: subscriber fell behind the change feed")
+	// ErrFeedClosed is returned by the cancel function of a subscription that ended because the quad store was closed.
+	ErrFeedClosed = errors.New("feed: quad store was closed")
+)
+
+const (
+	// FeedBufferSize is a number of batches buffered for each subscriber.
+	// Subscribers that fall behind further are unsubscribed and their channels are closed.
+	FeedBufferSize = 256
+	// FeedHistorySize is a number of recent batches kept by the feed to resume subscriptions.
+	FeedHistorySize = 1024
+)
+
+// DeltaFilter selects deltas from the change feed. Nil fields match any value.
+type DeltaFilter struct {
+	Subject   quad.Value
+	Predicate quad.Value
+	Label     quad.Value
+}
+
+func matchValue(f, v quad.Value) bool {
+	return f == nil || (v != nil && f.String() == v.String())
+}
+
+// Match checks if a delta passes the filter.
+func (f DeltaFilter) Match(d *Delta) bool {
+	return matchValue(f.Subject, d.Quad.Subject) &&
+		matchValue(f.Predicate, d.Quad.Predicate) &&
+		matchValue(f.Label, d.Quad.Label)
+}
+
+// FeedView is implemented by views of a quad store that share the change feed of the underlying store.
+type FeedView interface {
+	// FeedSource returns the quad store whose change feed is shared by the view.
+	FeedSource() QuadStore
+	// InView checks if a delta is visible through the view.
+	InView(d *Delta) bool
+}
+
+type subscriber struct {
+	filter DeltaFilter
+	views  []FeedView
+	c      chan []Delta
+	err    error // reason the channel was closed by the feed
+}
+
+func (s *subscriber) match(d *Delta) bool {
+	if !s.filter.Match(d) {
+		return false
+	}
+	for _, v := range s.views {
+		if !v.InView(d) {
+			return false
+		}
+	}
+	return true
+}
+
+// matching returns deltas matching the subscription, skipping all deltas up to a given ID if it's not negative.
+func (s *subscriber) matching(deltas []Delta, since int64) []Delta {
+	var out []Delta
+	for i := range deltas {
+		d := &deltas[i]
+		if since >= 0 && d.ID.Int() <= since {
+			continue
+		}
+		if s.match(d) {
+			out = append(out, Delta{ID: d.ID.Copy(), Quad: d.Quad, Action: d.Action, Timestamp: d.Timestamp})
+		}
+	}
+	return out
+}
+
+type feed struct {
+	mu     sync.Mutex
+	subs   map[*subscriber]struct{}
+	recent [][]Delta // recent batches, oldest first
+	start  int64     // all deltas after this ID are in recent
+}
+
+var feeds = struct {
+	sync.Mutex
+	m map[QuadStore]*feed
+}{m: make(map[QuadStore]*feed)}
+
+// Subscribe returns a channel that receives all deltas applied to the quad store
+// that match the filter, and a function to cancel the subscription.
+// Deltas are delivered in the same batches as they were applied, excluding
+// deltas that do not match the filter.
+//
+// Subscriber must read from the channel promptly; if it falls behind by more
+// than FeedBufferSize batches, the channel is closed. The channel is also closed
+// when the quad store is closed. After that, the cancel function returns
+// ErrFeedDropped or ErrFeedClosed respectively; it returns nil if the subscription
+// was cancelled by the caller.
+func Subscribe(qs QuadStore, f DeltaFilter) (<-chan []Delta, func() error) {
+	c, cancel, _ := subscribe(qs, f, -1)
+	return c, cancel
+}
+
+// SubscribeSince is like Subscribe, but it first sends all deltas with sequential IDs
+// greater than since that are still kept by the feed. It returns ErrFeedExpired if some
+// of the requested deltas are not available.
+func SubscribeSince(qs QuadStore, f DeltaFilter, since int64) (<-chan []Delta, func() error, error) {
+	if since < 0 {
+		since = 0
+	}
+	return subscribe(qs, f, since)
+}
+
+func subscribe(qs QuadStore, f DeltaFilter, since int64) (<-chan []Delta, func() error, error) {
+	s := &subscriber{filter: f, c: make(chan []Delta, FeedBufferSize)}
+	for {
+		v, ok := qs.(FeedView)
+		if !ok {
+			break
+		}
+		s.views = append(s.views, v)
+		qs = v.FeedSource()
+	}
+	feeds.Lock()
+	fd := feeds.m[qs]
+	if fd == nil {
+		h := qs.Horizon()
+		fd = &feed{subs: make(map[*subscriber]struct{}), start: -1}
+		if h.IsSequential() {
+			fd.start = h.Int()
+		}
+		feeds.m[qs] = fd
+	}
+	feeds.Unlock()
+
+	fd.mu.Lock()
+	defer fd.mu.Unlock()
+	if since >= 0 {
+		if fd.start < 0 {
+			return nil, nil, ErrFeedUnordered
+		} else if since < fd.start {
+			return nil, nil, ErrFeedExpired
+		}
+		var backlog [][]Delta
+		for _, b := range fd.recent {
+			if out := s.matching(b, since); len(out) != 0 {
+				backlog = append(backlog, out)
+			}
+		}
+		if len(backlog) > FeedBufferSize {
+			return nil, nil, ErrFeedExpired
+		}
+		for _, b := range backlog {
+			s.c <- b
+		}
+	}
+	fd.subs[s] = struct{}{}
+	cancel := func() error {
+		fd.mu.Lock()
+		defer fd.mu.Unlock()
+		if _, ok := fd.subs[s]; ok {
+			delete(fd.subs, s)
+			close(s.c)
+		}
+		return s.err
+	}
+	return s.c, cancel, nil
+}
+
+// Subscribed checks if the change feed of the quad store has any subscribers.
+func Subscribed(qs QuadStore) bool {
+	feeds.Lock()
+	fd := feeds.m[qs]
+	feeds.Unlock()
+	if fd == nil {
+		return false
+	}
+	fd.mu.Lock()
+	defer fd.mu.Unlock()
+	return len(fd.subs) != 0
+}
+
+// CloseFeed removes the change feed of the quad store with its recent batches, and closes channels of all its subscribers.
+// It is called when the quad store is closed.
+func CloseFeed(qs QuadStore) {
+	feeds.Lock()
+	fd := feeds.m[qs]
+	delete(feeds.m, qs)
+	feeds.Unlock()
+	if fd == nil {
+		return
+	}
+	fd.mu.Lock()
+	defer fd.mu.Unlock()
+	for s := range fd.subs {
+		delete(fd.subs, s)
+		s.err = ErrFeedClosed
+		close(s.c)
+	}
+	fd.recent = nil
+}
+
+// Publish sends deltas applied to the quad store to all subscribers of its change feed.
+// It is called by quad writers after a successful commit.
+func Publish(qs QuadStore, deltas []Delta) {
+	if len(deltas) == 0 {
+		return
+	}
+	feeds.Lock()
+	fd := feeds.m[qs]
+	feeds.Unlock()
+	if fd == nil {
+		return
+	}
+	// deltas may be reused by the caller
+	batch := make([]Delta, len(deltas))
+	for i := range deltas {
+		d := &deltas[i]
+		batch[i] = Delta{ID: d.ID.Copy(), Quad: d.Quad, Action: d.Action, Timestamp: d.Timestamp}
+	}
+
+	fd.mu.Lock()
+	defer fd.mu.Unlock()
+	if batch[0].ID.IsSequential() {
+		fd.recent = append(fd.recent, batch)
+		if n := len(fd.recent) - FeedHistorySize; n > 0 {
+			old := fd.recent[n-1]
+			fd.start = old[len(old)-1].ID.Int()
+			fd.recent = append([][]Delta{}, fd.recent[n:]...)
+		}
+	}
+	for s := range fd.subs {
+		out := s.matching(batch, -1)
+		if len(out) == 0 {
+			continue
+		}
+		select {
+		case s.c <- out:
+		default:
+			// subscriber is too slow; it can resume from the last delta it has seen
+			delete(fd.subs, s)
+			s.err = ErrFeedDropped
+			close(s.c)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package graph
+
+import (
+	"testing"
+
+	"github.com/codelingo/cayley/quad"
+	"github.com/stretchr/testify/require"
+)
+
+type feedStore struct {
+	QuadStore
+	horizon int64
+}
+
+func (qs *feedStore) Horizon() PrimaryKey { return NewSequentialKey(qs.horizon) }
+
+func (qs *feedStore) apply(quads ...quad.Quad) {
+	deltas := make([]Delta, len(quads))
+	for i, q := range quads {
+		qs.horizon++
+		deltas[i].ID = NewSequentialKey(qs.horizon)
+		deltas[i].Quad = q
+		deltas[i].Action = Add
+	}
+	Publish(qs, deltas)
+}
+
+func feedQuads(batch []Delta) []quad.Quad {
+	var out []quad.Quad
+	for i := range batch {
+		out = append(out, batch[i].Quad)
+	}
+	return out
+}
+
+func TestFeedSubscribe(t *testing.T) {
+	qs := &feedStore{}
+	q1 := quad.MakeIRI("a", "follows", "b", "")
+	q2 := quad.MakeIRI("b", "follows", "c", "")
+	q3 := quad.MakeIRI("a", "status", "cool", "")
+
+	// nobody is listening yet
+	qs.apply(q1)
+
+	all, cancelAll := Subscribe(qs, DeltaFilter{})
+	defer cancelAll()
+	status, cancelStatus := Subscribe(qs, DeltaFilter{Predicate: quad.IRI("status")})
+
+	qs.apply(q2, q3)
+	require.Equal(t, []quad.Quad{q2, q3}, feedQuads(<-all))
+	batch := <-status
+	require.Equal(t, []quad.Quad{q3}, feedQuads(batch))
+	require.Equal(t, int64(3), batch[0].ID.Int())
+
+	cancelStatus()
+	_, ok := <-status
+	require.False(t, ok, "channel must be closed after cancel")
+	cancelStatus()
+
+	qs.apply(q1)
+	require.Equal(t, []quad.Quad{q1}, feedQuads(<-all))
+}
+
+func TestFeedSubscribeSince(t *testing.T) {
+	qs := &feedStore{horizon: 10}
+	q1 := quad.MakeIRI("a", "follows", "b", "")
+	q2 := quad.MakeIRI("b", "follows", "c", "")
+	q3 := quad.MakeIRI("c", "follows", "d", "")
+
+	// create the feed
+	_, cancel := Subscribe(qs, DeltaFilter{})
+	cancel()
+
+	qs.apply(q1, q2)
+	qs.apply(q3)
+
+	c, cancel, err := SubscribeSince(qs, DeltaFilter{}, 11)
+	require.NoError(t, err)
+	require.Equal(t, []quad.Quad{q2}, feedQuads(<-c))
+	require.Equal(t, []quad.Quad{q3}, feedQuads(<-c))
+	cancel()
+
+	_, _, err = SubscribeSince(qs, DeltaFilter{}, 5)
+	require.Equal(t, ErrFeedExpired, err)
+
+	for i := 0; i < FeedHistorySize; i++ {
+		qs.apply(q1)
+	}
+	_, _, err = SubscribeSince(qs, DeltaFilter{}, 11)
+	require.Equal(t, ErrFeedExpired, err)
+}
+
+func TestFeedSlowSubscriber(t *testing.T) {
+	qs := &feedStore{}
+	c, cancel := Subscribe(qs, DeltaFilter{})
+	defer cancel()
+	q := quad.MakeIRI("a", "follows", "b", "")
+	for i := 0; i < FeedBufferSize+1; i++ {
+		qs.apply(q)
+	}
+	n := 0
+	for range c {
+		n++
+	}
+	require.Equal(t, FeedBufferSize, n)
+	require.Equal(t, ErrFeedDropped, cancel())
+}
+
+func TestFeedClose(t *testing.T) {
+	qs := &feedStore{}
+	c, cancel := Subscribe(qs, DeltaFilter{})
+	defer cancel()
+	qs.apply(quad.MakeIRI("a", "follows", "b", ""))
+	require.True(t, Subscribed(qs))
+
+	CloseFeed(qs)
+	require.Len(t, <-c, 1)
+	_, ok := <-c
+	require.False(t, ok, "channel must be closed with the feed")
+	require.False(t, Subscribed(qs))
+	feeds.Lock()
+	_, ok = feeds.m[qs]
+	feeds.Unlock()
+	require.False(t, ok, "feed must be removed")
+	require.Equal(t, ErrFeedClosed, cancel())
+}
@@ -0,0 +1,107 @@
+// Copyright 2017 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package fulltext implements full-text search over string literals stored in a quad store.
+//
+// Indexes are pluggable and are registered by name. The default "memory" index is an
+// inverted index kept in memory, which ranks results with BM25. The index is kept in sync
+// with the store by a quad store wrapper (see New), and is queried by the search iterator.
+package fulltext
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/codelingo/cayley/graph"
+	"github.com/codelingo/cayley/quad"
+)
+
+// DefaultIndex is the name of the index used when none is specified.
+const DefaultIndex = "memory"
+
+// Match is a single search result.
+type Match struct {
+	Value quad.Value
+	Score float64 // relevance of the value; higher is better
+}
+
+// Index is a full-text index of string literals.
+type Index interface {
+	// Add indexes a text of given values. Values that are already in the index are ignored.
+	Add(vals []quad.Value) error
+	// Remove removes given values from the index.
+	Remove(vals []quad.Value) error
+	// Search returns all values that contain every term of the query, ordered by relevance.
+	Search(query string) ([]Match, error)
+	// Close releases resources held by the index.
+	Close() error
+}
+
+// NewIndexFunc creates an index for a given quad store. It must index all values already stored in it.
+type NewIndexFunc func(qs graph.QuadStore, opts graph.Options) (Index, error)
+
+var indexes = make(map[string]NewIndexFunc)
+
+// RegisterIndex adds a new index type to the list of available indexes.
+func RegisterIndex(name string, fnc NewIndexFunc) {
+	if _, found := indexes[name]; found {
+		panic(fmt.Sprintf("already registered full-text index %q", name))
+	}
+	indexes[name] = fnc
+}
+
+// NewIndex creates an index of a given type for the quad store.
+func NewIndex(name string, qs graph.QuadStore, opts graph.Options) (Index, error) {
+	if name == "" {
+		name = DefaultIndex
+	}
+	fnc, ok := indexes[name]
+	if !ok {
+		return nil, fmt.Errorf("unsupported full-text index: %q", name)
+	}
+	return fnc(qs, opts)
+}
+
+// Indexes returns the names of all registered indexes.
+func Indexes() []string {
+	out := make([]string, 0, len(indexes))
+	for name := range indexes {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Text returns the text of a value that should be indexed.
+// Only string literals are indexed; IRIs, blank nodes and other values are not.
+func Text(v quad.Value) (string, bool) {
+	switch v := v.(type) {
+	case quad.String:
+		return string(v), true
+	case quad.TypedString:
+		return string(v.Value), true
+	case quad.LangString:
+		return string(v.Value), true
+	}
+	return "", false
+}
+
+// Tokenize splits a text into lowercase terms. Any character that is not a letter or a digit separates terms.
+func Tokenize(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
@@ -0,0 +1,232 @@
+// Copyright 2017 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fulltext
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/codelingo/cayley/graph"
+	_ "github.com/codelingo/cayley/graph/bolt"
+	"github.com/codelingo/cayley/graph/memstore"
+	"github.com/codelingo/cayley/quad"
+	"github.com/codelingo/cayley/writer"
+)
+
+var testQuads = []quad.Quad{
+	quad.MakeIRI("a", "title", "", ""),
+	quad.MakeIRI("b", "title", "", ""),
+	quad.MakeIRI("c", "title", "", ""),
+	quad.MakeIRI("d", "title", "", ""),
+}
+
+func init() {
+	testQuads[0].Object = quad.String("The quick brown fox")
+	testQuads[1].Object = quad.LangString{Value: "A quick, quick fox jumps", Lang: "en"}
+	testQuads[2].Object = quad.String("Lazy dogs")
+	testQuads[3].Object = quad.IRI("quick")
+}
+
+func matchValues(m []Match) []quad.Value {
+	var out []quad.Value
+	for _, v := range m {
+		out = append(out, v.Value)
+	}
+	return out
+}
+
+func TestTokenize(t *testing.T) {
+	got := Tokenize("Hello, World! foo_bar 42x")
+	expect := []string{"hello", "world", "foo", "bar", "42x"}
+	if !reflect.DeepEqual(got, expect) {
+		t.Errorf("unexpected terms, got:%q expect:%q", got, expect)
+	}
+}
+
+func TestMemoryIndex(t *testing.T) {
+	idx := NewMemoryIndex()
+	var vals []quad.Value
+	for _, q := range testQuads {
+		vals = append(vals, q.Subject, q.Object)
+	}
+	idx.Add(vals)
+	idx.Add(vals[:2])
+	if n := idx.Len(); n != 3 {
+		t.Fatalf("unexpected number of indexed values, got:%d expect:3", n)
+	}
+
+	m, err := idx.Search("QUICK")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// higher term frequency wins
+	expect := []quad.Value{testQuads[1].Object, testQuads[0].Object}
+	if got := matchValues(m); !reflect.DeepEqual(got, expect) {
+		t.Errorf("unexpected results, got:%v expect:%v", got, expect)
+	} else if m[0].Score <= m[1].Score {
+		t.Errorf("unexpected scores: %v", m)
+	}
+
+	m, _ = idx.Search("fox brown")
+	if got := matchValues(m); !reflect.DeepEqual(got, expect[1:]) {
+		t.Errorf("unexpected results, got:%v expect:%v", got, expect[1:])
+	}
+	if m, _ = idx.Search("fox cat"); len(m) != 0 {
+		t.Errorf("unexpected results: %v", m)
+	}
+	if m, _ = idx.Search(" ,"); len(m) != 0 {
+		t.Errorf("unexpected results: %v", m)
+	}
+
+	idx.Remove([]quad.Value{testQuads[1].Object})
+	m, _ = idx.Search("quick")
+	if got := matchValues(m); !reflect.DeepEqual(got, expect[1:]) {
+		t.Errorf("unexpected results after remove, got:%v expect:%v", got, expect[1:])
+	}
+}
+
+func search(t testing.TB, qs graph.QuadStore, query string, tag string) ([]quad.Value, []quad.Value) {
+	it := NewIterator(qs, query, tag)
+	defer it.Close()
+	var vals, scores []quad.Value
+	for it.Next(nil) {
+		vals = append(vals, qs.NameOf(it.Result()))
+		tags := make(map[string]graph.Value)
+		it.TagResults(tags)
+		scores = append(scores, qs.NameOf(tags[tag]))
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return vals, scores
+}
+
+func newWriter(t testing.TB, qs graph.QuadStore, quads ...quad.Quad) graph.QuadWriter {
+	w, err := writer.NewSingleReplication(qs, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = w.AddQuadSet(quads); err != nil {
+		t.Fatal(err)
+	}
+	return w
+}
+
+func TestQuadStore(t *testing.T) {
+	mem := memstore.New(testQuads[:2]...)
+
+	idx, err := NewIndex("", mem, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	qs := New(mem, idx)
+	defer qs.Close()
+
+	w := newWriter(t, qs, testQuads[2:]...)
+	// the same value is used by another quad
+	if err = w.AddQuad(quad.Make(quad.IRI("e"), quad.IRI("name"), testQuads[0].Object, nil)); err != nil {
+		t.Fatal(err)
+	}
+	vals, scores := search(t, qs, "quick", "score")
+	expect := []quad.Value{testQuads[1].Object, testQuads[0].Object}
+	if !reflect.DeepEqual(vals, expect) {
+		t.Errorf("unexpected results, got:%v expect:%v", vals, expect)
+	}
+	for _, s := range scores {
+		if f, ok := s.(quad.Float); !ok || f <= 0 {
+			t.Errorf("unexpected score: %#v", s)
+		}
+	}
+	if vals, _ = search(t, qs, "dogs", "score"); len(vals) != 1 {
+		t.Errorf("unexpected results: %v", vals)
+	}
+
+	if err = w.RemoveQuad(testQuads[0]); err != nil {
+		t.Fatal(err)
+	}
+	if err = w.RemoveQuad(testQuads[1]); err != nil {
+		t.Fatal(err)
+	}
+	vals, _ = search(t, qs, "quick", "score")
+	if !reflect.DeepEqual(vals, expect[1:]) {
+		t.Errorf("unexpected results after remove, got:%v expect:%v", vals, expect[1:])
+	}
+	if n := idx.(*MemoryIndex).Len(); n != 2 {
+		t.Errorf("unexpected number of indexed values, got:%d expect:2", n)
+	}
+
+	it := NewIterator(qs, "brown")
+	if !it.Contains(nil, qs.ValueOf(testQuads[0].Object)) {
+		t.Error("expected value to match")
+	} else if it.Contains(nil, qs.ValueOf(testQuads[2].Object)) {
+		t.Error("unexpected match")
+	}
+}
+
+func TestIteratorNoIndex(t *testing.T) {
+	qs := memstore.New(testQuads...)
+
+	// shorter value wins
+	vals, _ := search(t, qs, "fox", "score")
+	expect := []quad.Value{testQuads[0].Object, testQuads[1].Object}
+	if !reflect.DeepEqual(vals, expect) {
+		t.Errorf("unexpected results, got:%v expect:%v", vals, expect)
+	}
+}
+
+func TestAsOf(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cayley_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	dbpath := filepath.Join(dir, "db")
+	if err = graph.InitQuadStore("bolt", dbpath, nil); err != nil {
+		t.Fatal(err)
+	}
+	bs, err := graph.NewQuadStore("bolt", dbpath, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idx, err := NewIndex("", bs, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	qs := New(bs, idx)
+	defer qs.Close()
+
+	w := newWriter(t, qs, testQuads[:2]...)
+	past := qs.Horizon()
+	w.AddQuad(testQuads[2])
+
+	view, err := graph.AsOf(qs, past.Int())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vals, _ := search(t, view, "dogs", "score"); len(vals) != 0 {
+		t.Errorf("unexpected results in the view: %v", vals)
+	}
+	if vals, _ := search(t, view, "fox", "score"); len(vals) != 2 {
+		t.Errorf("unexpected results in the view: %v", vals)
+	}
+	// the index is shared and must stay open
+	view.Close()
+	if vals, _ := search(t, qs, "dogs", "score"); len(vals) != 1 {
+		t.Errorf("unexpected results: %v", vals)
+	}
+}
@@ -0,0 +1,242 @@
+// Copyright 2017 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fulltext
+
+import (
+	"fmt"
+
+	"github.com/codelingo/cayley/graph"
+	"github.com/codelingo/cayley/graph/iterator"
+	"github.com/codelingo/cayley/quad"
+)
+
+var fulltextType graph.Type
+
+func init() {
+	fulltextType = graph.RegisterIterator("fulltext")
+}
+
+// Type returns the type of the search iterator.
+func Type() graph.Type { return fulltextType }
+
+// searcherOf finds a full-text index of the quad store or one of the stores it wraps.
+func searcherOf(qs graph.QuadStore) (Searcher, bool) {
+	for {
+		if s, ok := qs.(Searcher); ok {
+			return s, true
+		}
+		w, ok := qs.(interface {
+			Unwrap() graph.QuadStore
+		})
+		if !ok {
+			return nil, false
+		}
+		qs = w.Unwrap()
+	}
+}
+
+// Score is a relevance score of a search result. It is returned in score tags.
+type Score float64
+
+var _ graph.PreFetchedValue = Score(0)
+
+func (s Score) NameOf() quad.Value {
+	return quad.Float(s)
+}
+
+// Iterator iterates over values that match a full-text query, most relevant first.
+//
+// If the quad store has no full-text index, the iterator builds a temporary in-memory
+// index of the whole store, which is only practical for small graphs.
+type Iterator struct {
+	uid       uint64
+	tags      graph.Tagger
+	qs        graph.QuadStore
+	query     string
+	scoreTags []string
+
+	matches []Match
+	scores  map[quad.Value]float64
+	index   int
+	result  graph.Value
+	score   float64
+	err     error
+}
+
+// NewIterator creates an iterator of values that match the query.
+// Relevance scores of results are stored in score tags.
+func NewIterator(qs graph.QuadStore, query string, scoreTags ...string) *Iterator {
+	return &Iterator{
+		uid:       iterator.NextUID(),
+		qs:        qs,
+		query:     query,
+		scoreTags: scoreTags,
+	}
+}
+
+// Query returns the full-text query of the iterator.
+func (it *Iterator) Query() string {
+	return it.query
+}
+
+// ScoreTags returns the tags that receive relevance scores.
+func (it *Iterator) ScoreTags() []string {
+	return it.scoreTags
+}
+
+func (it *Iterator) UID() uint64 {
+	return it.uid
+}
+
+// search runs the query on first use.
+func (it *Iterator) search() bool {
+	if it.scores != nil {
+		return true
+	} else if it.err != nil {
+		return false
+	}
+	s, ok := searcherOf(it.qs)
+	if !ok {
+		s, it.err = NewMemoryIndexFor(it.qs)
+		if it.err != nil {
+			return false
+		}
+	}
+	it.matches, it.err = s.Search(it.query)
+	if it.err != nil {
+		return false
+	}
+	it.scores = make(map[quad.Value]float64, len(it.matches))
+	for _, m := range it.matches {
+		it.scores[m.Value] = m.Score
+	}
+	return true
+}
+
+func (it *Iterator) Reset() {
+	it.index = 0
+	it.result = nil
+}
+
+func (it *Iterator) Close() error {
+	return nil
+}
+
+func (it *Iterator) Tagger() *graph.Tagger {
+	return &it.tags
+}
+
+func (it *Iterator) TagResults(dst map[string]graph.Value) {
+	for _, tag := range it.tags.Tags() {
+		dst[tag] = it.Result()
+	}
+
+	for tag, value := range it.tags.Fixed() {
+		dst[tag] = value
+	}
+
+	for _, tag := range it.scoreTags {
+		dst[tag] = Score(it.score)
+	}
+}
+
+func (it *Iterator) Clone() graph.Iterator {
+	out := NewIterator(it.qs, it.query, it.scoreTags...)
+	out.tags.CopyFrom(it)
+	return out
+}
+
+func (it *Iterator) Describe() graph.Description {
+	return graph.Description{
+		UID:  it.UID(),
+		Name: fmt.Sprintf("%q", it.query),
+		Type: it.Type(),
+		Tags: it.tags.Tags(),
+	}
+}
+
+func (it *Iterator) Type() graph.Type { return fulltextType }
+
+func (it *Iterator) Next(ctx *graph.IterationContext) bool {
+	graph.NextLogIn(ctx, it)
+	if !it.search() {
+		return graph.NextLogOut(ctx, it, false)
+	}
+	for it.index < len(it.matches) {
+		m := it.matches[it.index]
+		it.index++
+		v := it.qs.ValueOf(m.Value)
+		if v == nil {
+			continue
+		}
+		it.result, it.score = v, m.Score
+		return graph.NextLogOut(ctx, it, true)
+	}
+	return graph.NextLogOut(ctx, it, false)
+}
+
+func (it *Iterator) Contains(ctx *graph.IterationContext, v graph.Value) bool {
+	graph.ContainsLogIn(ctx, it, v)
+	if !it.search() {
+		return graph.ContainsLogOut(ctx, it, v, false)
+	}
+	score, ok := it.scores[it.qs.NameOf(v)]
+	if !ok {
+		return graph.ContainsLogOut(ctx, it, v, false)
+	}
+	it.result, it.score = v, score
+	return graph.ContainsLogOut(ctx, it, v, true)
+}
+
+func (it *Iterator) Err() error {
+	return it.err
+}
+
+func (it *Iterator) Result() graph.Value {
+	return it.result
+}
+
+func (it *Iterator) NextPath(ctx *graph.IterationContext) bool {
+	return false
+}
+
+func (it *Iterator) SubIterators() []graph.Iterator {
+	return nil
+}
+
+// Optimize allows quad stores with a native full-text search to replace the iterator.
+func (it *Iterator) Optimize() (graph.Iterator, bool) {
+	return it.qs.OptimizeIterator(it)
+}
+
+// Size runs the query and returns the exact number of results.
+func (it *Iterator) Size() (int64, bool) {
+	if !it.search() {
+		return 0, true
+	}
+	return int64(len(it.matches)), true
+}
+
+func (it *Iterator) Stats() graph.IteratorStats {
+	s, exact := it.Size()
+	return graph.IteratorStats{
+		ContainsCost: 1,
+		NextCost:     1,
+		Size:         s,
+		ExactSize:    exact,
+	}
+}
+
+var _ graph.Iterator = &Iterator{}
@@ -0,0 +1,209 @@
+// Copyright 2017 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fulltext
+
+import (
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/codelingo/cayley/graph"
+	"github.com/codelingo/cayley/quad"
+)
+
+func init() {
+	RegisterIndex(DefaultIndex, func(qs graph.QuadStore, _ graph.Options) (Index, error) {
+		return NewMemoryIndexFor(qs)
+	})
+}
+
+// BM25 parameters.
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+var _ Index = (*MemoryIndex)(nil)
+
+// MemoryIndex is an inverted index of string literals kept in memory.
+type MemoryIndex struct {
+	mu       sync.RWMutex
+	postings map[string]map[quad.Value]int // term -> value -> term frequency
+	lengths  map[quad.Value]int            // number of terms in each value
+	total    int                           // sum of all lengths
+}
+
+// NewMemoryIndex creates an empty in-memory index.
+func NewMemoryIndex() *MemoryIndex {
+	return &MemoryIndex{
+		postings: make(map[string]map[quad.Value]int),
+		lengths:  make(map[quad.Value]int),
+	}
+}
+
+// NewMemoryIndexFor creates an in-memory index of all values in the quad store.
+func NewMemoryIndexFor(qs graph.QuadStore) (*MemoryIndex, error) {
+	idx := NewMemoryIndex()
+	it := qs.QuadsAllIterator()
+	defer it.Close()
+	var vals []quad.Value
+	for it.Next(nil) {
+		q := qs.Quad(it.Result())
+		for _, d := range quad.Directions {
+			if v := q.Get(d); v != nil {
+				vals = append(vals, v)
+			}
+		}
+	}
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+	idx.Add(vals)
+	return idx, nil
+}
+
+func (idx *MemoryIndex) Add(vals []quad.Value) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	for _, v := range vals {
+		text, ok := Text(v)
+		if !ok {
+			continue
+		} else if _, ok = idx.lengths[v]; ok {
+			continue
+		}
+		terms := Tokenize(text)
+		for _, t := range terms {
+			p := idx.postings[t]
+			if p == nil {
+				p = make(map[quad.Value]int)
+				idx.postings[t] = p
+			}
+			p[v]++
+		}
+		idx.lengths[v] = len(terms)
+		idx.total += len(terms)
+	}
+	return nil
+}
+
+func (idx *MemoryIndex) Remove(vals []quad.Value) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	for _, v := range vals {
+		n, ok := idx.lengths[v]
+		if !ok {
+			continue
+		}
+		text, _ := Text(v)
+		for _, t := range Tokenize(text) {
+			p := idx.postings[t]
+			delete(p, v)
+			if len(p) == 0 {
+				delete(idx.postings, t)
+			}
+		}
+		delete(idx.lengths, v)
+		idx.total -= n
+	}
+	return nil
+}
+
+// Len returns the number of indexed values.
+func (idx *MemoryIndex) Len() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return len(idx.lengths)
+}
+
+func (idx *MemoryIndex) Search(query string) ([]Match, error) {
+	terms := uniqueTerms(Tokenize(query))
+	if len(terms) == 0 {
+		return nil, nil
+	}
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	lists := make([]map[quad.Value]int, 0, len(terms))
+	for _, t := range terms {
+		p := idx.postings[t]
+		if len(p) == 0 {
+			return nil, nil
+		}
+		lists = append(lists, p)
+	}
+	// walk the shortest list and check the others
+	sort.Sort(byLen(lists))
+
+	n := float64(len(idx.lengths))
+	avg := float64(idx.total) / n
+	idf := make([]float64, len(lists))
+	for i, p := range lists {
+		df := float64(len(p))
+		idf[i] = math.Log(1 + (n-df+0.5)/(df+0.5))
+	}
+	var out []Match
+next:
+	for v := range lists[0] {
+		dl := float64(idx.lengths[v])
+		var score float64
+		for i, p := range lists {
+			tf, ok := p[v]
+			if !ok {
+				continue next
+			}
+			f := float64(tf)
+			score += idf[i] * f * (bm25K1 + 1) / (f + bm25K1*(1-bm25B+bm25B*dl/avg))
+		}
+		out = append(out, Match{Value: v, Score: score})
+	}
+	sort.Sort(byScore(out))
+	return out, nil
+}
+
+func (idx *MemoryIndex) Close() error {
+	return nil
+}
+
+func uniqueTerms(terms []string) []string {
+	seen := make(map[string]struct{}, len(terms))
+	out := terms[:0]
+	for _, t := range terms {
+		if _, ok := seen[t]; ok {
+			continue
+		}
+		seen[t] = struct{}{}
+		out = append(out, t)
+	}
+	return out
+}
+
+type byLen []map[quad.Value]int
+
+func (a byLen) Len() int           { return len(a) }
+func (a byLen) Less(i, j int) bool { return len(a[i]) < len(a[j]) }
+func (a byLen) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+
+// byScore orders matches by descending score. Ties are ordered by value to make results stable.
+type byScore []Match
+
+func (a byScore) Len() int { return len(a) }
+func (a byScore) Less(i, j int) bool {
+	if a[i].Score != a[j].Score {
+		return a[i].Score > a[j].Score
+	}
+	return a[i].Value.String() < a[j].Value.String()
+}
+func (a byScore) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
@@ -0,0 +1,79 @@
+// Copyright 2017 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fulltext
+
+import (
+	"github.com/codelingo/cayley/graph"
+	"github.com/codelingo/cayley/graph/valueindex"
+	"github.com/codelingo/cayley/quad"
+)
+
+// Searcher is implemented by quad stores that have a full-text index.
+type Searcher interface {
+	Search(query string) ([]Match, error)
+}
+
+var (
+	_ graph.QuadStore    = (*QuadStore)(nil)
+	_ graph.HistoryStore = (*QuadStore)(nil)
+	_ Searcher           = (*QuadStore)(nil)
+)
+
+// QuadStore wraps any quad store and keeps a full-text index in sync with it.
+type QuadStore struct {
+	valueindex.Wrapper
+	idx Index
+}
+
+func isText(v quad.Value) bool {
+	_, ok := Text(v)
+	return ok
+}
+
+// New wraps a quad store with a full-text index. The index must already contain all values of the store.
+func New(qs graph.QuadStore, idx Index) *QuadStore {
+	return &QuadStore{Wrapper: valueindex.NewWrapper(qs, idx, isText), idx: idx}
+}
+
+// Index returns the full-text index of the store.
+func (qs *QuadStore) Index() Index {
+	return qs.idx
+}
+
+// AsOf returns a read-only view of the underlying store at a given horizon, with the same full-text index.
+// Searches in the view only return values that are in the view.
+func (qs *QuadStore) AsOf(horizon int64) (graph.QuadStore, error) {
+	w, err := qs.View(horizon)
+	if err != nil {
+		return nil, err
+	}
+	return &QuadStore{Wrapper: w, idx: qs.idx}, nil
+}
+
+// Search runs a query against the full-text index.
+func (qs *QuadStore) Search(query string) ([]Match, error) {
+	out, err := qs.idx.Search(query)
+	if err != nil {
+		return nil, err
+	}
+	n := 0
+	for _, m := range out {
+		if qs.Visible(m.Value) {
+			out[n] = m
+			n++
+		}
+	}
+	return out[:n], nil
+}
@@ -142,20 +142,20 @@ func (it *Iterator) Tagger() *graph.Tagger {
 	return &it.tags
 }
 func (it *Iterator) Contains(v graph.Value) bool {
-	graph.ContainsLogIn(it, v)
+	graph.ContainsLogIn(nil, it, v)
 	if it.isAll {
 		// The result needs to be set, so when contains is called, the result can be retrieved
 		it.result = v
-		return graph.ContainsLogOut(it, v, true)
+		return graph.ContainsLogOut(nil, it, v, true)
 	}
 	t := v.(*Token)
 	if t == nil {
 		clog.Errorf("Could not cast to token")
-		return graph.ContainsLogOut(it, v, false)
+		return graph.ContainsLogOut(nil, it, v, false)
 	}
 	if t.Kind == nodeKind {
 		clog.Errorf("Contains does not work with node values")
-		return graph.ContainsLogOut(it, v, false)
+		return graph.ContainsLogOut(nil, it, v, false)
 	}
 	// Contains is for when you want to know that an iterator refers to a quad
 	var offset int
@@ -171,9 +171,9 @@ func (it *Iterator) Contains(v graph.Value) bool {
 	}
 	val := t.Hash[offset : offset+(quad.HashSize*2)]
 	if val == it.hash {
-		return graph.ContainsLogOut(it, v, true)
+		return graph.ContainsLogOut(nil, it, v, true)
 	}
-	return graph.ContainsLogOut(it, v, false)
+	return graph.ContainsLogOut(nil, it, v, false)
 }
 
 func (it *Iterator) TagResults(dst map[string]graph.Value) {
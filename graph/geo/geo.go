@@ -0,0 +1,205 @@
+// Copyright 2017 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package geo implements spatial queries over geometry literals (quad.Point and quad.WKT) stored in a quad store.
+//
+// A geometry is within a region if all of its vertices are within it. Spatial filters are
+// answered by a spatial index if the quad store is wrapped with one (see New); otherwise each
+// value is checked directly. Indexes are pluggable and are registered by name; the default
+// "memory" index keeps geohashes of vertices in memory.
+package geo
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/codelingo/cayley/graph"
+	"github.com/codelingo/cayley/quad"
+	"github.com/codelingo/cayley/voc/geo"
+)
+
+// EarthRadius is the mean radius of the Earth in meters.
+const EarthRadius = 6371008.8
+
+// Region is an area on the surface of the Earth.
+type Region interface {
+	// Bounds returns a box that contains the whole region.
+	Bounds() Box
+	// Contains checks if a point is inside the region.
+	Contains(p quad.Point) bool
+	String() string
+}
+
+var (
+	_ Region = Box{}
+	_ Region = Circle{}
+)
+
+// Box is a region between two meridians and two parallels. It does not cross the antimeridian.
+type Box struct {
+	Min quad.Point // south-west corner
+	Max quad.Point // north-east corner
+}
+
+// NewBox creates a box from any two opposite corners.
+func NewBox(a, b quad.Point) Box {
+	return Box{
+		Min: quad.Point{Lon: math.Min(a.Lon, b.Lon), Lat: math.Min(a.Lat, b.Lat)},
+		Max: quad.Point{Lon: math.Max(a.Lon, b.Lon), Lat: math.Max(a.Lat, b.Lat)},
+	}
+}
+
+func (b Box) Bounds() Box { return b }
+
+func (b Box) Contains(p quad.Point) bool {
+	return p.Lon >= b.Min.Lon && p.Lon <= b.Max.Lon &&
+		p.Lat >= b.Min.Lat && p.Lat <= b.Max.Lat
+}
+
+func (b Box) String() string {
+	return fmt.Sprintf("box(%v %v, %v %v)", b.Min.Lon, b.Min.Lat, b.Max.Lon, b.Max.Lat)
+}
+
+// Circle is a region within a given distance from a point.
+type Circle struct {
+	Center quad.Point
+	Radius float64 // in meters
+}
+
+func (c Circle) Bounds() Box {
+	d := c.Radius / EarthRadius * 180 / math.Pi
+	minLat, maxLat := c.Center.Lat-d, c.Center.Lat+d
+	if minLat <= -90 || maxLat >= 90 {
+		// the circle contains a pole
+		return Box{
+			Min: quad.Point{Lon: -180, Lat: math.Max(minLat, -90)},
+			Max: quad.Point{Lon: 180, Lat: math.Min(maxLat, 90)},
+		}
+	}
+	// widest part of the circle is closer to the pole
+	lat := math.Max(math.Abs(minLat), math.Abs(maxLat)) * math.Pi / 180
+	dlon := d / math.Cos(lat)
+	minLon, maxLon := c.Center.Lon-dlon, c.Center.Lon+dlon
+	if minLon < -180 || maxLon > 180 {
+		// the circle crosses the antimeridian
+		minLon, maxLon = -180, 180
+	}
+	return Box{
+		Min: quad.Point{Lon: minLon, Lat: minLat},
+		Max: quad.Point{Lon: maxLon, Lat: maxLat},
+	}
+}
+
+func (c Circle) Contains(p quad.Point) bool {
+	return Distance(c.Center, p) <= c.Radius
+}
+
+func (c Circle) String() string {
+	return fmt.Sprintf("circle(%v %v, %vm)", c.Center.Lon, c.Center.Lat, c.Radius)
+}
+
+// Distance returns the great-circle distance between two points in meters.
+func Distance(a, b quad.Point) float64 {
+	const rad = math.Pi / 180
+	lat1, lat2 := a.Lat*rad, b.Lat*rad
+	dlat, dlon := (b.Lat-a.Lat)*rad, (b.Lon-a.Lon)*rad
+	h := math.Sin(dlat/2)*math.Sin(dlat/2) +
+		math.Cos(lat1)*math.Cos(lat2)*math.Sin(dlon/2)*math.Sin(dlon/2)
+	return 2 * EarthRadius * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// Geometry returns a geometry of a value. Besides quad.Point and quad.WKT, it accepts
+// typed strings with geo:wktLiteral type, as returned by stores that keep them in string form.
+func Geometry(v quad.Value) (quad.Geometry, bool) {
+	switch v := v.(type) {
+	case quad.Point:
+		return quad.Geometry{Type: quad.GeoPoint, Parts: [][]quad.Point{{v}}}, true
+	case quad.WKT:
+		g, err := v.Geometry()
+		return g, err == nil
+	case quad.TypedString:
+		if v.Type.Full() != quad.IRI(geo.WKTLiteral).Full() {
+			return quad.Geometry{}, false
+		}
+		g, err := quad.ParseWKT(string(v.Value))
+		return g, err == nil
+	}
+	return quad.Geometry{}, false
+}
+
+// Within checks if a value is a geometry that is within the region.
+func Within(v quad.Value, r Region) bool {
+	g, ok := Geometry(v)
+	return ok && within(g, r)
+}
+
+func within(g quad.Geometry, r Region) bool {
+	for _, p := range g.Points() {
+		if !r.Contains(p) {
+			return false
+		}
+	}
+	return true
+}
+
+// DefaultIndex is the name of the index used when none is specified.
+const DefaultIndex = "memory"
+
+// Index is a spatial index of geometry values.
+type Index interface {
+	// Add indexes given geometry values. Other values and values that are already in the index are ignored.
+	Add(vals []quad.Value) error
+	// Remove removes given values from the index.
+	Remove(vals []quad.Value) error
+	// Within returns all values that are within the region.
+	Within(r Region) ([]quad.Value, error)
+	// Close releases resources held by the index.
+	Close() error
+}
+
+// NewIndexFunc creates an index for a given quad store. It must index all values already stored in it.
+type NewIndexFunc func(qs graph.QuadStore, opts graph.Options) (Index, error)
+
+var indexes = make(map[string]NewIndexFunc)
+
+// RegisterIndex adds a new index type to the list of available indexes.
+func RegisterIndex(name string, fnc NewIndexFunc) {
+	if _, found := indexes[name]; found {
+		panic(fmt.Sprintf("already registered spatial index %q", name))
+	}
+	indexes[name] = fnc
+}
+
+// NewIndex creates an index of a given type for the quad store.
+func NewIndex(name string, qs graph.QuadStore, opts graph.Options) (Index, error) {
+	if name == "" {
+		name = DefaultIndex
+	}
+	fnc, ok := indexes[name]
+	if !ok {
+		return nil, fmt.Errorf("unsupported spatial index: %q", name)
+	}
+	return fnc(qs, opts)
+}
+
+// Indexes returns the names of all registered indexes.
+func Indexes() []string {
+	out := make([]string, 0, len(indexes))
+	for name := range indexes {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out
+}
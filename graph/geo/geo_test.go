@@ -0,0 +1,203 @@
+// Copyright 2017 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package geo
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/codelingo/cayley/graph"
+	"github.com/codelingo/cayley/graph/memstore"
+	"github.com/codelingo/cayley/quad"
+	"github.com/codelingo/cayley/writer"
+)
+
+var (
+	sf      = quad.Point{Lon: -122.4194, Lat: 37.7749}
+	oakland = quad.Point{Lon: -122.2711, Lat: 37.8044}
+	la      = quad.Point{Lon: -118.2437, Lat: 34.0522}
+	bay     = quad.WKT("POLYGON((-122.5 37.7, -122.2 37.7, -122.2 37.9, -122.5 37.9, -122.5 37.7))")
+)
+
+var testQuads = []quad.Quad{
+	quad.Make(quad.IRI("sf"), quad.IRI("location"), sf, nil),
+	quad.Make(quad.IRI("oakland"), quad.IRI("location"), oakland, nil),
+	quad.Make(quad.IRI("la"), quad.IRI("location"), la, nil),
+	quad.Make(quad.IRI("bay"), quad.IRI("area"), bay, nil),
+	quad.Make(quad.IRI("sf"), quad.IRI("name"), quad.String("San Francisco"), nil),
+}
+
+func TestDistance(t *testing.T) {
+	if d := Distance(sf, la); math.Abs(d-559e3) > 1e3 {
+		t.Errorf("unexpected distance: %v", d)
+	}
+	if d := Distance(sf, sf); d != 0 {
+		t.Errorf("unexpected distance: %v", d)
+	}
+	// one degree of a meridian
+	if d := Distance(quad.Point{Lat: 0}, quad.Point{Lat: 1}); math.Abs(d-111195) > 1 {
+		t.Errorf("unexpected distance: %v", d)
+	}
+}
+
+func TestCircleBounds(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for _, c := range []Circle{
+		{Center: sf, Radius: 20e3},
+		{Center: quad.Point{Lon: 179.9, Lat: 10}, Radius: 50e3},
+		{Center: quad.Point{Lon: 10, Lat: 89.9}, Radius: 50e3},
+		{Center: quad.Point{Lon: -30, Lat: -70}, Radius: 1000e3},
+	} {
+		b := c.Bounds()
+		for i := 0; i < 10000; i++ {
+			p := quad.Point{Lon: rnd.Float64()*360 - 180, Lat: rnd.Float64()*180 - 90}
+			if c.Contains(p) && !b.Contains(p) {
+				t.Fatalf("%v: point %v is not in bounds %v", c, p, b)
+			}
+		}
+	}
+}
+
+func TestMemoryIndex(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	idx := NewMemoryIndex()
+	var pts []quad.Value
+	for i := 0; i < 2000; i++ {
+		pts = append(pts, quad.Point{Lon: rnd.Float64()*40 - 20, Lat: rnd.Float64()*40 - 20})
+	}
+	idx.Add(pts)
+	idx.Add([]quad.Value{quad.String("POINT(1 2)"), quad.IRI("a")})
+	if n := idx.Len(); n != len(pts) {
+		t.Fatalf("unexpected number of indexed values, got:%d expect:%d", n, len(pts))
+	}
+	check := func(r Region, vals []quad.Value) {
+		got, err := idx.Within(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var expect []quad.Value
+		for _, v := range vals {
+			if Within(v, r) {
+				expect = append(expect, v)
+			}
+		}
+		sort.Sort(quad.ByValueString(got))
+		sort.Sort(quad.ByValueString(expect))
+		if len(got) != len(expect) {
+			t.Fatalf("%v: unexpected number of results, got:%d expect:%d", r, len(got), len(expect))
+		}
+		for i := range got {
+			if got[i] != expect[i] {
+				t.Fatalf("%v: unexpected result, got:%v expect:%v", r, got[i], expect[i])
+			}
+		}
+	}
+	regions := []Region{
+		NewBox(quad.Point{Lon: -1, Lat: -1}, quad.Point{Lon: 1, Lat: 1}),
+		NewBox(quad.Point{Lon: 5, Lat: -20}, quad.Point{Lon: 5.5, Lat: 20}),
+		NewBox(quad.Point{Lon: -180, Lat: -90}, quad.Point{Lon: 180, Lat: 90}),
+		Circle{Center: quad.Point{Lon: 3, Lat: 3}, Radius: 300e3},
+		Circle{Center: quad.Point{Lon: -19, Lat: 19}, Radius: 50e3},
+	}
+	for _, r := range regions {
+		check(r, pts)
+	}
+	idx.Remove(pts[:1500])
+	for _, r := range regions {
+		check(r, pts[1500:])
+	}
+}
+
+func search(t testing.TB, qs graph.QuadStore, r Region, optimize bool) []quad.Value {
+	var it graph.Iterator = NewIterator(qs, qs.NodesAllIterator(), r)
+	if optimize {
+		it, _ = it.Optimize()
+	}
+	defer it.Close()
+	var vals []quad.Value
+	for it.Next(nil) {
+		vals = append(vals, qs.NameOf(it.Result()))
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	sort.Sort(quad.ByValueString(vals))
+	return vals
+}
+
+func expectValues(t testing.TB, got []quad.Value, expect ...quad.Value) {
+	sort.Sort(quad.ByValueString(expect))
+	if len(got) != len(expect) {
+		t.Fatalf("unexpected results, got:%v expect:%v", got, expect)
+	}
+	for i := range got {
+		if got[i].String() != expect[i].String() {
+			t.Fatalf("unexpected results, got:%v expect:%v", got, expect)
+		}
+	}
+}
+
+func TestIterator(t *testing.T) {
+	mem := memstore.New(testQuads[:2]...)
+
+	idx, err := NewIndex("", mem, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	qs := New(mem, idx)
+	defer qs.Close()
+	w, err := writer.NewSingleReplication(qs, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = w.AddQuadSet(testQuads[2:]); err != nil {
+		t.Fatal(err)
+	}
+
+	box := NewBox(quad.Point{Lon: -123, Lat: 37}, quad.Point{Lon: -122, Lat: 38})
+	circle := Circle{Center: sf, Radius: 20e3}
+	for _, optimize := range []bool{false, true} {
+		expectValues(t, search(t, qs, box, optimize), sf, oakland, bay)
+		expectValues(t, search(t, qs, circle, optimize), sf, oakland)
+		// the same results without the index
+		expectValues(t, search(t, mem, box, optimize), sf, oakland, bay)
+	}
+
+	it, ok := NewIterator(qs, qs.NodesAllIterator(), box).Optimize()
+	if !ok || it.Type() != graph.And {
+		t.Errorf("unexpected optimized iterator: %v", it.Type())
+	}
+	it, ok = NewIterator(mem, mem.NodesAllIterator(), box).Optimize()
+	if ok || it.Type() != Type() {
+		t.Errorf("unexpected optimized iterator: %v", it.Type())
+	}
+
+	if err = w.RemoveQuad(testQuads[1]); err != nil {
+		t.Fatal(err)
+	}
+	expectValues(t, search(t, qs, circle, true), sf)
+	if n := idx.(*MemoryIndex).Len(); n != 3 {
+		t.Errorf("unexpected number of indexed values, got:%d expect:3", n)
+	}
+
+	it = NewIterator(qs, qs.NodesAllIterator(), circle)
+	if !it.Contains(nil, qs.ValueOf(sf)) {
+		t.Error("expected value to match")
+	} else if it.Contains(nil, qs.ValueOf(la)) {
+		t.Error("unexpected match")
+	}
+}
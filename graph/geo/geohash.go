@@ -0,0 +1,110 @@
+// Copyright 2017 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package geo
+
+import (
+	"sort"
+
+	"github.com/codelingo/cayley/quad"
+)
+
+// hashBits is the number of bits of each coordinate in a geohash.
+const hashBits = 26
+
+// maxCells is the maximal number of cells used to cover a box.
+const maxCells = 16
+
+// cell returns indexes of a cell that contains the point, with a given number of bits per coordinate.
+func cell(p quad.Point, bits uint) (x, y uint64) {
+	n := float64(uint64(1) << bits)
+	x = uint64((p.Lon + 180) / 360 * n)
+	y = uint64((p.Lat + 90) / 180 * n)
+	// points on the east and north edges belong to the last cell
+	if max := uint64(1)<<bits - 1; x > max {
+		x = max
+	}
+	if max := uint64(1)<<bits - 1; y > max {
+		y = max
+	}
+	return x, y
+}
+
+// interleave builds a geohash of a cell, interleaving bits of longitude and latitude.
+func interleave(x, y uint64, bits uint) uint64 {
+	var h uint64
+	for i := int(bits) - 1; i >= 0; i-- {
+		h = h<<1 | (x>>uint(i))&1
+		h = h<<1 | (y>>uint(i))&1
+	}
+	return h
+}
+
+// Geohash returns a geohash of a point with the full precision, as an integer with 52 significant bits.
+// Nearby points usually share a long prefix of their geohashes.
+func Geohash(p quad.Point) uint64 {
+	x, y := cell(p, hashBits)
+	return interleave(x, y, hashBits)
+}
+
+// hashRange is a range of geohashes [lo, hi).
+type hashRange struct {
+	lo, hi uint64
+}
+
+// cover returns sorted ranges of geohashes of cells that cover a box.
+// It uses the finest grid that covers the box with at most maxCells cells.
+func cover(b Box) []hashRange {
+	var (
+		bits           uint
+		x0, y0, x1, y1 uint64
+	)
+	// a grid of 2x2 cells always fits
+	for bits = hashBits; bits > 1; bits-- {
+		x0, y0 = cell(b.Min, bits)
+		x1, y1 = cell(b.Max, bits)
+		if (x1-x0+1)*(y1-y0+1) <= maxCells {
+			break
+		}
+	}
+	if bits == 1 {
+		x0, y0 = cell(b.Min, bits)
+		x1, y1 = cell(b.Max, bits)
+	}
+	shift := 2 * (hashBits - bits)
+	var out []hashRange
+	for x := x0; x <= x1; x++ {
+		for y := y0; y <= y1; y++ {
+			h := interleave(x, y, bits) << shift
+			out = append(out, hashRange{h, h + 1<<shift})
+		}
+	}
+	sort.Sort(byLo(out))
+	// merge adjacent cells
+	merged := out[:1]
+	for _, r := range out[1:] {
+		if last := &merged[len(merged)-1]; last.hi == r.lo {
+			last.hi = r.hi
+		} else {
+			merged = append(merged, r)
+		}
+	}
+	return merged
+}
+
+type byLo []hashRange
+
+func (a byLo) Len() int           { return len(a) }
+func (a byLo) Less(i, j int) bool { return a[i].lo < a[j].lo }
+func (a byLo) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
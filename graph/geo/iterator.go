@@ -0,0 +1,224 @@
+// Copyright 2017 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package geo
+
+import (
+	"github.com/codelingo/cayley/clog"
+	"github.com/codelingo/cayley/graph"
+	"github.com/codelingo/cayley/graph/iterator"
+)
+
+var geoType graph.Type
+
+func init() {
+	geoType = graph.RegisterIterator("geo")
+}
+
+// Type returns the type of the spatial filter iterator.
+func Type() graph.Type { return geoType }
+
+// searcherOf finds a spatial index of the quad store or one of the stores it wraps.
+func searcherOf(qs graph.QuadStore) (Searcher, bool) {
+	for {
+		if s, ok := qs.(Searcher); ok {
+			return s, true
+		}
+		w, ok := qs.(interface {
+			Unwrap() graph.QuadStore
+		})
+		if !ok {
+			return nil, false
+		}
+		qs = w.Unwrap()
+	}
+}
+
+// Iterator is a filter that passes nodes of the sub-iterator with geometries within a region.
+//
+// When optimized, it is replaced with an intersection of the sub-iterator and the results
+// of the spatial index, if the quad store has one.
+type Iterator struct {
+	uid    uint64
+	tags   graph.Tagger
+	subIt  graph.Iterator
+	region Region
+	qs     graph.QuadStore
+	result graph.Value
+	err    error
+}
+
+// NewIterator creates a filter of nodes within a region.
+func NewIterator(qs graph.QuadStore, sub graph.Iterator, r Region) *Iterator {
+	return &Iterator{
+		uid:    iterator.NextUID(),
+		subIt:  sub,
+		region: r,
+		qs:     qs,
+	}
+}
+
+func (it *Iterator) UID() uint64 {
+	return it.uid
+}
+
+// Region returns the region of the filter.
+func (it *Iterator) Region() Region {
+	return it.region
+}
+
+func (it *Iterator) within(v graph.Value) bool {
+	return Within(it.qs.NameOf(v), it.region)
+}
+
+func (it *Iterator) Reset() {
+	it.subIt.Reset()
+	it.err = nil
+	it.result = nil
+}
+
+func (it *Iterator) Close() error {
+	return it.subIt.Close()
+}
+
+func (it *Iterator) Tagger() *graph.Tagger {
+	return &it.tags
+}
+
+func (it *Iterator) TagResults(dst map[string]graph.Value) {
+	for _, tag := range it.tags.Tags() {
+		dst[tag] = it.Result()
+	}
+
+	for tag, value := range it.tags.Fixed() {
+		dst[tag] = value
+	}
+
+	it.subIt.TagResults(dst)
+}
+
+func (it *Iterator) Clone() graph.Iterator {
+	out := NewIterator(it.qs, it.subIt.Clone(), it.region)
+	out.tags.CopyFrom(it)
+	return out
+}
+
+func (it *Iterator) Next(ctx *graph.IterationContext) bool {
+	graph.NextLogIn(ctx, it)
+	for it.subIt.Next(ctx) {
+		val := it.subIt.Result()
+		if it.within(val) {
+			it.result = val
+			return graph.NextLogOut(ctx, it, true)
+		}
+	}
+	it.err = it.subIt.Err()
+	return graph.NextLogOut(ctx, it, false)
+}
+
+func (it *Iterator) Contains(ctx *graph.IterationContext, val graph.Value) bool {
+	graph.ContainsLogIn(ctx, it, val)
+	if !it.within(val) {
+		return graph.ContainsLogOut(ctx, it, val, false)
+	}
+	if !it.subIt.Contains(ctx, val) {
+		it.err = it.subIt.Err()
+		return graph.ContainsLogOut(ctx, it, val, false)
+	}
+	it.result = val
+	return graph.ContainsLogOut(ctx, it, val, true)
+}
+
+func (it *Iterator) NextPath(ctx *graph.IterationContext) bool {
+	for {
+		if !it.subIt.NextPath(ctx) {
+			it.err = it.subIt.Err()
+			return false
+		}
+		if it.within(it.subIt.Result()) {
+			break
+		}
+	}
+	it.result = it.subIt.Result()
+	return true
+}
+
+func (it *Iterator) Err() error {
+	return it.err
+}
+
+func (it *Iterator) Result() graph.Value {
+	return it.result
+}
+
+func (it *Iterator) SubIterators() []graph.Iterator {
+	return []graph.Iterator{it.subIt}
+}
+
+func (it *Iterator) Describe() graph.Description {
+	primary := it.subIt.Describe()
+	return graph.Description{
+		UID:      it.UID(),
+		Name:     it.region.String(),
+		Type:     it.Type(),
+		Tags:     it.tags.Tags(),
+		Iterator: &primary,
+	}
+}
+
+func (it *Iterator) Type() graph.Type { return geoType }
+
+// Optimize replaces the filter with results of the spatial index, if there is one.
+// Quad stores with a native spatial search may replace the iterator as well.
+func (it *Iterator) Optimize() (graph.Iterator, bool) {
+	newSub, changed := it.subIt.Optimize()
+	if changed {
+		it.subIt.Close()
+		it.subIt = newSub
+	}
+	if nit, ok := it.qs.OptimizeIterator(it); ok {
+		return nit, true
+	}
+	s, ok := searcherOf(it.qs)
+	if !ok {
+		return it, false
+	}
+	vals, err := s.Within(it.region)
+	if err != nil {
+		clog.Errorf("spatial index lookup failed: %v", err)
+		return it, false
+	}
+	fixed := it.qs.FixedIterator()
+	for _, v := range vals {
+		if gv := it.qs.ValueOf(v); gv != nil {
+			fixed.Add(gv)
+		}
+	}
+	// the index is usually much more selective than the path, thus it goes first
+	and := iterator.NewAnd(it.qs, fixed, it.subIt)
+	and.Tagger().CopyFrom(it)
+	return and, true
+}
+
+// Stats returns the stats of the sub-iterator, since each of its values is checked.
+func (it *Iterator) Stats() graph.IteratorStats {
+	return it.subIt.Stats()
+}
+
+func (it *Iterator) Size() (int64, bool) {
+	s, _ := it.subIt.Size()
+	return s, false
+}
+
+var _ graph.Iterator = &Iterator{}
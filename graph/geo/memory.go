@@ -0,0 +1,176 @@
+// Copyright 2017 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package geo
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/codelingo/cayley/graph"
+	"github.com/codelingo/cayley/quad"
+)
+
+func init() {
+	RegisterIndex(DefaultIndex, func(qs graph.QuadStore, _ graph.Options) (Index, error) {
+		return NewMemoryIndexFor(qs)
+	})
+}
+
+var _ Index = (*MemoryIndex)(nil)
+
+type hashEntry struct {
+	hash uint64
+	val  quad.Value
+}
+
+// MemoryIndex is a spatial index kept in memory. It keeps a sorted list of geohashes
+// of all vertices of indexed geometries.
+type MemoryIndex struct {
+	mu      sync.Mutex
+	entries []hashEntry
+	sorted  bool
+	vals    map[quad.Value]quad.Geometry
+	stale   int // number of entries of removed values
+}
+
+// NewMemoryIndex creates an empty in-memory index.
+func NewMemoryIndex() *MemoryIndex {
+	return &MemoryIndex{vals: make(map[quad.Value]quad.Geometry)}
+}
+
+// NewMemoryIndexFor creates an in-memory index of all values in the quad store.
+func NewMemoryIndexFor(qs graph.QuadStore) (*MemoryIndex, error) {
+	idx := NewMemoryIndex()
+	it := qs.NodesAllIterator()
+	defer it.Close()
+	var vals []quad.Value
+	for it.Next(nil) {
+		vals = append(vals, qs.NameOf(it.Result()))
+	}
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+	idx.Add(vals)
+	return idx, nil
+}
+
+func (idx *MemoryIndex) Add(vals []quad.Value) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	for _, v := range vals {
+		g, ok := Geometry(v)
+		if !ok {
+			continue
+		}
+		// geometries may be stored as typed strings
+		v = g.Value()
+		if _, ok = idx.vals[v]; ok {
+			continue
+		}
+		idx.vals[v] = g
+		for _, p := range g.Points() {
+			idx.entries = append(idx.entries, hashEntry{hash: Geohash(p), val: v})
+		}
+		idx.sorted = false
+	}
+	return nil
+}
+
+func (idx *MemoryIndex) Remove(vals []quad.Value) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	for _, v := range vals {
+		g, ok := Geometry(v)
+		if !ok {
+			continue
+		}
+		v = g.Value()
+		if _, ok = idx.vals[v]; !ok {
+			continue
+		}
+		delete(idx.vals, v)
+		idx.stale += len(g.Points())
+	}
+	// entries are compacted on the next search
+	if idx.stale > len(idx.entries)/2 {
+		idx.sorted = false
+	}
+	return nil
+}
+
+type byHash []hashEntry
+
+func (a byHash) Len() int           { return len(a) }
+func (a byHash) Less(i, j int) bool { return a[i].hash < a[j].hash }
+func (a byHash) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+
+// prepare sorts entries and drops entries of removed values, if necessary.
+func (idx *MemoryIndex) prepare() {
+	if idx.sorted {
+		return
+	}
+	if idx.stale != 0 {
+		entries := idx.entries[:0]
+		for _, e := range idx.entries {
+			if _, ok := idx.vals[e.val]; ok {
+				entries = append(entries, e)
+			}
+		}
+		idx.entries, idx.stale = entries, 0
+	}
+	sort.Sort(byHash(idx.entries))
+	idx.sorted = true
+}
+
+// Within returns all values within the region, in the order of geohashes of their vertices.
+//
+// Values that have at least one vertex in cells covering the region are checked against the region.
+func (idx *MemoryIndex) Within(r Region) ([]quad.Value, error) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.prepare()
+	var (
+		out  []quad.Value
+		seen = make(map[quad.Value]struct{})
+	)
+	for _, hr := range cover(r.Bounds()) {
+		i := sort.Search(len(idx.entries), func(i int) bool {
+			return idx.entries[i].hash >= hr.lo
+		})
+		for ; i < len(idx.entries) && idx.entries[i].hash < hr.hi; i++ {
+			v := idx.entries[i].val
+			if _, ok := seen[v]; ok {
+				continue
+			}
+			seen[v] = struct{}{}
+			g, ok := idx.vals[v]
+			if ok && within(g, r) {
+				out = append(out, v)
+			}
+		}
+	}
+	return out, nil
+}
+
+// Len returns the number of indexed values.
+func (idx *MemoryIndex) Len() int {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	return len(idx.vals)
+}
+
+func (idx *MemoryIndex) Close() error {
+	return nil
+}
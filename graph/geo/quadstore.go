@@ -0,0 +1,79 @@
+// Copyright 2017 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package geo
+
+import (
+	"github.com/codelingo/cayley/graph"
+	"github.com/codelingo/cayley/graph/valueindex"
+	"github.com/codelingo/cayley/quad"
+)
+
+// Searcher is implemented by quad stores that have a spatial index.
+type Searcher interface {
+	Within(r Region) ([]quad.Value, error)
+}
+
+var (
+	_ graph.QuadStore    = (*QuadStore)(nil)
+	_ graph.HistoryStore = (*QuadStore)(nil)
+	_ Searcher           = (*QuadStore)(nil)
+)
+
+// QuadStore wraps any quad store and keeps a spatial index in sync with it.
+type QuadStore struct {
+	valueindex.Wrapper
+	idx Index
+}
+
+func isGeometry(v quad.Value) bool {
+	_, ok := Geometry(v)
+	return ok
+}
+
+// New wraps a quad store with a spatial index. The index must already contain all values of the store.
+func New(qs graph.QuadStore, idx Index) *QuadStore {
+	return &QuadStore{Wrapper: valueindex.NewWrapper(qs, idx, isGeometry), idx: idx}
+}
+
+// Index returns the spatial index of the store.
+func (qs *QuadStore) Index() Index {
+	return qs.idx
+}
+
+// AsOf returns a read-only view of the underlying store at a given horizon, with the same spatial index.
+// Spatial filters in the view only return values that are in the view.
+func (qs *QuadStore) AsOf(horizon int64) (graph.QuadStore, error) {
+	w, err := qs.View(horizon)
+	if err != nil {
+		return nil, err
+	}
+	return &QuadStore{Wrapper: w, idx: qs.idx}, nil
+}
+
+// Within returns all values within the region, using the spatial index.
+func (qs *QuadStore) Within(r Region) ([]quad.Value, error) {
+	out, err := qs.idx.Within(r)
+	if err != nil {
+		return nil, err
+	}
+	n := 0
+	for _, v := range out {
+		if qs.Visible(v) {
+			out[n] = v
+			n++
+		}
+	}
+	return out[:n], nil
+}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/codelingo/cayley/graph"
 	"github.com/codelingo/cayley/graph/iterator"
+	"github.com/codelingo/cayley/graph/valueindex"
 	"github.com/codelingo/cayley/quad"
 	"github.com/codelingo/cayley/quad/nquads"
 	"github.com/codelingo/cayley/writer"
@@ -57,6 +58,7 @@ func TestAll(t testing.TB, gen DatabaseFunc, conf *Config) {
 	if !conf.UnTyped {
 		TestCompareTypedValues(t, gen, conf)
 	}
+	TestAsOf(t, gen)
 }
 
 func MakeWriter(t testing.TB, qs graph.QuadStore, opts graph.Options, data ...quad.Quad) graph.QuadWriter {
@@ -125,7 +127,7 @@ func MakeQuadSet() []quad.Quad {
 
 func IteratedQuads(t testing.TB, qs graph.QuadStore, it graph.Iterator) []quad.Quad {
 	var res quad.ByQuadString
-	for it.Next() {
+	for it.Next(nil) {
 		res = append(res, qs.Quad(it.Result()))
 	}
 	require.Nil(t, it.Err())
@@ -165,7 +167,7 @@ func ExpectIteratedValues(t testing.TB, qs graph.QuadStore, it graph.Iterator, e
 
 func IteratedRawStrings(t testing.TB, qs graph.QuadStore, it graph.Iterator) []string {
 	var res []string
-	for it.Next() {
+	for it.Next(nil) {
 		res = append(res, qs.NameOf(it.Result()).String())
 	}
 	require.Nil(t, it.Err())
@@ -175,7 +177,7 @@ func IteratedRawStrings(t testing.TB, qs graph.QuadStore, it graph.Iterator) []s
 
 func IteratedValues(t testing.TB, qs graph.QuadStore, it graph.Iterator) []quad.Value {
 	var res []quad.Value
-	for it.Next() {
+	for it.Next(nil) {
 		res = append(res, qs.NameOf(it.Result()))
 	}
 	require.Nil(t, it.Err())
@@ -291,13 +293,13 @@ func TestIterator(t testing.TB, gen DatabaseFunc) {
 	}
 
 	for _, pq := range expect {
-		require.True(t, it.Contains(qs.ValueOf(quad.Raw(pq))), "Failed to find and check %q correctly", pq)
+		require.True(t, it.Contains(nil, qs.ValueOf(quad.Raw(pq))), "Failed to find and check %q correctly", pq)
 
 	}
 	// FIXME(kortschak) Why does this fail?
 	/*
 		for _, pq := range []string{"baller"} {
-			if it.Contains(qs.ValueOf(pq)) {
+			if it.Contains(nil, qs.ValueOf(pq)) {
 				t.Errorf("Failed to check %q correctly", pq)
 			}
 		}
@@ -308,7 +310,7 @@ func TestIterator(t testing.TB, gen DatabaseFunc) {
 	optIt, changed = it.Optimize()
 	require.True(t, !changed && optIt == it, "Optimize unexpectedly changed iterator: %v, %T", changed, optIt)
 
-	require.True(t, it.Next())
+	require.True(t, it.Next(nil))
 
 	q := qs.Quad(it.Result())
 	require.Nil(t, it.Err())
@@ -475,6 +477,75 @@ func TestDeleteQuad(t testing.TB, gen DatabaseFunc) {
 	it.Close()
 }
 
+// TestAsOf checks read-only views of the past graph state. It does nothing if the store does not keep history.
+func TestAsOf(t testing.TB, gen DatabaseFunc) {
+	qs, opts, closer := gen(t)
+	defer closer()
+	if _, ok := qs.(graph.HistoryStore); !ok {
+		return
+	}
+
+	w := MakeWriter(t, qs, opts, MakeQuadSet()...)
+	past := qs.Horizon()
+	time.Sleep(10 * time.Millisecond)
+	ts := time.Now()
+	time.Sleep(10 * time.Millisecond)
+
+	err := w.RemoveQuad(quad.MakeRaw("E", "follows", "F", ""))
+	require.NoError(t, err)
+	err = w.AddQuad(quad.MakeRaw("X", "follows", "Y", ""))
+	require.NoError(t, err)
+
+	view, err := graph.AsOf(qs, past.Int())
+	require.NoError(t, err)
+	h := view.Horizon()
+	require.Equal(t, past.Int(), h.Int())
+
+	viewAt, err := graph.AsOfTime(qs, ts)
+	require.NoError(t, err)
+	h = viewAt.Horizon()
+	require.Equal(t, past.Int(), h.Int())
+
+	for _, v := range []graph.QuadStore{view, viewAt} {
+		it := v.QuadIterator(quad.Subject, v.ValueOf(quad.Raw("E")))
+		ExpectIteratedQuads(t, v, it, []quad.Quad{
+			quad.MakeRaw("E", "follows", "F", ""),
+		}, false)
+		it.Close()
+
+		it = v.QuadIterator(quad.Subject, v.ValueOf(quad.Raw("X")))
+		ExpectIteratedQuads(t, v, it, nil, false)
+		it.Close()
+
+		it = v.QuadsAllIterator()
+		ExpectIteratedQuads(t, v, it, MakeQuadSet(), true)
+		it.Close()
+
+		// nodes added or removed after the horizon must not affect the view
+		it = v.NodesAllIterator()
+		ExpectIteratedRawStrings(t, v, it, []string{
+			"A", "B", "C", "D", "E", "F", "G",
+			"cool", "follows", "status", "status_graph",
+		})
+		it.Close()
+
+		err = v.ApplyDeltas([]graph.Delta{{
+			ID:     graph.NewSequentialKey(h.Int() + 100),
+			Quad:   quad.MakeRaw("X", "follows", "Z", ""),
+			Action: graph.Add,
+		}}, graph.IgnoreOpts{})
+		require.Equal(t, graph.ErrReadOnlyView, err)
+		require.NoError(t, v.Close())
+	}
+
+	// parent store is not affected by views
+	it := qs.QuadIterator(quad.Subject, qs.ValueOf(quad.Raw("X")))
+	ExpectIteratedQuads(t, qs, it, []quad.Quad{
+		quad.MakeRaw("X", "follows", "Y", ""),
+	}, false)
+	it.Close()
+}
+
 func TestDeletedFromIterator(t testing.TB, gen DatabaseFunc) {
 	qs, opts, closer := gen(t)
 	defer closer()
@@ -685,7 +756,7 @@ func TestIteratorsAndNextResultOrderA(t testing.TB, gen DatabaseFunc) {
 	hasa := iterator.NewHasA(qs, innerAnd, quad.Subject)
 	outerAnd := iterator.NewAnd(qs, fixed, hasa)
 
-	require.True(t, outerAnd.Next(), "Expected one matching subtree")
+	require.True(t, outerAnd.Next(nil), "Expected one matching subtree")
 
 	val := outerAnd.Result()
 	require.Equal(t, quad.Raw("C"), qs.NameOf(val))
@@ -704,7 +775,7 @@ func TestIteratorsAndNextResultOrderA(t testing.TB, gen DatabaseFunc) {
 
 	require.Equal(t, expect, got)
 
-	require.True(t, !outerAnd.Next(), "More than one possible top level output?")
+	require.True(t, !outerAnd.Next(nil), "More than one possible top level output?")
 }
 
 const lt, lte, gt, gte = iterator.CompareLT, iterator.CompareLTE, iterator.CompareGT, iterator.CompareGTE
@@ -795,3 +866,79 @@ func TestCompareTypedValues(t testing.TB, gen DatabaseFunc, conf *Config) {
 		ExpectIteratedValues(t, qs, nit, c.expect)
 	}
 }
+
+// TestValueIndex checks that comparisons are answered by value indexes. A quad store must be created
+// with both valueindex.OptIndex and valueindex.OptIndexByPredicate options enabled.
+func TestValueIndex(t testing.TB, gen DatabaseFunc) {
+	qs, opts, closer := gen(t)
+	defer closer()
+
+	w := MakeWriter(t, qs, opts)
+
+	err := w.AddQuadSet([]quad.Quad{
+		{quad.IRI("alice"), quad.IRI("age"), quad.Int(30), nil},
+		{quad.IRI("bob"), quad.IRI("age"), quad.Int(25), nil},
+		{quad.IRI("charlie"), quad.IRI("age"), quad.Int(40), nil},
+		{quad.IRI("dani"), quad.IRI("age"), quad.String("35"), nil},
+		{quad.IRI("alice"), quad.IRI("score"), quad.Int(35), nil},
+		{quad.IRI("bob"), quad.IRI("height"), quad.Float(-1.5), nil},
+		{quad.IRI("charlie"), quad.IRI("height"), quad.Float(1.8), nil},
+		{quad.IRI("alice"), quad.IRI("born"), quad.Time(tzero), nil},
+		{quad.IRI("bob"), quad.IRI("born"), quad.Time(tzero.Add(time.Hour)), nil},
+	})
+	require.NoError(t, err)
+
+	byPred := func(pred string) graph.Iterator {
+		return iterator.NewHasA(qs, qs.QuadIterator(quad.Predicate, qs.ValueOf(quad.IRI(pred))), quad.Object)
+	}
+
+	cases := []struct {
+		name   string
+		sub    func() graph.Iterator
+		op     iterator.Operator
+		val    quad.Value
+		typ    graph.Type
+		expect []quad.Value
+	}{
+		{"all gte", qs.NodesAllIterator, gte, quad.Int(30), valueindex.Type(),
+			[]quad.Value{quad.Int(30), quad.Int(35), quad.Int(40)}},
+		{"all lt", qs.NodesAllIterator, lt, quad.Int(30), valueindex.Type(),
+			[]quad.Value{quad.Int(25)}},
+		{"all float", qs.NodesAllIterator, lte, quad.Float(0), valueindex.Type(),
+			[]quad.Value{quad.Float(-1.5)}},
+		{"all time", qs.NodesAllIterator, gt, quad.Time(tzero), valueindex.Type(),
+			[]quad.Value{quad.Time(tzero.Add(time.Hour))}},
+		{"all empty", qs.NodesAllIterator, gt, quad.Int(100), valueindex.Type(), nil},
+		{"pred gte", func() graph.Iterator { return byPred("age") }, gte, quad.Int(30), graph.And,
+			[]quad.Value{quad.Int(30), quad.Int(40)}},
+		{"pred gt", func() graph.Iterator { return byPred("score") }, gt, quad.Int(30), graph.And,
+			[]quad.Value{quad.Int(35)}},
+		{"pred time", func() graph.Iterator { return byPred("born") }, lte, quad.Time(tzero), graph.And,
+			[]quad.Value{quad.Time(tzero)}},
+	}
+	for _, c := range cases {
+		sort.Sort(quad.ByValueString(c.expect))
+
+		it := iterator.NewComparison(c.sub(), c.op, c.val, qs)
+		ExpectIteratedValues(t, qs, it, c.expect)
+
+		it = iterator.NewComparison(c.sub(), c.op, c.val, qs)
+		nit, ok := qs.OptimizeIterator(it)
+		require.True(t, ok, "%s: comparison was not optimized", c.name)
+		require.Equal(t, c.typ, nit.Type(), c.name)
+		ExpectIteratedValues(t, qs, nit, c.expect)
+	}
+
+	// strings are not indexed
+	it := iterator.NewComparison(qs.NodesAllIterator(), gte, quad.String("3"), qs)
+	_, ok := qs.OptimizeIterator(it)
+	require.False(t, ok)
+
+	// removed quads are filtered out by the predicate
+	err = w.RemoveQuad(quad.Make(quad.IRI("charlie"), quad.IRI("age"), quad.Int(40), nil))
+	require.NoError(t, err)
+
+	it = iterator.NewComparison(byPred("age"), gte, quad.Int(30), qs)
+	nit, _ := qs.OptimizeIterator(it)
+	ExpectIteratedValues(t, qs, nit, []quad.Value{quad.Int(30)})
+}
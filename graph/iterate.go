@@ -17,6 +17,8 @@ type IterateChain struct {
 
 	paths    bool
 	optimize bool
+	skip     bool              // do not run the iterator; set if it is only explained
+	ictx     *IterationContext // set if the iterator is profiled or can be cancelled
 
 	limit int
 	n     int
@@ -43,7 +45,7 @@ func (c *IterateChain) next() bool {
 	default:
 	}
 
-	ok := (c.limit < 0 || c.n < c.limit) && c.it.Next(nil)
+	ok := !c.skip && (c.limit < 0 || c.n < c.limit) && c.it.Next(c.ictx)
 	if ok {
 		c.n++
 	}
@@ -68,6 +70,15 @@ func (c *IterateChain) start() {
 			c.it, _ = c.qs.OptimizeIterator(c.it)
 		}
 	}
+	if e := ExplainerFromContext(c.ctx); e != nil {
+		c.skip = !e.Track(c.it)
+		c.ictx = e.IterationContext()
+	} else if c.ctx.Done() != nil {
+		c.ictx = NewIterationContext()
+	}
+	if c.ictx != nil {
+		c.ictx.ctx = c.ctx
+	}
 	if !clog.V(2) {
 		return
 	}
@@ -127,7 +138,7 @@ func (c *IterateChain) Each(fnc func(Value)) error {
 		default:
 		}
 		fnc(c.it.Result())
-		for c.nextPath(nil) {
+		for c.nextPath(c.ictx) {
 			select {
 			case <-done:
 				return c.ctx.Err()
@@ -159,7 +170,7 @@ iteration:
 		default:
 		}
 		cnt++
-		for c.nextPath(nil) {
+		for c.nextPath(c.ictx) {
 			select {
 			case <-done:
 				break iteration
@@ -185,7 +196,7 @@ iteration:
 		default:
 		}
 		out = append(out, c.it.Result())
-		for c.nextPath(nil) {
+		for c.nextPath(c.ictx) {
 			select {
 			case <-done:
 				break iteration
@@ -210,7 +221,7 @@ func (c *IterateChain) Send(out chan<- Value) error {
 			return c.ctx.Err()
 		case out <- c.it.Result():
 		}
-		for c.nextPath(nil) {
+		for c.nextPath(c.ictx) {
 			select {
 			case <-done:
 				return c.ctx.Err()
@@ -236,7 +247,7 @@ func (c *IterateChain) TagEach(fnc func(map[string]Value)) error {
 		tags := make(map[string]Value)
 		c.it.TagResults(tags)
 		fnc(tags)
-		for c.nextPath(nil) {
+		for c.nextPath(c.ictx) {
 			select {
 			case <-done:
 				return c.ctx.Err()
@@ -316,7 +327,7 @@ func (c *IterateChain) SendValues(qs QuadStore, out chan<- quad.Value) error {
 		if err := send(c.it.Result()); err != nil {
 			return err
 		}
-		for c.nextPath(nil) {
+		for c.nextPath(c.ictx) {
 			if err := send(c.it.Result()); err != nil {
 				return err
 			}
@@ -23,6 +23,7 @@ import (
 
 	"github.com/codelingo/cayley/clog"
 	"github.com/codelingo/cayley/quad"
+	"golang.org/x/net/context"
 )
 
 type Tagger struct {
@@ -221,6 +222,9 @@ type IterationContext struct {
 	values  map[string]Value
 	isBound map[string]bool
 	subIts  map[string]Iterator
+
+	ctx       context.Context // cancels the iteration, if set
+	explainer *Explainer      // records calls of iterators, if profiling
 }
 
 func NewIterationContext() *IterationContext {
@@ -231,6 +235,15 @@ func NewIterationContext() *IterationContext {
 	}
 }
 
+// Err returns an error if the iteration was cancelled. Iterators that may run for a long time
+// in a single call should check it periodically. It is safe to call on a nil context.
+func (c *IterationContext) Err() error {
+	if c == nil || c.ctx == nil {
+		return nil
+	}
+	return c.ctx.Err()
+}
+
 // BindVariable binds a variable if it has not already been bound.
 func (c *IterationContext) BindVariable(qs QuadStore, varName string) bool {
 	if val, ok := c.isBound[varName]; ok && val {
@@ -306,6 +319,9 @@ const (
 	Regex
 	Recursive
 	Count
+	Paths
+	GroupBy
+	Order
 )
 
 var (
@@ -334,7 +350,9 @@ var (
 		"regex",
 		"recursive",
 		"count",
-		"recursive",
+		"paths",
+		"groupby",
+		"order",
 	}
 )
 
@@ -401,14 +419,17 @@ func DumpStats(it Iterator) StatsContainer {
 
 // Utility logging functions for when an iterator gets called Next upon, or Contains upon, as
 // well as what they return. Highly useful for tracing the execution path of a query.
+// They also record calls of iterators that are being profiled with the iteration context (see Explainer).
 
-func ContainsLogIn(it Iterator, val Value) {
+func ContainsLogIn(ctx *IterationContext, it Iterator, val Value) {
+	profileIn(ctx, it)
 	if clog.V(4) {
 		clog.Infof("%s %d CHECK CONTAINS %v", strings.ToUpper(it.Type().String()), it.UID(), val)
 	}
 }
 
-func ContainsLogOut(it Iterator, val Value, good bool) bool {
+func ContainsLogOut(ctx *IterationContext, it Iterator, val Value, good bool) bool {
+	profileOut(ctx, it, true)
 	if clog.V(4) {
 		if good {
 			clog.Infof("%s %d CHECK CONTAINS %v GOOD", strings.ToUpper(it.Type().String()), it.UID(), val)
@@ -419,13 +440,15 @@ func ContainsLogOut(it Iterator, val Value, good bool) bool {
 	return good
 }
 
-func NextLogIn(it Iterator) {
+func NextLogIn(ctx *IterationContext, it Iterator) {
+	profileIn(ctx, it)
 	if clog.V(4) {
 		clog.Infof("%s %d NEXT", strings.ToUpper(it.Type().String()), it.UID())
 	}
 }
 
-func NextLogOut(it Iterator, ok bool) bool {
+func NextLogOut(ctx *IterationContext, it Iterator, ok bool) bool {
+	profileOut(ctx, it, false)
 	if clog.V(4) {
 		if ok {
 			val := it.Result()
@@ -101,10 +101,10 @@ func (it *Int64) Describe() graph.Description {
 // Next() on an Int64 all iterator is a simple incrementing counter.
 // Return the next integer, and mark it as the result.
 func (it *Int64) Next(ctx *graph.IterationContext) bool {
-	graph.NextLogIn(it)
+	graph.NextLogIn(ctx, it)
 	it.runstats.Next += 1
 	if it.at == -1 {
-		return graph.NextLogOut(it, false)
+		return graph.NextLogOut(ctx, it, false)
 	}
 	val := it.at
 	it.at = it.at + 1
@@ -112,7 +112,7 @@ func (it *Int64) Next(ctx *graph.IterationContext) bool {
 		it.at = -1
 	}
 	it.result = val
-	return graph.NextLogOut(it, true)
+	return graph.NextLogOut(ctx, it, true)
 }
 
 func (it *Int64) Err() error {
@@ -156,14 +156,14 @@ func valToInt64(v graph.Value) int64 {
 // Contains() for an Int64 is merely seeing if the passed value is
 // within the range, assuming the value is an int64.
 func (it *Int64) Contains(ctx *graph.IterationContext, tsv graph.Value) bool {
-	graph.ContainsLogIn(it, tsv)
+	graph.ContainsLogIn(ctx, it, tsv)
 	it.runstats.Contains += 1
 	v := valToInt64(tsv)
 	if it.min <= v && v <= it.max {
 		it.result = v
-		return graph.ContainsLogOut(it, it.toValue(v), true)
+		return graph.ContainsLogOut(ctx, it, it.toValue(v), true)
 	}
-	return graph.ContainsLogOut(it, it.toValue(v), false)
+	return graph.ContainsLogOut(ctx, it, it.toValue(v), false)
 }
 
 // The type of this iterator is an "all". This is important, as it puts it in
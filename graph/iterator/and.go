@@ -140,17 +140,17 @@ func (it *And) AddSubIterator(sub graph.Iterator) {
 // this value against the subiterators. A productive choice of primary iterator
 // is therefore very important.
 func (it *And) Next(ctx *graph.IterationContext) bool {
-	graph.NextLogIn(it)
+	graph.NextLogIn(ctx, it)
 	it.runstats.Next += 1
 	for it.primaryIt.Next(ctx) {
 		curr := it.primaryIt.Result()
 		if it.subItsContain(ctx, curr, nil) {
 			it.result = curr
-			return graph.NextLogOut(it, true)
+			return graph.NextLogOut(ctx, it, true)
 		}
 	}
 	it.err = it.primaryIt.Err()
-	return graph.NextLogOut(it, false)
+	return graph.NextLogOut(ctx, it, false)
 }
 
 func (it *And) Err() error {
@@ -211,12 +211,12 @@ func (it *And) checkContainsList(ctx *graph.IterationContext, val graph.Value, l
 	if ok {
 		it.result = val
 	}
-	return graph.ContainsLogOut(it, val, ok)
+	return graph.ContainsLogOut(ctx, it, val, ok)
 }
 
 // Check a value against the entire iterator, in order.
 func (it *And) Contains(ctx *graph.IterationContext, val graph.Value) bool {
-	graph.ContainsLogIn(it, val)
+	graph.ContainsLogIn(ctx, it, val)
 	it.runstats.Contains += 1
 	lastResult := it.result
 	if it.checkList != nil {
@@ -227,13 +227,13 @@ func (it *And) Contains(ctx *graph.IterationContext, val graph.Value) bool {
 		othersGood := it.subItsContain(ctx, val, lastResult)
 		if othersGood {
 			it.result = val
-			return graph.ContainsLogOut(it, val, true)
+			return graph.ContainsLogOut(ctx, it, val, true)
 		}
 	}
 	if lastResult != nil {
 		it.primaryIt.Contains(ctx, lastResult)
 	}
-	return graph.ContainsLogOut(it, val, false)
+	return graph.ContainsLogOut(ctx, it, val, false)
 }
 
 // Returns the approximate size of the And iterator. Because we're dealing
@@ -73,8 +73,9 @@ func (it *Count) SubIterators() []graph.Iterator {
 
 // Next counts a number of results in underlying iterator.
 func (it *Count) Next(ctx *graph.IterationContext) bool {
+	graph.NextLogIn(ctx, it)
 	if it.done {
-		return false
+		return graph.NextLogOut(ctx, it, false)
 	}
 	size, exact := it.it.Size()
 	if !exact {
@@ -85,7 +86,7 @@ func (it *Count) Next(ctx *graph.IterationContext) bool {
 	}
 	it.result = quad.Int(size)
 	it.done = true
-	return true
+	return graph.NextLogOut(ctx, it, true)
 }
 
 func (it *Count) Err() error {
@@ -100,16 +101,17 @@ func (it *Count) Result() graph.Value {
 }
 
 func (it *Count) Contains(ctx *graph.IterationContext, val graph.Value) bool {
+	graph.ContainsLogIn(ctx, it, val)
 	if !it.done {
 		it.Next(ctx)
 	}
 	if v, ok := val.(graph.PreFetchedValue); ok {
-		return v.NameOf() == it.result
+		return graph.ContainsLogOut(ctx, it, val, v.NameOf() == it.result)
 	}
 	if it.qs != nil {
-		return it.qs.NameOf(val) == it.result
+		return graph.ContainsLogOut(ctx, it, val, it.qs.NameOf(val) == it.result)
 	}
-	return false
+	return graph.ContainsLogOut(ctx, it, val, false)
 }
 
 func (it *Count) NextPath(ctx *graph.IterationContext) bool {
@@ -126,26 +126,26 @@ func (it *Fixed) Contains(ctx *graph.IterationContext, v graph.Value) bool {
 	// Could be optimized by keeping it sorted or using a better datastructure.
 	// However, for fixed iterators, which are by definition kind of tiny, this
 	// isn't a big issue.
-	graph.ContainsLogIn(it, v)
+	graph.ContainsLogIn(ctx, it, v)
 	for _, x := range it.values {
 		if it.cmp(x, v) {
 			it.result = x
-			return graph.ContainsLogOut(it, v, true)
+			return graph.ContainsLogOut(ctx, it, v, true)
 		}
 	}
-	return graph.ContainsLogOut(it, v, false)
+	return graph.ContainsLogOut(ctx, it, v, false)
 }
 
 // Next advances the iterator.
 func (it *Fixed) Next(ctx *graph.IterationContext) bool {
-	graph.NextLogIn(it)
+	graph.NextLogIn(ctx, it)
 	if it.lastIndex == len(it.values) {
-		return graph.NextLogOut(it, false)
+		return graph.NextLogOut(ctx, it, false)
 	}
 	out := it.values[it.lastIndex]
 	it.result = out
 	it.lastIndex++
-	return graph.NextLogOut(it, true)
+	return graph.NextLogOut(ctx, it, true)
 }
 
 func (it *Fixed) Err() error {
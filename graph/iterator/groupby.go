@@ -0,0 +1,418 @@
+// Copyright 2017 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package iterator
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/codelingo/cayley/graph"
+	"github.com/codelingo/cayley/quad"
+)
+
+// AggregateOp is a function that is computed over values of a tag in each group of results.
+type AggregateOp string
+
+const (
+	AggregateCount = AggregateOp("count") // number of values
+	AggregateSum   = AggregateOp("sum")   // sum of numbers
+	AggregateMin   = AggregateOp("min")   // minimal number or time
+	AggregateMax   = AggregateOp("max")   // maximal number or time
+	AggregateAvg   = AggregateOp("avg")   // average of numbers, or of times if there are no numbers
+)
+
+// Valid checks if the aggregate function is supported.
+func (op AggregateOp) Valid() bool {
+	switch op {
+	case AggregateCount, AggregateSum, AggregateMin, AggregateMax, AggregateAvg:
+		return true
+	}
+	return false
+}
+
+// Aggregate is a value computed for each group of results.
+type Aggregate struct {
+	Op  AggregateOp
+	Tag string // tag to aggregate; an empty tag aggregates results themselves
+	As  string // tag to store the computed value in
+}
+
+// GroupBy iterator groups results of the subiterator by values of the given tags,
+// and returns one result for each group with computed aggregates.
+//
+// Result of the iterator is the value of the first group tag, or the first aggregate if there are no
+// group tags. Only group tags and aggregates are tagged on results. Results of the subiterator
+// that don't have all of the group tags are ignored; without group tags all results form a single group.
+type GroupBy struct {
+	uid  uint64
+	qs   graph.QuadStore
+	it   graph.Iterator
+	by   []string
+	aggs []Aggregate
+	tags graph.Tagger
+
+	done   bool
+	groups []*group
+	cur    *group
+	index  int
+	err    error
+}
+
+type group struct {
+	vals []graph.Value // values of group tags
+	n    int           // number of results in the group
+	accs []accumulator
+	res  []quad.Value // computed aggregates
+}
+
+// NewGroupBy creates a new iterator that groups results of the subiterator by values of the given tags.
+func NewGroupBy(it graph.Iterator, qs graph.QuadStore, by ...string) *GroupBy {
+	return &GroupBy{
+		uid: NextUID(),
+		it:  it, qs: qs,
+		by: by,
+	}
+}
+
+// AddAggregate adds a value to compute for each group.
+func (it *GroupBy) AddAggregate(agg Aggregate) {
+	it.aggs = append(it.aggs, agg)
+}
+
+func (it *GroupBy) UID() uint64 {
+	return it.uid
+}
+
+func (it *GroupBy) Reset() {
+	it.done = false
+	it.groups, it.cur = nil, nil
+	it.index = 0
+	it.err = nil
+	it.it.Reset()
+}
+
+func (it *GroupBy) Tagger() *graph.Tagger {
+	return &it.tags
+}
+
+func (it *GroupBy) TagResults(dst map[string]graph.Value) {
+	if it.cur == nil {
+		return
+	}
+	for i, tag := range it.by {
+		dst[tag] = it.cur.vals[i]
+	}
+	for i, agg := range it.aggs {
+		if v := it.cur.res[i]; v != nil {
+			dst[agg.As] = fetchedValue{Val: v}
+		}
+	}
+	for _, tag := range it.tags.Tags() {
+		dst[tag] = it.Result()
+	}
+	for tag, value := range it.tags.Fixed() {
+		dst[tag] = value
+	}
+}
+
+func (it *GroupBy) Clone() graph.Iterator {
+	it2 := NewGroupBy(it.it.Clone(), it.qs, it.by...)
+	it2.aggs = append([]Aggregate(nil), it.aggs...)
+	it2.tags.CopyFrom(it)
+	return it2
+}
+
+func (it *GroupBy) SubIterators() []graph.Iterator {
+	return []graph.Iterator{it.it}
+}
+
+func (it *GroupBy) nameOf(v graph.Value) quad.Value {
+	if pv, ok := v.(graph.PreFetchedValue); ok {
+		return pv.NameOf()
+	}
+	return it.qs.NameOf(v)
+}
+
+// groupKey is a map key of a group; it is nested for each group tag.
+type groupKey struct {
+	prev interface{}
+	val  interface{}
+}
+
+// load reads all results of the subiterator and computes aggregates for each group.
+func (it *GroupBy) load(ctx *graph.IterationContext) {
+	it.done = true
+	for _, agg := range it.aggs {
+		if !agg.Op.Valid() {
+			it.err = fmt.Errorf("unknown aggregate function: %q", agg.Op)
+			return
+		}
+	}
+	index := make(map[interface{}]*group)
+	add := func() {
+		tags := make(map[string]graph.Value)
+		it.it.TagResults(tags)
+		var key interface{}
+		vals := make([]graph.Value, len(it.by))
+		for i, tag := range it.by {
+			v, ok := tags[tag]
+			if !ok || v == nil {
+				return
+			}
+			vals[i] = v
+			key = groupKey{prev: key, val: graph.ToKey(v)}
+		}
+		g := index[key]
+		if g == nil {
+			g = &group{vals: vals, accs: make([]accumulator, len(it.aggs))}
+			index[key] = g
+			it.groups = append(it.groups, g)
+		}
+		g.n++
+		for i, agg := range it.aggs {
+			v := it.it.Result()
+			if agg.Tag != "" {
+				v = tags[agg.Tag]
+			}
+			if v != nil {
+				g.accs[i].add(it.nameOf(v))
+			}
+		}
+	}
+	for it.it.Next(ctx) {
+		add()
+		for it.it.NextPath(ctx) {
+			add()
+		}
+	}
+	if it.err = it.it.Err(); it.err != nil {
+		return
+	}
+	if len(it.by) == 0 && len(it.groups) == 0 {
+		// aggregates over an empty set are still returned
+		it.groups = append(it.groups, &group{accs: make([]accumulator, len(it.aggs))})
+	}
+	groups := it.groups[:0]
+	for _, g := range it.groups {
+		g.res = make([]quad.Value, len(it.aggs))
+		for i, agg := range it.aggs {
+			g.res[i] = g.accs[i].value(agg.Op)
+		}
+		g.accs = nil
+		if it.groupResult(g) != nil {
+			groups = append(groups, g)
+		}
+	}
+	it.groups = groups
+}
+
+func (it *GroupBy) groupResult(g *group) graph.Value {
+	if len(it.by) != 0 {
+		return g.vals[0]
+	}
+	if len(it.aggs) != 0 {
+		if g.res[0] == nil {
+			return nil
+		}
+		return fetchedValue{Val: g.res[0]}
+	}
+	return fetchedValue{Val: quad.Int(g.n)}
+}
+
+// Next returns the next group of results.
+func (it *GroupBy) Next(ctx *graph.IterationContext) bool {
+	graph.NextLogIn(ctx, it)
+	if !it.done {
+		it.load(ctx)
+	}
+	if it.err != nil || it.index >= len(it.groups) {
+		it.cur = nil
+		return graph.NextLogOut(ctx, it, false)
+	}
+	it.cur = it.groups[it.index]
+	it.index++
+	return graph.NextLogOut(ctx, it, true)
+}
+
+func (it *GroupBy) Err() error {
+	return it.err
+}
+
+func (it *GroupBy) Result() graph.Value {
+	if it.cur == nil {
+		return nil
+	}
+	return it.groupResult(it.cur)
+}
+
+func (it *GroupBy) Contains(ctx *graph.IterationContext, val graph.Value) bool {
+	graph.ContainsLogIn(ctx, it, val)
+	if !it.done {
+		it.load(ctx)
+	}
+	name := it.nameOf(val)
+	for _, g := range it.groups {
+		if it.nameOf(it.groupResult(g)) == name {
+			it.cur = g
+			return graph.ContainsLogOut(ctx, it, val, true)
+		}
+	}
+	it.cur = nil
+	return graph.ContainsLogOut(ctx, it, val, false)
+}
+
+func (it *GroupBy) NextPath(ctx *graph.IterationContext) bool {
+	return false
+}
+
+func (it *GroupBy) Close() error {
+	return it.it.Close()
+}
+
+func (it *GroupBy) Type() graph.Type { return graph.GroupBy }
+
+func (it *GroupBy) Optimize() (graph.Iterator, bool) {
+	sub, optimized := it.it.Optimize()
+	it.it = sub
+	return it, optimized
+}
+
+// Stats assumes that results are split into a fixed number of groups.
+func (it *GroupBy) Stats() graph.IteratorStats {
+	const perGroup = 10
+	sub := it.it.Stats()
+	size := sub.Size / perGroup
+	if len(it.by) == 0 || size < 1 {
+		size = 1
+	}
+	return graph.IteratorStats{
+		NextCost:     sub.NextCost * perGroup,
+		ContainsCost: sub.NextCost * sub.Size,
+		Size:         size,
+		ExactSize:    len(it.by) == 0 && len(it.aggs) == 0,
+	}
+}
+
+func (it *GroupBy) Size() (int64, bool) {
+	st := it.Stats()
+	return st.Size, st.ExactSize
+}
+
+func (it *GroupBy) Describe() graph.Description {
+	return graph.Description{
+		UID:       it.UID(),
+		Type:      it.Type(),
+		Tags:      it.tags.Tags(),
+		Iterators: []graph.Description{it.it.Describe()},
+	}
+}
+
+var _ graph.Iterator = &GroupBy{}
+
+// accumulator collects values of a single aggregate in a group.
+type accumulator struct {
+	n      int // number of values of any type
+	nums   int // number of numeric values
+	floats bool
+	isum   int64
+	fsum   float64
+
+	times int
+	t0    time.Time
+	tsum  time.Duration // sum of differences from t0
+
+	min, max quad.Value
+}
+
+// less compares two numbers or times. It returns false in the second value if they are not comparable.
+func less(a, b quad.Value) (bool, bool) {
+	switch a := a.(type) {
+	case quad.Int:
+		switch b := b.(type) {
+		case quad.Int:
+			return a < b, true
+		case quad.Float:
+			return quad.Float(a) < b, true
+		}
+	case quad.Float:
+		switch b := b.(type) {
+		case quad.Int:
+			return a < quad.Float(b), true
+		case quad.Float:
+			return a < b, true
+		}
+	case quad.Time:
+		if b, ok := b.(quad.Time); ok {
+			return time.Time(a).Before(time.Time(b)), true
+		}
+	}
+	return false, false
+}
+
+func (a *accumulator) add(v quad.Value) {
+	a.n++
+	switch v := v.(type) {
+	case quad.Int:
+		a.nums++
+		a.isum += int64(v)
+		a.fsum += float64(v)
+	case quad.Float:
+		a.nums++
+		a.floats = true
+		a.fsum += float64(v)
+	case quad.Time:
+		if a.times == 0 {
+			a.t0 = time.Time(v)
+		}
+		a.times++
+		a.tsum += time.Time(v).Sub(a.t0)
+	default:
+		return
+	}
+	if a.min == nil {
+		a.min, a.max = v, v
+		return
+	}
+	if l, ok := less(v, a.min); ok && l {
+		a.min = v
+	}
+	if l, ok := less(a.max, v); ok && l {
+		a.max = v
+	}
+}
+
+// value returns a computed aggregate, or nil if there were no suitable values.
+func (a *accumulator) value(op AggregateOp) quad.Value {
+	switch op {
+	case AggregateCount:
+		return quad.Int(a.n)
+	case AggregateSum:
+		if a.floats {
+			return quad.Float(a.fsum)
+		}
+		return quad.Int(a.isum)
+	case AggregateMin:
+		return a.min
+	case AggregateMax:
+		return a.max
+	case AggregateAvg:
+		if a.nums != 0 {
+			return quad.Float(a.fsum / float64(a.nums))
+		} else if a.times != 0 {
+			return quad.Time(a.t0.Add(a.tsum / time.Duration(a.times)))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+// Copyright 2017 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package iterator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/codelingo/cayley/graph"
+	"github.com/codelingo/cayley/quad"
+)
+
+// tagRows is a fixed iterator that tags each result with a given set of values.
+type tagRows struct {
+	*Fixed
+	rows []map[string]graph.Value
+}
+
+func newTagRows(rows ...map[string]graph.Value) *tagRows {
+	it := &tagRows{Fixed: NewFixed(Identity), rows: rows}
+	for i := range rows {
+		it.Add(quad.Int(i))
+	}
+	return it
+}
+
+func (it *tagRows) TagResults(dst map[string]graph.Value) {
+	for k, v := range it.rows[it.lastIndex-1] {
+		dst[k] = v
+	}
+}
+
+func testRows() *tagRows {
+	day := func(d int) quad.Value {
+		return quad.Time(time.Date(2017, 1, d, 0, 0, 0, 0, time.UTC))
+	}
+	return newTagRows(
+		map[string]graph.Value{"customer": quad.IRI("alice"), "amount": quad.Int(10), "date": day(1)},
+		map[string]graph.Value{"customer": quad.IRI("bob"), "amount": quad.Float(2.5), "date": day(2)},
+		map[string]graph.Value{"customer": quad.IRI("alice"), "amount": quad.Int(20), "date": day(5)},
+		map[string]graph.Value{"customer": quad.IRI("bob"), "date": day(4)},
+		map[string]graph.Value{"amount": quad.Int(100)},
+	)
+}
+
+func TestGroupBy(t *testing.T) {
+	qs := &store{}
+	it := NewGroupBy(testRows(), qs, "customer")
+	for _, agg := range []Aggregate{
+		{Op: AggregateCount, As: "count"},
+		{Op: AggregateSum, Tag: "amount", As: "sum"},
+		{Op: AggregateAvg, Tag: "amount", As: "avg"},
+		{Op: AggregateMax, Tag: "amount", As: "max"},
+		{Op: AggregateMin, Tag: "date", As: "first"},
+		{Op: AggregateAvg, Tag: "date", As: "mid"},
+	} {
+		it.AddAggregate(agg)
+	}
+	var got []map[string]graph.Value
+	for it.Next(nil) {
+		tags := make(map[string]graph.Value)
+		it.TagResults(tags)
+		require.Equal(t, tags["customer"], it.Result())
+		got = append(got, tags)
+	}
+	require.NoError(t, it.Err())
+	day := func(d, h int) graph.Value {
+		return fetchedValue{Val: quad.Time(time.Date(2017, 1, d, h, 0, 0, 0, time.UTC))}
+	}
+	require.Equal(t, []map[string]graph.Value{
+		{
+			"customer": quad.IRI("alice"),
+			"count":    fetchedValue{Val: quad.Int(2)},
+			"sum":      fetchedValue{Val: quad.Int(30)},
+			"avg":      fetchedValue{Val: quad.Float(15)},
+			"max":      fetchedValue{Val: quad.Int(20)},
+			"first":    day(1, 0),
+			"mid":      day(3, 0),
+		},
+		{
+			"customer": quad.IRI("bob"),
+			"count":    fetchedValue{Val: quad.Int(2)},
+			"sum":      fetchedValue{Val: quad.Float(2.5)},
+			"avg":      fetchedValue{Val: quad.Float(2.5)},
+			"max":      fetchedValue{Val: quad.Float(2.5)},
+			"first":    day(2, 0),
+			"mid":      day(3, 0),
+		},
+	}, got)
+
+	require.True(t, it.Contains(nil, quad.IRI("bob")))
+	tags := make(map[string]graph.Value)
+	it.TagResults(tags)
+	require.Equal(t, fetchedValue{Val: quad.Float(2.5)}, tags["sum"])
+	require.False(t, it.Contains(nil, quad.IRI("charlie")))
+}
+
+func TestAggregate(t *testing.T) {
+	qs := &store{}
+	it := NewGroupBy(testRows(), qs)
+	it.AddAggregate(Aggregate{Op: AggregateSum, Tag: "amount", As: "total"})
+	it.Tagger().Add("result")
+	require.True(t, it.Next(nil))
+	require.Equal(t, fetchedValue{Val: quad.Float(132.5)}, it.Result())
+	tags := make(map[string]graph.Value)
+	it.TagResults(tags)
+	require.Equal(t, map[string]graph.Value{
+		"total":  fetchedValue{Val: quad.Float(132.5)},
+		"result": fetchedValue{Val: quad.Float(132.5)},
+	}, tags)
+	require.False(t, it.Next(nil))
+
+	// no values to compare
+	it = NewGroupBy(newTagRows(), qs)
+	it.AddAggregate(Aggregate{Op: AggregateMax, As: "max"})
+	require.False(t, it.Next(nil))
+	require.NoError(t, it.Err())
+
+	it = NewGroupBy(newTagRows(), qs)
+	it.AddAggregate(Aggregate{Op: "median", As: "median"})
+	require.False(t, it.Next(nil))
+	require.Error(t, it.Err())
+}
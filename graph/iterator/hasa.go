@@ -144,7 +144,7 @@ func (it *HasA) Describe() graph.Description {
 // iterator of "quads that have `val` in our direction", given to us by the quad store,
 // and then Next() values out of that iterator and Contains() them against our subiterator.
 func (it *HasA) Contains(ctx *graph.IterationContext, val graph.Value) bool {
-	graph.ContainsLogIn(it, val)
+	graph.ContainsLogIn(ctx, it, val)
 	it.runstats.Contains += 1
 	if clog.V(4) {
 		clog.Infof("Id is %v", it.qs.NameOf(val))
@@ -158,7 +158,7 @@ func (it *HasA) Contains(ctx *graph.IterationContext, val graph.Value) bool {
 	if it.err != nil {
 		return false
 	}
-	return graph.ContainsLogOut(it, val, ok)
+	return graph.ContainsLogOut(ctx, it, val, ok)
 }
 
 // NextContains() is shared code between Contains() and GetNextResult() -- calls next on the
@@ -213,7 +213,7 @@ func (it *HasA) NextPath(ctx *graph.IterationContext) bool {
 // subiterator we can get a value from, and we can take that resultant quad,
 // pull our direction out of it, and return that.
 func (it *HasA) Next(ctx *graph.IterationContext) bool {
-	graph.NextLogIn(it)
+	graph.NextLogIn(ctx, it)
 	it.runstats.Next += 1
 	if it.resultIt != nil {
 		it.resultIt.Close()
@@ -222,12 +222,12 @@ func (it *HasA) Next(ctx *graph.IterationContext) bool {
 
 	if !it.primaryIt.Next(ctx) {
 		it.err = it.primaryIt.Err()
-		return graph.NextLogOut(it, false)
+		return graph.NextLogOut(ctx, it, false)
 	}
 	tID := it.primaryIt.Result()
 	val := it.qs.QuadDirection(tID, it.dir)
 	it.result = val
-	return graph.NextLogOut(it, true)
+	return graph.NextLogOut(ctx, it, true)
 }
 
 func (it *HasA) Err() error {
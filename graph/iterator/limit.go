@@ -21,6 +21,11 @@ func NewLimit(primaryIt graph.Iterator, limit int64) *Limit {
 	}
 }
 
+// Limit returns the maximal number of values returned by the iterator.
+func (it *Limit) Limit() int64 {
+	return it.limit
+}
+
 func (it *Limit) UID() uint64 {
 	return it.uid
 }
@@ -50,15 +55,15 @@ func (it *Limit) SubIterators() []graph.Iterator {
 
 // Next advances the Limit iterator. It will stop iteration if limit was reached.
 func (it *Limit) Next(ctx *graph.IterationContext) bool {
-	graph.NextLogIn(it)
+	graph.NextLogIn(ctx, it)
 	if it.limit > 0 && it.count >= it.limit {
-		return graph.NextLogOut(it, false)
+		return graph.NextLogOut(ctx, it, false)
 	}
 	if it.primaryIt.Next(ctx) {
 		it.count++
-		return graph.NextLogOut(it, true)
+		return graph.NextLogOut(ctx, it, true)
 	}
-	return graph.NextLogOut(it, false)
+	return graph.NextLogOut(ctx, it, false)
 }
 
 func (it *Limit) Err() error {
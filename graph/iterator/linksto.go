@@ -112,15 +112,15 @@ func (it *LinksTo) Describe() graph.Description {
 // If it checks in the right direction for the subiterator, it is a valid link
 // for the LinksTo.
 func (it *LinksTo) Contains(ctx *graph.IterationContext, val graph.Value) bool {
-	graph.ContainsLogIn(it, val)
+	graph.ContainsLogIn(ctx, it, val)
 	it.runstats.Contains += 1
 	node := it.qs.QuadDirection(val, it.dir)
 	if it.primaryIt.Contains(ctx, node) {
 		it.result = val
-		return graph.ContainsLogOut(it, val, true)
+		return graph.ContainsLogOut(ctx, it, val, true)
 	}
 	it.err = it.primaryIt.Err()
-	return graph.ContainsLogOut(it, val, false)
+	return graph.ContainsLogOut(ctx, it, val, false)
 }
 
 // Return a list containing only our subiterator.
@@ -151,12 +151,12 @@ func (it *LinksTo) Optimize() (graph.Iterator, bool) {
 
 // Next()ing a LinksTo operates as described above.
 func (it *LinksTo) Next(ctx *graph.IterationContext) bool {
-	graph.NextLogIn(it)
+	graph.NextLogIn(ctx, it)
 	it.runstats.Next += 1
 	if it.nextIt.Next(ctx) {
 		it.runstats.ContainsNext += 1
 		it.result = it.nextIt.Result()
-		return graph.NextLogOut(it, true)
+		return graph.NextLogOut(ctx, it, true)
 	}
 
 	// If there's an error in the 'next' iterator, we save it and we're done.
@@ -171,7 +171,7 @@ func (it *LinksTo) Next(ctx *graph.IterationContext) bool {
 		it.err = it.primaryIt.Err()
 
 		// We're out of nodes in our subiterator, so we're done as well.
-		return graph.NextLogOut(it, false)
+		return graph.NextLogOut(ctx, it, false)
 	}
 	it.nextIt.Close()
 	it.nextIt = it.qs.QuadIterator(it.dir, it.primaryIt.Result())
@@ -186,7 +186,7 @@ func (it *Materialize) Stats() graph.IteratorStats {
 }
 
 func (it *Materialize) Next(ctx *graph.IterationContext) bool {
-	graph.NextLogIn(it)
+	graph.NextLogIn(ctx, it)
 	it.runstats.Next += 1
 	if !it.hasRun {
 		it.materializeSet(ctx)
@@ -203,9 +203,9 @@ func (it *Materialize) Next(ctx *graph.IterationContext) bool {
 	it.index++
 	it.subindex = 0
 	if it.index >= len(it.values) {
-		return graph.NextLogOut(it, false)
+		return graph.NextLogOut(ctx, it, false)
 	}
-	return graph.NextLogOut(it, true)
+	return graph.NextLogOut(ctx, it, true)
 }
 
 func (it *Materialize) Err() error {
@@ -213,7 +213,7 @@ func (it *Materialize) Err() error {
 }
 
 func (it *Materialize) Contains(ctx *graph.IterationContext, v graph.Value) bool {
-	graph.ContainsLogIn(it, v)
+	graph.ContainsLogIn(ctx, it, v)
 	it.runstats.Contains += 1
 	if !it.hasRun {
 		it.materializeSet(ctx)
@@ -228,9 +228,9 @@ func (it *Materialize) Contains(ctx *graph.IterationContext, v graph.Value) bool
 	if i, ok := it.containsMap[key]; ok {
 		it.index = i
 		it.subindex = 0
-		return graph.ContainsLogOut(it, v, true)
+		return graph.ContainsLogOut(ctx, it, v, true)
 	}
-	return graph.ContainsLogOut(it, v, false)
+	return graph.ContainsLogOut(ctx, it, v, false)
 }
 
 func (it *Materialize) NextPath(ctx *graph.IterationContext) bool {
@@ -70,18 +70,18 @@ func (it *Not) SubIterators() []graph.Iterator {
 // new value. It fetches the next value of the all iterator which is not
 // contained by the primary iterator.
 func (it *Not) Next(ctx *graph.IterationContext) bool {
-	graph.NextLogIn(it)
+	graph.NextLogIn(ctx, it)
 	it.runstats.Next += 1
 
 	for it.allIt.Next(ctx) {
 		if curr := it.allIt.Result(); !it.primaryIt.Contains(ctx, curr) {
 			it.result = curr
 			it.runstats.ContainsNext += 1
-			return graph.NextLogOut(it, true)
+			return graph.NextLogOut(ctx, it, true)
 		}
 	}
 	it.err = it.allIt.Err()
-	return graph.NextLogOut(it, false)
+	return graph.NextLogOut(ctx, it, false)
 }
 
 func (it *Not) Err() error {
@@ -96,11 +96,11 @@ func (it *Not) Result() graph.Value {
 // complement. For a valid value, it updates the Result returned by the iterator
 // to the value itself.
 func (it *Not) Contains(ctx *graph.IterationContext, val graph.Value) bool {
-	graph.ContainsLogIn(it, val)
+	graph.ContainsLogIn(ctx, it, val)
 	it.runstats.Contains += 1
 
 	if it.primaryIt.Contains(ctx, val) {
-		return graph.ContainsLogOut(it, val, false)
+		return graph.ContainsLogOut(ctx, it, val, false)
 	}
 
 	it.err = it.primaryIt.Err()
@@ -110,7 +110,7 @@ func (it *Not) Contains(ctx *graph.IterationContext, val graph.Value) bool {
 	}
 
 	it.result = val
-	return graph.ContainsLogOut(it, val, true)
+	return graph.ContainsLogOut(ctx, it, val, true)
 }
 
 // NextPath checks whether there is another path. Not applicable, hence it will
@@ -112,11 +112,12 @@ func (it *Optional) SubIterators() []graph.Iterator {
 // of whether the subiterator matched. But we keep track of whether the subiterator
 // matched for results purposes.
 func (it *Optional) Contains(ctx *graph.IterationContext, val graph.Value) bool {
+	graph.ContainsLogIn(ctx, it, val)
 	checked := it.subIt.Contains(ctx, val)
 	it.lastCheck = checked
 	it.err = it.subIt.Err()
 	it.result = val
-	return true
+	return graph.ContainsLogOut(ctx, it, val, true)
 }
 
 // If we failed the check, then the subiterator should not contribute to the result
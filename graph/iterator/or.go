@@ -129,7 +129,7 @@ func (it *Or) AddSubIterator(sub graph.Iterator) {
 // subiterators, it must produce from all subiterators -- unless it it
 // shortcircuiting, in which case, it is the first one that returns anything.
 func (it *Or) Next(ctx *graph.IterationContext) bool {
-	graph.NextLogIn(it)
+	graph.NextLogIn(ctx, it)
 	var first bool
 	for {
 		if it.currentIterator == -1 {
@@ -140,12 +140,12 @@ func (it *Or) Next(ctx *graph.IterationContext) bool {
 
 		if curIt.Next(ctx) {
 			it.result = curIt.Result()
-			return graph.NextLogOut(it, true)
+			return graph.NextLogOut(ctx, it, true)
 		}
 
 		it.err = curIt.Err()
 		if it.err != nil {
-			return graph.NextLogOut(it, false)
+			return graph.NextLogOut(ctx, it, false)
 		}
 
 		if it.isShortCircuiting && !first {
@@ -157,7 +157,7 @@ func (it *Or) Next(ctx *graph.IterationContext) bool {
 		}
 	}
 
-	return graph.NextLogOut(it, false)
+	return graph.NextLogOut(ctx, it, false)
 }
 
 func (it *Or) Err() error {
@@ -188,16 +188,16 @@ func (it *Or) subItsContain(ctx *graph.IterationContext, val graph.Value) (bool,
 
 // Check a value against the entire graph.iterator, in order.
 func (it *Or) Contains(ctx *graph.IterationContext, val graph.Value) bool {
-	graph.ContainsLogIn(it, val)
+	graph.ContainsLogIn(ctx, it, val)
 	anyGood, err := it.subItsContain(ctx, val)
 	if err != nil {
 		it.err = err
 		return false
 	} else if !anyGood {
-		return graph.ContainsLogOut(it, val, false)
+		return graph.ContainsLogOut(ctx, it, val, false)
 	}
 	it.result = val
-	return graph.ContainsLogOut(it, val, true)
+	return graph.ContainsLogOut(ctx, it, val, true)
 }
 
 // Returns the approximate size of the Or graph.iterator. Because we're dealing
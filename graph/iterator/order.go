@@ -0,0 +1,576 @@
+// Copyright 2017 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package iterator
+
+import (
+	"bufio"
+	"container/heap"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/codelingo/cayley/graph"
+	"github.com/codelingo/cayley/quad"
+	"github.com/codelingo/cayley/quad/pquads"
+)
+
+// OrderSpillSize is the number of results the Order iterator sorts in memory.
+// Larger result sets are sorted in chunks of this size that are spilled to temporary files and merged.
+var OrderSpillSize = 100000
+
+// OrderKey is a value to sort results by.
+type OrderKey struct {
+	Tag  string // tag with a value to sort by; an empty tag sorts by results themselves
+	Desc bool   // sort in descending order
+}
+
+// Order iterator sorts results of the subiterator by values of the given keys.
+//
+// Values are compared by type: numbers are ordered before times, times before strings, and strings
+// before other values (IRIs, blank nodes, etc). Results without a value for the key are always ordered last.
+// Results with equal keys are ordered by their own values, so the order is the same across backends.
+// Equal results that end up next to each other are returned once, with the rest of them as paths.
+type Order struct {
+	uid  uint64
+	qs   graph.QuadStore
+	it   graph.Iterator
+	keys []OrderKey
+	tags graph.Tagger
+
+	done     bool
+	contains bool
+	rows     []orderRow
+	index    int
+	files    []*os.File
+	merge    orderMerge
+	cur      *orderRow
+	err      error
+}
+
+type orderRow struct {
+	res  graph.Value
+	name quad.Value // value of the result
+	tags map[string]graph.Value
+	keys []quad.Value // values of the order keys
+}
+
+// NewOrder creates a new iterator that sorts results of the subiterator by values of the given keys.
+func NewOrder(it graph.Iterator, qs graph.QuadStore, keys ...OrderKey) *Order {
+	if len(keys) == 0 {
+		keys = []OrderKey{{}}
+	}
+	return &Order{
+		uid: NextUID(),
+		it:  it, qs: qs,
+		keys: keys,
+	}
+}
+
+func (it *Order) UID() uint64 {
+	return it.uid
+}
+
+func (it *Order) Reset() {
+	it.cleanup()
+	it.done, it.contains = false, false
+	it.rows, it.cur = nil, nil
+	it.index = 0
+	it.err = nil
+	it.it.Reset()
+}
+
+func (it *Order) Tagger() *graph.Tagger {
+	return &it.tags
+}
+
+func (it *Order) TagResults(dst map[string]graph.Value) {
+	if it.contains {
+		it.it.TagResults(dst)
+	} else if it.cur != nil {
+		for k, v := range it.cur.tags {
+			dst[k] = v
+		}
+	}
+	for _, tag := range it.tags.Tags() {
+		dst[tag] = it.Result()
+	}
+	for tag, value := range it.tags.Fixed() {
+		dst[tag] = value
+	}
+}
+
+func (it *Order) Clone() graph.Iterator {
+	it2 := NewOrder(it.it.Clone(), it.qs, it.keys...)
+	it2.tags.CopyFrom(it)
+	return it2
+}
+
+func (it *Order) SubIterators() []graph.Iterator {
+	return []graph.Iterator{it.it}
+}
+
+func (it *Order) nameOf(v graph.Value) quad.Value {
+	if v == nil {
+		return nil
+	}
+	if pv, ok := v.(graph.PreFetchedValue); ok {
+		return pv.NameOf()
+	}
+	return it.qs.NameOf(v)
+}
+
+func (it *Order) valueOf(v quad.Value) graph.Value {
+	if v == nil {
+		return nil
+	}
+	if gv := it.qs.ValueOf(v); gv != nil {
+		return gv
+	}
+	return fetchedValue{Val: v}
+}
+
+func (it *Order) makeRow(res graph.Value, name quad.Value, tags map[string]graph.Value, names map[string]quad.Value) orderRow {
+	r := orderRow{res: res, name: name, tags: tags, keys: make([]quad.Value, len(it.keys))}
+	for i, k := range it.keys {
+		if k.Tag == "" {
+			r.keys[i] = name
+		} else if names != nil {
+			r.keys[i] = names[k.Tag]
+		} else {
+			r.keys[i] = it.nameOf(tags[k.Tag])
+		}
+	}
+	return r
+}
+
+// compare compares two rows by order keys, and then by their results.
+func (it *Order) compare(a, b *orderRow) int {
+	for i, k := range it.keys {
+		va, vb := a.keys[i], b.keys[i]
+		if c := compareNil(va, vb); c != 0 {
+			return c
+		} else if va == nil {
+			continue
+		}
+		c := compareValues(va, vb)
+		if k.Desc {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	if c := compareNil(a.name, b.name); c != 0 || a.name == nil {
+		return c
+	}
+	return compareValues(a.name, b.name)
+}
+
+type orderRows struct {
+	it   *Order
+	rows []orderRow
+}
+
+func (r orderRows) Len() int           { return len(r.rows) }
+func (r orderRows) Less(i, j int) bool { return r.it.compare(&r.rows[i], &r.rows[j]) < 0 }
+func (r orderRows) Swap(i, j int)      { r.rows[i], r.rows[j] = r.rows[j], r.rows[i] }
+
+// load reads all results of the subiterator and sorts them.
+func (it *Order) load(ctx *graph.IterationContext) {
+	it.done = true
+	add := func() {
+		res := it.it.Result()
+		tags := make(map[string]graph.Value)
+		it.it.TagResults(tags)
+		it.rows = append(it.rows, it.makeRow(res, it.nameOf(res), tags, nil))
+		if len(it.rows) >= OrderSpillSize && it.err == nil {
+			it.err = it.spill()
+		}
+	}
+	for it.err == nil && it.it.Next(ctx) {
+		add()
+		for it.err == nil && it.it.NextPath(ctx) {
+			add()
+		}
+	}
+	if it.err == nil {
+		it.err = it.it.Err()
+	}
+	if it.err != nil {
+		return
+	}
+	if len(it.files) == 0 {
+		sort.Stable(orderRows{it: it, rows: it.rows})
+		return
+	}
+	if len(it.rows) != 0 {
+		if it.err = it.spill(); it.err != nil {
+			return
+		}
+	}
+	it.merge.it = it
+	for i, f := range it.files {
+		r := &orderReader{it: it, r: bufio.NewReader(f), index: i}
+		if r.next() {
+			it.merge.rs = append(it.merge.rs, r)
+		} else if r.err != nil {
+			it.err = r.err
+			return
+		}
+	}
+	heap.Init(&it.merge)
+}
+
+// spill sorts buffered rows and writes them to a temporary file.
+func (it *Order) spill() error {
+	sort.Stable(orderRows{it: it, rows: it.rows})
+	f, err := ioutil.TempFile("", "cayley-order-")
+	if err != nil {
+		return err
+	}
+	it.files = append(it.files, f)
+	w := bufio.NewWriter(f)
+	for _, r := range it.rows {
+		if err = it.writeRow(w, &r); err != nil {
+			return err
+		}
+	}
+	if err = w.Flush(); err != nil {
+		return err
+	}
+	it.rows = nil
+	_, err = f.Seek(0, 0)
+	return err
+}
+
+func writeBytes(w *bufio.Writer, p []byte) error {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], uint64(len(p)))
+	if _, err := w.Write(buf[:n]); err != nil {
+		return err
+	}
+	_, err := w.Write(p)
+	return err
+}
+
+func writeValue(w *bufio.Writer, v quad.Value) error {
+	p, err := pquads.MarshalValue(v)
+	if err != nil {
+		return err
+	}
+	return writeBytes(w, p)
+}
+
+func (it *Order) writeRow(w *bufio.Writer, r *orderRow) error {
+	if err := writeValue(w, r.name); err != nil {
+		return err
+	}
+	names := make(map[string]quad.Value, len(r.tags))
+	for k, v := range r.tags {
+		if name := it.nameOf(v); name != nil {
+			names[k] = name
+		}
+	}
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], uint64(len(names)))
+	if _, err := w.Write(buf[:n]); err != nil {
+		return err
+	}
+	for k, v := range names {
+		if err := writeBytes(w, []byte(k)); err != nil {
+			return err
+		}
+		if err := writeValue(w, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// orderReader reads sorted rows spilled to a file.
+type orderReader struct {
+	it    *Order
+	r     *bufio.Reader
+	index int // index of the file; used to keep the sort stable
+	row   orderRow
+	err   error
+}
+
+func (r *orderReader) readBytes() ([]byte, error) {
+	n, err := binary.ReadUvarint(r.r)
+	if err != nil {
+		return nil, err
+	}
+	p := make([]byte, n)
+	_, err = io.ReadFull(r.r, p)
+	return p, err
+}
+
+func (r *orderReader) readValue() (quad.Value, error) {
+	p, err := r.readBytes()
+	if err != nil {
+		return nil, err
+	}
+	return pquads.UnmarshalValue(p)
+}
+
+// next reads the next row. It returns false at the end of the file or on error.
+func (r *orderReader) next() bool {
+	name, err := r.readValue()
+	if err == io.EOF {
+		return false
+	} else if err != nil {
+		r.err = err
+		return false
+	}
+	n, err := binary.ReadUvarint(r.r)
+	if err != nil {
+		r.err = err
+		return false
+	}
+	tags := make(map[string]graph.Value, n)
+	names := make(map[string]quad.Value, n)
+	for i := uint64(0); i < n; i++ {
+		k, err := r.readBytes()
+		if err != nil {
+			r.err = err
+			return false
+		}
+		v, err := r.readValue()
+		if err != nil {
+			r.err = err
+			return false
+		}
+		tags[string(k)] = r.it.valueOf(v)
+		names[string(k)] = v
+	}
+	r.row = r.it.makeRow(r.it.valueOf(name), name, tags, names)
+	return true
+}
+
+// orderMerge is a heap of spilled files ordered by their current rows.
+type orderMerge struct {
+	it *Order
+	rs []*orderReader
+}
+
+func (m orderMerge) Len() int { return len(m.rs) }
+func (m orderMerge) Less(i, j int) bool {
+	if c := m.it.compare(&m.rs[i].row, &m.rs[j].row); c != 0 {
+		return c < 0
+	}
+	return m.rs[i].index < m.rs[j].index
+}
+func (m orderMerge) Swap(i, j int)       { m.rs[i], m.rs[j] = m.rs[j], m.rs[i] }
+func (m *orderMerge) Push(x interface{}) { m.rs = append(m.rs, x.(*orderReader)) }
+func (m *orderMerge) Pop() interface{} {
+	r := m.rs[len(m.rs)-1]
+	m.rs = m.rs[:len(m.rs)-1]
+	return r
+}
+
+// peek returns the next sorted row without advancing the iterator.
+func (it *Order) peek() *orderRow {
+	if len(it.files) != 0 {
+		if len(it.merge.rs) == 0 {
+			return nil
+		}
+		return &it.merge.rs[0].row
+	} else if it.index < len(it.rows) {
+		return &it.rows[it.index]
+	}
+	return nil
+}
+
+// pop advances the iterator to the next sorted row.
+func (it *Order) pop() {
+	if len(it.files) == 0 {
+		it.cur = &it.rows[it.index]
+		it.index++
+		return
+	}
+	r := it.merge.rs[0]
+	row := r.row
+	it.cur = &row
+	if r.next() {
+		heap.Fix(&it.merge, 0)
+	} else {
+		heap.Pop(&it.merge)
+		if r.err != nil {
+			it.err = r.err
+		}
+	}
+}
+
+// Next returns the next result in the sorted order.
+func (it *Order) Next(ctx *graph.IterationContext) bool {
+	graph.NextLogIn(ctx, it)
+	if !it.done {
+		it.load(ctx)
+	}
+	it.contains = false
+	for it.err == nil {
+		r := it.peek()
+		if r == nil {
+			break
+		}
+		// skip remaining paths of the previous result
+		prev := it.cur
+		it.pop()
+		if prev != nil && graph.ToKey(prev.res) == graph.ToKey(it.cur.res) {
+			continue
+		}
+		return graph.NextLogOut(ctx, it, true)
+	}
+	it.cur = nil
+	return graph.NextLogOut(ctx, it, false)
+}
+
+func (it *Order) Err() error {
+	return it.err
+}
+
+func (it *Order) Result() graph.Value {
+	if it.contains {
+		return it.it.Result()
+	} else if it.cur == nil {
+		return nil
+	}
+	return it.cur.res
+}
+
+// Contains checks the value with the subiterator; ordering does not change the set of results.
+func (it *Order) Contains(ctx *graph.IterationContext, val graph.Value) bool {
+	graph.ContainsLogIn(ctx, it, val)
+	it.contains = true
+	return graph.ContainsLogOut(ctx, it, val, it.it.Contains(ctx, val))
+}
+
+// NextPath returns the next row of the current result. Rows of the same result are returned
+// as paths only if they follow each other in the sorted order.
+func (it *Order) NextPath(ctx *graph.IterationContext) bool {
+	if it.contains {
+		return it.it.NextPath(ctx)
+	} else if it.cur == nil || it.err != nil {
+		return false
+	}
+	r := it.peek()
+	if r == nil || graph.ToKey(r.res) != graph.ToKey(it.cur.res) {
+		return false
+	}
+	it.pop()
+	return it.err == nil
+}
+
+func (it *Order) cleanup() {
+	for _, f := range it.files {
+		f.Close()
+		os.Remove(f.Name())
+	}
+	it.files, it.merge.rs = nil, nil
+}
+
+func (it *Order) Close() error {
+	it.cleanup()
+	return it.it.Close()
+}
+
+func (it *Order) Type() graph.Type { return graph.Order }
+
+func (it *Order) Optimize() (graph.Iterator, bool) {
+	sub, optimized := it.it.Optimize()
+	it.it = sub
+	return it, optimized
+}
+
+func (it *Order) Stats() graph.IteratorStats {
+	return it.it.Stats()
+}
+
+func (it *Order) Size() (int64, bool) {
+	return it.it.Size()
+}
+
+func (it *Order) Describe() graph.Description {
+	return graph.Description{
+		UID:       it.UID(),
+		Type:      it.Type(),
+		Tags:      it.tags.Tags(),
+		Iterators: []graph.Description{it.it.Describe()},
+	}
+}
+
+var _ graph.Iterator = &Order{}
+
+// orderRank returns a position of the value type in the sort order.
+func orderRank(v quad.Value) int {
+	switch v.(type) {
+	case quad.Int, quad.Float:
+		return 0
+	case quad.Time:
+		return 1
+	case quad.String, quad.TypedString, quad.LangString:
+		return 2
+	}
+	return 3
+}
+
+func stringOf(v quad.Value) string {
+	switch v := v.(type) {
+	case quad.String:
+		return string(v)
+	case quad.TypedString:
+		return string(v.Value)
+	case quad.LangString:
+		return string(v.Value)
+	}
+	return v.String()
+}
+
+// compareNil orders nil values after all other values.
+func compareNil(a, b quad.Value) int {
+	if a == nil && b != nil {
+		return +1
+	} else if a != nil && b == nil {
+		return -1
+	}
+	return 0
+}
+
+// compareValues compares two non-nil values in the sort order.
+func compareValues(a, b quad.Value) int {
+	ra, rb := orderRank(a), orderRank(b)
+	if ra != rb {
+		if ra < rb {
+			return -1
+		}
+		return +1
+	}
+	if l, ok := less(a, b); ok {
+		if l {
+			return -1
+		} else if l, _ = less(b, a); l {
+			return +1
+		}
+		return 0
+	}
+	if c := strings.Compare(stringOf(a), stringOf(b)); c != 0 {
+		return c
+	}
+	return strings.Compare(a.String(), b.String())
+}
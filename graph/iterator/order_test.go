@@ -0,0 +1,114 @@
+// Copyright 2017 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package iterator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/codelingo/cayley/graph"
+	"github.com/codelingo/cayley/quad"
+)
+
+func orderResults(t testing.TB, it graph.Iterator) []graph.Value {
+	var out []graph.Value
+	for it.Next(nil) {
+		out = append(out, it.Result())
+	}
+	require.NoError(t, it.Err())
+	return out
+}
+
+var orderTests = []struct {
+	name   string
+	keys   []OrderKey
+	expect []graph.Value
+}{
+	{
+		name:   "number",
+		keys:   []OrderKey{{Tag: "amount"}},
+		expect: []graph.Value{quad.Int(1), quad.Int(0), quad.Int(2), quad.Int(4), quad.Int(3)},
+	},
+	{
+		name:   "number desc",
+		keys:   []OrderKey{{Tag: "amount", Desc: true}},
+		expect: []graph.Value{quad.Int(4), quad.Int(2), quad.Int(0), quad.Int(1), quad.Int(3)},
+	},
+	{
+		name:   "time desc",
+		keys:   []OrderKey{{Tag: "date", Desc: true}},
+		expect: []graph.Value{quad.Int(2), quad.Int(3), quad.Int(1), quad.Int(0), quad.Int(4)},
+	},
+	{
+		name:   "multiple keys",
+		keys:   []OrderKey{{Tag: "customer"}, {Tag: "amount", Desc: true}},
+		expect: []graph.Value{quad.Int(2), quad.Int(0), quad.Int(1), quad.Int(3), quad.Int(4)},
+	},
+}
+
+func TestOrder(t *testing.T) {
+	qs := &store{}
+	for _, spill := range []int{OrderSpillSize, 2} {
+		OrderSpillSize = spill
+		for _, c := range orderTests {
+			it := NewOrder(testRows(), qs, c.keys...)
+			require.Equal(t, c.expect, orderResults(t, it), "%s (spill: %d)", c.name, spill)
+			if spill == 2 {
+				require.NotEmpty(t, it.files, "results were not spilled")
+			}
+			require.NoError(t, it.Close())
+			require.Empty(t, it.files)
+		}
+	}
+	OrderSpillSize = 100000
+
+	it := NewOrder(testRows(), qs, OrderKey{Tag: "amount"})
+	require.True(t, it.Next(nil))
+	tags := make(map[string]graph.Value)
+	it.TagResults(tags)
+	require.Equal(t, quad.Float(2.5), tags["amount"])
+	require.True(t, it.Contains(nil, quad.Int(3)))
+	require.Equal(t, quad.Int(3), it.Result())
+}
+
+func TestOrderTypes(t *testing.T) {
+	tm := quad.Time(time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC))
+	fixed := NewFixed(Identity,
+		quad.Int(3), quad.String("b"), tm, quad.IRI("a"), quad.Float(1.5), quad.String("a"),
+	)
+	it := NewOrder(fixed, &store{})
+	require.Equal(t, []graph.Value{
+		quad.Float(1.5), quad.Int(3), tm, quad.String("a"), quad.String("b"), quad.IRI("a"),
+	}, orderResults(t, it))
+}
+
+func TestOrderPaths(t *testing.T) {
+	fixed := NewFixed(Identity, quad.Int(2), quad.Int(1), quad.Int(2))
+	it := NewOrder(fixed, &store{})
+	require.True(t, it.Next(nil))
+	require.Equal(t, quad.Int(1), it.Result())
+	require.False(t, it.NextPath(nil))
+	require.True(t, it.Next(nil))
+	require.Equal(t, quad.Int(2), it.Result())
+	require.True(t, it.NextPath(nil))
+	require.False(t, it.NextPath(nil))
+	require.False(t, it.Next(nil))
+
+	// remaining paths are skipped by Next
+	it.Reset()
+	require.Equal(t, []graph.Value{quad.Int(1), quad.Int(2)}, orderResults(t, it))
+}
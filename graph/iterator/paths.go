@@ -0,0 +1,578 @@
+// Copyright 2017 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package iterator
+
+import (
+	"container/heap"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/codelingo/cayley/graph"
+	"github.com/codelingo/cayley/quad"
+)
+
+var (
+	// DefaultPathsDepth is the maximal number of quads in paths found by AllPaths, if no depth is set.
+	DefaultPathsDepth = 5
+	// MaxPaths is the maximal number of paths that AllPaths finds from a single node.
+	MaxPaths = 100000
+)
+
+// StepTag returns a name of the tag that holds i-th step of paths tagged with a given step tag.
+func StepTag(tag string, i int) string {
+	return tag + "[" + strconv.Itoa(i) + "]"
+}
+
+// SplitStepTag splits a tag name returned by StepTag into the step tag and the step index.
+func SplitStepTag(name string) (tag string, i int, ok bool) {
+	if !strings.HasSuffix(name, "]") {
+		return "", 0, false
+	}
+	j := strings.LastIndex(name, "[")
+	if j <= 0 {
+		return "", 0, false
+	}
+	i, err := strconv.Atoi(name[j+1 : len(name)-1])
+	if err != nil || i < 0 {
+		return "", 0, false
+	}
+	return name[:j], i, true
+}
+
+// Paths iterator finds paths from nodes of a sub-iterator to nodes of a target iterator,
+// following quads with given predicates. It returns the last node of each path.
+//
+// Steps of a path are the nodes and predicates it passes through, in order, starting
+// and ending with a node. They are returned in step tags, one tag per step (see StepTag).
+type Paths struct {
+	uid      uint64
+	tags     graph.Tagger
+	qs       graph.QuadStore
+	subIt    graph.Iterator
+	to       graph.Iterator
+	via      graph.Iterator
+	labels   graph.Iterator
+	dir      quad.Direction
+	all      bool
+	maxDepth int
+	weight   graph.Value
+	runstats graph.IteratorStats
+	err      error
+
+	stepTags   []string
+	weightTags []string
+
+	weights map[interface{}]nodeWeight
+	found   []foundPath
+	index   int
+	byEnd   map[interface{}][]foundPath
+	inPaths []foundPath
+	result  foundPath
+}
+
+type foundPath struct {
+	steps  []graph.Value
+	weight float64
+	tags   map[string]graph.Value
+}
+
+type nodeWeight struct {
+	val float64
+	ok  bool
+}
+
+var _ graph.Iterator = &Paths{}
+
+// NewShortestPaths returns an iterator of the shortest paths from each node of it to each
+// reachable node of to. Paths are found by following quads from their dir node to the
+// opposite one; quad.Subject follows quads forward.
+//
+// If via is not nil, only quads with predicates from via are followed. If to is nil, all
+// reachable nodes are targets. Paths have at least one step, and are returned in order of
+// increasing weight for each start node.
+func NewShortestPaths(qs graph.QuadStore, it, to, via graph.Iterator, dir quad.Direction) *Paths {
+	return newPaths(qs, it, to, via, dir, false, 0)
+}
+
+// NewAllPaths is like NewShortestPaths, but returns all paths without cycles that are at most
+// maxDepth quads long. DefaultPathsDepth is used if maxDepth is not positive. The iterator fails
+// if more than MaxPaths paths are found from a single node.
+func NewAllPaths(qs graph.QuadStore, it, to, via graph.Iterator, dir quad.Direction, maxDepth int) *Paths {
+	return newPaths(qs, it, to, via, dir, true, maxDepth)
+}
+
+func newPaths(qs graph.QuadStore, it, to, via graph.Iterator, dir quad.Direction, all bool, maxDepth int) *Paths {
+	if maxDepth <= 0 {
+		maxDepth = DefaultPathsDepth
+	}
+	return &Paths{
+		uid:      NextUID(),
+		qs:       qs,
+		subIt:    it,
+		to:       to,
+		via:      via,
+		dir:      dir,
+		all:      all,
+		maxDepth: maxDepth,
+		weights:  make(map[interface{}]nodeWeight),
+	}
+}
+
+func (it *Paths) UID() uint64 {
+	return it.uid
+}
+
+// SetLabels restricts paths to quads with labels from a given iterator.
+func (it *Paths) SetLabels(labels graph.Iterator) {
+	it.labels = labels
+}
+
+// SetWeight sets a numeric predicate that holds weights of quads. The weight of a quad is
+// the value of this predicate on its label, or on its predicate if the label has none.
+// Quads without a weight have a weight of 1.
+func (it *Paths) SetWeight(pred graph.Value) {
+	it.weight = pred
+}
+
+// AddStepTag adds a tag for steps of found paths.
+func (it *Paths) AddStepTag(tag string) {
+	it.stepTags = append(it.stepTags, tag)
+}
+
+// AddWeightTag adds a tag for total weights of found paths.
+func (it *Paths) AddWeightTag(tag string) {
+	it.weightTags = append(it.weightTags, tag)
+}
+
+func (it *Paths) Reset() {
+	it.subIt.Reset()
+	it.err = nil
+	it.found = nil
+	it.index = 0
+	it.byEnd = nil
+	it.inPaths = nil
+	it.result = foundPath{}
+}
+
+func (it *Paths) Tagger() *graph.Tagger {
+	return &it.tags
+}
+
+func (it *Paths) TagResults(dst map[string]graph.Value) {
+	for k, v := range it.result.tags {
+		dst[k] = v
+	}
+	for _, tag := range it.tags.Tags() {
+		dst[tag] = it.Result()
+	}
+	for tag, value := range it.tags.Fixed() {
+		dst[tag] = value
+	}
+	for _, tag := range it.stepTags {
+		for i, v := range it.result.steps {
+			dst[StepTag(tag, i)] = v
+		}
+	}
+	if len(it.weightTags) != 0 {
+		var w quad.Value = quad.Float(it.result.weight)
+		if it.weight == nil {
+			w = quad.Int(it.result.weight)
+		}
+		for _, tag := range it.weightTags {
+			dst[tag] = fetchedValue{Val: w}
+		}
+	}
+}
+
+func (it *Paths) Clone() graph.Iterator {
+	var to, via, labels graph.Iterator
+	if it.to != nil {
+		to = it.to.Clone()
+	}
+	if it.via != nil {
+		via = it.via.Clone()
+	}
+	if it.labels != nil {
+		labels = it.labels.Clone()
+	}
+	n := newPaths(it.qs, it.subIt.Clone(), to, via, it.dir, it.all, it.maxDepth)
+	n.labels = labels
+	n.weight = it.weight
+	n.stepTags = append([]string(nil), it.stepTags...)
+	n.weightTags = append([]string(nil), it.weightTags...)
+	n.tags.CopyFrom(it)
+	return n
+}
+
+func (it *Paths) SubIterators() []graph.Iterator {
+	out := []graph.Iterator{it.subIt}
+	for _, sub := range []graph.Iterator{it.to, it.via, it.labels} {
+		if sub != nil {
+			out = append(out, sub)
+		}
+	}
+	return out
+}
+
+func (it *Paths) Next(ctx *graph.IterationContext) bool {
+	graph.NextLogIn(ctx, it)
+	it.runstats.Next += 1
+	it.inPaths = nil
+	for it.index >= len(it.found) {
+		if !it.subIt.Next(ctx) {
+			it.err = it.subIt.Err()
+			return graph.NextLogOut(ctx, it, false)
+		}
+		if !it.explore(ctx) {
+			return graph.NextLogOut(ctx, it, false)
+		}
+	}
+	it.result = it.found[it.index]
+	it.index++
+	return graph.NextLogOut(ctx, it, true)
+}
+
+// explore finds all paths from the current result of the sub-iterator.
+func (it *Paths) explore(ctx *graph.IterationContext) bool {
+	start := it.subIt.Result()
+	tags := make(map[string]graph.Value)
+	it.subIt.TagResults(tags)
+	it.found, it.index = it.found[:0], 0
+	if it.all {
+		it.allPaths(ctx, start, tags)
+	} else {
+		it.shortestPaths(ctx, start, tags)
+	}
+	return it.err == nil
+}
+
+// edge is a quad that leads from one node to another.
+type edge struct {
+	pred   graph.Value
+	node   graph.Value
+	weight float64
+}
+
+// edges returns all quads that can be followed from a node.
+func (it *Paths) edges(ctx *graph.IterationContext, v graph.Value) ([]edge, error) {
+	goal := quad.Object
+	if it.dir == quad.Object {
+		goal = quad.Subject
+	}
+	qi := it.qs.QuadIterator(it.dir, v)
+	defer qi.Close()
+	var out []edge
+	for qi.Next(ctx) {
+		q := qi.Result()
+		pred := it.qs.QuadDirection(q, quad.Predicate)
+		if it.via != nil && !it.via.Contains(ctx, pred) {
+			continue
+		}
+		label := it.qs.QuadDirection(q, quad.Label)
+		if it.labels != nil && (label == nil || !it.labels.Contains(ctx, label)) {
+			continue
+		}
+		w := 1.0
+		if it.weight != nil {
+			var ok bool
+			if w, ok = it.weightOf(ctx, label); !ok {
+				if w, ok = it.weightOf(ctx, pred); !ok {
+					w = 1
+				}
+			}
+			if w < 0 {
+				return nil, fmt.Errorf("negative weight of %v: %v", it.qs.NameOf(pred), w)
+			}
+		}
+		out = append(out, edge{pred: pred, node: it.qs.QuadDirection(q, goal), weight: w})
+	}
+	return out, qi.Err()
+}
+
+// weightOf returns a numeric value of the weight predicate on a node.
+func (it *Paths) weightOf(ctx *graph.IterationContext, v graph.Value) (float64, bool) {
+	if v == nil {
+		return 0, false
+	}
+	key := graph.ToKey(v)
+	if w, ok := it.weights[key]; ok {
+		return w.val, w.ok
+	}
+	var w nodeWeight
+	qi := it.qs.QuadIterator(quad.Subject, v)
+	for qi.Next(ctx) {
+		q := qi.Result()
+		if graph.ToKey(it.qs.QuadDirection(q, quad.Predicate)) != graph.ToKey(it.weight) {
+			continue
+		}
+		switch o := it.qs.NameOf(it.qs.QuadDirection(q, quad.Object)).(type) {
+		case quad.Int:
+			w = nodeWeight{val: float64(o), ok: true}
+		case quad.Float:
+			w = nodeWeight{val: float64(o), ok: true}
+		default:
+			continue
+		}
+		break
+	}
+	qi.Close()
+	it.weights[key] = w
+	return w.val, w.ok
+}
+
+func (it *Paths) isTarget(ctx *graph.IterationContext, v graph.Value) bool {
+	return it.to == nil || it.to.Contains(ctx, v)
+}
+
+type pathNode struct {
+	node  graph.Value
+	dist  float64
+	seq   int
+	prev  *pathNode
+	pred  graph.Value
+	depth int
+}
+
+// pathQueue is a priority queue of nodes ordered by distance, and by insertion order for equal distances.
+type pathQueue []*pathNode
+
+func (q pathQueue) Len() int { return len(q) }
+func (q pathQueue) Less(i, j int) bool {
+	if q[i].dist != q[j].dist {
+		return q[i].dist < q[j].dist
+	}
+	return q[i].seq < q[j].seq
+}
+func (q pathQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *pathQueue) Push(x interface{}) { *q = append(*q, x.(*pathNode)) }
+func (q *pathQueue) Pop() interface{} {
+	old := *q
+	n := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return n
+}
+
+func (n *pathNode) steps() []graph.Value {
+	out := make([]graph.Value, 2*n.depth+1)
+	for i := len(out) - 1; n != nil; n = n.prev {
+		out[i] = n.node
+		if n.prev != nil {
+			out[i-1] = n.pred
+		}
+		i -= 2
+	}
+	return out
+}
+
+// shortestPaths finds the shortest paths from a start node with Dijkstra's algorithm.
+func (it *Paths) shortestPaths(ctx *graph.IterationContext, start graph.Value, tags map[string]graph.Value) {
+	seq := 0
+	done := make(map[interface{}]bool)
+	best := make(map[interface{}]float64)
+	q := &pathQueue{{node: start}}
+	for q.Len() != 0 {
+		if err := ctx.Err(); err != nil {
+			it.err = err
+			return
+		}
+		cur := heap.Pop(q).(*pathNode)
+		key := graph.ToKey(cur.node)
+		if done[key] {
+			continue
+		}
+		done[key] = true
+		if cur.prev != nil && it.isTarget(ctx, cur.node) {
+			it.found = append(it.found, foundPath{steps: cur.steps(), weight: cur.dist, tags: tags})
+		}
+		edges, err := it.edges(ctx, cur.node)
+		if err != nil {
+			it.err = err
+			return
+		}
+		for _, e := range edges {
+			nkey := graph.ToKey(e.node)
+			dist := cur.dist + e.weight
+			if done[nkey] {
+				continue
+			} else if d, ok := best[nkey]; ok && d <= dist {
+				continue
+			}
+			best[nkey] = dist
+			seq++
+			heap.Push(q, &pathNode{node: e.node, dist: dist, seq: seq, prev: cur, pred: e.pred, depth: cur.depth + 1})
+		}
+	}
+}
+
+// allPaths finds all paths without cycles from a start node with a depth-first search.
+func (it *Paths) allPaths(ctx *graph.IterationContext, start graph.Value, tags map[string]graph.Value) {
+	onPath := map[interface{}]bool{graph.ToKey(start): true}
+	var walk func(cur *pathNode)
+	walk = func(cur *pathNode) {
+		if it.err != nil || cur.depth >= it.maxDepth {
+			return
+		} else if err := ctx.Err(); err != nil {
+			it.err = err
+			return
+		}
+		edges, err := it.edges(ctx, cur.node)
+		if err != nil {
+			it.err = err
+			return
+		}
+		for _, e := range edges {
+			key := graph.ToKey(e.node)
+			if onPath[key] {
+				continue
+			}
+			next := &pathNode{node: e.node, dist: cur.dist + e.weight, prev: cur, pred: e.pred, depth: cur.depth + 1}
+			if it.isTarget(ctx, e.node) {
+				if len(it.found) >= MaxPaths {
+					it.err = fmt.Errorf("paths: more than %d paths from a single node", MaxPaths)
+					return
+				}
+				it.found = append(it.found, foundPath{steps: next.steps(), weight: next.dist, tags: tags})
+			}
+			onPath[key] = true
+			walk(next)
+			onPath[key] = false
+		}
+	}
+	walk(&pathNode{node: start})
+}
+
+func (it *Paths) Err() error {
+	return it.err
+}
+
+func (it *Paths) Result() graph.Value {
+	if len(it.result.steps) == 0 {
+		return nil
+	}
+	return it.result.steps[len(it.result.steps)-1]
+}
+
+// Contains checks if there is a path to a given node. Alternative paths to the node are returned by NextPath.
+//
+// All paths are found on the first call.
+func (it *Paths) Contains(ctx *graph.IterationContext, val graph.Value) bool {
+	graph.ContainsLogIn(ctx, it, val)
+	it.runstats.Contains += 1
+	if it.byEnd == nil {
+		it.byEnd = make(map[interface{}][]foundPath)
+		it.subIt.Reset()
+		it.found, it.index = nil, 0
+		for it.subIt.Next(ctx) {
+			if !it.explore(ctx) {
+				return graph.ContainsLogOut(ctx, it, val, false)
+			}
+			for _, p := range it.found {
+				key := graph.ToKey(p.steps[len(p.steps)-1])
+				it.byEnd[key] = append(it.byEnd[key], p)
+			}
+			it.found = nil
+		}
+		if it.err = it.subIt.Err(); it.err != nil {
+			return graph.ContainsLogOut(ctx, it, val, false)
+		}
+	}
+	it.inPaths = it.byEnd[graph.ToKey(val)]
+	if len(it.inPaths) == 0 {
+		return graph.ContainsLogOut(ctx, it, val, false)
+	}
+	it.result = it.inPaths[0]
+	it.inPaths = it.inPaths[1:]
+	return graph.ContainsLogOut(ctx, it, val, true)
+}
+
+// NextPath returns the next path to the node that was checked by Contains. Paths found by Next
+// are returned as separate results.
+func (it *Paths) NextPath(ctx *graph.IterationContext) bool {
+	if len(it.inPaths) == 0 {
+		return false
+	}
+	it.result = it.inPaths[0]
+	it.inPaths = it.inPaths[1:]
+	return true
+}
+
+func (it *Paths) Close() error {
+	err := it.subIt.Close()
+	for _, sub := range []graph.Iterator{it.to, it.via, it.labels} {
+		if sub == nil {
+			continue
+		}
+		if err2 := sub.Close(); err == nil {
+			err = err2
+		}
+	}
+	it.found = nil
+	it.byEnd = nil
+	return err
+}
+
+func (it *Paths) Type() graph.Type { return graph.Paths }
+
+func (it *Paths) Optimize() (graph.Iterator, bool) {
+	if newIt, ok := it.subIt.Optimize(); ok {
+		it.subIt = newIt
+	}
+	if it.to != nil {
+		if newIt, ok := it.to.Optimize(); ok {
+			it.to = newIt
+		}
+	}
+	if it.via != nil {
+		if newIt, ok := it.via.Optimize(); ok {
+			it.via = newIt
+		}
+	}
+	return it, false
+}
+
+func (it *Paths) Size() (int64, bool) {
+	return it.Stats().Size, false
+}
+
+// Stats assumes that each start node reaches a fixed number of nodes, since the real
+// number is only known after the search.
+func (it *Paths) Stats() graph.IteratorStats {
+	const reach = 20
+	subitStats := it.subIt.Stats()
+	nextCost := subitStats.NextCost + reach*subitStats.ContainsCost
+	return graph.IteratorStats{
+		NextCost:     nextCost,
+		ContainsCost: subitStats.Size * nextCost,
+		Size:         subitStats.Size * reach,
+		Next:         it.runstats.Next,
+		Contains:     it.runstats.Contains,
+		ContainsNext: it.runstats.ContainsNext,
+	}
+}
+
+func (it *Paths) Describe() graph.Description {
+	subs := it.SubIterators()
+	descs := make([]graph.Description, 0, len(subs))
+	for _, sub := range subs {
+		descs = append(descs, sub.Describe())
+	}
+	return graph.Description{
+		UID:       it.UID(),
+		Type:      it.Type(),
+		Tags:      it.tags.Tags(),
+		Iterators: descs,
+	}
+}
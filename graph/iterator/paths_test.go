@@ -0,0 +1,207 @@
+// Copyright 2017 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package iterator
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/codelingo/cayley/graph"
+	"github.com/codelingo/cayley/quad"
+	"golang.org/x/net/context"
+)
+
+var paths_test_qs = &store{
+	data: []quad.Quad{
+		quad.Make(quad.IRI("a"), quad.IRI("road"), quad.IRI("b"), quad.IRI("e1")),
+		quad.Make(quad.IRI("b"), quad.IRI("road"), quad.IRI("c"), quad.IRI("e2")),
+		quad.Make(quad.IRI("a"), quad.IRI("road"), quad.IRI("c"), quad.IRI("e3")),
+		quad.Make(quad.IRI("c"), quad.IRI("rail"), quad.IRI("d"), nil),
+		quad.Make(quad.IRI("e1"), quad.IRI("cost"), quad.Int(1), nil),
+		quad.Make(quad.IRI("e2"), quad.IRI("cost"), quad.Int(2), nil),
+		quad.Make(quad.IRI("e3"), quad.IRI("cost"), quad.Int(5), nil),
+		quad.Make(quad.IRI("rail"), quad.IRI("cost"), quad.Float(0.5), nil),
+	},
+}
+
+func pathsFrom(qs graph.QuadStore, nodes ...string) graph.Iterator {
+	fixed := qs.FixedIterator()
+	for _, n := range nodes {
+		fixed.Add(quad.IRI(n))
+	}
+	return fixed
+}
+
+func pathsNameOf(v graph.Value) quad.Value {
+	if pv, ok := v.(graph.PreFetchedValue); ok {
+		return pv.NameOf()
+	}
+	return paths_test_qs.NameOf(v)
+}
+
+func collectPaths(t *testing.T, it *Paths) (paths [][]string, weights []quad.Value) {
+	for it.Next(nil) {
+		tags := make(map[string]graph.Value)
+		it.TagResults(tags)
+		var steps []string
+		for i := 0; ; i++ {
+			v, ok := tags[StepTag("path", i)]
+			if !ok {
+				break
+			}
+			steps = append(steps, quad.StringOf(pathsNameOf(v)))
+		}
+		if last := steps[len(steps)-1]; last != quad.StringOf(pathsNameOf(it.Result())) {
+			t.Errorf("unexpected result: %v, path ends with %v", it.Result(), last)
+		}
+		paths = append(paths, steps)
+		weights = append(weights, pathsNameOf(tags["weight"]))
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return
+}
+
+func TestShortestPaths(t *testing.T) {
+	qs := paths_test_qs
+	for _, c := range []struct {
+		name    string
+		weight  quad.Value
+		to      []string
+		paths   [][]string
+		weights []quad.Value
+	}{
+		{
+			name: "unweighted",
+			to:   []string{"c", "d"},
+			paths: [][]string{
+				{"<a>", "<road>", "<c>"},
+				{"<a>", "<road>", "<c>", "<rail>", "<d>"},
+			},
+			weights: []quad.Value{quad.Int(1), quad.Int(2)},
+		},
+		{
+			name:   "weighted",
+			weight: quad.IRI("cost"),
+			to:     []string{"c", "d"},
+			paths: [][]string{
+				{"<a>", "<road>", "<b>", "<road>", "<c>"},
+				{"<a>", "<road>", "<b>", "<road>", "<c>", "<rail>", "<d>"},
+			},
+			weights: []quad.Value{quad.Float(3), quad.Float(3.5)},
+		},
+	} {
+		it := NewShortestPaths(qs, pathsFrom(qs, "a"), pathsFrom(qs, c.to...), pathsFrom(qs, "road", "rail"), quad.Subject)
+		it.AddStepTag("path")
+		it.AddWeightTag("weight")
+		if c.weight != nil {
+			it.SetWeight(qs.ValueOf(c.weight))
+		}
+		paths, weights := collectPaths(t, it)
+		if !reflect.DeepEqual(paths, c.paths) {
+			t.Errorf("%s: unexpected paths: %v, expected: %v", c.name, paths, c.paths)
+		}
+		if !reflect.DeepEqual(weights, c.weights) {
+			t.Errorf("%s: unexpected weights: %v, expected: %v", c.name, weights, c.weights)
+		}
+	}
+}
+
+func TestAllPaths(t *testing.T) {
+	qs := paths_test_qs
+	it := NewAllPaths(qs, pathsFrom(qs, "a"), pathsFrom(qs, "c"), pathsFrom(qs, "road"), quad.Subject, 0)
+	it.AddStepTag("path")
+	paths, _ := collectPaths(t, it)
+	expect := [][]string{
+		{"<a>", "<road>", "<b>", "<road>", "<c>"},
+		{"<a>", "<road>", "<c>"},
+	}
+	if !reflect.DeepEqual(paths, expect) {
+		t.Errorf("unexpected paths: %v, expected: %v", paths, expect)
+	}
+
+	it = NewAllPaths(qs, pathsFrom(qs, "a"), pathsFrom(qs, "c"), pathsFrom(qs, "road"), quad.Subject, 1)
+	if !it.Contains(nil, qs.ValueOf(quad.IRI("c"))) {
+		t.Fatal("expected a path to c")
+	}
+	if it.NextPath(nil) {
+		t.Error("unexpected path longer than max depth")
+	}
+	if it.Contains(nil, qs.ValueOf(quad.IRI("b"))) {
+		t.Error("unexpected path to a node that is not a target")
+	}
+}
+
+func TestAllPathsLimit(t *testing.T) {
+	qs := paths_test_qs
+	defer func(n int) { MaxPaths = n }(MaxPaths)
+	MaxPaths = 1
+	it := NewAllPaths(qs, pathsFrom(qs, "a"), pathsFrom(qs, "c"), pathsFrom(qs, "road"), quad.Subject, 0)
+	if it.Next(nil) {
+		t.Error("expected paths search to fail")
+	}
+	if it.Err() == nil {
+		t.Error("expected an error for too many paths")
+	}
+}
+
+// cancelOnContains cancels the iteration when it is checked for a value.
+type cancelOnContains struct {
+	graph.Iterator
+	cancel func()
+}
+
+func (it cancelOnContains) Contains(ctx *graph.IterationContext, v graph.Value) bool {
+	it.cancel()
+	return it.Iterator.Contains(ctx, v)
+}
+
+func TestAllPathsCancel(t *testing.T) {
+	qs := paths_test_qs
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	via := cancelOnContains{Iterator: pathsFrom(qs, "road"), cancel: cancel}
+	it := NewAllPaths(qs, pathsFrom(qs, "a"), pathsFrom(qs, "c"), via, quad.Subject, 0)
+	n := 0
+	graph.Iterate(ctx, it).Each(func(graph.Value) { n++ })
+	if n != 0 || it.Err() != context.Canceled {
+		t.Errorf("expected the search to be cancelled, got %d paths and error %v", n, it.Err())
+	}
+}
+
+func TestStepTag(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		tag  string
+		i    int
+		ok   bool
+	}{
+		{name: "path[0]", tag: "path", i: 0, ok: true},
+		{name: "a[b][12]", tag: "a[b]", i: 12, ok: true},
+		{name: "path"},
+		{name: "[1]"},
+		{name: "path[x]"},
+		{name: "path[-1]"},
+	} {
+		tag, i, ok := SplitStepTag(c.name)
+		if ok != c.ok || tag != c.tag || i != c.i {
+			t.Errorf("unexpected split of %q: %q, %d, %v", c.name, tag, i, ok)
+		}
+		if ok && StepTag(tag, i) != c.name {
+			t.Errorf("unexpected step tag: %q, expected: %q", StepTag(tag, i), c.name)
+		}
+	}
+}
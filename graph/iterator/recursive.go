@@ -138,7 +138,7 @@ func (it *Recursive) Next(ctx *graph.IterationContext) bool {
 		ok := it.nextIt.Next(ctx)
 		if !ok {
 			if len(it.depthCache) == 0 {
-				return graph.NextLogOut(it, false)
+				return graph.NextLogOut(ctx, it, false)
 			}
 			it.depth++
 			it.baseIt = it.qs.FixedIterator()
@@ -167,7 +167,7 @@ func (it *Recursive) Next(ctx *graph.IterationContext) bool {
 		it.depthCache = append(it.depthCache, val)
 		break
 	}
-	return graph.NextLogOut(it, true)
+	return graph.NextLogOut(ctx, it, true)
 }
 
 func (it *Recursive) Err() error {
@@ -209,23 +209,23 @@ func (it *Recursive) ResetIfVarsUpdated(ctx *graph.IterationContext) {
 func (it *Recursive) Contains(ctx *graph.IterationContext, val graph.Value) bool {
 	it.ResetIfVarsUpdated(ctx)
 
-	graph.ContainsLogIn(it, val)
+	graph.ContainsLogIn(ctx, it, val)
 	it.pathIndex = 0
 	if at, ok := it.seen[graph.ToKey(val)]; ok {
 		it.containsValue = it.getBaseValue(val)
 		it.result.depth = at.depth
 		it.result.val = val
-		return graph.ContainsLogOut(it, val, true)
+		return graph.ContainsLogOut(ctx, it, val, true)
 	}
 	i := 0
 	for it.Next(ctx) {
 		i++
 		if it.Result() == val {
-			return graph.ContainsLogOut(it, val, true)
+			return graph.ContainsLogOut(ctx, it, val, true)
 		}
 	}
 	_ = i
-	return graph.ContainsLogOut(it, val, false)
+	return graph.ContainsLogOut(ctx, it, val, false)
 }
 
 func (it *Recursive) NextPath(ctx *graph.IterationContext) bool {
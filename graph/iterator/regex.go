@@ -62,6 +62,12 @@ func (it *Regex) AllowRefs(v bool) {
 	it.allowRefs = v
 }
 
+// Regexp returns the regular expression used by the iterator.
+func (it *Regex) Regexp() *regexp.Regexp { return it.re }
+
+// RefsAllowed returns true if the iterator matches IRIs and BNodes.
+func (it *Regex) RefsAllowed() bool { return it.allowRefs }
+
 func (it *Regex) testRegex(val graph.Value) bool {
 	// Type switch to avoid coercing and testing numeric types
 	v := it.qs.NameOf(val)
@@ -110,15 +116,16 @@ func (it *Regex) Clone() graph.Iterator {
 }
 
 func (it *Regex) Next(ctx *graph.IterationContext) bool {
+	graph.NextLogIn(ctx, it)
 	for it.subIt.Next(ctx) {
 		val := it.subIt.Result()
 		if it.testRegex(val) {
 			it.result = val
-			return true
+			return graph.NextLogOut(ctx, it, true)
 		}
 	}
 	it.err = it.subIt.Err()
-	return false
+	return graph.NextLogOut(ctx, it, false)
 }
 
 func (it *Regex) Err() error {
@@ -149,14 +156,17 @@ func (it *Regex) SubIterators() []graph.Iterator {
 }
 
 func (it *Regex) Contains(ctx *graph.IterationContext, val graph.Value) bool {
+	graph.ContainsLogIn(ctx, it, val)
 	if !it.testRegex(val) {
-		return false
+		return graph.ContainsLogOut(ctx, it, val, false)
 	}
 	ok := it.subIt.Contains(ctx, val)
 	if !ok {
 		it.err = it.subIt.Err()
+	} else {
+		it.result = val
 	}
-	return ok
+	return graph.ContainsLogOut(ctx, it, val, ok)
 }
 
 // Registers the Regex iterator.
@@ -174,13 +184,19 @@ func (it *Regex) Describe() graph.Description {
 }
 
 // There's nothing to optimize, locally, for a Regex iterator.
-// Replace the underlying iterator if need be.
+// Replace the underlying iterator if need be, and ask the QuadStore
+// if it can run the regular expression itself.
 func (it *Regex) Optimize() (graph.Iterator, bool) {
 	newSub, changed := it.subIt.Optimize()
 	if changed {
 		it.subIt.Close()
 		it.subIt = newSub
 	}
+	newReplacement, hasOne := it.qs.OptimizeIterator(it)
+	if hasOne {
+		it.Close()
+		return newReplacement, true
+	}
 	return it, false
 }
 
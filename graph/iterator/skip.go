@@ -20,6 +20,11 @@ func NewSkip(primaryIt graph.Iterator, skip int64) *Skip {
 	}
 }
 
+// Skip returns the number of values skipped by the iterator.
+func (it *Skip) Skip() int64 {
+	return it.skip
+}
+
 func (it *Skip) UID() uint64 {
 	return it.uid
 }
@@ -50,16 +55,16 @@ func (it *Skip) SubIterators() []graph.Iterator {
 // Next advances the Skip iterator. It will skip all initial values
 // before returning actual result.
 func (it *Skip) Next(ctx *graph.IterationContext) bool {
-	graph.NextLogIn(it)
+	graph.NextLogIn(ctx, it)
 	for ; it.skipped < it.skip; it.skipped++ {
 		if !it.primaryIt.Next(ctx) {
-			return graph.NextLogOut(it, false)
+			return graph.NextLogOut(ctx, it, false)
 		}
 	}
 	if it.primaryIt.Next(ctx) {
-		return graph.NextLogOut(it, true)
+		return graph.NextLogOut(ctx, it, true)
 	}
-	return graph.NextLogOut(it, false)
+	return graph.NextLogOut(ctx, it, false)
 }
 
 func (it *Skip) Err() error {
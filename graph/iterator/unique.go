@@ -67,7 +67,7 @@ func (it *Unique) SubIterators() []graph.Iterator {
 // Next advances the subiterator, continuing until it returns a value which it
 // has not previously seen.
 func (it *Unique) Next(ctx *graph.IterationContext) bool {
-	graph.NextLogIn(it)
+	graph.NextLogIn(ctx, it)
 	it.runstats.Next += 1
 
 	for it.subIt.Next(ctx) {
@@ -79,11 +79,11 @@ func (it *Unique) Next(ctx *graph.IterationContext) bool {
 		if ok := it.seen[key]; !ok {
 			it.result = curr
 			it.seen[key] = true
-			return graph.NextLogOut(it, true)
+			return graph.NextLogOut(ctx, it, true)
 		}
 	}
 	it.err = it.subIt.Err()
-	return graph.NextLogOut(it, false)
+	return graph.NextLogOut(ctx, it, false)
 }
 
 func (it *Unique) Err() error {
@@ -97,9 +97,9 @@ func (it *Unique) Result() graph.Value {
 // Contains checks whether the passed value is part of the primary iterator,
 // which is irrelevant for uniqueness.
 func (it *Unique) Contains(ctx *graph.IterationContext, val graph.Value) bool {
-	graph.ContainsLogIn(it, val)
+	graph.ContainsLogIn(ctx, it, val)
 	it.runstats.Contains += 1
-	return graph.ContainsLogOut(it, val, it.subIt.Contains(ctx, val))
+	return graph.ContainsLogOut(ctx, it, val, it.subIt.Contains(ctx, val))
 }
 
 // NextPath for unique always returns false. If we were to return multiple
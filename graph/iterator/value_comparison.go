@@ -194,15 +194,16 @@ func (it *Comparison) Clone() graph.Iterator {
 }
 
 func (it *Comparison) Next(ctx *graph.IterationContext) bool {
+	graph.NextLogIn(ctx, it)
 	for it.subIt.Next(ctx) {
 		val := it.subIt.Result()
 		if it.doComparison(val) {
 			it.result = val
-			return true
+			return graph.NextLogOut(ctx, it, true)
 		}
 	}
 	it.err = it.subIt.Err()
-	return false
+	return graph.NextLogOut(ctx, it, false)
 }
 
 func (it *Comparison) Err() error {
@@ -233,14 +234,17 @@ func (it *Comparison) SubIterators() []graph.Iterator {
 }
 
 func (it *Comparison) Contains(ctx *graph.IterationContext, val graph.Value) bool {
+	graph.ContainsLogIn(ctx, it, val)
 	if !it.doComparison(val) {
-		return false
+		return graph.ContainsLogOut(ctx, it, val, false)
 	}
 	ok := it.subIt.Contains(ctx, val)
 	if !ok {
 		it.err = it.subIt.Err()
+	} else {
+		it.result = val
 	}
-	return ok
+	return graph.ContainsLogOut(ctx, it, val, ok)
 }
 
 // If we failed the check, then the subiterator should not contribute to the result
@@ -278,6 +282,13 @@ func (it *Comparison) Optimize() (graph.Iterator, bool) {
 		it.subIt.Close()
 		it.subIt = newSub
 	}
+	// Some backends can run the comparison themselves, given a value index
+	// or a query language with typed values.
+	newReplacement, hasOne := it.qs.OptimizeIterator(it)
+	if hasOne {
+		it.Close()
+		return newReplacement, true
+	}
 	return it, false
 }
 
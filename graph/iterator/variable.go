@@ -109,22 +109,22 @@ func (it *Variable) Type() graph.Type { return graph.Variable }
 // Contains checks if the passed value is equal to the current value of the variable.
 // Contains is not defined for a bind variable.
 func (it *Variable) Contains(ctx *graph.IterationContext, v graph.Value) bool {
-	graph.ContainsLogIn(it, v)
+	graph.ContainsLogIn(ctx, it, v)
 	if ctx.BindVariable(it.qs, it.varName) || it.isBinder {
 		panic("Reorder iterator tree for variables. Contains should not bind a variable.")
 	}
 
 	if v == ctx.CurrentValue(it.varName) {
-		return graph.ContainsLogOut(it, v, true)
+		return graph.ContainsLogOut(ctx, it, v, true)
 	}
 
-	return graph.ContainsLogOut(it, v, false)
+	return graph.ContainsLogOut(ctx, it, v, false)
 
 }
 
 // Next advances the value of the variable on the iteration context.
 func (it *Variable) Next(ctx *graph.IterationContext) bool {
-	graph.NextLogIn(it)
+	graph.NextLogIn(ctx, it)
 
 	if ctx.BindVariable(it.qs, it.varName) {
 		it.isBinder = true
@@ -133,10 +133,10 @@ func (it *Variable) Next(ctx *graph.IterationContext) bool {
 	if it.isBinder {
 		if ctx.Next(it.varName) {
 			it.result = ctx.CurrentValue(it.varName)
-			return graph.NextLogOut(it, true)
+			return graph.NextLogOut(ctx, it, true)
 		}
 		it.result = nil
-		return graph.NextLogOut(it, false)
+		return graph.NextLogOut(ctx, it, false)
 	}
 
 	panic("query should be reordered so that only binders call next")
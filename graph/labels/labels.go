@@ -0,0 +1,288 @@
+// Copyright 2017 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package labels implements views of a quad store that are restricted to a set of named graphs (labels).
+//
+// All iterators returned by a view range only over quads with one of the view's labels, and over
+// nodes used by these quads. Quads written through a view without a label are written to the first
+// label of the view.
+package labels
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/codelingo/cayley/graph"
+	"github.com/codelingo/cayley/graph/iterator"
+	"github.com/codelingo/cayley/quad"
+)
+
+const QuadStoreType = "labels"
+
+// ErrOutOfView is returned on attempt to write a quad with a label that is not in the view.
+var ErrOutOfView = errors.New("labels: quad label is not in the view")
+
+var (
+	_ graph.QuadStore    = (*QuadStore)(nil)
+	_ graph.HistoryStore = (*QuadStore)(nil)
+	_ graph.FeedView     = (*QuadStore)(nil)
+)
+
+// QuadStore is a view of a quad store restricted to a set of labels.
+type QuadStore struct {
+	qs     graph.QuadStore
+	labels []quad.Value
+	keys   map[string]struct{}
+}
+
+// New creates a view of a quad store restricted to given labels. At least one label must be given.
+func New(qs graph.QuadStore, list ...quad.Value) *QuadStore {
+	if len(list) == 0 {
+		panic("labels: at least one label is required")
+	}
+	keys := make(map[string]struct{}, len(list))
+	for _, l := range list {
+		keys[quad.StringOf(l)] = struct{}{}
+	}
+	return &QuadStore{qs: qs, labels: list, keys: keys}
+}
+
+// Parse parses a comma-separated list of labels, for example "<g1>,<g2>".
+func Parse(s string) []quad.Value {
+	var out []quad.Value
+	for _, f := range strings.Split(s, ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			out = append(out, quad.StringToValue(f))
+		}
+	}
+	return out
+}
+
+// NewForRequest restricts the quad store to labels listed in the "graph" option or in the "graph"
+// URL parameter of the "HTTPRequest" option, for example "/api/v1/query/gizmo?graph=<g1>,<g2>".
+// It returns the quad store itself if neither of them is set.
+//
+// It has the signature of graph.NewStoreForRequestFunc.
+func NewForRequest(qs graph.QuadStore, opts graph.Options) (graph.QuadStore, error) {
+	s, _, err := opts.StringKey("graph")
+	if err != nil {
+		return nil, err
+	}
+	if s == "" {
+		if r, ok := opts["HTTPRequest"].(*http.Request); ok {
+			s = r.URL.Query().Get("graph")
+		}
+	}
+	list := Parse(s)
+	if len(list) == 0 {
+		return qs, nil
+	}
+	return New(qs, list...), nil
+}
+
+// Unwrap returns an underlying quad store.
+func (qs *QuadStore) Unwrap() graph.QuadStore {
+	return qs.qs
+}
+
+// Labels returns labels of the view.
+func (qs *QuadStore) Labels() []quad.Value {
+	return qs.labels
+}
+
+// Default returns a label that is set on quads written without one.
+func (qs *QuadStore) Default() quad.Value {
+	return qs.labels[0]
+}
+
+// Has checks if a label is in the view.
+func (qs *QuadStore) Has(l quad.Value) bool {
+	if l == nil {
+		return false
+	}
+	_, ok := qs.keys[quad.StringOf(l)]
+	return ok
+}
+
+// scope sets the default label on a quad without one and checks that the label is in the view.
+func (qs *QuadStore) scope(q quad.Quad) (quad.Quad, error) {
+	if q.Label == nil {
+		q.Label = qs.Default()
+	} else if !qs.Has(q.Label) {
+		return q, fmt.Errorf("%v: %v", ErrOutOfView, q.Label)
+	}
+	return q, nil
+}
+
+// ApplyDeltas applies deltas to the underlying store. Quads without a label are updated in place
+// with the default label, thus the change feed sees the quads as they were written.
+func (qs *QuadStore) ApplyDeltas(deltas []graph.Delta, opts graph.IgnoreOpts) error {
+	for i := range deltas {
+		if _, err := qs.scope(deltas[i].Quad); err != nil {
+			return err
+		}
+	}
+	for i := range deltas {
+		deltas[i].Quad, _ = qs.scope(deltas[i].Quad)
+	}
+	return qs.qs.ApplyDeltas(deltas, opts)
+}
+
+func (qs *QuadStore) Quad(v graph.Value) quad.Quad {
+	return qs.qs.Quad(v)
+}
+
+func (qs *QuadStore) QuadDirection(v graph.Value, d quad.Direction) graph.Value {
+	return qs.qs.QuadDirection(v, d)
+}
+
+// labeled returns an iterator of all quads in the view.
+func (qs *QuadStore) labeled() graph.Iterator {
+	var its []graph.Iterator
+	for _, l := range qs.labels {
+		if v := qs.qs.ValueOf(l); v != nil {
+			its = append(its, qs.qs.QuadIterator(quad.Label, v))
+		}
+	}
+	switch len(its) {
+	case 0:
+		return iterator.NewNull()
+	case 1:
+		return its[0]
+	}
+	return iterator.NewOr(its...)
+}
+
+// QuadIterator returns an iterator of quads in the view that have a given node in a specified direction.
+func (qs *QuadStore) QuadIterator(d quad.Direction, v graph.Value) graph.Iterator {
+	if d == quad.Label {
+		if !qs.Has(qs.qs.NameOf(v)) {
+			return iterator.NewNull()
+		}
+		return qs.qs.QuadIterator(d, v)
+	}
+	return iterator.NewAnd(qs, qs.qs.QuadIterator(d, v), qs.labeled())
+}
+
+// QuadsAllIterator returns an iterator of all quads in the view.
+func (qs *QuadStore) QuadsAllIterator() graph.Iterator {
+	return qs.labeled()
+}
+
+// NodesAllIterator returns an iterator of all nodes used by quads in the view, including the labels.
+func (qs *QuadStore) NodesAllIterator() graph.Iterator {
+	its := make([]graph.Iterator, 0, len(quad.Directions))
+	for _, d := range quad.Directions {
+		its = append(its, iterator.NewHasA(qs, qs.labeled(), d))
+	}
+	return iterator.NewUnique(iterator.NewOr(its...))
+}
+
+func (qs *QuadStore) ValueOf(name quad.Value) graph.Value {
+	return qs.qs.ValueOf(name)
+}
+
+func (qs *QuadStore) NameOf(v graph.Value) quad.Value {
+	return qs.qs.NameOf(v)
+}
+
+// Size returns the number of quads in the view, if the underlying store can tell it exactly,
+// or an estimate otherwise.
+func (qs *QuadStore) Size() int64 {
+	it := qs.labeled()
+	defer it.Close()
+	n, _ := it.Size()
+	return n
+}
+
+func (qs *QuadStore) Horizon() graph.PrimaryKey {
+	return qs.qs.Horizon()
+}
+
+func (qs *QuadStore) FixedIterator() graph.FixedIterator {
+	return qs.qs.FixedIterator()
+}
+
+// OptimizeIterator replaces links to a single fixed node with quads of the node in the view.
+//
+// Iterators are never passed to the underlying store, since its optimizer would replace
+// them with native iterators which know nothing about the view.
+func (qs *QuadStore) OptimizeIterator(it graph.Iterator) (graph.Iterator, bool) {
+	if it.Type() != graph.LinksTo {
+		return it, false
+	}
+	lto := it.(*iterator.LinksTo)
+	subs := lto.SubIterators()
+	if len(subs) != 1 {
+		return it, false
+	}
+	primary := subs[0]
+	if primary.Type() != graph.Fixed {
+		return it, false
+	}
+	if size, _ := primary.Size(); size != 1 {
+		return it, false
+	}
+	if !primary.Next(nil) {
+		panic("unexpected size during optimize")
+	}
+	val := primary.Result()
+	newIt := qs.QuadIterator(lto.Direction(), val)
+	nt := newIt.Tagger()
+	nt.CopyFrom(it)
+	for _, tag := range primary.Tagger().Tags() {
+		nt.AddFixed(tag, val)
+	}
+	it.Close()
+	return newIt, true
+}
+
+// AsOf returns the same view of the underlying store as it was at a given horizon.
+func (qs *QuadStore) AsOf(horizon int64) (graph.QuadStore, error) {
+	sub, err := graph.AsOf(qs.qs, horizon)
+	if err != nil {
+		return nil, err
+	}
+	return New(sub, qs.labels...), nil
+}
+
+func (qs *QuadStore) HorizonAt(t time.Time) (int64, error) {
+	hs, ok := qs.qs.(graph.HistoryStore)
+	if !ok {
+		return 0, graph.ErrOperationNotSupported
+	}
+	return hs.HorizonAt(t)
+}
+
+// FeedSource returns the underlying quad store, since the view shares its change feed.
+func (qs *QuadStore) FeedSource() graph.QuadStore {
+	return qs.qs
+}
+
+// InView checks if a delta changes a quad in the view.
+func (qs *QuadStore) InView(d *graph.Delta) bool {
+	return qs.Has(d.Quad.Label)
+}
+
+// Close closes the underlying quad store.
+func (qs *QuadStore) Close() error {
+	return qs.qs.Close()
+}
+
+func (qs *QuadStore) Type() string {
+	return QuadStoreType
+}
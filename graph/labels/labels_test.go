@@ -0,0 +1,192 @@
+// Copyright 2017 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package labels
+
+import (
+	"net/http"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+
+	"github.com/codelingo/cayley/graph"
+	"github.com/codelingo/cayley/graph/memstore"
+	"github.com/codelingo/cayley/graph/path"
+	"github.com/codelingo/cayley/quad"
+	"github.com/codelingo/cayley/writer"
+)
+
+var testQuads = []quad.Quad{
+	quad.MakeIRI("alice", "follows", "bob", "g1"),
+	quad.MakeIRI("bob", "follows", "charlie", "g1"),
+	quad.MakeIRI("alice", "follows", "dani", "g2"),
+	quad.MakeIRI("alice", "follows", "emily", ""),
+	quad.MakeIRI("dani", "status", "cool", "g3"),
+}
+
+func newStore(t testing.TB) graph.QuadStore {
+	return memstore.New(testQuads...)
+}
+
+func newWriter(t testing.TB, qs graph.QuadStore) graph.QuadWriter {
+	qw, err := writer.NewSingleReplication(qs, nil)
+	require.NoError(t, err)
+	return qw
+}
+
+func sorted(vals []quad.Value) []string {
+	out := make([]string, 0, len(vals))
+	for _, v := range vals {
+		out = append(out, quad.StringOf(v))
+	}
+	sort.Strings(out)
+	return out
+}
+
+func iterValues(t testing.TB, qs graph.QuadStore, it graph.Iterator) []string {
+	vals, err := graph.Iterate(context.TODO(), it).Paths(false).AllValues(qs)
+	require.NoError(t, err)
+	return sorted(vals)
+}
+
+func iterQuads(t testing.TB, qs graph.QuadStore, it graph.Iterator) []quad.Quad {
+	var out []quad.Quad
+	err := graph.Iterate(context.TODO(), it).Paths(false).Each(func(v graph.Value) {
+		out = append(out, qs.Quad(v))
+	})
+	require.NoError(t, err)
+	sort.Sort(quad.ByQuadString(out))
+	return out
+}
+
+func TestView(t *testing.T) {
+	view := New(newStore(t), quad.IRI("g1"), quad.IRI("g2"))
+
+	require.Equal(t, int64(3), view.Size())
+	require.Equal(t, []quad.Quad{testQuads[0], testQuads[2], testQuads[1]}, iterQuads(t, view, view.QuadsAllIterator()))
+	require.Equal(t, []string{
+		"<alice>", "<bob>", "<charlie>", "<dani>", "<follows>", "<g1>", "<g2>",
+	}, iterValues(t, view, view.NodesAllIterator()))
+
+	alice := view.ValueOf(quad.IRI("alice"))
+	require.Equal(t, []quad.Quad{testQuads[0], testQuads[2]}, iterQuads(t, view, view.QuadIterator(quad.Subject, alice)))
+	require.Empty(t, iterQuads(t, view, view.QuadIterator(quad.Label, view.ValueOf(quad.IRI("g3")))))
+
+	p := path.StartPath(view, quad.IRI("alice")).Out(quad.IRI("follows"))
+	vals, err := p.Iterate(context.TODO()).Paths(false).AllValues(view)
+	require.NoError(t, err)
+	require.Equal(t, []string{"<bob>", "<dani>"}, sorted(vals))
+
+	p = path.StartPath(view).Has(quad.IRI("follows"), quad.IRI("charlie"))
+	vals, err = p.Iterate(context.TODO()).Paths(false).AllValues(view)
+	require.NoError(t, err)
+	require.Equal(t, []string{"<bob>"}, sorted(vals))
+
+	// labels that do not exist yet
+	empty := New(newStore(t), quad.IRI("g4"))
+	require.Equal(t, int64(0), empty.Size())
+	require.Empty(t, iterValues(t, empty, empty.NodesAllIterator()))
+}
+
+func TestViewWrite(t *testing.T) {
+	qs := newStore(t)
+	view := New(qs, quad.IRI("g1"), quad.IRI("g2"))
+	w := NewWriter(view, newWriter(t, qs))
+
+	require.NoError(t, w.AddQuad(quad.MakeIRI("charlie", "follows", "dani", "")))
+	require.NoError(t, w.AddQuad(quad.MakeIRI("charlie", "follows", "fred", "g2")))
+	err := w.AddQuad(quad.MakeIRI("charlie", "follows", "greg", "g3"))
+	require.Error(t, err)
+
+	all := iterQuads(t, qs, qs.QuadsAllIterator())
+	require.Contains(t, all, quad.MakeIRI("charlie", "follows", "dani", "g1"))
+	require.Contains(t, all, quad.MakeIRI("charlie", "follows", "fred", "g2"))
+	require.NotContains(t, all, quad.MakeIRI("charlie", "follows", "greg", "g3"))
+
+	require.NoError(t, w.RemoveQuad(quad.MakeIRI("charlie", "follows", "dani", "")))
+	require.NotContains(t, iterQuads(t, qs, qs.QuadsAllIterator()), quad.MakeIRI("charlie", "follows", "dani", "g1"))
+
+	// only quads in the view are removed
+	require.NoError(t, w.RemoveNode(qs.ValueOf(quad.IRI("alice"))))
+	require.Equal(t, []quad.Quad{
+		testQuads[3],
+		testQuads[1],
+		quad.MakeIRI("charlie", "follows", "fred", "g2"),
+		testQuads[4],
+	}, iterQuads(t, qs, qs.QuadsAllIterator()))
+
+	require.NoError(t, view.ApplyDeltas([]graph.Delta{
+		{Quad: quad.MakeIRI("fred", "follows", "greg", ""), Action: graph.Add},
+	}, graph.IgnoreOpts{}))
+	require.Contains(t, iterQuads(t, qs, qs.QuadsAllIterator()), quad.MakeIRI("fred", "follows", "greg", "g1"))
+}
+
+func TestViewFeed(t *testing.T) {
+	qs := newStore(t)
+	w := newWriter(t, qs)
+	view := New(qs, quad.IRI("g1"))
+
+	deltas, cancel := graph.Subscribe(view, graph.DeltaFilter{})
+	defer cancel()
+	require.NoError(t, w.AddQuadSet([]quad.Quad{
+		quad.MakeIRI("charlie", "follows", "dani", "g1"),
+		quad.MakeIRI("charlie", "follows", "fred", "g2"),
+	}))
+	require.NoError(t, NewWriter(view, w).AddQuad(quad.MakeIRI("charlie", "follows", "greg", "")))
+
+	var got []quad.Quad
+	for i := 0; i < 2; i++ {
+		batch := <-deltas
+		for i := range batch {
+			got = append(got, batch[i].Quad)
+		}
+	}
+	require.Equal(t, []quad.Quad{
+		quad.MakeIRI("charlie", "follows", "dani", "g1"),
+		quad.MakeIRI("charlie", "follows", "greg", "g1"),
+	}, got)
+}
+
+func TestNewForRequest(t *testing.T) {
+	qs := newStore(t)
+	for _, c := range []struct {
+		url    string
+		opts   graph.Options
+		expect []quad.Value
+	}{
+		{url: "/api/v1/query/gizmo"},
+		{url: "/api/v1/query/gizmo?graph=", expect: nil},
+		{url: "/api/v1/query/gizmo?graph=<g1>", expect: []quad.Value{quad.IRI("g1")}},
+		{url: "/api/v1/query/gizmo?graph=<g1>,%20<g2>", expect: []quad.Value{quad.IRI("g1"), quad.IRI("g2")}},
+		{url: "/api/v1/query/gizmo", opts: graph.Options{"graph": "_:g"}, expect: []quad.Value{quad.BNode("g")}},
+	} {
+		r, err := http.NewRequest("POST", c.url, nil)
+		require.NoError(t, err)
+		opts := graph.Options{"HTTPRequest": r}
+		for k, v := range c.opts {
+			opts[k] = v
+		}
+		got, err := NewForRequest(qs, opts)
+		require.NoError(t, err)
+		if c.expect == nil {
+			require.True(t, got == qs, "expected the store itself for %q", c.url)
+			continue
+		}
+		view, ok := got.(*QuadStore)
+		require.True(t, ok, "expected a view for %q", c.url)
+		require.Equal(t, c.expect, view.Labels())
+	}
+}
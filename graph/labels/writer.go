@@ -0,0 +1,103 @@
+// Copyright 2017 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package labels
+
+import (
+	"github.com/codelingo/cayley/graph"
+	"github.com/codelingo/cayley/quad"
+)
+
+var _ graph.QuadWriter = (*Writer)(nil)
+
+// Writer writes quads through a view, using a writer of the underlying quad store.
+// Quads without a label are written to the default label of the view.
+type Writer struct {
+	qs *QuadStore
+	w  graph.QuadWriter
+}
+
+// NewWriter creates a writer for a view. The underlying writer must write to the store wrapped by the view.
+func NewWriter(qs *QuadStore, w graph.QuadWriter) *Writer {
+	return &Writer{qs: qs, w: w}
+}
+
+func (w *Writer) AddQuad(q quad.Quad) error {
+	q, err := w.qs.scope(q)
+	if err != nil {
+		return err
+	}
+	return w.w.AddQuad(q)
+}
+
+func (w *Writer) AddQuadSet(set []quad.Quad) error {
+	scoped := make([]quad.Quad, len(set))
+	for i, q := range set {
+		var err error
+		if scoped[i], err = w.qs.scope(q); err != nil {
+			return err
+		}
+	}
+	return w.w.AddQuadSet(scoped)
+}
+
+func (w *Writer) RemoveQuad(q quad.Quad) error {
+	q, err := w.qs.scope(q)
+	if err != nil {
+		return err
+	}
+	return w.w.RemoveQuad(q)
+}
+
+func (w *Writer) ApplyTransaction(t *graph.Transaction) error {
+	tx := graph.NewTransaction()
+	for i := range t.Deltas {
+		d := &t.Deltas[i]
+		q, err := w.qs.scope(d.Quad)
+		if err != nil {
+			return err
+		}
+		if d.Action == graph.Add {
+			tx.AddQuad(q)
+		} else {
+			tx.RemoveQuad(q)
+		}
+	}
+	return w.w.ApplyTransaction(tx)
+}
+
+// RemoveNode removes all quads in the view which have the given node as subject, predicate, object, or label.
+func (w *Writer) RemoveNode(v graph.Value) error {
+	tx := graph.NewTransaction()
+	for _, d := range quad.Directions {
+		it := w.qs.QuadIterator(d, v)
+		for it.Next(nil) {
+			tx.RemoveQuad(w.qs.Quad(it.Result()))
+		}
+		err := it.Err()
+		it.Close()
+		if err != nil {
+			return err
+		}
+	}
+	if len(tx.Deltas) == 0 {
+		return nil
+	}
+	return w.w.ApplyTransaction(tx)
+}
+
+// Close does nothing, since the underlying writer is usually shared with other views.
+func (w *Writer) Close() error {
+	return nil
+}
@@ -101,7 +101,12 @@ func (it *AllIterator) Clone() graph.Iterator {
 	return out
 }
 
-func (it *AllIterator) Next() bool {
+func (it *AllIterator) Next(ctx *graph.IterationContext) bool {
+	graph.NextLogIn(ctx, it)
+	return graph.NextLogOut(ctx, it, it.next())
+}
+
+func (it *AllIterator) next() bool {
 	if it.iter == nil {
 		it.result = nil
 		return false
@@ -115,7 +120,12 @@ func (it *AllIterator) Next() bool {
 	}
 	for {
 		out := clone(it.iter.Key())
-		live := it.nodes || isLiveValue(it.iter.Value())
+		var live bool
+		if it.nodes {
+			live = !it.qs.view || it.qs.hasLiveQuad(out)
+		} else {
+			live = it.qs.isLiveValue(it.iter.Value())
+		}
 		if !it.iter.Next() {
 			it.Close()
 			if !live {
@@ -151,7 +161,12 @@ func (it *AllIterator) SubIterators() []graph.Iterator {
 	return nil
 }
 
-func (it *AllIterator) Contains(v graph.Value) bool {
+func (it *AllIterator) Contains(ctx *graph.IterationContext, v graph.Value) bool {
+	graph.ContainsLogIn(ctx, it, v)
+	return graph.ContainsLogOut(ctx, it, v, it.contains(v))
+}
+
+func (it *AllIterator) contains(v graph.Value) bool {
 	it.result = v
 	return true
 }
@@ -0,0 +1,97 @@
+// Copyright 2017 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package leveldb
+
+import (
+	"sort"
+	"time"
+
+	"github.com/syndtr/goleveldb/leveldb/util"
+
+	"github.com/codelingo/cayley/graph"
+	"github.com/codelingo/cayley/graph/proto"
+)
+
+var _ graph.HistoryStore = (*QuadStore)(nil)
+
+// history returns the part of the quad history (a list of delta IDs) visible in this quad store.
+func (qs *QuadStore) history(h []uint64) []uint64 {
+	if !qs.view {
+		return h
+	}
+	n := sort.Search(len(h), func(i int) bool {
+		return int64(h[i]) > qs.horizon
+	})
+	return h[:n]
+}
+
+// hasLiveQuad checks if a node is used by any quad visible in this quad store.
+func (qs *QuadStore) hasLiveQuad(node Token) bool {
+	for _, pref := range []string{"sp", "po", "os", "cp"} {
+		it := qs.db.NewIterator(util.BytesPrefix(append([]byte(pref), node[1:]...)), qs.readopts)
+		live := false
+		for !live && it.Next() {
+			live = qs.isLiveValue(it.Value())
+		}
+		it.Release()
+		if live {
+			return true
+		}
+	}
+	return false
+}
+
+// AsOf returns a read-only view of the graph at a given horizon.
+//
+// Quads are checked against the history stored in the indexes, thus the view is
+// as fast as the store itself. Node and quad counts are estimated from the current state.
+func (qs *QuadStore) AsOf(horizon int64) (graph.QuadStore, error) {
+	if horizon > qs.horizon {
+		horizon = qs.horizon
+	}
+	return &QuadStore{
+		dbOpts:    qs.dbOpts,
+		db:        qs.db,
+		path:      qs.path,
+		open:      qs.open,
+		size:      qs.size,
+		horizon:   horizon,
+		writeopts: qs.writeopts,
+		readopts:  qs.readopts,
+		view:      true,
+		vopts:     qs.vopts,
+	}, nil
+}
+
+// HorizonAt returns the ID of the last delta applied before or at a given time.
+//
+// Delta keys are not ordered by ID, thus it scans the whole log.
+func (qs *QuadStore) HorizonAt(t time.Time) (int64, error) {
+	ts := t.UnixNano()
+	var id int64
+	it := qs.db.NewIterator(util.BytesPrefix([]byte{'d'}), qs.readopts)
+	defer it.Release()
+	for it.Next() {
+		var d proto.LogDelta
+		if err := d.Unmarshal(it.Value()); err != nil {
+			return 0, err
+		}
+		did := int64(d.ID)
+		if d.Timestamp <= ts && did > id && (!qs.view || did <= qs.horizon) {
+			id = did
+		}
+	}
+	return id, it.Error()
+}
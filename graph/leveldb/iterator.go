@@ -116,7 +116,12 @@ func (it *Iterator) Close() error {
 	return nil
 }
 
-func (it *Iterator) Next() bool {
+func (it *Iterator) Next(ctx *graph.IterationContext) bool {
+	graph.NextLogIn(ctx, it)
+	return graph.NextLogOut(ctx, it, it.next())
+}
+
+func (it *Iterator) next() bool {
 	if it.iter == nil {
 		it.result = nil
 		return false
@@ -135,7 +140,7 @@ func (it *Iterator) Next() bool {
 			it.result = nil
 			return false
 		}
-		if !isLiveValue(it.iter.Value()) {
+		if !it.qs.isLiveValue(it.iter.Value()) {
 			if !it.iter.Next() {
 				it.Close()
 				return false
@@ -220,7 +225,12 @@ func PositionOf(prefix []byte, d quad.Direction, qs *QuadStore) int {
 	panic("unreachable")
 }
 
-func (it *Iterator) Contains(v graph.Value) bool {
+func (it *Iterator) Contains(ctx *graph.IterationContext, v graph.Value) bool {
+	graph.ContainsLogIn(ctx, it, v)
+	return graph.ContainsLogOut(ctx, it, v, it.contains(v))
+}
+
+func (it *Iterator) contains(v graph.Value) bool {
 	val := v.(Token)
 	if val.IsNode() {
 		return false
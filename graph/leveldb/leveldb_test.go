@@ -24,6 +24,7 @@ import (
 	"github.com/codelingo/cayley/graph/graphtest"
 	"github.com/codelingo/cayley/graph/iterator"
 	"github.com/codelingo/cayley/graph/path/pathtest"
+	"github.com/codelingo/cayley/graph/valueindex"
 	"github.com/codelingo/cayley/quad"
 	"github.com/codelingo/cayley/writer"
 )
@@ -73,6 +74,71 @@ func makeLevelDB(t testing.TB) (graph.QuadStore, graph.Options, func()) {
 	}
 }
 
+var valueIndexOpts = graph.Options{
+	valueindex.OptIndex:            true,
+	valueindex.OptIndexByPredicate: true,
+}
+
+func TestLevelDBValueIndex(t *testing.T) {
+	graphtest.TestValueIndex(t, func(t testing.TB) (graph.QuadStore, graph.Options, func()) {
+		tmpDir, err := ioutil.TempDir(os.TempDir(), "cayley_test")
+		if err != nil {
+			t.Fatalf("Could not create working directory: %v", err)
+		}
+		err = createNewLevelDB(tmpDir, nil)
+		if err != nil {
+			os.RemoveAll(tmpDir)
+			t.Fatal("Failed to create LevelDB database.", err)
+		}
+		qs, err := newQuadStore(tmpDir, valueIndexOpts)
+		if qs == nil || err != nil {
+			os.RemoveAll(tmpDir)
+			t.Fatal("Failed to create LevelDB QuadStore.", err)
+		}
+		return qs, nil, func() {
+			qs.Close()
+			os.RemoveAll(tmpDir)
+		}
+	})
+}
+
+func TestLevelDBValueIndexBackfill(t *testing.T) {
+	tmpDir, err := ioutil.TempDir(os.TempDir(), "cayley_test")
+	if err != nil {
+		t.Fatalf("Could not create working directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	err = createNewLevelDB(tmpDir, nil)
+	if err != nil {
+		t.Fatal("Failed to create LevelDB database.", err)
+	}
+	qs, err := newQuadStore(tmpDir, nil)
+	if err != nil {
+		t.Fatal("Failed to create LevelDB QuadStore.", err)
+	}
+	graphtest.MakeWriter(t, qs, nil,
+		quad.Make(quad.IRI("alice"), quad.IRI("age"), quad.Int(30), nil),
+		quad.Make(quad.IRI("bob"), quad.IRI("age"), quad.Int(25), nil),
+	)
+	qs.Close()
+
+	qs, err = newQuadStore(tmpDir, valueIndexOpts)
+	if err != nil {
+		t.Fatal("Failed to open LevelDB QuadStore.", err)
+	}
+	defer qs.Close()
+
+	pred := iterator.NewHasA(qs, qs.QuadIterator(quad.Predicate, qs.ValueOf(quad.IRI("age"))), quad.Object)
+	for _, sub := range []graph.Iterator{qs.NodesAllIterator(), pred} {
+		it, ok := qs.OptimizeIterator(iterator.NewComparison(sub, iterator.CompareGT, quad.Int(26), qs))
+		if !ok {
+			t.Fatal("Comparison was not optimized")
+		}
+		graphtest.ExpectIteratedValues(t, qs, it, []quad.Value{quad.Int(30)})
+	}
+}
+
 func TestLevelDBAll(t *testing.T) {
 	graphtest.TestAll(t, makeLevelDB, &graphtest.Config{
 		SkipDeletedFromIterator: true,
@@ -194,10 +260,10 @@ func TestOptimize(t *testing.T) {
 		t.Errorf("Optimized iteration does not match original")
 	}
 
-	oldIt.Next()
+	oldIt.Next(nil)
 	oldResults := make(map[string]graph.Value)
 	oldIt.TagResults(oldResults)
-	newIt.Next()
+	newIt.Next(nil)
 	newResults := make(map[string]graph.Value)
 	newIt.TagResults(newResults)
 	if !reflect.DeepEqual(newResults, oldResults) {
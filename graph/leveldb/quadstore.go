@@ -27,6 +27,7 @@ import (
 	"github.com/codelingo/cayley/graph"
 	"github.com/codelingo/cayley/graph/iterator"
 	"github.com/codelingo/cayley/graph/proto"
+	"github.com/codelingo/cayley/graph/valueindex"
 	"github.com/codelingo/cayley/quad"
 	"github.com/codelingo/cayley/quad/pquads"
 )
@@ -68,10 +69,10 @@ func clone(b []byte) []byte {
 	return out
 }
 
-func isLiveValue(val []byte) bool {
+func (qs *QuadStore) isLiveValue(val []byte) bool {
 	var entry proto.HistoryEntry
 	entry.Unmarshal(val)
-	return len(entry.History)%2 != 0
+	return len(qs.history(entry.History))%2 != 0
 }
 
 type QuadStore struct {
@@ -83,6 +84,9 @@ type QuadStore struct {
 	horizon   int64
 	writeopts *opt.WriteOptions
 	readopts  *opt.ReadOptions
+	view      bool // read-only view of the graph at the horizon; see AsOf
+
+	vopts valueindex.Options // value indexes kept by the store
 }
 
 func createNewLevelDB(path string, _ graph.Options) error {
@@ -167,6 +171,15 @@ func newQuadStore(path string, options graph.Options) (graph.QuadStore, error) {
 		db.Close()
 		return nil, err
 	}
+	qs.vopts, err = valueindex.ParseOptions(options)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	if err = qs.initValueIndexes(); err != nil {
+		db.Close()
+		return nil, err
+	}
 	return &qs, nil
 }
 
@@ -252,6 +265,9 @@ func deltaToProto(delta graph.Delta) proto.LogDelta {
 }
 
 func (qs *QuadStore) ApplyDeltas(deltas []graph.Delta, ignoreOpts graph.IgnoreOpts) error {
+	if qs.view {
+		return graph.ErrReadOnlyView
+	}
 	batch := &leveldb.Batch{}
 	resizeMap := make(map[quad.Value]int64)
 	sizeChange := int64(0)
@@ -340,6 +356,9 @@ func (qs *QuadStore) buildQuadWrite(batch *leveldb.Batch, q quad.Quad, id int64,
 	if q.Get(quad.Label) != nil {
 		batch.Put(createKeyFor(cps, q), bytes)
 	}
+	if isAdd {
+		qs.indexQuad(batch, q)
+	}
 	return nil
 }
 
@@ -389,6 +408,10 @@ func (qs *QuadStore) UpdateValueKeyBy(name quad.Value, amount int64, batch *leve
 }
 
 func (qs *QuadStore) Close() error {
+	if qs.view {
+		// database is owned by the parent quad store
+		return nil
+	}
 	buf := new(bytes.Buffer)
 	err := binary.Write(buf, order, qs.size)
 	if err == nil {
@@ -429,7 +452,11 @@ func (qs *QuadStore) Quad(k graph.Value) quad.Quad {
 		clog.Errorf("Error: could not reconstruct history. %v", err)
 		return quad.Quad{}
 	}
-	b, err = qs.db.Get(createDeltaKeyFor(int64(in.History[len(in.History)-1])), qs.readopts)
+	hist := qs.history(in.History)
+	if len(hist) == 0 {
+		return quad.Quad{}
+	}
+	b, err = qs.db.Get(createDeltaKeyFor(int64(hist[len(hist)-1])), qs.readopts)
 	if err == leveldb.ErrNotFound {
 		// No harm, no foul.
 		return quad.Quad{}
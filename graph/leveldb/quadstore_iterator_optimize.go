@@ -23,7 +23,8 @@ func (qs *QuadStore) OptimizeIterator(it graph.Iterator) (graph.Iterator, bool)
 	switch it.Type() {
 	case graph.LinksTo:
 		return qs.optimizeLinksTo(it.(*iterator.LinksTo))
-
+	case graph.Comparison:
+		return qs.optimizeComparison(it.(*iterator.Comparison))
 	}
 	return it, false
 }
@@ -37,7 +38,7 @@ func (qs *QuadStore) optimizeLinksTo(it *iterator.LinksTo) (graph.Iterator, bool
 	if primary.Type() == graph.Fixed {
 		size, _ := primary.Size()
 		if size == 1 {
-			if !primary.Next() {
+			if !primary.Next(nil) {
 				panic("unexpected size during optimize")
 			}
 			val := primary.Result()
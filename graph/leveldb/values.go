@@ -0,0 +1,198 @@
+// Copyright 2017 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package leveldb
+
+import (
+	"bytes"
+
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+
+	"github.com/codelingo/cayley/clog"
+	"github.com/codelingo/cayley/graph"
+	"github.com/codelingo/cayley/graph/iterator"
+	"github.com/codelingo/cayley/graph/proto"
+	"github.com/codelingo/cayley/graph/valueindex"
+	"github.com/codelingo/cayley/quad"
+)
+
+const (
+	// valuePrefix starts keys of the value index: value keys followed by node hashes.
+	valuePrefix = 'v'
+	// predValuePrefix starts keys of the value index by predicate: predicate hashes, value keys and object hashes.
+	predValuePrefix = 'w'
+
+	valueIndexKey     = "__value_index"
+	predValueIndexKey = "__value_index_by_predicate"
+)
+
+// initValueIndexes builds value indexes that are enabled in options and drops the ones that
+// are not, since the latter are not updated on writes. A marker key is kept for each built index.
+func (qs *QuadStore) initValueIndexes() error {
+	for _, ind := range []struct {
+		prefix  byte
+		marker  string
+		enabled bool
+		fill    func(batch *leveldb.Batch) error
+	}{
+		{valuePrefix, valueIndexKey, qs.vopts.Global, qs.fillValueIndex},
+		{predValuePrefix, predValueIndexKey, qs.vopts.ByPredicate, qs.fillPredValueIndex},
+	} {
+		exists, err := qs.db.Has([]byte(ind.marker), qs.readopts)
+		if err != nil {
+			return err
+		} else if exists == ind.enabled {
+			continue
+		}
+		batch := &leveldb.Batch{}
+		if exists {
+			it := qs.db.NewIterator(util.BytesPrefix([]byte{ind.prefix}), qs.readopts)
+			for it.Next() {
+				batch.Delete(clone(it.Key()))
+			}
+			it.Release()
+			if err = it.Error(); err != nil {
+				return err
+			}
+			batch.Delete([]byte(ind.marker))
+		} else {
+			clog.Infof("leveldb: building %s index", ind.marker)
+			if err = ind.fill(batch); err != nil {
+				return err
+			}
+			batch.Put([]byte(ind.marker), []byte{1})
+		}
+		if err = qs.db.Write(batch, qs.writeopts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (qs *QuadStore) fillValueIndex(batch *leveldb.Batch) error {
+	it := qs.db.NewIterator(util.BytesPrefix([]byte{'z'}), qs.readopts)
+	defer it.Release()
+	for it.Next() {
+		var nd proto.NodeData
+		if err := nd.Unmarshal(it.Value()); err != nil {
+			return err
+		}
+		indexValue(batch, nd.GetNativeValue(), it.Key()[1:])
+	}
+	return it.Error()
+}
+
+func (qs *QuadStore) fillPredValueIndex(batch *leveldb.Batch) error {
+	it := qs.db.NewIterator(util.BytesPrefix([]byte("po")), qs.readopts)
+	defer it.Release()
+	for it.Next() {
+		k := it.Key()[2:]
+		p, o := k[:quad.HashSize], k[quad.HashSize:2*quad.HashSize]
+		nd := qs.valueData(append([]byte{'z'}, o...))
+		indexPredValue(batch, p, nd.GetNativeValue(), o)
+	}
+	return it.Error()
+}
+
+func indexValue(batch *leveldb.Batch, v quad.Value, node []byte) {
+	k, ok := valueindex.Key(v)
+	if !ok {
+		return
+	}
+	key := make([]byte, 0, 1+len(k)+len(node))
+	key = append(key, valuePrefix)
+	key = append(key, k...)
+	key = append(key, node...)
+	batch.Put(key, nil)
+}
+
+func indexPredValue(batch *leveldb.Batch, pred []byte, v quad.Value, node []byte) {
+	k, ok := valueindex.Key(v)
+	if !ok {
+		return
+	}
+	key := make([]byte, 0, 1+len(pred)+len(k)+len(node))
+	key = append(key, predValuePrefix)
+	key = append(key, pred...)
+	key = append(key, k...)
+	key = append(key, node...)
+	batch.Put(key, nil)
+}
+
+// indexQuad adds values of an added quad to value indexes. Index entries are never removed,
+// the same way as nodes are never removed from the store.
+func (qs *QuadStore) indexQuad(batch *leveldb.Batch, q quad.Quad) {
+	if qs.vopts.Global {
+		for _, d := range quad.Directions {
+			if v := q.Get(d); v != nil {
+				indexValue(batch, v, createValueKeyFor(v)[1:])
+			}
+		}
+	}
+	if qs.vopts.ByPredicate {
+		indexPredValue(batch, createValueKeyFor(q.Predicate)[1:], q.Object, createValueKeyFor(q.Object)[1:])
+	}
+}
+
+// scanValues returns a function that reads entries of a value index. Index keys end with node hashes.
+func (qs *QuadStore) scanValues(prefix byte) valueindex.ScanFunc {
+	return func(from, to []byte, n int) ([][]byte, []graph.Value, error) {
+		var (
+			keys [][]byte
+			vals []graph.Value
+		)
+		it := qs.db.NewIterator(&util.Range{
+			Start: append([]byte{prefix}, from...),
+			Limit: append([]byte{prefix}, to...),
+		}, qs.readopts)
+		defer it.Release()
+		for len(keys) < n && it.Next() {
+			k := it.Key()
+			keys = append(keys, clone(k[1:]))
+			vals = append(vals, Token(append([]byte{'z'}, k[len(k)-quad.HashSize:]...)))
+		}
+		return keys, vals, it.Error()
+	}
+}
+
+// predicateOf checks if an iterator returns objects of quads with a given predicate.
+func (qs *QuadStore) predicateOf(it graph.Iterator) ([]byte, bool) {
+	hasa, ok := it.(*iterator.HasA)
+	if !ok || hasa.Direction() != quad.Object {
+		return nil, false
+	}
+	sub, ok := hasa.SubIterators()[0].(*Iterator)
+	if !ok || sub.dir != quad.Predicate || !bytes.HasPrefix(sub.checkID, []byte{'z'}) {
+		return nil, false
+	}
+	return sub.checkID[1:], true
+}
+
+func (qs *QuadStore) optimizeComparison(it *iterator.Comparison) (graph.Iterator, bool) {
+	if !valueindex.Indexable(it) {
+		return it, false
+	}
+	sub := it.SubIterators()[0]
+	if p, ok := qs.predicateOf(sub); ok && qs.vopts.ByPredicate {
+		rit := valueindex.NewIterator(qs, it.Operator(), it.Value(), p, qs.scanValues(predValuePrefix))
+		return valueindex.Intersect(qs, it, rit, false), true
+	}
+	if !qs.vopts.Global {
+		return it, false
+	}
+	all, ok := sub.(*AllIterator)
+	rit := valueindex.NewIterator(qs, it.Operator(), it.Value(), nil, qs.scanValues(valuePrefix))
+	return valueindex.Intersect(qs, it, rit, ok && all.nodes), true
+}
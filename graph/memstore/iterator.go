@@ -119,9 +119,9 @@ func (it *Iterator) checkValid(index int64) bool {
 }
 
 func (it *Iterator) Next(ctx *graph.IterationContext) bool {
-	graph.NextLogIn(it)
+	graph.NextLogIn(ctx, it)
 	if it.iter == nil {
-		return graph.NextLogOut(it, false)
+		return graph.NextLogOut(ctx, it, false)
 	}
 	for {
 		result, _, err := it.iter.Next()
@@ -129,13 +129,13 @@ func (it *Iterator) Next(ctx *graph.IterationContext) bool {
 			if err != io.EOF {
 				it.err = err
 			}
-			return graph.NextLogOut(it, false)
+			return graph.NextLogOut(ctx, it, false)
 		}
 		if !it.checkValid(result) {
 			continue
 		}
 		it.result = result
-		return graph.NextLogOut(it, true)
+		return graph.NextLogOut(ctx, it, true)
 	}
 }
 
@@ -164,11 +164,11 @@ func (it *Iterator) Size() (int64, bool) {
 }
 
 func (it *Iterator) Contains(ctx *graph.IterationContext, v graph.Value) bool {
-	graph.ContainsLogIn(it, v)
+	graph.ContainsLogIn(ctx, it, v)
 	if v == nil {
-		return graph.ContainsLogOut(it, v, false)
+		return graph.ContainsLogOut(ctx, it, v, false)
 	} else if it.nodes != it.qs.isNode(v) {
-		return graph.ContainsLogOut(it, v, false)
+		return graph.ContainsLogOut(ctx, it, v, false)
 	}
 	var vi int64
 	if it.nodes {
@@ -178,9 +178,9 @@ func (it *Iterator) Contains(ctx *graph.IterationContext, v graph.Value) bool {
 	}
 	if _, ok := it.tree.Get(vi); ok {
 		it.result = vi
-		return graph.ContainsLogOut(it, v, true)
+		return graph.ContainsLogOut(ctx, it, v, true)
 	}
-	return graph.ContainsLogOut(it, v, false)
+	return graph.ContainsLogOut(ctx, it, v, false)
 }
 
 func (it *Iterator) Describe() graph.Description {
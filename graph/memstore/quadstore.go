@@ -23,6 +23,7 @@ import (
 	"github.com/codelingo/cayley/graph"
 	"github.com/codelingo/cayley/graph/iterator"
 	"github.com/codelingo/cayley/graph/memstore/b"
+	"github.com/codelingo/cayley/graph/valueindex"
 	"github.com/codelingo/cayley/quad"
 )
 
@@ -30,8 +31,14 @@ const QuadStoreType = "memstore"
 
 func init() {
 	graph.RegisterQuadStore(QuadStoreType, graph.QuadStoreRegistration{
-		NewFunc: func(string, graph.Options) (graph.QuadStore, error) {
-			return newQuadStore(), nil
+		NewFunc: func(_ string, opts graph.Options) (graph.QuadStore, error) {
+			vopts, err := valueindex.ParseOptions(opts)
+			if err != nil {
+				return nil, err
+			}
+			qs := newQuadStore()
+			qs.EnableValueIndex(vopts)
+			return qs, nil
 		},
 		NewForRequestFunc: nil,
 		UpgradeFunc:       nil,
@@ -96,7 +103,8 @@ type QuadStore struct {
 	log        []LogEntry
 	size       int64
 	index      QuadDirectionIndex
-	// vip_index map[string]map[int64]map[string]map[int64]*b.Tree
+	values     *valueList           // ordered index of typed values; see EnableValueIndex
+	predValues map[int64]*valueList // ordered index of typed objects by predicate
 }
 
 // New creates a new in-memory quad store and loads provided quads.
@@ -254,9 +262,19 @@ func (qs *QuadStore) AddDelta(d graph.Delta) error {
 		tree := qs.index.Tree(dir, id)
 		tree.Set(qid, struct{}{})
 		l.IDs[int(dir)-1] = id
+		if qs.values != nil {
+			qs.values.add(sid, id)
+		}
+	}
+	if qs.predValues != nil {
+		pid := l.IDs[quad.Predicate-1]
+		vals := qs.predValues[pid]
+		if vals == nil {
+			vals = newValueList()
+			qs.predValues[pid] = vals
+		}
+		vals.add(d.Quad.Object, l.IDs[quad.Object-1])
 	}
-
-	// TODO(barakmich): Add VIP indexing
 	return nil
 }
 
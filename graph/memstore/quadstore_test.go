@@ -22,6 +22,7 @@ import (
 	"github.com/codelingo/cayley/graph"
 	"github.com/codelingo/cayley/graph/graphtest"
 	"github.com/codelingo/cayley/graph/iterator"
+	"github.com/codelingo/cayley/graph/valueindex"
 	"github.com/codelingo/cayley/quad"
 	"github.com/codelingo/cayley/writer"
 )
@@ -84,6 +85,61 @@ func TestMemstoreAll(t *testing.T) {
 	})
 }
 
+func TestMemstoreValueIndex(t *testing.T) {
+	graphtest.TestValueIndex(t, func(t testing.TB) (graph.QuadStore, graph.Options, func()) {
+		qs := newQuadStore()
+		qs.EnableValueIndex(valueindex.Options{Global: true, ByPredicate: true})
+		return qs, nil, func() {}
+	})
+}
+
+func TestMemstoreValueIndexBackfill(t *testing.T) {
+	qs := New(
+		quad.Make(quad.IRI("alice"), quad.IRI("age"), quad.Int(30), nil),
+		quad.Make(quad.IRI("bob"), quad.IRI("age"), quad.Int(25), nil),
+	)
+	qs.EnableValueIndex(valueindex.Options{Global: true, ByPredicate: true})
+
+	pred := iterator.NewHasA(qs, qs.QuadIterator(quad.Predicate, qs.ValueOf(quad.IRI("age"))), quad.Object)
+	for _, sub := range []graph.Iterator{qs.NodesAllIterator(), pred} {
+		it, ok := qs.OptimizeIterator(iterator.NewComparison(sub, iterator.CompareGT, quad.Int(26), qs))
+		if !ok {
+			t.Fatal("Comparison was not optimized")
+		}
+		graphtest.ExpectIteratedValues(t, qs, it, []quad.Value{quad.Int(30)})
+	}
+}
+
+func TestMemstoreValueIndexConcurrent(t *testing.T) {
+	qs := newQuadStore()
+	qs.EnableValueIndex(valueindex.Options{Global: true})
+	var quads []quad.Quad
+	for i := 0; i < 100; i++ {
+		quads = append(quads, quad.Make(quad.IRI("n"), quad.IRI("v"), quad.Int(100-i), nil))
+	}
+	w, _ := writer.NewSingleReplication(qs, nil)
+	if err := w.AddQuadSet(quads); err != nil {
+		t.Fatal(err)
+	}
+	// index is not sorted yet; all readers will find it in this state
+	counts := make(chan int)
+	for i := 0; i < 4; i++ {
+		go func() {
+			it, _ := qs.OptimizeIterator(iterator.NewComparison(qs.NodesAllIterator(), iterator.CompareGT, quad.Int(50), qs))
+			n := 0
+			for it.Next(nil) {
+				n++
+			}
+			counts <- n
+		}()
+	}
+	for i := 0; i < 4; i++ {
+		if n := <-counts; n != 50 {
+			t.Errorf("unexpected number of values: %d", n)
+		}
+	}
+}
+
 type pair struct {
 	query string
 	value int64
@@ -126,7 +182,7 @@ func TestIteratorsAndNextResultOrderA(t *testing.T) {
 	hasa := iterator.NewHasA(qs, innerAnd, quad.Subject)
 	outerAnd := iterator.NewAnd(qs, fixed, hasa)
 
-	if !outerAnd.Next() {
+	if !outerAnd.Next(nil) {
 		t.Error("Expected one matching subtree")
 	}
 	val := outerAnd.Result()
@@ -150,7 +206,7 @@ func TestIteratorsAndNextResultOrderA(t *testing.T) {
 		t.Errorf("Unexpected result, got:%q expect:%q", got, expect)
 	}
 
-	if outerAnd.Next() {
+	if outerAnd.Next(nil) {
 		t.Error("More than one possible top level output?")
 	}
 }
@@ -214,7 +270,7 @@ func TestRemoveQuad(t *testing.T) {
 	hasa := iterator.NewHasA(qs, innerAnd, quad.Object)
 
 	newIt, _ := hasa.Optimize()
-	if newIt.Next() {
+	if newIt.Next(nil) {
 		t.Error("E should not have any followers.")
 	}
 }
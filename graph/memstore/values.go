@@ -0,0 +1,149 @@
+// Copyright 2017 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memstore
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/codelingo/cayley/graph"
+	"github.com/codelingo/cayley/graph/iterator"
+	"github.com/codelingo/cayley/graph/valueindex"
+	"github.com/codelingo/cayley/quad"
+)
+
+type valueEntry struct {
+	key string
+	id  int64
+}
+
+// valueList is an ordered index of typed values. Entries are appended as nodes are added,
+// and are sorted on the first scan after that. Scans may run concurrently, thus the lock.
+type valueList struct {
+	mu      sync.Mutex
+	entries []valueEntry
+	sorted  bool
+	seen    map[int64]struct{}
+}
+
+func newValueList() *valueList {
+	return &valueList{seen: make(map[int64]struct{})}
+}
+
+func (l *valueList) add(v quad.Value, id int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.seen[id]; ok {
+		return
+	}
+	k, ok := valueindex.Key(v)
+	if !ok {
+		return
+	}
+	l.seen[id] = struct{}{}
+	l.entries = append(l.entries, valueEntry{key: string(k), id: id})
+	l.sorted = false
+}
+
+func (l *valueList) Len() int           { return len(l.entries) }
+func (l *valueList) Less(i, j int) bool { return l.entries[i].key < l.entries[j].key }
+func (l *valueList) Swap(i, j int)      { l.entries[i], l.entries[j] = l.entries[j], l.entries[i] }
+
+func (l *valueList) scan(from, to []byte, n int) ([][]byte, []graph.Value, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.sorted {
+		sort.Sort(l)
+		l.sorted = true
+	}
+	lo, hi := string(from), string(to)
+	i := sort.Search(len(l.entries), func(i int) bool {
+		return l.entries[i].key >= lo
+	})
+	var (
+		keys [][]byte
+		vals []graph.Value
+	)
+	for ; i < len(l.entries) && len(vals) < n; i++ {
+		e := l.entries[i]
+		if e.key >= hi {
+			break
+		}
+		keys = append(keys, []byte(e.key))
+		vals = append(vals, iterator.Int64Node(e.id))
+	}
+	return keys, vals, nil
+}
+
+// EnableValueIndex enables ordered indexes of quad.Int, quad.Float and quad.Time values,
+// which are used to optimize Comparison iterators. Values already in the store are indexed as well.
+func (qs *QuadStore) EnableValueIndex(opts valueindex.Options) {
+	if opts.Global && qs.values == nil {
+		qs.values = newValueList()
+		for id, v := range qs.revIDMap {
+			qs.values.add(v, id)
+		}
+	}
+	if opts.ByPredicate && qs.predValues == nil {
+		qs.predValues = make(map[int64]*valueList)
+		for i := 1; i < len(qs.log); i++ {
+			l := &qs.log[i]
+			if l.Action != graph.Add {
+				continue
+			}
+			pid := l.IDs[quad.Predicate-1]
+			vals := qs.predValues[pid]
+			if vals == nil {
+				vals = newValueList()
+				qs.predValues[pid] = vals
+			}
+			vals.add(l.Quad.Object, l.IDs[quad.Object-1])
+		}
+	}
+}
+
+// predicateOf checks if an iterator returns objects of quads with a given predicate.
+func (qs *QuadStore) predicateOf(it graph.Iterator) (int64, bool) {
+	hasa, ok := it.(*iterator.HasA)
+	if !ok || hasa.Direction() != quad.Object {
+		return 0, false
+	}
+	sub, ok := hasa.SubIterators()[0].(*Iterator)
+	if !ok || sub.d != quad.Predicate {
+		return 0, false
+	}
+	return int64(sub.value.(iterator.Int64Node)), true
+}
+
+func (qs *QuadStore) optimizeComparison(it *iterator.Comparison) (graph.Iterator, bool) {
+	if !valueindex.Indexable(it) {
+		return it, false
+	}
+	sub := it.SubIterators()[0]
+	if pid, ok := qs.predicateOf(sub); ok && qs.predValues != nil {
+		vals := qs.predValues[pid]
+		if vals == nil {
+			return iterator.NewNull(), true
+		}
+		rit := valueindex.NewIterator(qs, it.Operator(), it.Value(), nil, vals.scan)
+		return valueindex.Intersect(qs, it, rit, false), true
+	}
+	if qs.values == nil {
+		return it, false
+	}
+	_, all := sub.(*nodesAllIterator)
+	rit := valueindex.NewIterator(qs, it.Operator(), it.Value(), nil, qs.values.scan)
+	return valueindex.Intersect(qs, it, rit, all), true
+}
@@ -114,7 +114,7 @@ func (it *LinksTo) Next() bool {
 		Added   []int64 `bson:"Added"`
 		Deleted []int64 `bson:"Deleted"`
 	}
-	graph.NextLogIn(it)
+	graph.NextLogIn(nil, it)
 next:
 	for {
 		it.runstats.Next += 1
@@ -124,7 +124,7 @@ next:
 				continue next
 			}
 			it.result = QuadHash(result.ID)
-			return graph.NextLogOut(it, true)
+			return graph.NextLogOut(nil, it, true)
 		}
 
 		if it.nextIt != nil {
@@ -141,7 +141,7 @@ next:
 			it.err = it.primaryIt.Err()
 
 			// We're out of nodes in our subiterator, so we're done as well.
-			return graph.NextLogOut(it, false)
+			return graph.NextLogOut(nil, it, false)
 		}
 		if it.nextIt != nil {
 			it.nextIt.Close()
@@ -193,23 +193,23 @@ func (it *LinksTo) Clone() graph.Iterator {
 }
 
 func (it *LinksTo) Contains(val graph.Value) bool {
-	graph.ContainsLogIn(it, val)
+	graph.ContainsLogIn(nil, it, val)
 	it.runstats.Contains += 1
 
 	for _, link := range it.lset {
 		dval := it.qs.QuadDirection(val, link.Dir)
 		if dval != link.Value {
-			return graph.ContainsLogOut(it, val, false)
+			return graph.ContainsLogOut(nil, it, val, false)
 		}
 	}
 
 	node := it.qs.QuadDirection(val, it.dir)
 	if it.primaryIt.Contains(node) {
 		it.result = val
-		return graph.ContainsLogOut(it, val, true)
+		return graph.ContainsLogOut(nil, it, val, true)
 	}
 	it.err = it.primaryIt.Err()
-	return graph.ContainsLogOut(it, val, false)
+	return graph.ContainsLogOut(nil, it, val, false)
 }
 
 func (it *LinksTo) Describe() graph.Description {
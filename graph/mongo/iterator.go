@@ -181,17 +181,17 @@ func (it *Iterator) SubIterators() []graph.Iterator {
 }
 
 func (it *Iterator) Contains(v graph.Value) bool {
-	graph.ContainsLogIn(it, v)
+	graph.ContainsLogIn(nil, it, v)
 	if it.isAll {
 		it.result = v
-		return graph.ContainsLogOut(it, v, true)
+		return graph.ContainsLogOut(nil, it, v, true)
 	}
 	val := NodeHash(v.(QuadHash).Get(it.dir))
 	if val == it.hash {
 		it.result = v
-		return graph.ContainsLogOut(it, v, true)
+		return graph.ContainsLogOut(nil, it, v, true)
 	}
-	return graph.ContainsLogOut(it, v, false)
+	return graph.ContainsLogOut(nil, it, v, false)
 }
 
 func (it *Iterator) Size() (int64, bool) {
@@ -20,6 +20,8 @@ import (
 	"strconv"
 
 	"github.com/codelingo/cayley/graph"
+	"github.com/codelingo/cayley/graph/fulltext"
+	"github.com/codelingo/cayley/graph/geo"
 	"github.com/codelingo/cayley/graph/iterator"
 	"github.com/codelingo/cayley/quad"
 )
@@ -89,6 +91,28 @@ func regexMorphism(pattern *regexp.Regexp, refs bool) morphism {
 	}
 }
 
+// searchMorphism represents nodes that match a full-text query.
+func searchMorphism(query string, scoreTags []string) morphism {
+	return morphism{
+		Name:     "search",
+		Reversal: func(ctx *pathContext) (morphism, *pathContext) { return searchMorphism(query, scoreTags), ctx },
+		Apply: func(qs graph.QuadStore, in graph.Iterator, ctx *pathContext) (graph.Iterator, *pathContext) {
+			return join(qs, fulltext.NewIterator(qs, query, scoreTags...), in), ctx
+		},
+	}
+}
+
+// withinMorphism represents nodes with geometries within a region.
+func withinMorphism(r geo.Region) morphism {
+	return morphism{
+		Name:     "within",
+		Reversal: func(ctx *pathContext) (morphism, *pathContext) { return withinMorphism(r), ctx },
+		Apply: func(qs graph.QuadStore, in graph.Iterator, ctx *pathContext) (graph.Iterator, *pathContext) {
+			return geo.NewIterator(qs, in, r), ctx
+		},
+	}
+}
+
 // isNodeMorphism represents all nodes passed in-- if there are none, this function
 // acts as a passthrough for the previous iterator.
 func isNodeMorphism(nodes ...graph.Value) morphism {
@@ -409,6 +433,52 @@ func followRecursiveMorphism(p *Path, depthTags []string) morphism {
 	}
 }
 
+// pathsMorphism finds paths from the current nodes to nodes of "to", or to any node if it is nil.
+func pathsMorphism(to *Path, all bool, maxDepth int, opts PathOptions, via ...interface{}) morphism {
+	return morphism{
+		Name: "paths",
+		Reversal: func(ctx *pathContext) (morphism, *pathContext) {
+			rev := opts
+			rev.Reverse = !rev.Reverse
+			return pathsMorphism(nil, all, maxDepth, rev, via...), ctx
+		},
+		Apply: func(qs graph.QuadStore, in graph.Iterator, ctx *pathContext) (graph.Iterator, *pathContext) {
+			var toIt, viaIt graph.Iterator
+			if to != nil {
+				toIt = to.BuildIteratorOn(qs)
+			}
+			if len(via) != 0 {
+				viaIt = buildViaPath(qs, via...).BuildIterator()
+			}
+			dir := quad.Subject
+			if opts.Reverse {
+				dir = quad.Object
+			}
+			var it *iterator.Paths
+			if all {
+				it = iterator.NewAllPaths(qs, in, toIt, viaIt, dir, maxDepth)
+			} else {
+				it = iterator.NewShortestPaths(qs, in, toIt, viaIt, dir)
+			}
+			if ctx != nil && ctx.labelSet != nil {
+				it.SetLabels(ctx.labelSet.BuildIteratorOn(qs))
+			}
+			if opts.Weight != nil {
+				it.SetWeight(qs.ValueOf(opts.Weight))
+			}
+			tag := opts.Tag
+			if tag == "" {
+				tag = DefaultPathTag
+			}
+			it.AddStepTag(tag)
+			if opts.WeightTag != "" {
+				it.AddWeightTag(opts.WeightTag)
+			}
+			return it, ctx
+		},
+	}
+}
+
 // exceptMorphism removes all results on p.(*Path) from the current iterators.
 func exceptMorphism(p *Path) morphism {
 	return morphism{
@@ -655,3 +725,43 @@ func countMorphism() morphism {
 		},
 	}
 }
+
+// groupByMorphism will group values by tags.
+func groupByMorphism(tags ...string) morphism {
+	return morphism{
+		Name:     "groupby",
+		Reversal: func(ctx *pathContext) (morphism, *pathContext) { return groupByMorphism(tags...), ctx },
+		Apply: func(qs graph.QuadStore, in graph.Iterator, ctx *pathContext) (graph.Iterator, *pathContext) {
+			return iterator.NewGroupBy(in, qs, tags...), ctx
+		},
+		tags: tags,
+	}
+}
+
+// orderMorphism will sort results by values of the given keys.
+func orderMorphism(keys ...iterator.OrderKey) morphism {
+	return morphism{
+		Name:     "order",
+		Reversal: func(ctx *pathContext) (morphism, *pathContext) { return orderMorphism(keys...), ctx },
+		Apply: func(qs graph.QuadStore, in graph.Iterator, ctx *pathContext) (graph.Iterator, *pathContext) {
+			return iterator.NewOrder(in, qs, keys...), ctx
+		},
+	}
+}
+
+// aggregateMorphism will add an aggregate to a preceding group by, or aggregate all values if there is none.
+func aggregateMorphism(agg iterator.Aggregate) morphism {
+	return morphism{
+		Name:     "aggregate",
+		Reversal: func(ctx *pathContext) (morphism, *pathContext) { return aggregateMorphism(agg), ctx },
+		Apply: func(qs graph.QuadStore, in graph.Iterator, ctx *pathContext) (graph.Iterator, *pathContext) {
+			g, ok := in.(*iterator.GroupBy)
+			if !ok {
+				g = iterator.NewGroupBy(in, qs)
+			}
+			g.AddAggregate(agg)
+			return g, ctx
+		},
+		tags: []string{agg.As},
+	}
+}
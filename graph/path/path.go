@@ -18,6 +18,7 @@ import (
 	"regexp"
 
 	"github.com/codelingo/cayley/graph"
+	"github.com/codelingo/cayley/graph/geo"
 	"github.com/codelingo/cayley/graph/iterator"
 	"github.com/codelingo/cayley/quad"
 	"golang.org/x/net/context"
@@ -193,6 +194,33 @@ func (p *Path) Filter(op iterator.Operator, node quad.Value) *Path {
 	return np
 }
 
+// Search represents the nodes with string values that contain every term of the full-text query.
+// Relevance scores of matching nodes are stored in scoreTags.
+//
+// The query is answered by a full-text index of the quad store (see graph/fulltext).
+func (p *Path) Search(query string, scoreTags ...string) *Path {
+	np := p.clone()
+	np.stack = append(np.stack, searchMorphism(query, scoreTags))
+	return np
+}
+
+// WithinDistance represents the nodes with geometries that are within a given distance
+// (in meters) from a point. A geometry is within the distance if all of its vertices are.
+//
+// The filter is answered by a spatial index of the quad store, if there is one (see graph/geo).
+func (p *Path) WithinDistance(point quad.Point, meters float64) *Path {
+	np := p.clone()
+	np.stack = append(np.stack, withinMorphism(geo.Circle{Center: point, Radius: meters}))
+	return np
+}
+
+// WithinBox represents the nodes with geometries that are within a box with given opposite corners.
+func (p *Path) WithinBox(a, b quad.Point) *Path {
+	np := p.clone()
+	np.stack = append(np.stack, withinMorphism(geo.NewBox(a, b)))
+	return np
+}
+
 // Tag adds tag strings to the nodes at this point in the path for each result
 // path in the set.
 func (p *Path) Tag(tags ...string) *Path {
@@ -376,6 +404,58 @@ func (p *Path) FollowRecursive(via interface{}, depthTags []string) *Path {
 	return p
 }
 
+// DefaultPathTag is a tag for steps of paths found by ShortestPath and AllPaths,
+// if no other tag is set in PathOptions.
+const DefaultPathTag = "path"
+
+// PathOptions are options of ShortestPathWith and AllPathsWith.
+type PathOptions struct {
+	// Tag is a tag for steps of found paths. See iterator.StepTag for names of step tags.
+	Tag string
+	// Weight is a numeric predicate with weights of quads. The weight of a quad is read from
+	// its label, or from its predicate if the label has none. If Weight is nil, all quads
+	// have a weight of 1.
+	Weight quad.Value
+	// WeightTag is an optional tag for total weights of found paths.
+	WeightTag string
+	// Reverse follows predicates in the inbound direction.
+	Reverse bool
+}
+
+// ShortestPath updates this path to represent the nodes of the "to" path that are reachable
+// from the current nodes by following the given outbound predicates. Only the shortest path
+// to each node is returned, and its steps, starting from the current node, are stored in
+// the DefaultPathTag step tags.
+//
+// For example:
+//  // Will return "dani", with steps "alice", "follows", "bob", "follows", "dani".
+//  StartPath(qs, "alice").ShortestPath(StartMorphism("dani"), "follows")
+func (p *Path) ShortestPath(to *Path, via ...interface{}) *Path {
+	return p.ShortestPathWith(to, PathOptions{}, via...)
+}
+
+// ShortestPathWith is the same as ShortestPath, but allows to set weights of quads and tags of paths.
+func (p *Path) ShortestPathWith(to *Path, opts PathOptions, via ...interface{}) *Path {
+	np := p.clone()
+	np.stack = append(np.stack, pathsMorphism(to, false, 0, opts, via...))
+	return np
+}
+
+// AllPaths is the same as ShortestPath, but returns all paths without cycles that are at most
+// maxDepth quads long, one result per path. iterator.DefaultPathsDepth is used if maxDepth is not positive.
+//
+// The number of paths grows exponentially with depth, so keep maxDepth small.
+func (p *Path) AllPaths(to *Path, maxDepth int, via ...interface{}) *Path {
+	return p.AllPathsWith(to, maxDepth, PathOptions{}, via...)
+}
+
+// AllPathsWith is the same as AllPaths, but allows to set weights of quads and tags of paths.
+func (p *Path) AllPathsWith(to *Path, maxDepth int, opts PathOptions, via ...interface{}) *Path {
+	np := p.clone()
+	np.stack = append(np.stack, pathsMorphism(to, true, maxDepth, opts, via...))
+	return np
+}
+
 // Save will, from the current nodes in the path, retrieve the node
 // one linkage away (given by either a path or a predicate), add the given
 // tag, and propagate that to the result set.
@@ -535,6 +615,41 @@ func (p *Path) Count() *Path {
 	return p
 }
 
+// GroupBy will group results by values of the given tags, returning one result per group.
+// Each result is tagged with values of the group tags, and its value is the value of the first tag.
+// Results that don't have all of the tags are skipped. Without tags all results form a single group.
+//
+// Use Aggregate to compute values for each group:
+//  // Will return each person that follows anyone, with the number of people they follow.
+//  StartPath(qs).Tag("person").Out("follows").GroupBy("person").Aggregate(iterator.AggregateCount, "", "follows")
+func (p *Path) GroupBy(tags ...string) *Path {
+	np := p.clone()
+	np.stack = append(np.stack, groupByMorphism(tags...))
+	return np
+}
+
+// Aggregate will compute a function over values of a tag in each group of a preceding GroupBy,
+// and store the result in the "as" tag. An empty tag aggregates values of the results themselves.
+//
+// If the path is not grouped, values are aggregated over all results, and the value
+// of the first aggregate becomes a single result of the path.
+func (p *Path) Aggregate(op iterator.AggregateOp, tag, as string) *Path {
+	np := p.clone()
+	np.stack = append(np.stack, aggregateMorphism(iterator.Aggregate{Op: op, Tag: tag, As: as}))
+	return np
+}
+
+// Order will sort results by values of the given keys, or by values of the results themselves if no keys are given.
+//
+// Use it before Skip and Limit to get the same pages on every backend:
+//  // Will return the second page of people, sorted by age from the oldest.
+//  StartPath(qs).Save("age", "age").Order(iterator.OrderKey{Tag: "age", Desc: true}).Skip(10).Limit(10)
+func (p *Path) Order(keys ...iterator.OrderKey) *Path {
+	np := p.clone()
+	np.stack = append(np.stack, orderMorphism(keys...))
+	return np
+}
+
 // Iterate is an shortcut for graph.Iterate.
 func (p *Path) Iterate(ctx context.Context) *graph.IterateChain {
 	return graph.Iterate(ctx, p.BuildIterator()).On(p.qs)
@@ -89,6 +89,7 @@ type test struct {
 	expect    []quad.Value
 	expectAlt []quad.Value
 	tag       string
+	ordered   bool // results are compared in order
 }
 
 // Define morphisms without a QuadStore
@@ -158,6 +159,16 @@ func testSet(qs graph.QuadStore) []test {
 			path:    StartPath(qs, vBob).In(vFollows).RegexWithRefs(regexp.MustCompile("ar?li.*e")),
 			expect:  []quad.Value{vAlice, vCharlie},
 		},
+		{
+			message: "use Search",
+			path:    StartPath(qs).Search("SMART person").In(vStatus),
+			expect:  []quad.Value{vEmily, vGreg},
+		},
+		{
+			message: "use Search after Out",
+			path:    StartPath(qs, vBob, vEmily).Out(vStatus).Search("cool"),
+			expect:  []quad.Value{vCool},
+		},
 		{
 			message: "use path Out",
 			path:    StartPath(qs, vBob).Out(StartPath(qs, vPredicate).Out(vAre)),
@@ -346,6 +357,74 @@ func testSet(qs graph.QuadStore) []test {
 			path:    StartPath(qs, vCharlie).FollowRecursive(vFollows, nil),
 			expect:  []quad.Value{vBob, vDani, vFred, vGreg},
 		},
+		{
+			message: "shortest path",
+			path:    StartPath(qs, vCharlie).ShortestPath(StartMorphism(vGreg), vFollows),
+			expect:  []quad.Value{vGreg},
+		},
+		{
+			message: "shortest path steps",
+			path:    StartPath(qs, vCharlie).ShortestPath(StartMorphism(vGreg), vFollows),
+			tag:     iterator.StepTag(DefaultPathTag, 2),
+			expect:  []quad.Value{vDani},
+		},
+		{
+			message: "shortest path in reverse",
+			path:    StartPath(qs, vGreg).ShortestPathWith(StartMorphism(vCharlie), PathOptions{Reverse: true}, vFollows),
+			tag:     iterator.StepTag(DefaultPathTag, 2),
+			expect:  []quad.Value{vDani},
+		},
+		{
+			message: "shortest path to any node",
+			path:    StartPath(qs, vAlice).ShortestPath(nil),
+			expect:  []quad.Value{vBob, vFred, vGreg, vCool, vSmart},
+		},
+		{
+			message: "all paths",
+			path:    StartPath(qs, vCharlie).AllPaths(StartMorphism(vGreg), 0, vFollows),
+			expect:  []quad.Value{vGreg, vGreg, vGreg},
+		},
+		{
+			message: "all paths with depth",
+			path:    StartPath(qs, vCharlie).AllPaths(StartMorphism(vGreg), 3, vFollows),
+			expect:  []quad.Value{vGreg, vGreg},
+		},
+		{
+			message: "group by a tag",
+			path:    StartPath(qs).Out(vFollows).Tag("target").GroupBy("target"),
+			expect:  []quad.Value{vBob, vDani, vFred, vGreg},
+		},
+		{
+			message: "count values in groups",
+			path:    StartPath(qs).Tag("person").Out(vFollows).Tag("target").GroupBy("target").Aggregate(iterator.AggregateCount, "person", "n"),
+			tag:     "n",
+			expect:  []quad.Value{quad.Int(3), quad.Int(1), quad.Int(2), quad.Int(2)},
+		},
+		{
+			message: "aggregate all values",
+			path:    StartPath(qs).Has(vStatus).Aggregate(iterator.AggregateCount, "", "n"),
+			expect:  []quad.Value{quad.Int(5)},
+		},
+		{
+			message: "use Order with Skip and Limit",
+			path:    StartPath(qs).Has(vStatus, vCool).Order().Skip(1).Limit(1),
+			expect:  []quad.Value{vDani},
+			ordered: true,
+		},
+		{
+			message: "order by values",
+			path:    StartPath(qs).Out(vStatus).Order(iterator.OrderKey{Desc: true}),
+			expect:  []quad.Value{vSmart, vCool},
+			ordered: true,
+		},
+		{
+			message: "order by tags",
+			path: StartPath(qs).Tag("person").Out(vFollows).Tag("target").
+				Order(iterator.OrderKey{Tag: "target", Desc: true}, iterator.OrderKey{Tag: "person"}),
+			tag:     "person",
+			expect:  []quad.Value{vDani, vFred, vBob, vEmily, vCharlie, vAlice, vCharlie, vDani},
+			ordered: true,
+		},
 	}
 }
 
@@ -372,8 +451,10 @@ func RunTestMorphisms(t testing.TB, fnc graphtest.DatabaseFunc) {
 				t.Errorf("Failed to %s%s: %v", test.message, unopt, err)
 				continue
 			}
-			sort.Sort(quad.ByValueString(got))
-			sort.Sort(quad.ByValueString(test.expect))
+			if !test.ordered {
+				sort.Sort(quad.ByValueString(got))
+				sort.Sort(quad.ByValueString(test.expect))
+			}
 			eq := reflect.DeepEqual(got, test.expect)
 			if !eq && test.expectAlt != nil {
 				eq = reflect.DeepEqual(got, test.expectAlt)
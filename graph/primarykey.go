@@ -80,6 +80,20 @@ func (p *PrimaryKey) Next() PrimaryKey {
 	return PrimaryKey{}
 }
 
+// IsSequential checks if the key is a sequential integer key.
+func (p *PrimaryKey) IsSequential() bool {
+	return p.keyType == sequential
+}
+
+// Copy returns a copy of the key without copying the lock.
+func (p *PrimaryKey) Copy() PrimaryKey {
+	return PrimaryKey{
+		keyType:      p.keyType,
+		sequentialID: p.sequentialID,
+		uniqueID:     p.uniqueID,
+	}
+}
+
 func (p *PrimaryKey) Int() int64 {
 	switch p.keyType {
 	case sequential:
@@ -25,6 +25,7 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"time"
 
 	"github.com/codelingo/cayley/quad"
 )
@@ -176,3 +177,77 @@ type BulkLoader interface {
 	// you cannot load in bulk to a non-empty database, and the db is non-empty.
 	BulkLoad(quad.Reader) error
 }
+
+// ErrReadOnlyView is returned on attempt to apply deltas to a read-only view of the quad store.
+var ErrReadOnlyView = errors.New("quadstore: cannot write to a read-only view")
+
+// HistoryStore is an optional interface for quad stores that keep the history of all applied deltas.
+type HistoryStore interface {
+	// AsOf returns a read-only view of the graph as it was after applying all deltas up to
+	// a given sequential horizon (see QuadStore.Horizon).
+	AsOf(horizon int64) (QuadStore, error)
+	// HorizonAt returns the horizon of the last delta applied before or at a given time.
+	HorizonAt(t time.Time) (int64, error)
+}
+
+// AsOf returns a read-only view of the graph at a given horizon.
+// It returns ErrOperationNotSupported if the quad store does not keep the history.
+func AsOf(qs QuadStore, horizon int64) (QuadStore, error) {
+	hs, ok := qs.(HistoryStore)
+	if !ok {
+		return nil, ErrOperationNotSupported
+	}
+	return hs.AsOf(horizon)
+}
+
+// AsOfTime returns a read-only view of the graph as it was at a given time.
+// It returns ErrOperationNotSupported if the quad store does not keep the history.
+func AsOfTime(qs QuadStore, t time.Time) (QuadStore, error) {
+	hs, ok := qs.(HistoryStore)
+	if !ok {
+		return nil, ErrOperationNotSupported
+	}
+	h, err := hs.HorizonAt(t)
+	if err != nil {
+		return nil, err
+	}
+	return hs.AsOf(h)
+}
+
+// HasQuad checks if a quad is present in the store. If anyLabel is set, the label of the quad is ignored.
+func HasQuad(qs QuadStore, q quad.Quad, anyLabel bool) bool {
+	dirs := quad.Directions
+	if anyLabel {
+		dirs = dirs[:3]
+	}
+	var vals [4]Value
+	for i, d := range dirs {
+		v := q.Get(d)
+		if v == nil {
+			continue
+		}
+		if vals[i] = qs.ValueOf(v); vals[i] == nil || qs.NameOf(vals[i]) == nil {
+			return false
+		}
+	}
+	it := qs.QuadIterator(quad.Subject, vals[0])
+	defer it.Close()
+	for it.Next(nil) {
+		res := it.Result()
+		ok := true
+		for i, d := range dirs[1:] {
+			if q.Get(d) == nil {
+				ok = qs.NameOf(qs.QuadDirection(res, d)) == nil
+			} else {
+				ok = ToKey(qs.QuadDirection(res, d)) == ToKey(vals[i+1])
+			}
+			if !ok {
+				break
+			}
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
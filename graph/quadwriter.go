@@ -66,6 +66,7 @@ type IgnoreOpts struct {
 
 func (h *Handle) Close() error {
 	err := h.QuadWriter.Close()
+	CloseFeed(h.QuadStore)
 	h.QuadStore.Close()
 	return err
 }
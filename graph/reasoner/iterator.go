@@ -0,0 +1,244 @@
+package reasoner
+
+import (
+	"fmt"
+
+	"github.com/codelingo/cayley/graph"
+	"github.com/codelingo/cayley/graph/iterator"
+	"github.com/codelingo/cayley/quad"
+)
+
+var reasonerType graph.Type
+
+func init() {
+	reasonerType = graph.RegisterIterator("reasoner")
+}
+
+// Iterator iterates over base and entailed quads that have a given node in a specified direction.
+//
+// It scans base quads that may entail a matching quad, derives all quads from them and
+// filters the results. Each quad is returned only once.
+type Iterator struct {
+	uid  uint64
+	tags graph.Tagger
+	qs   *QuadStore
+	dir  quad.Direction // quad.Any for all quads
+	val  graph.Value
+	key  interface{}
+
+	tb     *tbox
+	scans  []graph.Linkage
+	cur    int
+	it     graph.Iterator
+	buf    []quadRef
+	seen   map[interface{}]struct{}
+	result graph.Value
+	err    error
+}
+
+func newIterator(qs *QuadStore, d quad.Direction, v graph.Value) *Iterator {
+	it := &Iterator{
+		uid: iterator.NextUID(),
+		qs:  qs,
+		dir: d,
+		val: v,
+	}
+	if v != nil {
+		it.key = graph.ToKey(v)
+	}
+	return it
+}
+
+func (it *Iterator) UID() uint64 {
+	return it.uid
+}
+
+func (it *Iterator) Tagger() *graph.Tagger {
+	return &it.tags
+}
+
+func (it *Iterator) TagResults(dst map[string]graph.Value) {
+	for _, tag := range it.tags.Tags() {
+		dst[tag] = it.Result()
+	}
+
+	for tag, value := range it.tags.Fixed() {
+		dst[tag] = value
+	}
+}
+
+func (it *Iterator) Clone() graph.Iterator {
+	out := newIterator(it.qs, it.dir, it.val)
+	out.tags.CopyFrom(it)
+	return out
+}
+
+// init loads the schema and prepares the list of base iterators to scan.
+func (it *Iterator) init() bool {
+	if it.tb != nil {
+		return true
+	} else if it.err != nil {
+		return false
+	}
+	it.tb, it.err = it.qs.tbox()
+	if it.err != nil {
+		return false
+	}
+	if it.dir != quad.Any {
+		it.scans = it.qs.scans(it.tb, it.dir, it.val)
+	}
+	it.seen = make(map[interface{}]struct{})
+	return true
+}
+
+func (it *Iterator) nextBase() graph.Iterator {
+	if it.dir == quad.Any {
+		if it.cur > 0 {
+			return nil
+		}
+		return it.qs.qs.QuadsAllIterator()
+	}
+	if it.cur >= len(it.scans) {
+		return nil
+	}
+	l := it.scans[it.cur]
+	return it.qs.qs.QuadIterator(l.Dir, l.Value)
+}
+
+func (it *Iterator) matches(q quadRef) bool {
+	return it.dir == quad.Any || graph.ToKey(q.get(it.dir)) == it.key
+}
+
+func (it *Iterator) Next(ctx *graph.IterationContext) bool {
+	graph.NextLogIn(ctx, it)
+	if !it.init() {
+		return graph.NextLogOut(ctx, it, false)
+	}
+	for {
+		for len(it.buf) != 0 {
+			q := it.buf[0]
+			it.buf = it.buf[1:]
+			k := q.Key()
+			if _, ok := it.seen[k]; ok {
+				continue
+			}
+			it.seen[k] = struct{}{}
+			it.result = q
+			return graph.NextLogOut(ctx, it, true)
+		}
+		if it.it == nil {
+			if it.it = it.nextBase(); it.it == nil {
+				return graph.NextLogOut(ctx, it, false)
+			}
+		}
+		if !it.it.Next(ctx) {
+			it.err = it.it.Err()
+			it.it.Close()
+			it.it = nil
+			if it.err != nil {
+				return graph.NextLogOut(ctx, it, false)
+			}
+			it.cur++
+			continue
+		}
+		it.qs.derive(it.tb, it.it.Result(), func(q quadRef) {
+			if it.matches(q) {
+				it.buf = append(it.buf, q)
+			}
+		})
+	}
+}
+
+func (it *Iterator) Err() error {
+	return it.err
+}
+
+func (it *Iterator) Result() graph.Value {
+	return it.result
+}
+
+func (it *Iterator) NextPath(ctx *graph.IterationContext) bool {
+	return false
+}
+
+// Contains checks if the quad has the iterator node in the specified direction.
+// Only the reasoner creates quad values, thus any value passed here is already entailed.
+func (it *Iterator) Contains(ctx *graph.IterationContext, v graph.Value) bool {
+	graph.ContainsLogIn(ctx, it, v)
+	q, ok := v.(quadRef)
+	if !ok || !it.matches(q) {
+		return graph.ContainsLogOut(ctx, it, v, false)
+	}
+	it.result = q
+	return graph.ContainsLogOut(ctx, it, v, true)
+}
+
+func (it *Iterator) Reset() {
+	if it.it != nil {
+		it.it.Close()
+		it.it = nil
+	}
+	it.cur = 0
+	it.buf = nil
+	it.result = nil
+	if it.seen != nil {
+		it.seen = make(map[interface{}]struct{})
+	}
+}
+
+func (it *Iterator) Close() error {
+	it.Reset()
+	return nil
+}
+
+// No subiterators.
+func (it *Iterator) SubIterators() []graph.Iterator {
+	return nil
+}
+
+func (it *Iterator) Optimize() (graph.Iterator, bool) {
+	return it, false
+}
+
+// Size returns the number of base quads that will be scanned by the iterator.
+func (it *Iterator) Size() (int64, bool) {
+	if !it.init() {
+		return 0, true
+	}
+	if it.dir == quad.Any {
+		return it.qs.qs.Size(), false
+	}
+	var n int64
+	for _, l := range it.scans {
+		sit := it.qs.qs.QuadIterator(l.Dir, l.Value)
+		sz, _ := sit.Size()
+		sit.Close()
+		n += sz
+	}
+	return n, false
+}
+
+func (it *Iterator) Describe() graph.Description {
+	size, _ := it.Size()
+	return graph.Description{
+		UID:  it.UID(),
+		Name: fmt.Sprintf("dir:%s val:%v", it.dir, it.val),
+		Type: it.Type(),
+		Tags: it.tags.Tags(),
+		Size: size,
+	}
+}
+
+func (it *Iterator) Type() graph.Type { return reasonerType }
+
+func (it *Iterator) Stats() graph.IteratorStats {
+	size, exact := it.Size()
+	return graph.IteratorStats{
+		ContainsCost: 1,
+		NextCost:     2,
+		Size:         size,
+		ExactSize:    exact,
+	}
+}
+
+var _ graph.Iterator = &Iterator{}
@@ -0,0 +1,309 @@
+// Package reasoner implements a quad store wrapper that exposes quads entailed by RDFS and a subset of OWL.
+//
+// Supported entailment rules are:
+//
+//	rdfs:subClassOf and rdfs:subPropertyOf transitivity
+//	typing of subjects and objects by rdfs:domain and rdfs:range
+//	owl:inverseOf
+//	owl:sameAs
+//
+// Entailed quads are never written to the underlying store. Instead, they are derived
+// on the fly from base quads by iterators returned from the wrapper.
+package reasoner
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/codelingo/cayley/graph"
+	"github.com/codelingo/cayley/graph/iterator"
+	"github.com/codelingo/cayley/quad"
+	"github.com/codelingo/cayley/voc/rdf"
+)
+
+const QuadStoreType = "reasoner"
+
+var _ graph.QuadStore = (*QuadStore)(nil)
+
+// QuadStore wraps any quad store and extends it with entailed quads.
+//
+// Schema statements (class and property hierarchies, domains, ranges, etc) are loaded
+// on first use and reloaded after each write to the store.
+type QuadStore struct {
+	qs graph.QuadStore
+
+	mu  sync.Mutex
+	tb  *tbox
+	err error
+}
+
+// New wraps a quad store with a reasoner.
+func New(qs graph.QuadStore) *QuadStore {
+	return &QuadStore{qs: qs}
+}
+
+// Unwrap returns an underlying quad store.
+func (qs *QuadStore) Unwrap() graph.QuadStore {
+	return qs.qs
+}
+
+func (qs *QuadStore) tbox() (*tbox, error) {
+	qs.mu.Lock()
+	defer qs.mu.Unlock()
+	if qs.tb == nil && qs.err == nil {
+		qs.tb, qs.err = loadTBox(qs.qs)
+	}
+	return qs.tb, qs.err
+}
+
+func (qs *QuadStore) invalidate() {
+	qs.mu.Lock()
+	qs.tb, qs.err = nil, nil
+	qs.mu.Unlock()
+}
+
+func (qs *QuadStore) ApplyDeltas(deltas []graph.Delta, opts graph.IgnoreOpts) error {
+	defer qs.invalidate()
+	return qs.qs.ApplyDeltas(deltas, opts)
+}
+
+// quadRef is a value of an entailed quad. It holds node values of the underlying store.
+type quadRef struct {
+	dirs [4]graph.Value
+}
+
+func (q quadRef) Key() interface{} {
+	var k [4]interface{}
+	for i, v := range q.dirs {
+		if v != nil {
+			k[i] = graph.ToKey(v)
+		}
+	}
+	return k
+}
+
+func (q quadRef) get(d quad.Direction) graph.Value {
+	return q.dirs[d-1]
+}
+
+func (qs *QuadStore) Quad(v graph.Value) quad.Quad {
+	ref, ok := v.(quadRef)
+	if !ok {
+		return qs.qs.Quad(v)
+	}
+	return quad.Quad{
+		Subject:   qs.NameOf(ref.get(quad.Subject)),
+		Predicate: qs.NameOf(ref.get(quad.Predicate)),
+		Object:    qs.NameOf(ref.get(quad.Object)),
+		Label:     qs.NameOf(ref.get(quad.Label)),
+	}
+}
+
+func (qs *QuadStore) QuadDirection(v graph.Value, d quad.Direction) graph.Value {
+	ref, ok := v.(quadRef)
+	if !ok {
+		return qs.qs.QuadDirection(v, d)
+	}
+	return ref.get(d)
+}
+
+// QuadIterator returns an iterator of all base and entailed quads that have a given node in a specified direction.
+func (qs *QuadStore) QuadIterator(d quad.Direction, v graph.Value) graph.Iterator {
+	return newIterator(qs, d, v)
+}
+
+// QuadsAllIterator returns an iterator of all base and entailed quads.
+//
+// Iterator keeps track of all quads returned so far, thus it may use a lot of memory on large graphs.
+func (qs *QuadStore) QuadsAllIterator() graph.Iterator {
+	return newIterator(qs, quad.Any, nil)
+}
+
+func (qs *QuadStore) NodesAllIterator() graph.Iterator {
+	return qs.qs.NodesAllIterator()
+}
+
+func (qs *QuadStore) ValueOf(name quad.Value) graph.Value {
+	v := qs.qs.ValueOf(name)
+	if iri, ok := name.(quad.IRI); ok && !exists(qs.qs, v) && iri.Full() == rdf.NS+"type" {
+		// rdf:type may be entailed even if there are no explicit type statements
+		if tb, err := qs.tbox(); err == nil {
+			return tb.typ
+		}
+	}
+	return v
+}
+
+func (qs *QuadStore) NameOf(v graph.Value) quad.Value {
+	if t, ok := v.(termNode); ok {
+		return quad.IRI(t)
+	}
+	return qs.qs.NameOf(v)
+}
+
+func (qs *QuadStore) Size() int64 {
+	return qs.qs.Size()
+}
+
+func (qs *QuadStore) Horizon() graph.PrimaryKey {
+	return qs.qs.Horizon()
+}
+
+func (qs *QuadStore) FixedIterator() graph.FixedIterator {
+	return qs.qs.FixedIterator()
+}
+
+// OptimizeIterator replaces links to a single fixed node with the reasoner iterator.
+//
+// Iterators are never passed to the underlying store, since its optimizer would replace
+// them with native iterators which know nothing about entailed quads.
+func (qs *QuadStore) OptimizeIterator(it graph.Iterator) (graph.Iterator, bool) {
+	if it.Type() != graph.LinksTo {
+		return it, false
+	}
+	lto := it.(*iterator.LinksTo)
+	subs := lto.SubIterators()
+	if len(subs) != 1 {
+		return it, false
+	}
+	primary := subs[0]
+	if primary.Type() != graph.Fixed {
+		return it, false
+	}
+	if size, _ := primary.Size(); size != 1 {
+		return it, false
+	}
+	if !primary.Next(nil) {
+		panic("unexpected size during optimize")
+	}
+	val := primary.Result()
+	newIt := qs.QuadIterator(lto.Direction(), val)
+	nt := newIt.Tagger()
+	nt.CopyFrom(it)
+	for _, tag := range primary.Tagger().Tags() {
+		nt.AddFixed(tag, val)
+	}
+	it.Close()
+	return newIt, true
+}
+
+// AsOf returns a reasoner over a read-only view of the underlying store at a given horizon.
+// Schema statements are loaded from the view.
+func (qs *QuadStore) AsOf(horizon int64) (graph.QuadStore, error) {
+	sub, err := graph.AsOf(qs.qs, horizon)
+	if err != nil {
+		return nil, err
+	}
+	return New(sub), nil
+}
+
+func (qs *QuadStore) HorizonAt(t time.Time) (int64, error) {
+	hs, ok := qs.qs.(graph.HistoryStore)
+	if !ok {
+		return 0, graph.ErrOperationNotSupported
+	}
+	return hs.HorizonAt(t)
+}
+
+func (qs *QuadStore) Close() error {
+	return qs.qs.Close()
+}
+
+func (qs *QuadStore) Type() string {
+	return QuadStoreType
+}
+
+// derive calls fnc for a base quad and each quad entailed from it.
+// The same quad may be passed multiple times.
+func (qs *QuadStore) derive(tb *tbox, q graph.Value, fnc func(quadRef)) {
+	s := qs.qs.QuadDirection(q, quad.Subject)
+	p := qs.qs.QuadDirection(q, quad.Predicate)
+	o := qs.qs.QuadDirection(q, quad.Object)
+	l := qs.qs.QuadDirection(q, quad.Label)
+
+	literal := false
+	switch qs.qs.NameOf(o).(type) {
+	case quad.IRI, quad.BNode, quad.Raw:
+		// raw values are usually used as node names in legacy data
+	default:
+		literal = true
+	}
+
+	emit := func(a, p, b graph.Value) {
+		for _, a2 := range tb.sameAs(a) {
+			for _, b2 := range tb.sameAs(b) {
+				fnc(quadRef{dirs: [4]graph.Value{a2, p, b2, l}})
+			}
+		}
+	}
+	triple := func(a, p, b graph.Value, literal bool) {
+		emit(a, p, b)
+		kp := graph.ToKey(p)
+		if tb.isType[kp] {
+			for _, c := range tb.superClasses[graph.ToKey(b)] {
+				emit(a, tb.typ, c)
+			}
+		}
+		for _, c := range tb.domains[kp] {
+			emit(a, tb.typ, c)
+		}
+		if !literal {
+			for _, c := range tb.ranges[kp] {
+				emit(b, tb.typ, c)
+			}
+		}
+	}
+	for _, sp := range withSuper(tb, p) {
+		triple(s, sp, o, literal)
+		if literal {
+			continue
+		}
+		for _, inv := range tb.inverse[graph.ToKey(sp)] {
+			for _, ip := range withSuper(tb, inv) {
+				triple(o, ip, s, false)
+			}
+		}
+	}
+}
+
+func withSuper(tb *tbox, p graph.Value) []graph.Value {
+	return append([]graph.Value{p}, tb.superProps[graph.ToKey(p)]...)
+}
+
+// scans returns a list of base quad iterators that may contain quads entailing a quad with node v in direction d.
+func (qs *QuadStore) scans(tb *tbox, d quad.Direction, v graph.Value) []graph.Linkage {
+	var out []graph.Linkage
+	seen := make(map[graph.Linkage]bool)
+	add := func(d quad.Direction, vals ...graph.Value) {
+		for _, v := range vals {
+			if _, ok := v.(termNode); ok {
+				continue
+			}
+			l := graph.Linkage{Dir: d, Value: v}
+			if k := (graph.Linkage{Dir: d, Value: graph.ToKey(v)}); !seen[k] {
+				seen[k] = true
+				out = append(out, l)
+			}
+		}
+	}
+	switch d {
+	case quad.Subject, quad.Object:
+		for _, m := range tb.sameAs(v) {
+			// object of a base quad may become a subject due to inverse properties or range typing, and vice versa
+			add(quad.Subject, m)
+			add(quad.Object, m)
+			if d == quad.Object {
+				add(quad.Object, tb.subClasses[graph.ToKey(m)]...)
+				add(quad.Predicate, tb.typers(m)...)
+			}
+		}
+	case quad.Predicate:
+		add(quad.Predicate, tb.producers(v)...)
+	case quad.Label:
+		add(quad.Label, v)
+	default:
+		panic(fmt.Errorf("unexpected direction: %v", d))
+	}
+	return out
+}
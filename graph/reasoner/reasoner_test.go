@@ -0,0 +1,223 @@
+package reasoner
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+
+	"github.com/codelingo/cayley/graph"
+	_ "github.com/codelingo/cayley/graph/bolt"
+	"github.com/codelingo/cayley/graph/memstore"
+	"github.com/codelingo/cayley/graph/path"
+	"github.com/codelingo/cayley/quad"
+	"github.com/codelingo/cayley/voc/owl"
+	"github.com/codelingo/cayley/voc/rdf"
+	"github.com/codelingo/cayley/voc/rdfs"
+	_ "github.com/codelingo/cayley/writer"
+)
+
+var (
+	rdfType       = quad.IRI(rdf.Type).Full()
+	subClassOf    = quad.IRI(rdfs.SubClassOf).Full()
+	subPropertyOf = quad.IRI(rdfs.SubPropertyOf).Full()
+	domain        = quad.IRI(rdfs.Domain).Full()
+	rng           = quad.IRI(rdfs.Range).Full()
+	inverseOf     = quad.IRI(owl.InverseOf).Full()
+	sameAs        = quad.IRI(owl.SameAs).Full()
+)
+
+var testGraph = []quad.Quad{
+	quad.MakeIRI("Dog", string(subClassOf), "Mammal", ""),
+	quad.MakeIRI("Mammal", string(subClassOf), "Animal", ""),
+	quad.MakeIRI("Cat", string(subClassOf), "Mammal", ""),
+	quad.MakeIRI("owns", string(domain), "Person", ""),
+	quad.MakeIRI("owns", string(rng), "Pet", ""),
+	quad.MakeIRI("ownedBy", string(inverseOf), "owns", ""),
+	quad.MakeIRI("hasCat", string(subPropertyOf), "owns", ""),
+	quad.MakeIRI("rex", string(rdfType), "Dog", ""),
+	quad.MakeIRI("tom", string(rdfType), "Cat", ""),
+	quad.MakeIRI("bob", "hasCat", "tom", "home"),
+	quad.MakeIRI("robert", string(sameAs), "bob", ""),
+	quad.Make(quad.IRI("rex"), quad.IRI("name"), quad.String("Rex"), nil),
+}
+
+func makeStore(t testing.TB) *QuadStore {
+	return New(memstore.New(testGraph...))
+}
+
+func values(t testing.TB, qs graph.QuadStore, p *path.Path) []string {
+	return iterate(t, qs, p, true)
+}
+
+func iterate(t testing.TB, qs graph.QuadStore, p *path.Path, opt bool) []string {
+	it := p.Iterate(context.TODO())
+	if !opt {
+		it = it.UnOptimized()
+	}
+	vals, err := it.AllValues(qs)
+	require.NoError(t, err)
+	var out []string
+	for _, v := range vals {
+		out = append(out, quad.StringOf(v))
+	}
+	sort.Strings(out)
+	return out
+}
+
+var pathCases = []struct {
+	name   string
+	path   func(qs graph.QuadStore) *path.Path
+	expect []string
+}{
+	{
+		name: "subclass instances",
+		path: func(qs graph.QuadStore) *path.Path {
+			return path.StartPath(qs).Has(rdfType, quad.IRI("Animal"))
+		},
+		expect: []string{"<rex>", "<tom>"},
+	},
+	{
+		name: "types of a node",
+		path: func(qs graph.QuadStore) *path.Path {
+			return path.StartPath(qs, quad.IRI("tom")).Out(rdfType)
+		},
+		expect: []string{"<Animal>", "<Cat>", "<Mammal>", "<Pet>"},
+	},
+	{
+		name: "sub-property",
+		path: func(qs graph.QuadStore) *path.Path {
+			return path.StartPath(qs, quad.IRI("bob")).Out(quad.IRI("owns"))
+		},
+		expect: []string{"<tom>"},
+	},
+	{
+		name: "inverse property",
+		path: func(qs graph.QuadStore) *path.Path {
+			return path.StartPath(qs, quad.IRI("tom")).Out(quad.IRI("ownedBy"))
+		},
+		expect: []string{"<bob>", "<robert>"},
+	},
+	{
+		name: "domain typing",
+		path: func(qs graph.QuadStore) *path.Path {
+			return path.StartPath(qs).Has(rdfType, quad.IRI("Person"))
+		},
+		expect: []string{"<bob>", "<robert>"},
+	},
+	{
+		name: "same as",
+		path: func(qs graph.QuadStore) *path.Path {
+			return path.StartPath(qs, quad.IRI("robert")).Out(quad.IRI("hasCat"))
+		},
+		expect: []string{"<tom>"},
+	},
+	{
+		name: "literals are not typed",
+		path: func(qs graph.QuadStore) *path.Path {
+			return path.StartPath(qs, quad.IRI("rex")).Out(quad.IRI("name"))
+		},
+		expect: []string{`"Rex"`},
+	},
+	{
+		name: "labels are preserved",
+		path: func(qs graph.QuadStore) *path.Path {
+			return path.StartPath(qs).LabelContext(quad.IRI("home")).Has(rdfType, quad.IRI("Pet"))
+		},
+		expect: []string{"<tom>"},
+	},
+}
+
+func TestPaths(t *testing.T) {
+	qs := makeStore(t)
+	for _, opt := range []bool{true, false} {
+		for _, c := range pathCases {
+			got := iterate(t, qs, c.path(qs), opt)
+			require.Equal(t, c.expect, got, "%s (optimized: %v)", c.name, opt)
+		}
+	}
+}
+
+func TestQuadIterator(t *testing.T) {
+	qs := makeStore(t)
+	it := qs.QuadIterator(quad.Subject, qs.ValueOf(quad.IRI("tom")))
+	defer it.Close()
+	var got []string
+	for it.Next(nil) {
+		got = append(got, qs.Quad(it.Result()).NQuad())
+	}
+	require.NoError(t, it.Err())
+	sort.Strings(got)
+	require.Equal(t, []string{
+		"<tom> <" + string(rdfType) + "> <Animal> .",
+		"<tom> <" + string(rdfType) + "> <Cat> .",
+		"<tom> <" + string(rdfType) + "> <Mammal> .",
+		"<tom> <" + string(rdfType) + "> <Pet> <home> .",
+		"<tom> <ownedBy> <bob> <home> .",
+		"<tom> <ownedBy> <robert> <home> .",
+	}, got)
+}
+
+func TestImplicitType(t *testing.T) {
+	// no rdf:type statements in the store
+	qs := New(memstore.New(
+		quad.MakeIRI("owns", string(domain), "Person", ""),
+		quad.MakeIRI("bob", "owns", "tom", ""),
+	))
+	got := values(t, qs, path.StartPath(qs).Has(quad.IRI(rdf.Type), quad.IRI("Person")))
+	require.Equal(t, []string{"<bob>"}, got)
+}
+
+func TestApplyDeltas(t *testing.T) {
+	qs := makeStore(t)
+	w, err := graph.NewQuadWriter("single", qs, nil)
+	require.NoError(t, err)
+
+	p := path.StartPath(qs).Has(rdfType, quad.IRI("Animal"))
+	require.Equal(t, []string{"<rex>", "<tom>"}, values(t, qs, p))
+
+	err = w.AddQuad(quad.MakeIRI("Bird", string(subClassOf), "Animal", ""))
+	require.NoError(t, err)
+	err = w.AddQuad(quad.MakeIRI("tweety", string(rdfType), "Bird", ""))
+	require.NoError(t, err)
+	err = w.RemoveQuad(quad.MakeIRI("Cat", string(subClassOf), "Mammal", ""))
+	require.NoError(t, err)
+
+	p = path.StartPath(qs).Has(rdfType, quad.IRI("Animal"))
+	require.Equal(t, []string{"<rex>", "<tweety>"}, values(t, qs, p))
+}
+
+func TestAsOf(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cayley_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	dbpath := filepath.Join(dir, "db")
+	require.NoError(t, graph.InitQuadStore("bolt", dbpath, nil))
+	bs, err := graph.NewQuadStore("bolt", dbpath, nil)
+	require.NoError(t, err)
+	qs := New(bs)
+	defer qs.Close()
+	w, err := graph.NewQuadWriter("single", qs, nil)
+	require.NoError(t, err)
+	require.NoError(t, w.AddQuadSet(testGraph))
+	past := qs.Horizon()
+
+	require.NoError(t, w.AddQuadSet([]quad.Quad{
+		quad.MakeIRI("Bird", string(subClassOf), "Animal", ""),
+		quad.MakeIRI("tweety", string(rdfType), "Bird", ""),
+	}))
+	view, err := graph.AsOf(qs, past.Int())
+	require.NoError(t, err)
+	require.IsType(t, &QuadStore{}, view)
+	defer view.Close()
+
+	animals := func(qs graph.QuadStore) []string {
+		return values(t, qs, path.StartPath(qs).Has(rdfType, quad.IRI("Animal")))
+	}
+	require.Equal(t, []string{"<rex>", "<tom>"}, animals(view))
+	require.Equal(t, []string{"<rex>", "<tom>", "<tweety>"}, animals(qs))
+}
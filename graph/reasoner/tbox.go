@@ -0,0 +1,269 @@
+package reasoner
+
+import (
+	"github.com/codelingo/cayley/graph"
+	"github.com/codelingo/cayley/quad"
+	"github.com/codelingo/cayley/voc/owl"
+	"github.com/codelingo/cayley/voc/rdf"
+	"github.com/codelingo/cayley/voc/rdfs"
+)
+
+// termNode represents a vocabulary term that is not yet present in the underlying store,
+// but is required to express entailed quads.
+type termNode quad.IRI
+
+// tbox holds the schema of the graph: class and property hierarchies, and other
+// statements that affect entailment. All maps are indexed by graph.ToKey of a node.
+type tbox struct {
+	typ    graph.Value          // rdf:type value used for entailed quads
+	isType map[interface{}]bool // all forms of rdf:type present in the store
+
+	superProps   map[interface{}][]graph.Value // transitive, without the property itself
+	subProps     map[interface{}][]graph.Value
+	superClasses map[interface{}][]graph.Value // transitive, without the class itself
+	subClasses   map[interface{}][]graph.Value
+	inverse      map[interface{}][]graph.Value
+	domains      map[interface{}][]graph.Value // direct domains, closed under superclasses
+	ranges       map[interface{}][]graph.Value // direct ranges, closed under superclasses
+	same         map[interface{}][]graph.Value // owl:sameAs equivalence classes, including the node itself
+
+	props []graph.Value // all properties mentioned in the schema
+}
+
+// edges is an adjacency list of nodes.
+type edges struct {
+	vals map[interface{}]graph.Value
+	out  map[interface{}][]graph.Value
+}
+
+func newEdges() *edges {
+	return &edges{
+		vals: make(map[interface{}]graph.Value),
+		out:  make(map[interface{}][]graph.Value),
+	}
+}
+
+func (e *edges) add(from, to graph.Value) {
+	k := graph.ToKey(from)
+	e.vals[k] = from
+	e.vals[graph.ToKey(to)] = to
+	e.out[k] = append(e.out[k], to)
+}
+
+// closure returns all nodes reachable from a given one, excluding the node itself.
+func (e *edges) closure(from graph.Value) []graph.Value {
+	start := graph.ToKey(from)
+	seen := map[interface{}]bool{start: true}
+	var out []graph.Value
+	stack := append([]graph.Value{}, e.out[start]...)
+	for len(stack) > 0 {
+		v := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		k := graph.ToKey(v)
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+		out = append(out, v)
+		stack = append(stack, e.out[k]...)
+	}
+	return out
+}
+
+// closeAll computes a closure for each node that has outgoing edges.
+func (e *edges) closeAll() map[interface{}][]graph.Value {
+	m := make(map[interface{}][]graph.Value, len(e.out))
+	for k := range e.out {
+		if c := e.closure(e.vals[k]); len(c) != 0 {
+			m[k] = c
+		}
+	}
+	return m
+}
+
+// termValues returns values of all forms (full and short) of the vocabulary term that are present in the store.
+func termValues(qs graph.QuadStore, term quad.IRI) []graph.Value {
+	var out []graph.Value
+	for _, name := range []quad.IRI{term.Full(), term.Short()} {
+		if v := qs.ValueOf(name); exists(qs, v) {
+			if len(out) == 0 || graph.ToKey(out[0]) != graph.ToKey(v) {
+				out = append(out, v)
+			}
+		}
+	}
+	return out
+}
+
+func exists(qs graph.QuadStore, v graph.Value) bool {
+	return v != nil && qs.NameOf(v) != nil
+}
+
+// scanPairs calls fnc for subject and object of each quad that uses one of the given predicates.
+func scanPairs(qs graph.QuadStore, preds []graph.Value, fnc func(s, o graph.Value)) error {
+	for _, p := range preds {
+		it := qs.QuadIterator(quad.Predicate, p)
+		for it.Next(nil) {
+			q := it.Result()
+			fnc(qs.QuadDirection(q, quad.Subject), qs.QuadDirection(q, quad.Object))
+		}
+		err := it.Err()
+		it.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadTBox reads schema statements from the store.
+func loadTBox(qs graph.QuadStore) (*tbox, error) {
+	tb := &tbox{
+		isType:  make(map[interface{}]bool),
+		inverse: make(map[interface{}][]graph.Value),
+		domains: make(map[interface{}][]graph.Value),
+		ranges:  make(map[interface{}][]graph.Value),
+		same:    make(map[interface{}][]graph.Value),
+	}
+	types := termValues(qs, rdf.Type)
+	for _, v := range types {
+		tb.isType[graph.ToKey(v)] = true
+	}
+	if len(types) != 0 {
+		tb.typ = types[0]
+	} else {
+		tb.typ = termNode(rdf.NS + "type")
+	}
+	props := make(map[interface{}]graph.Value)
+	addProp := func(v graph.Value) {
+		props[graph.ToKey(v)] = v
+	}
+
+	subClass, subProp := newEdges(), newEdges()
+	if err := scanPairs(qs, termValues(qs, rdfs.SubClassOf), subClass.add); err != nil {
+		return nil, err
+	}
+	if err := scanPairs(qs, termValues(qs, rdfs.SubPropertyOf), func(s, o graph.Value) {
+		subProp.add(s, o)
+		addProp(s)
+		addProp(o)
+	}); err != nil {
+		return nil, err
+	}
+	tb.superClasses = subClass.closeAll()
+	tb.superProps = subProp.closeAll()
+	tb.subClasses = reverse(subClass).closeAll()
+	tb.subProps = reverse(subProp).closeAll()
+
+	// domains and ranges are closed under superclasses, so entailed types never need another pass
+	withSuper := func(m map[interface{}][]graph.Value) func(p, c graph.Value) {
+		return func(p, c graph.Value) {
+			k := graph.ToKey(p)
+			m[k] = append(m[k], c)
+			m[k] = append(m[k], tb.superClasses[graph.ToKey(c)]...)
+			addProp(p)
+		}
+	}
+	if err := scanPairs(qs, termValues(qs, rdfs.Domain), withSuper(tb.domains)); err != nil {
+		return nil, err
+	}
+	if err := scanPairs(qs, termValues(qs, rdfs.Range), withSuper(tb.ranges)); err != nil {
+		return nil, err
+	}
+	if err := scanPairs(qs, termValues(qs, owl.InverseOf), func(s, o graph.Value) {
+		ks, ko := graph.ToKey(s), graph.ToKey(o)
+		tb.inverse[ks] = append(tb.inverse[ks], o)
+		if ks != ko {
+			tb.inverse[ko] = append(tb.inverse[ko], s)
+		}
+		addProp(s)
+		addProp(o)
+	}); err != nil {
+		return nil, err
+	}
+	for _, p := range props {
+		tb.props = append(tb.props, p)
+	}
+
+	// sameAs is symmetric and transitive, thus each connected component is an equivalence class
+	same := newEdges()
+	if err := scanPairs(qs, termValues(qs, owl.SameAs), func(s, o graph.Value) {
+		same.add(s, o)
+		same.add(o, s)
+	}); err != nil {
+		return nil, err
+	}
+	for k, v := range same.vals {
+		if _, ok := tb.same[k]; ok {
+			continue
+		}
+		class := append([]graph.Value{v}, same.closure(v)...)
+		for _, m := range class {
+			tb.same[graph.ToKey(m)] = class
+		}
+	}
+	return tb, nil
+}
+
+func reverse(e *edges) *edges {
+	r := newEdges()
+	for k, to := range e.out {
+		for _, v := range to {
+			r.add(v, e.vals[k])
+		}
+	}
+	return r
+}
+
+// sameAs returns all nodes that are the same as v, including v itself.
+func (tb *tbox) sameAs(v graph.Value) []graph.Value {
+	if c, ok := tb.same[graph.ToKey(v)]; ok {
+		return c
+	}
+	return []graph.Value{v}
+}
+
+// withSub returns v and all its sub-nodes from the given hierarchy.
+func withSub(m map[interface{}][]graph.Value, v graph.Value) []graph.Value {
+	return append([]graph.Value{v}, m[graph.ToKey(v)]...)
+}
+
+// producers returns base predicates that may entail a quad with predicate p.
+func (tb *tbox) producers(p graph.Value) []graph.Value {
+	k := graph.ToKey(p)
+	if tb.isType[k] || k == graph.ToKey(tb.typ) {
+		// any property with a domain or a range may entail a type
+		return append(append([]graph.Value{p}, tb.subProps[k]...), tb.props...)
+	}
+	out := withSub(tb.subProps, p)
+	for _, sp := range withSub(tb.subProps, p) {
+		for _, inv := range tb.inverse[graph.ToKey(sp)] {
+			out = append(out, withSub(tb.subProps, inv)...)
+		}
+	}
+	return out
+}
+
+// typers returns base predicates that may entail a type c because of their domain or range.
+func (tb *tbox) typers(c graph.Value) []graph.Value {
+	kc := graph.ToKey(c)
+	has := func(list []graph.Value) bool {
+		for _, v := range list {
+			if graph.ToKey(v) == kc {
+				return true
+			}
+		}
+		return false
+	}
+	var out []graph.Value
+	for _, p := range tb.props {
+		kp := graph.ToKey(p)
+		if !has(tb.domains[kp]) && !has(tb.ranges[kp]) {
+			continue
+		}
+		out = append(out, withSub(tb.subProps, p)...)
+		for _, inv := range tb.inverse[kp] {
+			out = append(out, withSub(tb.subProps, inv)...)
+		}
+	}
+	return out
+}
@@ -0,0 +1,330 @@
+package rules
+
+import (
+	"errors"
+	"sync"
+
+	"golang.org/x/net/context"
+
+	"github.com/codelingo/cayley/graph"
+	"github.com/codelingo/cayley/quad"
+)
+
+// DefaultLabel is a label for derived quads used if no other label is set.
+const DefaultLabel = quad.IRI("urn:cayley:rules")
+
+var errStop = errors.New("rules: stop iteration")
+
+// Engine applies rules to the quad store and keeps derived quads up to date.
+//
+// All derived quads are stored in a single label. Rule bodies match quads in any label,
+// including derived ones. Derived quads should not be modified directly.
+type Engine struct {
+	qs    graph.QuadStore
+	label quad.Value
+
+	mu    sync.Mutex
+	rules []*Rule
+}
+
+// New creates a rule engine for a quad store. Derived quads will be written to the given label,
+// or to DefaultLabel if it is nil.
+func New(qs graph.QuadStore, label quad.Value) *Engine {
+	if label == nil {
+		label = DefaultLabel
+	}
+	return &Engine{qs: qs, label: label}
+}
+
+// Label returns a label of derived quads.
+func (e *Engine) Label() quad.Value {
+	return e.label
+}
+
+// AddRule registers a new rule. It does not derive any quads; see Materialize.
+func (e *Engine) AddRule(r *Rule) error {
+	if err := r.Validate(); err != nil {
+		return err
+	}
+	e.mu.Lock()
+	e.rules = append(e.rules, r)
+	e.mu.Unlock()
+	return nil
+}
+
+// Rules returns a list of registered rules.
+func (e *Engine) Rules() []*Rule {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]*Rule{}, e.rules...)
+}
+
+// Materialize applies all rules to the whole graph and writes missing derived quads with qw.
+// It must be called after adding new rules to derive quads from existing data.
+func (e *Engine) Materialize(ctx context.Context, qw graph.QuadWriter) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	d := e.newDeriver()
+	for _, r := range e.rules {
+		err := solve(ctx, e.qs, r.Body, nil, func(b binding) error {
+			d.add(instantiate(e.qs, r.Head, b, e.label)...)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	added, err := d.write(qw)
+	if err != nil {
+		return err
+	}
+	return e.propagate(ctx, qw, added)
+}
+
+// deriver collects new derived quads.
+type deriver struct {
+	e    *Engine
+	seen map[quad.Quad]struct{}
+	out  []quad.Quad
+}
+
+func (e *Engine) newDeriver() *deriver {
+	return &deriver{e: e, seen: make(map[quad.Quad]struct{})}
+}
+
+// add records derived quads that are not yet in the store, in any label.
+func (d *deriver) add(quads ...quad.Quad) {
+	for _, q := range quads {
+		if _, ok := d.seen[q]; ok {
+			continue
+		}
+		d.seen[q] = struct{}{}
+		if !graph.HasQuad(d.e.qs, q, true) {
+			d.out = append(d.out, q)
+		}
+	}
+}
+
+// write flushes collected quads to the store.
+func (d *deriver) write(qw graph.QuadWriter) ([]quad.Quad, error) {
+	out := d.out
+	d.out = nil
+	if len(out) == 0 {
+		return nil, nil
+	}
+	tx := graph.NewTransaction()
+	for _, q := range out {
+		tx.AddQuad(q)
+	}
+	return out, qw.ApplyTransaction(tx)
+}
+
+// consequences calls fnc for each rule solution that uses a given quad in the body.
+func (e *Engine) consequences(ctx context.Context, q quad.Quad, fnc func(r *Rule, b binding) error) error {
+	for _, r := range e.rules {
+		for _, p := range r.Body {
+			b, ok := match(e.qs, p, q)
+			if !ok {
+				continue
+			}
+			err := solve(ctx, e.qs, r.Body, b, func(b binding) error {
+				return fnc(r, b)
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// propagate derives quads from newly added ones until no more quads can be derived.
+func (e *Engine) propagate(ctx context.Context, qw graph.QuadWriter, added []quad.Quad) error {
+	d := e.newDeriver()
+	for len(added) != 0 {
+		for _, q := range added {
+			err := e.consequences(ctx, q, func(r *Rule, b binding) error {
+				d.add(instantiate(e.qs, r.Head, b, e.label)...)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+		var err error
+		if added, err = d.write(qw); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// overDelete finds all derived quads that may depend on removed quads.
+// It must be called before quads are removed from the store.
+func (e *Engine) overDelete(ctx context.Context, removed []quad.Quad) ([]quad.Quad, error) {
+	seen := make(map[quad.Quad]struct{})
+	var out []quad.Quad
+	for len(removed) != 0 {
+		var next []quad.Quad
+		for _, q := range removed {
+			err := e.consequences(ctx, q, func(r *Rule, b binding) error {
+				for _, h := range instantiate(e.qs, r.Head, b, e.label) {
+					if _, ok := seen[h]; ok {
+						continue
+					}
+					seen[h] = struct{}{}
+					if graph.HasQuad(e.qs, h, false) {
+						next = append(next, h)
+					}
+				}
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+		out = append(out, next...)
+		removed = next
+	}
+	return out, nil
+}
+
+// derivable checks if a quad can still be derived by any rule.
+func (e *Engine) derivable(ctx context.Context, q quad.Quad) (bool, error) {
+	if q.Label != e.label {
+		return false, nil
+	}
+	for _, r := range e.rules {
+		for _, h := range r.Head {
+			b, ok := match(e.qs, h, q)
+			if !ok {
+				continue
+			}
+			err := solve(ctx, e.qs, r.Body, b, func(binding) error {
+				return errStop
+			})
+			if err == errStop {
+				return true, nil
+			} else if err != nil {
+				return false, err
+			}
+		}
+	}
+	return false, nil
+}
+
+// Writer returns a quad writer that keeps derived quads up to date with changes made through it.
+//
+// Added quads are matched against rule bodies and all new consequences are derived.
+// For removed quads, derived quads that depend on them are removed first, and then
+// rederived if they are still supported by other quads.
+func (e *Engine) Writer(qw graph.QuadWriter) graph.QuadWriter {
+	return &writer{e: e, qw: qw}
+}
+
+type writer struct {
+	e  *Engine
+	qw graph.QuadWriter
+}
+
+func (w *writer) AddQuad(q quad.Quad) error {
+	return w.AddQuadSet([]quad.Quad{q})
+}
+
+func (w *writer) AddQuadSet(quads []quad.Quad) error {
+	tx := graph.NewTransaction()
+	for _, q := range quads {
+		tx.AddQuad(q)
+	}
+	return w.ApplyTransaction(tx)
+}
+
+func (w *writer) RemoveQuad(q quad.Quad) error {
+	tx := graph.NewTransaction()
+	tx.RemoveQuad(q)
+	return w.ApplyTransaction(tx)
+}
+
+func (w *writer) RemoveNode(v graph.Value) error {
+	qs := w.e.qs
+	tx := graph.NewTransaction()
+	for _, d := range quad.Directions {
+		it := qs.QuadIterator(d, v)
+		for it.Next(nil) {
+			tx.RemoveQuad(qs.Quad(it.Result()))
+		}
+		err := it.Err()
+		it.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return w.ApplyTransaction(tx)
+}
+
+func (w *writer) ApplyTransaction(tx *graph.Transaction) error {
+	e := w.e
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	ctx := context.Background()
+
+	var added, removed []quad.Quad
+	for i := range tx.Deltas {
+		d := &tx.Deltas[i]
+		switch d.Action {
+		case graph.Add:
+			added = append(added, d.Quad)
+		case graph.Delete:
+			removed = append(removed, d.Quad)
+		}
+	}
+	over, err := e.overDelete(ctx, removed)
+	if err != nil {
+		return err
+	}
+	if err = w.qw.ApplyTransaction(tx); err != nil {
+		return err
+	}
+	if len(over) != 0 {
+		rtx := graph.NewTransaction()
+		for _, q := range over {
+			rtx.RemoveQuad(q)
+		}
+		if err = w.qw.ApplyTransaction(rtx); err != nil {
+			return err
+		}
+	}
+	// removed quads are not derived while present in other labels, thus they are candidates as well
+	for _, q := range removed {
+		if q.Label != e.label {
+			q.Label = e.label
+			over = append(over, q)
+		}
+	}
+	// restore quads that are still supported; each restored quad may support others
+	for changed := true; changed; {
+		changed = false
+		d := e.newDeriver()
+		rest := over[:0]
+		for _, q := range over {
+			ok, err := e.derivable(ctx, q)
+			if err != nil {
+				return err
+			} else if ok {
+				d.add(q)
+				changed = true
+			} else {
+				rest = append(rest, q)
+			}
+		}
+		over = rest
+		if _, err = d.write(w.qw); err != nil {
+			return err
+		}
+	}
+	return e.propagate(ctx, w.qw, added)
+}
+
+func (w *writer) Close() error {
+	return w.qw.Close()
+}
@@ -0,0 +1,246 @@
+package rules
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/context"
+
+	"github.com/codelingo/cayley/graph"
+	"github.com/codelingo/cayley/graph/path"
+	"github.com/codelingo/cayley/quad"
+)
+
+// binding maps variable names to node values.
+type binding map[string]graph.Value
+
+func (b binding) clone() binding {
+	nb := make(binding, len(b)+2)
+	for k, v := range b {
+		nb[k] = v
+	}
+	return nb
+}
+
+// component is a compiled connected part of the rule body.
+type component struct {
+	path    *path.Path
+	vars    []string          // variables tagged by the path
+	aliases map[string]string // repeated occurrences of variables; must be equal to the first one
+}
+
+// compiler converts rule body to paths.
+//
+// Body patterns are seen as edges of a graph with terms as vertices. Each connected
+// part of this graph is converted to a tree of paths joined by And. The first occurrence
+// of each variable is tagged with the variable name. Other occurrences (in cycles, or
+// variables used as predicates) are tagged with an alias and compared after the iteration.
+type compiler struct {
+	qs      graph.QuadStore
+	body    []Pattern
+	b       binding
+	visited []bool
+	seen    map[string]int
+	comp    *component
+}
+
+// value returns a fixed value of the term. It returns false if the term is an unbound variable.
+func (c *compiler) value(t Term) (quad.Value, bool) {
+	if !t.IsVar() {
+		return t.Value, true
+	} else if v, ok := c.b[t.Var]; ok {
+		return c.qs.NameOf(v), true
+	}
+	return nil, false
+}
+
+// tag returns a tag name for the next occurrence of the variable.
+func (c *compiler) tag(name string) string {
+	n := c.seen[name]
+	c.seen[name] = n + 1
+	if n == 0 {
+		c.comp.vars = append(c.comp.vars, name)
+		return name
+	}
+	alias := name + "#" + strconv.Itoa(n)
+	c.comp.aliases[alias] = name
+	return alias
+}
+
+// link joins the current node path with a path of the other end of the pattern.
+func (c *compiler) link(p *path.Path, pat Pattern, other Term, out bool) *path.Path {
+	var (
+		tags []string
+		via  []interface{}
+	)
+	if v, ok := c.value(pat.Predicate); ok {
+		via = append(via, v)
+	} else {
+		tags = []string{c.tag(pat.Predicate.Var)}
+	}
+	sub := c.node(other)
+	if out {
+		// current node is a subject; go back from objects
+		return p.And(sub.InWithTags(tags, via...))
+	}
+	return p.And(sub.OutWithTags(tags, via...))
+}
+
+// node builds a path for a term, following all unvisited patterns that use the same variable.
+func (c *compiler) node(t Term) *path.Path {
+	if v, ok := c.value(t); ok {
+		return path.StartMorphism(v)
+	} else if c.seen[t.Var] != 0 {
+		return path.StartMorphism().Tag(c.tag(t.Var))
+	}
+	p := path.StartMorphism().Tag(c.tag(t.Var))
+	for i, pat := range c.body {
+		if c.visited[i] {
+			continue
+		}
+		if s := pat.Subject; s.IsVar() && s.Var == t.Var && !c.bound(s) {
+			c.visited[i] = true
+			p = c.link(p, pat, pat.Object, true)
+		} else if o := pat.Object; o.IsVar() && o.Var == t.Var && !c.bound(o) {
+			c.visited[i] = true
+			p = c.link(p, pat, pat.Subject, false)
+		}
+	}
+	return p
+}
+
+func (c *compiler) bound(t Term) bool {
+	_, ok := c.value(t)
+	return ok
+}
+
+// compile converts rule body patterns to a list of independent paths, given a binding of some variables.
+func compile(qs graph.QuadStore, body []Pattern, b binding) []component {
+	c := &compiler{
+		qs: qs, body: body, b: b,
+		visited: make([]bool, len(body)),
+		seen:    make(map[string]int),
+	}
+	var out []component
+	for i, pat := range body {
+		if c.visited[i] {
+			continue
+		}
+		c.comp = &component{aliases: make(map[string]string)}
+		var p *path.Path
+		if !c.bound(pat.Subject) {
+			p = c.node(pat.Subject)
+		} else if !c.bound(pat.Object) {
+			p = c.node(pat.Object)
+		} else {
+			// both ends are fixed
+			c.visited[i] = true
+			p = c.link(c.node(pat.Subject), pat, pat.Object, true)
+		}
+		c.comp.path = p
+		out = append(out, *c.comp)
+	}
+	return out
+}
+
+// solve calls fnc for each solution of the rule body that extends a given binding.
+func solve(ctx context.Context, qs graph.QuadStore, body []Pattern, b binding, fnc func(binding) error) error {
+	return solveComponents(ctx, qs, compile(qs, body, b), b, fnc)
+}
+
+// solveComponents computes a cross product of solutions for independent parts of the rule body.
+func solveComponents(ctx context.Context, qs graph.QuadStore, comps []component, b binding, fnc func(binding) error) error {
+	if len(comps) == 0 {
+		return fnc(b)
+	}
+	c := comps[0]
+	it := c.path.BuildIteratorOn(qs)
+	// collect solutions first to avoid running nested queries while iterating
+	var sols []binding
+	err := graph.Iterate(ctx, it).On(qs).Paths(true).TagEach(func(tags map[string]graph.Value) {
+		for alias, name := range c.aliases {
+			want, ok := tags[name]
+			if !ok {
+				// variable is bound by one of the previous components
+				want = b[name]
+			}
+			if graph.ToKey(tags[alias]) != graph.ToKey(want) {
+				return
+			}
+		}
+		nb := b.clone()
+		for _, name := range c.vars {
+			nb[name] = tags[name]
+		}
+		sols = append(sols, nb)
+	})
+	if err != nil {
+		return err
+	}
+	seen := make(map[string]struct{}, len(sols))
+	for _, nb := range sols {
+		// the same solution may be found via different paths
+		k := nb.key(c.vars)
+		if _, ok := seen[k]; ok {
+			continue
+		}
+		seen[k] = struct{}{}
+		if err = solveComponents(ctx, qs, comps[1:], nb, fnc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// key returns a unique key for values of given variables. It is used to remove duplicate solutions.
+func (b binding) key(vars []string) string {
+	parts := make([]string, 0, len(vars))
+	for _, name := range vars {
+		parts = append(parts, fmt.Sprintf("%v", graph.ToKey(b[name])))
+	}
+	return strings.Join(parts, "\x00")
+}
+
+// instantiate fills head patterns with values of the binding.
+func instantiate(qs graph.QuadStore, head []Pattern, b binding, label quad.Value) []quad.Quad {
+	out := make([]quad.Quad, 0, len(head))
+	get := func(t Term) quad.Value {
+		if t.IsVar() {
+			return qs.NameOf(b[t.Var])
+		}
+		return t.Value
+	}
+	for _, p := range head {
+		q := quad.Quad{
+			Subject:   get(p.Subject),
+			Predicate: get(p.Predicate),
+			Object:    get(p.Object),
+			Label:     label,
+		}
+		if q.IsValid() {
+			out = append(out, q)
+		}
+	}
+	return out
+}
+
+// match binds variables of the pattern to values of the quad. It returns false if the quad doesn't match the pattern.
+func match(qs graph.QuadStore, p Pattern, q quad.Quad) (binding, bool) {
+	b := make(binding, 3)
+	vals := [3]quad.Value{q.Subject, q.Predicate, q.Object}
+	for i, t := range p.terms() {
+		if !t.IsVar() {
+			if t.Value.String() != vals[i].String() {
+				return nil, false
+			}
+			continue
+		}
+		v := qs.ValueOf(vals[i])
+		if cur, ok := b[t.Var]; ok && graph.ToKey(cur) != graph.ToKey(v) {
+			return nil, false
+		}
+		b[t.Var] = v
+	}
+	return b, true
+}
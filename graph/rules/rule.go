@@ -0,0 +1,269 @@
+// Package rules implements a forward-chaining rule engine that materializes derived quads.
+//
+// Rules are written in a Datalog-like syntax: a comma-separated list of body patterns,
+// an arrow and a list of head patterns. Variables start with a question mark:
+//
+//	(?a <parent> ?b), (?b <parent> ?c) -> (?a <grandparent> ?c)
+//
+// Each head pattern is instantiated for every solution of the rule body, and the
+// resulting quads are written to a dedicated label. Rules are applied until no new
+// quads can be derived, thus recursive rules are supported as well.
+package rules
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/codelingo/cayley/quad"
+	"github.com/codelingo/cayley/quad/nquads"
+)
+
+// Term is either a variable or a fixed value in a rule pattern.
+type Term struct {
+	Var   string
+	Value quad.Value
+}
+
+// IsVar checks if term is a variable.
+func (t Term) IsVar() bool { return t.Var != "" }
+
+func (t Term) String() string {
+	if t.IsVar() {
+		return "?" + t.Var
+	}
+	return quad.StringOf(t.Value)
+}
+
+// Pattern is a triple pattern of a rule.
+type Pattern struct {
+	Subject, Predicate, Object Term
+}
+
+func (p Pattern) terms() [3]Term {
+	return [3]Term{p.Subject, p.Predicate, p.Object}
+}
+
+func (p Pattern) String() string {
+	return "(" + p.Subject.String() + " " + p.Predicate.String() + " " + p.Object.String() + ")"
+}
+
+// Rule derives head patterns for each solution of body patterns.
+type Rule struct {
+	Body []Pattern
+	Head []Pattern
+}
+
+func (r *Rule) String() string {
+	list := func(pats []Pattern) string {
+		s := make([]string, 0, len(pats))
+		for _, p := range pats {
+			s = append(s, p.String())
+		}
+		return strings.Join(s, ", ")
+	}
+	return list(r.Body) + " -> " + list(r.Head)
+}
+
+// Validate checks that the rule has a body and a head, and all head variables are bound by the body.
+func (r *Rule) Validate() error {
+	if len(r.Body) == 0 {
+		return fmt.Errorf("rules: empty rule body")
+	} else if len(r.Head) == 0 {
+		return fmt.Errorf("rules: empty rule head")
+	}
+	vars := make(map[string]bool)
+	for _, p := range r.Body {
+		for _, t := range p.terms() {
+			if t.IsVar() {
+				vars[t.Var] = true
+			}
+		}
+	}
+	for _, p := range r.Head {
+		for _, t := range p.terms() {
+			if t.IsVar() && !vars[t.Var] {
+				return fmt.Errorf("rules: variable ?%s is not bound by the rule body", t.Var)
+			} else if !t.IsVar() && t.Value == nil {
+				return fmt.Errorf("rules: empty value in the rule head")
+			}
+		}
+	}
+	return nil
+}
+
+// Parse parses a single rule.
+func Parse(s string) (*Rule, error) {
+	p := &parser{s: s}
+	r, err := p.rule()
+	if err != nil {
+		return nil, fmt.Errorf("rules: %v in %q", err, s)
+	}
+	if err = r.Validate(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// ReadRules reads rules from r, one rule per line. Empty lines and lines starting with '#' are ignored.
+func ReadRules(r io.Reader) ([]*Rule, error) {
+	var out []*Rule
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rule, err := Parse(line)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, rule)
+	}
+	return out, sc.Err()
+}
+
+type parser struct {
+	s   string
+	pos int
+}
+
+func (p *parser) skipSpace() {
+	for p.pos < len(p.s) && strings.IndexByte(" \t\r\n", p.s[p.pos]) >= 0 {
+		p.pos++
+	}
+}
+
+// consume skips spaces and the given token, if it's present.
+func (p *parser) consume(tok string) bool {
+	p.skipSpace()
+	if strings.HasPrefix(p.s[p.pos:], tok) {
+		p.pos += len(tok)
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(tok string) error {
+	if !p.consume(tok) {
+		return p.errorf("expected %q", tok)
+	}
+	return nil
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("at %d: %s", p.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *parser) rule() (*Rule, error) {
+	body, err := p.patterns()
+	if err != nil {
+		return nil, err
+	}
+	if err = p.expect("->"); err != nil {
+		return nil, err
+	}
+	head, err := p.patterns()
+	if err != nil {
+		return nil, err
+	}
+	if p.consume("."); p.pos != len(p.s) {
+		return nil, p.errorf("unexpected data after the rule")
+	}
+	return &Rule{Body: body, Head: head}, nil
+}
+
+func (p *parser) patterns() ([]Pattern, error) {
+	var out []Pattern
+	for {
+		pat, err := p.pattern()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, pat)
+		if !p.consume(",") {
+			return out, nil
+		}
+	}
+}
+
+func (p *parser) pattern() (pat Pattern, err error) {
+	if err = p.expect("("); err != nil {
+		return
+	}
+	if pat.Subject, err = p.term(); err != nil {
+		return
+	}
+	if pat.Predicate, err = p.term(); err != nil {
+		return
+	}
+	if pat.Object, err = p.term(); err != nil {
+		return
+	}
+	err = p.expect(")")
+	return
+}
+
+func isDelim(c byte) bool {
+	return strings.IndexByte(" \t\r\n(),", c) >= 0
+}
+
+func (p *parser) term() (Term, error) {
+	p.skipSpace()
+	if p.pos == len(p.s) {
+		return Term{}, p.errorf("unexpected end of rule")
+	}
+	start := p.pos
+	switch c := p.s[p.pos]; {
+	case c == '?':
+		p.pos++
+		for p.pos < len(p.s) && !isDelim(p.s[p.pos]) {
+			p.pos++
+		}
+		if p.pos == start+1 {
+			return Term{}, p.errorf("empty variable name")
+		}
+		return Term{Var: p.s[start+1 : p.pos]}, nil
+	case c == '<':
+		i := strings.IndexByte(p.s[p.pos:], '>')
+		if i < 0 {
+			return Term{}, p.errorf("unterminated IRI")
+		}
+		p.pos += i + 1
+	case c == '"':
+		p.pos++
+		for p.pos < len(p.s) && p.s[p.pos] != '"' {
+			if p.s[p.pos] == '\\' {
+				p.pos++
+			}
+			p.pos++
+		}
+		if p.pos >= len(p.s) {
+			return Term{}, p.errorf("unterminated string")
+		}
+		p.pos++
+		if strings.HasPrefix(p.s[p.pos:], "^^<") {
+			i := strings.IndexByte(p.s[p.pos:], '>')
+			if i < 0 {
+				return Term{}, p.errorf("unterminated IRI")
+			}
+			p.pos += i + 1
+		} else {
+			for p.pos < len(p.s) && !isDelim(p.s[p.pos]) {
+				p.pos++
+			}
+		}
+	case strings.HasPrefix(p.s[p.pos:], "_:"):
+		for p.pos < len(p.s) && !isDelim(p.s[p.pos]) {
+			p.pos++
+		}
+	default:
+		return Term{}, p.errorf("unexpected character %q", c)
+	}
+	v, err := nquads.ParseValue(p.s[start:p.pos])
+	if err != nil {
+		return Term{}, p.errorf("%v", err)
+	}
+	return Term{Value: v}, nil
+}
@@ -0,0 +1,204 @@
+package rules
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+
+	"github.com/codelingo/cayley/graph"
+	"github.com/codelingo/cayley/graph/memstore"
+	"github.com/codelingo/cayley/quad"
+	_ "github.com/codelingo/cayley/writer"
+)
+
+var parseCases = []struct {
+	rule string
+	exp  *Rule
+	err  bool
+}{
+	{
+		rule: `(?a <parent> ?b), (?b <parent> ?c) -> (?a <grandparent> ?c)`,
+		exp: &Rule{
+			Body: []Pattern{
+				{Term{Var: "a"}, Term{Value: quad.IRI("parent")}, Term{Var: "b"}},
+				{Term{Var: "b"}, Term{Value: quad.IRI("parent")}, Term{Var: "c"}},
+			},
+			Head: []Pattern{
+				{Term{Var: "a"}, Term{Value: quad.IRI("grandparent")}, Term{Var: "c"}},
+			},
+		},
+	},
+	{
+		rule: `(?a <status> "cool"@en), (_:b ?p "x, y"^^<type>) -> (?a <is> "cool"), (?a ?p _:b) .`,
+		exp: &Rule{
+			Body: []Pattern{
+				{Term{Var: "a"}, Term{Value: quad.IRI("status")}, Term{Value: quad.LangString{Value: "cool", Lang: "en"}}},
+				{Term{Value: quad.BNode("b")}, Term{Var: "p"}, Term{Value: quad.TypedString{Value: "x, y", Type: "type"}}},
+			},
+			Head: []Pattern{
+				{Term{Var: "a"}, Term{Value: quad.IRI("is")}, Term{Value: quad.String("cool")}},
+				{Term{Var: "a"}, Term{Var: "p"}, Term{Value: quad.BNode("b")}},
+			},
+		},
+	},
+	{rule: `(?a <p> ?b) -> (?a <q> ?c)`, err: true},
+	{rule: `(?a <p> ?b)`, err: true},
+	{rule: `(?a <p> ?b) -> (?a <q> ?b) x`, err: true},
+	{rule: `(?a <p> bare) -> (?a <q> ?b)`, err: true},
+}
+
+func TestParse(t *testing.T) {
+	for _, c := range parseCases {
+		r, err := Parse(c.rule)
+		if c.err {
+			require.Error(t, err, "%s", c.rule)
+			continue
+		}
+		require.NoError(t, err, "%s", c.rule)
+		require.Equal(t, c.exp, r, "%s", c.rule)
+	}
+}
+
+func TestReadRules(t *testing.T) {
+	rules, err := ReadRules(strings.NewReader(`
+# family
+(?a <parent> ?b), (?b <parent> ?c) -> (?a <grandparent> ?c)
+
+(?a <parent> ?b) -> (?b <child> ?a)
+`))
+	require.NoError(t, err)
+	require.Len(t, rules, 2)
+	require.Equal(t, `(?a <parent> ?b) -> (?b <child> ?a)`, rules[1].String())
+}
+
+// derived returns all quads in the label of derived quads.
+func derived(t testing.TB, qs graph.QuadStore, label quad.Value) []string {
+	var out []string
+	it := qs.QuadIterator(quad.Label, qs.ValueOf(label))
+	defer it.Close()
+	for it.Next(nil) {
+		q := qs.Quad(it.Result())
+		q.Label = nil
+		out = append(out, q.NQuad())
+	}
+	require.NoError(t, it.Err())
+	sort.Strings(out)
+	return out
+}
+
+func newEngine(t testing.TB, qs graph.QuadStore, rules ...string) *Engine {
+	e := New(qs, nil)
+	for _, s := range rules {
+		r, err := Parse(s)
+		require.NoError(t, err)
+		require.NoError(t, e.AddRule(r))
+	}
+	return e
+}
+
+func newWriter(t testing.TB, qs graph.QuadStore) graph.QuadWriter {
+	qw, err := graph.NewQuadWriter("single", qs, nil)
+	require.NoError(t, err)
+	return qw
+}
+
+func TestMaterialize(t *testing.T) {
+	qs := memstore.New(
+		quad.MakeIRI("alice", "parent", "bob", ""),
+		quad.MakeIRI("bob", "parent", "charlie", ""),
+		quad.MakeIRI("charlie", "parent", "dani", ""),
+		quad.MakeIRI("alice", "likes", "alice", ""),
+		quad.MakeIRI("bob", "likes", "alice", ""),
+	)
+	qw := newWriter(t, qs)
+	e := newEngine(t, qs,
+		`(?a <parent> ?b) -> (?a <ancestor> ?b)`,
+		`(?a <ancestor> ?b), (?b <parent> ?c) -> (?a <ancestor> ?c)`,
+		`(?a <likes> ?a) -> (?a <narcissist> "true")`,
+	)
+	require.NoError(t, e.Materialize(context.TODO(), qw))
+	require.Equal(t, []string{
+		`<alice> <ancestor> <bob> .`,
+		`<alice> <ancestor> <charlie> .`,
+		`<alice> <ancestor> <dani> .`,
+		`<alice> <narcissist> "true" .`,
+		`<bob> <ancestor> <charlie> .`,
+		`<bob> <ancestor> <dani> .`,
+		`<charlie> <ancestor> <dani> .`,
+	}, derived(t, qs, DefaultLabel))
+
+	// must be idempotent
+	require.NoError(t, e.Materialize(context.TODO(), qw))
+	require.Len(t, derived(t, qs, DefaultLabel), 7)
+}
+
+func TestVariablePredicate(t *testing.T) {
+	qs := memstore.New(
+		quad.MakeIRI("knows", "symmetric", "true", ""),
+		quad.MakeIRI("alice", "knows", "bob", ""),
+		quad.MakeIRI("alice", "follows", "bob", ""),
+	)
+	qw := newWriter(t, qs)
+	e := newEngine(t, qs,
+		`(?p <symmetric> <true>), (?a ?p ?b) -> (?b ?p ?a)`,
+	)
+	require.NoError(t, e.Materialize(context.TODO(), qw))
+	require.Equal(t, []string{
+		`<bob> <knows> <alice> .`,
+	}, derived(t, qs, DefaultLabel))
+
+	// quad is derived when it's removed from other labels
+	w := e.Writer(qw)
+	err := w.AddQuadSet([]quad.Quad{
+		quad.MakeIRI("carol", "knows", "alice", ""),
+		quad.MakeIRI("bob", "knows", "alice", ""),
+	})
+	require.NoError(t, err)
+	err = w.RemoveQuad(quad.MakeIRI("alice", "knows", "bob", ""))
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		`<alice> <knows> <bob> .`,
+		`<alice> <knows> <carol> .`,
+	}, derived(t, qs, DefaultLabel))
+}
+
+func TestWriter(t *testing.T) {
+	qs := memstore.New()
+	e := newEngine(t, qs,
+		`(?a <parent> ?b) -> (?a <ancestor> ?b)`,
+		`(?a <ancestor> ?b), (?b <ancestor> ?c) -> (?a <ancestor> ?c)`,
+	)
+	qw := e.Writer(newWriter(t, qs))
+
+	err := qw.AddQuadSet([]quad.Quad{
+		quad.MakeIRI("alice", "parent", "bob", ""),
+		quad.MakeIRI("bob", "parent", "charlie", ""),
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		`<alice> <ancestor> <bob> .`,
+		`<alice> <ancestor> <charlie> .`,
+		`<bob> <ancestor> <charlie> .`,
+	}, derived(t, qs, DefaultLabel))
+
+	// second path to the same quad
+	err = qw.AddQuad(quad.MakeIRI("alice", "parent", "charlie", ""))
+	require.NoError(t, err)
+	require.Len(t, derived(t, qs, DefaultLabel), 3)
+
+	err = qw.RemoveQuad(quad.MakeIRI("bob", "parent", "charlie", ""))
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		`<alice> <ancestor> <bob> .`,
+		`<alice> <ancestor> <charlie> .`,
+	}, derived(t, qs, DefaultLabel))
+
+	err = qw.RemoveQuad(quad.MakeIRI("alice", "parent", "charlie", ""))
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		`<alice> <ancestor> <bob> .`,
+	}, derived(t, qs, DefaultLabel))
+}
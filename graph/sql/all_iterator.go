@@ -128,7 +128,7 @@ func (it *AllIterator) SubIterators() []graph.Iterator {
 }
 
 func (it *AllIterator) Next(ctx *graph.IterationContext) bool {
-	graph.NextLogIn(it)
+	graph.NextLogIn(ctx, it)
 	if it.cursor == nil {
 		it.makeCursor()
 		if it.cursor == nil {
@@ -166,13 +166,13 @@ func (it *AllIterator) Next(ctx *graph.IterationContext) bool {
 		return false
 	}
 	it.result = q
-	return graph.NextLogOut(it, true)
+	return graph.NextLogOut(ctx, it, true)
 }
 
 func (it *AllIterator) Contains(ctx *graph.IterationContext, v graph.Value) bool {
-	graph.ContainsLogIn(it, v)
+	graph.ContainsLogIn(ctx, it, v)
 	it.result = v
-	return graph.ContainsLogOut(it, v, true)
+	return graph.ContainsLogOut(ctx, it, v, true)
 }
 
 func (it *AllIterator) Size() (int64, bool) {
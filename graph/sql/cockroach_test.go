@@ -60,6 +60,7 @@ func TestCockroachAll(t *testing.T) {
 	graphtest.TestAll(t, makeCockroach, &graphtest.Config{
 		TimeInMcs:               true,
 		TimeRound:               true,
+		OptimizesComparison:     true,
 		OptimizesHasAToUnique:   true,
 		SkipIntHorizon:          true,
 		SkipNodeDelAfterQuadDel: true,
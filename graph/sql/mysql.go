@@ -59,6 +59,10 @@ func init() {
 		},
 		Estimated: nil,
 		RunTx:     runTxMysql,
+		Binary: func(col string) string {
+			return "BINARY " + col
+		},
+		RegexpOp: "REGEXP",
 	})
 }
 
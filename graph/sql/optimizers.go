@@ -16,9 +16,14 @@ package sql
 
 import (
 	"errors"
+	"reflect"
+	"regexp/syntax"
+	"strings"
+	"time"
 
 	"github.com/codelingo/cayley/clog"
 	"github.com/codelingo/cayley/graph"
+	"github.com/codelingo/cayley/graph/fulltext"
 	"github.com/codelingo/cayley/graph/iterator"
 	"github.com/codelingo/cayley/quad"
 )
@@ -143,6 +148,16 @@ func (qs *QuadStore) OptimizeIterator(it graph.Iterator) (graph.Iterator, bool)
 		return qs.optimizeHasA(it.(*iterator.HasA))
 	case graph.And:
 		return qs.optimizeAnd(it.(*iterator.And))
+	case graph.Comparison:
+		return qs.optimizeComparison(it.(*iterator.Comparison))
+	case graph.Regex:
+		return qs.optimizeRegex(it.(*iterator.Regex))
+	case graph.Limit:
+		return qs.optimizeLimit(it.(*iterator.Limit))
+	case graph.Skip:
+		return qs.optimizeSkip(it.(*iterator.Skip))
+	case fulltext.Type():
+		return qs.optimizeSearch(it.(*fulltext.Iterator))
 	}
 	return it, false
 }
@@ -327,3 +342,228 @@ func (qs *QuadStore) optimizeHasA(it *iterator.HasA) (graph.Iterator, bool) {
 	}
 	return it, false
 }
+
+// nodeIterator returns an SQL iterator for nodes that can be filtered by values.
+func (qs *QuadStore) nodeIterator(it graph.Iterator) (*SQLIterator, bool) {
+	switch it := it.(type) {
+	case *AllIterator:
+		if it.table != "nodes" {
+			return nil, false
+		}
+		n := &SQLNodeIterator{tableName: newTableName()}
+		n.tagger.CopyFrom(it)
+		return NewSQLIterator(qs, n), true
+	case *SQLIterator:
+		if it.limit > 0 || it.offset > 0 {
+			// filters must be applied after the limit
+			return nil, false
+		}
+		switch it.sql.(type) {
+		case *SQLNodeIterator, *SQLNodeIntersection:
+			return it, true
+		}
+	}
+	return nil, false
+}
+
+// addNodeFilter adds a filter to a node iterator. It returns false if the iterator already has it.
+func addNodeFilter(it *SQLIterator, f nodeFilter) bool {
+	var filters *[]nodeFilter
+	switch n := it.sql.(type) {
+	case *SQLNodeIterator:
+		filters = &n.filters
+	case *SQLNodeIntersection:
+		filters = &n.filters
+	default:
+		panic("sql: filter on a non-node iterator")
+	}
+	for _, f2 := range *filters {
+		if f2.where == f.where && reflect.DeepEqual(f2.values, f.values) {
+			return false
+		}
+	}
+	*filters = append(*filters, f)
+	return true
+}
+
+func (qs *QuadStore) optimizeComparison(it *iterator.Comparison) (graph.Iterator, bool) {
+	subs := it.SubIterators()
+	if len(subs) != 1 {
+		return it, false
+	}
+	var op string
+	switch it.Operator() {
+	case iterator.CompareLT:
+		op = "<"
+	case iterator.CompareLTE:
+		op = "<="
+	case iterator.CompareGT:
+		op = ">"
+	case iterator.CompareGTE:
+		op = ">="
+	default:
+		return it, false
+	}
+	var f nodeFilter
+	str := qs.flavor.binary("value_string")
+	switch v := it.Value().(type) {
+	case quad.String:
+		f.where = str + " " + op + " ? AND iri IS NULL AND bnode IS NULL AND datatype IS NULL AND language IS NULL"
+		f.values = sqlArgs{escapeNullByte(string(v))}
+	case quad.IRI:
+		f.where = str + " " + op + " ? AND iri IS NOT NULL"
+		f.values = sqlArgs{string(v)}
+	case quad.BNode:
+		f.where = str + " " + op + " ? AND bnode IS NOT NULL"
+		f.values = sqlArgs{string(v)}
+	case quad.Int:
+		f.where = "value_int " + op + " ?"
+		f.values = sqlArgs{int64(v)}
+	case quad.Float:
+		f.where = "value_float " + op + " ?"
+		f.values = sqlArgs{float64(v)}
+	case quad.Time:
+		f.where = "value_time " + op + " ?"
+		f.values = sqlArgs{time.Time(v)}
+	default:
+		// other values are compared as strings of any type
+		return it, false
+	}
+	newit, ok := qs.nodeIterator(subs[0])
+	if !ok || !addNodeFilter(newit, f) {
+		return it, false
+	}
+	newit.Tagger().CopyFrom(it)
+	return newit, true
+}
+
+// portableRegexp checks if a regular expression has the same meaning in Go and in
+// POSIX extended regular expressions, which are used by most databases.
+func portableRegexp(expr string) bool {
+	if _, err := syntax.Parse(expr, syntax.Perl); err != nil {
+		return false
+	}
+	// flags and non-capturing groups
+	if strings.Contains(expr, "(?") {
+		return false
+	}
+	for i := 0; i < len(expr); i++ {
+		switch expr[i] {
+		case '\\':
+			// only escaped punctuation; classes like \d or \b are not portable
+			if i+1 >= len(expr) {
+				return false
+			}
+			i++
+			if c := expr[i]; c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' {
+				return false
+			}
+		case '*', '+', '?', '}':
+			// non-greedy repetitions
+			if i+1 < len(expr) && expr[i+1] == '?' {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (qs *QuadStore) optimizeRegex(it *iterator.Regex) (graph.Iterator, bool) {
+	subs := it.SubIterators()
+	if len(subs) != 1 || qs.flavor.RegexpOp == "" {
+		return it, false
+	}
+	expr := it.Regexp().String()
+	if !portableRegexp(expr) {
+		return it, false
+	}
+	where := qs.flavor.binary("value_string") + " " + qs.flavor.RegexpOp + " ?"
+	if !it.RefsAllowed() {
+		where += " AND iri IS NULL AND bnode IS NULL"
+	}
+	// Values of other types (quad.Raw, for example) are stored in a binary form and are
+	// checked by the Regex iterator that is left on top of the query.
+	f := nodeFilter{
+		where:  "(" + where + ") OR value IS NOT NULL",
+		values: sqlArgs{expr},
+	}
+	newit, ok := qs.nodeIterator(subs[0])
+	if !ok || !addNodeFilter(newit, f) {
+		return it, false
+	}
+	out := iterator.NewRegex(newit, it.Regexp(), qs)
+	out.AllowRefs(it.RefsAllowed())
+	out.Tagger().CopyFrom(it)
+	return out, true
+}
+
+// optimizeSearch replaces a full-text search with a native search of the database.
+// Results are not ranked, thus searches that need relevance scores are left to the full-text index.
+func (qs *QuadStore) optimizeSearch(it *fulltext.Iterator) (graph.Iterator, bool) {
+	if qs.flavor.TextSearch == nil || len(it.ScoreTags()) != 0 {
+		return it, false
+	}
+	n := &SQLNodeIterator{tableName: newTableName()}
+	n.tagger.CopyFrom(it)
+	n.filters = []nodeFilter{{
+		where:  "value_string IS NOT NULL AND iri IS NULL AND bnode IS NULL AND " + qs.flavor.TextSearch("value_string"),
+		values: sqlArgs{it.Query()},
+	}}
+	return NewSQLIterator(qs, n), true
+}
+
+// limitable checks if the number of rows returned by an SQL iterator can be limited.
+// Tags may produce multiple rows for the same result, which are then merged into paths.
+func (qs *QuadStore) limitable(it graph.Iterator) (*SQLIterator, bool) {
+	switch it.Type() {
+	case graph.Limit, graph.Skip:
+		// these iterators do not ask the QuadStore to optimize them
+		it, _ = qs.OptimizeIterator(it)
+	}
+	sit, ok := it.(*SQLIterator)
+	if !ok || len(sit.sql.getTags()) != 0 {
+		return nil, false
+	}
+	return sit, true
+}
+
+func (qs *QuadStore) optimizeLimit(it *iterator.Limit) (graph.Iterator, bool) {
+	subs := it.SubIterators()
+	if len(subs) != 1 || it.Limit() <= 0 {
+		return it, false
+	}
+	sit, ok := qs.limitable(subs[0])
+	if !ok {
+		return it, false
+	}
+	// the iterator may be still used by the original tree
+	sit = sit.Clone().(*SQLIterator)
+	if sit.limit <= 0 || it.Limit() < sit.limit {
+		sit.limit = it.Limit()
+	}
+	sit.Tagger().CopyFrom(it)
+	return sit, true
+}
+
+func (qs *QuadStore) optimizeSkip(it *iterator.Skip) (graph.Iterator, bool) {
+	subs := it.SubIterators()
+	if len(subs) != 1 || it.Skip() <= 0 {
+		return it, false
+	}
+	sit, ok := qs.limitable(subs[0])
+	if !ok {
+		return it, false
+	}
+	// the iterator may be still used by the original tree
+	sit = sit.Clone().(*SQLIterator)
+	if sit.limit > 0 {
+		// skipping after the limit was applied
+		if sit.limit <= it.Skip() {
+			return iterator.NewNull(), true
+		}
+		sit.limit -= it.Skip()
+	}
+	sit.offset += it.Skip()
+	sit.Tagger().CopyFrom(it)
+	return sit, true
+}
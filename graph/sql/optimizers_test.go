@@ -15,9 +15,12 @@
 package sql
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/codelingo/cayley/graph"
+	"github.com/codelingo/cayley/graph/fulltext"
+	"github.com/codelingo/cayley/graph/iterator"
 	"github.com/codelingo/cayley/quad"
 )
 
@@ -68,6 +71,74 @@ func TestBuildLinksTo(t *testing.T) {
 	t.Log(s, v)
 }
 
+func TestBuildValueFilters(t *testing.T) {
+	qs := &QuadStore{flavor: flavors[flavorPostgres]}
+	a := NewSQLLinkIterator(qs, quad.Predicate, quad.IRI("age"))
+	it, err := hasa(a.sql, quad.Object, qs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nit, ok := qs.OptimizeIterator(iterator.NewComparison(it, iterator.CompareGT, quad.Int(21), qs))
+	if !ok {
+		t.Fatal("comparison was not optimized")
+	}
+	nit, ok = qs.OptimizeIterator(iterator.NewLimit(nit, 10))
+	if !ok {
+		t.Fatal("limit was not optimized")
+	}
+	sit := nit.(*SQLIterator)
+	s, v := sit.sql.buildSQL(&qs.flavor, true, nil)
+	if !strings.Contains(s, "IN (SELECT hash FROM nodes WHERE value_int > ?)") {
+		t.Errorf("unexpected query: %s", s)
+	}
+	if len(v) != 2 || v[1] != int64(21) {
+		t.Errorf("unexpected query values: %v", v)
+	}
+	if s = limitSQL(s, sit.limit, sit.offset); !strings.HasSuffix(s, " LIMIT 10;") || strings.Contains(s, "DISTINCT") {
+		t.Errorf("unexpected query: %s", s)
+	}
+}
+
+func TestBuildTextSearch(t *testing.T) {
+	qs := &QuadStore{flavor: flavors[flavorPostgres]}
+	if _, ok := qs.OptimizeIterator(fulltext.NewIterator(qs, "foo bar", "score")); ok {
+		t.Error("search with scores must not be optimized")
+	}
+	nit, ok := qs.OptimizeIterator(fulltext.NewIterator(qs, "foo bar"))
+	if !ok {
+		t.Fatal("search was not optimized")
+	}
+	s, v := nit.(*SQLIterator).sql.buildSQL(&qs.flavor, true, nil)
+	if !strings.Contains(s, "@@ plainto_tsquery('simple', ?)") {
+		t.Errorf("unexpected query: %s", s)
+	}
+	if len(v) != 1 || v[0] != "foo bar" {
+		t.Errorf("unexpected query values: %v", v)
+	}
+}
+
+var casesPortableRegexp = []struct {
+	expr string
+	ok   bool
+}{
+	{`^bob`, true},
+	{`^[a-z]+ (foo|bar)?$`, true},
+	{`a\.b{1,3}`, true},
+	{`\d+`, false},
+	{`\bword`, false},
+	{`(?i)bob`, false},
+	{`a.*?b`, false},
+	{`a(`, false},
+}
+
+func TestPortableRegexp(t *testing.T) {
+	for _, c := range casesPortableRegexp {
+		if ok := portableRegexp(c.expr); ok != c.ok {
+			t.Errorf("unexpected result for %q: got:%v expect:%v", c.expr, ok, c.ok)
+		}
+	}
+}
+
 func TestInterestingQuery(t *testing.T) {
 	if *postgres_path == "" {
 		t.SkipNow()
@@ -117,7 +188,7 @@ func TestInterestingQuery(t *testing.T) {
 	s, v := it8.sql.buildSQL(&Flavor{}, true, nil)
 	it8.Tagger().Add("id")
 	t.Log(s, v)
-	for it8.Next() {
+	for it8.Next(nil) {
 		t.Log(it8.Result())
 		out := make(map[string]graph.Value)
 		it8.TagResults(out)
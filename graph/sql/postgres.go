@@ -75,6 +75,13 @@ func init() {
 			return "SELECT reltuples::BIGINT AS estimate FROM pg_class WHERE relname='" + table + "';"
 		},
 		RunTx: runTxPostgres,
+		Binary: func(col string) string {
+			return col + ` COLLATE "C"`
+		},
+		RegexpOp: "~",
+		TextSearch: func(col string) string {
+			return "to_tsvector('simple', " + col + ") @@ plainto_tsquery('simple', ?)"
+		},
 	})
 }
 
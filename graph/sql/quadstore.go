@@ -111,6 +111,22 @@ type Flavor struct {
 	Error       func(error) error
 	Estimated   func(table string) string
 	RunTx       func(tx *sql.Tx, in []graph.Delta, opts graph.IgnoreOpts) error
+	// Binary, if set, converts a string column to compare it byte-by-byte.
+	Binary func(col string) string
+	// RegexpOp is an operator that matches a string column against a regular expression.
+	// Regex iterators are not optimized if it's not set.
+	RegexpOp string
+	// TextSearch, if set, returns a condition that matches a string column against a full-text query.
+	// The query is passed as a single placeholder. Search iterators without score tags are optimized only if it's set.
+	TextSearch func(col string) string
+}
+
+// binary converts a string column for a byte-wise comparison, the way Go compares strings.
+func (fl *Flavor) binary(col string) string {
+	if fl.Binary == nil {
+		return col
+	}
+	return fl.Binary(col)
 }
 
 var flavors = make(map[string]Flavor)
@@ -53,6 +53,14 @@ func (t tagDir) SQL(c rune) string {
 	return fmt.Sprintf("%s.%s_hash as %s", t.table, t.dir, tag)
 }
 
+// column returns a qualified name of the column that holds node hashes for this tag.
+func (t tagDir) column() string {
+	if t.dir == quad.Any {
+		return fmt.Sprintf("%s.%s", t.table, t.tag)
+	}
+	return fmt.Sprintf("%s.%s_hash", t.table, t.dir)
+}
+
 type tableDef struct {
 	table  string
 	name   string
@@ -87,6 +95,10 @@ type SQLIterator struct {
 
 	sql sqlIterator
 
+	// limit and offset are applied to the top-level query only
+	limit  int64
+	offset int64
+
 	result      map[string]graph.Value
 	resultIndex int
 	resultList  [][]NodeHash
@@ -96,9 +108,11 @@ type SQLIterator struct {
 
 func (it *SQLIterator) Clone() graph.Iterator {
 	m := &SQLIterator{
-		uid: iterator.NextUID(),
-		qs:  it.qs,
-		sql: it.sql.sqlClone(),
+		uid:    iterator.NextUID(),
+		qs:     it.qs,
+		sql:    it.sql.sqlClone(),
+		limit:  it.limit,
+		offset: it.offset,
 	}
 	return m
 }
@@ -163,7 +177,17 @@ func (it *SQLIterator) Sorted() bool                     { return false }
 func (it *SQLIterator) Optimize() (graph.Iterator, bool) { return it, false }
 
 func (it *SQLIterator) Size() (int64, bool) {
-	return it.sql.Size(it.qs)
+	size, exact := it.sql.Size(it.qs)
+	if it.offset > 0 {
+		size -= it.offset
+		if size < 0 {
+			size = 0
+		}
+	}
+	if it.limit > 0 && size > it.limit {
+		size = it.limit
+	}
+	return size, exact
 }
 
 func (it *SQLIterator) Describe() graph.Description {
@@ -197,7 +221,7 @@ func (it *SQLIterator) NextPath(ctx *graph.IterationContext) bool {
 
 func (it *SQLIterator) Next(ctx *graph.IterationContext) bool {
 	var err error
-	graph.NextLogIn(it)
+	graph.NextLogIn(ctx, it)
 	if it.cursor == nil {
 		err = it.makeCursor(true, nil)
 		if err != nil {
@@ -269,10 +293,10 @@ func (it *SQLIterator) Next(ctx *graph.IterationContext) bool {
 	}
 
 	if len(it.resultList) == 0 {
-		return graph.NextLogOut(it, false)
+		return graph.NextLogOut(ctx, it, false)
 	}
 	it.buildResult(0)
-	return graph.NextLogOut(it, true)
+	return graph.NextLogOut(ctx, it, true)
 }
 
 func (it *SQLIterator) Contains(ctx *graph.IterationContext, v graph.Value) bool {
@@ -354,6 +378,9 @@ func (it *SQLIterator) makeCursor(next bool, value graph.Value) error {
 	var q string
 	var values sqlArgs
 	q, values = it.sql.buildSQL(&it.qs.flavor, next, value)
+	if next && (it.limit > 0 || it.offset > 0) {
+		q = limitSQL(q, it.limit, it.offset)
+	}
 	if it.qs.flavor.Name == flavorPostgres || it.qs.flavor.Driver == flavorPostgres {
 		q = convertToPostgres(q, values)
 	}
@@ -367,6 +394,24 @@ func (it *SQLIterator) makeCursor(next bool, value graph.Value) error {
 	return nil
 }
 
+// maxLimit is used as a LIMIT value when only an OFFSET is set, since not every
+// flavor accepts OFFSET without LIMIT.
+const maxLimit = 1<<63 - 1
+
+// limitSQL adds LIMIT and OFFSET clauses to a query. Each row is returned by Next
+// as a separate result, thus rows are counted the same way as by Limit and Skip iterators.
+func limitSQL(query string, limit, offset int64) string {
+	query = strings.TrimSuffix(query, ";")
+	if limit <= 0 {
+		limit = maxLimit
+	}
+	query += fmt.Sprintf(" LIMIT %d", limit)
+	if offset > 0 {
+		query += fmt.Sprintf(" OFFSET %d", offset)
+	}
+	return query + ";"
+}
+
 func convertToPostgres(query string, values sqlArgs) string {
 	for i := 1; i <= len(values); i++ {
 		query = strings.Replace(query, "?", fmt.Sprintf("$%d", i), 1)
@@ -203,7 +203,9 @@ func (l *SQLLinkIterator) buildWhere() (string, sqlArgs) {
 	}
 	for _, i := range l.nodeIts {
 		s, v := i.it.buildWhere()
-		q = append(q, s)
+		if s != "" {
+			q = append(q, s)
+		}
 		vals = append(vals, v...)
 	}
 	query := strings.Join(q, " AND ")
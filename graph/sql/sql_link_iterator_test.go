@@ -41,14 +41,14 @@ func TestSQLLinkIteration(t *testing.T) {
 		t.Fatal(err)
 	}
 	it := NewSQLLinkIterator(qs, quad.Object, quad.Raw("Humphrey Bogart"))
-	for it.Next() {
+	for it.Next(nil) {
 		fmt.Println(it.Result())
 	}
 	it = NewSQLLinkIterator(qs, quad.Subject, quad.Raw("/en/casablanca_1942"))
 	s, v := it.sql.buildSQL(&qs.flavor, true, nil)
 	t.Log(s, v)
 	c := 0
-	for it.Next() {
+	for it.Next(nil) {
 		fmt.Println(it.Result())
 		c += 1
 	}
@@ -81,7 +81,7 @@ func TestSQLNodeIteration(t *testing.T) {
 	s, v := it.sql.buildSQL(&qs.flavor, true, nil)
 	t.Log(s, v)
 	c := 0
-	for it.Next() {
+	for it.Next(nil) {
 		t.Log(it.Result())
 		c += 1
 	}
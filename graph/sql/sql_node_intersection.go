@@ -32,6 +32,7 @@ type SQLNodeIntersection struct {
 	nodetables []string
 	size       int64
 	tagger     graph.Tagger
+	filters    []nodeFilter
 
 	result graph.Value
 }
@@ -40,10 +41,12 @@ func (n *SQLNodeIntersection) sqlClone() sqlIterator {
 	m := &SQLNodeIntersection{
 		tableName: n.tableName,
 		size:      n.size,
+		filters:   make([]nodeFilter, len(n.filters)),
 	}
 	for _, i := range n.nodeIts {
 		m.nodeIts = append(m.nodeIts, i.sqlClone())
 	}
+	copy(m.filters, n.filters)
 	m.tagger.CopyFromTagger(n.Tagger())
 	return m
 }
@@ -158,6 +161,11 @@ func (n *SQLNodeIntersection) buildWhere() (string, sqlArgs) {
 	for _, tb := range n.nodetables[1:] {
 		q = append(q, fmt.Sprintf("%s.__execd = %s.__execd", n.nodetables[0], tb))
 	}
+	if len(n.filters) != 0 {
+		s, v := filterWhere(n.tableID().column(), n.filters)
+		q = append(q, s)
+		vals = append(vals, v...)
+	}
 	query := strings.Join(q, " AND ")
 	return query, vals
 }
@@ -190,7 +198,7 @@ func (n *SQLNodeIntersection) buildSQL(fl *Flavor, next bool, val graph.Value) (
 		if constraint != "" {
 			constraint += " AND "
 		}
-		constraint += fmt.Sprintf("%s.%s_hash = ?", topData.table, topData.dir)
+		constraint += fmt.Sprintf("%s = ?", topData.column())
 		values = append(values, v.toSQL())
 	}
 	query += constraint
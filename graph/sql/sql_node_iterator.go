@@ -43,6 +43,27 @@ func newNodeTableName() string {
 	return fmt.Sprintf("n_%d", id)
 }
 
+// nodeFilter is a condition on the columns of the nodes table.
+type nodeFilter struct {
+	where  string
+	values sqlArgs
+}
+
+// filterWhere returns a constraint that only passes node hashes in a given column that match all filters.
+func filterWhere(col string, filters []nodeFilter) (string, sqlArgs) {
+	var (
+		q    []string
+		vals sqlArgs
+	)
+	for _, f := range filters {
+		q = append(q, fmt.Sprintf("%s IN (SELECT hash FROM nodes WHERE %s)", col, f.where))
+		vals = append(vals, f.values...)
+	}
+	return strings.Join(q, " AND "), vals
+}
+
+// SQLNodeIterator iterates over nodes in a given direction of quads from the link iterator.
+// If there is no link iterator, it iterates over all nodes.
 type SQLNodeIterator struct {
 	tableName string
 
@@ -50,6 +71,7 @@ type SQLNodeIterator struct {
 	size     int64
 	tagger   graph.Tagger
 	fixedSet []quad.Value
+	filters  []nodeFilter
 
 	result graph.Value
 }
@@ -60,12 +82,16 @@ func (n *SQLNodeIterator) sqlClone() sqlIterator {
 		size:      n.size,
 		linkIt: sqlItDir{
 			dir: n.linkIt.dir,
-			it:  n.linkIt.it.sqlClone(),
 		},
 		fixedSet: make([]quad.Value, len(n.fixedSet)),
+		filters:  make([]nodeFilter, len(n.filters)),
+	}
+	if n.linkIt.it != nil {
+		m.linkIt.it = n.linkIt.it.sqlClone()
 	}
 	m.tagger.CopyFromTagger(n.Tagger())
 	copy(m.fixedSet, n.fixedSet)
+	copy(m.filters, n.filters)
 	return m
 }
 
@@ -110,7 +136,7 @@ func (n *SQLNodeIterator) getTables(fl *Flavor) []tableDef {
 		out = n.linkIt.it.getTables(fl)
 	}
 	if len(out) == 0 {
-		out = append(out, tableDef{table: "quads", name: n.tableName})
+		out = append(out, tableDef{table: "(SELECT hash AS __execd FROM nodes)", name: n.tableName})
 	}
 	return out
 }
@@ -157,17 +183,24 @@ func (n *SQLNodeIterator) buildWhere() (string, sqlArgs) {
 	var vals sqlArgs
 	if n.linkIt.it != nil {
 		s, v := n.linkIt.it.buildWhere()
-		q = append(q, s)
+		if s != "" {
+			q = append(q, s)
+		}
 		vals = append(vals, v...)
 	}
+	topData := n.tableID()
 	if len(n.fixedSet) != 0 {
-		topData := n.tableID()
 		var valueChain []string
 		for _, v := range n.fixedSet {
 			vals = append(vals, hashOf(v).toSQL())
 			valueChain = append(valueChain, "?")
 		}
-		q = append(q, fmt.Sprintf("%s.%s_hash IN (%s)", topData.table, topData.dir, strings.Join(valueChain, ", ")))
+		q = append(q, fmt.Sprintf("%s IN (%s)", topData.column(), strings.Join(valueChain, ", ")))
+	}
+	if len(n.filters) != 0 {
+		s, v := filterWhere(topData.column(), n.filters)
+		q = append(q, s)
+		vals = append(vals, v...)
 	}
 	query := strings.Join(q, " AND ")
 	return query, vals
@@ -201,7 +234,7 @@ func (n *SQLNodeIterator) buildSQL(fl *Flavor, next bool, val graph.Value) (stri
 		if constraint != "" {
 			constraint += " AND "
 		}
-		constraint += fmt.Sprintf("%s.%s_hash = ?", topData.table, topData.dir)
+		constraint += fmt.Sprintf("%s = ?", topData.column())
 		values = append(values, v.toSQL())
 	}
 
@@ -0,0 +1,241 @@
+// +build cgo
+
+package sql
+
+import (
+	"database/sql"
+	"regexp"
+	"strings"
+
+	"github.com/codelingo/cayley/clog"
+	"github.com/codelingo/cayley/graph"
+	"github.com/codelingo/cayley/internal/lru"
+	"github.com/codelingo/cayley/quad"
+	"github.com/mattn/go-sqlite3"
+)
+
+const (
+	flavorSqlite = "sqlite"
+	// driverSqlite is the SQLite driver with a REGEXP function, which is not built in.
+	driverSqlite = "sqlite3_cayley"
+)
+
+func init() {
+	sql.Register(driverSqlite, &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			return conn.RegisterFunc("regexp", sqliteRegexp, true)
+		},
+	})
+	RegisterFlavor(Flavor{
+		Name:   flavorSqlite,
+		Driver: driverSqlite,
+		NodesTable: `CREATE TABLE nodes (
+	hash BLOB PRIMARY KEY,
+	value BLOB,
+	value_string TEXT,
+	datatype TEXT,
+	language TEXT,
+	iri BOOLEAN,
+	bnode BOOLEAN,
+	value_int INTEGER,
+	value_bool BOOLEAN,
+	value_float REAL,
+	value_time DATETIME
+);`,
+		// SQLite cannot add constraints to an existing table, thus foreign keys are declared here.
+		QuadsTable: `CREATE TABLE quads (
+	horizon INTEGER PRIMARY KEY AUTOINCREMENT,
+	subject_hash BLOB NOT NULL REFERENCES nodes (hash),
+	predicate_hash BLOB NOT NULL REFERENCES nodes (hash),
+	object_hash BLOB NOT NULL REFERENCES nodes (hash),
+	label_hash BLOB REFERENCES nodes (hash),
+	id INTEGER,
+	ts DATETIME
+);`,
+		FieldQuote:  '"',
+		Placeholder: func(n int) string { return "?" },
+		Indexes: func(options graph.Options) []string {
+			return []string{
+				`CREATE UNIQUE INDEX spol_unique ON quads (subject_hash, predicate_hash, object_hash, label_hash) WHERE label_hash IS NOT NULL;`,
+				`CREATE UNIQUE INDEX spo_unique ON quads (subject_hash, predicate_hash, object_hash) WHERE label_hash IS NULL;`,
+				`CREATE INDEX spo_index ON quads (subject_hash);`,
+				`CREATE INDEX pos_index ON quads (predicate_hash);`,
+				`CREATE INDEX osp_index ON quads (object_hash);`,
+			}
+		},
+		Error: func(err error) error {
+			e, ok := err.(sqlite3.Error)
+			if !ok {
+				return err
+			}
+			if e.Code == sqlite3.ErrError && strings.Contains(e.Error(), "already exists") {
+				return graph.ErrDatabaseExists
+			}
+			return err
+		},
+		Estimated: nil,
+		RunTx:     runTxSqlite,
+		RegexpOp:  "REGEXP",
+	})
+}
+
+// sqliteRegexps caches compiled expressions, since the REGEXP function is called for each row.
+var sqliteRegexps = lru.New(64)
+
+// sqliteRegexp implements "value REGEXP expr" with Go regular expressions.
+func sqliteRegexp(expr string, v interface{}) (bool, error) {
+	var s string
+	switch v := v.(type) {
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	default:
+		return false, nil
+	}
+	if re, ok := sqliteRegexps.Get(expr); ok {
+		return re.(*regexp.Regexp).MatchString(s), nil
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return false, err
+	}
+	sqliteRegexps.Put(expr, re)
+	return re.MatchString(s), nil
+}
+
+func runTxSqlite(tx *sql.Tx, in []graph.Delta, opts graph.IgnoreOpts) error {
+	insert := "INSERT"
+	if opts.IgnoreDup {
+		insert = "INSERT OR IGNORE"
+	}
+
+	var (
+		insertQuad  *sql.Stmt
+		insertValue map[int]*sql.Stmt     // prepared statements for each value type
+		inserted    map[NodeHash]struct{} // tracks already inserted values
+
+		deleteQuad   *sql.Stmt
+		deleteTriple *sql.Stmt
+	)
+
+	var err error
+	for i := range in {
+		d := &in[i]
+		switch d.Action {
+		case graph.Add:
+			if insertQuad == nil {
+				insertQuad, err = tx.Prepare(insert + ` INTO quads(subject_hash, predicate_hash, object_hash, label_hash, id, ts) VALUES (?, ?, ?, ?, ?, ?);`)
+				if err != nil {
+					return err
+				}
+				insertValue = make(map[int]*sql.Stmt)
+				inserted = make(map[NodeHash]struct{}, len(in))
+			}
+			var hs, hp, ho, hl NodeHash
+			for _, dir := range quad.Directions {
+				v := d.Quad.Get(dir)
+				if v == nil {
+					continue
+				}
+				h := hashOf(v)
+				switch dir {
+				case quad.Subject:
+					hs = h
+				case quad.Predicate:
+					hp = h
+				case quad.Object:
+					ho = h
+				case quad.Label:
+					hl = h
+				}
+				if !h.Valid() {
+					continue
+				} else if _, ok := inserted[h]; ok {
+					continue
+				}
+				nodeKey, values, err := nodeValues(h, v)
+				if err != nil {
+					return err
+				}
+				stmt, ok := insertValue[nodeKey]
+				if !ok {
+					var ph = make([]string, len(values)-1)
+					for i := range ph {
+						ph[i] = "?"
+					}
+					stmt, err = tx.Prepare(`INSERT OR IGNORE INTO nodes(hash, ` +
+						strings.Join(nodeInsertColumns[nodeKey], ", ") +
+						`) VALUES (?, ` +
+						strings.Join(ph, ", ") +
+						`);`)
+					if err != nil {
+						return err
+					}
+					insertValue[nodeKey] = stmt
+				}
+				_, err = stmt.Exec(values...)
+				err = convInsertErrorSqlite(err)
+				if err != nil {
+					clog.Errorf("couldn't exec INSERT statement: %v", err)
+					return err
+				}
+				inserted[h] = struct{}{}
+			}
+			_, err := insertQuad.Exec(
+				hs.toSQL(), hp.toSQL(), ho.toSQL(), hl.toSQL(),
+				d.ID.Int(),
+				d.Timestamp,
+			)
+			err = convInsertErrorSqlite(err)
+			if err != nil {
+				clog.Errorf("couldn't exec INSERT statement: %v", err)
+				return err
+			}
+		case graph.Delete:
+			if deleteQuad == nil {
+				deleteQuad, err = tx.Prepare(`DELETE FROM quads WHERE subject_hash=? and predicate_hash=? and object_hash=? and label_hash=?;`)
+				if err != nil {
+					return err
+				}
+				deleteTriple, err = tx.Prepare(`DELETE FROM quads WHERE subject_hash=? and predicate_hash=? and object_hash=? and label_hash is null;`)
+				if err != nil {
+					return err
+				}
+			}
+			var result sql.Result
+			if d.Quad.Label == nil {
+				result, err = deleteTriple.Exec(hashOf(d.Quad.Subject).toSQL(), hashOf(d.Quad.Predicate).toSQL(), hashOf(d.Quad.Object).toSQL())
+			} else {
+				result, err = deleteQuad.Exec(hashOf(d.Quad.Subject).toSQL(), hashOf(d.Quad.Predicate).toSQL(), hashOf(d.Quad.Object).toSQL(), hashOf(d.Quad.Label).toSQL())
+			}
+			if err != nil {
+				clog.Errorf("couldn't exec DELETE statement: %v", err)
+				return err
+			}
+			affected, err := result.RowsAffected()
+			if err != nil {
+				clog.Errorf("couldn't get DELETE RowsAffected: %v", err)
+				return err
+			}
+			if affected != 1 && !opts.IgnoreMissing {
+				return graph.ErrQuadNotExist
+			}
+		default:
+			panic("unknown action")
+		}
+	}
+	return nil
+}
+
+func convInsertErrorSqlite(err error) error {
+	if err == nil {
+		return err
+	}
+	if e, ok := err.(sqlite3.Error); ok {
+		if e.ExtendedCode == sqlite3.ErrConstraintUnique {
+			return graph.ErrQuadExists
+		}
+	}
+	return err
+}
@@ -0,0 +1,148 @@
+// +build cgo
+
+package sql
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/codelingo/cayley/graph"
+	"github.com/codelingo/cayley/graph/graphtest"
+	"github.com/codelingo/cayley/graph/iterator"
+	"github.com/codelingo/cayley/graph/path/pathtest"
+	"github.com/codelingo/cayley/quad"
+)
+
+func makeSqlite(t testing.TB) (graph.QuadStore, graph.Options, func()) {
+	tmpDir, err := ioutil.TempDir(os.TempDir(), "cayley_test")
+	if err != nil {
+		t.Fatalf("Could not create working directory: %v", err)
+	}
+	opts := graph.Options{"flavor": flavorSqlite}
+	addr := filepath.Join(tmpDir, "cayley.db")
+	if err := createSQLTables(addr, opts); err != nil {
+		os.RemoveAll(tmpDir)
+		t.Fatal(err)
+	}
+	qs, err := newQuadStore(addr, opts)
+	if err != nil {
+		os.RemoveAll(tmpDir)
+		t.Fatal(err)
+	}
+	return qs, nil, func() {
+		qs.Close()
+		os.RemoveAll(tmpDir)
+	}
+}
+
+func TestSqliteAll(t *testing.T) {
+	graphtest.TestAll(t, makeSqlite, &graphtest.Config{
+		OptimizesComparison:     true,
+		OptimizesHasAToUnique:   true,
+		SkipNodeDelAfterQuadDel: true,
+	})
+}
+
+func TestSqlitePaths(t *testing.T) {
+	pathtest.RunTestMorphisms(t, makeSqlite)
+}
+
+func TestSqliteCreateExisting(t *testing.T) {
+	tmpDir, err := ioutil.TempDir(os.TempDir(), "cayley_test")
+	if err != nil {
+		t.Fatalf("Could not create working directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	opts := graph.Options{"flavor": flavorSqlite}
+	addr := filepath.Join(tmpDir, "cayley.db")
+	if err := createSQLTables(addr, opts); err != nil {
+		t.Fatal(err)
+	}
+	if err := createSQLTables(addr, opts); err != graph.ErrDatabaseExists {
+		t.Errorf("Expected %v, got: %v", graph.ErrDatabaseExists, err)
+	}
+}
+
+func TestSqliteOptimize(t *testing.T) {
+	qs, opts, closer := makeSqlite(t)
+	defer closer()
+
+	graphtest.MakeWriter(t, qs, opts, graphtest.MakeQuadSet()...)
+
+	// subjects that follow "B"
+	lto := iterator.NewLinksTo(qs, iterator.NewFixed(iterator.Identity, qs.ValueOf(quad.Raw("B"))), quad.Object)
+	and := iterator.NewAnd(qs, lto, iterator.NewLinksTo(qs, iterator.NewFixed(iterator.Identity, qs.ValueOf(quad.Raw("follows"))), quad.Predicate))
+	hasa := iterator.NewHasA(qs, and, quad.Subject)
+
+	oldIt := hasa.Clone()
+	newIt, ok := hasa.Optimize()
+	if !ok {
+		t.Fatal("Failed to optimize iterator")
+	}
+	if _, ok := newIt.(*SQLIterator); !ok {
+		t.Errorf("Expected an SQL iterator, got: %T", newIt)
+	}
+
+	expect := []string{"A", "C", "D"}
+	graphtest.ExpectIteratedRawStrings(t, qs, oldIt, expect)
+	graphtest.ExpectIteratedRawStrings(t, qs, newIt, expect)
+}
+
+func TestSqliteValueFilters(t *testing.T) {
+	qs, opts, closer := makeSqlite(t)
+	defer closer()
+
+	quads := graphtest.MakeQuadSet()
+	for _, q := range []quad.Quad{
+		{quad.IRI("alice"), quad.IRI("age"), quad.Int(30), nil},
+		{quad.IRI("bob"), quad.IRI("age"), quad.Int(17), nil},
+		{quad.IRI("charlie"), quad.IRI("age"), quad.Int(21), nil},
+		{quad.IRI("alice"), quad.IRI("name"), quad.String("alice"), nil},
+		{quad.IRI("charlie"), quad.IRI("name"), quad.String("charlie"), nil},
+	} {
+		quads = append(quads, q)
+	}
+	graphtest.MakeWriter(t, qs, opts, quads...)
+
+	ages := func() graph.Iterator {
+		fixed := iterator.NewFixed(iterator.Identity, qs.ValueOf(quad.IRI("age")))
+		return iterator.NewHasA(qs, iterator.NewLinksTo(qs, fixed, quad.Predicate), quad.Object)
+	}
+	optimize := func(it graph.Iterator) graph.Iterator {
+		it, _ = it.Optimize()
+		it, _ = qs.OptimizeIterator(it)
+		return it
+	}
+
+	it := optimize(iterator.NewComparison(ages(), iterator.CompareGTE, quad.Int(21), qs))
+	if _, ok := it.(*SQLIterator); !ok {
+		t.Errorf("Expected an SQL iterator, got: %T", it)
+	}
+	graphtest.ExpectIteratedValues(t, qs, it, []quad.Value{quad.Int(21), quad.Int(30)})
+
+	// raw values are not stored as strings, thus they are checked outside of the database
+	it = optimize(iterator.NewRegex(qs.NodesAllIterator(), regexp.MustCompile(`^c`), qs))
+	graphtest.ExpectIteratedValues(t, qs, it, []quad.Value{quad.String("charlie"), quad.Raw("cool")})
+
+	it = optimize(iterator.NewLimit(iterator.NewSkip(ages(), 1), 1))
+	if _, ok := it.(*SQLIterator); !ok {
+		t.Errorf("Expected an SQL iterator, got: %T", it)
+	}
+	if n := len(graphtest.IteratedValues(t, qs, it)); n != 1 {
+		t.Errorf("Unexpected number of results, got:%d expect:1", n)
+	}
+
+	it = optimize(iterator.NewSkip(iterator.NewLimit(ages(), 2), 1))
+	if n := len(graphtest.IteratedValues(t, qs, it)); n != 1 {
+		t.Errorf("Unexpected number of results, got:%d expect:1", n)
+	}
+
+	it = optimize(iterator.NewSkip(iterator.NewLimit(ages(), 2), 2))
+	if n := len(graphtest.IteratedValues(t, qs, it)); n != 0 {
+		t.Errorf("Unexpected number of results, got:%d expect:0", n)
+	}
+}
@@ -0,0 +1,211 @@
+// Copyright 2017 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package valueindex
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/codelingo/cayley/graph"
+	"github.com/codelingo/cayley/graph/iterator"
+	"github.com/codelingo/cayley/quad"
+)
+
+var rangeType graph.Type
+
+func init() {
+	rangeType = graph.RegisterIterator("range")
+}
+
+// Type returns the type of the range iterator.
+func Type() graph.Type { return rangeType }
+
+// ScanFunc reads up to n entries of an index with keys in a range [from, to).
+// It returns keys of entries in order, and the nodes they point to.
+type ScanFunc func(from, to []byte, n int) ([][]byte, []graph.Value, error)
+
+// bufferSize is the number of index entries read by a single scan.
+var bufferSize = 100
+
+// Iterator iterates over nodes with values in a given range, using an ordered value index.
+//
+// Index keys may be prefixed, for example by a predicate; in this case the iterator only
+// returns nodes from the part of the index with the prefix. Contains checks only the value
+// of the node, thus the iterator must be intersected with the set that the prefix stands for.
+type Iterator struct {
+	uid    uint64
+	tags   graph.Tagger
+	qs     graph.QuadStore
+	op     iterator.Operator
+	val    quad.Value
+	prefix []byte
+	scan   ScanFunc
+
+	lo, hi []byte // value range, without the prefix
+	from   []byte // next key to read
+	keys   [][]byte
+	buf    []graph.Value
+	off    int
+	done   bool
+	result graph.Value
+	err    error
+}
+
+// NewIterator creates an iterator of nodes with values that pass a comparison with a given value.
+// It panics if the value cannot be indexed (see Key).
+func NewIterator(qs graph.QuadStore, op iterator.Operator, val quad.Value, prefix []byte, scan ScanFunc) *Iterator {
+	lo, hi, ok := Range(op, val)
+	if !ok {
+		panic(fmt.Errorf("value cannot be indexed: %#v", val))
+	}
+	it := &Iterator{
+		uid:    iterator.NextUID(),
+		qs:     qs,
+		op:     op,
+		val:    val,
+		prefix: prefix,
+		scan:   scan,
+		lo:     lo,
+		hi:     hi,
+	}
+	it.Reset()
+	return it
+}
+
+func (it *Iterator) withPrefix(k []byte) []byte {
+	out := make([]byte, 0, len(it.prefix)+len(k))
+	out = append(out, it.prefix...)
+	return append(out, k...)
+}
+
+func (it *Iterator) UID() uint64 {
+	return it.uid
+}
+
+func (it *Iterator) Reset() {
+	it.from = it.withPrefix(it.lo)
+	it.keys, it.buf, it.off = nil, nil, 0
+	it.done = false
+	it.result = nil
+}
+
+func (it *Iterator) Close() error {
+	it.keys, it.buf = nil, nil
+	it.done = true
+	return nil
+}
+
+func (it *Iterator) Tagger() *graph.Tagger {
+	return &it.tags
+}
+
+func (it *Iterator) TagResults(dst map[string]graph.Value) {
+	for _, tag := range it.tags.Tags() {
+		dst[tag] = it.Result()
+	}
+
+	for tag, value := range it.tags.Fixed() {
+		dst[tag] = value
+	}
+}
+
+func (it *Iterator) Clone() graph.Iterator {
+	out := NewIterator(it.qs, it.op, it.val, it.prefix, it.scan)
+	out.tags.CopyFrom(it)
+	return out
+}
+
+func (it *Iterator) Next(ctx *graph.IterationContext) bool {
+	graph.NextLogIn(ctx, it)
+	if it.off >= len(it.buf) {
+		if it.done {
+			return graph.NextLogOut(ctx, it, false)
+		}
+		it.keys, it.buf, it.err = it.scan(it.from, it.withPrefix(it.hi), bufferSize)
+		it.off = 0
+		if it.err != nil || len(it.buf) == 0 {
+			it.done = true
+			return graph.NextLogOut(ctx, it, false)
+		}
+		if len(it.buf) < bufferSize {
+			it.done = true
+		} else {
+			// continue right after the last key
+			last := it.keys[len(it.keys)-1]
+			it.from = append(append([]byte{}, last...), 0)
+		}
+	}
+	it.result = it.buf[it.off]
+	it.off++
+	return graph.NextLogOut(ctx, it, true)
+}
+
+func (it *Iterator) Contains(ctx *graph.IterationContext, v graph.Value) bool {
+	graph.ContainsLogIn(ctx, it, v)
+	k, ok := Key(it.qs.NameOf(v))
+	if !ok || bytes.Compare(k, it.lo) < 0 || bytes.Compare(k, it.hi) >= 0 {
+		return graph.ContainsLogOut(ctx, it, v, false)
+	}
+	it.result = v
+	return graph.ContainsLogOut(ctx, it, v, true)
+}
+
+func (it *Iterator) Err() error {
+	return it.err
+}
+
+func (it *Iterator) Result() graph.Value {
+	return it.result
+}
+
+func (it *Iterator) NextPath(ctx *graph.IterationContext) bool {
+	return false
+}
+
+func (it *Iterator) SubIterators() []graph.Iterator {
+	return nil
+}
+
+func (it *Iterator) Describe() graph.Description {
+	return graph.Description{
+		UID:  it.UID(),
+		Name: fmt.Sprint(it.val),
+		Type: it.Type(),
+		Tags: it.tags.Tags(),
+	}
+}
+
+func (it *Iterator) Type() graph.Type { return rangeType }
+
+func (it *Iterator) Optimize() (graph.Iterator, bool) {
+	return it, false
+}
+
+// Size returns the size of the quad store as an estimate, since ranges are not counted.
+func (it *Iterator) Size() (int64, bool) {
+	return it.qs.Size(), false
+}
+
+func (it *Iterator) Stats() graph.IteratorStats {
+	s, exact := it.Size()
+	return graph.IteratorStats{
+		ContainsCost: 1,
+		NextCost:     1,
+		Size:         s,
+		ExactSize:    exact,
+	}
+}
+
+var _ graph.Iterator = &Iterator{}
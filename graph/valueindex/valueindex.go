@@ -0,0 +1,171 @@
+// Copyright 2017 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package valueindex implements helpers for ordered secondary indexes of typed values.
+//
+// Values of type quad.Int, quad.Float and quad.Time are encoded into keys that sort
+// byte-wise in the same order as the values. Quad stores keep these keys, optionally
+// prefixed by a predicate, and answer Comparison iterators with range scans.
+//
+// The package also provides a Wrapper that keeps external value indexes, such as full-text
+// or spatial ones, in sync with any quad store.
+package valueindex
+
+import (
+	"encoding/binary"
+	"math"
+	"time"
+
+	"github.com/codelingo/cayley/graph"
+	"github.com/codelingo/cayley/graph/iterator"
+	"github.com/codelingo/cayley/quad"
+)
+
+// Options of value indexes, as passed in quad store options.
+const (
+	// OptIndex enables an index of all typed values.
+	OptIndex = "value_index"
+	// OptIndexByPredicate enables an index of typed values in the object direction, scoped by predicate.
+	OptIndexByPredicate = "value_index_by_predicate"
+)
+
+// Options describes which value indexes a quad store should keep.
+type Options struct {
+	Global      bool
+	ByPredicate bool
+}
+
+// Enabled checks if any index is enabled.
+func (o Options) Enabled() bool {
+	return o.Global || o.ByPredicate
+}
+
+// ParseOptions reads index options from quad store options.
+func ParseOptions(opts graph.Options) (Options, error) {
+	var (
+		o   Options
+		err error
+	)
+	if o.Global, _, err = opts.BoolKey(OptIndex); err != nil {
+		return o, err
+	}
+	if o.ByPredicate, _, err = opts.BoolKey(OptIndexByPredicate); err != nil {
+		return o, err
+	}
+	return o, nil
+}
+
+// Type tags of keys. Values of different types are never compared to each other.
+const (
+	tagInt   = 'i'
+	tagFloat = 'f'
+	tagTime  = 't'
+)
+
+const signBit = 1 << 63
+
+// Key encodes a value into a key that preserves the order of values of the same type.
+// It returns false if the value cannot be indexed.
+func Key(v quad.Value) ([]byte, bool) {
+	switch v := v.(type) {
+	case quad.Int:
+		b := make([]byte, 9)
+		b[0] = tagInt
+		binary.BigEndian.PutUint64(b[1:], uint64(v)^signBit)
+		return b, true
+	case quad.Float:
+		f := float64(v)
+		if math.IsNaN(f) {
+			return nil, false
+		} else if f == 0 {
+			f = 0 // negative zero equals to zero
+		}
+		bits := math.Float64bits(f)
+		if bits&signBit != 0 {
+			bits = ^bits
+		} else {
+			bits |= signBit
+		}
+		b := make([]byte, 9)
+		b[0] = tagFloat
+		binary.BigEndian.PutUint64(b[1:], bits)
+		return b, true
+	case quad.Time:
+		t := time.Time(v)
+		b := make([]byte, 13)
+		b[0] = tagTime
+		binary.BigEndian.PutUint64(b[1:], uint64(t.Unix())^signBit)
+		binary.BigEndian.PutUint32(b[9:], uint32(t.Nanosecond()))
+		return b, true
+	}
+	return nil, false
+}
+
+// successor returns the smallest key that is greater than any key starting with a given prefix.
+func successor(p []byte) []byte {
+	out := make([]byte, len(p))
+	copy(out, p)
+	for i := len(out) - 1; i >= 0; i-- {
+		if out[i] != 0xff {
+			out[i]++
+			return out[:i+1]
+		}
+	}
+	return nil
+}
+
+// Range returns a range of keys [lo, hi) of values that pass the comparison with a given value.
+// Keys in the range may have any suffix, thus they can be followed by a node identifier.
+// It returns false if the value cannot be indexed.
+func Range(op iterator.Operator, v quad.Value) (lo, hi []byte, ok bool) {
+	k, ok := Key(v)
+	if !ok {
+		return nil, nil, false
+	}
+	tag := k[:1]
+	switch op {
+	case iterator.CompareLT:
+		return tag, k, true
+	case iterator.CompareLTE:
+		return tag, successor(k), true
+	case iterator.CompareGT:
+		return successor(k), successor(tag), true
+	case iterator.CompareGTE:
+		return k, successor(tag), true
+	}
+	return nil, nil, false
+}
+
+// Indexable checks if a comparison can be answered by a value index.
+func Indexable(it *iterator.Comparison) bool {
+	_, _, ok := Range(it.Operator(), it.Value())
+	return ok && len(it.SubIterators()) == 1
+}
+
+// Intersect builds a replacement for the comparison from a range iterator.
+// If all is set, the sub-iterator of the comparison iterates all nodes and is dropped.
+//
+// The range iterator is the primary iterator of the result, thus nodes are checked
+// against the sub-iterator only after they are found in the index.
+func Intersect(qs graph.QuadStore, it *iterator.Comparison, rit *Iterator, all bool) graph.Iterator {
+	sub := it.SubIterators()[0]
+	if all {
+		rit.Tagger().CopyFrom(it)
+		rit.Tagger().CopyFrom(sub)
+		return rit
+	}
+	and := iterator.NewAnd(qs, rit, sub.Clone())
+	and.Tagger().CopyFrom(it)
+	return and
+}
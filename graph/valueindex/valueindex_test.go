@@ -0,0 +1,110 @@
+// Copyright 2017 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package valueindex
+
+import (
+	"bytes"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/codelingo/cayley/graph/iterator"
+	"github.com/codelingo/cayley/quad"
+)
+
+var t0 = time.Unix(1500000000, 0)
+
+// ordered lists values of the same type in ascending order.
+var ordered = [][]quad.Value{
+	{quad.Int(math.MinInt64), quad.Int(-100), quad.Int(-1), quad.Int(0), quad.Int(1), quad.Int(100), quad.Int(math.MaxInt64)},
+	{
+		quad.Float(math.Inf(-1)), quad.Float(-1e10), quad.Float(-1.5), quad.Float(-1e-10),
+		quad.Float(0), quad.Float(1e-10), quad.Float(1.5), quad.Float(1e10), quad.Float(math.Inf(1)),
+	},
+	{
+		quad.Time(time.Unix(-1000, 0)), quad.Time(time.Unix(0, 0)), quad.Time(t0),
+		quad.Time(t0.Add(time.Nanosecond)), quad.Time(t0.Add(time.Second)), quad.Time(t0.Add(24 * time.Hour)),
+	},
+}
+
+func TestKeyOrder(t *testing.T) {
+	for _, vals := range ordered {
+		for i := 1; i < len(vals); i++ {
+			a, ok1 := Key(vals[i-1])
+			b, ok2 := Key(vals[i])
+			if !ok1 || !ok2 {
+				t.Fatalf("values are not indexable: %v, %v", vals[i-1], vals[i])
+			}
+			if bytes.Compare(a, b) >= 0 {
+				t.Errorf("wrong order of keys for %v and %v: %x vs %x", vals[i-1], vals[i], a, b)
+			}
+		}
+	}
+}
+
+func TestKeySpecial(t *testing.T) {
+	for _, v := range []quad.Value{
+		quad.String("1"), quad.IRI("a"), quad.Bool(true), quad.Float(math.NaN()), nil,
+	} {
+		if _, ok := Key(v); ok {
+			t.Errorf("value should not be indexable: %#v", v)
+		}
+	}
+	pz, _ := Key(quad.Float(0))
+	nz, _ := Key(quad.Float(math.Copysign(0, -1)))
+	if !bytes.Equal(pz, nz) {
+		t.Errorf("negative zero should have the same key as zero: %x vs %x", nz, pz)
+	}
+}
+
+func TestRange(t *testing.T) {
+	const lt, lte, gt, gte = iterator.CompareLT, iterator.CompareLTE, iterator.CompareGT, iterator.CompareGTE
+	in := func(lo, hi []byte, v quad.Value) bool {
+		k, _ := Key(v)
+		// keys in the index are followed by node identifiers
+		k = append(k, 0xff, 0x00)
+		return bytes.Compare(k, lo) >= 0 && bytes.Compare(k, hi) < 0
+	}
+	var cases = []struct {
+		op     iterator.Operator
+		val    quad.Value
+		expect []bool
+	}{
+		{lt, quad.Int(0), []bool{true, true, true, false, false, false, false}},
+		{lte, quad.Int(0), []bool{true, true, true, true, false, false, false}},
+		{gt, quad.Int(0), []bool{false, false, false, false, true, true, true}},
+		{gte, quad.Int(0), []bool{false, false, false, true, true, true, true}},
+		{gte, quad.Int(math.MaxInt64), []bool{false, false, false, false, false, false, true}},
+	}
+	for _, c := range cases {
+		lo, hi, ok := Range(c.op, c.val)
+		if !ok {
+			t.Fatalf("no range for %v %v", c.op, c.val)
+		}
+		for i, v := range ordered[0] {
+			if got := in(lo, hi, v); got != c.expect[i] {
+				t.Errorf("%v %v: unexpected result for %v: %v", c.op, c.val, v, got)
+			}
+		}
+		// values of other types are never in range
+		for _, vals := range ordered[1:] {
+			for _, v := range vals {
+				if in(lo, hi, v) {
+					t.Errorf("%v %v: unexpected value in range: %v", c.op, c.val, v)
+				}
+			}
+		}
+	}
+}
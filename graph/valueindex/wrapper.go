@@ -0,0 +1,147 @@
+// Copyright 2017 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package valueindex
+
+import (
+	"time"
+
+	"github.com/codelingo/cayley/graph"
+	"github.com/codelingo/cayley/quad"
+)
+
+// SyncedIndex is an external index of values that is updated on each write to a quad store.
+type SyncedIndex interface {
+	// Add indexes given values. Values that are already in the index are ignored.
+	Add(vals []quad.Value) error
+	// Remove removes given values from the index.
+	Remove(vals []quad.Value) error
+	// Close releases resources held by the index.
+	Close() error
+}
+
+// Wrapper wraps any quad store and keeps an external index of some of its values in sync with it.
+//
+// It is embedded by quad stores that answer queries with such indexes, for example full-text or spatial ones.
+type Wrapper struct {
+	graph.QuadStore
+	idx     SyncedIndex
+	indexed func(v quad.Value) bool
+	view    bool // read-only view of the store that shares the index; see View
+}
+
+// NewWrapper wraps a quad store with an index of all values accepted by indexed.
+// The index must already contain all such values of the store.
+func NewWrapper(qs graph.QuadStore, idx SyncedIndex, indexed func(v quad.Value) bool) Wrapper {
+	return Wrapper{QuadStore: qs, idx: idx, indexed: indexed}
+}
+
+// Unwrap returns an underlying quad store.
+func (w *Wrapper) Unwrap() graph.QuadStore {
+	return w.QuadStore
+}
+
+// ApplyDeltas applies deltas to the underlying store and updates the index.
+//
+// Deleted values are removed from the index only if no quads in the store refer to them anymore.
+func (w *Wrapper) ApplyDeltas(deltas []graph.Delta, opts graph.IgnoreOpts) error {
+	if err := w.QuadStore.ApplyDeltas(deltas, opts); err != nil {
+		return err
+	}
+	var added, deleted []quad.Value
+	for i := range deltas {
+		d := &deltas[i]
+		for _, dir := range quad.Directions {
+			v := d.Quad.Get(dir)
+			if v == nil || !w.indexed(v) {
+				continue
+			}
+			if d.Action == graph.Add {
+				added = append(added, v)
+			} else {
+				deleted = append(deleted, v)
+			}
+		}
+	}
+	if len(added) != 0 {
+		if err := w.idx.Add(added); err != nil {
+			return err
+		}
+	}
+	var unused []quad.Value
+	for _, v := range deleted {
+		if !w.Used(v) {
+			unused = append(unused, v)
+		}
+	}
+	if len(unused) != 0 {
+		return w.idx.Remove(unused)
+	}
+	return nil
+}
+
+// Used checks if any quad in the underlying store refers to a value.
+func (w *Wrapper) Used(v quad.Value) bool {
+	gv := w.QuadStore.ValueOf(v)
+	if gv == nil {
+		return false
+	}
+	for _, d := range quad.Directions {
+		it := w.QuadStore.QuadIterator(d, gv)
+		ok := it.Next(nil)
+		it.Close()
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+// View returns a wrapper of a read-only view of the underlying store at a given horizon (see graph.AsOf).
+//
+// The view shares the index, which is not versioned: it may contain values that were added after the horizon,
+// and may miss values that were removed since. Use Visible to check values found in the index.
+func (w *Wrapper) View(horizon int64) (Wrapper, error) {
+	sub, err := graph.AsOf(w.QuadStore, horizon)
+	if err != nil {
+		return Wrapper{}, err
+	}
+	return Wrapper{QuadStore: sub, idx: w.idx, indexed: w.indexed, view: true}, nil
+}
+
+// HorizonAt returns the horizon of the underlying store at a given time (see graph.HistoryStore).
+func (w *Wrapper) HorizonAt(t time.Time) (int64, error) {
+	hs, ok := w.QuadStore.(graph.HistoryStore)
+	if !ok {
+		return 0, graph.ErrOperationNotSupported
+	}
+	return hs.HorizonAt(t)
+}
+
+// Visible checks if a value found in the index is in the store. It is always true, unless the wrapper is a view.
+func (w *Wrapper) Visible(v quad.Value) bool {
+	return !w.view || w.Used(v)
+}
+
+// Close closes the underlying store and the index. Views do not close the shared index.
+func (w *Wrapper) Close() error {
+	err := w.QuadStore.Close()
+	if w.view {
+		return err
+	}
+	if err2 := w.idx.Close(); err == nil {
+		err = err2
+	}
+	return err
+}
@@ -35,6 +35,14 @@ type Config struct {
 	Timeout                    time.Duration
 	LoadSize                   int
 	RequiresHTTPRequestContext bool
+	Reasoning                  bool
+	TextIndex                  string
+	GeoIndex                   string
+	Rules                      []string
+	RulesLabel                 string
+	Admin                      bool
+	Databases                  map[string]*Config
+	DatabasesRoot              string
 }
 
 type config struct {
@@ -49,6 +57,14 @@ type config struct {
 	Timeout                    duration               `json:"timeout"`
 	LoadSize                   int                    `json:"load_size"`
 	RequiresHTTPRequestContext bool                   `json:"http_request_context"`
+	Reasoning                  bool                   `json:"reasoning"`
+	TextIndex                  string                 `json:"text_index"`
+	GeoIndex                   string                 `json:"geo_index"`
+	Rules                      []string               `json:"rules"`
+	RulesLabel                 string                 `json:"rules_label"`
+	Admin                      bool                   `json:"admin"`
+	Databases                  map[string]*Config     `json:"databases"`
+	DatabasesRoot              string                 `json:"databases_root"`
 }
 
 func (c *Config) UnmarshalJSON(data []byte) error {
@@ -69,6 +85,14 @@ func (c *Config) UnmarshalJSON(data []byte) error {
 		Timeout:                    time.Duration(t.Timeout),
 		LoadSize:                   t.LoadSize,
 		RequiresHTTPRequestContext: t.RequiresHTTPRequestContext,
+		Reasoning:                  t.Reasoning,
+		TextIndex:                  t.TextIndex,
+		GeoIndex:                   t.GeoIndex,
+		Rules:                      t.Rules,
+		RulesLabel:                 t.RulesLabel,
+		Admin:                      t.Admin,
+		Databases:                  t.Databases,
+		DatabasesRoot:              t.DatabasesRoot,
 	}
 	return nil
 }
@@ -85,9 +109,69 @@ func (c *Config) MarshalJSON() ([]byte, error) {
 		ReadOnly:           c.ReadOnly,
 		Timeout:            duration(c.Timeout),
 		LoadSize:           c.LoadSize,
+		Reasoning:          c.Reasoning,
+		TextIndex:          c.TextIndex,
+		GeoIndex:           c.GeoIndex,
+		Rules:              c.Rules,
+		RulesLabel:         c.RulesLabel,
+		Admin:              c.Admin,
+		Databases:          c.Databases,
+		DatabasesRoot:      c.DatabasesRoot,
 	})
 }
 
+// Database returns the config of a named database from the Databases section. Options that are
+// not set for the database are inherited from the main config. Boolean options (read-only mode and
+// reasoning) that are enabled in the main config apply to all databases.
+func (c *Config) Database(name string) (*Config, bool) {
+	dc, ok := c.Databases[name]
+	if !ok || dc == nil {
+		return nil, false
+	}
+	return c.Inherit(dc), true
+}
+
+// Inherit returns a copy of a database config with unset options taken from this config.
+// Options that are specific to the main config (db_path, listen address, admin and databases) are not inherited.
+// Neither is replication: its options (listen address, log file and leader) belong to a single database, thus
+// databases use the "single" writer unless their own replication options are set.
+func (c *Config) Inherit(dc *Config) *Config {
+	out := *dc
+	out.ListenHost, out.ListenPort = "", ""
+	out.Admin, out.Databases, out.DatabasesRoot = false, nil, ""
+	if out.DatabaseType == "" {
+		out.DatabaseType = c.DatabaseType
+	}
+	if out.DatabaseOptions == nil {
+		out.DatabaseOptions = c.DatabaseOptions
+	}
+	if out.ReplicationType == "" {
+		out.ReplicationType = "single"
+	}
+	if out.Timeout == 0 {
+		out.Timeout = c.Timeout
+	}
+	if out.LoadSize == 0 {
+		out.LoadSize = c.LoadSize
+	}
+	if out.TextIndex == "" {
+		out.TextIndex = c.TextIndex
+	}
+	if out.GeoIndex == "" {
+		out.GeoIndex = c.GeoIndex
+	}
+	if out.Rules == nil {
+		out.Rules = c.Rules
+	}
+	if out.RulesLabel == "" {
+		out.RulesLabel = c.RulesLabel
+	}
+	out.ReadOnly = out.ReadOnly || c.ReadOnly
+	out.Reasoning = out.Reasoning || c.Reasoning
+	out.RequiresHTTPRequestContext = out.RequiresHTTPRequestContext || c.RequiresHTTPRequestContext
+	return &out
+}
+
 // duration is a time.Duration that satisfies the
 // json.UnMarshaler and json.Marshaler interfaces.
 type duration time.Duration
@@ -0,0 +1,217 @@
+// Copyright 2017 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/codelingo/cayley/clog"
+	"github.com/codelingo/cayley/graph"
+	"github.com/codelingo/cayley/internal/config"
+)
+
+var (
+	ErrDatabaseNotFound = errors.New("database not found")
+	ErrNameInUse        = errors.New("database name is already in use")
+)
+
+var validName = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// CheckName returns an error if the name cannot be used for a database. Valid names
+// consist of letters, digits, '_' and '-', so they can be used as file names as well.
+func CheckName(name string) error {
+	if !validName.MatchString(name) {
+		return fmt.Errorf("invalid database name: %q", name)
+	}
+	return nil
+}
+
+// Database is an open named database.
+type Database struct {
+	Name   string
+	Config *config.Config
+	*graph.Handle
+
+	mu      sync.Mutex
+	closing bool
+	wg      sync.WaitGroup // in-flight requests
+}
+
+// Acquire marks the start of a request to the database. It returns false if the database is being
+// closed; otherwise Release must be called when the request is done. The database is closed
+// only after all requests are released.
+func (db *Database) Acquire() bool {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if db.closing {
+		return false
+	}
+	db.wg.Add(1)
+	return true
+}
+
+// Release marks the end of a request started with Acquire.
+func (db *Database) Release() {
+	db.wg.Done()
+}
+
+// close waits for all in-flight requests and closes the database.
+func (db *Database) close() error {
+	db.mu.Lock()
+	db.closing = true
+	db.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		db.wg.Wait()
+		close(done)
+	}()
+	for {
+		// end streaming requests that wait for changes; they might subscribe again until they are released
+		graph.CloseFeed(db.QuadStore)
+		select {
+		case <-done:
+			return db.Close()
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// Databases is a set of named databases served by one process.
+type Databases struct {
+	// Load is called for each opened database that is not persistent, to load quads from its db_path.
+	Load func(h *graph.Handle, cfg *config.Config) error
+
+	cfg *config.Config
+
+	mu  sync.RWMutex
+	dbs map[string]*Database
+}
+
+// NewDatabases creates an empty set of databases. Options that are not set for a database are inherited from cfg.
+func NewDatabases(cfg *config.Config) *Databases {
+	return &Databases{cfg: cfg, dbs: make(map[string]*Database)}
+}
+
+// OpenAll opens all databases listed in the config. Persistent databases are initialized
+// first if init is set. All databases are closed if any of them fails to open.
+func (d *Databases) OpenAll(init bool) error {
+	names := make([]string, 0, len(d.cfg.Databases))
+	for name := range d.cfg.Databases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		cfg, _ := d.cfg.Database(name)
+		if _, err := d.Open(name, cfg, init); err != nil {
+			d.Close()
+			return fmt.Errorf("cannot open database %q: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// Open opens a database and adds it to the set under a given name. Unset options of the config
+// are inherited. Persistent databases are initialized first if init is set; existing databases
+// are opened as is.
+func (d *Databases) Open(name string, cfg *config.Config, init bool) (*Database, error) {
+	if err := CheckName(name); err != nil {
+		return nil, err
+	}
+	cfg = d.cfg.Inherit(cfg)
+	if _, ok := d.Get(name); ok {
+		return nil, ErrNameInUse
+	}
+	clog.Infof("Opening database %q", name)
+	if init && graph.IsPersistent(cfg.DatabaseType) {
+		if err := Init(cfg); err != nil && err != graph.ErrDatabaseExists {
+			return nil, err
+		}
+	}
+	h, err := Open(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if !graph.IsPersistent(cfg.DatabaseType) && d.Load != nil && cfg.DatabasePath != "" {
+		if err = d.Load(h, cfg); err != nil {
+			h.Close()
+			return nil, err
+		}
+	}
+	db := &Database{Name: name, Config: cfg, Handle: h}
+
+	d.mu.Lock()
+	_, exists := d.dbs[name]
+	if !exists {
+		d.dbs[name] = db
+	}
+	d.mu.Unlock()
+	if exists {
+		// opened concurrently by another caller
+		h.Close()
+		return nil, ErrNameInUse
+	}
+	return db, nil
+}
+
+// Get returns an open database by name.
+func (d *Databases) Get(name string) (*Database, bool) {
+	d.mu.RLock()
+	db, ok := d.dbs[name]
+	d.mu.RUnlock()
+	return db, ok
+}
+
+// Names returns sorted names of all open databases.
+func (d *Databases) Names() []string {
+	d.mu.RLock()
+	names := make([]string, 0, len(d.dbs))
+	for name := range d.dbs {
+		names = append(names, name)
+	}
+	d.mu.RUnlock()
+	sort.Strings(names)
+	return names
+}
+
+// Drop removes a database from the set and closes it after all in-flight requests are released.
+// Data of persistent databases is kept at their db_path.
+func (d *Databases) Drop(name string) error {
+	d.mu.Lock()
+	db, ok := d.dbs[name]
+	delete(d.dbs, name)
+	d.mu.Unlock()
+	if !ok {
+		return ErrDatabaseNotFound
+	}
+	clog.Infof("Closing database %q", name)
+	return db.close()
+}
+
+// Close closes all databases.
+func (d *Databases) Close() error {
+	var err error
+	for _, name := range d.Names() {
+		if err2 := d.Drop(name); err == nil && err2 != ErrDatabaseNotFound {
+			err = err2
+		}
+	}
+	return err
+}
@@ -19,10 +19,17 @@ import (
 	"fmt"
 	"os"
 
+	"golang.org/x/net/context"
+
 	"github.com/codelingo/cayley/clog"
 
 	"github.com/codelingo/cayley/graph"
+	"github.com/codelingo/cayley/graph/fulltext"
+	"github.com/codelingo/cayley/graph/geo"
+	"github.com/codelingo/cayley/graph/reasoner"
+	"github.com/codelingo/cayley/graph/rules"
 	"github.com/codelingo/cayley/internal/config"
+	"github.com/codelingo/cayley/quad"
 )
 
 var ErrNotPersistent = errors.New("database type is not persistent")
@@ -44,9 +51,38 @@ func Open(cfg *config.Config) (*graph.Handle, error) {
 	if err != nil {
 		return nil, err
 	}
+	if len(cfg.Rules) != 0 {
+		qw, err = openRules(qs, qw, cfg)
+		if err != nil {
+			return nil, err
+		}
+	}
 	return &graph.Handle{QuadStore: qs, QuadWriter: qw}, nil
 }
 
+// openRules derives quads for all configured rules and returns a writer that keeps them up to date.
+func openRules(qs graph.QuadStore, qw graph.QuadWriter, cfg *config.Config) (graph.QuadWriter, error) {
+	var label quad.Value
+	if cfg.RulesLabel != "" {
+		label = quad.IRI(cfg.RulesLabel)
+	}
+	e := rules.New(qs, label)
+	for _, s := range cfg.Rules {
+		r, err := rules.Parse(s)
+		if err != nil {
+			return nil, err
+		}
+		if err = e.AddRule(r); err != nil {
+			return nil, err
+		}
+	}
+	clog.Infof("Applying %d rules", len(cfg.Rules))
+	if err := e.Materialize(context.Background(), qw); err != nil {
+		return nil, err
+	}
+	return e.Writer(qw), nil
+}
+
 func OpenQuadStore(cfg *config.Config) (graph.QuadStore, error) {
 	clog.Infof("Opening quad store %q at %s", cfg.DatabaseType, cfg.DatabasePath)
 	qs, err := graph.NewQuadStore(cfg.DatabaseType, cfg.DatabasePath, cfg.DatabaseOptions)
@@ -59,6 +95,27 @@ func OpenQuadStore(cfg *config.Config) (graph.QuadStore, error) {
 	if err != nil {
 		return nil, err
 	}
+	if cfg.TextIndex != "" {
+		clog.Infof("Building %q full-text index", cfg.TextIndex)
+		idx, err := fulltext.NewIndex(cfg.TextIndex, qs, cfg.DatabaseOptions)
+		if err != nil {
+			qs.Close()
+			return nil, err
+		}
+		qs = fulltext.New(qs, idx)
+	}
+	if cfg.GeoIndex != "" {
+		clog.Infof("Building %q spatial index", cfg.GeoIndex)
+		idx, err := geo.NewIndex(cfg.GeoIndex, qs, cfg.DatabaseOptions)
+		if err != nil {
+			qs.Close()
+			return nil, err
+		}
+		qs = geo.New(qs, idx)
+	}
+	if cfg.Reasoning {
+		qs = reasoner.New(qs)
+	}
 
 	return qs, nil
 }
@@ -72,6 +72,35 @@ func Run(ctx context.Context, qu string, ses query.REPLSession) error {
 	return nil
 }
 
+// explain runs the query with an explainer and writes optimized iterator trees with their costs.
+// If profile is set, the query is run and its results are counted, but not written.
+func explain(ctx context.Context, w io.Writer, qu string, ses query.Session, profile bool) error {
+	e := graph.NewExplainer(profile)
+	c := make(chan query.Result, 5)
+	go ses.Execute(graph.WithExplainer(ctx, e), qu, c, 100)
+	var (
+		err      error
+		nResults int
+	)
+	for res := range c {
+		// read all results to let the query finish
+		if rerr := res.Err(); rerr != nil && err == nil {
+			err = rerr
+		}
+		nResults++
+	}
+	if err != nil {
+		return err
+	}
+	if err = graph.WriteExplanation(w, e.Explain()); err != nil {
+		return err
+	}
+	if profile {
+		_, err = fmt.Fprintf(w, "-----------\n%d results\n", nResults)
+	}
+	return err
+}
+
 const (
 	defaultLanguage = "gremlin"
 
@@ -162,6 +191,15 @@ func Repl(ctx context.Context, h *graph.Handle, queryLanguage string, timeout ti
 				fmt.Printf("Debug set to %t\n", debug)
 				continue
 
+			case ":explain", ":profile":
+				nctx, cancel := newCtx()
+				err = explain(nctx, os.Stdout, strings.TrimSpace(args), ses, cmd == ":profile")
+				cancel()
+				if err != nil {
+					fmt.Println("Error: ", err)
+				}
+				continue
+
 			case ":a":
 				quad, err := nquads.Parse(args)
 				if err == nil {
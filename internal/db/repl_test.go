@@ -15,7 +15,16 @@
 package db
 
 import (
+	"bytes"
+	"strings"
 	"testing"
+
+	"golang.org/x/net/context"
+
+	"github.com/codelingo/cayley/graph/memstore"
+	"github.com/codelingo/cayley/quad"
+	"github.com/codelingo/cayley/query/gizmo"
+	"github.com/codelingo/cayley/writer"
 )
 
 var testSplitLines = []struct {
@@ -64,3 +73,32 @@ func TestSplitLines(t *testing.T) {
 		}
 	}
 }
+
+func TestExplain(t *testing.T) {
+	qs := memstore.New()
+	w, err := writer.NewSingleReplication(qs, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = w.AddQuadSet([]quad.Quad{
+		quad.MakeIRI("alice", "follows", "bob", ""),
+		quad.MakeIRI("alice", "follows", "charlie", ""),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	const qu = `g.V("<alice>").Out("<follows>").All()`
+	for _, profile := range []bool{false, true} {
+		buf := bytes.NewBuffer(nil)
+		if err := explain(context.TODO(), buf, qu, gizmo.NewSession(qs), profile); err != nil {
+			t.Fatal(err)
+		}
+		out := buf.String()
+		if !strings.HasPrefix(out, "hasa #") {
+			t.Errorf("unexpected explanation:\n%s", out)
+		}
+		if profile != strings.Contains(out, "2 results") {
+			t.Errorf("unexpected profile (%v):\n%s", profile, out)
+		}
+	}
+}
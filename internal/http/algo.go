@@ -0,0 +1,89 @@
+// Copyright 2017 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package http
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/codelingo/cayley/graph/algo"
+	"github.com/codelingo/cayley/quad"
+)
+
+type algoResult struct {
+	ID    string      `json:"id"`
+	Value interface{} `json:"value"`
+}
+
+// ServeV2Algo runs a graph algorithm over edges with predicates given in the "pred" parameters (all quads by default).
+//
+// If the "write" parameter is set, results are written back to the store with this predicate and an optional
+// "label", instead of being returned. Writes are only accepted in POST requests.
+func (api *API) ServeV2Algo(w http.ResponseWriter, r *http.Request, params httprouter.Params) int {
+	ctx, cancel := api.contextForRequest(r)
+	defer cancel()
+	q := r.URL.Query()
+	var pred, label quad.Value
+	if s := q.Get("write"); s != "" {
+		if r.Method != "POST" {
+			w.Header().Set("Allow", "POST")
+			return jsonResponse(w, http.StatusMethodNotAllowed, "Results can only be written with a POST request.")
+		}
+		if api.config.ReadOnly {
+			return jsonResponse(w, http.StatusForbidden, "Database is read-only.")
+		}
+		pred = quad.StringToValue(s)
+		if s = q.Get("label"); s != "" {
+			label = quad.StringToValue(s)
+		}
+	}
+	var preds []quad.Value
+	for _, s := range q["pred"] {
+		preds = append(preds, quad.StringToValue(s))
+	}
+	h, err := api.GetHandleForRequest(r)
+	if err != nil {
+		return jsonResponse(w, http.StatusBadRequest, err)
+	}
+	g, err := algo.Load(ctx, h.QuadStore, preds...)
+	if err != nil {
+		return jsonResponse(w, http.StatusInternalServerError, err)
+	}
+	vals, err := algo.Run(ctx, g, params.ByName("name"))
+	if err != nil {
+		return jsonResponse(w, http.StatusBadRequest, err)
+	}
+	if pred != nil {
+		if err = algo.Write(h.QuadWriter, g, vals, pred, label, api.config.LoadSize); err != nil {
+			return jsonResponse(w, http.StatusInternalServerError, err)
+		}
+		w.Header().Set(hdrContentType, contentTypeJSON)
+		fmt.Fprintf(w, `{"result": "Successfully wrote %d values.", "count": %d}`+"\n", len(vals), len(vals))
+		return http.StatusOK
+	}
+	out := make([]algoResult, 0, len(vals))
+	for i, v := range vals {
+		out = append(out, algoResult{ID: g.NameOf(i).String(), Value: quad.NativeOf(v)})
+	}
+	data, err := WrapResult(out)
+	if err != nil {
+		return jsonResponse(w, http.StatusInternalServerError, err)
+	}
+	w.Header().Set(hdrContentType, contentTypeJSON)
+	w.Write(data)
+	return http.StatusOK
+}
@@ -0,0 +1,82 @@
+// Copyright 2017 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/stretchr/testify/require"
+
+	"github.com/codelingo/cayley/graph"
+	"github.com/codelingo/cayley/graph/graphtest"
+	"github.com/codelingo/cayley/graph/memstore"
+	"github.com/codelingo/cayley/internal/config"
+	"github.com/codelingo/cayley/quad"
+	"github.com/codelingo/cayley/writer"
+)
+
+func TestV2Algo(t *testing.T) {
+	qs := memstore.New()
+	graphtest.MakeWriter(t, qs, nil,
+		quad.MakeIRI("alice", "follows", "bob", ""),
+		quad.MakeIRI("bob", "follows", "alice", ""),
+		quad.MakeIRI("alice", "status", "cool", ""),
+	)
+	qw, err := writer.NewSingleReplication(qs, nil)
+	require.NoError(t, err)
+	api := &API{config: &config.Config{}, handle: &graph.Handle{QuadStore: qs, QuadWriter: qw}}
+	r := httprouter.New()
+	api.APIv2(r)
+	doMethod := func(method, url string) string {
+		req, err := http.NewRequest(method, url, nil)
+		require.NoError(t, err)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Body.String()
+	}
+	do := func(url string) string {
+		return doMethod("POST", url)
+	}
+
+	var out struct {
+		Result []algoResult `json:"result"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(do("/api/v2/algo/outdegree?pred=<follows>")), &out))
+	require.Equal(t, []algoResult{
+		{ID: "<alice>", Value: 1.0},
+		{ID: "<bob>", Value: 1.0},
+	}, out.Result)
+	require.Contains(t, do("/api/v2/algo/unknown"), `{"error"`)
+
+	// writes are rejected in GET requests
+	require.Contains(t, doMethod("GET", "/api/v2/algo/indegree?pred=<follows>&write=<followers>"), "POST")
+	require.Equal(t, int64(3), qs.Size())
+	require.Contains(t, do("/api/v2/algo/indegree?pred=<follows>&write=<followers>"), `"count": 2`)
+	it := qs.QuadIterator(quad.Predicate, qs.ValueOf(quad.IRI("followers")))
+	defer it.Close()
+	n := 0
+	for it.Next(nil) {
+		require.Equal(t, quad.Int(1), qs.Quad(it.Result()).Object)
+		n++
+	}
+	require.Equal(t, 2, n)
+
+	api.config.ReadOnly = true
+	require.Contains(t, do("/api/v2/algo/indegree?write=<followers>"), "read-only")
+}
@@ -0,0 +1,157 @@
+// Copyright 2017 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/codelingo/cayley/graph"
+	"github.com/codelingo/cayley/quad"
+)
+
+const hdrLastEventID = "Last-Event-ID"
+
+// changesKeepAlive is an interval between keep-alive comments in the change feed stream.
+var changesKeepAlive = 30 * time.Second
+
+// writeEvent writes a single Server-Sent Event with JSON data. Empty id is omitted.
+func writeEvent(w io.Writer, id, event string, data interface{}) error {
+	buf, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	if id != "" {
+		if _, err = fmt.Fprintf(w, "id: %s\n", id); err != nil {
+			return err
+		}
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, buf)
+	return err
+}
+
+type deltaJSON struct {
+	ID        string    `json:"id"`
+	Action    string    `json:"action"`
+	Quad      quad.Quad `json:"quad"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+func deltasToJSON(deltas []graph.Delta) []deltaJSON {
+	out := make([]deltaJSON, 0, len(deltas))
+	for i := range deltas {
+		d := &deltas[i]
+		out = append(out, deltaJSON{
+			ID:        d.ID.String(),
+			Action:    d.Action.String(),
+			Quad:      d.Quad,
+			Timestamp: d.Timestamp,
+		})
+	}
+	return out
+}
+
+// deltaFilterFromRequest reads subject, predicate and label filters from URL parameters.
+func deltaFilterFromRequest(r *http.Request) graph.DeltaFilter {
+	params := r.URL.Query()
+	value := func(name string) quad.Value {
+		if s := params.Get(name); s != "" {
+			return quad.StringToValue(s)
+		}
+		return nil
+	}
+	return graph.DeltaFilter{
+		Subject:   value("subject"),
+		Predicate: value("predicate"),
+		Label:     value("label"),
+	}
+}
+
+// ServeV2Changes streams the change feed of the quad store as Server-Sent Events.
+//
+// Each event contains a JSON array of deltas applied in a single batch, and the ID of the last delta in the batch.
+// Stream can be resumed after this ID by passing it in the "since" parameter or Last-Event-ID header.
+func (api *API) ServeV2Changes(w http.ResponseWriter, r *http.Request, _ httprouter.Params) int {
+	h, err := api.GetHandleForRequest(r)
+	if err != nil {
+		return jsonResponse(w, http.StatusBadRequest, err)
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return jsonResponse(w, http.StatusInternalServerError, "streaming is not supported")
+	}
+	filter := deltaFilterFromRequest(r)
+	since := r.URL.Query().Get("since")
+	if since == "" {
+		since = r.Header.Get(hdrLastEventID)
+	}
+	var (
+		deltas <-chan []graph.Delta
+		cancel func() error
+	)
+	if since != "" {
+		id, err := strconv.ParseInt(since, 10, 64)
+		if err != nil {
+			return jsonResponse(w, http.StatusBadRequest, fmt.Errorf("invalid resume token: %q", since))
+		}
+		deltas, cancel, err = graph.SubscribeSince(h.QuadStore, filter, id)
+		if err == graph.ErrFeedExpired {
+			return jsonResponse(w, http.StatusGone, err)
+		} else if err != nil {
+			return jsonResponse(w, http.StatusBadRequest, err)
+		}
+	} else {
+		deltas, cancel = graph.Subscribe(h.QuadStore, filter)
+	}
+	defer cancel()
+
+	var gone <-chan bool
+	if cn, ok := w.(http.CloseNotifier); ok {
+		gone = cn.CloseNotify()
+	}
+	w.Header().Set(hdrContentType, "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	ticker := time.NewTicker(changesKeepAlive)
+	defer ticker.Stop()
+	for {
+		select {
+		case batch, ok := <-deltas:
+			if !ok {
+				// subscriber was too slow; client should reconnect with the last ID
+				return http.StatusOK
+			}
+			last := &batch[len(batch)-1]
+			if err := writeEvent(w, last.ID.String(), "deltas", deltasToJSON(batch)); err != nil {
+				return http.StatusOK
+			}
+		case <-ticker.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return http.StatusOK
+			}
+		case <-gone:
+			return http.StatusOK
+		}
+		flusher.Flush()
+	}
+}
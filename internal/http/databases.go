@@ -0,0 +1,185 @@
+// Copyright 2017 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"sync"
+
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/codelingo/cayley/graph"
+	"github.com/codelingo/cayley/internal/config"
+	"github.com/codelingo/cayley/internal/db"
+)
+
+// DatabasesAPI serves the HTTP API of each named database under /db/:db_name/,
+// and admin endpoints to list, create and drop databases.
+type DatabasesAPI struct {
+	config *config.Config
+	dbs    *db.Databases
+
+	mu      sync.Mutex
+	routers map[*db.Database]*httprouter.Router
+}
+
+// NewDatabasesAPI creates an API for a set of databases. Unset options of databases are inherited from cfg.
+func NewDatabasesAPI(dbs *db.Databases, cfg *config.Config) *DatabasesAPI {
+	return &DatabasesAPI{
+		config:  cfg,
+		dbs:     dbs,
+		routers: make(map[*db.Database]*httprouter.Router),
+	}
+}
+
+func (m *DatabasesAPI) Routes(r *httprouter.Router) {
+	for _, method := range []string{"GET", "POST", "PUT", "DELETE"} {
+		r.Handle(method, "/db/:db_name/*path", m.ServeDatabase)
+	}
+	if !m.config.Admin {
+		return
+	}
+	r.GET("/admin/dbs", CORS(LogRequest(m.ServeList)))
+	if m.config.ReadOnly {
+		return
+	}
+	r.POST("/admin/dbs/:db_name", CORS(LogRequest(m.ServeCreate)))
+	r.DELETE("/admin/dbs/:db_name", CORS(LogRequest(m.ServeDrop)))
+}
+
+// router returns a router with all API endpoints of a database.
+func (m *DatabasesAPI) router(d *db.Database) *httprouter.Router {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	r, ok := m.routers[d]
+	if !ok {
+		api := &API{config: d.Config, handle: d.Handle}
+		r = httprouter.New()
+		api.APIv1(r)
+		api.APIv2(r)
+		api.SPARQL(r)
+		m.routers[d] = r
+	}
+	return r
+}
+
+// ServeDatabase passes the request to the API of a named database, stripping the /db/:db_name prefix.
+func (m *DatabasesAPI) ServeDatabase(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	d, ok := m.dbs.Get(params.ByName("db_name"))
+	if !ok || !d.Acquire() {
+		jsonResponse(w, http.StatusNotFound, db.ErrDatabaseNotFound)
+		return
+	}
+	defer d.Release()
+	r2 := new(http.Request)
+	*r2 = *r
+	u := *r.URL
+	u.Path = params.ByName("path")
+	r2.URL = &u
+	m.router(d).ServeHTTP(w, r2)
+}
+
+// ServeList returns names of all databases.
+func (m *DatabasesAPI) ServeList(w http.ResponseWriter, r *http.Request, _ httprouter.Params) int {
+	data, err := WrapResult(m.dbs.Names())
+	if err != nil {
+		return jsonResponse(w, http.StatusInternalServerError, err)
+	}
+	w.Header().Set("Content-Type", contentTypeJSON)
+	w.Write(data)
+	return http.StatusOK
+}
+
+var errNoDatabasesRoot = errors.New("databases_root is not set, only non-persistent databases can be created")
+
+// createOptions are options of a database that can be set on creation through the HTTP API.
+// Other options, such as db_path or replication, are taken from the main config.
+type createOptions struct {
+	DatabaseType string `json:"database"`
+	ReadOnly     bool   `json:"read_only"`
+}
+
+// ServeCreate creates and opens a database. The body is an optional JSON object with the backend
+// and read-only mode of the database; other options are inherited from the main config.
+// Persistent databases are stored under databases_root, in a file or directory named after the database.
+func (m *DatabasesAPI) ServeCreate(w http.ResponseWriter, r *http.Request, params httprouter.Params) int {
+	name := params.ByName("db_name")
+	if err := db.CheckName(name); err != nil {
+		return jsonResponse(w, http.StatusBadRequest, err)
+	}
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return jsonResponse(w, http.StatusBadRequest, err)
+	}
+	var opts createOptions
+	if len(data) != 0 {
+		// reject unknown options instead of silently ignoring them
+		var fields map[string]json.RawMessage
+		if err = json.Unmarshal(data, &fields); err != nil {
+			return jsonResponse(w, http.StatusBadRequest, err)
+		}
+		for k := range fields {
+			if k != "database" && k != "read_only" {
+				return jsonResponse(w, http.StatusBadRequest, fmt.Errorf("option %q cannot be set for a new database", k))
+			}
+		}
+		if err = json.Unmarshal(data, &opts); err != nil {
+			return jsonResponse(w, http.StatusBadRequest, err)
+		}
+	}
+	cfg := &config.Config{DatabaseType: opts.DatabaseType, ReadOnly: opts.ReadOnly}
+	typ := cfg.DatabaseType
+	if typ == "" {
+		typ = m.config.DatabaseType
+	}
+	if graph.IsPersistent(typ) {
+		if m.config.DatabasesRoot == "" {
+			return jsonResponse(w, http.StatusBadRequest, errNoDatabasesRoot)
+		}
+		cfg.DatabasePath = filepath.Join(m.config.DatabasesRoot, name)
+	}
+	if _, err = m.dbs.Open(name, cfg, true); err == db.ErrNameInUse {
+		return jsonResponse(w, http.StatusConflict, err)
+	} else if err != nil {
+		return jsonResponse(w, http.StatusBadRequest, err)
+	}
+	w.Header().Set("Content-Type", contentTypeJSON)
+	w.Write([]byte(`{"result": "Successfully created database."}`))
+	return http.StatusOK
+}
+
+// ServeDrop closes a database and removes it from the server. Data of persistent databases is kept.
+func (m *DatabasesAPI) ServeDrop(w http.ResponseWriter, r *http.Request, params httprouter.Params) int {
+	d, ok := m.dbs.Get(params.ByName("db_name"))
+	if !ok {
+		return jsonResponse(w, http.StatusNotFound, db.ErrDatabaseNotFound)
+	}
+	m.mu.Lock()
+	delete(m.routers, d)
+	m.mu.Unlock()
+	if err := m.dbs.Drop(d.Name); err == db.ErrDatabaseNotFound {
+		return jsonResponse(w, http.StatusNotFound, err)
+	} else if err != nil {
+		return jsonResponse(w, http.StatusInternalServerError, err)
+	}
+	w.Header().Set("Content-Type", contentTypeJSON)
+	w.Write([]byte(`{"result": "Successfully dropped database."}`))
+	return http.StatusOK
+}
@@ -0,0 +1,101 @@
+// Copyright 2017 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/stretchr/testify/require"
+
+	_ "github.com/codelingo/cayley/graph/memstore"
+	"github.com/codelingo/cayley/internal/config"
+	"github.com/codelingo/cayley/internal/db"
+	_ "github.com/codelingo/cayley/quad/nquads"
+	_ "github.com/codelingo/cayley/writer"
+)
+
+func TestDatabasesAPI(t *testing.T) {
+	cfg := &config.Config{
+		DatabaseType: "memstore",
+		// replication of the main database must not apply to other databases
+		ReplicationType:    "follower",
+		ReplicationOptions: map[string]interface{}{"leader": "http://127.0.0.1:1"},
+		Admin:              true,
+		Databases: map[string]*config.Config{
+			"a": {},
+			"b": {ReadOnly: true},
+		},
+	}
+	dbs := db.NewDatabases(cfg)
+	require.NoError(t, dbs.OpenAll(false))
+	defer dbs.Close()
+
+	r := httprouter.New()
+	NewDatabasesAPI(dbs, cfg).Routes(r)
+	// errors are reported in the body, with any status code
+	do := func(method, url, body string, ok bool) string {
+		req, err := http.NewRequest(method, url, strings.NewReader(body))
+		require.NoError(t, err)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		out := w.Body.String()
+		require.Equal(t, ok, w.Code == http.StatusOK && !strings.HasPrefix(out, `{"error"`), "%s %s: %d %s", method, url, w.Code, out)
+		return out
+	}
+	const data = `[{"subject": "<alice>", "predicate": "<follows>", "object": "<bob>"}]`
+
+	do("POST", "/db/a/api/v1/write", data, true)
+	require.Equal(t, "<alice> <follows> <bob> .\n", do("GET", "/db/a/api/v2/read?format=nquads", "", true))
+	do("POST", "/db/b/api/v1/write", data, false)
+	require.Equal(t, "", do("GET", "/db/b/api/v2/read?format=nquads", "", true))
+	do("GET", "/db/c/api/v2/read?format=nquads", "", false)
+
+	do("POST", "/admin/dbs/c", `{"database": "memstore"}`, true)
+	do("POST", "/admin/dbs/c", "", false)
+	do("POST", "/admin/dbs/c.d", "", false)
+	do("POST", "/admin/dbs/d", `{"database": "memstore", "db_path": "/etc/passwd"}`, false)
+	do("POST", "/admin/dbs/d", `{"replication_options": {"listen": "127.0.0.1:0"}}`, false)
+	require.Contains(t, do("GET", "/admin/dbs", "", true), `"a",`+"\n"+`  "b",`+"\n"+`  "c"`)
+	do("POST", "/db/c/api/v1/write", data, true)
+
+	// dropped database is closed only after in-flight requests are done
+	d, ok := dbs.Get("a")
+	require.True(t, ok)
+	require.True(t, d.Acquire())
+	req, err := http.NewRequest("DELETE", "/admin/dbs/a", nil)
+	require.NoError(t, err)
+	dropped := make(chan int)
+	go func() {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		dropped <- w.Code
+	}()
+	select {
+	case <-dropped:
+		t.Fatal("database was closed with a request in flight")
+	case <-time.After(50 * time.Millisecond):
+	}
+	do("GET", "/db/a/api/v2/read?format=nquads", "", false)
+	d.Release()
+	require.Equal(t, http.StatusOK, <-dropped)
+	do("DELETE", "/admin/dbs/a", "", false)
+	do("GET", "/db/a/api/v2/read?format=nquads", "", false)
+	require.Equal(t, []string{"b", "c"}, dbs.Names())
+}
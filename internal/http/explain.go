@@ -0,0 +1,107 @@
+// Copyright 2017 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package http
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/codelingo/cayley/graph"
+	"github.com/codelingo/cayley/query"
+)
+
+type explainResult struct {
+	Iterators []graph.Explanation `json:"iterators"`
+	Results   int                 `json:"results,omitempty"`
+	Time      time.Duration       `json:"time,omitempty"`
+}
+
+// ServeV1Explain describes optimized iterator trees of a query, with estimated costs of each iterator.
+//
+// If the "profile" parameter is set, the query is run and the actual number of calls and time spent
+// in each iterator are added. Trees are rendered as indented text if the "format" parameter is "text".
+func (api *API) ServeV1Explain(w http.ResponseWriter, r *http.Request, params httprouter.Params) int {
+	ctx, cancel := api.contextForRequest(r)
+	defer cancel()
+	l := query.GetLanguage(params.ByName("query_lang"))
+	if l == nil {
+		return jsonResponse(w, http.StatusBadRequest, "Unknown query language.")
+	} else if l.Session == nil {
+		return jsonResponse(w, http.StatusBadRequest, "Explain is not supported for this query language.")
+	}
+	q := r.URL.Query()
+	var profile bool
+	if s := q.Get("profile"); s != "" {
+		var err error
+		if profile, err = strconv.ParseBool(s); err != nil {
+			return jsonResponse(w, http.StatusBadRequest, fmt.Errorf("invalid profile value %q", s))
+		}
+	}
+	h, err := api.GetHandleForRequest(r)
+	if err != nil {
+		return jsonResponse(w, http.StatusBadRequest, err)
+	}
+	qs, err := quadStoreAsOf(h.QuadStore, r)
+	if err != nil {
+		return jsonResponse(w, http.StatusBadRequest, err)
+	}
+	bodyBytes, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return jsonResponse(w, http.StatusBadRequest, err)
+	}
+	e := graph.NewExplainer(profile)
+	ses := l.Session(qs)
+
+	c := make(chan query.Result, 5)
+	start := time.Now()
+	go ses.Execute(graph.WithExplainer(ctx, e), string(bodyBytes), c, defaultQueryLimit)
+	var out explainResult
+	for res := range c {
+		// read all results to let the query finish
+		if rerr := res.Err(); rerr != nil && err == nil {
+			err = rerr
+		}
+		out.Results++
+	}
+	if err != nil {
+		return jsonResponse(w, http.StatusBadRequest, err)
+	}
+	out.Iterators = e.Explain()
+	if profile {
+		out.Time = time.Since(start)
+	} else {
+		out.Results = 0
+	}
+	if q.Get("format") == "text" {
+		w.Header().Set(hdrContentType, "text/plain")
+		graph.WriteExplanation(w, out.Iterators)
+		if profile {
+			fmt.Fprintf(w, "%d results in %v\n", out.Results, out.Time)
+		}
+		return http.StatusOK
+	}
+	data, err := WrapResult(out)
+	if err != nil {
+		return jsonResponse(w, http.StatusInternalServerError, err)
+	}
+	w.Header().Set(hdrContentType, contentTypeJSON)
+	w.Write(data)
+	return http.StatusOK
+}
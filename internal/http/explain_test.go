@@ -0,0 +1,71 @@
+// Copyright 2017 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/stretchr/testify/require"
+
+	"github.com/codelingo/cayley/graph"
+	"github.com/codelingo/cayley/graph/graphtest"
+	"github.com/codelingo/cayley/graph/memstore"
+	"github.com/codelingo/cayley/internal/config"
+	"github.com/codelingo/cayley/quad"
+	_ "github.com/codelingo/cayley/query/gizmo"
+)
+
+func TestV1Explain(t *testing.T) {
+	qs := memstore.New()
+	graphtest.MakeWriter(t, qs, nil,
+		quad.MakeIRI("alice", "follows", "bob", ""),
+		quad.MakeIRI("alice", "follows", "charlie", ""),
+	)
+	api := &API{config: &config.Config{}, handle: &graph.Handle{QuadStore: qs}}
+	r := httprouter.New()
+	api.APIv1(r)
+	do := func(params string) string {
+		req, err := http.NewRequest("POST", "/api/v1/explain/gizmo?"+params,
+			strings.NewReader(`g.V("<alice>").Out("<follows>").All()`))
+		require.NoError(t, err)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Body.String()
+	}
+	var out struct {
+		Result explainResult `json:"result"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(do("")), &out))
+	require.Len(t, out.Result.Iterators, 1)
+	require.Nil(t, out.Result.Iterators[0].Profile)
+	require.Equal(t, 0, out.Result.Results)
+
+	out.Result = explainResult{}
+	require.NoError(t, json.Unmarshal([]byte(do("profile=true")), &out))
+	require.Len(t, out.Result.Iterators, 1)
+	require.Equal(t, 2, out.Result.Results)
+	require.NotNil(t, out.Result.Iterators[0].Profile)
+	require.Equal(t, int64(3), out.Result.Iterators[0].Profile.Next)
+
+	text := do("profile=true&format=text")
+	require.True(t, strings.HasPrefix(text, "hasa #"), text)
+	require.Contains(t, text, "2 results in ")
+	require.Contains(t, do("profile=maybe"), `{"error"`)
+}
@@ -27,6 +27,7 @@ import (
 	"github.com/julienschmidt/httprouter"
 
 	"github.com/codelingo/cayley/graph"
+	"github.com/codelingo/cayley/graph/labels"
 	"github.com/codelingo/cayley/internal/config"
 	"github.com/codelingo/cayley/internal/db"
 	"github.com/codelingo/cayley/internal/gephi"
@@ -117,22 +118,35 @@ type API struct {
 }
 
 func (api *API) GetHandleForRequest(r *http.Request) (*graph.Handle, error) {
-	if !api.config.RequiresHTTPRequestContext {
-		return api.handle, nil
-	}
-
 	opts := make(graph.Options)
 	opts["HTTPRequest"] = r
 
-	qs, err := graph.NewQuadStoreForRequest(api.handle.QuadStore, opts)
-	if err != nil {
-		return nil, err
+	h := api.handle
+	if api.config.RequiresHTTPRequestContext {
+		qs, err := graph.NewQuadStoreForRequest(h.QuadStore, opts)
+		if err != nil {
+			return nil, err
+		}
+		qw, err := db.OpenQuadWriter(qs, api.config)
+		if err != nil {
+			return nil, err
+		}
+		h = &graph.Handle{QuadStore: qs, QuadWriter: qw}
 	}
-	qw, err := db.OpenQuadWriter(qs, api.config)
+	return scopeHandle(h, opts)
+}
+
+// scopeHandle restricts the handle to named graphs listed in the graph URL parameter, if any.
+func scopeHandle(h *graph.Handle, opts graph.Options) (*graph.Handle, error) {
+	qs, err := labels.NewForRequest(h.QuadStore, opts)
 	if err != nil {
 		return nil, err
 	}
-	return &graph.Handle{QuadStore: qs, QuadWriter: qw}, nil
+	view, ok := qs.(*labels.QuadStore)
+	if !ok {
+		return h, nil
+	}
+	return &graph.Handle{QuadStore: view, QuadWriter: labels.NewWriter(view, h.QuadWriter)}, nil
 }
 
 func (api *API) RWOnly(handler httprouter.Handle) httprouter.Handle {
@@ -163,6 +177,7 @@ func CORS(h httprouter.Handle) httprouter.Handle {
 func (api *API) APIv1(r *httprouter.Router) {
 	r.POST("/api/v1/query/:query_lang", CORS(LogRequest(api.ServeV1Query)))
 	r.POST("/api/v1/shape/:query_lang", CORS(LogRequest(api.ServeV1Shape)))
+	r.POST("/api/v1/explain/:query_lang", CORS(LogRequest(api.ServeV1Explain)))
 	r.POST("/api/v1/write", CORS(api.RWOnly(LogRequest(api.ServeV1Write))))
 	r.POST("/api/v1/write/file/nquad", CORS(api.RWOnly(LogRequest(api.ServeV1WriteNQuad))))
 	r.POST("/api/v1/delete", CORS(api.RWOnly(LogRequest(api.ServeV1Delete))))
@@ -174,9 +189,19 @@ func (api *API) APIv2(r *httprouter.Router) {
 	r.POST("/api/v2/read", CORS(LogRequest(api.ServeV2Read)))
 	r.GET("/api/v2/read", CORS(LogRequest(api.ServeV2Read)))
 	r.GET("/api/v2/formats", CORS(LogRequest(api.ServeV2Formats)))
+	r.GET("/api/v2/changes", CORS(LogRequest(api.ServeV2Changes)))
+	r.GET("/api/v2/watch/:query_lang", CORS(LogRequest(api.ServeV2Watch)))
+	r.POST("/api/v2/watch/:query_lang", CORS(LogRequest(api.ServeV2Watch)))
+	r.GET("/api/v2/algo/:name", CORS(LogRequest(api.ServeV2Algo)))
+	r.POST("/api/v2/algo/:name", CORS(LogRequest(api.ServeV2Algo)))
 }
 
 func SetupRoutes(handle *graph.Handle, cfg *config.Config) {
+	SetupDatabaseRoutes(handle, nil, cfg)
+}
+
+// SetupDatabaseRoutes is like SetupRoutes, but also serves named databases under /db/:db_name/.
+func SetupDatabaseRoutes(handle *graph.Handle, dbs *db.Databases, cfg *config.Config) {
 	r := httprouter.New()
 	assets := findAssetsPath()
 	if clog.V(2) {
@@ -190,6 +215,10 @@ func SetupRoutes(handle *graph.Handle, cfg *config.Config) {
 	r.OPTIONS("/*path", CORSFunc)
 	api.APIv1(r)
 	api.APIv2(r)
+	api.SPARQL(r)
+	if dbs != nil {
+		NewDatabasesAPI(dbs, cfg).Routes(r)
+	}
 	gs := &gephi.GraphStreamHandler{QS: handle.QuadStore}
 	const gephiPath = "/gephi/gs"
 	r.GET(gephiPath, gs.ServeHTTP)
@@ -204,8 +233,8 @@ func SetupRoutes(handle *graph.Handle, cfg *config.Config) {
 	http.Handle("/", r)
 }
 
-func Serve(handle *graph.Handle, cfg *config.Config) {
-	SetupRoutes(handle, cfg)
+func Serve(handle *graph.Handle, dbs *db.Databases, cfg *config.Config) {
+	SetupDatabaseRoutes(handle, dbs, cfg)
 	clog.Infof("Cayley now listening on %s:%s\n", cfg.ListenHost, cfg.ListenPort)
 	fmt.Printf("Cayley now listening on %s:%s\n", cfg.ListenHost, cfg.ListenPort)
 	err := http.ListenAndServe(fmt.Sprintf("%s:%s", cfg.ListenHost, cfg.ListenPort), nil)
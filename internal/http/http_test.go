@@ -16,9 +16,13 @@ package http
 
 import (
 	"fmt"
+	"net/http"
 	"reflect"
 	"testing"
 
+	"github.com/codelingo/cayley/graph"
+	"github.com/codelingo/cayley/graph/labels"
+	"github.com/codelingo/cayley/graph/memstore"
 	"github.com/codelingo/cayley/quad"
 )
 
@@ -71,3 +75,56 @@ func TestParseJSON(t *testing.T) {
 		}
 	}
 }
+
+func TestQuadStoreAsOf(t *testing.T) {
+	qs := memstore.New()
+	for _, c := range []struct {
+		url string
+		err bool
+	}{
+		{url: "/api/v1/query/gremlin"},
+		{url: "/api/v1/query/gremlin?as_of=10", err: true},
+		{url: "/api/v1/query/gremlin?as_of=2017-03-01T00:00:00Z", err: true},
+		{url: "/api/v1/query/gremlin?as_of=yesterday", err: true},
+	} {
+		r, err := http.NewRequest("POST", c.url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := quadStoreAsOf(qs, r)
+		if c.err {
+			if err == nil {
+				t.Errorf("expected an error for %q", c.url)
+			}
+		} else if err != nil || got != graph.QuadStore(qs) {
+			t.Errorf("unexpected result for %q: %v", c.url, err)
+		}
+	}
+}
+
+func TestScopeHandle(t *testing.T) {
+	qs := memstore.New()
+	h := &graph.Handle{QuadStore: qs}
+	for _, c := range []struct {
+		url    string
+		scoped bool
+	}{
+		{url: "/api/v1/query/gizmo"},
+		{url: "/api/v1/query/gizmo?graph=<g1>,<g2>", scoped: true},
+	} {
+		r, err := http.NewRequest("POST", c.url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := scopeHandle(h, graph.Options{"HTTPRequest": r})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, ok := got.QuadStore.(*labels.QuadStore)
+		if ok != c.scoped {
+			t.Errorf("unexpected quad store for %q: %T", c.url, got.QuadStore)
+		} else if !c.scoped && got != h {
+			t.Errorf("expected the same handle for %q", c.url)
+		}
+	}
+}
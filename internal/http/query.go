@@ -15,19 +15,26 @@
 package http
 
 import (
+	"encoding/base64"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"hash/fnv"
 	"io/ioutil"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/julienschmidt/httprouter"
 	"golang.org/x/net/context"
 
+	"github.com/codelingo/cayley/graph"
 	"github.com/codelingo/cayley/query"
 )
 
 type SuccessQueryWrapper struct {
 	Result interface{} `json:"result"`
+	Cursor string      `json:"cursor,omitempty"`
 }
 
 type ErrorQueryWrapper struct {
@@ -63,6 +70,97 @@ func (api *API) contextForRequest(r *http.Request) (context.Context, func()) {
 	return ctx, cancel
 }
 
+// quadStoreAsOf returns a read-only view of the quad store at the horizon or RFC 3339 time
+// given in the as_of URL parameter. It returns the store itself if the parameter is not set.
+func quadStoreAsOf(qs graph.QuadStore, r *http.Request) (graph.QuadStore, error) {
+	s := r.URL.Query().Get("as_of")
+	if s == "" {
+		return qs, nil
+	}
+	if h, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return graph.AsOf(qs, h)
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid as_of value %q: expected a horizon or an RFC 3339 time", s)
+	}
+	return graph.AsOfTime(qs, t)
+}
+
+const (
+	// defaultQueryLimit is the maximal number of results returned by a query, or by a single page of results.
+	defaultQueryLimit = 100
+	// maxQueryPage is the maximal page size that can be requested with the limit parameter.
+	maxQueryPage = 1000
+	// maxQueryOffset is the maximal number of results on previous pages of a paginated query.
+	maxQueryOffset = 100000
+)
+
+// queryCursor is a position in results of a paginated query.
+//
+// Cursors are offset-based: the query is evaluated again for each page, and results
+// on previous pages are skipped. The cost of a page thus grows with the offset, which
+// is limited by maxQueryOffset.
+type queryCursor struct {
+	Query   uint64 `json:"q"`           // hash of the query language and text
+	Offset  int    `json:"o"`           // number of results on previous pages
+	Horizon int64  `json:"h,omitempty"` // horizon of the store view, if the store keeps the history
+}
+
+func queryHash(lang, code string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(lang))
+	h.Write([]byte{0})
+	h.Write([]byte(code))
+	return h.Sum64()
+}
+
+func (c queryCursor) String() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func parseQueryCursor(s string) (*queryCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+	var c queryCursor
+	if err = json.Unmarshal(data, &c); err != nil || c.Offset < 0 || c.Offset > maxQueryOffset {
+		return nil, errors.New("invalid cursor")
+	}
+	return &c, nil
+}
+
+// queryPage returns a page size and a cursor from the limit and cursor URL parameters.
+// The page size is zero if the results are not paginated, and is at most maxQueryPage.
+func queryPage(r *http.Request) (int, *queryCursor, error) {
+	q := r.URL.Query()
+	sl, sc := q.Get("limit"), q.Get("cursor")
+	if sl == "" && sc == "" {
+		return 0, nil, nil
+	}
+	page := defaultQueryLimit
+	if sl != "" {
+		n, err := strconv.Atoi(sl)
+		if err != nil || n <= 0 {
+			return 0, nil, fmt.Errorf("invalid limit value %q", sl)
+		}
+		page = n
+		if page > maxQueryPage {
+			page = maxQueryPage
+		}
+	}
+	if sc == "" {
+		return page, nil, nil
+	}
+	c, err := parseQueryCursor(sc)
+	if err != nil {
+		return 0, nil, err
+	}
+	return page, c, nil
+}
+
 func defaultErrorFunc(w query.ResponseWriter, err error) {
 	data, _ := json.Marshal(err.Error())
 	w.WriteHeader(http.StatusBadRequest)
@@ -89,21 +187,39 @@ func (api *API) ServeV1Query(w http.ResponseWriter, r *http.Request, params http
 		return 0
 	default:
 	}
+	page, cursor, err := queryPage(r)
+	if err != nil {
+		errFunc(w, err)
+		return 400
+	}
 	h, err := api.GetHandleForRequest(r)
 	if err != nil {
 		errFunc(w, err)
 		return 400
 	}
+	var qs graph.QuadStore
+	if cursor != nil && cursor.Horizon > 0 {
+		qs, err = graph.AsOf(h.QuadStore, cursor.Horizon)
+	} else {
+		qs, err = quadStoreAsOf(h.QuadStore, r)
+	}
+	if err != nil {
+		errFunc(w, err)
+		return 400
+	}
 	if l.HTTPQuery != nil {
+		if page > 0 {
+			errFunc(w, errors.New("Pagination is not supported for this query language."))
+			return 400
+		}
 		defer r.Body.Close()
-		l.HTTPQuery(ctx, h.QuadStore, w, r.Body)
+		l.HTTPQuery(ctx, qs, w, r.Body)
 		return 0
 	}
 	if l.HTTP == nil {
 		errFunc(w, errors.New("HTTP interface is not supported for this query language."))
 		return 400
 	}
-	ses := l.HTTP(h.QuadStore)
 	bodyBytes, err := ioutil.ReadAll(r.Body)
 	if err != nil {
 		errFunc(w, err)
@@ -111,9 +227,30 @@ func (api *API) ServeV1Query(w http.ResponseWriter, r *http.Request, params http
 	}
 	code := string(bodyBytes)
 
+	limit := defaultQueryLimit
+	if page > 0 {
+		hash := queryHash(params.ByName("query_lang"), code)
+		if cursor == nil {
+			// pin the first page to the current horizon, so the next pages see the same data
+			cursor = &queryCursor{Query: hash}
+			if hk := qs.Horizon(); hk.IsSequential() && hk.Int() > 0 {
+				if view, err := graph.AsOf(qs, hk.Int()); err == nil {
+					qs, cursor.Horizon = view, hk.Int()
+				}
+			}
+		} else if cursor.Query != hash {
+			errFunc(w, errors.New("cursor does not match the query"))
+			return 400
+		}
+		// one more result is requested to check if there is a next page
+		limit = cursor.Offset + page + 1
+	}
+	ses := l.HTTP(qs)
+
 	c := make(chan query.Result, 5)
-	go ses.Execute(ctx, code, c, 100)
+	go ses.Execute(ctx, code, c, limit)
 
+	n, more := 0, false
 	for res := range c {
 		if err := res.Err(); err != nil {
 			if err == nil {
@@ -122,6 +259,16 @@ func (api *API) ServeV1Query(w http.ResponseWriter, r *http.Request, params http
 			errFunc(w, err)
 			return 400
 		}
+		if page > 0 {
+			i := n
+			n++
+			if i < cursor.Offset {
+				continue
+			} else if i >= cursor.Offset+page {
+				more = true
+				continue
+			}
+		}
 		ses.Collate(res)
 	}
 	output, err := ses.Results()
@@ -129,7 +276,13 @@ func (api *API) ServeV1Query(w http.ResponseWriter, r *http.Request, params http
 		errFunc(w, err)
 		return 400
 	}
-	bytes, err := WrapResult(output)
+	wrap := SuccessQueryWrapper{Result: output}
+	if more && cursor.Offset+page <= maxQueryOffset {
+		next := *cursor
+		next.Offset += page
+		wrap.Cursor = next.String()
+	}
+	bytes, err := json.MarshalIndent(wrap, "", " ")
 	if err != nil {
 		errFunc(w, err)
 		return 400
@@ -0,0 +1,91 @@
+// Copyright 2017 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/stretchr/testify/require"
+
+	"github.com/codelingo/cayley/graph"
+	"github.com/codelingo/cayley/graph/graphtest"
+	"github.com/codelingo/cayley/graph/memstore"
+	"github.com/codelingo/cayley/internal/config"
+	"github.com/codelingo/cayley/quad"
+	_ "github.com/codelingo/cayley/query/gizmo"
+)
+
+func TestV1QueryPagination(t *testing.T) {
+	qs := memstore.New()
+	graphtest.MakeWriter(t, qs, nil,
+		quad.MakeIRI("e", "follows", "a", ""),
+		quad.MakeIRI("c", "follows", "d", ""),
+		quad.MakeIRI("b", "follows", "a", ""),
+	)
+	api := &API{config: &config.Config{}, handle: &graph.Handle{QuadStore: qs}}
+	r := httprouter.New()
+	api.APIv1(r)
+	do := func(code, params string) (out struct {
+		Result []string `json:"result"`
+		Cursor string   `json:"cursor"`
+		Error  string   `json:"error"`
+	}) {
+		req, err := http.NewRequest("POST", "/api/v1/query/gizmo?"+params, strings.NewReader(code))
+		require.NoError(t, err)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &out), w.Body.String())
+		return out
+	}
+	const code = `g.V().Order().ForEach(function(d) { g.Emit(d.id) })`
+	var (
+		got    []string
+		cursor string
+	)
+	for i := 0; ; i++ {
+		params := "limit=2"
+		if cursor != "" {
+			params += "&cursor=" + url.QueryEscape(cursor)
+		}
+		out := do(code, params)
+		require.Empty(t, out.Error)
+		require.True(t, len(out.Result) <= 2)
+		got = append(got, out.Result...)
+		if cursor = out.Cursor; cursor == "" {
+			require.Equal(t, 3, i+1, "unexpected number of pages")
+			break
+		}
+	}
+	require.Equal(t, []string{"<a>", "<b>", "<c>", "<d>", "<e>", "<follows>"}, got)
+
+	out := do(code, "limit=2")
+	require.NotEmpty(t, out.Cursor)
+	require.Contains(t, do(`g.V().All()`, "cursor="+url.QueryEscape(out.Cursor)).Error, "cursor")
+	require.NotEmpty(t, do(code, "limit=-1").Error)
+	require.NotEmpty(t, do(code, "cursor=bad").Error)
+
+	// page size and offset are limited
+	out = do(code, "limit=1000000000")
+	require.Empty(t, out.Error)
+	require.Len(t, out.Result, 6)
+	far := queryCursor{Query: queryHash("gizmo", code), Offset: maxQueryOffset + 1}
+	require.Contains(t, do(code, "cursor="+far.String()).Error, "cursor")
+}
@@ -0,0 +1,206 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"sort"
+
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/codelingo/cayley/clog"
+	"github.com/codelingo/cayley/graph"
+	"github.com/codelingo/cayley/quad"
+	"github.com/codelingo/cayley/query"
+	"github.com/codelingo/cayley/query/sparql"
+)
+
+const contentTypeSPARQLQuery = "application/sparql-query"
+
+// SPARQL registers handlers for SPARQL 1.1 Protocol query operation.
+func (api *API) SPARQL(r *httprouter.Router) {
+	r.GET("/sparql", CORS(LogRequest(api.ServeSPARQL)))
+	r.POST("/sparql", CORS(LogRequest(api.ServeSPARQL)))
+}
+
+// sortAccept sorts Accept header values by quality, preserving the order of values with equal quality.
+func sortAccept(specs []AcceptSpec) []AcceptSpec {
+	sort.Stable(byQuality(specs))
+	return specs
+}
+
+type byQuality []AcceptSpec
+
+func (a byQuality) Len() int           { return len(a) }
+func (a byQuality) Less(i, j int) bool { return a[i].Q > a[j].Q }
+func (a byQuality) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+
+func isAnyMime(v string) bool {
+	return v == "*/*" || v == "application/*"
+}
+
+// sparqlResultsFormat selects results format for SELECT and ASK queries based on Accept header.
+// It returns nil if none of the requested formats are supported.
+func sparqlResultsFormat(r *http.Request) *sparql.ResultsFormat {
+	specs := ParseAccept(r.Header, hdrAccept)
+	if len(specs) == 0 {
+		return sparql.ResultsFormatByName("json")
+	}
+	for _, s := range sortAccept(specs) {
+		if s.Q <= 0 {
+			continue
+		}
+		if isAnyMime(s.Value) {
+			return sparql.ResultsFormatByName("json")
+		}
+		if f := sparql.ResultsFormatByMime(s.Value); f != nil {
+			return f
+		}
+	}
+	return nil
+}
+
+// sparqlGraphFormat selects quad format for CONSTRUCT queries based on Accept header.
+// It returns nil if none of the requested formats are supported.
+func sparqlGraphFormat(r *http.Request) *quad.Format {
+	specs := ParseAccept(r.Header, hdrAccept)
+	if len(specs) == 0 {
+		return quad.FormatByName(defaultFormat)
+	}
+	for _, s := range sortAccept(specs) {
+		if s.Q <= 0 {
+			continue
+		}
+		if isAnyMime(s.Value) || s.Value == "text/*" {
+			return quad.FormatByName(defaultFormat)
+		}
+		if f := quad.FormatByMime(s.Value); f != nil && f.Writer != nil {
+			return f
+		}
+	}
+	return nil
+}
+
+// sparqlQueryFromRequest extracts query string from the request, as defined by SPARQL 1.1 Protocol.
+func sparqlQueryFromRequest(r *http.Request) (string, error) {
+	var qu string
+	switch r.Method {
+	case "GET":
+		qu = r.URL.Query().Get("query")
+	case "POST":
+		ct, _, err := mime.ParseMediaType(r.Header.Get(hdrContentType))
+		if err != nil {
+			return "", err
+		}
+		switch ct {
+		case contentTypeSPARQLQuery:
+			data, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				return "", err
+			}
+			qu = string(data)
+		case "application/x-www-form-urlencoded":
+			qu = r.PostFormValue("query")
+		default:
+			return "", fmt.Errorf("unsupported content type: %q", ct)
+		}
+	default:
+		return "", fmt.Errorf("unsupported method: %s", r.Method)
+	}
+	if qu == "" {
+		return "", errors.New("query is not specified")
+	}
+	if r.FormValue("default-graph-uri") != "" || r.FormValue("named-graph-uri") != "" {
+		return "", errors.New("RDF dataset specification is not supported")
+	}
+	return qu, nil
+}
+
+// ServeSPARQL runs a query sent according to SPARQL 1.1 Protocol.
+//
+// Results of SELECT and ASK queries are written in one of SPARQL results formats (JSON, XML, CSV or TSV),
+// and results of CONSTRUCT queries are written in one of registered quad formats.
+func (api *API) ServeSPARQL(w http.ResponseWriter, r *http.Request, _ httprouter.Params) int {
+	defer r.Body.Close()
+	qu, err := sparqlQueryFromRequest(r)
+	if err != nil {
+		return jsonResponse(w, http.StatusBadRequest, err)
+	}
+	q, err := sparql.Parse(qu)
+	if err == query.ErrParseMore {
+		return jsonResponse(w, http.StatusBadRequest, "Incomplete query.")
+	} else if err != nil {
+		return jsonResponse(w, http.StatusBadRequest, err)
+	}
+	h, err := api.GetHandleForRequest(r)
+	if err != nil {
+		return jsonResponse(w, http.StatusBadRequest, err)
+	}
+	ctx, cancel := api.contextForRequest(r)
+	defer cancel()
+
+	var (
+		gformat *quad.Format
+		rformat *sparql.ResultsFormat
+	)
+	if q.Type == sparql.Construct {
+		gformat = sparqlGraphFormat(r)
+	} else {
+		rformat = sparqlResultsFormat(r)
+	}
+	if gformat == nil && rformat == nil {
+		return jsonResponse(w, http.StatusNotAcceptable, "Requested format is not supported.")
+	}
+
+	wr := writerFrom(w, r, hdrAcceptEncoding)
+	defer wr.Close()
+	cw := &checkWriter{w: wr}
+
+	if gformat != nil {
+		if len(gformat.Mime) != 0 {
+			w.Header().Set(hdrContentType, gformat.Mime[0])
+		}
+		qw := gformat.Writer(cw)
+		err = sparql.Run(ctx, h.QuadStore, q, -1, func(res query.Result) error {
+			return qw.WriteQuad(res.Result().(quad.Quad))
+		})
+		if cerr := qw.Close(); err == nil {
+			err = cerr
+		}
+	} else {
+		w.Header().Set(hdrContentType, rformat.Mime[0])
+		rw := rformat.Writer(cw)
+		if q.Type == sparql.Select {
+			err = rw.WriteHead(q.Variables())
+		}
+		if err == nil {
+			err = sparql.Run(ctx, h.QuadStore, q, -1, func(res query.Result) error {
+				switch v := res.Result().(type) {
+				case bool:
+					return rw.WriteBool(v)
+				case map[string]graph.Value:
+					row := make(map[string]quad.Value, len(v))
+					for k, gv := range v {
+						row[k] = h.QuadStore.NameOf(gv)
+					}
+					return rw.WriteRow(row)
+				}
+				return nil
+			})
+		}
+		if err == nil {
+			// small results are buffered, so an error can still be reported to the client
+			err = rw.Close()
+		}
+	}
+	if err != nil && !cw.written {
+		return jsonResponse(w, http.StatusInternalServerError, err)
+	} else if err != nil {
+		// headers were already sent
+		clog.Errorf("sparql query error: %v", err)
+		return 500
+	}
+	return 200
+}
@@ -0,0 +1,121 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/codelingo/cayley/graph"
+	_ "github.com/codelingo/cayley/graph/memstore"
+	"github.com/codelingo/cayley/internal/config"
+	"github.com/codelingo/cayley/quad"
+	_ "github.com/codelingo/cayley/quad/nquads"
+	_ "github.com/codelingo/cayley/writer"
+)
+
+func makeSPARQLTestAPI(t *testing.T) *API {
+	qs, err := graph.NewQuadStore("memstore", "", nil)
+	require.NoError(t, err)
+	qw, err := graph.NewQuadWriter("single", qs, nil)
+	require.NoError(t, err)
+	err = qw.AddQuadSet([]quad.Quad{
+		quad.MakeIRI("alice", "follows", "bob", ""),
+		quad.MakeIRI("bob", "follows", "fred", ""),
+	})
+	require.NoError(t, err)
+	return &API{config: &config.Config{}, handle: &graph.Handle{QuadStore: qs, QuadWriter: qw}}
+}
+
+var sparqlProtocolTests = []struct {
+	message string
+	method  string
+	ctype   string
+	body    string
+	accept  string
+	code    int
+	rtype   string
+	expect  string
+}{
+	{
+		message: "select via get",
+		method:  "GET", body: `SELECT ?x WHERE { ?x <follows> <bob> }`,
+		code: 200, rtype: "application/sparql-results+json",
+		expect: `{"head":{"vars":["x"]},"results":{"bindings":[{"x":{"type":"uri","value":"alice"}}]}}` + "\n",
+	},
+	{
+		message: "select via post",
+		method:  "POST", ctype: contentTypeSPARQLQuery, body: `SELECT ?x WHERE { ?x <follows> <bob> }`,
+		accept: "text/csv",
+		code:   200, rtype: "text/csv",
+		expect: "x\nalice\n",
+	},
+	{
+		message: "select via form",
+		method:  "POST", ctype: "application/x-www-form-urlencoded", body: `SELECT ?x WHERE { ?x <follows> <bob> }`,
+		accept: "text/csv;q=0.5, text/tab-separated-values",
+		code:   200, rtype: "text/tab-separated-values",
+		expect: "?x\n<alice>\n",
+	},
+	{
+		message: "ask in xml",
+		method:  "GET", body: `ASK { <alice> <follows> <fred> }`,
+		accept: "application/sparql-results+xml",
+		code:   200, rtype: "application/sparql-results+xml",
+		expect: `<?xml version="1.0" encoding="UTF-8"?>` + "\n" +
+			`<sparql xmlns="http://www.w3.org/2005/sparql-results#">` + "\n<head/>\n<boolean>false</boolean>\n</sparql>\n",
+	},
+	{
+		message: "construct",
+		method:  "GET", body: `CONSTRUCT { ?y <followed_by> ?x } WHERE { ?x <follows> ?y . ?y <follows> <fred> }`,
+		code: 200, rtype: "application/n-quads",
+		expect: "<bob> <followed_by> <alice> .\n",
+	},
+	{
+		message: "unsupported format",
+		method:  "GET", body: `SELECT ?x WHERE { ?x <follows> <bob> }`,
+		accept: "image/png",
+		code:   http.StatusNotAcceptable,
+	},
+	{
+		message: "invalid query",
+		method:  "GET", body: `SELECT ?x WHERE { ?x <follows> <bob> `,
+		code: http.StatusBadRequest,
+	},
+}
+
+func TestServeSPARQL(t *testing.T) {
+	api := makeSPARQLTestAPI(t)
+	for _, c := range sparqlProtocolTests {
+		var (
+			r   *http.Request
+			err error
+		)
+		switch {
+		case c.method == "GET":
+			r, err = http.NewRequest("GET", "/sparql?"+url.Values{"query": {c.body}}.Encode(), strings.NewReader(""))
+		case c.ctype == contentTypeSPARQLQuery:
+			r, err = http.NewRequest("POST", "/sparql", strings.NewReader(c.body))
+		default:
+			r, err = http.NewRequest("POST", "/sparql", strings.NewReader(url.Values{"query": {c.body}}.Encode()))
+		}
+		require.NoError(t, err)
+		if c.ctype != "" {
+			r.Header.Set(hdrContentType, c.ctype)
+		}
+		if c.accept != "" {
+			r.Header.Set(hdrAccept, c.accept)
+		}
+		w := httptest.NewRecorder()
+		code := api.ServeSPARQL(w, r, nil)
+		require.Equal(t, c.code, code, "%s: %s", c.message, w.Body.String())
+		if c.code != 200 {
+			continue
+		}
+		require.Equal(t, c.rtype, w.Header().Get(hdrContentType), c.message)
+		require.Equal(t, c.expect, w.Body.String(), c.message)
+	}
+}
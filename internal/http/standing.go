@@ -0,0 +1,107 @@
+// Copyright 2017 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package http
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"golang.org/x/net/context"
+
+	"github.com/codelingo/cayley/query"
+)
+
+// ServeV2Watch registers a standing query and streams changes of its results as Server-Sent Events.
+//
+// Query is read from the "query" parameter of GET request, or from the body of POST request.
+// The first event contains all current results, and each following event contains rows
+// that were added or removed by writes to the graph.
+func (api *API) ServeV2Watch(w http.ResponseWriter, r *http.Request, params httprouter.Params) int {
+	lang := params.ByName("query_lang")
+	if query.GetLanguage(lang) == nil {
+		return jsonResponse(w, http.StatusBadRequest, "Unknown query language.")
+	}
+	h, err := api.GetHandleForRequest(r)
+	if err != nil {
+		return jsonResponse(w, http.StatusBadRequest, err)
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return jsonResponse(w, http.StatusInternalServerError, "streaming is not supported")
+	}
+	qu := r.URL.Query().Get("query")
+	if r.Method == "POST" {
+		data, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			return jsonResponse(w, http.StatusBadRequest, err)
+		}
+		qu = string(data)
+	}
+	if qu == "" {
+		return jsonResponse(w, http.StatusBadRequest, "query is not set")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	diffs := make(chan query.Diff)
+	errc := make(chan error, 1)
+	go func() {
+		errc <- query.Standing(ctx, h.QuadStore, lang, qu, diffs)
+	}()
+	// wait for the first evaluation to report query errors with a proper status code
+	var d query.Diff
+	select {
+	case d = <-diffs:
+	case err := <-errc:
+		return jsonResponse(w, http.StatusBadRequest, err)
+	}
+
+	var gone <-chan bool
+	if cn, ok := w.(http.CloseNotifier); ok {
+		gone = cn.CloseNotify()
+	}
+	w.Header().Set(hdrContentType, "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	ticker := time.NewTicker(changesKeepAlive)
+	defer ticker.Stop()
+	for {
+		if err := writeEvent(w, "", "diff", d); err != nil {
+			return http.StatusOK
+		}
+		flusher.Flush()
+	wait:
+		for {
+			select {
+			case d = <-diffs:
+				break wait
+			case err := <-errc:
+				writeEvent(w, "", "error", ErrorQueryWrapper{Error: err.Error()})
+				return http.StatusOK
+			case <-ticker.C:
+				if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+					return http.StatusOK
+				}
+				flusher.Flush()
+			case <-gone:
+				return http.StatusOK
+			}
+		}
+	}
+}
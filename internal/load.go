@@ -11,6 +11,7 @@ import (
 	"github.com/codelingo/cayley/clog"
 	"github.com/codelingo/cayley/graph"
 	"github.com/codelingo/cayley/quad"
+	"github.com/codelingo/cayley/quad/csv"
 	"github.com/codelingo/cayley/quad/nquads"
 )
 
@@ -24,11 +25,68 @@ func Load(qw graph.QuadWriter, batch int, path, typ string) error {
 // it, and then call the given load function to process the decompressed graph.
 // If no loadFn is provided, db.Load is called.
 func DecompressAndLoad(qw graph.QuadWriter, batch int, path, typ string, writerFunc func(graph.QuadWriter) graph.BatchWriter) error {
-	var r io.Reader
+	if path == "" {
+		return nil
+	}
+	r, c, err := open(path)
+	if err == io.EOF {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	var qr quad.Reader
+	switch typ {
+	case "cquad":
+		qr = nquads.NewReader(r, false)
+	case "nquad":
+		qr = nquads.NewReader(r, true)
+	default:
+		rf := quad.FormatByName(typ)
+		if rf == nil {
+			return fmt.Errorf("unknown quad format %q", typ)
+		} else if rf.Reader == nil {
+			return fmt.Errorf("decoding of %q is not supported", typ)
+		}
+		qr = rf.Reader(r)
+	}
+	return load(qw, batch, qr, writerFunc)
+}
 
+// LoadTable loads tabular data from the given path, converts rows to quads according
+// to the mapping and writes them to qw. Type of the data must be either "csv" or "tsv".
+func LoadTable(qw graph.QuadWriter, batch int, path, typ string, m *csv.Mapping) error {
 	if path == "" {
 		return nil
 	}
+	r, c, err := open(path)
+	if err == io.EOF {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	var qr quad.Reader
+	switch typ {
+	case "csv":
+		qr = csv.NewReader(r, m)
+	case "tsv":
+		qr = csv.NewTSVReader(r, m)
+	default:
+		return fmt.Errorf("unknown table format %q", typ)
+	}
+	return load(qw, batch, qr, nil)
+}
+
+// open opens a file or fetches a resource from the given path and decompresses it.
+// It returns io.EOF if the data is empty.
+func open(path string) (io.Reader, io.Closer, error) {
+	var (
+		r io.Reader
+		c io.Closer
+	)
 	u, err := url.Parse(path)
 	if err != nil || u.Scheme == "file" || u.Scheme == "" {
 		// Don't alter relative URL path or non-URL path parameter.
@@ -38,49 +96,33 @@ func DecompressAndLoad(qw graph.QuadWriter, batch int, path, typ string, writerF
 		}
 		f, err := os.Open(path)
 		if err != nil {
-			return fmt.Errorf("could not open file %q: %v", path, err)
+			return nil, nil, fmt.Errorf("could not open file %q: %v", path, err)
 		}
-		defer f.Close()
-		r = f
+		r, c = f, f
 	} else {
 		res, err := http.Get(path)
 		if err != nil {
-			return fmt.Errorf("could not get resource <%s>: %v", u, err)
+			return nil, nil, fmt.Errorf("could not get resource <%s>: %v", u, err)
 		}
-		defer res.Body.Close()
-		r = res.Body
+		r, c = res.Body, res.Body
 	}
 
 	r, err = Decompressor(r)
 	if err != nil {
-		if err == io.EOF {
-			return nil
-		}
-		return err
-	}
-
-	var qr quad.Reader
-	switch typ {
-	case "cquad":
-		qr = nquads.NewReader(r, false)
-	case "nquad":
-		qr = nquads.NewReader(r, true)
-	default:
-		rf := quad.FormatByName(typ)
-		if rf == nil {
-			return fmt.Errorf("unknown quad format %q", typ)
-		} else if rf.Reader == nil {
-			return fmt.Errorf("decoding of %q is not supported", typ)
-		}
-		qr = rf.Reader(r)
+		c.Close()
+		return nil, nil, err
 	}
+	return r, c, nil
+}
 
+// load copies quads from qr to qw in batches.
+func load(qw graph.QuadWriter, batch int, qr quad.Reader, writerFunc func(graph.QuadWriter) graph.BatchWriter) error {
 	if writerFunc == nil {
 		writerFunc = graph.NewWriter
 	}
 	dest := writerFunc(qw)
 
-	_, err = quad.CopyBatch(&batchLogger{BatchWriter: dest}, qr, batch)
+	_, err := quad.CopyBatch(&batchLogger{BatchWriter: dest}, qr, batch)
 	if err != nil {
 		return fmt.Errorf("db: failed to load data: %v", err)
 	}
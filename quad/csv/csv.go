@@ -0,0 +1,170 @@
+// Package csv converts tabular data (CSV and TSV) to quads, according to a mapping specification.
+//
+// Mapping is similar in spirit to R2RML and CSV on the Web: it defines a subject IRI template
+// for each row and a predicate and a value type for each column. See Mapping for details.
+package csv
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/codelingo/cayley/quad"
+	"github.com/codelingo/cayley/voc/rdf"
+)
+
+var rdfType = quad.IRI(rdf.NS + "type")
+
+// NewReader returns a reader that converts comma-separated values from r to quads.
+func NewReader(r io.Reader, m *Mapping) *Reader {
+	return newReader(r, m, ',')
+}
+
+// NewTSVReader returns a reader that converts tab-separated values from r to quads.
+func NewTSVReader(r io.Reader, m *Mapping) *Reader {
+	cr := newReader(r, m, '\t')
+	cr.r.LazyQuotes = true
+	return cr
+}
+
+func newReader(r io.Reader, m *Mapping, delim rune) *Reader {
+	if m.Delimiter != "" {
+		delim = []rune(m.Delimiter)[0]
+	}
+	cr := csv.NewReader(r)
+	cr.Comma = delim
+	cr.FieldsPerRecord = -1
+	return &Reader{r: cr, m: m}
+}
+
+// Reader reads rows of the table and converts them to quads.
+//
+// Rows are processed one at a time, thus the whole table is never loaded into memory.
+type Reader struct {
+	r   *csv.Reader
+	m   *Mapping
+	err error
+
+	started bool
+	row     int
+	cols    map[string]int // column indexes by name
+	subj    template
+	columns []column
+	label   quad.Value
+	buf     []quad.Quad
+}
+
+// column is a compiled column mapping.
+type column struct {
+	Column
+	index int
+	tmpl  template
+}
+
+// checkColumns verifies that all columns referenced by the template are present in the table.
+func (r *Reader) checkColumns(t template) error {
+	for i := 1; i < len(t); i += 2 {
+		if _, ok := r.cols[t[i]]; !ok {
+			return fmt.Errorf("csv: column %q is not found", t[i])
+		}
+	}
+	return nil
+}
+
+// start compiles the mapping against column names of the table.
+func (r *Reader) start(header []string) error {
+	if err := r.m.Validate(); err != nil {
+		return err
+	}
+	r.cols = make(map[string]int, len(header))
+	for i, name := range header {
+		if r.m.NoHeader {
+			name = strconv.Itoa(i + 1)
+		}
+		r.cols[name] = i
+	}
+	r.subj, _ = parseTemplate(r.m.Subject)
+	if err := r.checkColumns(r.subj); err != nil {
+		return err
+	}
+	for _, c := range r.m.Columns {
+		i, ok := r.cols[c.Name]
+		if !ok {
+			return fmt.Errorf("csv: column %q is not found", c.Name)
+		}
+		col := column{Column: c, index: i}
+		if c.Template != "" {
+			col.tmpl, _ = parseTemplate(c.Template)
+			if err := r.checkColumns(col.tmpl); err != nil {
+				return err
+			}
+		}
+		r.columns = append(r.columns, col)
+	}
+	if r.m.Label != "" {
+		r.label = quad.IRI(r.m.Label)
+	}
+	return nil
+}
+
+func (r *Reader) emit(s, p, o quad.Value) {
+	r.buf = append(r.buf, quad.Quad{Subject: s, Predicate: p, Object: o, Label: r.label})
+}
+
+// readRow reads the next row of the table and converts it to quads.
+func (r *Reader) readRow() error {
+	row, err := r.r.Read()
+	if err != nil {
+		return err
+	}
+	r.row++
+	if !r.started {
+		r.started = true
+		if err = r.start(row); err != nil {
+			return err
+		} else if !r.m.NoHeader {
+			return nil
+		}
+	}
+	for len(row) < len(r.cols) {
+		// missing trailing values
+		row = append(row, "")
+	}
+	s, ok := r.subj.expand(row, r.cols)
+	if !ok {
+		// subject cannot be built for this row
+		return nil
+	}
+	for _, t := range r.m.Types {
+		r.emit(s, rdfType, quad.IRI(t))
+	}
+	for i := range r.columns {
+		c := &r.columns[i]
+		if row[c.index] == "" {
+			continue
+		}
+		v, err := c.value(row, r.cols)
+		if err != nil {
+			return fmt.Errorf("csv: row %d, column %q: %v", r.row, c.Name, err)
+		} else if v != nil {
+			r.emit(s, quad.IRI(c.Predicate), v)
+		}
+	}
+	return nil
+}
+
+// ReadQuad returns the next quad converted from the table, or an error.
+func (r *Reader) ReadQuad() (quad.Quad, error) {
+	for len(r.buf) == 0 {
+		if r.err != nil {
+			return quad.Quad{}, r.err
+		}
+		r.err = r.readRow()
+	}
+	q := r.buf[0]
+	r.buf = r.buf[1:]
+	return q, nil
+}
+
+func (r *Reader) Close() error { return nil }
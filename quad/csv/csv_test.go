@@ -0,0 +1,104 @@
+package csv
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/codelingo/cayley/quad"
+)
+
+const testMapping = `{
+	"subject": "http://example.org/person/{id}",
+	"types": ["http://schema.org/Person"],
+	"columns": [
+		{"name": "name", "predicate": "http://schema.org/name", "lang": "en"},
+		{"name": "age", "predicate": "http://schema.org/age", "type": "int"},
+		{"name": "height", "predicate": "http://schema.org/height", "type": "float"},
+		{"name": "born", "predicate": "http://schema.org/birthDate", "type": "time", "format": "2006-01-02"},
+		{"name": "employer", "predicate": "http://schema.org/worksFor", "type": "iri", "template": "http://example.org/org/{employer}"},
+		{"name": "code", "predicate": "http://example.org/code", "type": "http://example.org/Code"}
+	]
+}`
+
+func TestReader(t *testing.T) {
+	m, err := ReadMapping(strings.NewReader(testMapping))
+	require.NoError(t, err)
+
+	const data = `id,name,age,height,born,employer,code
+1,Bob,42,1.8,1975-04-01,Acme Inc,x1
+2,Alice,,,,,
+,Nobody,1,,,,
+`
+	quads, err := quad.ReadAll(NewReader(strings.NewReader(data), m))
+	require.NoError(t, err)
+
+	born, _ := time.Parse("2006-01-02", "1975-04-01")
+	p1, p2 := quad.IRI("http://example.org/person/1"), quad.IRI("http://example.org/person/2")
+	typ := quad.IRI("http://www.w3.org/1999/02/22-rdf-syntax-ns#type")
+	require.Equal(t, []quad.Quad{
+		{Subject: p1, Predicate: typ, Object: quad.IRI("http://schema.org/Person")},
+		{Subject: p1, Predicate: quad.IRI("http://schema.org/name"), Object: quad.LangString{Value: "Bob", Lang: "en"}},
+		{Subject: p1, Predicate: quad.IRI("http://schema.org/age"), Object: quad.Int(42)},
+		{Subject: p1, Predicate: quad.IRI("http://schema.org/height"), Object: quad.Float(1.8)},
+		{Subject: p1, Predicate: quad.IRI("http://schema.org/birthDate"), Object: quad.Time(born)},
+		{Subject: p1, Predicate: quad.IRI("http://schema.org/worksFor"), Object: quad.IRI("http://example.org/org/Acme%20Inc")},
+		{Subject: p1, Predicate: quad.IRI("http://example.org/code"), Object: quad.TypedString{Value: "x1", Type: "http://example.org/Code"}},
+		{Subject: p2, Predicate: typ, Object: quad.IRI("http://schema.org/Person")},
+		{Subject: p2, Predicate: quad.IRI("http://schema.org/name"), Object: quad.LangString{Value: "Alice", Lang: "en"}},
+	}, quads)
+}
+
+func TestTSVReader(t *testing.T) {
+	m := &Mapping{
+		NoHeader: true,
+		Subject:  "{1}",
+		Label:    "http://example.org/graph",
+		Columns: []Column{
+			{Name: "2", Predicate: "knows", Type: TypeIRI},
+		},
+	}
+	quads, err := quad.ReadAll(NewTSVReader(strings.NewReader("alice\tbob\nbob\tfred\n"), m))
+	require.NoError(t, err)
+	require.Equal(t, []quad.Quad{
+		quad.MakeIRI("alice", "knows", "bob", "http://example.org/graph"),
+		quad.MakeIRI("bob", "knows", "fred", "http://example.org/graph"),
+	}, quads)
+}
+
+var testMappingErrors = []struct {
+	message string
+	mapping Mapping
+	data    string
+}{
+	{
+		message: "no subject",
+		mapping: Mapping{},
+		data:    "a\n1\n",
+	},
+	{
+		message: "unknown subject column",
+		mapping: Mapping{Subject: "{b}"},
+		data:    "a\n1\n",
+	},
+	{
+		message: "unknown column",
+		mapping: Mapping{Subject: "{a}", Columns: []Column{{Name: "b", Predicate: "p"}}},
+		data:    "a\n1\n",
+	},
+	{
+		message: "invalid value",
+		mapping: Mapping{Subject: "{a}", Columns: []Column{{Name: "a", Predicate: "p", Type: TypeInt}}},
+		data:    "a\nx\n",
+	},
+}
+
+func TestReaderErrors(t *testing.T) {
+	for _, c := range testMappingErrors {
+		m := c.mapping
+		_, err := quad.ReadAll(NewReader(strings.NewReader(c.data), &m))
+		require.Error(t, err, c.message)
+	}
+}
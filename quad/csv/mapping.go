@@ -0,0 +1,219 @@
+package csv
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/codelingo/cayley/quad"
+)
+
+// Column types supported by the mapping. Any other type is treated as a datatype IRI.
+const (
+	TypeString = "string"
+	TypeInt    = "int"
+	TypeFloat  = "float"
+	TypeBool   = "bool"
+	TypeTime   = "time"
+	TypeIRI    = "iri"
+)
+
+// Mapping describes how rows of a table are converted to quads.
+//
+// Each row produces a single subject from the Subject template and a quad
+// for each non-empty mapped column. For example, mapping
+//
+//	{
+//		"subject": "http://example.org/person/{id}",
+//		"types": ["http://schema.org/Person"],
+//		"columns": [
+//			{"name": "name", "predicate": "http://schema.org/name"},
+//			{"name": "age", "predicate": "http://schema.org/age", "type": "int"},
+//			{"name": "employer", "predicate": "http://schema.org/worksFor", "type": "iri", "template": "http://example.org/org/{employer}"}
+//		]
+//	}
+//
+// will convert a row "1,Bob,42,acme" of a table with "id,name,age,employer" header
+// into four quads with <http://example.org/person/1> as a subject.
+type Mapping struct {
+	// Delimiter is a field delimiter. Defaults to comma for CSV and to tab for TSV.
+	Delimiter string `json:"delimiter,omitempty"`
+	// NoHeader must be set if the first row of the table is not a header.
+	// Columns are referenced by their 1-based index in this case.
+	NoHeader bool `json:"no_header,omitempty"`
+	// Subject is an IRI template for row subject. Column values are referenced as {name}.
+	Subject string `json:"subject"`
+	// Types is a list of class IRIs that will be set as rdf:type for each subject.
+	Types []string `json:"types,omitempty"`
+	// Label is an optional IRI of a graph label for all quads.
+	Label string `json:"label,omitempty"`
+	// Columns lists mapped columns. Columns not listed here are ignored.
+	Columns []Column `json:"columns"`
+}
+
+// Column describes how a value of table column is converted to a quad.
+type Column struct {
+	// Name is a column name from the header, or its 1-based index if table has no header.
+	Name string `json:"name"`
+	// Predicate is an IRI of the predicate.
+	Predicate string `json:"predicate"`
+	// Type of the value. One of "string" (default), "int", "float", "bool", "time",
+	// "iri" or a datatype IRI for typed strings.
+	Type string `json:"type,omitempty"`
+	// Lang is a language tag for string values.
+	Lang string `json:"lang,omitempty"`
+	// Template is an IRI template for "iri" values. Defaults to the column value.
+	Template string `json:"template,omitempty"`
+	// Format is a time layout (as in time package) for "time" values. Defaults to RFC 3339.
+	Format string `json:"format,omitempty"`
+}
+
+// ReadMapping decodes JSON mapping specification from r.
+func ReadMapping(r io.Reader) (*Mapping, error) {
+	var m Mapping
+	if err := json.NewDecoder(r).Decode(&m); err != nil {
+		return nil, err
+	}
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// Validate checks if mapping specification is complete.
+func (m *Mapping) Validate() error {
+	if m.Subject == "" {
+		return errors.New("csv: subject template is not set")
+	} else if _, err := parseTemplate(m.Subject); err != nil {
+		return err
+	}
+	if len([]rune(m.Delimiter)) > 1 {
+		return fmt.Errorf("csv: delimiter must be a single character: %q", m.Delimiter)
+	}
+	for i, c := range m.Columns {
+		if c.Name == "" {
+			return fmt.Errorf("csv: name is not set for column %d", i+1)
+		} else if c.Predicate == "" {
+			return fmt.Errorf("csv: predicate is not set for column %q", c.Name)
+		}
+		if c.Template != "" {
+			if _, err := parseTemplate(c.Template); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// template is a compiled IRI template. Odd elements are column names.
+type template []string
+
+func parseTemplate(s string) (template, error) {
+	var t template
+	for {
+		i := strings.IndexByte(s, '{')
+		if i < 0 {
+			if strings.IndexByte(s, '}') >= 0 {
+				return nil, fmt.Errorf("csv: unexpected '}' in template")
+			}
+			return append(t, s), nil
+		}
+		j := strings.IndexByte(s[i:], '}')
+		if j < 0 {
+			return nil, fmt.Errorf("csv: unterminated column reference in template")
+		}
+		name := s[i+1 : i+j]
+		if name == "" {
+			return nil, fmt.Errorf("csv: empty column reference in template")
+		}
+		t = append(t, s[:i], name)
+		s = s[i+j+1:]
+	}
+}
+
+// escapeIRI percent-encodes all characters except unreserved ones, as defined by RFC 3986.
+func escapeIRI(s string) string {
+	const hex = "0123456789ABCDEF"
+	buf := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') ||
+			c == '-' || c == '.' || c == '_' || c == '~' {
+			buf = append(buf, c)
+		} else {
+			buf = append(buf, '%', hex[c>>4], hex[c&15])
+		}
+	}
+	return string(buf)
+}
+
+// expand fills a template with escaped values of the row. It returns false if any of referenced values is empty.
+func (t template) expand(row []string, cols map[string]int) (quad.IRI, bool) {
+	var buf []byte
+	for i, s := range t {
+		if i%2 == 0 {
+			buf = append(buf, s...)
+			continue
+		}
+		v := row[cols[s]]
+		if v == "" {
+			return "", false
+		}
+		buf = append(buf, escapeIRI(v)...)
+	}
+	return quad.IRI(buf), true
+}
+
+// value converts a cell of a table into a quad value.
+func (c *column) value(row []string, cols map[string]int) (quad.Value, error) {
+	s := row[c.index]
+	switch c.Type {
+	case "", TypeString:
+		if c.Lang != "" {
+			return quad.LangString{Value: quad.String(s), Lang: c.Lang}, nil
+		}
+		return quad.String(s), nil
+	case TypeInt:
+		v, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		return quad.Int(v), nil
+	case TypeFloat:
+		v, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		if err != nil {
+			return nil, err
+		}
+		return quad.Float(v), nil
+	case TypeBool:
+		v, err := strconv.ParseBool(strings.TrimSpace(s))
+		if err != nil {
+			return nil, err
+		}
+		return quad.Bool(v), nil
+	case TypeTime:
+		layout := c.Format
+		if layout == "" {
+			layout = time.RFC3339
+		}
+		v, err := time.Parse(layout, strings.TrimSpace(s))
+		if err != nil {
+			return nil, err
+		}
+		return quad.Time(v), nil
+	case TypeIRI:
+		if c.tmpl == nil {
+			return quad.IRI(s), nil
+		}
+		v, ok := c.tmpl.expand(row, cols)
+		if !ok {
+			return nil, nil
+		}
+		return v, nil
+	}
+	return quad.TypedString{Value: quad.String(s), Type: quad.IRI(c.Type)}, nil
+}
@@ -0,0 +1,269 @@
+package quad
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/codelingo/cayley/voc/geo"
+)
+
+const defaultGeoType IRI = geo.WKTLiteral
+
+// crs84 is the default coordinate reference system of WKT literals: WGS84 longitude and latitude.
+const crs84 = `<http://www.opengis.net/def/crs/OGC/1.3/CRS84>`
+
+// Geometry types of WKT literals.
+const (
+	GeoPoint           = "POINT"
+	GeoMultiPoint      = "MULTIPOINT"
+	GeoLineString      = "LINESTRING"
+	GeoMultiLineString = "MULTILINESTRING"
+	GeoPolygon         = "POLYGON"
+)
+
+func init() {
+	RegisterStringConversion(defaultGeoType, stringToGeo)
+}
+
+func stringToGeo(s string) (Value, error) {
+	g, err := ParseWKT(s)
+	if err != nil {
+		return nil, err
+	}
+	return g.Value(), nil
+}
+
+// Point is a native wrapper for a geographic point, with longitude and latitude in degrees (WGS84).
+//
+// It uses NQuad notation similar to TypedString, with geo:wktLiteral type.
+type Point struct {
+	Lon, Lat float64
+}
+
+func (s Point) String() string {
+	return s.TypedString().String()
+}
+func (s Point) Native() interface{} { return s }
+func (s Point) TypedString() TypedString {
+	return TypedString{
+		Value: String(Geometry{Type: GeoPoint, Parts: [][]Point{{s}}}.WKT()),
+		Type:  defaultGeoType,
+	}
+}
+
+// WKT is a geometry in Well-Known Text notation (ex: LINESTRING(30 10, 10 30)).
+// Single points are represented by Point values instead.
+//
+// It uses NQuad notation similar to TypedString, with geo:wktLiteral type.
+type WKT string
+
+func (s WKT) String() string {
+	return s.TypedString().String()
+}
+func (s WKT) Native() interface{} { return s }
+func (s WKT) TypedString() TypedString {
+	return TypedString{
+		Value: String(s),
+		Type:  defaultGeoType,
+	}
+}
+
+// Geometry parses the geometry.
+func (s WKT) Geometry() (Geometry, error) {
+	return ParseWKT(string(s))
+}
+
+// Geometry is a parsed WKT geometry.
+type Geometry struct {
+	Type  string    // one of Geo* constants
+	Parts [][]Point // single points, lines or polygon rings, depending on the type
+}
+
+// Points returns all vertices of the geometry.
+func (g Geometry) Points() []Point {
+	var out []Point
+	for _, p := range g.Parts {
+		out = append(out, p...)
+	}
+	return out
+}
+
+// Value returns a Point value for a single point, and a WKT value for other geometries.
+func (g Geometry) Value() Value {
+	if g.Type == GeoPoint {
+		return g.Parts[0][0]
+	}
+	return WKT(g.WKT())
+}
+
+// WKT returns the geometry in Well-Known Text notation.
+func (g Geometry) WKT() string {
+	buf := &bytes.Buffer{}
+	buf.WriteString(g.Type)
+	switch g.Type {
+	case GeoPoint, GeoLineString:
+		writeWKTPoints(buf, g.Parts[0])
+	default:
+		buf.WriteByte('(')
+		for i, p := range g.Parts {
+			if i > 0 {
+				buf.WriteString(", ")
+			}
+			writeWKTPoints(buf, p)
+		}
+		buf.WriteByte(')')
+	}
+	return buf.String()
+}
+
+func writeWKTPoints(buf *bytes.Buffer, pts []Point) {
+	buf.WriteByte('(')
+	for i, p := range pts {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		buf.WriteString(strconv.FormatFloat(p.Lon, 'f', -1, 64))
+		buf.WriteByte(' ')
+		buf.WriteString(strconv.FormatFloat(p.Lat, 'f', -1, 64))
+	}
+	buf.WriteByte(')')
+}
+
+var errEmptyGeometry = errors.New("wkt: empty geometry")
+
+// ParseWKT parses a geometry in Well-Known Text notation. Only two-dimensional geometries
+// with WGS84 coordinates (longitude first) of types POINT, MULTIPOINT, LINESTRING,
+// MULTILINESTRING and POLYGON are supported.
+func ParseWKT(s string) (Geometry, error) {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, crs84) {
+		s = strings.TrimSpace(s[len(crs84):])
+	} else if strings.HasPrefix(s, "<") {
+		return Geometry{}, fmt.Errorf("wkt: unsupported coordinate system in %q", s)
+	}
+	i := strings.IndexByte(s, '(')
+	if i < 0 || s[len(s)-1] != ')' {
+		if strings.HasSuffix(strings.ToUpper(s), "EMPTY") {
+			return Geometry{}, errEmptyGeometry
+		}
+		return Geometry{}, fmt.Errorf("wkt: invalid geometry %q", s)
+	}
+	g := Geometry{Type: strings.ToUpper(strings.TrimSpace(s[:i]))}
+	body := s[i+1 : len(s)-1]
+	var err error
+	switch g.Type {
+	case GeoPoint:
+		var p Point
+		p, err = parseWKTPoint(body)
+		g.Parts = [][]Point{{p}}
+	case GeoLineString:
+		var pts []Point
+		pts, err = parseWKTPoints(body, 2)
+		g.Parts = [][]Point{pts}
+	case GeoMultiPoint:
+		for _, part := range splitWKT(body) {
+			part = strings.TrimSpace(part)
+			if strings.HasPrefix(part, "(") && strings.HasSuffix(part, ")") {
+				part = part[1 : len(part)-1]
+			}
+			var p Point
+			if p, err = parseWKTPoint(part); err != nil {
+				break
+			}
+			g.Parts = append(g.Parts, []Point{p})
+		}
+	case GeoMultiLineString, GeoPolygon:
+		for _, part := range splitWKT(body) {
+			part = strings.TrimSpace(part)
+			if !strings.HasPrefix(part, "(") || !strings.HasSuffix(part, ")") {
+				err = fmt.Errorf("wkt: expected a list of points, got %q", part)
+				break
+			}
+			var pts []Point
+			if g.Type == GeoPolygon {
+				pts, err = parseWKTPoints(part[1:len(part)-1], 4)
+				if err == nil && pts[0] != pts[len(pts)-1] {
+					err = fmt.Errorf("wkt: polygon ring is not closed: %q", part)
+				}
+			} else {
+				pts, err = parseWKTPoints(part[1:len(part)-1], 2)
+			}
+			if err != nil {
+				break
+			}
+			g.Parts = append(g.Parts, pts)
+		}
+	default:
+		return Geometry{}, fmt.Errorf("wkt: unsupported geometry type %q", g.Type)
+	}
+	if err != nil {
+		return Geometry{}, err
+	} else if len(g.Parts) == 0 {
+		return Geometry{}, errEmptyGeometry
+	}
+	return g, nil
+}
+
+// splitWKT splits a list by commas that are not enclosed in parentheses.
+func splitWKT(s string) []string {
+	var (
+		out   []string
+		depth int
+		last  int
+	)
+	for i, r := range s {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				out = append(out, s[last:i])
+				last = i + 1
+			}
+		}
+	}
+	if strings.TrimSpace(s[last:]) != "" {
+		out = append(out, s[last:])
+	}
+	return out
+}
+
+func parseWKTPoints(s string, min int) ([]Point, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) < min {
+		return nil, fmt.Errorf("wkt: expected at least %d points, got %q", min, s)
+	}
+	out := make([]Point, 0, len(parts))
+	for _, part := range parts {
+		p, err := parseWKTPoint(part)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, p)
+	}
+	return out, nil
+}
+
+func parseWKTPoint(s string) (Point, error) {
+	f := strings.Fields(s)
+	if len(f) != 2 {
+		return Point{}, fmt.Errorf("wkt: expected two coordinates, got %q", s)
+	}
+	lon, err := strconv.ParseFloat(f[0], 64)
+	if err != nil {
+		return Point{}, err
+	}
+	lat, err := strconv.ParseFloat(f[1], 64)
+	if err != nil {
+		return Point{}, err
+	}
+	if lon < -180 || lon > 180 || lat < -90 || lat > 90 {
+		return Point{}, fmt.Errorf("wkt: coordinates are out of range: %q", s)
+	}
+	return Point{Lon: lon, Lat: lat}, nil
+}
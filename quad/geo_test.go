@@ -0,0 +1,66 @@
+package quad
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/codelingo/cayley/voc/geo"
+)
+
+var wktCases = []struct {
+	wkt    string
+	expect Value
+	err    bool
+}{
+	{wkt: `POINT(-122.4194 37.7749)`, expect: Point{Lon: -122.4194, Lat: 37.7749}},
+	{wkt: ` point ( 10  20 ) `, expect: Point{Lon: 10, Lat: 20}},
+	{wkt: `<http://www.opengis.net/def/crs/OGC/1.3/CRS84> POINT(1 2)`, expect: Point{Lon: 1, Lat: 2}},
+	{wkt: `LINESTRING(30 10,10 30, 40 40)`, expect: WKT(`LINESTRING(30 10, 10 30, 40 40)`)},
+	{wkt: `MULTIPOINT(10 40, 40 30)`, expect: WKT(`MULTIPOINT((10 40), (40 30))`)},
+	{wkt: `MULTIPOINT((10 40), (40 30))`, expect: WKT(`MULTIPOINT((10 40), (40 30))`)},
+	{wkt: `MULTILINESTRING((10 10, 20 20), (40 40, 30 30))`, expect: WKT(`MULTILINESTRING((10 10, 20 20), (40 40, 30 30))`)},
+	{
+		wkt:    `POLYGON((35 10, 45 45, 15 40, 10 20, 35 10),(20 30, 35 35, 30 20, 20 30))`,
+		expect: WKT(`POLYGON((35 10, 45 45, 15 40, 10 20, 35 10), (20 30, 35 35, 30 20, 20 30))`),
+	},
+	{wkt: `POINT EMPTY`, err: true},
+	{wkt: `POINT(1 2 3)`, err: true},
+	{wkt: `POINT(200 10)`, err: true},
+	{wkt: `LINESTRING(1 2)`, err: true},
+	{wkt: `POLYGON((0 0, 1 0, 1 1, 0 1))`, err: true},
+	{wkt: `CIRCLE(1 2)`, err: true},
+	{wkt: `<http://example.com/crs> POINT(1 2)`, err: true},
+}
+
+func TestParseWKT(t *testing.T) {
+	for _, c := range wktCases {
+		g, err := ParseWKT(c.wkt)
+		if c.err {
+			if err == nil {
+				t.Errorf("expected an error for %q", c.wkt)
+			}
+			continue
+		} else if err != nil {
+			t.Errorf("unexpected error for %q: %v", c.wkt, err)
+			continue
+		}
+		if v := g.Value(); !reflect.DeepEqual(v, c.expect) {
+			t.Errorf("unexpected value for %q: %#v vs %#v", c.wkt, v, c.expect)
+		}
+	}
+}
+
+func TestGeoConversion(t *testing.T) {
+	p := Point{Lon: 1.5, Lat: -2}
+	ts := p.TypedString()
+	if ts.String() != `"POINT(1.5 -2)"^^<`+geo.WKTLiteral+`>` {
+		t.Errorf("unexpected string form: %v", ts)
+	}
+	if v, err := ts.ParseValue(); err != nil || v != p {
+		t.Errorf("unexpected value: %#v (%v)", v, err)
+	}
+	full := TypedString{Value: "LINESTRING(0 0, 1 1)", Type: IRI(geo.WKTLiteral).Full()}
+	if v, err := full.ParseValue(); err != nil || v != WKT("LINESTRING(0 0, 1 1)") {
+		t.Errorf("unexpected value: %#v (%v)", v, err)
+	}
+}
@@ -1,18 +1,25 @@
-// Package gml provides an encoder for Graph Modeling Format
+// Package gml provides an encoder and a decoder for Graph Modeling Format
 package gml
 
 import (
+	"bufio"
+	"bytes"
+	"errors"
 	"fmt"
+	"html"
 	"io"
+	"strconv"
 	"strings"
 
 	"github.com/codelingo/cayley/quad"
+	"github.com/codelingo/cayley/quad/nquads"
 )
 
 func init() {
 	quad.RegisterFormat(quad.Format{
 		Name:   "gml",
 		Ext:    []string{".gml"},
+		Reader: func(r io.Reader) quad.ReadCloser { return NewReader(r) },
 		Writer: func(w io.Writer) quad.WriteCloser { return NewWriter(w) },
 	})
 }
@@ -99,3 +106,305 @@ func (w *Writer) Close() error {
 
 const header = "Creator \"Cayley\"\ngraph [ directed 1\n"
 const footer = "]\n"
+
+// NewReader returns a GML decoder that takes its input from the provided io.Reader.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: bufio.NewReader(r), nodes: make(map[string]quad.Value)}
+}
+
+// Reader decodes a graph in GML format.
+//
+// Node labels are decoded as quad values, in the same notation as emitted by Writer.
+// Other node attributes are converted to quads with an attribute name as a predicate.
+// Nodes without a label are represented as blank nodes with the same id.
+// Every edge must have a label which is used as a predicate; other edge attributes are ignored.
+type Reader struct {
+	r   *bufio.Reader
+	err error
+
+	inGraph bool
+	nodes   map[string]quad.Value
+	buf     []quad.Quad
+}
+
+type tokenType int
+
+const (
+	tokEOF tokenType = iota
+	tokKey
+	tokInt
+	tokFloat
+	tokString
+	tokOpen
+	tokClose
+)
+
+type token struct {
+	typ tokenType
+	val string
+}
+
+// attr is a key-value pair of GML list. Value is nil for nested lists.
+type attr struct {
+	key string
+	val quad.Value
+}
+
+func isKeyChar(c byte, first bool) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (!first && c >= '0' && c <= '9')
+}
+
+func (r *Reader) next() (token, error) {
+	for {
+		c, err := r.r.ReadByte()
+		if err == io.EOF {
+			return token{typ: tokEOF}, nil
+		} else if err != nil {
+			return token{}, err
+		}
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			continue
+		case c == '#':
+			if _, err = r.r.ReadString('\n'); err == io.EOF {
+				return token{typ: tokEOF}, nil
+			} else if err != nil {
+				return token{}, err
+			}
+			continue
+		case c == '[':
+			return token{typ: tokOpen}, nil
+		case c == ']':
+			return token{typ: tokClose}, nil
+		case c == '"':
+			s, err := r.r.ReadString('"')
+			if err == io.EOF {
+				return token{}, io.ErrUnexpectedEOF
+			} else if err != nil {
+				return token{}, err
+			}
+			return token{typ: tokString, val: html.UnescapeString(s[:len(s)-1])}, nil
+		}
+		var buf bytes.Buffer
+		buf.WriteByte(c)
+		key := isKeyChar(c, true)
+		if !key && c != '+' && c != '-' && c != '.' && (c < '0' || c > '9') {
+			return token{}, fmt.Errorf("unexpected character: %q", c)
+		}
+		for {
+			b, err := r.r.Peek(1)
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				return token{}, err
+			}
+			c = b[0]
+			if key && !isKeyChar(c, false) {
+				break
+			} else if !key && c != '+' && c != '-' && c != '.' && c != 'e' && c != 'E' && (c < '0' || c > '9') {
+				break
+			}
+			r.r.ReadByte()
+			buf.WriteByte(c)
+		}
+		s := buf.String()
+		if key {
+			return token{typ: tokKey, val: s}, nil
+		} else if _, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return token{typ: tokInt, val: s}, nil
+		} else if _, err := strconv.ParseFloat(s, 64); err == nil {
+			return token{typ: tokFloat, val: s}, nil
+		}
+		return token{}, fmt.Errorf("invalid number: %q", s)
+	}
+}
+
+// parseValue converts a string from GML document into a quad value.
+func parseValue(s string) quad.Value {
+	v, err := nquads.ParseValue(s)
+	if _, raw := v.(quad.Raw); err != nil || v == nil || raw {
+		// not an N-Quads value; most probably a document was written by other tool
+		return quad.String(s)
+	}
+	return v
+}
+
+// value reads a value of the key. Nested lists are skipped and returned as nil.
+func (r *Reader) value() (quad.Value, error) {
+	t, err := r.next()
+	if err != nil {
+		return nil, err
+	}
+	switch t.typ {
+	case tokString:
+		return parseValue(t.val), nil
+	case tokInt:
+		v, _ := strconv.ParseInt(t.val, 10, 64)
+		return quad.Int(v), nil
+	case tokFloat:
+		v, _ := strconv.ParseFloat(t.val, 64)
+		return quad.Float(v), nil
+	case tokOpen:
+		_, err = r.list()
+		return nil, err
+	case tokEOF:
+		return nil, io.ErrUnexpectedEOF
+	}
+	return nil, fmt.Errorf("unexpected token: %q", t.val)
+}
+
+// list reads key-value pairs until the end of the list.
+func (r *Reader) list() ([]attr, error) {
+	var out []attr
+	for {
+		t, err := r.next()
+		if err != nil {
+			return nil, err
+		}
+		switch t.typ {
+		case tokClose:
+			return out, nil
+		case tokKey:
+			v, err := r.value()
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, attr{key: t.val, val: v})
+		case tokEOF:
+			return nil, io.ErrUnexpectedEOF
+		default:
+			return nil, fmt.Errorf("expected a key, got %q", t.val)
+		}
+	}
+}
+
+func idOf(v quad.Value) string {
+	if i, ok := v.(quad.Int); ok {
+		return strconv.FormatInt(int64(i), 10)
+	}
+	return quad.StringOf(v)
+}
+
+func (r *Reader) node(attrs []attr) error {
+	var (
+		id    string
+		label quad.Value
+	)
+	for _, a := range attrs {
+		switch a.key {
+		case "id":
+			id = idOf(a.val)
+		case "label":
+			label = a.val
+		}
+	}
+	if id == "" {
+		return errors.New("node without an id")
+	}
+	if label == nil {
+		label = quad.BNode(id)
+	}
+	r.nodes[id] = label
+	for _, a := range attrs {
+		if a.key == "id" || a.key == "label" || a.val == nil {
+			continue
+		}
+		r.buf = append(r.buf, quad.Quad{Subject: label, Predicate: quad.IRI(a.key), Object: a.val})
+	}
+	return nil
+}
+
+func (r *Reader) nodeByID(id string) quad.Value {
+	if v, ok := r.nodes[id]; ok {
+		return v
+	}
+	return quad.BNode(id)
+}
+
+func (r *Reader) edge(attrs []attr) error {
+	var q quad.Quad
+	for _, a := range attrs {
+		switch a.key {
+		case "source":
+			q.Subject = r.nodeByID(idOf(a.val))
+		case "target":
+			q.Object = r.nodeByID(idOf(a.val))
+		case "label":
+			q.Predicate = a.val
+		}
+	}
+	if q.Subject == nil || q.Object == nil {
+		return errors.New("edge without source or target")
+	} else if q.Predicate == nil {
+		return errors.New("edge without a label")
+	}
+	r.buf = append(r.buf, q)
+	return nil
+}
+
+// readItem reads the next top-level item or the next node or edge of the graph.
+func (r *Reader) readItem() error {
+	t, err := r.next()
+	if err != nil {
+		return err
+	}
+	switch t.typ {
+	case tokEOF:
+		if r.inGraph {
+			return io.ErrUnexpectedEOF
+		}
+		return io.EOF
+	case tokClose:
+		if !r.inGraph {
+			return errors.New("unexpected ']'")
+		}
+		r.inGraph = false
+		return nil
+	case tokKey:
+	default:
+		return fmt.Errorf("expected a key, got %q", t.val)
+	}
+	key := t.val
+	if !r.inGraph && key != "graph" {
+		_, err = r.value()
+		return err
+	}
+	if t, err = r.next(); err != nil {
+		return err
+	} else if t.typ != tokOpen {
+		if !r.inGraph {
+			return errors.New("expected a list for graph")
+		}
+		// attribute of the graph
+		return nil
+	}
+	if !r.inGraph {
+		r.inGraph = true
+		return nil
+	}
+	attrs, err := r.list()
+	if err != nil {
+		return err
+	}
+	switch key {
+	case "node":
+		return r.node(attrs)
+	case "edge":
+		return r.edge(attrs)
+	}
+	return nil
+}
+
+func (r *Reader) ReadQuad() (quad.Quad, error) {
+	for len(r.buf) == 0 {
+		if r.err != nil {
+			return quad.Quad{}, r.err
+		}
+		r.err = r.readItem()
+	}
+	q := r.buf[0]
+	r.buf = r.buf[1:]
+	return q, nil
+}
+
+func (r *Reader) Close() error { return nil }
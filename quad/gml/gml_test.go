@@ -2,6 +2,8 @@ package gml_test
 
 import (
 	"bytes"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/codelingo/cayley/quad"
@@ -68,3 +70,56 @@ func TestWriter(t *testing.T) {
 		}
 	}
 }
+
+func TestReader(t *testing.T) {
+	for _, c := range testData {
+		r := gml.NewReader(strings.NewReader(c.data))
+		quads, err := quad.ReadAll(r)
+		if err != nil {
+			t.Fatal("read failed:", err)
+		}
+		if !reflect.DeepEqual(c.quads, quads) {
+			t.Fatalf("wrong quads:\n%v\n\nvs\n\n%v", quads, c.quads)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, c := range testData {
+		buf := bytes.NewBuffer(nil)
+		w := gml.NewWriter(buf)
+		if _, err := quad.Copy(w, gml.NewReader(strings.NewReader(c.data))); err != nil {
+			t.Fatal("copy failed:", err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal("error on close:", err)
+		}
+		if c.data != buf.String() {
+			t.Fatalf("wrong output:\n%s\n\nvs\n\n%s", buf.String(), c.data)
+		}
+	}
+}
+
+const testAttrs = `# graph from another tool
+graph [
+	directed 1
+	node [ id 1 label "alice" age 30 graphics [ x 1.0 y 2.0 ] ]
+	node [ id 2 weight 1.5 ]
+	edge [ source 1 target 2 label "knows" value 3 ]
+]
+`
+
+func TestReaderAttributes(t *testing.T) {
+	quads, err := quad.ReadAll(gml.NewReader(strings.NewReader(testAttrs)))
+	if err != nil {
+		t.Fatal("read failed:", err)
+	}
+	expect := []quad.Quad{
+		{Subject: quad.String("alice"), Predicate: quad.IRI("age"), Object: quad.Int(30)},
+		{Subject: quad.BNode("2"), Predicate: quad.IRI("weight"), Object: quad.Float(1.5)},
+		{Subject: quad.String("alice"), Predicate: quad.String("knows"), Object: quad.BNode("2")},
+	}
+	if !reflect.DeepEqual(expect, quads) {
+		t.Fatalf("wrong quads:\n%v\n\nvs\n\n%v", quads, expect)
+	}
+}
@@ -1,12 +1,16 @@
-// Package graphml provides an encoder for GraphML format
+// Package graphml provides an encoder and a decoder for GraphML format
 package graphml
 
 import (
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"io"
+	"strconv"
+	"strings"
 
 	"github.com/codelingo/cayley/quad"
+	"github.com/codelingo/cayley/quad/nquads"
 )
 
 func init() {
@@ -14,6 +18,7 @@ func init() {
 		Name:   "graphml",
 		Ext:    []string{".graphml"},
 		Mime:   []string{"application/xml"},
+		Reader: func(r io.Reader) quad.ReadCloser { return NewReader(r) },
 		Writer: func(w io.Writer) quad.WriteCloser { return NewWriter(w) },
 	})
 }
@@ -107,3 +112,204 @@ const header = `<?xml version="1.0" encoding="UTF-8"?>
 	<graph id="G" edgedefault="directed">
 `
 const footer = "\t</graph>\n</graphml>\n"
+
+// NewReader returns a GraphML decoder that takes its input from the provided io.Reader.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{
+		dec:   xml.NewDecoder(r),
+		keys:  make(map[string]xmlKey),
+		nodes: make(map[string]quad.Value),
+	}
+}
+
+// Reader decodes a graph in GraphML format.
+//
+// Node and edge data with "description" or "label" name are decoded as quad values,
+// in the same notation as emitted by Writer. Other node data are converted to quads
+// with data name as a predicate. Nodes without a label are represented as blank nodes
+// with the same id. Every edge must have a label which is used as a predicate;
+// other edge data are ignored. Edges are returned after all nodes, since they may
+// reference nodes defined later in the document.
+type Reader struct {
+	dec *xml.Decoder
+	err error
+
+	keys  map[string]xmlKey
+	nodes map[string]quad.Value
+	edges []edge // edges with node ids that are resolved at the end of the document
+	buf   []quad.Quad
+}
+
+type edge struct {
+	source, target string
+	pred           quad.Value
+}
+
+type xmlKey struct {
+	ID   string `xml:"id,attr"`
+	For  string `xml:"for,attr"`
+	Name string `xml:"attr.name,attr"`
+	Type string `xml:"attr.type,attr"`
+}
+
+type xmlData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+type xmlNode struct {
+	ID   string    `xml:"id,attr"`
+	Data []xmlData `xml:"data"`
+}
+
+type xmlEdge struct {
+	Source string    `xml:"source,attr"`
+	Target string    `xml:"target,attr"`
+	Data   []xmlData `xml:"data"`
+}
+
+// parseValue converts a string from GraphML document into a quad value.
+func parseValue(s string) quad.Value {
+	v, err := nquads.ParseValue(s)
+	if _, raw := v.(quad.Raw); err != nil || v == nil || raw {
+		// not an N-Quads value; most probably a document was written by other tool
+		return quad.String(s)
+	}
+	return v
+}
+
+func isLabel(k xmlKey) bool {
+	return k.Name == "description" || k.Name == "label"
+}
+
+// value converts data to a quad value, according to the type of the key.
+func (k xmlKey) value(s string) quad.Value {
+	switch k.Type {
+	case "int", "long":
+		if v, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64); err == nil {
+			return quad.Int(v)
+		}
+	case "float", "double":
+		if v, err := strconv.ParseFloat(strings.TrimSpace(s), 64); err == nil {
+			return quad.Float(v)
+		}
+	case "boolean":
+		if v, err := strconv.ParseBool(strings.TrimSpace(s)); err == nil {
+			return quad.Bool(v)
+		}
+	}
+	return parseValue(s)
+}
+
+func (r *Reader) node(n xmlNode) {
+	var label quad.Value
+	for _, d := range n.Data {
+		if k := r.keys[d.Key]; isLabel(k) {
+			label = k.value(d.Value)
+		}
+	}
+	if label == nil {
+		label = quad.BNode(n.ID)
+	}
+	r.nodes[n.ID] = label
+	for _, d := range n.Data {
+		k, ok := r.keys[d.Key]
+		if ok && isLabel(k) {
+			continue
+		}
+		name := k.Name
+		if name == "" {
+			name = d.Key
+		}
+		r.buf = append(r.buf, quad.Quad{Subject: label, Predicate: quad.IRI(name), Object: k.value(d.Value)})
+	}
+}
+
+func (r *Reader) nodeByID(id string) quad.Value {
+	if v, ok := r.nodes[id]; ok {
+		return v
+	}
+	return quad.BNode(id)
+}
+
+func (r *Reader) edge(e xmlEdge) error {
+	var pred quad.Value
+	for _, d := range e.Data {
+		if k := r.keys[d.Key]; isLabel(k) {
+			pred = k.value(d.Value)
+		}
+	}
+	if pred == nil {
+		return errors.New("edge without a label")
+	}
+	r.edges = append(r.edges, edge{source: e.Source, target: e.Target, pred: pred})
+	return nil
+}
+
+// resolveEdges converts buffered edges to quads, once all nodes are known.
+func (r *Reader) resolveEdges() {
+	for _, e := range r.edges {
+		r.buf = append(r.buf, quad.Quad{Subject: r.nodeByID(e.source), Predicate: e.pred, Object: r.nodeByID(e.target)})
+	}
+	r.edges = nil
+}
+
+// readItem reads the next key, node or edge element.
+func (r *Reader) readItem() error {
+	for {
+		tok, err := r.dec.Token()
+		if err == io.EOF && len(r.edges) != 0 {
+			r.resolveEdges()
+			return nil
+		} else if err != nil {
+			return err
+		}
+		el, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch el.Name.Local {
+		case "graphml", "graph":
+			// descend into the element
+		case "key":
+			var k xmlKey
+			if err = r.dec.DecodeElement(&k, &el); err != nil {
+				return err
+			}
+			r.keys[k.ID] = k
+		case "node":
+			var n xmlNode
+			if err = r.dec.DecodeElement(&n, &el); err != nil {
+				return err
+			}
+			r.node(n)
+			return nil
+		case "edge":
+			var e xmlEdge
+			if err = r.dec.DecodeElement(&e, &el); err != nil {
+				return err
+			}
+			if err = r.edge(e); err != nil {
+				return err
+			}
+		default:
+			if err = r.dec.Skip(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (r *Reader) ReadQuad() (quad.Quad, error) {
+	for len(r.buf) == 0 {
+		if r.err != nil {
+			return quad.Quad{}, r.err
+		}
+		r.err = r.readItem()
+	}
+	q := r.buf[0]
+	r.buf = r.buf[1:]
+	return q, nil
+}
+
+func (r *Reader) Close() error { return nil }
@@ -2,6 +2,8 @@ package graphml_test
 
 import (
 	"bytes"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/codelingo/cayley/quad"
@@ -74,3 +76,86 @@ func TestWriter(t *testing.T) {
 		}
 	}
 }
+
+func TestReader(t *testing.T) {
+	for _, c := range testData {
+		r := graphml.NewReader(strings.NewReader(c.data))
+		quads, err := quad.ReadAll(r)
+		if err != nil {
+			t.Fatal("read failed:", err)
+		}
+		if !reflect.DeepEqual(c.quads, quads) {
+			t.Fatalf("wrong quads:\n%v\n\nvs\n\n%v", quads, c.quads)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, c := range testData {
+		buf := bytes.NewBuffer(nil)
+		w := graphml.NewWriter(buf)
+		if _, err := quad.Copy(w, graphml.NewReader(strings.NewReader(c.data))); err != nil {
+			t.Fatal("copy failed:", err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal("error on close:", err)
+		}
+		if c.data != buf.String() {
+			t.Fatalf("wrong output:\n%s\n\nvs\n\n%s", buf.String(), c.data)
+		}
+	}
+}
+
+const testAttrs = `<?xml version="1.0" encoding="UTF-8"?>
+<graphml xmlns="http://graphml.graphdrawing.org/xmlns">
+	<key id="name" for="node" attr.name="label" attr.type="string"/>
+	<key id="age" for="node" attr.name="age" attr.type="int"/>
+	<key id="w" for="edge" attr.name="weight" attr.type="double"/>
+	<key id="rel" for="edge" attr.name="label" attr.type="string"/>
+	<graph id="G" edgedefault="directed">
+		<desc>graph from another tool</desc>
+		<node id="1"><data key="name">alice</data><data key="age">30</data></node>
+		<node id="2"/>
+		<edge source="1" target="2"><data key="rel">knows</data><data key="w">0.5</data></edge>
+	</graph>
+</graphml>
+`
+
+func TestReaderAttributes(t *testing.T) {
+	quads, err := quad.ReadAll(graphml.NewReader(strings.NewReader(testAttrs)))
+	if err != nil {
+		t.Fatal("read failed:", err)
+	}
+	expect := []quad.Quad{
+		{Subject: quad.String("alice"), Predicate: quad.IRI("age"), Object: quad.Int(30)},
+		{Subject: quad.String("alice"), Predicate: quad.String("knows"), Object: quad.BNode("2")},
+	}
+	if !reflect.DeepEqual(expect, quads) {
+		t.Fatalf("wrong quads:\n%v\n\nvs\n\n%v", quads, expect)
+	}
+}
+
+const testForwardEdge = `<?xml version="1.0" encoding="UTF-8"?>
+<graphml xmlns="http://graphml.graphdrawing.org/xmlns">
+	<key id="name" for="node" attr.name="label" attr.type="string"/>
+	<key id="rel" for="edge" attr.name="label" attr.type="string"/>
+	<graph id="G" edgedefault="directed">
+		<edge source="1" target="2"><data key="rel">knows</data></edge>
+		<node id="1"><data key="name">alice</data></node>
+		<node id="2"><data key="name">bob</data></node>
+	</graph>
+</graphml>
+`
+
+func TestReaderForwardEdge(t *testing.T) {
+	quads, err := quad.ReadAll(graphml.NewReader(strings.NewReader(testForwardEdge)))
+	if err != nil {
+		t.Fatal("read failed:", err)
+	}
+	expect := []quad.Quad{
+		{Subject: quad.String("alice"), Predicate: quad.String("knows"), Object: quad.String("bob")},
+	}
+	if !reflect.DeepEqual(expect, quads) {
+		t.Fatalf("wrong quads:\n%v\n\nvs\n\n%v", quads, expect)
+	}
+}
@@ -111,6 +111,17 @@ func (dec *Reader) ReadQuad() (quad.Quad, error) {
 }
 func (dec *Reader) Close() error { return nil }
 
+// ParseValue parses a single value in N-Quads notation, as returned by quad.Value String method.
+func ParseValue(s string) (quad.Value, error) {
+	q, err := Parse("_:s <p> " + s + " .")
+	if err != nil {
+		return nil, err
+	} else if q.Label != nil {
+		return nil, fmt.Errorf("unexpected data after the value: %q", s)
+	}
+	return q.Object, nil
+}
+
 func unEscape(r []rune, spec int, isQuoted, isEscaped bool) quad.Value {
 	raw := r
 	var sp []rune
@@ -645,6 +645,29 @@ func TestParse(t *testing.T) {
 	}
 }
 
+var testValues = []quad.Value{
+	quad.IRI("/film/performance/character"),
+	quad.BNode("subject1"),
+	quad.String("Tom\u00e1s \"de\" Torquemada\n"),
+	quad.LangString{Value: "chat", Lang: "fr"},
+	quad.TypedString{Value: "1990-07-04", Type: "http://www.w3.org/2001/XMLSchema#date"},
+	quad.Int(42),
+}
+
+func TestParseValue(t *testing.T) {
+	for _, v := range testValues {
+		got, err := ParseValue(v.String())
+		if err != nil {
+			t.Errorf("Failed to parse %q: %v", v.String(), err)
+		} else if !reflect.DeepEqual(got, v) {
+			t.Errorf("Unexpected value, got:%#v expect:%#v", got, v)
+		}
+	}
+	if _, err := ParseValue("<a> <b>"); err == nil {
+		t.Errorf("Expected an error for multiple values")
+	}
+}
+
 // This is a sample taken from 30kmoviedata.nq.
 // It has intentional defects:
 // The second comment is inset one space and
@@ -47,6 +47,13 @@ func MakeValue(qv quad.Value) *Value {
 			Seconds: seconds,
 			Nanos:   nanos,
 		}}}
+	case quad.TypedStringer:
+		// other native types, like geometries, are stored in their string form
+		ts := v.TypedString()
+		return &Value{&Value_TypedStr{&Value_TypedString{
+			Value: string(ts.Value),
+			Type:  string(ts.Type),
+		}}}
 	default:
 		panic(fmt.Errorf("unsupported type: %T", qv))
 	}
@@ -0,0 +1,39 @@
+// Package rdfxml implements RDF/XML serialization format.
+//
+// Format is defined by https://www.w3.org/TR/rdf-syntax-grammar/.
+package rdfxml
+
+import (
+	"io"
+
+	"github.com/codelingo/cayley/quad"
+	"github.com/codelingo/cayley/voc/rdf"
+)
+
+func init() {
+	quad.RegisterFormat(quad.Format{
+		Name:   "rdfxml",
+		Ext:    []string{".rdf", ".owl"},
+		Mime:   []string{"application/rdf+xml"},
+		Reader: func(r io.Reader) quad.ReadCloser { return NewReader(r) },
+		Writer: func(w io.Writer) quad.WriteCloser { return NewWriter(w) },
+	})
+}
+
+const (
+	nsXML = `http://www.w3.org/XML/1998/namespace`
+
+	rdfPrefix = `rdf`
+)
+
+var (
+	rdfType       = quad.IRI(rdf.NS + "type")
+	rdfFirst      = quad.IRI(rdf.NS + "first")
+	rdfRest       = quad.IRI(rdf.NS + "rest")
+	rdfNil        = quad.IRI(rdf.NS + "nil")
+	rdfSubject    = quad.IRI(rdf.NS + "subject")
+	rdfPredicate  = quad.IRI(rdf.NS + "predicate")
+	rdfObject     = quad.IRI(rdf.NS + "object")
+	rdfStatement  = quad.IRI(rdf.NS + "Statement")
+	rdfXMLLiteral = quad.IRI(rdf.NS + "XMLLiteral")
+)
@@ -0,0 +1,232 @@
+package rdfxml
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/codelingo/cayley/quad"
+	"github.com/codelingo/cayley/voc"
+)
+
+type ByQuad []quad.Quad
+
+func (a ByQuad) Len() int           { return len(a) }
+func (a ByQuad) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a ByQuad) Less(i, j int) bool { return a[i].NQuad() < a[j].NQuad() }
+
+// normalize replaces blank nodes with sequential labels in order of their appearance.
+func normalize(quads []quad.Quad) []quad.Quad {
+	names := make(map[quad.BNode]quad.BNode)
+	rename := func(v quad.Value) quad.Value {
+		b, ok := v.(quad.BNode)
+		if !ok {
+			return v
+		}
+		n, ok := names[b]
+		if !ok {
+			n = quad.BNode("b" + string(rune('0'+len(names))))
+			names[b] = n
+		}
+		return n
+	}
+	out := make([]quad.Quad, 0, len(quads))
+	for _, q := range quads {
+		out = append(out, quad.Quad{
+			Subject: rename(q.Subject), Predicate: rename(q.Predicate),
+			Object: rename(q.Object), Label: rename(q.Label),
+		})
+	}
+	return out
+}
+
+const (
+	rdfNS  = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+	exNS   = "http://example.org/"
+	foafNS = "http://xmlns.com/foaf/0.1/"
+)
+
+var testReadCases = []struct {
+	message string
+	data    string
+	expect  []quad.Quad
+	err     bool
+}{
+	{
+		message: "descriptions and typed nodes",
+		data: `<?xml version="1.0"?>
+<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+	xmlns:foaf="http://xmlns.com/foaf/0.1/"
+	xml:base="http://example.org/">
+	<foaf:Person rdf:about="alice" foaf:nick="al">
+		<foaf:knows rdf:resource="bob"/>
+		<foaf:name xml:lang="en">Alice</foaf:name>
+		<foaf:age rdf:datatype="http://www.w3.org/2001/XMLSchema#integer">42</foaf:age>
+		<foaf:weight rdf:datatype="http://example.org/kg">60</foaf:weight>
+	</foaf:Person>
+	<rdf:Description rdf:ID="bob" rdf:type="http://xmlns.com/foaf/0.1/Person"/>
+</rdf:RDF>
+`,
+		expect: []quad.Quad{
+			{Subject: quad.IRI(exNS + "alice"), Predicate: quad.IRI(rdfNS + "type"), Object: quad.IRI(foafNS + "Person")},
+			{Subject: quad.IRI(exNS + "alice"), Predicate: quad.IRI(foafNS + "nick"), Object: quad.String("al")},
+			{Subject: quad.IRI(exNS + "alice"), Predicate: quad.IRI(foafNS + "knows"), Object: quad.IRI(exNS + "bob")},
+			{Subject: quad.IRI(exNS + "alice"), Predicate: quad.IRI(foafNS + "name"), Object: quad.LangString{Value: "Alice", Lang: "en"}},
+			{Subject: quad.IRI(exNS + "alice"), Predicate: quad.IRI(foafNS + "age"), Object: quad.Int(42)},
+			{Subject: quad.IRI(exNS + "alice"), Predicate: quad.IRI(foafNS + "weight"), Object: quad.TypedString{Value: "60", Type: exNS + "kg"}},
+			{Subject: quad.IRI(exNS + "#bob"), Predicate: quad.IRI(rdfNS + "type"), Object: quad.IRI(foafNS + "Person")},
+		},
+	},
+	{
+		message: "nested nodes and blank nodes",
+		data: `<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns:ex="http://example.org/">
+	<rdf:Description rdf:nodeID="a">
+		<ex:knows>
+			<rdf:Description ex:name="Bob"/>
+		</ex:knows>
+		<ex:address rdf:parseType="Resource">
+			<ex:city>Paris</ex:city>
+		</ex:address>
+		<ex:empty/>
+		<ex:prop ex:value="v"/>
+	</rdf:Description>
+</rdf:RDF>`,
+		expect: []quad.Quad{
+			{Subject: quad.BNode("b0"), Predicate: quad.IRI(exNS + "name"), Object: quad.String("Bob")},
+			{Subject: quad.BNode("b1"), Predicate: quad.IRI(exNS + "knows"), Object: quad.BNode("b0")},
+			{Subject: quad.BNode("b1"), Predicate: quad.IRI(exNS + "address"), Object: quad.BNode("b2")},
+			{Subject: quad.BNode("b2"), Predicate: quad.IRI(exNS + "city"), Object: quad.String("Paris")},
+			{Subject: quad.BNode("b1"), Predicate: quad.IRI(exNS + "empty"), Object: quad.String("")},
+			{Subject: quad.BNode("b3"), Predicate: quad.IRI(exNS + "value"), Object: quad.String("v")},
+			{Subject: quad.BNode("b1"), Predicate: quad.IRI(exNS + "prop"), Object: quad.BNode("b3")},
+		},
+	},
+	{
+		message: "collections and containers",
+		data: `<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns:ex="http://example.org/">
+	<rdf:Seq rdf:about="http://example.org/seq">
+		<rdf:li>a</rdf:li>
+		<rdf:li rdf:resource="http://example.org/b"/>
+	</rdf:Seq>
+	<rdf:Description rdf:about="http://example.org/s">
+		<ex:list rdf:parseType="Collection">
+			<rdf:Description rdf:about="http://example.org/x"/>
+		</ex:list>
+	</rdf:Description>
+</rdf:RDF>`,
+		expect: []quad.Quad{
+			{Subject: quad.IRI(exNS + "seq"), Predicate: quad.IRI(rdfNS + "type"), Object: quad.IRI(rdfNS + "Seq")},
+			{Subject: quad.IRI(exNS + "seq"), Predicate: quad.IRI(rdfNS + "_1"), Object: quad.String("a")},
+			{Subject: quad.IRI(exNS + "seq"), Predicate: quad.IRI(rdfNS + "_2"), Object: quad.IRI(exNS + "b")},
+			{Subject: quad.BNode("b0"), Predicate: quad.IRI(rdfNS + "first"), Object: quad.IRI(exNS + "x")},
+			{Subject: quad.BNode("b0"), Predicate: quad.IRI(rdfNS + "rest"), Object: quad.IRI(rdfNS + "nil")},
+			{Subject: quad.IRI(exNS + "s"), Predicate: quad.IRI(exNS + "list"), Object: quad.BNode("b0")},
+		},
+	},
+	{
+		message: "xml literal",
+		data: `<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns:ex="http://example.org/">
+	<rdf:Description rdf:about="http://example.org/s">
+		<ex:html rdf:parseType="Literal"><ex:b a="1">bold</ex:b> &amp; text</ex:html>
+	</rdf:Description>
+</rdf:RDF>`,
+		expect: []quad.Quad{
+			{Subject: quad.IRI(exNS + "s"), Predicate: quad.IRI(exNS + "html"), Object: quad.TypedString{
+				Value: `<ex:b a="1">bold</ex:b> &amp; text`, Type: rdfNS + "XMLLiteral",
+			}},
+		},
+	},
+	{
+		message: "single node element",
+		data:    `<ex:Thing xmlns:ex="http://example.org/" xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" rdf:about="http://example.org/t"/>`,
+		expect: []quad.Quad{
+			{Subject: quad.IRI(exNS + "t"), Predicate: quad.IRI(rdfNS + "type"), Object: quad.IRI(exNS + "Thing")},
+		},
+	},
+	{
+		message: "unexpected eof",
+		data:    `<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#"><rdf:Description>`,
+		err:     true,
+	},
+}
+
+func TestRead(t *testing.T) {
+	for _, c := range testReadCases {
+		quads, err := quad.ReadAll(NewReader(strings.NewReader(c.data)))
+		if c.err {
+			require.Error(t, err, c.message)
+			continue
+		}
+		require.NoError(t, err, c.message)
+		require.Equal(t, c.expect, normalize(quads), c.message)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	quads := []quad.Quad{
+		{Subject: quad.IRI("http://schema.org/alice"), Predicate: quad.IRI(rdfNS + "type"), Object: quad.IRI("http://schema.org/Person")},
+		{Subject: quad.IRI("http://schema.org/alice"), Predicate: quad.IRI("http://schema.org/knows"), Object: quad.BNode("bob")},
+		{Subject: quad.IRI("http://schema.org/alice"), Predicate: quad.IRI("http://schema.org/name"), Object: quad.LangString{Value: "Alice & Co", Lang: "en"}},
+		{Subject: quad.BNode("bob"), Predicate: quad.IRI(foafNS + "age"), Object: quad.Int(5)},
+		{Subject: quad.BNode("bob"), Predicate: quad.IRI(foafNS + "name"), Object: quad.String("Bob")},
+	}
+	buf := bytes.NewBuffer(nil)
+	w := NewWriter(buf)
+	// pin namespaces, since generated prefixes depend on the number of registered ones
+	var ns voc.Namespaces
+	ns.Register(voc.Namespace{Prefix: "schema:", Full: "http://schema.org/"})
+	w.SetNamespaces(&ns)
+	for _, q := range quads {
+		require.NoError(t, w.WriteQuad(q))
+	}
+	require.NoError(t, w.Close())
+	out := buf.String()
+	require.True(t, strings.HasSuffix(out, `>
+	<rdf:Description rdf:about="http://schema.org/alice">
+		<rdf:type rdf:resource="http://schema.org/Person"/>
+		<schema:knows rdf:nodeID="bob"/>
+		<schema:name xml:lang="en">Alice &amp; Co</schema:name>
+	</rdf:Description>
+	<rdf:Description rdf:nodeID="bob">
+		<ns1:age xmlns:ns1="http://xmlns.com/foaf/0.1/" rdf:datatype="schema:Integer">5</ns1:age>
+		<ns1:name xmlns:ns1="http://xmlns.com/foaf/0.1/">Bob</ns1:name>
+	</rdf:Description>
+</rdf:RDF>
+`), "unexpected output:\n%s", out)
+
+	got, err := quad.ReadAll(NewReader(strings.NewReader(out)))
+	require.NoError(t, err)
+	sort.Sort(ByQuad(quads))
+	sort.Sort(ByQuad(got))
+	require.Equal(t, quads, got)
+}
+
+func TestWriteIRIsAsGiven(t *testing.T) {
+	quads := []quad.Quad{
+		{Subject: quad.IRI("rdf:"), Predicate: quad.IRI("rdf:type"), Object: quad.IRI("rdf:")},
+		{Subject: quad.IRI("rdf:"), Predicate: quad.IRI(rdfNS + "value"), Object: quad.TypedString{Value: "1", Type: "rdf:"}},
+	}
+	buf := bytes.NewBuffer(nil)
+	w := NewWriter(buf)
+	for _, q := range quads {
+		require.NoError(t, w.WriteQuad(q))
+	}
+	require.NoError(t, w.Close())
+	out := buf.String()
+	require.Contains(t, out, `<rdf:Description rdf:about="rdf:">`)
+	require.Contains(t, out, `rdf:resource="rdf:"/>`)
+	require.Contains(t, out, `<rdf:value rdf:datatype="rdf:">1</rdf:value>`)
+
+	got, err := quad.ReadAll(NewReader(strings.NewReader(out)))
+	require.NoError(t, err)
+	require.Equal(t, quads, got)
+}
+
+func TestWriteNamedGraph(t *testing.T) {
+	w := NewWriter(bytes.NewBuffer(nil))
+	err := w.WriteQuad(quad.MakeIRI("a", "b", "c", "g"))
+	require.Equal(t, ErrNamedGraph, err)
+}
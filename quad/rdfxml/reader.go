@@ -0,0 +1,494 @@
+package rdfxml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/codelingo/cayley/quad"
+	"github.com/codelingo/cayley/voc/rdf"
+)
+
+// AutoConvertTypedString allows to convert TypedString values to native
+// equivalents directly while parsing. It will call ParseValue on all TypedString values.
+var AutoConvertTypedString = true
+
+// NewReader returns an RDF/XML decoder that takes its input from the provided io.Reader.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{dec: xml.NewDecoder(r), prefixes: make(map[string]string)}
+}
+
+// Reader implements RDF/XML document parsing.
+//
+// Document is parsed one top-level node element at a time, thus quads are emitted before the whole document is read.
+type Reader struct {
+	dec *xml.Decoder
+	err error
+
+	started bool  // root element was read
+	inRDF   bool  // root element is rdf:RDF
+	root    scope // scope of rdf:RDF element
+	buf     []quad.Quad
+
+	prefixes map[string]string // namespace prefixes seen so far; used for XML literals
+}
+
+// scope holds inherited values of xml:base and xml:lang attributes.
+type scope struct {
+	base *url.URL
+	lang string
+}
+
+// with returns a new scope, updated with xml attributes of the element.
+func (s scope) with(attrs []xml.Attr) (scope, error) {
+	for _, a := range attrs {
+		if a.Name.Space != nsXML {
+			continue
+		}
+		switch a.Name.Local {
+		case "lang":
+			s.lang = a.Value
+		case "base":
+			u, err := url.Parse(a.Value)
+			if err != nil {
+				return s, fmt.Errorf("invalid xml:base: %v", err)
+			}
+			if s.base != nil {
+				u = s.base.ResolveReference(u)
+			}
+			u.Fragment = ""
+			s.base = u
+		}
+	}
+	return s, nil
+}
+
+func (s scope) resolve(iri string) quad.IRI {
+	if s.base == nil {
+		return quad.IRI(iri)
+	}
+	u, err := url.Parse(iri)
+	if err != nil {
+		return quad.IRI(iri)
+	}
+	return quad.IRI(s.base.ResolveReference(u).String())
+}
+
+func (s scope) literal(v string) quad.Value {
+	if s.lang != "" {
+		return quad.LangString{Value: quad.String(v), Lang: s.lang}
+	}
+	return quad.String(v)
+}
+
+func typedValue(v, typ string) quad.Value {
+	ts := quad.TypedString{Value: quad.String(v), Type: quad.IRI(typ)}
+	if AutoConvertTypedString {
+		if nv, err := ts.ParseValue(); err == nil {
+			return nv
+		}
+	}
+	return ts
+}
+
+func iriOf(n xml.Name) quad.IRI {
+	return quad.IRI(n.Space + n.Local)
+}
+
+func isRDF(n xml.Name, local string) bool {
+	return n.Space == rdf.NS && n.Local == local
+}
+
+// isSyntaxAttr checks if an attribute is one of RDF/XML syntax terms, or xml attribute.
+func isSyntaxAttr(a xml.Attr) bool {
+	switch a.Name.Space {
+	case "", "xmlns", nsXML:
+		// unqualified attributes are not allowed as property attributes
+		return true
+	case rdf.NS:
+		switch a.Name.Local {
+		case "about", "ID", "nodeID", "resource", "parseType", "datatype", "aboutEach", "aboutEachPrefix", "bagID":
+			return true
+		}
+	}
+	return false
+}
+
+func rdfAttr(attrs []xml.Attr, local string) (string, bool) {
+	for _, a := range attrs {
+		if isRDF(a.Name, local) {
+			return a.Value, true
+		}
+	}
+	return "", false
+}
+
+// ReadQuad returns the next quad from the document, or an error.
+func (r *Reader) ReadQuad() (quad.Quad, error) {
+	for len(r.buf) == 0 {
+		if r.err != nil {
+			return quad.Quad{}, r.err
+		}
+		r.err = r.next()
+	}
+	q := r.buf[0]
+	r.buf = r.buf[1:]
+	return q, nil
+}
+
+func (r *Reader) Close() error { return nil }
+
+func (r *Reader) emit(s, p, o quad.Value) {
+	r.buf = append(r.buf, quad.Quad{Subject: s, Predicate: p, Object: o})
+}
+
+func (r *Reader) read() (xml.Token, error) {
+	tok, err := r.dec.Token()
+	if el, ok := tok.(xml.StartElement); ok {
+		for _, a := range el.Attr {
+			if a.Name.Space == "xmlns" {
+				r.prefixes[a.Value] = a.Name.Local
+			}
+		}
+	}
+	return tok, err
+}
+
+// token returns the next token inside the document element.
+func (r *Reader) token() (xml.Token, error) {
+	tok, err := r.read()
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return tok, err
+}
+
+// next reads the next top-level node element.
+func (r *Reader) next() error {
+	for {
+		tok, err := r.read()
+		if err == io.EOF && r.inRDF {
+			return io.ErrUnexpectedEOF
+		} else if err != nil {
+			return err
+		}
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			if !r.started {
+				r.started = true
+				if isRDF(tok.Name, "RDF") {
+					r.inRDF = true
+					r.root, err = r.root.with(tok.Attr)
+					if err != nil {
+						return err
+					}
+					continue
+				}
+				// document consists of a single node element
+				if _, err = r.nodeElement(r.root, tok); err != nil {
+					return err
+				}
+				return nil
+			} else if !r.inRDF {
+				return fmt.Errorf("unexpected element: %s", tok.Name.Local)
+			}
+			_, err = r.nodeElement(r.root, tok)
+			return err
+		case xml.EndElement:
+			// end of rdf:RDF
+			r.inRDF = false
+			return io.EOF
+		case xml.CharData:
+			if len(bytes.TrimSpace(tok)) != 0 && r.inRDF {
+				return fmt.Errorf("unexpected text: %q", string(tok))
+			}
+		}
+	}
+}
+
+// nodeElement parses a node element and all its properties. It returns a subject of the node.
+func (r *Reader) nodeElement(sc scope, el xml.StartElement) (quad.Value, error) {
+	sc, err := sc.with(el.Attr)
+	if err != nil {
+		return nil, err
+	}
+	var s quad.Value
+	if v, ok := rdfAttr(el.Attr, "about"); ok {
+		s = sc.resolve(v)
+	} else if v, ok = rdfAttr(el.Attr, "ID"); ok {
+		s = sc.resolve("#" + v)
+	} else if v, ok = rdfAttr(el.Attr, "nodeID"); ok {
+		s = quad.BNode(v)
+	} else {
+		s = quad.RandomBlankNode()
+	}
+	if !isRDF(el.Name, "Description") {
+		r.emit(s, rdfType, iriOf(el.Name))
+	}
+	r.propertyAttrs(sc, s, el.Attr)
+	return s, r.propertyElements(sc, s)
+}
+
+// propertyAttrs emits quads for property attributes of an element.
+func (r *Reader) propertyAttrs(sc scope, s quad.Value, attrs []xml.Attr) {
+	for _, a := range attrs {
+		if isSyntaxAttr(a) {
+			continue
+		}
+		if isRDF(a.Name, "type") {
+			r.emit(s, rdfType, sc.resolve(a.Value))
+		} else {
+			r.emit(s, iriOf(a.Name), sc.literal(a.Value))
+		}
+	}
+}
+
+// propertyElements parses property elements of a node until the end of the node element.
+func (r *Reader) propertyElements(sc scope, s quad.Value) error {
+	li := 0
+	for {
+		tok, err := r.token()
+		if err != nil {
+			return err
+		}
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			if err = r.propertyElement(sc, s, tok, &li); err != nil {
+				return err
+			}
+		case xml.EndElement:
+			return nil
+		case xml.CharData:
+			if len(bytes.TrimSpace(tok)) != 0 {
+				return fmt.Errorf("unexpected text: %q", string(tok))
+			}
+		}
+	}
+}
+
+// propertyElement parses a single property element of node s.
+func (r *Reader) propertyElement(sc scope, s quad.Value, el xml.StartElement, li *int) error {
+	sc, err := sc.with(el.Attr)
+	if err != nil {
+		return err
+	}
+	p := iriOf(el.Name)
+	if isRDF(el.Name, "li") {
+		*li++
+		p = quad.IRI(rdf.NS + "_" + strconv.Itoa(*li))
+	}
+	var o quad.Value
+	if pt, ok := rdfAttr(el.Attr, "parseType"); ok {
+		switch pt {
+		case "Resource":
+			o = quad.RandomBlankNode()
+			r.emit(s, p, o)
+			if err = r.propertyElements(sc, o); err != nil {
+				return err
+			}
+		case "Collection":
+			if o, err = r.collection(sc); err != nil {
+				return err
+			}
+			r.emit(s, p, o)
+		default: // "Literal" and any other parse type
+			lit, err := r.xmlLiteral()
+			if err != nil {
+				return err
+			}
+			o = quad.TypedString{Value: quad.String(lit), Type: rdfXMLLiteral}
+			r.emit(s, p, o)
+		}
+		return r.reify(sc, el, s, p, o)
+	}
+	// resource, literal or empty property element
+	var text bytes.Buffer
+	for o == nil {
+		tok, err := r.token()
+		if err != nil {
+			return err
+		}
+		switch tok := tok.(type) {
+		case xml.CharData:
+			text.Write(tok)
+		case xml.StartElement:
+			if len(bytes.TrimSpace(text.Bytes())) != 0 {
+				return fmt.Errorf("unexpected text: %q", text.String())
+			}
+			if o, err = r.nodeElement(sc, tok); err != nil {
+				return err
+			}
+			if err = r.expectEnd(); err != nil {
+				return err
+			}
+		case xml.EndElement:
+			o, err = r.emptyOrLiteral(sc, el, text.String())
+			if err != nil {
+				return err
+			}
+		}
+	}
+	r.emit(s, p, o)
+	return r.reify(sc, el, s, p, o)
+}
+
+// expectEnd skips white space until the end of the current element.
+func (r *Reader) expectEnd() error {
+	for {
+		tok, err := r.token()
+		if err != nil {
+			return err
+		}
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			return fmt.Errorf("unexpected element: %s", tok.Name.Local)
+		case xml.EndElement:
+			return nil
+		case xml.CharData:
+			if len(bytes.TrimSpace(tok)) != 0 {
+				return fmt.Errorf("unexpected text: %q", string(tok))
+			}
+		}
+	}
+}
+
+// emptyOrLiteral returns an object for property element without child elements.
+func (r *Reader) emptyOrLiteral(sc scope, el xml.StartElement, text string) (quad.Value, error) {
+	if dt, ok := rdfAttr(el.Attr, "datatype"); ok {
+		return typedValue(text, string(sc.resolve(dt))), nil
+	}
+	var o quad.Value
+	if v, ok := rdfAttr(el.Attr, "resource"); ok {
+		o = sc.resolve(v)
+	} else if v, ok = rdfAttr(el.Attr, "nodeID"); ok {
+		o = quad.BNode(v)
+	}
+	hasProps := false
+	for _, a := range el.Attr {
+		if !isSyntaxAttr(a) {
+			hasProps = true
+			break
+		}
+	}
+	if o == nil && !hasProps {
+		return sc.literal(text), nil
+	}
+	if strings.TrimSpace(text) != "" {
+		return nil, fmt.Errorf("unexpected text in empty property element: %q", text)
+	}
+	if o == nil {
+		o = quad.RandomBlankNode()
+	}
+	r.propertyAttrs(sc, o, el.Attr)
+	return o, nil
+}
+
+// collection parses node elements of rdf:parseType="Collection" property and builds an RDF list from them.
+func (r *Reader) collection(sc scope) (quad.Value, error) {
+	var head, cur quad.Value = rdfNil, nil
+	for {
+		tok, err := r.token()
+		if err != nil {
+			return nil, err
+		}
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			v, err := r.nodeElement(sc, tok)
+			if err != nil {
+				return nil, err
+			}
+			node := quad.RandomBlankNode()
+			if cur == nil {
+				head = node
+			} else {
+				r.emit(cur, rdfRest, node)
+			}
+			r.emit(node, rdfFirst, v)
+			cur = node
+		case xml.EndElement:
+			if cur != nil {
+				r.emit(cur, rdfRest, rdfNil)
+			}
+			return head, nil
+		case xml.CharData:
+			if len(bytes.TrimSpace(tok)) != 0 {
+				return nil, fmt.Errorf("unexpected text: %q", string(tok))
+			}
+		}
+	}
+}
+
+// xmlLiteral reads the content of the current element as-is, until the end of the element.
+func (r *Reader) xmlLiteral() (string, error) {
+	var buf bytes.Buffer
+	name := func(n xml.Name) (string, bool) {
+		if n.Space == "" {
+			return n.Local, true
+		} else if n.Space == "xmlns" {
+			return "xmlns:" + n.Local, true
+		} else if n.Space == nsXML {
+			return "xml:" + n.Local, true
+		} else if pref, ok := r.prefixes[n.Space]; ok {
+			return pref + ":" + n.Local, true
+		}
+		return n.Local, false
+	}
+	depth := 0
+	for {
+		tok, err := r.token()
+		if err != nil {
+			return "", err
+		}
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			depth++
+			el, ok := name(tok.Name)
+			buf.WriteString("<" + el)
+			if !ok {
+				// namespace was declared without a prefix
+				buf.WriteString(` xmlns="`)
+				xml.EscapeText(&buf, []byte(tok.Name.Space))
+				buf.WriteString(`"`)
+			}
+			for _, a := range tok.Attr {
+				if a.Name.Space == "" && a.Name.Local == "xmlns" {
+					continue
+				}
+				an, _ := name(a.Name)
+				buf.WriteString(" " + an + `="`)
+				xml.EscapeText(&buf, []byte(a.Value))
+				buf.WriteString(`"`)
+			}
+			buf.WriteString(">")
+		case xml.EndElement:
+			if depth == 0 {
+				return buf.String(), nil
+			}
+			depth--
+			el, _ := name(tok.Name)
+			buf.WriteString("</" + el + ">")
+		case xml.CharData:
+			xml.EscapeText(&buf, tok)
+		case xml.Comment:
+			buf.WriteString("<!--" + string(tok) + "-->")
+		}
+	}
+}
+
+// reify emits a reification of the statement if property element has an rdf:ID attribute.
+func (r *Reader) reify(sc scope, el xml.StartElement, s, p, o quad.Value) error {
+	id, ok := rdfAttr(el.Attr, "ID")
+	if !ok {
+		return nil
+	}
+	st := sc.resolve("#" + id)
+	r.emit(st, rdfType, rdfStatement)
+	r.emit(st, rdfSubject, s)
+	r.emit(st, rdfPredicate, p)
+	r.emit(st, rdfObject, o)
+	return nil
+}
@@ -0,0 +1,203 @@
+package rdfxml
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/codelingo/cayley/quad"
+	"github.com/codelingo/cayley/voc"
+	"github.com/codelingo/cayley/voc/rdf"
+)
+
+// ErrNamedGraph is returned by RDF/XML writer for quads with a label.
+var ErrNamedGraph = errors.New("rdfxml: named graphs are not supported")
+
+// NewWriter returns an RDF/XML encoder that writes its output to the provided io.Writer.
+//
+// IRIs are written as given. Prefixes of vocabularies registered in voc package are declared
+// in the document and used only to shorten property names.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: bufio.NewWriter(w), ns: voc.Clone()}
+}
+
+// Writer implements RDF/XML document generator.
+//
+// Consecutive quads with the same subject are written as a single node element.
+type Writer struct {
+	w   *bufio.Writer
+	ns  *voc.Namespaces
+	err error
+
+	written  bool
+	declared map[string]string // namespaces declared on the root element
+	local    map[string]string // generated prefixes of namespaces declared on property elements
+	subj     quad.Value        // subject of the open node element
+}
+
+// SetNamespaces sets a list of namespaces that will be used for property names.
+// It must be called before writing any quads.
+func (w *Writer) SetNamespaces(ns *voc.Namespaces) {
+	w.ns = ns
+}
+
+func (w *Writer) write(s string) {
+	if w.err == nil {
+		_, w.err = w.w.WriteString(s)
+	}
+}
+
+func escape(s string) string {
+	var buf bytes.Buffer
+	xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
+
+func (w *Writer) writeHeader() {
+	w.write(xml.Header + "<rdf:RDF xmlns:rdf=\"" + rdf.NS + "\"")
+	w.declared = map[string]string{rdf.NS: rdfPrefix}
+	list := w.ns.List()
+	sort.Sort(voc.ByFullName(list))
+	for _, ns := range list {
+		pref := strings.TrimSuffix(ns.Prefix, ":")
+		if _, ok := w.declared[ns.Full]; ok || pref == rdfPrefix || !isNCName(pref) {
+			continue
+		}
+		w.declared[ns.Full] = pref
+		w.write("\n\txmlns:" + pref + "=\"" + escape(ns.Full) + "\"")
+	}
+	w.write(">\n")
+}
+
+func (w *Writer) closeNode() {
+	if w.subj != nil {
+		w.write("\t</rdf:Description>\n")
+		w.subj = nil
+	}
+}
+
+func (w *Writer) WriteQuad(q quad.Quad) error {
+	if w.err != nil {
+		return w.err
+	}
+	if !q.IsValid() {
+		return nil
+	}
+	if q.Label != nil {
+		return ErrNamedGraph
+	}
+	if !w.written {
+		w.writeHeader()
+		w.written = true
+	}
+	pred, ok := q.Predicate.(quad.IRI)
+	if !ok {
+		return fmt.Errorf("rdfxml: predicate must be an IRI, got %v", q.Predicate)
+	}
+	prop, decl, err := w.splitIRI(string(pred))
+	if err != nil {
+		return err
+	}
+	if w.subj == nil || w.subj.String() != q.Subject.String() {
+		w.closeNode()
+		switch s := q.Subject.(type) {
+		case quad.IRI:
+			w.write("\t<rdf:Description rdf:about=\"" + escape(string(s)) + "\">\n")
+		case quad.BNode:
+			w.write("\t<rdf:Description rdf:nodeID=\"" + escape(string(s)) + "\">\n")
+		default:
+			return fmt.Errorf("rdfxml: subject must be an IRI or a blank node, got %v", q.Subject)
+		}
+		w.subj = q.Subject
+	}
+	w.write("\t\t<" + prop + decl)
+	o := q.Object
+	if ts, ok := o.(quad.TypedStringer); ok {
+		o = ts.TypedString()
+	}
+	switch o := o.(type) {
+	case quad.IRI:
+		w.write(" rdf:resource=\"" + escape(string(o)) + "\"/>\n")
+		return w.err
+	case quad.BNode:
+		w.write(" rdf:nodeID=\"" + escape(string(o)) + "\"/>\n")
+		return w.err
+	case quad.LangString:
+		w.write(" xml:lang=\"" + escape(o.Lang) + "\">" + escape(string(o.Value)))
+	case quad.TypedString:
+		w.write(" rdf:datatype=\"" + escape(string(o.Type)) + "\">" + escape(string(o.Value)))
+	case quad.String:
+		w.write(">" + escape(string(o)))
+	default:
+		w.write(">" + escape(quad.StringOf(o)))
+	}
+	w.write("</" + prop + ">\n")
+	return w.err
+}
+
+func (w *Writer) Close() error {
+	if !w.written {
+		w.writeHeader()
+		w.written = true
+	}
+	w.closeNode()
+	w.write("</rdf:RDF>\n")
+	if w.err != nil {
+		return w.err
+	}
+	return w.w.Flush()
+}
+
+// splitIRI returns a qualified name of the property and a namespace declaration, if it's not declared on the root element.
+func (w *Writer) splitIRI(iri string) (name, decl string, _ error) {
+	// only ASCII characters are considered to avoid splitting multi-byte characters
+	i := len(iri)
+	for i > 0 && iri[i-1] < utf8.RuneSelf && isNCNameChar(rune(iri[i-1])) {
+		i--
+	}
+	// local name must start with a letter or underscore
+	for i < len(iri) && !isNCNameStart(rune(iri[i])) {
+		i++
+	}
+	if i == 0 || i == len(iri) {
+		return "", "", fmt.Errorf("rdfxml: cannot use %q as a property name", iri)
+	}
+	ns, local := iri[:i], iri[i:]
+	if pref, ok := w.declared[ns]; ok {
+		return pref + ":" + local, "", nil
+	}
+	pref, ok := w.local[ns]
+	if !ok {
+		if w.local == nil {
+			w.local = make(map[string]string)
+		}
+		pref = "ns" + strconv.Itoa(len(w.local)+1)
+		w.local[ns] = pref
+	}
+	return pref + ":" + local, " xmlns:" + pref + "=\"" + escape(ns) + "\"", nil
+}
+
+func isNCNameStart(r rune) bool {
+	return r == '_' || unicode.IsLetter(r)
+}
+
+func isNCNameChar(r rune) bool {
+	return r == '-' || r == '.' || unicode.IsDigit(r) || isNCNameStart(r)
+}
+
+func isNCName(s string) bool {
+	for i, r := range s {
+		if (i == 0 && !isNCNameStart(r)) || !isNCNameChar(r) {
+			return false
+		}
+	}
+	return s != ""
+}
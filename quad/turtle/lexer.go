@@ -0,0 +1,392 @@
+package turtle
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"unicode"
+	"unicode/utf8"
+)
+
+type tokenType int
+
+const (
+	tokEOF tokenType = iota
+	tokIRI
+	tokPName
+	tokBNode
+	tokString
+	tokLang // also used for @prefix and @base
+	tokInteger
+	tokDecimal
+	tokDouble
+	tokWord // a, true, false, PREFIX, BASE, GRAPH
+	tokPunct
+)
+
+type token struct {
+	typ  tokenType
+	val  string
+	line int
+}
+
+func (t token) String() string {
+	switch t.typ {
+	case tokEOF:
+		return "EOF"
+	case tokIRI:
+		return "<" + t.val + ">"
+	case tokString:
+		return strconv.Quote(t.val)
+	case tokBNode:
+		return "_:" + t.val
+	case tokLang:
+		return "@" + t.val
+	}
+	return t.val
+}
+
+func (t token) is(typ tokenType, v string) bool {
+	return t.typ == typ && t.val == v
+}
+
+// lexer splits Turtle or TriG document into tokens.
+type lexer struct {
+	r    *bufio.Reader
+	line int
+	buf  bytes.Buffer
+}
+
+func newLexer(r io.Reader) *lexer {
+	return &lexer{r: bufio.NewReader(r), line: 1}
+}
+
+func (l *lexer) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("line %d: %s", l.line, fmt.Sprintf(format, args...))
+}
+
+func (l *lexer) read() (rune, error) {
+	r, _, err := l.r.ReadRune()
+	if err == nil && r == '\n' {
+		l.line++
+	}
+	return r, err
+}
+
+// peekRune returns the next rune without consuming it. It returns -1 at the end of input.
+func (l *lexer) peekRune() rune {
+	b, _ := l.r.Peek(utf8.UTFMax)
+	if len(b) == 0 {
+		return -1
+	}
+	r, _ := utf8.DecodeRune(b)
+	return r
+}
+
+// peek returns n-th byte after the current position, or 0 if it's not available.
+func (l *lexer) peek(n int) byte {
+	b, err := l.r.Peek(n + 1)
+	if err != nil || len(b) <= n {
+		return 0
+	}
+	return b[n]
+}
+
+func (l *lexer) skipSpace() error {
+	for {
+		r := l.peekRune()
+		if r < 0 {
+			return io.EOF
+		}
+		if r == '#' {
+			for r >= 0 && r != '\n' {
+				l.read()
+				r = l.peekRune()
+			}
+			continue
+		}
+		if !unicode.IsSpace(r) {
+			return nil
+		}
+		l.read()
+	}
+}
+
+func isPNCharsBase(r rune) bool {
+	return unicode.IsLetter(r) || (r >= 0xC0 && r != 0xD7 && r != 0xF7 && !unicode.IsSpace(r) && unicode.IsPrint(r) && !unicode.IsPunct(r))
+}
+
+func isPNChars(r rune) bool {
+	return r == '_' || r == '-' || r == 0xB7 || unicode.IsDigit(r) || isPNCharsBase(r)
+}
+
+func isDigit(b byte) bool { return b >= '0' && b <= '9' }
+
+// next returns the next token, or EOF token if there is no more input.
+func (l *lexer) next() (token, error) {
+	if err := l.skipSpace(); err == io.EOF {
+		return token{typ: tokEOF, line: l.line}, nil
+	} else if err != nil {
+		return token{}, err
+	}
+	line := l.line
+	tok := func(typ tokenType, v string) (token, error) {
+		return token{typ: typ, val: v, line: line}, nil
+	}
+	r := l.peekRune()
+	switch {
+	case r == '+' || r == '-' || (r >= '0' && r <= '9') || (r == '.' && isDigit(l.peek(1))):
+		return l.number()
+	case r == '_' && l.peek(1) == ':':
+		l.read()
+		l.read()
+		s := l.local()
+		if s == "" {
+			return token{}, l.errorf("empty blank node label")
+		}
+		return tok(tokBNode, s)
+	}
+	l.read()
+	switch r {
+	case '<':
+		s, err := l.iri()
+		if err != nil {
+			return token{}, err
+		}
+		return tok(tokIRI, s)
+	case '"', '\'':
+		s, err := l.str(r)
+		if err != nil {
+			return token{}, err
+		}
+		return tok(tokString, s)
+	case '@':
+		s := l.langTag()
+		if s == "" {
+			return token{}, l.errorf("empty language tag")
+		}
+		return tok(tokLang, s)
+	case '^':
+		if c, _ := l.read(); c != '^' {
+			return token{}, l.errorf("expected '^^'")
+		}
+		return tok(tokPunct, "^^")
+	case '.', ';', ',', '[', ']', '(', ')', '{', '}':
+		return tok(tokPunct, string(r))
+	}
+	if r != ':' && !isPNCharsBase(r) {
+		return token{}, l.errorf("unexpected character %q", r)
+	}
+	// prefixed name or a keyword
+	l.buf.Reset()
+	for r != ':' {
+		l.buf.WriteRune(r)
+		r = l.peekRune()
+		if r == '.' {
+			// dot cannot be the last character of a prefix
+			if c := rune(l.peek(1)); c != ':' && c != '.' && !isPNChars(c) {
+				return tok(tokWord, l.buf.String())
+			}
+		} else if r != ':' && !isPNChars(r) {
+			return tok(tokWord, l.buf.String())
+		}
+		l.read()
+	}
+	pref := l.buf.String()
+	return tok(tokPName, pref+":"+l.local())
+}
+
+func (l *lexer) iri() (string, error) {
+	l.buf.Reset()
+	for {
+		r, err := l.read()
+		if err == io.EOF {
+			return "", l.errorf("unterminated IRI")
+		} else if err != nil {
+			return "", err
+		}
+		switch r {
+		case '>':
+			return l.buf.String(), nil
+		case '\\':
+			c, err := l.read()
+			if err != nil {
+				return "", l.errorf("unterminated IRI")
+			}
+			if c != 'u' && c != 'U' {
+				return "", l.errorf("invalid escape in IRI: \\%c", c)
+			}
+			u, err := l.unicodeEscape(c)
+			if err != nil {
+				return "", err
+			}
+			l.buf.WriteRune(u)
+		case ' ', '\t', '\n', '\r', '<', '"', '{', '}', '|', '`':
+			return "", l.errorf("invalid character in IRI: %q", r)
+		default:
+			l.buf.WriteRune(r)
+		}
+	}
+}
+
+func (l *lexer) unicodeEscape(c rune) (rune, error) {
+	n := 4
+	if c == 'U' {
+		n = 8
+	}
+	hex := make([]rune, 0, n)
+	for i := 0; i < n; i++ {
+		r, err := l.read()
+		if err != nil {
+			return 0, l.errorf("unterminated unicode escape")
+		}
+		hex = append(hex, r)
+	}
+	v, err := strconv.ParseUint(string(hex), 16, 32)
+	if err != nil {
+		return 0, l.errorf("invalid unicode escape: %v", err)
+	}
+	return rune(v), nil
+}
+
+func (l *lexer) str(q rune) (string, error) {
+	long := l.peek(0) == byte(q) && l.peek(1) == byte(q)
+	if long {
+		l.read()
+		l.read()
+	}
+	l.buf.Reset()
+	for {
+		r, err := l.read()
+		if err == io.EOF {
+			return "", l.errorf("unterminated string")
+		} else if err != nil {
+			return "", err
+		}
+		switch {
+		case r == q && !long:
+			return l.buf.String(), nil
+		case r == q && long && l.peek(0) == byte(q) && l.peek(1) == byte(q):
+			l.read()
+			l.read()
+			return l.buf.String(), nil
+		case (r == '\n' || r == '\r') && !long:
+			return "", l.errorf("new line in string")
+		case r == '\\':
+			c, err := l.read()
+			if err != nil {
+				return "", l.errorf("unterminated string")
+			}
+			switch c {
+			case 't':
+				l.buf.WriteByte('\t')
+			case 'b':
+				l.buf.WriteByte('\b')
+			case 'n':
+				l.buf.WriteByte('\n')
+			case 'r':
+				l.buf.WriteByte('\r')
+			case 'f':
+				l.buf.WriteByte('\f')
+			case '"', '\'', '\\':
+				l.buf.WriteRune(c)
+			case 'u', 'U':
+				u, err := l.unicodeEscape(c)
+				if err != nil {
+					return "", err
+				}
+				l.buf.WriteRune(u)
+			default:
+				return "", l.errorf("invalid escape sequence: \\%c", c)
+			}
+		default:
+			l.buf.WriteRune(r)
+		}
+	}
+}
+
+func (l *lexer) langTag() string {
+	l.buf.Reset()
+	for {
+		r := l.peekRune()
+		if r == '-' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			l.read()
+			l.buf.WriteRune(r)
+			continue
+		}
+		return l.buf.String()
+	}
+}
+
+// local reads a local part of prefixed name or a blank node label.
+// Escape sequences are decoded, while percent-encoded characters are preserved.
+func (l *lexer) local() string {
+	l.buf.Reset()
+	for {
+		r := l.peekRune()
+		switch {
+		case r == '\\':
+			l.read()
+			if c, err := l.read(); err == nil {
+				l.buf.WriteRune(c)
+			}
+			continue
+		case r == ':' || r == '%' || isPNChars(r):
+			l.read()
+			l.buf.WriteRune(r)
+			continue
+		case r == '.':
+			// dot cannot be the last character
+			if c := rune(l.peek(1)); c == ':' || c == '%' || c == '\\' || isPNChars(c) {
+				l.read()
+				l.buf.WriteRune(r)
+				continue
+			}
+		}
+		return l.buf.String()
+	}
+}
+
+func (l *lexer) number() (token, error) {
+	line := l.line
+	l.buf.Reset()
+	digits := func() int {
+		n := 0
+		for isDigit(l.peek(0)) {
+			r, _ := l.read()
+			l.buf.WriteRune(r)
+			n++
+		}
+		return n
+	}
+	if c := l.peek(0); c == '+' || c == '-' {
+		r, _ := l.read()
+		l.buf.WriteRune(r)
+	}
+	typ := tokInteger
+	n := digits()
+	if l.peek(0) == '.' && isDigit(l.peek(1)) {
+		r, _ := l.read()
+		l.buf.WriteRune(r)
+		n += digits()
+		typ = tokDecimal
+	}
+	if n == 0 {
+		return token{}, l.errorf("invalid number")
+	}
+	if c := l.peek(0); c == 'e' || c == 'E' {
+		r, _ := l.read()
+		l.buf.WriteRune(r)
+		if c := l.peek(0); c == '+' || c == '-' {
+			r, _ := l.read()
+			l.buf.WriteRune(r)
+		}
+		if digits() == 0 {
+			return token{}, l.errorf("invalid exponent")
+		}
+		typ = tokDouble
+	}
+	return token{typ: typ, val: l.buf.String(), line: line}, nil
+}
@@ -0,0 +1,570 @@
+package turtle
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/codelingo/cayley/quad"
+	"github.com/codelingo/cayley/voc"
+	"github.com/codelingo/cayley/voc/rdf"
+)
+
+const nsXSD = `http://www.w3.org/2001/XMLSchema#`
+
+var (
+	rdfType  = quad.IRI(rdf.NS + "type")
+	rdfFirst = quad.IRI(rdf.NS + "first")
+	rdfRest  = quad.IRI(rdf.NS + "rest")
+	rdfNil   = quad.IRI(rdf.NS + "nil")
+)
+
+// AutoConvertTypedString allows to convert TypedString values to native
+// equivalents directly while parsing. It will call ParseValue on all TypedString values.
+var AutoConvertTypedString = true
+
+// NewReader returns a Turtle decoder that takes its input from the provided io.Reader.
+func NewReader(r io.Reader) *Reader {
+	return newReader(r, false)
+}
+
+// NewTriGReader returns a TriG decoder that takes its input from the provided io.Reader.
+func NewTriGReader(r io.Reader) *Reader {
+	return newReader(r, true)
+}
+
+func newReader(r io.Reader, trig bool) *Reader {
+	return &Reader{lex: newLexer(r), trig: trig, prefixes: make(map[string]string)}
+}
+
+// Reader implements Turtle and TriG document parsing.
+//
+// Document is parsed statement by statement, thus quads are emitted before the whole document is read.
+type Reader struct {
+	lex  *lexer
+	trig bool
+	err  error
+
+	tok    token
+	peeked bool
+
+	base     *url.URL
+	prefixes map[string]string
+
+	label   quad.Value // current graph for TriG
+	inGraph bool
+	buf     []quad.Quad
+}
+
+func (r *Reader) next() (token, error) {
+	if r.peeked {
+		r.peeked = false
+		return r.tok, nil
+	}
+	t, err := r.lex.next()
+	if err != nil {
+		return t, err
+	}
+	r.tok = t
+	return t, nil
+}
+
+func (r *Reader) peek() (token, error) {
+	if r.peeked {
+		return r.tok, nil
+	}
+	t, err := r.next()
+	if err != nil {
+		return t, err
+	}
+	r.peeked = true
+	return t, nil
+}
+
+func (r *Reader) errorf(t token, format string, args ...interface{}) error {
+	return fmt.Errorf("line %d: %s", t.line, fmt.Sprintf(format, args...))
+}
+
+func (r *Reader) expect(v string) error {
+	t, err := r.next()
+	if err != nil {
+		return err
+	}
+	if !t.is(tokPunct, v) {
+		return r.errorf(t, "expected %q, got %v", v, t)
+	}
+	return nil
+}
+
+// ReadQuad returns the next quad from the document, or an error.
+func (r *Reader) ReadQuad() (quad.Quad, error) {
+	for len(r.buf) == 0 {
+		if r.err != nil {
+			return quad.Quad{}, r.err
+		}
+		r.err = r.statement()
+	}
+	q := r.buf[0]
+	r.buf = r.buf[1:]
+	return q, nil
+}
+
+func (r *Reader) Close() error { return nil }
+
+func (r *Reader) emit(s, p, o quad.Value) {
+	r.buf = append(r.buf, quad.Quad{Subject: s, Predicate: p, Object: o, Label: r.label})
+}
+
+func (r *Reader) resolve(iri string) quad.IRI {
+	if r.base == nil {
+		return quad.IRI(iri)
+	}
+	u, err := url.Parse(iri)
+	if err != nil || u.IsAbs() {
+		return quad.IRI(iri)
+	}
+	return quad.IRI(r.base.ResolveReference(u).String())
+}
+
+func (r *Reader) expandName(t token) (quad.IRI, error) {
+	i := strings.Index(t.val, ":")
+	pref, local := t.val[:i+1], t.val[i+1:]
+	if ns, ok := r.prefixes[pref]; ok {
+		return quad.IRI(ns + local), nil
+	}
+	// fallback to globally registered vocabularies
+	if full := voc.FullIRI(t.val); full != t.val {
+		return quad.IRI(full), nil
+	}
+	return "", r.errorf(t, "undefined prefix: %q", pref)
+}
+
+// statement reads a single directive, triples statement or a graph block.
+func (r *Reader) statement() error {
+	t, err := r.peek()
+	if err != nil {
+		return err
+	}
+	switch {
+	case t.typ == tokEOF:
+		if r.inGraph {
+			return r.errorf(t, "unexpected end of graph block")
+		}
+		return io.EOF
+	case t.typ == tokLang && (t.val == "prefix" || t.val == "base"):
+		r.next()
+		if err := r.directive(t.val); err != nil {
+			return err
+		}
+		return r.expect(".")
+	case t.typ == tokWord && (strings.EqualFold(t.val, "prefix") || strings.EqualFold(t.val, "base")):
+		r.next()
+		return r.directive(strings.ToLower(t.val))
+	}
+	if !r.trig {
+		if err := r.triples(); err != nil {
+			return err
+		}
+		return r.expect(".")
+	}
+	return r.trigStatement()
+}
+
+func (r *Reader) directive(name string) error {
+	if name == "prefix" {
+		t, err := r.next()
+		if err != nil {
+			return err
+		}
+		if t.typ != tokPName || !strings.HasSuffix(t.val, ":") {
+			return r.errorf(t, "expected prefix name, got %v", t)
+		}
+		iri, err := r.next()
+		if err != nil {
+			return err
+		}
+		if iri.typ != tokIRI {
+			return r.errorf(iri, "expected IRI, got %v", iri)
+		}
+		r.prefixes[t.val] = string(r.resolve(iri.val))
+		return nil
+	}
+	t, err := r.next()
+	if err != nil {
+		return err
+	}
+	if t.typ != tokIRI {
+		return r.errorf(t, "expected IRI, got %v", t)
+	}
+	u, err := url.Parse(string(r.resolve(t.val)))
+	if err != nil {
+		return r.errorf(t, "invalid base IRI: %v", err)
+	}
+	r.base = u
+	return nil
+}
+
+// trigStatement reads a graph block or triples statement in TriG document.
+func (r *Reader) trigStatement() error {
+	t, err := r.peek()
+	if err != nil {
+		return err
+	}
+	if r.inGraph {
+		if t.is(tokPunct, "}") {
+			r.next()
+			r.inGraph, r.label = false, nil
+			return nil
+		}
+		if err := r.triples(); err != nil {
+			return err
+		}
+		// the last dot in a graph block is optional
+		if t, err = r.peek(); err != nil {
+			return err
+		} else if t.is(tokPunct, "}") {
+			return nil
+		}
+		return r.expect(".")
+	}
+	switch {
+	case t.is(tokPunct, "{"):
+		r.next()
+		r.inGraph, r.label = true, nil
+		return nil
+	case t.typ == tokWord && strings.EqualFold(t.val, "graph"):
+		r.next()
+		label, err := r.labelTerm()
+		if err != nil {
+			return err
+		}
+		if err := r.expect("{"); err != nil {
+			return err
+		}
+		r.inGraph, r.label = true, label
+		return nil
+	case t.typ == tokIRI || t.typ == tokPName || t.typ == tokBNode || t.is(tokPunct, "["):
+		// either a graph label or a subject of triples statement
+		if t.is(tokPunct, "[") {
+			r.next()
+			if nt, err := r.peek(); err != nil {
+				return err
+			} else if nt.is(tokPunct, "]") {
+				r.next()
+				bn := quad.RandomBlankNode()
+				if nt, err = r.peek(); err != nil {
+					return err
+				} else if nt.is(tokPunct, "{") {
+					r.next()
+					r.inGraph, r.label = true, bn
+					return nil
+				}
+				return r.triplesAfterSubject(bn)
+			}
+			s, err := r.blankNodePropertyList()
+			if err != nil {
+				return err
+			}
+			return r.triplesAfterBNodeList(s)
+		}
+		s, err := r.term()
+		if err != nil {
+			return err
+		}
+		nt, err := r.peek()
+		if err != nil {
+			return err
+		}
+		if nt.is(tokPunct, "{") {
+			r.next()
+			r.inGraph, r.label = true, s
+			return nil
+		}
+		return r.triplesAfterSubject(s)
+	}
+	if err := r.triples(); err != nil {
+		return err
+	}
+	return r.expect(".")
+}
+
+func (r *Reader) triplesAfterSubject(s quad.Value) error {
+	if err := r.predicateObjectList(s); err != nil {
+		return err
+	}
+	return r.expect(".")
+}
+
+func (r *Reader) triplesAfterBNodeList(s quad.Value) error {
+	t, err := r.peek()
+	if err != nil {
+		return err
+	}
+	if !t.is(tokPunct, ".") {
+		if err := r.predicateObjectList(s); err != nil {
+			return err
+		}
+	}
+	return r.expect(".")
+}
+
+func (r *Reader) labelTerm() (quad.Value, error) {
+	t, err := r.next()
+	if err != nil {
+		return nil, err
+	}
+	switch t.typ {
+	case tokIRI:
+		return r.resolve(t.val), nil
+	case tokPName:
+		return r.expandName(t)
+	case tokBNode:
+		return quad.BNode(t.val), nil
+	}
+	if t.is(tokPunct, "[") {
+		if err := r.expect("]"); err != nil {
+			return nil, err
+		}
+		return quad.RandomBlankNode(), nil
+	}
+	return nil, r.errorf(t, "expected graph label, got %v", t)
+}
+
+// triples reads a subject with a predicate-object list, without the trailing dot.
+func (r *Reader) triples() error {
+	t, err := r.peek()
+	if err != nil {
+		return err
+	}
+	if t.is(tokPunct, "[") {
+		r.next()
+		if nt, err := r.peek(); err != nil {
+			return err
+		} else if nt.is(tokPunct, "]") {
+			r.next()
+			return r.predicateObjectList(quad.RandomBlankNode())
+		}
+		s, err := r.blankNodePropertyList()
+		if err != nil {
+			return err
+		}
+		// predicate-object list is optional after blank node property list
+		if nt, err := r.peek(); err != nil {
+			return err
+		} else if nt.is(tokPunct, ".") || nt.is(tokPunct, "}") {
+			return nil
+		}
+		return r.predicateObjectList(s)
+	}
+	var s quad.Value
+	if t.is(tokPunct, "(") {
+		r.next()
+		s, err = r.collection()
+	} else {
+		s, err = r.term()
+	}
+	if err != nil {
+		return err
+	}
+	switch s.(type) {
+	case quad.IRI, quad.BNode:
+	default:
+		return r.errorf(t, "invalid subject: %v", t)
+	}
+	return r.predicateObjectList(s)
+}
+
+func (r *Reader) predicateObjectList(s quad.Value) error {
+	for {
+		p, err := r.verb()
+		if err != nil {
+			return err
+		}
+		if err := r.objectList(s, p); err != nil {
+			return err
+		}
+		t, err := r.peek()
+		if err != nil {
+			return err
+		}
+		if !t.is(tokPunct, ";") {
+			return nil
+		}
+		// multiple semicolons are allowed, as well as a trailing one
+		for t.is(tokPunct, ";") {
+			r.next()
+			if t, err = r.peek(); err != nil {
+				return err
+			}
+		}
+		if t.is(tokPunct, ".") || t.is(tokPunct, "]") || t.is(tokPunct, "}") || t.typ == tokEOF {
+			return nil
+		}
+	}
+}
+
+func (r *Reader) verb() (quad.Value, error) {
+	t, err := r.next()
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case t.is(tokWord, "a"):
+		return rdfType, nil
+	case t.typ == tokIRI:
+		return r.resolve(t.val), nil
+	case t.typ == tokPName:
+		return r.expandName(t)
+	}
+	return nil, r.errorf(t, "expected predicate, got %v", t)
+}
+
+func (r *Reader) objectList(s, p quad.Value) error {
+	for {
+		o, err := r.object()
+		if err != nil {
+			return err
+		}
+		r.emit(s, p, o)
+		t, err := r.peek()
+		if err != nil {
+			return err
+		}
+		if !t.is(tokPunct, ",") {
+			return nil
+		}
+		r.next()
+	}
+}
+
+func (r *Reader) object() (quad.Value, error) {
+	t, err := r.peek()
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case t.is(tokPunct, "["):
+		r.next()
+		if nt, err := r.peek(); err != nil {
+			return nil, err
+		} else if nt.is(tokPunct, "]") {
+			r.next()
+			return quad.RandomBlankNode(), nil
+		}
+		return r.blankNodePropertyList()
+	case t.is(tokPunct, "("):
+		r.next()
+		return r.collection()
+	}
+	return r.term()
+}
+
+// blankNodePropertyList reads a predicate-object list of a new blank node. Opening bracket should be consumed.
+func (r *Reader) blankNodePropertyList() (quad.Value, error) {
+	s := quad.RandomBlankNode()
+	if err := r.predicateObjectList(s); err != nil {
+		return nil, err
+	}
+	if err := r.expect("]"); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// collection reads an RDF list. Opening parenthesis should be consumed.
+func (r *Reader) collection() (quad.Value, error) {
+	var head, cur quad.Value = rdfNil, nil
+	for {
+		t, err := r.peek()
+		if err != nil {
+			return nil, err
+		}
+		if t.is(tokPunct, ")") {
+			r.next()
+			break
+		}
+		o, err := r.object()
+		if err != nil {
+			return nil, err
+		}
+		node := quad.RandomBlankNode()
+		if cur == nil {
+			head = node
+		} else {
+			r.emit(cur, rdfRest, node)
+		}
+		r.emit(node, rdfFirst, o)
+		cur = node
+	}
+	if cur != nil {
+		r.emit(cur, rdfRest, rdfNil)
+	}
+	return head, nil
+}
+
+func typedValue(v, typ string) quad.Value {
+	ts := quad.TypedString{Value: quad.String(v), Type: quad.IRI(typ)}
+	if AutoConvertTypedString {
+		if nv, err := ts.ParseValue(); err == nil {
+			return nv
+		}
+	}
+	return ts
+}
+
+// term reads a single IRI, blank node or literal.
+func (r *Reader) term() (quad.Value, error) {
+	t, err := r.next()
+	if err != nil {
+		return nil, err
+	}
+	switch t.typ {
+	case tokIRI:
+		return r.resolve(t.val), nil
+	case tokPName:
+		return r.expandName(t)
+	case tokBNode:
+		return quad.BNode(t.val), nil
+	case tokInteger:
+		return typedValue(strings.TrimPrefix(t.val, "+"), nsXSD+"integer"), nil
+	case tokDecimal:
+		return typedValue(t.val, nsXSD+"decimal"), nil
+	case tokDouble:
+		return typedValue(t.val, nsXSD+"double"), nil
+	case tokWord:
+		switch t.val {
+		case "true", "false":
+			return typedValue(t.val, nsXSD+"boolean"), nil
+		}
+	case tokString:
+		nt, err := r.peek()
+		if err != nil {
+			return nil, err
+		}
+		if nt.typ == tokLang {
+			r.next()
+			return quad.LangString{Value: quad.String(t.val), Lang: nt.val}, nil
+		} else if nt.is(tokPunct, "^^") {
+			r.next()
+			tt, err := r.next()
+			if err != nil {
+				return nil, err
+			}
+			var typ quad.IRI
+			switch tt.typ {
+			case tokIRI:
+				typ = r.resolve(tt.val)
+			case tokPName:
+				if typ, err = r.expandName(tt); err != nil {
+					return nil, err
+				}
+			default:
+				return nil, r.errorf(tt, "expected datatype IRI, got %v", tt)
+			}
+			return typedValue(t.val, string(typ)), nil
+		}
+		return quad.String(t.val), nil
+	}
+	return nil, r.errorf(t, "unexpected token: %v", t)
+}
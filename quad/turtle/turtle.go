@@ -0,0 +1,28 @@
+// Package turtle implements Turtle and TriG RDF serialization formats.
+//
+// Turtle is defined by https://www.w3.org/TR/turtle/ and TriG, which extends Turtle
+// with named graphs, is defined by https://www.w3.org/TR/trig/.
+package turtle
+
+import (
+	"io"
+
+	"github.com/codelingo/cayley/quad"
+)
+
+func init() {
+	quad.RegisterFormat(quad.Format{
+		Name:   "turtle",
+		Ext:    []string{".ttl"},
+		Mime:   []string{"text/turtle", "application/x-turtle"},
+		Reader: func(r io.Reader) quad.ReadCloser { return NewReader(r) },
+		Writer: func(w io.Writer) quad.WriteCloser { return NewWriter(w) },
+	})
+	quad.RegisterFormat(quad.Format{
+		Name:   "trig",
+		Ext:    []string{".trig"},
+		Mime:   []string{"application/trig"},
+		Reader: func(r io.Reader) quad.ReadCloser { return NewTriGReader(r) },
+		Writer: func(w io.Writer) quad.WriteCloser { return NewTriGWriter(w) },
+	})
+}
@@ -0,0 +1,302 @@
+package turtle
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/codelingo/cayley/quad"
+)
+
+type ByQuad []quad.Quad
+
+func (a ByQuad) Len() int           { return len(a) }
+func (a ByQuad) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a ByQuad) Less(i, j int) bool { return a[i].NQuad() < a[j].NQuad() }
+
+// normalize replaces blank nodes with sequential labels in order of their appearance.
+func normalize(quads []quad.Quad) []quad.Quad {
+	names := make(map[quad.BNode]quad.BNode)
+	rename := func(v quad.Value) quad.Value {
+		b, ok := v.(quad.BNode)
+		if !ok {
+			return v
+		}
+		n, ok := names[b]
+		if !ok {
+			n = quad.BNode("b" + string(rune('0'+len(names))))
+			names[b] = n
+		}
+		return n
+	}
+	out := make([]quad.Quad, 0, len(quads))
+	for _, q := range quads {
+		out = append(out, quad.Quad{
+			Subject: rename(q.Subject), Predicate: rename(q.Predicate),
+			Object: rename(q.Object), Label: rename(q.Label),
+		})
+	}
+	return out
+}
+
+const (
+	rdfNS = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+	exNS  = "http://example.org/"
+)
+
+var testReadCases = []struct {
+	message string
+	trig    bool
+	data    string
+	expect  []quad.Quad
+	err     bool
+}{
+	{
+		message: "prefixes and predicate-object lists",
+		data: `@prefix ex: <http://example.org/> .
+PREFIX foaf: <http://xmlns.com/foaf/0.1/>
+# comment
+ex:alice a foaf:Person ;
+	foaf:knows ex:bob, ex:fred ;
+	foaf:name "Alice" ; .
+`,
+		expect: []quad.Quad{
+			{Subject: quad.IRI(exNS + "alice"), Predicate: quad.IRI(rdfNS + "type"), Object: quad.IRI("http://xmlns.com/foaf/0.1/Person")},
+			{Subject: quad.IRI(exNS + "alice"), Predicate: quad.IRI("http://xmlns.com/foaf/0.1/knows"), Object: quad.IRI(exNS + "bob")},
+			{Subject: quad.IRI(exNS + "alice"), Predicate: quad.IRI("http://xmlns.com/foaf/0.1/knows"), Object: quad.IRI(exNS + "fred")},
+			{Subject: quad.IRI(exNS + "alice"), Predicate: quad.IRI("http://xmlns.com/foaf/0.1/name"), Object: quad.String("Alice")},
+		},
+	},
+	{
+		message: "base",
+		data: `@base <http://example.org/a/> .
+<b> <c> <../d> .
+BASE <http://example.com/>
+<e> <#f> <http://example.net/g> .
+`,
+		expect: []quad.Quad{
+			{Subject: quad.IRI("http://example.org/a/b"), Predicate: quad.IRI("http://example.org/a/c"), Object: quad.IRI("http://example.org/d")},
+			{Subject: quad.IRI("http://example.com/e"), Predicate: quad.IRI("http://example.com/#f"), Object: quad.IRI("http://example.net/g")},
+		},
+	},
+	{
+		message: "literals",
+		data: `@prefix ex: <http://example.org/> .
+@prefix xsd: <http://www.w3.org/2001/XMLSchema#> .
+ex:s ex:p "chat"@fr, 'single', """long
+string""", -5, 1.5, 1e3, true, "7"^^xsd:integer, "x"^^ex:type, "a\tbé" .
+`,
+		expect: []quad.Quad{
+			{Subject: quad.IRI(exNS + "s"), Predicate: quad.IRI(exNS + "p"), Object: quad.LangString{Value: "chat", Lang: "fr"}},
+			{Subject: quad.IRI(exNS + "s"), Predicate: quad.IRI(exNS + "p"), Object: quad.String("single")},
+			{Subject: quad.IRI(exNS + "s"), Predicate: quad.IRI(exNS + "p"), Object: quad.String("long\nstring")},
+			{Subject: quad.IRI(exNS + "s"), Predicate: quad.IRI(exNS + "p"), Object: quad.Int(-5)},
+			{Subject: quad.IRI(exNS + "s"), Predicate: quad.IRI(exNS + "p"), Object: quad.TypedString{Value: "1.5", Type: "http://www.w3.org/2001/XMLSchema#decimal"}},
+			{Subject: quad.IRI(exNS + "s"), Predicate: quad.IRI(exNS + "p"), Object: quad.Float(1000)},
+			{Subject: quad.IRI(exNS + "s"), Predicate: quad.IRI(exNS + "p"), Object: quad.Bool(true)},
+			{Subject: quad.IRI(exNS + "s"), Predicate: quad.IRI(exNS + "p"), Object: quad.Int(7)},
+			{Subject: quad.IRI(exNS + "s"), Predicate: quad.IRI(exNS + "p"), Object: quad.TypedString{Value: "x", Type: exNS + "type"}},
+			{Subject: quad.IRI(exNS + "s"), Predicate: quad.IRI(exNS + "p"), Object: quad.String("a\tbé")},
+		},
+	},
+	{
+		message: "blank node property lists",
+		data: `@prefix ex: <http://example.org/> .
+_:a ex:knows [ ex:name "Bob" ; ex:age 5 ] .
+[ ex:name "Fred" ] ex:knows _:a .
+`,
+		expect: []quad.Quad{
+			{Subject: quad.BNode("b0"), Predicate: quad.IRI(exNS + "name"), Object: quad.String("Bob")},
+			{Subject: quad.BNode("b0"), Predicate: quad.IRI(exNS + "age"), Object: quad.Int(5)},
+			{Subject: quad.BNode("b1"), Predicate: quad.IRI(exNS + "knows"), Object: quad.BNode("b0")},
+			{Subject: quad.BNode("b2"), Predicate: quad.IRI(exNS + "name"), Object: quad.String("Fred")},
+			{Subject: quad.BNode("b2"), Predicate: quad.IRI(exNS + "knows"), Object: quad.BNode("b1")},
+		},
+	},
+	{
+		message: "collections",
+		data: `@prefix ex: <http://example.org/> .
+ex:s ex:list ( ex:a "b" ) ; ex:empty () .
+`,
+		expect: []quad.Quad{
+			{Subject: quad.BNode("b0"), Predicate: quad.IRI(rdfNS + "first"), Object: quad.IRI(exNS + "a")},
+			{Subject: quad.BNode("b0"), Predicate: quad.IRI(rdfNS + "rest"), Object: quad.BNode("b1")},
+			{Subject: quad.BNode("b1"), Predicate: quad.IRI(rdfNS + "first"), Object: quad.String("b")},
+			{Subject: quad.BNode("b1"), Predicate: quad.IRI(rdfNS + "rest"), Object: quad.IRI(rdfNS + "nil")},
+			{Subject: quad.IRI(exNS + "s"), Predicate: quad.IRI(exNS + "list"), Object: quad.BNode("b0")},
+			{Subject: quad.IRI(exNS + "s"), Predicate: quad.IRI(exNS + "empty"), Object: quad.IRI(rdfNS + "nil")},
+		},
+	},
+	{
+		message: "trig graphs",
+		trig:    true,
+		data: `@prefix ex: <http://example.org/> .
+ex:a ex:p ex:b .
+ex:g { ex:a ex:p ex:c }
+GRAPH ex:h {
+	ex:a ex:p ex:d .
+	ex:a ex:p ex:e .
+}
+{ ex:a ex:p ex:f }
+`,
+		expect: []quad.Quad{
+			{Subject: quad.IRI(exNS + "a"), Predicate: quad.IRI(exNS + "p"), Object: quad.IRI(exNS + "b")},
+			{Subject: quad.IRI(exNS + "a"), Predicate: quad.IRI(exNS + "p"), Object: quad.IRI(exNS + "c"), Label: quad.IRI(exNS + "g")},
+			{Subject: quad.IRI(exNS + "a"), Predicate: quad.IRI(exNS + "p"), Object: quad.IRI(exNS + "d"), Label: quad.IRI(exNS + "h")},
+			{Subject: quad.IRI(exNS + "a"), Predicate: quad.IRI(exNS + "p"), Object: quad.IRI(exNS + "e"), Label: quad.IRI(exNS + "h")},
+			{Subject: quad.IRI(exNS + "a"), Predicate: quad.IRI(exNS + "p"), Object: quad.IRI(exNS + "f")},
+		},
+	},
+	{
+		message: "undefined prefix",
+		data:    `undefined:a <b> <c> .`,
+		err:     true,
+	},
+	{
+		message: "missing dot",
+		data:    `<a> <b> <c>`,
+		err:     true,
+	},
+	{
+		message: "graph in turtle",
+		data:    `<g> { <a> <b> <c> }`,
+		err:     true,
+	},
+}
+
+func TestRead(t *testing.T) {
+	for _, c := range testReadCases {
+		var r *Reader
+		if c.trig {
+			r = NewTriGReader(strings.NewReader(c.data))
+		} else {
+			r = NewReader(strings.NewReader(c.data))
+		}
+		quads, err := quad.ReadAll(r)
+		if c.err {
+			require.Error(t, err, c.message)
+			continue
+		}
+		require.NoError(t, err, c.message)
+		require.Equal(t, c.expect, normalize(quads), c.message)
+	}
+}
+
+var testWriteCases = []struct {
+	message string
+	trig    bool
+	quads   []quad.Quad
+	expect  string
+}{
+	{
+		message: "prefixed names and lists",
+		quads: []quad.Quad{
+			{Subject: quad.IRI("http://schema.org/alice"), Predicate: quad.IRI(rdfNS + "type"), Object: quad.IRI("http://schema.org/Person")},
+			{Subject: quad.IRI("http://schema.org/alice"), Predicate: quad.IRI("http://schema.org/knows"), Object: quad.IRI("bob")},
+			{Subject: quad.IRI("http://schema.org/alice"), Predicate: quad.IRI("http://schema.org/knows"), Object: quad.BNode("fred")},
+			{Subject: quad.IRI("bob"), Predicate: quad.IRI("name"), Object: quad.LangString{Value: "Bob", Lang: "en"}},
+			{Subject: quad.IRI("bob"), Predicate: quad.IRI("age"), Object: quad.Int(5)},
+		},
+		expect: `<bob> <name> "Bob"@en ;
+	<age> "5"^^<schema:Integer> .
+`,
+	},
+	{
+		message: "trig graphs",
+		trig:    true,
+		quads: []quad.Quad{
+			{Subject: quad.IRI("a"), Predicate: quad.IRI("p"), Object: quad.IRI("b")},
+			{Subject: quad.IRI("a"), Predicate: quad.IRI("p"), Object: quad.IRI("c"), Label: quad.IRI("g")},
+			{Subject: quad.IRI("a"), Predicate: quad.IRI("q"), Object: quad.String("d"), Label: quad.IRI("g")},
+		},
+		expect: `<a> <p> <b> .
+<g> {
+	<a> <p> <c> ;
+		<q> "d" .
+}
+`,
+	},
+	{
+		message: "prefixes declared on first use",
+		trig:    true,
+		quads: []quad.Quad{
+			{Subject: quad.IRI("a"), Predicate: quad.IRI("p"), Object: quad.IRI("b"), Label: quad.IRI("g")},
+			{Subject: quad.IRI("a"), Predicate: quad.IRI(rdfNS + "value"), Object: quad.IRI("c"), Label: quad.IRI("g")},
+		},
+		expect: `<g> {
+	<a> <p> <b> .
+}
+
+@prefix rdf: <` + rdfNS + `> .
+
+<g> {
+	<a> rdf:value <c> .
+}
+`,
+	},
+}
+
+func TestWrite(t *testing.T) {
+	for _, c := range testWriteCases {
+		buf := bytes.NewBuffer(nil)
+		var w *Writer
+		if c.trig {
+			w = NewTriGWriter(buf)
+		} else {
+			w = NewWriter(buf)
+		}
+		for _, q := range c.quads {
+			require.NoError(t, w.WriteQuad(q), c.message)
+		}
+		require.NoError(t, w.Close(), c.message)
+		out := buf.String()
+		require.True(t, strings.HasSuffix(out, c.expect), "%s: unexpected output:\n%s", c.message, out)
+
+		// output must be readable and contain the same quads
+		var r *Reader
+		if c.trig {
+			r = NewTriGReader(strings.NewReader(out))
+		} else {
+			r = NewReader(strings.NewReader(out))
+		}
+		quads, err := quad.ReadAll(r)
+		require.NoError(t, err, c.message)
+		exp := append([]quad.Quad{}, c.quads...)
+		sort.Sort(ByQuad(exp))
+		sort.Sort(ByQuad(quads))
+		require.Equal(t, exp, quads, c.message)
+	}
+}
+
+func TestWriteIRIsAsGiven(t *testing.T) {
+	quads := []quad.Quad{
+		{Subject: quad.IRI("rdf:"), Predicate: quad.IRI("rdf:type"), Object: quad.IRI("schema:Person")},
+		{Subject: quad.IRI("rdf:"), Predicate: quad.IRI(rdfNS + "value"), Object: quad.TypedString{Value: "1", Type: "rdf:"}},
+	}
+	buf := bytes.NewBuffer(nil)
+	w := NewWriter(buf)
+	for _, q := range quads {
+		require.NoError(t, w.WriteQuad(q))
+	}
+	require.NoError(t, w.Close())
+	out := buf.String()
+	require.Equal(t, `<rdf:> <rdf:type> <schema:Person> .
+
+@prefix rdf: <`+rdfNS+`> .
+
+<rdf:> rdf:value "1"^^<rdf:> .
+`, out)
+
+	got, err := quad.ReadAll(NewReader(strings.NewReader(out)))
+	require.NoError(t, err)
+	require.Equal(t, quads, got)
+}
+
+func TestWriteNamedGraph(t *testing.T) {
+	w := NewWriter(bytes.NewBuffer(nil))
+	err := w.WriteQuad(quad.MakeIRI("a", "b", "c", "g"))
+	require.Equal(t, ErrNamedGraph, err)
+}
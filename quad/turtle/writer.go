@@ -0,0 +1,255 @@
+package turtle
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/codelingo/cayley/quad"
+	"github.com/codelingo/cayley/voc"
+)
+
+// ErrNamedGraph is returned by Turtle writer for quads with a label. TriG format should be used instead.
+var ErrNamedGraph = errors.New("turtle: named graphs are not supported, use trig format instead")
+
+// NewWriter returns a Turtle encoder that writes its output to the provided io.Writer.
+//
+// Full IRIs of vocabularies registered in voc package are written as prefixed names.
+// Other IRIs are written as given.
+func NewWriter(w io.Writer) *Writer {
+	return newWriter(w, false)
+}
+
+// NewTriGWriter returns a TriG encoder that writes its output to the provided io.Writer.
+//
+// See NewWriter for how IRIs are written.
+func NewTriGWriter(w io.Writer) *Writer {
+	return newWriter(w, true)
+}
+
+func newWriter(w io.Writer, trig bool) *Writer {
+	return &Writer{w: bufio.NewWriter(w), trig: trig, ns: voc.Clone()}
+}
+
+// Writer implements Turtle and TriG document generator.
+//
+// Consecutive quads with the same subject (and predicate) are written as predicate-object lists.
+type Writer struct {
+	w    *bufio.Writer
+	trig bool
+	ns   *voc.Namespaces
+	err  error
+
+	declared map[string]string // prefix -> namespace
+	pending  []voc.Namespace   // prefixes to declare before the next statement
+
+	written bool
+	open    bool // statement is not terminated
+	subj    quad.Value
+	pred    quad.Value
+
+	inGraph bool
+	label   quad.Value
+}
+
+// SetNamespaces sets a list of namespaces that will be used to shorten IRIs.
+// It must be called before writing any quads.
+func (w *Writer) SetNamespaces(ns *voc.Namespaces) {
+	w.ns = ns
+}
+
+func (w *Writer) write(s string) {
+	if w.err == nil {
+		_, w.err = w.w.WriteString(s)
+	}
+}
+
+// writePrefixes declares pending prefixes. Directives are not allowed inside
+// statements and graph blocks, thus both are terminated first.
+func (w *Writer) writePrefixes() {
+	w.endStatement()
+	if w.inGraph {
+		w.write("}\n")
+		w.inGraph, w.label = false, nil
+	}
+	if w.written {
+		w.write("\n")
+	}
+	for _, ns := range w.pending {
+		w.write("@prefix " + ns.Prefix + " <" + escapeIRI(ns.Full) + "> .\n")
+	}
+	w.write("\n")
+	w.pending = w.pending[:0]
+}
+
+// endStatement terminates the current triples statement, if any.
+func (w *Writer) endStatement() {
+	if w.open {
+		w.write(" .\n")
+		w.open = false
+	}
+	w.subj, w.pred = nil, nil
+}
+
+func (w *Writer) WriteQuad(q quad.Quad) error {
+	if w.err != nil {
+		return w.err
+	}
+	if !q.IsValid() {
+		return nil
+	}
+	if q.Label != nil && !w.trig {
+		return ErrNamedGraph
+	}
+	var label string
+	if q.Label != nil {
+		label = w.term(q.Label)
+	}
+	subj, pred, obj := w.term(q.Subject), w.predicate(q.Predicate), w.term(q.Object)
+	if len(w.pending) != 0 {
+		w.writePrefixes()
+	}
+	w.written = true
+	indent := ""
+	if w.trig {
+		if w.inGraph && (q.Label == nil || !sameValue(q.Label, w.label)) {
+			w.endStatement()
+			w.write("}\n\n")
+			w.inGraph, w.label = false, nil
+		}
+		if !w.inGraph && q.Label != nil {
+			w.endStatement()
+			w.write(label + " {\n")
+			w.inGraph, w.label = true, q.Label
+		}
+		if w.inGraph {
+			indent = "\t"
+		}
+	}
+	switch {
+	case w.open && sameValue(w.subj, q.Subject) && sameValue(w.pred, q.Predicate):
+		w.write(" ,\n" + indent + "\t\t" + obj)
+	case w.open && sameValue(w.subj, q.Subject):
+		w.write(" ;\n" + indent + "\t" + pred + " " + obj)
+	default:
+		w.endStatement()
+		w.write(indent + subj + " " + pred + " " + obj)
+	}
+	w.open = true
+	w.subj, w.pred = q.Subject, q.Predicate
+	return w.err
+}
+
+func (w *Writer) Close() error {
+	w.endStatement()
+	if w.inGraph {
+		w.write("}\n")
+		w.inGraph = false
+	}
+	if w.err != nil {
+		return w.err
+	}
+	return w.w.Flush()
+}
+
+func sameValue(a, b quad.Value) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a == b || a.String() == b.String()
+}
+
+func (w *Writer) predicate(p quad.Value) string {
+	if iri, ok := p.(quad.IRI); ok && iri == rdfType {
+		return "a"
+	}
+	return w.term(p)
+}
+
+var iriEscaper = strings.NewReplacer(
+	">", `\u003E`,
+	"<", `\u003C`,
+	`"`, `\u0022`,
+	" ", `\u0020`,
+	"{", `\u007B`,
+	"}", `\u007D`,
+	"|", `\u007C`,
+	"`", `\u0060`,
+	`\`, `\u005C`,
+	"^", `\u005E`,
+)
+
+func escapeIRI(s string) string {
+	return iriEscaper.Replace(s)
+}
+
+// isValidLocal checks if a string can be written as a local part of prefixed name without escaping.
+func isValidLocal(s string) bool {
+	if s == "" {
+		return true
+	}
+	for i, r := range s {
+		switch {
+		case r == '.':
+			if i == 0 || i == len(s)-1 {
+				return false
+			}
+		case r == '-' || r == 0xB7:
+			if i == 0 {
+				return false
+			}
+		case r == '_' || r == ':' || unicode.IsDigit(r) || isPNCharsBase(r):
+		default:
+			return false
+		}
+	}
+	return utf8.ValidString(s)
+}
+
+// iri writes full IRIs of known vocabularies as prefixed names and
+// schedules a declaration of the prefix. Other IRIs are written as given.
+func (w *Writer) iri(v quad.IRI) string {
+	s := string(v)
+	if short := w.ns.ShortIRI(s); short != s {
+		i := strings.Index(short, ":")
+		if i >= 0 && isValidLocal(short[i+1:]) && isValidLocal(short[:i]) && strings.HasSuffix(s, short[i+1:]) {
+			pref, ns := short[:i+1], s[:len(s)-len(short[i+1:])]
+			if w.declare(pref, ns) {
+				return short
+			}
+		}
+	}
+	return "<" + escapeIRI(s) + ">"
+}
+
+// declare schedules a prefix declaration, if it was not declared yet.
+// It returns false if the prefix is already bound to a different namespace.
+func (w *Writer) declare(pref, ns string) bool {
+	if cur, ok := w.declared[pref]; ok {
+		return cur == ns
+	}
+	if w.declared == nil {
+		w.declared = make(map[string]string)
+	}
+	w.declared[pref] = ns
+	w.pending = append(w.pending, voc.Namespace{Prefix: pref, Full: ns})
+	return true
+}
+
+// term returns a Turtle representation of the value.
+func (w *Writer) term(v quad.Value) string {
+	if ts, ok := v.(quad.TypedStringer); ok {
+		v = ts.TypedString()
+	}
+	switch v := v.(type) {
+	case quad.IRI:
+		return w.iri(v)
+	case quad.TypedString:
+		return v.Value.String() + "^^" + w.iri(v.Type)
+	}
+	// other values use the same notation as N-Quads
+	return v.String()
+}
@@ -24,6 +24,7 @@ import (
 	"github.com/dop251/goja"
 
 	"github.com/codelingo/cayley/graph/iterator"
+	"github.com/codelingo/cayley/graph/labels"
 	"github.com/codelingo/cayley/graph/path"
 	"github.com/codelingo/cayley/quad"
 	"github.com/codelingo/cayley/schema"
@@ -46,6 +47,18 @@ func (g *graphObject) AddDefaultNamespaces() {
 func (g *graphObject) LoadNamespaces() error {
 	return schema.LoadNamespaces(g.s.ctx, g.s.qs, &g.s.ns)
 }
+func (g *graphObject) Using(call goja.FunctionCall) goja.Value {
+	qv, err := toQuadValues(exportArgs(call.Arguments))
+	if err != nil {
+		return throwErr(g.s.vm, err)
+	}
+	if len(qv) == 0 {
+		g.s.qs = g.s.base
+	} else {
+		g.s.qs = labels.New(g.s.base, qv...)
+	}
+	return g.s.vm.ToValue(g)
+}
 func (g *graphObject) V(call goja.FunctionCall) goja.Value {
 	return g.Vertex(call)
 }
@@ -150,6 +163,30 @@ func cmpRegexp(vm *goja.Runtime, call goja.FunctionCall) goja.Value {
 	return vm.ToValue(cmpOperator{regex: true, val: v})
 }
 
+func geoPoint(vm *goja.Runtime, call goja.FunctionCall) goja.Value {
+	args := exportArgs(call.Arguments)
+	if len(args) != 2 {
+		return throwErr(vm, errArgCount2{Expected: 2, Got: len(args)})
+	}
+	p, err := toPoint(args)
+	if err != nil {
+		return throwErr(vm, err)
+	}
+	return vm.ToValue(p)
+}
+
+func geoWKT(vm *goja.Runtime, call goja.FunctionCall) goja.Value {
+	args := toStrings(exportArgs(call.Arguments))
+	if len(args) != 1 {
+		return throwErr(vm, errArgCount2{Expected: 1, Got: len(args)})
+	}
+	g, err := quad.ParseWKT(args[0])
+	if err != nil {
+		return throwErr(vm, err)
+	}
+	return vm.ToValue(g.Value())
+}
+
 type cmpOperator struct {
 	op    iterator.Operator
 	val   quad.Value
@@ -200,6 +237,9 @@ var defaultEnv = map[string]func(vm *goja.Runtime, call goja.FunctionCall) goja.
 		return quad.TypedString{Value: quad.String(s), Type: quad.IRI(typ)}
 	}),
 
+	"point": geoPoint,
+	"wkt":   geoWKT,
+
 	"lt":    cmpOpType(iterator.CompareLT),
 	"lte":   cmpOpType(iterator.CompareLTE),
 	"gt":    cmpOpType(iterator.CompareGT),
@@ -248,6 +288,44 @@ func toInt(o interface{}) int {
 	}
 }
 
+func toFloat(o interface{}) (float64, bool) {
+	switch v := o.(type) {
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	default:
+		return 0, false
+	}
+}
+
+// toPoint converts a point value, a WKT string or a pair of longitude and latitude to a point.
+func toPoint(o interface{}) (quad.Point, error) {
+	switch v := o.(type) {
+	case quad.Point:
+		return v, nil
+	case string:
+		g, err := quad.ParseWKT(v)
+		if err != nil {
+			return quad.Point{}, err
+		} else if g.Type != quad.GeoPoint {
+			return quad.Point{}, fmt.Errorf("expected a point, got: %s", g.Type)
+		}
+		return g.Parts[0][0], nil
+	case []interface{}:
+		if len(v) == 2 {
+			lon, ok1 := toFloat(v[0])
+			lat, ok2 := toFloat(v[1])
+			if ok1 && ok2 {
+				return quad.Point{Lon: lon, Lat: lat}, nil
+			}
+		}
+	}
+	return quad.Point{}, fmt.Errorf("expected a point, got: %v", o)
+}
+
 func toQuadValue(o interface{}) (quad.Value, error) {
 	var qv quad.Value
 	switch v := o.(type) {
@@ -48,7 +48,7 @@ func init() {
 
 func NewSession(qs graph.QuadStore) *Session {
 	s := &Session{
-		qs: qs, limit: -1,
+		qs: qs, base: qs, limit: -1,
 	}
 	if err := s.buildEnv(); err != nil {
 		panic(err)
@@ -57,9 +57,10 @@ func NewSession(qs graph.QuadStore) *Session {
 }
 
 type Session struct {
-	qs graph.QuadStore
-	vm *goja.Runtime
-	ns voc.Namespaces
+	qs   graph.QuadStore
+	base graph.QuadStore // quad store without the scope set by graph.Using
+	vm   *goja.Runtime
+	ns   voc.Namespaces
 
 	last string
 	p    *goja.Program
@@ -100,8 +101,30 @@ func (s *Session) tagsToValueMap(m map[string]graph.Value) map[string]interface{
 	for k, v := range m {
 		outputMap[k] = quadValueToNative(s.qs.NameOf(v))
 	}
+	collectSteps(outputMap)
 	return outputMap
 }
+
+// collectSteps replaces step tags of paths (see iterator.StepTag) with arrays of steps.
+func collectSteps(m map[string]interface{}) {
+	steps := make(map[string][]interface{})
+	for k, v := range m {
+		tag, i, ok := iterator.SplitStepTag(k)
+		if !ok {
+			continue
+		}
+		arr := steps[tag]
+		for len(arr) <= i {
+			arr = append(arr, nil)
+		}
+		arr[i] = v
+		steps[tag] = arr
+		delete(m, k)
+	}
+	for tag, arr := range steps {
+		m[tag] = arr
+	}
+}
 func (s *Session) runIteratorToArray(it graph.Iterator, limit int) ([]map[string]interface{}, error) {
 	ctx := s.context()
 
@@ -343,6 +366,7 @@ func (s *Session) Collate(result query.Result) {
 			delete(obj, k)
 		}
 	}
+	collectSteps(obj)
 	if len(obj) != 0 {
 		s.dataOutput = append(s.dataOutput, obj)
 	}
@@ -19,6 +19,7 @@ import (
 	"reflect"
 	"sort"
 	"testing"
+	"time"
 
 	"golang.org/x/net/context"
 
@@ -61,6 +62,7 @@ var testQueries = []struct {
 	query   string
 	tag     string
 	expect  []string
+	ordered bool // results are compared in order
 	err     bool // TODO(dennwc): define error types for Gremlin and handle them
 }{
 	// Simple query tests.
@@ -155,6 +157,21 @@ var testQueries = []struct {
 		`,
 		expect: []string{"<charlie>"},
 	},
+	{
+		message: "use .Search()",
+		query: `
+			g.V().Search("Person smart").In("<status>").All()
+		`,
+		expect: []string{"<emily>", "<greg>"},
+	},
+	{
+		message: "use .Search() with score tag",
+		query: `
+			g.V("<bob>").Out("<status>").Search("cool", "score").All()
+		`,
+		tag:    "score",
+		expect: []string{`"6.931471805599453E-01"^^<schema:Float>`},
+	},
 	{
 		message: "use .Both()",
 		query: `
@@ -428,6 +445,22 @@ var testQueries = []struct {
 		`,
 		expect: []string{"<dani>", "<fred>"},
 	},
+	{
+		message: "restrict queries to a graph with Using",
+		query: `
+			graph.Using("<smart_graph>")
+			g.V().In("<status>").All()
+		`,
+		expect: []string{"<emily>", "<greg>"},
+	},
+	{
+		message: "reset the graph with Using",
+		query: `
+			graph.Using("<smart_graph>").Using()
+			g.V("<greg>").Out("<status>").All()
+		`,
+		expect: []string{"cool_person", "smart_person"},
+	},
 	{
 		message: "issue #254",
 		query:   `g.V({"id":"<alice>"}).All()`,
@@ -522,6 +555,81 @@ var testQueries = []struct {
 		`,
 		expect: []string{"<bob>", "<dani>", "<fred>", "<greg>"},
 	},
+	{
+		message: "shortest path",
+		query: `
+			g.V("<charlie>").ShortestPath(g.V("<greg>"), "<follows>").All();
+		`,
+		tag:    "path[2]",
+		expect: []string{"<dani>"},
+	},
+	{
+		message: "shortest path to any of nodes",
+		query: `
+			g.V("<alice>").ShortestPath(["<fred>", "<greg>"], "<follows>", {tag: "p", weightTag: "w"}).All();
+		`,
+		tag:    "w",
+		expect: []string{`"2"^^<schema:Integer>`, `"3"^^<schema:Integer>`},
+	},
+	{
+		message: "all paths",
+		query: `
+			g.V("<charlie>").AllPaths("<greg>", 3, "<follows>").All();
+		`,
+		expect: []string{"<greg>", "<greg>"},
+	},
+	{
+		message: "all paths as arrays",
+		query: `
+			g.V("<charlie>").AllPaths("<greg>", 2, "<follows>").ForEach(function(d) { g.Emit(d.path.join(" ")) });
+		`,
+		expect: []string{"<charlie> <follows> <dani> <follows> <greg>"},
+	},
+	{
+		message: "group by with count",
+		query: `
+			g.V().Tag("person").Out("<follows>").Tag("target").GroupBy("target").Aggregate("count", "person", "n").All();
+		`,
+		tag:    "n",
+		expect: []string{`"1"^^<schema:Integer>`, `"2"^^<schema:Integer>`, `"2"^^<schema:Integer>`, `"3"^^<schema:Integer>`},
+	},
+	{
+		message: "group by tags in results",
+		query: `
+			g.V("<charlie>", "<dani>").Tag("person").Out("<follows>").GroupBy("person").Aggregate("count", "n").ForEach(function(d) { g.Emit(d.id + " " + d.person + " " + d.n) });
+		`,
+		expect: []string{"<charlie> <charlie> 2", "<dani> <dani> 2"},
+	},
+	{
+		message: "aggregate all results",
+		query: `
+			g.V().Has("<status>").Aggregate("count", "n").All();
+		`,
+		expect: []string{`"5"^^<schema:Integer>`},
+	},
+	{
+		message: "order nodes and skip",
+		query: `
+			g.V().Has("<status>", "cool_person").Order().Skip(1).All();
+		`,
+		expect:  []string{"<dani>", "<greg>"},
+		ordered: true,
+	},
+	{
+		message: "order by tags",
+		query: `
+			g.V("<bob>", "<charlie>", "<dani>").Tag("person").Out("<follows>").Tag("target").Order("target", true, "person").ForEach(function(d) { g.Emit(d.person + " " + d.target) });
+		`,
+		expect:  []string{"<dani> <greg>", "<bob> <fred>", "<charlie> <dani>", "<charlie> <bob>", "<dani> <bob>"},
+		ordered: true,
+	},
+	{
+		message: "order with a wrong argument",
+		query: `
+			g.V().Order(1).All();
+		`,
+		err: true,
+	},
 }
 
 func runQueryGetTag(rec func(), g []quad.Quad, qu string, tag string) ([]string, error) {
@@ -574,8 +682,10 @@ func TestGizmo(t *testing.T) {
 				}
 				t.Errorf("unexpected error on %s: %v", test.message, err)
 			}
-			sort.Strings(got)
-			sort.Strings(test.expect)
+			if !test.ordered {
+				sort.Strings(got)
+				sort.Strings(test.expect)
+			}
 			t.Log("testing", test.message)
 			if !reflect.DeepEqual(got, test.expect) {
 				t.Errorf("Failed to %s, got: %v expected: %v", test.message, got, test.expect)
@@ -596,6 +706,82 @@ var issue160TestGraph = []quad.Quad{
 	quad.MakeRaw("danie", "is", "not cool", ""),
 }
 
+var geoTestGraph = []quad.Quad{
+	quad.Make(quad.IRI("sf"), quad.IRI("location"), quad.Point{Lon: -122.4194, Lat: 37.7749}, nil),
+	quad.Make(quad.IRI("oakland"), quad.IRI("location"), quad.Point{Lon: -122.2711, Lat: 37.8044}, nil),
+	quad.Make(quad.IRI("la"), quad.IRI("location"), quad.Point{Lon: -118.2437, Lat: 34.0522}, nil),
+	quad.Make(quad.IRI("bay"), quad.IRI("area"), quad.WKT("POLYGON((-122.5 37.7, -122.2 37.7, -122.2 37.9, -122.5 37.9, -122.5 37.7))"), nil),
+}
+
+var geoQueries = []struct {
+	message string
+	query   string
+	expect  []string
+	err     bool
+}{
+	{
+		message: "use WithinDistance",
+		query: `
+			g.V().Out("<location>").WithinDistance(point(-122.4194, 37.7749), 20000).In("<location>").All()
+		`,
+		expect: []string{"<oakland>", "<sf>"},
+	},
+	{
+		message: "use WithinDistance with WKT",
+		query: `
+			g.V("<sf>").Out("<location>").WithinDistance(wkt("POINT(-118.2437 34.0522)"), 600000).In("<location>").All()
+		`,
+		expect: []string{"<sf>"},
+	},
+	{
+		message: "use WithinBox",
+		query: `
+			g.V().WithinBox("POINT(-123 37)", [-122, 38]).In().All()
+		`,
+		expect: []string{"<bay>", "<oakland>", "<sf>"},
+	},
+	{
+		message: "use WithinBox that cuts a polygon",
+		query: `
+			g.V().WithinBox(point(-123, 37), point(-122, 37.8)).In().All()
+		`,
+		expect: []string{"<sf>"},
+	},
+	{
+		message: "use WithinDistance without a point",
+		query: `
+			g.V().WithinDistance(wkt("LINESTRING(1 1, 2 2)"), 1000).All()
+		`,
+		err: true,
+	},
+}
+
+func TestGeo(t *testing.T) {
+	for _, test := range geoQueries {
+		func() {
+			rec := func() {
+				if r := recover(); r != nil {
+					t.Errorf("Unexpected panic on %s: %v", test.message, r)
+				}
+			}
+			defer rec()
+			got, err := runQueryGetTag(rec, geoTestGraph, test.query, TopResultTag)
+			if test.err {
+				if err == nil {
+					t.Errorf("expected an error on %s", test.message)
+				}
+				return
+			} else if err != nil {
+				t.Errorf("unexpected error on %s: %v", test.message, err)
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, test.expect) {
+				t.Errorf("Failed to %s, got: %v expected: %v", test.message, got, test.expect)
+			}
+		}()
+	}
+}
+
 func TestIssue160(t *testing.T) {
 	qu := `g.V().Tag('query').Out(raw('follows')).Out(raw('follows')).ForEach(function (item) { if (item.id !== item.query) g.Emit({ id: item.id }); })`
 	expect := []string{
@@ -623,3 +809,58 @@ func TestIssue160(t *testing.T) {
 		t.Errorf("Unexpected result, got: %q expected: %q", got, expect)
 	}
 }
+
+func TestStanding(t *testing.T) {
+	qs, _ := graph.NewQuadStore("memstore", "", nil)
+	w, _ := graph.NewQuadWriter("single", qs, nil)
+	w.AddQuad(quad.MakeIRI("alice", "follows", "bob", ""))
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	out := make(chan query.Diff)
+	errc := make(chan error, 1)
+	go func() {
+		errc <- query.Standing(ctx, qs, Name, `g.V("<alice>").Out("<follows>").All()`, out)
+	}()
+	next := func() query.Diff {
+		select {
+		case d := <-out:
+			return d
+		case err := <-errc:
+			t.Fatalf("unexpected error: %v", err)
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout")
+		}
+		return query.Diff{}
+	}
+	row := func(id string) interface{} {
+		return map[string]interface{}{"id": "<" + id + ">"}
+	}
+
+	if d := next(); !reflect.DeepEqual(d.Added, []interface{}{row("bob")}) || len(d.Removed) != 0 {
+		t.Fatalf("unexpected initial results: %#v", d)
+	}
+	w.AddQuad(quad.MakeIRI("alice", "follows", "charlie", ""))
+	if d := next(); !reflect.DeepEqual(d.Added, []interface{}{row("charlie")}) || len(d.Removed) != 0 {
+		t.Fatalf("unexpected diff: %#v", d)
+	}
+	// not related to the query; must not produce an empty diff
+	w.AddQuad(quad.MakeIRI("bob", "follows", "fred", ""))
+	w.RemoveQuad(quad.MakeIRI("alice", "follows", "bob", ""))
+	if d := next(); !reflect.DeepEqual(d.Removed, []interface{}{row("bob")}) || len(d.Added) != 0 {
+		t.Fatalf("unexpected diff: %#v", d)
+	}
+	// closed store ends the query instead of subscribing again
+	graph.CloseFeed(qs)
+	select {
+	case err := <-errc:
+		if err != graph.ErrFeedClosed {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout")
+	}
+	if graph.Subscribed(qs) {
+		t.Fatal("feed was created for a closed store")
+	}
+}
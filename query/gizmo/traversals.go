@@ -24,6 +24,7 @@ import (
 	"github.com/codelingo/cayley/graph"
 	"github.com/codelingo/cayley/graph/iterator"
 	"github.com/codelingo/cayley/graph/path"
+	"github.com/codelingo/cayley/quad"
 )
 
 type pathObject struct {
@@ -117,6 +118,78 @@ func (p *pathObject) FollowRecursive(call goja.FunctionCall) goja.Value {
 	np = np.FollowRecursive(preds[0], tags)
 	return p.newVal(np)
 }
+func (p *pathObject) ShortestPath(call goja.FunctionCall) goja.Value {
+	return p.paths(call, false)
+}
+func (p *pathObject) AllPaths(call goja.FunctionCall) goja.Value {
+	return p.paths(call, true)
+}
+func (p *pathObject) paths(call goja.FunctionCall, all bool) goja.Value {
+	args := exportArgs(call.Arguments)
+	depth := 0
+	if all {
+		if len(args) < 2 {
+			return throwErr(p.s.vm, errArgCount2{Expected: 2, Got: len(args)})
+		}
+		depth = toInt(args[1])
+		args = append([]interface{}{args[0]}, args[2:]...)
+	}
+	if len(args) == 0 || len(args) > 3 {
+		return throwErr(p.s.vm, errArgCount{Got: len(args)})
+	}
+	var to *path.Path
+	switch v := args[0].(type) {
+	case nil:
+	case *path.Path:
+		to = v
+	default:
+		arr, ok := v.([]interface{})
+		if !ok {
+			arr = []interface{}{v}
+		}
+		qv, err := toQuadValues(arr)
+		if err != nil {
+			return throwErr(p.s.vm, err)
+		}
+		to = path.StartMorphism(qv...)
+	}
+	var via []interface{}
+	if len(args) > 1 {
+		via = toVia([]interface{}{args[1]})
+	}
+	var opts path.PathOptions
+	if len(args) > 2 {
+		m, ok := args[2].(map[string]interface{})
+		if !ok {
+			return throwErr(p.s.vm, fmt.Errorf("expected options object, got: %T", args[2]))
+		}
+		for k, v := range m {
+			switch k {
+			case "tag":
+				opts.Tag, _ = v.(string)
+			case "weightTag":
+				opts.WeightTag, _ = v.(string)
+			case "reverse":
+				opts.Reverse, _ = v.(bool)
+			case "weight":
+				qv, err := toQuadValue(v)
+				if err != nil {
+					return throwErr(p.s.vm, err)
+				}
+				opts.Weight = qv
+			default:
+				return throwErr(p.s.vm, fmt.Errorf("unknown path option: %q", k))
+			}
+		}
+	}
+	np := p.clonePath()
+	if all {
+		np = np.AllPathsWith(to, depth, opts, via...)
+	} else {
+		np = np.ShortestPathWith(to, opts, via...)
+	}
+	return p.newVal(np)
+}
 func (p *pathObject) And(ep *pathObject) *pathObject {
 	np := p.clonePath().And(ep.path)
 	return p.new(np)
@@ -253,6 +326,42 @@ func (p *pathObject) Filter(args ...cmpOperator) (*pathObject, error) {
 	}
 	return p.new(np), nil
 }
+func (p *pathObject) Search(query string, scoreTags ...string) *pathObject {
+	np := p.clonePath().Search(query, scoreTags...)
+	return p.new(np)
+}
+func (p *pathObject) WithinDistance(call goja.FunctionCall) goja.Value {
+	args := exportArgs(call.Arguments)
+	if len(args) != 2 {
+		return throwErr(p.s.vm, errArgCount2{Expected: 2, Got: len(args)})
+	}
+	pt, err := toPoint(args[0])
+	if err != nil {
+		return throwErr(p.s.vm, err)
+	}
+	meters, ok := toFloat(args[1])
+	if !ok {
+		return throwErr(p.s.vm, fmt.Errorf("expected a distance in meters, got: %v", args[1]))
+	}
+	np := p.clonePath().WithinDistance(pt, meters)
+	return p.newVal(np)
+}
+func (p *pathObject) WithinBox(call goja.FunctionCall) goja.Value {
+	args := exportArgs(call.Arguments)
+	if len(args) != 2 {
+		return throwErr(p.s.vm, errArgCount2{Expected: 2, Got: len(args)})
+	}
+	var pts [2]quad.Point
+	for i, a := range args {
+		pt, err := toPoint(a)
+		if err != nil {
+			return throwErr(p.s.vm, err)
+		}
+		pts[i] = pt
+	}
+	np := p.clonePath().WithinBox(pts[0], pts[1])
+	return p.newVal(np)
+}
 func (p *pathObject) Limit(n int) *pathObject {
 	np := p.clonePath().Limit(int64(n))
 	return p.new(np)
@@ -265,3 +374,52 @@ func (p *pathObject) Count() *pathObject {
 	np := p.clonePath().Count()
 	return p.new(np)
 }
+func (p *pathObject) Order(call goja.FunctionCall) goja.Value {
+	// each tag starts a new key, and an optional flag after it sets descending order;
+	// a flag without a tag orders by the current nodes
+	var keys []iterator.OrderKey
+	for _, a := range exportArgs(call.Arguments) {
+		switch a := a.(type) {
+		case string:
+			keys = append(keys, iterator.OrderKey{Tag: a})
+		case bool:
+			if len(keys) == 0 {
+				keys = append(keys, iterator.OrderKey{})
+			}
+			keys[len(keys)-1].Desc = a
+		default:
+			return throwErr(p.s.vm, fmt.Errorf("expected string or bool, got: %T", a))
+		}
+	}
+	np := p.clonePath().Order(keys...)
+	return p.newVal(np)
+}
+func (p *pathObject) GroupBy(tags ...string) *pathObject {
+	np := p.clonePath().GroupBy(tags...)
+	return p.new(np)
+}
+func (p *pathObject) Aggregate(call goja.FunctionCall) goja.Value {
+	args := exportArgs(call.Arguments)
+	if len(args) != 2 && len(args) != 3 {
+		return throwErr(p.s.vm, errArgCount{Got: len(args)})
+	}
+	strs := make([]string, len(args))
+	for i, a := range args {
+		s, ok := a.(string)
+		if !ok {
+			return throwErr(p.s.vm, fmt.Errorf("expected string, got: %T", a))
+		}
+		strs[i] = s
+	}
+	op := iterator.AggregateOp(strs[0])
+	if !op.Valid() {
+		return throwErr(p.s.vm, fmt.Errorf("unknown aggregate function: %q", strs[0]))
+	}
+	// tag is optional: Aggregate(op, as) aggregates the current nodes
+	tag, as := "", strs[len(strs)-1]
+	if len(strs) == 3 {
+		tag = strs[1]
+	}
+	np := p.clonePath().Aggregate(op, tag, as)
+	return p.newVal(np)
+}
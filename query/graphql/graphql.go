@@ -14,6 +14,7 @@ import (
 	"golang.org/x/net/context"
 
 	"github.com/codelingo/cayley/graph"
+	"github.com/codelingo/cayley/graph/iterator"
 	"github.com/codelingo/cayley/graph/path"
 	"github.com/codelingo/cayley/quad"
 	"github.com/codelingo/cayley/query"
@@ -51,6 +52,7 @@ type Session struct {
 }
 
 func (s *Session) Execute(ctx context.Context, qu string, out chan query.Result, limit int) {
+	defer close(out)
 	q, err := Parse(strings.NewReader(qu))
 	if err != nil {
 		select {
@@ -81,6 +83,10 @@ var (
 	ValueKey = "id"
 	LimitKey = "first"
 	SkipKey  = "offset"
+
+	// PathKey and WeightKey are fields with steps and total weights of paths found by @shortestPath and @allPaths.
+	PathKey   = "path"
+	WeightKey = "weight"
 )
 
 type Query struct {
@@ -93,16 +99,45 @@ type has struct {
 	Values []quad.Value
 }
 
+// paths are arguments of @shortestPath and @allPaths directives.
+type paths struct {
+	All    bool
+	To     []quad.Value
+	Via    []quad.Value
+	Depth  int
+	Weight quad.Value
+}
+
+// groupBy are arguments of the @groupBy directive.
+type groupBy struct {
+	By []string
+}
+
+// orderBy are arguments of the @order directive.
+type orderBy struct {
+	By   []string
+	Desc bool
+}
+
 type field struct {
-	Via    quad.IRI
-	Alias  string
-	Rev    bool
-	Opt    bool
-	Has    []has
-	Fields []field
+	Via       quad.IRI
+	Alias     string
+	Rev       bool
+	Opt       bool
+	Has       []has
+	Paths     *paths
+	GroupBy   *groupBy
+	Aggregate iterator.AggregateOp
+	Order     []orderBy
+	Fields    []field
 }
 
-func (f field) isSave() bool { return len(f.Has)+len(f.Fields) == 0 }
+func (f field) isSave() bool { return len(f.Has)+len(f.Fields) == 0 && f.Paths == nil }
+
+// isPathKey checks if a field of an object found by @shortestPath or @allPaths holds steps or weight of the path.
+func (f field) isPathKey() bool {
+	return f.isSave() && (f.Via == quad.IRI(PathKey) || f.Via == quad.IRI(WeightKey))
+}
 
 type object struct {
 	id     graph.Value
@@ -142,12 +177,40 @@ func iterateObject(ctx context.Context, qs graph.QuadStore, f *field, p *path.Pa
 			}
 		}
 	}
+	// steps of paths are only returned if the path field is requested
+	var stepTag string
+	if f.Paths != nil {
+		opts := path.PathOptions{Tag: PathKey, Weight: f.Paths.Weight, Reverse: f.Rev}
+		for _, f2 := range f.Fields {
+			if !f2.isPathKey() {
+				continue
+			} else if f2.Via == quad.IRI(PathKey) {
+				opts.Tag, stepTag = f2.Alias, f2.Alias
+			} else {
+				opts.WeightTag = f2.Alias
+			}
+		}
+		via := make([]interface{}, 0, len(f.Paths.Via))
+		for _, v := range f.Paths.Via {
+			via = append(via, v)
+		}
+		to := path.StartMorphism(f.Paths.To...)
+		if f.Paths.All {
+			p = p.AllPathsWith(to, f.Paths.Depth, opts, via...)
+		} else {
+			p = p.ShortestPathWith(to, opts, via...)
+		}
+	}
 	for _, f2 := range f.Fields {
+		if f.Paths != nil && f2.isPathKey() {
+			continue
+		}
 		if f2.isSave() {
 			if f2.Via == quad.IRI(ValueKey) {
 				p = p.Tag(f2.Alias)
 			} else {
-				if f2.Opt {
+				// objects without a value are still counted by other aggregates
+				if f2.Opt || f2.Aggregate != "" {
 					if f2.Rev {
 						p = p.SaveOptionalReverse(f2.Via, f2.Alias)
 					} else {
@@ -163,6 +226,13 @@ func iterateObject(ctx context.Context, qs graph.QuadStore, f *field, p *path.Pa
 			}
 		}
 	}
+	var err error
+	if p, err = groupObjects(f, p); err != nil {
+		return nil, err
+	}
+	if p, err = orderObjects(f, p); err != nil {
+		return nil, err
+	}
 	if skip > 0 {
 		p = p.Skip(int64(skip))
 	}
@@ -174,6 +244,9 @@ func iterateObject(ctx context.Context, qs graph.QuadStore, f *field, p *path.Pa
 	it, _ := p.BuildIterator().Optimize()
 	it, _ = qs.OptimizeIterator(it)
 	defer it.Close()
+	if e := graph.ExplainerFromContext(ctx); e != nil && !e.Track(it) {
+		return nil, nil
+	}
 
 	var results []object
 	for i := 0; limit < 0 || i < limit; i++ {
@@ -219,7 +292,19 @@ func iterateObject(ctx context.Context, qs graph.QuadStore, f *field, p *path.Pa
 	// load values and complex keys
 	for _, r := range results {
 		obj := make(map[string]interface{})
+		steps := make(map[string][]quad.Value)
 		for k, arr := range r.fields {
+			if tag, i, ok := iterator.SplitStepTag(k); ok && f.Paths != nil {
+				if tag == stepTag {
+					s := steps[tag]
+					for len(s) <= i {
+						s = append(s, nil)
+					}
+					s[i] = qs.NameOf(arr[0])
+					steps[tag] = s
+				}
+				continue
+			}
 			var vals []quad.Value
 			for _, v := range arr {
 				vals = append(vals, qs.NameOf(v))
@@ -230,15 +315,25 @@ func iterateObject(ctx context.Context, qs graph.QuadStore, f *field, p *path.Pa
 				obj[k] = vals
 			}
 		}
+		for tag, s := range steps {
+			obj[tag] = s
+		}
 		for _, f2 := range f.Fields {
 			if f2.isSave() {
 				continue
 			}
 			p := path.StartPathNodes(qs, r.id)
-			if f2.Rev {
-				p = p.In(f2.Via)
-			} else {
-				p = p.Out(f2.Via)
+			if f2.Paths == nil {
+				if f2.Rev {
+					p = p.In(f2.Via)
+				} else {
+					p = p.Out(f2.Via)
+				}
+			} else if len(f2.Paths.Via) == 0 {
+				// paths of nested fields follow the field predicate by default
+				pt := *f2.Paths
+				pt.Via = []quad.Value{f2.Via}
+				f2.Paths = &pt
 			}
 			arr, err := iterateObject(ctx, qs, &f2, p)
 			if err != nil {
@@ -257,6 +352,78 @@ func iterateObject(ctx context.Context, qs graph.QuadStore, f *field, p *path.Pa
 	return out, nil
 }
 
+// groupObjects groups objects by fields listed in @groupBy, and computes aggregates of other fields.
+func groupObjects(f *field, p *path.Path) (*path.Path, error) {
+	if f.GroupBy == nil {
+		for _, f2 := range f.Fields {
+			if f2.Aggregate != "" {
+				return nil, fmt.Errorf("@%s on field %s requires @groupBy on %s", f2.Aggregate, f2.Alias, f.Alias)
+			}
+		}
+		return p, nil
+	}
+	by := make(map[string]bool, len(f.GroupBy.By))
+	for _, tag := range f.GroupBy.By {
+		by[tag] = true
+	}
+	fields := make(map[string]bool, len(f.Fields))
+	for _, f2 := range f.Fields {
+		if !f2.isSave() {
+			return nil, fmt.Errorf("@groupBy does not support nested objects: %s", f2.Alias)
+		} else if f2.Aggregate == "" && !by[f2.Alias] {
+			return nil, fmt.Errorf("field %s should be listed in @groupBy or aggregated", f2.Alias)
+		}
+		fields[f2.Alias] = true
+	}
+	for _, tag := range f.GroupBy.By {
+		if !fields[tag] {
+			return nil, fmt.Errorf("unknown field in @groupBy: %s", tag)
+		}
+	}
+	p = p.GroupBy(f.GroupBy.By...)
+	for _, f2 := range f.Fields {
+		if f2.Aggregate != "" {
+			p = p.Aggregate(f2.Aggregate, f2.Alias, f2.Alias)
+		}
+	}
+	return p, nil
+}
+
+// orderObjects sorts objects by fields listed in @order directives, or by their ids.
+func orderObjects(f *field, p *path.Path) (*path.Path, error) {
+	if len(f.Order) == 0 {
+		return p, nil
+	}
+	var keys []iterator.OrderKey
+	for _, o := range f.Order {
+		if len(o.By) == 0 {
+			keys = append(keys, iterator.OrderKey{Desc: o.Desc})
+			continue
+		}
+	loop:
+		for _, name := range o.By {
+			for _, f2 := range f.Fields {
+				if f2.Alias != name {
+					continue
+				} else if !f2.isSave() {
+					return nil, fmt.Errorf("@order does not support nested objects: %s", name)
+				}
+				tag := name
+				if f2.Via == quad.IRI(ValueKey) {
+					tag = ""
+				}
+				keys = append(keys, iterator.OrderKey{Tag: tag, Desc: o.Desc})
+				continue loop
+			}
+			if name != ValueKey {
+				return nil, fmt.Errorf("unknown field in @order: %s", name)
+			}
+			keys = append(keys, iterator.OrderKey{Desc: o.Desc})
+		}
+	}
+	return p.Order(keys...), nil
+}
+
 func (q *Query) Execute(ctx context.Context, qs graph.QuadStore) (map[string]interface{}, error) {
 	out := make(map[string]interface{})
 	for _, f := range q.fields {
@@ -378,11 +545,135 @@ func convField(fld *ast.Field) (out field, err error) {
 			}
 		case "opt", "optional":
 			out.Opt = true
+		case "shortestPath", "allPaths":
+			out.Paths, err = argsToPaths(d.Name.Value, d.Arguments)
+			if err != nil {
+				return
+			}
+		case "groupBy":
+			out.GroupBy, err = argsToGroupBy(d.Arguments)
+			if err != nil {
+				return
+			}
+		case "order":
+			var o *orderBy
+			o, err = argsToOrder(d.Arguments)
+			if err != nil {
+				return
+			}
+			out.Order = append(out.Order, *o)
+		default:
+			if op := iterator.AggregateOp(d.Name.Value); op.Valid() {
+				if !out.isSave() {
+					err = fmt.Errorf("@%s is only supported on scalar fields", op)
+					return
+				}
+				out.Aggregate = op
+			}
 		}
 	}
 	return
 }
 
+func argsToPaths(name string, args []*ast.Argument) (*paths, error) {
+	out := &paths{All: name == "allPaths"}
+	for _, arg := range args {
+		vals, err := convValue(arg.Value)
+		if err != nil {
+			return nil, err
+		}
+		switch arg.Name.Value {
+		case "to":
+			out.To = vals
+		case "via":
+			out.Via = vals
+		case "depth", "weight":
+			if len(vals) != 1 {
+				return nil, fmt.Errorf("unexpected arguments: %v (%d)", vals, len(vals))
+			}
+			if arg.Name.Value == "weight" {
+				out.Weight = vals[0]
+				continue
+			}
+			n, ok := vals[0].(quad.Int)
+			if !ok {
+				return nil, fmt.Errorf("unexpected value type: %T", vals[0])
+			}
+			out.Depth = int(n)
+		default:
+			return nil, fmt.Errorf("unknown argument of @%s: %s", name, arg.Name.Value)
+		}
+	}
+	if len(out.To) == 0 {
+		return nil, fmt.Errorf("@%s requires the 'to' argument", name)
+	}
+	return out, nil
+}
+
+func argsToGroupBy(args []*ast.Argument) (*groupBy, error) {
+	out := &groupBy{}
+	for _, arg := range args {
+		if arg.Name.Value != "by" {
+			return nil, fmt.Errorf("unknown argument of @groupBy: %s", arg.Name.Value)
+		}
+		by, err := argToFieldNames("groupBy", arg)
+		if err != nil {
+			return nil, err
+		}
+		out.By = append(out.By, by...)
+	}
+	return out, nil
+}
+
+func argsToOrder(args []*ast.Argument) (*orderBy, error) {
+	out := &orderBy{}
+	for _, arg := range args {
+		switch arg.Name.Value {
+		case "by":
+			by, err := argToFieldNames("order", arg)
+			if err != nil {
+				return nil, err
+			}
+			out.By = append(out.By, by...)
+		case "desc":
+			vals, err := convValue(arg.Value)
+			if err != nil {
+				return nil, err
+			} else if len(vals) != 1 {
+				return nil, fmt.Errorf("unexpected arguments: %v (%d)", vals, len(vals))
+			}
+			b, ok := vals[0].(quad.Bool)
+			if !ok {
+				return nil, fmt.Errorf("unexpected value type: %T", vals[0])
+			}
+			out.Desc = bool(b)
+		default:
+			return nil, fmt.Errorf("unknown argument of @order: %s", arg.Name.Value)
+		}
+	}
+	return out, nil
+}
+
+// argToFieldNames returns names of fields listed in the argument of a directive.
+func argToFieldNames(name string, arg *ast.Argument) ([]string, error) {
+	vals, err := convValue(arg.Value)
+	if err != nil {
+		return nil, err
+	}
+	var out []string
+	for _, v := range vals {
+		switch v := v.(type) {
+		case quad.IRI:
+			out = append(out, string(v))
+		case quad.String:
+			out = append(out, string(v))
+		default:
+			return nil, fmt.Errorf("unexpected field name in @%s: %v", name, v)
+		}
+	}
+	return out, nil
+}
+
 func convValue(v ast.Value) (out []quad.Value, _ error) {
 	switch v := v.(type) {
 	case *ast.EnumValue:
@@ -158,6 +158,77 @@ var casesExecute = []struct {
 			},
 		},
 	},
+	{
+		`{
+  route(` + ValueKey + `: <charlie>) @shortestPath(to: <greg>, via: <follows>) {
+    ` + ValueKey + `
+    ` + PathKey + `
+    hops: ` + WeightKey + `
+  }
+}`,
+		map[string]interface{}{
+			"route": map[string]interface{}{
+				ValueKey: quad.IRI("greg"),
+				PathKey: []quad.Value{
+					quad.IRI("charlie"), quad.IRI("follows"), quad.IRI("dani"), quad.IRI("follows"), quad.IRI("greg"),
+				},
+				"hops": quad.Int(2),
+			},
+		},
+	},
+	{
+		`{
+  people(status: "cool_person") @groupBy {
+    n: ` + ValueKey + ` @count
+  }
+}`,
+		map[string]interface{}{
+			"people": map[string]interface{}{
+				"n": quad.Int(3),
+			},
+		},
+	},
+	{
+		`{
+  people @groupBy(by: status) {
+    status
+    n: ` + ValueKey + ` @count
+  }
+}`,
+		map[string]interface{}{
+			"people": []map[string]interface{}{
+				{"status": quad.String("cool_person"), "n": quad.Int(3)},
+				{"status": quad.String("smart_person"), "n": quad.Int(2)},
+			},
+		},
+	},
+	{
+		`{
+  people(status: "cool_person", ` + SkipKey + `: 1) @order(desc: true) {
+    id: ` + ValueKey + `
+  }
+}`,
+		map[string]interface{}{
+			"people": []map[string]interface{}{
+				{"id": quad.IRI("dani")},
+				{"id": quad.IRI("bob")},
+			},
+		},
+	},
+	{
+		`{
+  people @groupBy(by: status) @order(by: n) {
+    status
+    n: ` + ValueKey + ` @count
+  }
+}`,
+		map[string]interface{}{
+			"people": []map[string]interface{}{
+				{"status": quad.String("smart_person"), "n": quad.Int(2)},
+				{"status": quad.String("cool_person"), "n": quad.Int(3)},
+			},
+		},
+	},
 }
 
 func toJson(o interface{}) string {
@@ -147,11 +147,33 @@ func quadValueToNative(v quad.Value) interface{} {
 	return out
 }
 
+// collectSteps replaces step tags of paths (see iterator.StepTag) with arrays of steps.
+func collectSteps(m map[string]interface{}) {
+	steps := make(map[string][]interface{})
+	for k, v := range m {
+		tag, i, ok := iterator.SplitStepTag(k)
+		if !ok {
+			continue
+		}
+		arr := steps[tag]
+		for len(arr) <= i {
+			arr = append(arr, nil)
+		}
+		arr[i] = v
+		steps[tag] = arr
+		delete(m, k)
+	}
+	for tag, arr := range steps {
+		m[tag] = arr
+	}
+}
+
 func (wk *worker) tagsToValueMap(m map[string]graph.Value) map[string]interface{} {
 	outputMap := make(map[string]interface{})
 	for k, v := range m {
 		outputMap[k] = quadValueToNative(wk.qs.NameOf(v))
 	}
+	collectSteps(outputMap)
 	return outputMap
 }
 
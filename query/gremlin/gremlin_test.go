@@ -230,6 +230,21 @@ var testQueries = []struct {
 		`,
 		expect: []string{"<alice>", "<charlie>", "<dani>"},
 	},
+	{
+		message: "show shortest path",
+		query: `
+			g.V("<charlie>").ShortestPath(g.V("<greg>"), "<follows>").All()
+		`,
+		tag:    "path[2]",
+		expect: []string{"<dani>"},
+	},
+	{
+		message: "show all paths",
+		query: `
+			g.V("<charlie>").AllPaths("<greg>", 3, "<follows>").All()
+		`,
+		expect: []string{"<greg>", "<greg>"},
+	},
 
 	// Intersection tests.
 	{
@@ -260,6 +260,7 @@ func (s *Session) Collate(result query.Result) {
 			delete(obj, k)
 		}
 	}
+	collectSteps(obj)
 	if len(obj) != 0 {
 		s.dataOutput = append(s.dataOutput, obj)
 	}
@@ -105,6 +105,69 @@ func (p *pathObject) Follow(call otto.FunctionCall) otto.Value {
 func (p *pathObject) FollowR(call otto.FunctionCall) otto.Value {
 	return p.follow(call, true)
 }
+func (p *pathObject) ShortestPath(call otto.FunctionCall) otto.Value {
+	return p.paths(call, false)
+}
+func (p *pathObject) AllPaths(call otto.FunctionCall) otto.Value {
+	return p.paths(call, true)
+}
+func (p *pathObject) paths(call otto.FunctionCall, all bool) otto.Value {
+	args := exportArgs(call.ArgumentList)
+	depth := 0
+	if all {
+		if len(args) < 2 {
+			return otto.NullValue()
+		}
+		depth = toInt(args[1])
+		args = append([]interface{}{args[0]}, args[2:]...)
+	}
+	if len(args) == 0 || len(args) > 3 {
+		return otto.NullValue()
+	}
+	var to *path.Path
+	switch v := args[0].(type) {
+	case nil:
+	case *path.Path:
+		to = v
+	default:
+		arr, ok := v.([]interface{})
+		if !ok {
+			arr = []interface{}{v}
+		}
+		qv, err := toQuadValues(arr)
+		if err != nil {
+			//TODO(dennwc): pass error to caller
+			return otto.NullValue()
+		}
+		to = path.StartMorphism(qv...)
+	}
+	var via []interface{}
+	if len(args) > 1 {
+		via = toVia([]interface{}{args[1]})
+	}
+	var opts path.PathOptions
+	if len(args) > 2 {
+		m, ok := args[2].(map[string]interface{})
+		if !ok {
+			return otto.NullValue()
+		}
+		opts.Tag, _ = toString(m["tag"])
+		opts.WeightTag, _ = toString(m["weightTag"])
+		opts.Reverse, _ = m["reverse"].(bool)
+		if w, ok := m["weight"]; ok {
+			if opts.Weight, ok = toQuadValue(w); !ok {
+				return otto.NullValue()
+			}
+		}
+	}
+	np := p.clonePath()
+	if all {
+		np = np.AllPathsWith(to, depth, opts, via...)
+	} else {
+		np = np.ShortestPathWith(to, opts, via...)
+	}
+	return outObj(call, p.new(np))
+}
 func (p *pathObject) And(call otto.FunctionCall) otto.Value {
 	ep, ok := exportAsPath(call.ArgumentList)
 	if !ok {
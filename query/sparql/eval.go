@@ -0,0 +1,729 @@
+package sparql
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/context"
+
+	"github.com/codelingo/cayley/graph"
+	"github.com/codelingo/cayley/graph/iterator"
+	"github.com/codelingo/cayley/quad"
+)
+
+// binding is a single solution of a graph pattern: a mapping from variable names to graph values.
+type binding map[string]graph.Value
+
+func (b binding) clone() binding {
+	b2 := make(binding, len(b)+1)
+	for k, v := range b {
+		b2[k] = v
+	}
+	return b2
+}
+
+// key returns a unique key for a solution. It is used to remove duplicate solutions.
+func (b binding) key() string {
+	names := make([]string, 0, len(b))
+	for k := range b {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	parts := make([]string, 0, len(names))
+	for _, k := range names {
+		parts = append(parts, fmt.Sprintf("%s=%v", k, graph.ToKey(b[k])))
+	}
+	return strings.Join(parts, "\x00")
+}
+
+func isHiddenVar(name string) bool {
+	return strings.HasPrefix(name, "_:")
+}
+
+var errStop = errors.New("sparql: stop iteration")
+
+// evaluator runs graph patterns against a QuadStore.
+//
+// Consecutive basic graph patterns and simple OPTIONAL groups are compiled to a single tree of
+// HasA, LinksTo, And and Optional iterators with a tag for each variable (see tree), which is
+// then optimized by the store. Unions, nested groups and other OPTIONAL groups are joined in Go,
+// by substituting already bound variables into the following patterns.
+//
+// Patterns are not compiled to graph/path: a path is a walk from a single set of nodes, while
+// a variable of a graph pattern may join any number of triples in any position, including
+// predicates and labels, and may close cycles.
+type evaluator struct {
+	ctx context.Context
+	qs  graph.QuadStore
+}
+
+// constraint restricts the values of a variable by wrapping an iterator of nodes.
+type constraint func(it graph.Iterator) graph.Iterator
+
+// constraints maps variable names to the list of constraints on their values.
+type constraints map[string][]constraint
+
+// flipOperator returns an operator for a comparison with swapped arguments.
+func flipOperator(op string) string {
+	switch op {
+	case "<":
+		return ">"
+	case "<=":
+		return ">="
+	case ">":
+		return "<"
+	case ">=":
+		return "<="
+	}
+	return op
+}
+
+// pushdown extracts filters that can be evaluated by Comparison iterators.
+//
+// Only simple comparisons of a variable with a string or IRI constant are extracted, since the
+// iterators compare values of other types differently from SPARQL. Filters are still applied
+// to the group solutions, so constraints only need to select a superset of the matching values.
+// REGEX is not extracted, since the Regex iterator does not match language-tagged strings.
+func (c constraints) pushdown(qs graph.QuadStore, filters []Expr) constraints {
+	out := make(constraints, len(c))
+	for k, v := range c {
+		out[k] = v
+	}
+	for _, f := range filters {
+		switch f := f.(type) {
+		case binaryExpr:
+			op := f.Op
+			switch op {
+			case "<", "<=", ">", ">=":
+			default:
+				continue
+			}
+			v, lok := f.Left.(varExpr)
+			val, rok := f.Right.(valueExpr)
+			if !lok || !rok {
+				if v, lok = f.Right.(varExpr); !lok {
+					continue
+				} else if val, rok = f.Left.(valueExpr); !rok {
+					continue
+				}
+				op = flipOperator(op)
+			}
+			switch val.Value.(type) {
+			case quad.String, quad.IRI:
+			default:
+				continue
+			}
+			cop := toOperator(op)
+			out[string(v)] = append(out[string(v)], func(it graph.Iterator) graph.Iterator {
+				return iterator.NewComparison(it, cop, val.Value, qs)
+			})
+		}
+	}
+	return out
+}
+
+// evalGroup calls fnc for each solution of the group that extends the given binding.
+func (e *evaluator) evalGroup(g *Group, b binding, cons constraints, fnc func(binding) error) error {
+	cons = cons.pushdown(e.qs, g.Filters)
+	if len(g.Filters) != 0 {
+		next := fnc
+		fnc = func(b binding) error {
+			for _, f := range g.Filters {
+				if !e.filter(f, b) {
+					return nil
+				}
+			}
+			return next(b)
+		}
+	}
+	return e.evalPatterns(g.Patterns, b, cons, fnc)
+}
+
+// filter checks if solution passes a filter expression. Evaluation errors are treated as false.
+func (e *evaluator) filter(f Expr, b binding) bool {
+	v, err := f.eval(e.qs, b)
+	if err != nil {
+		return false
+	}
+	ok, err := effectiveBool(v)
+	return err == nil && ok
+}
+
+func (e *evaluator) evalPatterns(pats []Pattern, b binding, cons constraints, fnc func(binding) error) error {
+	if len(pats) == 0 {
+		return fnc(b)
+	}
+	// compile the longest prefix of patterns that can be expressed as a single iterator tree
+	t := newTree(e.qs, b, cons)
+	n := 0
+loop:
+	for _, p := range pats {
+		switch p := p.(type) {
+		case BGP:
+			t.addTriples([]Triple(p))
+		case Optional:
+			if !t.addOptional(p.Group) {
+				break loop
+			}
+		default:
+			break loop
+		}
+		n++
+	}
+	if n != 0 {
+		return e.evalTree(t, func(b binding) error {
+			return e.evalPatterns(pats[n:], b, cons, fnc)
+		})
+	}
+	next := func(b binding) error {
+		return e.evalPatterns(pats[1:], b, cons, fnc)
+	}
+	switch p := pats[0].(type) {
+	case *Group:
+		return e.evalGroup(p, b, cons, next)
+	case Union:
+		for _, g := range p {
+			if err := e.evalGroup(g, b, cons, next); err != nil {
+				return err
+			}
+		}
+		return nil
+	case Optional:
+		// Constraints from outer filters are not pushed into optional patterns.
+		// Dropping optional values early may change which solutions are extended.
+		found := false
+		err := e.evalGroup(p.Group, b, nil, func(b2 binding) error {
+			found = true
+			return next(b2)
+		})
+		if err != nil || found {
+			return err
+		}
+		return next(b)
+	}
+	return fmt.Errorf("sparql: unsupported pattern: %T", pats[0])
+}
+
+// evalTree runs iterators of all connected components of the tree and calls fnc for each
+// combination of their solutions. Only the first component is streamed; solutions of the
+// other ones are loaded once and joined in Go, since they share no variables.
+func (e *evaluator) evalTree(t *tree, fnc func(binding) error) error {
+	if len(t.roots) == 0 {
+		return fnc(t.bound)
+	}
+	loaded := make([][]map[string]graph.Value, len(t.roots))
+	var join func(i int, b binding) error
+	join = func(i int, b binding) error {
+		if i == len(t.roots) {
+			return fnc(b)
+		}
+		each := func(tags map[string]graph.Value) error {
+			nb, ok := t.merge(b, tags)
+			if !ok {
+				return nil
+			}
+			return join(i+1, nb)
+		}
+		if i == 0 {
+			return e.iterate(t.iterator(t.roots[0]), each)
+		}
+		if loaded[i] == nil {
+			sols := []map[string]graph.Value{}
+			err := e.iterate(t.iterator(t.roots[i]), func(tags map[string]graph.Value) error {
+				sols = append(sols, tags)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			loaded[i] = sols
+		}
+		for _, tags := range loaded[i] {
+			if err := each(tags); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return join(0, t.bound)
+}
+
+// iterate optimizes the iterator and calls fnc with tags of each of its results and their paths.
+func (e *evaluator) iterate(it graph.Iterator, fnc func(map[string]graph.Value) error) error {
+	if nit, ok := it.Optimize(); ok {
+		it = nit
+	}
+	if nit, ok := e.qs.OptimizeIterator(it); ok {
+		it = nit
+	}
+	defer it.Close()
+	var ictx *graph.IterationContext
+	if ex := graph.ExplainerFromContext(e.ctx); ex != nil {
+		if !ex.Track(it) {
+			return nil
+		}
+		ictx = ex.IterationContext()
+	}
+	done := e.ctx.Done()
+	send := func() error {
+		select {
+		case <-done:
+			return e.ctx.Err()
+		default:
+		}
+		tags := make(map[string]graph.Value)
+		it.TagResults(tags)
+		return fnc(tags)
+	}
+	for it.Next(ictx) {
+		if err := send(); err != nil {
+			return err
+		}
+		for it.NextPath(ictx) {
+			if err := send(); err != nil {
+				return err
+			}
+		}
+	}
+	return it.Err()
+}
+
+var tripleDirs = []quad.Direction{quad.Subject, quad.Predicate, quad.Object}
+
+// tree is a plan of iterator trees for a sequence of graph patterns.
+//
+// Each variable is a node, and each triple pattern is a link from the node it was reached
+// from to the nodes of its other terms. A node is matched by an intersection of HasA
+// iterators of its links, and a link is matched by an intersection of LinksTo iterators
+// of its other nodes. Triple patterns that are not connected to each other form separate trees.
+//
+// Patterns that close a cycle, or refer to a variable bound only by an OPTIONAL group, cannot
+// reuse the node of the variable. Such terms get a new node with a separate tag, and values of
+// both tags are compared in Go (see merge).
+type tree struct {
+	qs    graph.QuadStore
+	bound binding // variables bound by previous patterns; they are matched as constants
+	cons  constraints
+
+	roots []*node
+	vars  map[string]*node // nodes of variables that are always bound
+	opt   map[string]bool  // variables bound only by OPTIONAL groups
+	dups  []dupVar         // additional nodes of variables
+}
+
+type node struct {
+	tag   string // variable name or a tag of its additional node; empty for constants
+	fixed bool   // node is matched by value
+	value graph.Value
+	cons  []constraint
+	links []*link
+	opts  [][]*link // links of OPTIONAL groups
+}
+
+// link is a triple pattern reached from a node in a given direction.
+type link struct {
+	dir   quad.Direction
+	terms []linkTerm
+}
+
+type linkTerm struct {
+	dir  quad.Direction
+	node *node
+}
+
+// dupVar is an additional node of a variable.
+type dupVar struct {
+	tag, name string
+}
+
+func newTree(qs graph.QuadStore, b binding, cons constraints) *tree {
+	return &tree{
+		qs: qs, bound: b, cons: cons,
+		vars: make(map[string]*node),
+		opt:  make(map[string]bool),
+	}
+}
+
+// termNode returns a node for a constant or a bound variable, or nil if the term is an unbound variable.
+func (t *tree) termNode(term Term) *node {
+	if !term.IsVar() {
+		// a value that is not in the store matches nothing
+		return &node{fixed: true, value: t.qs.ValueOf(term.Value)}
+	} else if v, ok := t.bound[term.Var]; ok {
+		return &node{fixed: true, value: v}
+	}
+	return nil
+}
+
+// addTriples adds triple patterns to the tree. Patterns that share a variable with the tree
+// are attached to its node; others start a new tree.
+func (t *tree) addTriples(ts []Triple) {
+	pending := append([]Triple{}, ts...)
+	for len(pending) != 0 {
+		attached := false
+		for i := 0; i < len(pending); {
+			if n, d, ok := t.attachPoint(pending[i]); ok {
+				n.links = append(n.links, t.link(pending[i], d))
+				pending = append(pending[:i], pending[i+1:]...)
+				attached = true
+				continue
+			}
+			i++
+		}
+		if attached {
+			continue
+		}
+		tr := pending[0]
+		pending = pending[1:]
+		var root *node
+		d := quad.Subject
+		for _, d2 := range tripleDirs {
+			if term := tr.get(d2); t.termNode(term) == nil && !t.opt[term.Var] {
+				d = d2
+				root = &node{tag: term.Var, cons: t.cons[term.Var]}
+				t.vars[term.Var] = root
+				break
+			}
+		}
+		if root == nil {
+			root = t.termNode(tr.get(d))
+			if root == nil {
+				// subject is bound only by an OPTIONAL group
+				root = t.dupNode(tr.get(d).Var)
+			}
+		}
+		root.links = append(root.links, t.link(tr, d))
+		t.roots = append(t.roots, root)
+	}
+}
+
+// attachPoint finds a node of the tree for a variable of the triple pattern.
+func (t *tree) attachPoint(tr Triple) (*node, quad.Direction, bool) {
+	for _, d := range tripleDirs {
+		if term := tr.get(d); term.IsVar() {
+			if n := t.vars[term.Var]; n != nil {
+				return n, d, true
+			}
+		}
+	}
+	return nil, quad.Any, false
+}
+
+// dupNode creates an additional node for a variable that already has one.
+func (t *tree) dupNode(name string) *node {
+	tag := fmt.Sprintf("%s\x00%d", name, len(t.dups))
+	t.dups = append(t.dups, dupVar{tag: tag, name: name})
+	return &node{tag: tag, cons: t.cons[name]}
+}
+
+// link creates a link for a triple pattern reached in a given direction, and nodes for its other terms.
+func (t *tree) link(tr Triple, from quad.Direction) *link {
+	l := &link{dir: from}
+	for _, d := range tripleDirs {
+		if d == from {
+			continue
+		}
+		term := tr.get(d)
+		n := t.termNode(term)
+		if n == nil {
+			if t.vars[term.Var] != nil || t.opt[term.Var] {
+				n = t.dupNode(term.Var)
+			} else {
+				n = &node{tag: term.Var, cons: t.cons[term.Var]}
+				t.vars[term.Var] = n
+			}
+		}
+		l.terms = append(l.terms, linkTerm{dir: d, node: n})
+	}
+	return l
+}
+
+// addOptional adds an OPTIONAL group to the tree. Only groups of triple patterns without filters
+// are supported, which form a tree attached to exactly one node of the tree. It returns false
+// and keeps the tree unchanged if the group cannot be added.
+func (t *tree) addOptional(g *Group) bool {
+	if len(g.Filters) != 0 {
+		return false
+	}
+	var ts []Triple
+	for _, p := range g.Patterns {
+		bgp, ok := p.(BGP)
+		if !ok {
+			return false
+		}
+		ts = append(ts, bgp...)
+	}
+	var at string
+	for _, tr := range ts {
+		for _, d := range tripleDirs {
+			term := tr.get(d)
+			if t.termNode(term) != nil {
+				continue
+			} else if t.opt[term.Var] {
+				return false
+			} else if t.vars[term.Var] != nil {
+				if at != "" && at != term.Var {
+					return false
+				}
+				at = term.Var
+			}
+		}
+	}
+	if at == "" {
+		return false
+	}
+	// optional nodes are not shared with the rest of the tree, and constraints are not pushed into them
+	local := map[string]*node{at: {}}
+	var links []*link
+	for len(ts) != 0 {
+		attached := false
+		for i := 0; i < len(ts); {
+			tr := ts[i]
+			from := quad.Any
+			for _, d := range tripleDirs {
+				if term := tr.get(d); term.IsVar() && local[term.Var] != nil {
+					from = d
+					break
+				}
+			}
+			if from == quad.Any {
+				i++
+				continue
+			}
+			l := &link{dir: from}
+			for _, d := range tripleDirs {
+				if d == from {
+					continue
+				}
+				term := tr.get(d)
+				n := t.termNode(term)
+				if n == nil {
+					if local[term.Var] != nil {
+						// a cycle in the group
+						return false
+					}
+					n = &node{tag: term.Var}
+					local[term.Var] = n
+				}
+				l.terms = append(l.terms, linkTerm{dir: d, node: n})
+			}
+			if from := tr.get(from).Var; from == at {
+				links = append(links, l)
+			} else {
+				local[from].links = append(local[from].links, l)
+			}
+			ts = append(ts[:i], ts[i+1:]...)
+			attached = true
+		}
+		if !attached {
+			// patterns that are not connected to the tree
+			return false
+		}
+	}
+	n := t.vars[at]
+	n.opts = append(n.opts, links)
+	for name := range local {
+		if name != at {
+			t.opt[name] = true
+		}
+	}
+	return true
+}
+
+// iterator builds an iterator for a node and all its links.
+func (t *tree) iterator(n *node) graph.Iterator {
+	var its []graph.Iterator
+	if n.fixed {
+		fixed := t.qs.FixedIterator()
+		if n.value != nil {
+			fixed.Add(n.value)
+		}
+		its = append(its, fixed)
+	} else if len(n.cons) != 0 {
+		it := t.qs.NodesAllIterator()
+		for _, f := range n.cons {
+			it = f(it)
+		}
+		its = append(its, it)
+	}
+	for _, l := range n.links {
+		its = append(its, t.linkIterator(l))
+	}
+	if len(n.opts) != 0 && len(its) == 0 {
+		// optional iterators cannot be the only ones in the intersection
+		its = append(its, t.qs.NodesAllIterator())
+	}
+	for _, links := range n.opts {
+		subs := make([]graph.Iterator, 0, len(links))
+		for _, l := range links {
+			subs = append(subs, t.linkIterator(l))
+		}
+		its = append(its, iterator.NewOptional(and(t.qs, subs)))
+	}
+	var it graph.Iterator
+	if len(its) == 0 {
+		it = t.qs.NodesAllIterator()
+	} else {
+		it = and(t.qs, its)
+	}
+	if n.tag != "" {
+		it.Tagger().Add(n.tag)
+	}
+	return it
+}
+
+// linkIterator builds an iterator of nodes that have quads matching the link.
+func (t *tree) linkIterator(l *link) graph.Iterator {
+	its := make([]graph.Iterator, 0, len(l.terms))
+	for _, lt := range l.terms {
+		its = append(its, iterator.NewLinksTo(t.qs, t.iterator(lt.node), lt.dir))
+	}
+	return iterator.NewHasA(t.qs, and(t.qs, its), l.dir)
+}
+
+func and(qs graph.QuadStore, its []graph.Iterator) graph.Iterator {
+	if len(its) == 1 {
+		return its[0]
+	}
+	return iterator.NewAnd(qs, its...)
+}
+
+// merge adds tagged values of a solution of the tree to the binding. Values of additional nodes
+// must be equal to the values of the variable, unless it is not bound by an OPTIONAL group.
+func (t *tree) merge(b binding, tags map[string]graph.Value) (binding, bool) {
+	nb := b.clone()
+	for k, v := range tags {
+		if strings.IndexByte(k, 0) < 0 {
+			nb[k] = v
+		}
+	}
+	for _, d := range t.dups {
+		v, ok := tags[d.tag]
+		if !ok {
+			continue
+		}
+		if cur, ok := nb[d.name]; !ok {
+			nb[d.name] = v
+		} else if graph.ToKey(cur) != graph.ToKey(v) {
+			return nil, false
+		}
+	}
+	return nb, true
+}
+
+// project removes all variables that are not selected by the query.
+func (q *Query) project(b binding) binding {
+	out := make(binding, len(b))
+	if len(q.Vars) == 0 {
+		for k, v := range b {
+			if !isHiddenVar(k) {
+				out[k] = v
+			}
+		}
+		return out
+	}
+	for _, k := range q.Vars {
+		if v, ok := b[k]; ok {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+// iterate evaluates the query and calls fnc for each projected solution.
+// Solution modifiers (DISTINCT, OFFSET, LIMIT) are applied to the results.
+//
+// If limit is positive, at most limit solutions will be returned, regardless of the LIMIT in the query.
+func (q *Query) iterate(ctx context.Context, qs graph.QuadStore, limit int, fnc func(binding) error) error {
+	lim := q.Limit
+	if q.Type == Ask {
+		lim = 1
+	}
+	if limit > 0 && (lim < 0 || limit < lim) {
+		lim = limit
+	}
+	if lim == 0 {
+		return nil
+	}
+	var seen map[string]struct{}
+	if q.Distinct {
+		seen = make(map[string]struct{})
+	}
+	skip, n := q.Offset, 0
+	e := &evaluator{ctx: ctx, qs: qs}
+	err := e.evalGroup(q.Where, binding{}, nil, func(b binding) error {
+		if q.Type == Select {
+			b = q.project(b)
+		}
+		if seen != nil {
+			k := b.key()
+			if _, ok := seen[k]; ok {
+				return nil
+			}
+			seen[k] = struct{}{}
+		}
+		if skip > 0 {
+			skip--
+			return nil
+		}
+		if err := fnc(b); err != nil {
+			return err
+		}
+		n++
+		if lim > 0 && n >= lim {
+			return errStop
+		}
+		return nil
+	})
+	if err == errStop {
+		err = nil
+	}
+	return err
+}
+
+// construct instantiates the query template with a given solution.
+// Blank nodes in the template are replaced with new blank nodes for each solution.
+func (q *Query) construct(qs graph.QuadStore, b binding, seq *quad.Sequence) []quad.Quad {
+	bnodes := make(map[string]quad.BNode)
+	term := func(t Term) quad.Value {
+		if !t.IsVar() {
+			return t.Value
+		}
+		if isHiddenVar(t.Var) {
+			bn, ok := bnodes[t.Var]
+			if !ok {
+				bn = seq.Next()
+				bnodes[t.Var] = bn
+			}
+			return bn
+		}
+		v, ok := b[t.Var]
+		if !ok {
+			return nil
+		}
+		return qs.NameOf(v)
+	}
+	var out []quad.Quad
+	for _, t := range q.Template {
+		qd := quad.Quad{
+			Subject:   term(t.Subject),
+			Predicate: term(t.Predicate),
+			Object:    term(t.Object),
+		}
+		if !qd.IsValid() {
+			continue
+		}
+		switch qd.Subject.(type) {
+		case quad.IRI, quad.BNode:
+		default:
+			continue
+		}
+		if _, ok := qd.Predicate.(quad.IRI); !ok {
+			continue
+		}
+		out = append(out, qd)
+	}
+	return out
+}
@@ -0,0 +1,591 @@
+package sparql
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/codelingo/cayley/graph"
+	"github.com/codelingo/cayley/graph/iterator"
+	"github.com/codelingo/cayley/quad"
+)
+
+// Expr is a FILTER expression.
+type Expr interface {
+	eval(qs graph.QuadStore, b binding) (quad.Value, error)
+}
+
+var (
+	errUnbound  = errors.New("sparql: unbound variable")
+	errTypeCast = errors.New("sparql: type error")
+)
+
+// normalize converts typed strings to native values, if possible.
+func normalize(v quad.Value) quad.Value {
+	if ts, ok := v.(quad.TypedString); ok {
+		if nv, err := ts.ParseValue(); err == nil {
+			return nv
+		}
+	}
+	return v
+}
+
+type varExpr string
+
+func (e varExpr) eval(qs graph.QuadStore, b binding) (quad.Value, error) {
+	v, ok := b[string(e)]
+	if !ok {
+		return nil, errUnbound
+	}
+	return normalize(qs.NameOf(v)), nil
+}
+
+type valueExpr struct {
+	Value quad.Value
+}
+
+func (e valueExpr) eval(graph.QuadStore, binding) (quad.Value, error) {
+	return e.Value, nil
+}
+
+type unaryExpr struct {
+	Op   string
+	Expr Expr
+}
+
+func (e unaryExpr) eval(qs graph.QuadStore, b binding) (quad.Value, error) {
+	v, err := e.Expr.eval(qs, b)
+	if err != nil {
+		return nil, err
+	}
+	switch e.Op {
+	case "!":
+		ok, err := effectiveBool(v)
+		if err != nil {
+			return nil, err
+		}
+		return quad.Bool(!ok), nil
+	case "-":
+		switch v := v.(type) {
+		case quad.Int:
+			return -v, nil
+		case quad.Float:
+			return -v, nil
+		}
+		return nil, errTypeCast
+	case "+":
+		switch v.(type) {
+		case quad.Int, quad.Float:
+			return v, nil
+		}
+		return nil, errTypeCast
+	}
+	return nil, fmt.Errorf("sparql: unknown operator: %q", e.Op)
+}
+
+type binaryExpr struct {
+	Op          string
+	Left, Right Expr
+}
+
+func (e binaryExpr) eval(qs graph.QuadStore, b binding) (quad.Value, error) {
+	switch e.Op {
+	case "||", "&&":
+		return e.evalLogical(qs, b)
+	}
+	l, err := e.Left.eval(qs, b)
+	if err != nil {
+		return nil, err
+	}
+	r, err := e.Right.eval(qs, b)
+	if err != nil {
+		return nil, err
+	}
+	switch e.Op {
+	case "=", "!=":
+		eq, err := equal(l, r)
+		if err != nil {
+			return nil, err
+		}
+		return quad.Bool(eq == (e.Op == "=")), nil
+	case "<", "<=", ">", ">=":
+		ok, err := compare(l, toOperator(e.Op), r)
+		if err != nil {
+			return nil, err
+		}
+		return quad.Bool(ok), nil
+	case "+", "-", "*", "/":
+		return arithmetic(l, e.Op, r)
+	}
+	return nil, fmt.Errorf("sparql: unknown operator: %q", e.Op)
+}
+
+// evalLogical implements a three-valued logic for && and || operators, as defined by SPARQL spec.
+func (e binaryExpr) evalLogical(qs graph.QuadStore, b binding) (quad.Value, error) {
+	lv, lerr := e.Left.eval(qs, b)
+	var l bool
+	if lerr == nil {
+		l, lerr = effectiveBool(lv)
+	}
+	if lerr == nil && l == (e.Op == "||") {
+		return quad.Bool(l), nil
+	}
+	rv, rerr := e.Right.eval(qs, b)
+	var r bool
+	if rerr == nil {
+		r, rerr = effectiveBool(rv)
+	}
+	if rerr == nil && r == (e.Op == "||") {
+		return quad.Bool(r), nil
+	}
+	if lerr != nil {
+		return nil, lerr
+	} else if rerr != nil {
+		return nil, rerr
+	}
+	return quad.Bool(r), nil
+}
+
+func toOperator(op string) iterator.Operator {
+	switch op {
+	case "<":
+		return iterator.CompareLT
+	case "<=":
+		return iterator.CompareLTE
+	case ">":
+		return iterator.CompareGT
+	default:
+		return iterator.CompareGTE
+	}
+}
+
+// effectiveBool calculates an effective boolean value of the expression result.
+func effectiveBool(v quad.Value) (bool, error) {
+	switch v := v.(type) {
+	case quad.Bool:
+		return bool(v), nil
+	case quad.String:
+		return v != "", nil
+	case quad.LangString:
+		return v.Value != "", nil
+	case quad.Int:
+		return v != 0, nil
+	case quad.Float:
+		return v != 0 && v == v, nil
+	}
+	return false, errTypeCast
+}
+
+func isNumeric(v quad.Value) bool {
+	switch v.(type) {
+	case quad.Int, quad.Float:
+		return true
+	}
+	return false
+}
+
+func toFloat(v quad.Value) quad.Float {
+	switch v := v.(type) {
+	case quad.Int:
+		return quad.Float(v)
+	case quad.Float:
+		return v
+	}
+	return 0
+}
+
+func equal(l, r quad.Value) (bool, error) {
+	if isNumeric(l) && isNumeric(r) {
+		return toFloat(l) == toFloat(r), nil
+	}
+	if eq, ok := l.(quad.Equaler); ok {
+		return eq.Equal(r), nil
+	}
+	return l == r, nil
+}
+
+// compare runs comparison of two values with the same functions as Comparison iterator does.
+func compare(l quad.Value, op iterator.Operator, r quad.Value) (bool, error) {
+	if isNumeric(l) && isNumeric(r) {
+		if li, ok := l.(quad.Int); ok {
+			if ri, ok := r.(quad.Int); ok {
+				return iterator.RunIntOp(li, op, ri), nil
+			}
+		}
+		return iterator.RunFloatOp(toFloat(l), op, toFloat(r)), nil
+	}
+	switch l := l.(type) {
+	case quad.String:
+		if r, ok := r.(quad.String); ok {
+			return iterator.RunStrOp(string(l), op, string(r)), nil
+		}
+	case quad.LangString:
+		if r, ok := r.(quad.LangString); ok && l.Lang == r.Lang {
+			return iterator.RunStrOp(string(l.Value), op, string(r.Value)), nil
+		}
+	case quad.IRI:
+		if r, ok := r.(quad.IRI); ok {
+			return iterator.RunStrOp(string(l), op, string(r)), nil
+		}
+	case quad.Time:
+		if r, ok := r.(quad.Time); ok {
+			return iterator.RunTimeOp(time.Time(l), op, time.Time(r)), nil
+		}
+	case quad.Bool:
+		if r, ok := r.(quad.Bool); ok {
+			li, ri := 0, 0
+			if l {
+				li = 1
+			}
+			if r {
+				ri = 1
+			}
+			return iterator.RunIntOp(quad.Int(li), op, quad.Int(ri)), nil
+		}
+	}
+	return false, errTypeCast
+}
+
+func arithmetic(l quad.Value, op string, r quad.Value) (quad.Value, error) {
+	if !isNumeric(l) || !isNumeric(r) {
+		return nil, errTypeCast
+	}
+	li, lok := l.(quad.Int)
+	ri, rok := r.(quad.Int)
+	if lok && rok && op != "/" {
+		switch op {
+		case "+":
+			return li + ri, nil
+		case "-":
+			return li - ri, nil
+		case "*":
+			return li * ri, nil
+		}
+	}
+	lf, rf := toFloat(l), toFloat(r)
+	switch op {
+	case "+":
+		return lf + rf, nil
+	case "-":
+		return lf - rf, nil
+	case "*":
+		return lf * rf, nil
+	case "/":
+		if rf == 0 {
+			return nil, errTypeCast
+		}
+		return lf / rf, nil
+	}
+	return nil, fmt.Errorf("sparql: unknown operator: %q", op)
+}
+
+// stringValue returns a string value of a literal, or an error if value is not a literal.
+func stringValue(v quad.Value) (string, error) {
+	switch v := v.(type) {
+	case quad.String:
+		return string(v), nil
+	case quad.LangString:
+		return string(v.Value), nil
+	case quad.TypedString:
+		return string(v.Value), nil
+	case quad.TypedStringer:
+		return string(v.TypedString().Value), nil
+	}
+	return "", errTypeCast
+}
+
+type builtin struct {
+	args int // -1 for variable number of arguments
+	fnc  func(args []quad.Value) (quad.Value, error)
+}
+
+// builtins is a set of supported SPARQL functions that operate on values.
+// Functions that require an access to unevaluated arguments (BOUND, REGEX) are handled separately.
+var builtins = map[string]builtin{
+	"BOUND": {args: 1},
+	"REGEX": {args: -1},
+	"STR": {args: 1, fnc: func(a []quad.Value) (quad.Value, error) {
+		switch v := a[0].(type) {
+		case quad.IRI:
+			return quad.String(v), nil
+		case quad.BNode:
+			return nil, errTypeCast
+		}
+		s, err := stringValue(a[0])
+		return quad.String(s), err
+	}},
+	"LANG": {args: 1, fnc: func(a []quad.Value) (quad.Value, error) {
+		switch v := a[0].(type) {
+		case quad.LangString:
+			return quad.String(v.Lang), nil
+		case quad.IRI, quad.BNode:
+			return nil, errTypeCast
+		}
+		return quad.String(""), nil
+	}},
+	"DATATYPE": {args: 1, fnc: func(a []quad.Value) (quad.Value, error) {
+		switch v := a[0].(type) {
+		case quad.String:
+			return quad.IRI(xsdPrefix + "string"), nil
+		case quad.LangString:
+			return quad.IRI(rdfLangString), nil
+		case quad.TypedString:
+			return v.Type.Full(), nil
+		case quad.TypedStringer:
+			return v.TypedString().Type.Full(), nil
+		}
+		return nil, errTypeCast
+	}},
+	"ISIRI": {args: 1, fnc: func(a []quad.Value) (quad.Value, error) {
+		_, ok := a[0].(quad.IRI)
+		return quad.Bool(ok), nil
+	}},
+	"ISBLANK": {args: 1, fnc: func(a []quad.Value) (quad.Value, error) {
+		_, ok := a[0].(quad.BNode)
+		return quad.Bool(ok), nil
+	}},
+	"ISLITERAL": {args: 1, fnc: func(a []quad.Value) (quad.Value, error) {
+		switch a[0].(type) {
+		case quad.IRI, quad.BNode:
+			return quad.Bool(false), nil
+		}
+		return quad.Bool(true), nil
+	}},
+	"ISNUMERIC": {args: 1, fnc: func(a []quad.Value) (quad.Value, error) {
+		return quad.Bool(isNumeric(a[0])), nil
+	}},
+	"SAMETERM": {args: 2, fnc: func(a []quad.Value) (quad.Value, error) {
+		return quad.Bool(a[0] == a[1]), nil
+	}},
+	"STRLEN": {args: 1, fnc: func(a []quad.Value) (quad.Value, error) {
+		s, err := stringValue(a[0])
+		return quad.Int(len([]rune(s))), err
+	}},
+	"UCASE":     {args: 1, fnc: stringFunc(strings.ToUpper)},
+	"LCASE":     {args: 1, fnc: stringFunc(strings.ToLower)},
+	"CONTAINS":  {args: 2, fnc: stringPredicate(strings.Contains)},
+	"STRSTARTS": {args: 2, fnc: stringPredicate(strings.HasPrefix)},
+	"STRENDS":   {args: 2, fnc: stringPredicate(strings.HasSuffix)},
+	"LANGMATCHES": {args: 2, fnc: func(a []quad.Value) (quad.Value, error) {
+		tag, err := stringValue(a[0])
+		if err != nil {
+			return nil, err
+		}
+		rng, err := stringValue(a[1])
+		if err != nil {
+			return nil, err
+		}
+		if rng == "*" {
+			return quad.Bool(tag != ""), nil
+		}
+		tag, rng = strings.ToLower(tag), strings.ToLower(rng)
+		return quad.Bool(tag == rng || strings.HasPrefix(tag, rng+"-")), nil
+	}},
+}
+
+func init() {
+	builtins["ISURI"] = builtins["ISIRI"]
+}
+
+const rdfLangString = "http://www.w3.org/1999/02/22-rdf-syntax-ns#langString"
+
+func stringFunc(fnc func(string) string) func([]quad.Value) (quad.Value, error) {
+	return func(a []quad.Value) (quad.Value, error) {
+		switch v := a[0].(type) {
+		case quad.String:
+			return quad.String(fnc(string(v))), nil
+		case quad.LangString:
+			v.Value = quad.String(fnc(string(v.Value)))
+			return v, nil
+		}
+		return nil, errTypeCast
+	}
+}
+
+func stringPredicate(fnc func(string, string) bool) func([]quad.Value) (quad.Value, error) {
+	return func(a []quad.Value) (quad.Value, error) {
+		s, err := stringValue(a[0])
+		if err != nil {
+			return nil, err
+		}
+		sub, err := stringValue(a[1])
+		if err != nil {
+			return nil, err
+		}
+		return quad.Bool(fnc(s, sub)), nil
+	}
+}
+
+func newCallExpr(name string, args []Expr) (Expr, error) {
+	fnc := builtins[name]
+	if fnc.args >= 0 && len(args) != fnc.args {
+		return nil, fmt.Errorf("sparql: %s expects %d arguments, got %d", name, fnc.args, len(args))
+	}
+	switch name {
+	case "BOUND":
+		v, ok := args[0].(varExpr)
+		if !ok {
+			return nil, errors.New("sparql: BOUND expects a variable")
+		}
+		return boundExpr(v), nil
+	case "REGEX":
+		return newRegexExpr(args)
+	}
+	return callExpr{Name: name, Args: args, fnc: fnc.fnc}, nil
+}
+
+type callExpr struct {
+	Name string
+	Args []Expr
+	fnc  func([]quad.Value) (quad.Value, error)
+}
+
+func (e callExpr) eval(qs graph.QuadStore, b binding) (quad.Value, error) {
+	args := make([]quad.Value, 0, len(e.Args))
+	for _, a := range e.Args {
+		v, err := a.eval(qs, b)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, v)
+	}
+	return e.fnc(args)
+}
+
+type boundExpr varExpr
+
+func (e boundExpr) eval(qs graph.QuadStore, b binding) (quad.Value, error) {
+	_, ok := b[string(e)]
+	return quad.Bool(ok), nil
+}
+
+// regexExpr is a REGEX function call. Pattern is compiled only once if it's a constant.
+type regexExpr struct {
+	Text, Pattern, Flags Expr
+	re                   *regexp.Regexp
+}
+
+func newRegexExpr(args []Expr) (Expr, error) {
+	if len(args) != 2 && len(args) != 3 {
+		return nil, fmt.Errorf("sparql: REGEX expects 2 or 3 arguments, got %d", len(args))
+	}
+	e := regexExpr{Text: args[0], Pattern: args[1]}
+	if len(args) == 3 {
+		e.Flags = args[2]
+	}
+	pv, ok := e.Pattern.(valueExpr)
+	if !ok {
+		return e, nil
+	}
+	var fv valueExpr
+	if e.Flags != nil {
+		if fv, ok = e.Flags.(valueExpr); !ok {
+			return e, nil
+		}
+	}
+	re, err := compileRegex(pv.Value, fv.Value)
+	if err != nil {
+		return nil, err
+	}
+	e.re = re
+	return e, nil
+}
+
+func compileRegex(pattern, flags quad.Value) (*regexp.Regexp, error) {
+	pat, err := stringValue(pattern)
+	if err != nil {
+		return nil, err
+	}
+	if flags != nil {
+		fl, err := stringValue(flags)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range fl {
+			if !strings.ContainsRune("imsU", f) {
+				return nil, fmt.Errorf("sparql: unsupported regex flag: %q", f)
+			}
+		}
+		if fl != "" {
+			pat = "(?" + fl + ")" + pat
+		}
+	}
+	return regexp.Compile(pat)
+}
+
+func (e regexExpr) eval(qs graph.QuadStore, b binding) (quad.Value, error) {
+	tv, err := e.Text.eval(qs, b)
+	if err != nil {
+		return nil, err
+	}
+	text, err := stringValue(tv)
+	if err != nil {
+		return nil, err
+	}
+	re := e.re
+	if re == nil {
+		pv, err := e.Pattern.eval(qs, b)
+		if err != nil {
+			return nil, err
+		}
+		var fv quad.Value
+		if e.Flags != nil {
+			if fv, err = e.Flags.eval(qs, b); err != nil {
+				return nil, err
+			}
+		}
+		if re, err = compileRegex(pv, fv); err != nil {
+			return nil, err
+		}
+	}
+	return quad.Bool(re.MatchString(text)), nil
+}
+
+// castExpr is a XSD constructor function call, like xsd:integer(?x).
+type castExpr struct {
+	Type quad.IRI
+	Arg  Expr
+}
+
+func newCastExpr(typ quad.IRI, args []Expr) (Expr, error) {
+	if !strings.HasPrefix(string(typ), xsdPrefix) {
+		return nil, fmt.Errorf("sparql: unknown function: %v", typ)
+	}
+	if len(args) != 1 {
+		return nil, fmt.Errorf("sparql: %v expects 1 argument, got %d", typ, len(args))
+	}
+	return castExpr{Type: typ, Arg: args[0]}, nil
+}
+
+func (e castExpr) eval(qs graph.QuadStore, b binding) (quad.Value, error) {
+	v, err := e.Arg.eval(qs, b)
+	if err != nil {
+		return nil, err
+	}
+	var s string
+	switch v := v.(type) {
+	case quad.Int:
+		s = strconv.FormatInt(int64(v), 10)
+	case quad.Float:
+		s = strconv.FormatFloat(float64(v), 'g', -1, 64)
+	case quad.Bool:
+		s = strconv.FormatBool(bool(v))
+	case quad.IRI:
+		s = string(v)
+	default:
+		if s, err = stringValue(v); err != nil {
+			return nil, err
+		}
+	}
+	if e.Type == xsdPrefix+"string" {
+		return quad.String(s), nil
+	}
+	nv, err := quad.TypedString{Value: quad.String(s), Type: e.Type}.ParseValue()
+	if err != nil {
+		return nil, errTypeCast
+	}
+	return nv, nil
+}
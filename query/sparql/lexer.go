@@ -0,0 +1,393 @@
+package sparql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type tokenType int
+
+const (
+	tokEOF tokenType = iota
+	tokIRI
+	tokPName
+	tokBNode
+	tokVar
+	tokString
+	tokLang
+	tokDataType // ^^
+	tokInteger
+	tokDecimal
+	tokDouble
+	tokIdent
+	tokPunct
+	tokAnon // []
+)
+
+type token struct {
+	typ tokenType
+	val string
+	pos int
+}
+
+func (t token) String() string {
+	switch t.typ {
+	case tokEOF:
+		return "EOF"
+	case tokIRI:
+		return "<" + t.val + ">"
+	case tokVar:
+		return "?" + t.val
+	case tokString:
+		return strconv.Quote(t.val)
+	case tokBNode:
+		return "_:" + t.val
+	case tokLang:
+		return "@" + t.val
+	}
+	return t.val
+}
+
+// is checks if token is a punctuation or a keyword with a given value.
+// Keywords are compared case-insensitively.
+func (t token) is(v string) bool {
+	switch t.typ {
+	case tokPunct, tokDataType, tokAnon:
+		return t.val == v
+	case tokIdent:
+		return strings.EqualFold(t.val, v)
+	}
+	return false
+}
+
+// errIncomplete is returned by the lexer when the input ends in the middle of a token.
+type errIncomplete struct {
+	what string
+}
+
+func (e errIncomplete) Error() string { return "sparql: unterminated " + e.what }
+
+// lex splits SPARQL query into a list of tokens.
+func lex(s string) ([]token, error) {
+	l := &lexer{s: s}
+	var out []token
+	for {
+		t, err := l.next()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, t)
+		if t.typ == tokEOF {
+			return out, nil
+		}
+	}
+}
+
+type lexer struct {
+	s   string
+	pos int
+}
+
+func (l *lexer) peek(i int) byte {
+	if l.pos+i >= len(l.s) {
+		return 0
+	}
+	return l.s[l.pos+i]
+}
+
+func (l *lexer) skipSpace() {
+	for l.pos < len(l.s) {
+		c := l.s[l.pos]
+		if c == '#' {
+			for l.pos < len(l.s) && l.s[l.pos] != '\n' {
+				l.pos++
+			}
+			continue
+		}
+		if c != ' ' && c != '\t' && c != '\n' && c != '\r' {
+			return
+		}
+		l.pos++
+	}
+}
+
+func (l *lexer) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("sparql: at %d: %s", l.pos, fmt.Sprintf(format, args...))
+}
+
+func isNameStart(r rune) bool {
+	return r == '_' || unicode.IsLetter(r)
+}
+
+func isNameChar(r rune) bool {
+	return r == '_' || r == '-' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func (l *lexer) name(local bool) string {
+	start := l.pos
+	for l.pos < len(l.s) {
+		r, n := utf8.DecodeRuneInString(l.s[l.pos:])
+		if isNameChar(r) || (local && (r == ':' || r == '%')) {
+			l.pos += n
+			continue
+		}
+		if r == '.' {
+			// dot is allowed only in the middle of the name
+			r2, _ := utf8.DecodeRuneInString(l.s[l.pos+1:])
+			if l.pos+1 < len(l.s) && (isNameChar(r2) || (local && r2 == ':')) {
+				l.pos += n
+				continue
+			}
+		}
+		break
+	}
+	return l.s[start:l.pos]
+}
+
+func (l *lexer) next() (token, error) {
+	l.skipSpace()
+	pos := l.pos
+	tok := func(typ tokenType, v string) (token, error) {
+		return token{typ: typ, val: v, pos: pos}, nil
+	}
+	if l.pos >= len(l.s) {
+		return tok(tokEOF, "")
+	}
+	c := l.s[l.pos]
+	switch c {
+	case '<':
+		if iri, ok := l.iriRef(); ok {
+			return tok(tokIRI, iri)
+		}
+		l.pos++
+		if l.peek(0) == '=' {
+			l.pos++
+			return tok(tokPunct, "<=")
+		}
+		return tok(tokPunct, "<")
+	case '>':
+		l.pos++
+		if l.peek(0) == '=' {
+			l.pos++
+			return tok(tokPunct, ">=")
+		}
+		return tok(tokPunct, ">")
+	case '!':
+		l.pos++
+		if l.peek(0) == '=' {
+			l.pos++
+			return tok(tokPunct, "!=")
+		}
+		return tok(tokPunct, "!")
+	case '&', '|':
+		if l.peek(1) != c {
+			return token{}, l.errorf("unexpected character %q", c)
+		}
+		l.pos += 2
+		return tok(tokPunct, string([]byte{c, c}))
+	case '^':
+		if l.peek(1) != '^' {
+			return token{}, l.errorf("unexpected character %q", c)
+		}
+		l.pos += 2
+		return tok(tokDataType, "^^")
+	case '[':
+		l.pos++
+		save := l.pos
+		l.skipSpace()
+		if l.peek(0) == ']' {
+			l.pos++
+			return tok(tokAnon, "[]")
+		}
+		l.pos = save
+		return tok(tokPunct, "[")
+	case '{', '}', '(', ')', ']', '.', ';', ',', '*', '=', '+', '-', '/':
+		if c == '.' && l.peek(1) >= '0' && l.peek(1) <= '9' {
+			return l.number()
+		}
+		l.pos++
+		return tok(tokPunct, string(c))
+	case '?', '$':
+		l.pos++
+		name := l.name(false)
+		if name == "" {
+			return token{}, l.errorf("empty variable name")
+		}
+		return tok(tokVar, name)
+	case '@':
+		l.pos++
+		start := l.pos
+		for l.pos < len(l.s) {
+			c := l.s[l.pos]
+			if c == '-' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') {
+				l.pos++
+				continue
+			}
+			break
+		}
+		if start == l.pos {
+			return token{}, l.errorf("empty language tag")
+		}
+		return tok(tokLang, l.s[start:l.pos])
+	case '"', '\'':
+		s, err := l.str()
+		if err != nil {
+			return token{}, err
+		}
+		return tok(tokString, s)
+	case '_':
+		if l.peek(1) == ':' {
+			l.pos += 2
+			name := l.name(false)
+			if name == "" {
+				return token{}, l.errorf("empty blank node label")
+			}
+			return tok(tokBNode, name)
+		}
+	}
+	if c >= '0' && c <= '9' {
+		return l.number()
+	}
+	r, n := utf8.DecodeRuneInString(l.s[l.pos:])
+	if c == ':' || isNameStart(r) {
+		if c != ':' {
+			l.pos += n
+		}
+		pref := l.s[pos:l.pos] + l.name(false)
+		if l.peek(0) != ':' {
+			return tok(tokIdent, pref)
+		}
+		l.pos++
+		return tok(tokPName, pref+":"+l.name(true))
+	}
+	return token{}, l.errorf("unexpected character %q", r)
+}
+
+// iriRef tries to read an IRI enclosed in angle brackets.
+// It returns false and leaves the position unchanged if it's not a valid IRI,
+// in which case '<' should be treated as an operator.
+func (l *lexer) iriRef() (string, bool) {
+	for i := l.pos + 1; i < len(l.s); i++ {
+		switch c := l.s[i]; c {
+		case '>':
+			iri := l.s[l.pos+1 : i]
+			l.pos = i + 1
+			return iri, true
+		case '<', '"', '{', '}', '|', '^', '`', '\\':
+			return "", false
+		default:
+			if c <= 0x20 {
+				return "", false
+			}
+		}
+	}
+	return "", false
+}
+
+func (l *lexer) number() (token, error) {
+	pos := l.pos
+	digits := func() int {
+		n := 0
+		for l.pos < len(l.s) && l.s[l.pos] >= '0' && l.s[l.pos] <= '9' {
+			l.pos++
+			n++
+		}
+		return n
+	}
+	typ := tokInteger
+	digits()
+	if l.peek(0) == '.' && l.peek(1) >= '0' && l.peek(1) <= '9' {
+		l.pos++
+		digits()
+		typ = tokDecimal
+	}
+	if c := l.peek(0); c == 'e' || c == 'E' {
+		save := l.pos
+		l.pos++
+		if c := l.peek(0); c == '+' || c == '-' {
+			l.pos++
+		}
+		if digits() == 0 {
+			l.pos = save
+		} else {
+			typ = tokDouble
+		}
+	}
+	return token{typ: typ, val: l.s[pos:l.pos], pos: pos}, nil
+}
+
+func (l *lexer) str() (string, error) {
+	q := l.s[l.pos]
+	long := l.peek(1) == q && l.peek(2) == q
+	if long {
+		l.pos += 3
+	} else {
+		l.pos++
+	}
+	var buf []byte
+	for {
+		if l.pos >= len(l.s) {
+			return "", errIncomplete{what: "string"}
+		}
+		c := l.s[l.pos]
+		switch {
+		case c == q && !long:
+			l.pos++
+			return string(buf), nil
+		case c == q && long && l.peek(1) == q && l.peek(2) == q:
+			l.pos += 3
+			return string(buf), nil
+		case (c == '\n' || c == '\r') && !long:
+			return "", l.errorf("new line in string")
+		case c == '\\':
+			r, err := l.escape()
+			if err != nil {
+				return "", err
+			}
+			buf = append(buf, string(r)...)
+		default:
+			buf = append(buf, c)
+			l.pos++
+		}
+	}
+}
+
+func (l *lexer) escape() (rune, error) {
+	if l.pos+1 >= len(l.s) {
+		return 0, errIncomplete{what: "string"}
+	}
+	c := l.s[l.pos+1]
+	l.pos += 2
+	switch c {
+	case 't':
+		return '\t', nil
+	case 'n':
+		return '\n', nil
+	case 'r':
+		return '\r', nil
+	case 'b':
+		return '\b', nil
+	case 'f':
+		return '\f', nil
+	case '"', '\'', '\\':
+		return rune(c), nil
+	case 'u', 'U':
+		n := 4
+		if c == 'U' {
+			n = 8
+		}
+		if l.pos+n > len(l.s) {
+			return 0, errIncomplete{what: "string"}
+		}
+		v, err := strconv.ParseUint(l.s[l.pos:l.pos+n], 16, 32)
+		if err != nil {
+			return 0, l.errorf("invalid unicode escape: %v", err)
+		}
+		l.pos += n
+		return rune(v), nil
+	}
+	return 0, l.errorf("unknown escape sequence: \\%c", c)
+}
@@ -0,0 +1,797 @@
+package sparql
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/codelingo/cayley/quad"
+	"github.com/codelingo/cayley/query"
+	"github.com/codelingo/cayley/voc"
+)
+
+// QueryType is a form of SPARQL query.
+type QueryType int
+
+const (
+	Select QueryType = iota
+	Ask
+	Construct
+)
+
+func (t QueryType) String() string {
+	switch t {
+	case Select:
+		return "SELECT"
+	case Ask:
+		return "ASK"
+	case Construct:
+		return "CONSTRUCT"
+	}
+	return "invalid"
+}
+
+// Term is either a variable or a concrete quad value in a triple pattern.
+type Term struct {
+	Var   string
+	Value quad.Value
+}
+
+// IsVar checks if the term is a variable.
+func (t Term) IsVar() bool { return t.Value == nil }
+
+func (t Term) String() string {
+	if t.IsVar() {
+		return "?" + t.Var
+	}
+	return t.Value.String()
+}
+
+// Triple is a single triple pattern.
+type Triple struct {
+	Subject, Predicate, Object Term
+}
+
+func (t Triple) get(d quad.Direction) Term {
+	switch d {
+	case quad.Subject:
+		return t.Subject
+	case quad.Predicate:
+		return t.Predicate
+	case quad.Object:
+		return t.Object
+	}
+	panic(fmt.Errorf("unexpected direction: %v", d))
+}
+
+// Pattern is an element of a group graph pattern.
+type Pattern interface {
+	isPattern()
+}
+
+// BGP is a basic graph pattern - a set of triple patterns.
+type BGP []Triple
+
+// Group is a group graph pattern. Filters are applied to all the solutions of the group.
+type Group struct {
+	Patterns []Pattern
+	Filters  []Expr
+}
+
+// Optional is an OPTIONAL group graph pattern.
+type Optional struct {
+	Group *Group
+}
+
+// Union is an alternative of group graph patterns.
+type Union []*Group
+
+func (BGP) isPattern()      {}
+func (*Group) isPattern()   {}
+func (Optional) isPattern() {}
+func (Union) isPattern()    {}
+
+// Query is a parsed SPARQL query.
+type Query struct {
+	Type     QueryType
+	Distinct bool
+	// Vars is a list of projected variables. Empty list means all variables (SELECT *).
+	Vars     []string
+	Template []Triple
+	Where    *Group
+	Limit    int
+	Offset   int
+}
+
+// Parse parses a SPARQL query.
+//
+// It returns query.ErrParseMore if the query is incomplete.
+func Parse(qu string) (*Query, error) {
+	toks, err := lex(qu)
+	if _, ok := err.(errIncomplete); ok {
+		return nil, query.ErrParseMore
+	} else if err != nil {
+		return nil, err
+	}
+	p := &parser{toks: toks, prefixes: make(map[string]string)}
+	q, err := p.parseQuery()
+	if err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+type parser struct {
+	toks     []token
+	pos      int
+	base     *url.URL
+	prefixes map[string]string
+	bnodes   int
+}
+
+func (p *parser) peek() token { return p.toks[p.pos] }
+
+func (p *parser) next() token {
+	t := p.toks[p.pos]
+	if t.typ != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) errorf(t token, format string, args ...interface{}) error {
+	if t.typ == tokEOF {
+		return query.ErrParseMore
+	}
+	return fmt.Errorf("sparql: at %d: %s", t.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *parser) expect(v string) error {
+	t := p.next()
+	if !t.is(v) {
+		return p.errorf(t, "expected %q, got %v", v, t)
+	}
+	return nil
+}
+
+// accept consumes the next token if it matches a given value.
+func (p *parser) accept(v string) bool {
+	if p.peek().is(v) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) newBNode() Term {
+	p.bnodes++
+	return Term{Var: "_:b" + strconv.Itoa(p.bnodes)}
+}
+
+func (p *parser) parseQuery() (*Query, error) {
+	if err := p.parsePrologue(); err != nil {
+		return nil, err
+	}
+	q := &Query{Limit: -1}
+	t := p.next()
+	switch {
+	case t.is("SELECT"):
+		q.Type = Select
+		if p.accept("DISTINCT") {
+			q.Distinct = true
+		} else if p.accept("REDUCED") {
+			// REDUCED permits, but not requires eliminating duplicates
+		}
+		if !p.accept("*") {
+			for p.peek().typ == tokVar {
+				q.Vars = append(q.Vars, p.next().val)
+			}
+			if len(q.Vars) == 0 {
+				return nil, p.errorf(p.peek(), "expected variables list or '*'")
+			}
+		}
+	case t.is("ASK"):
+		q.Type = Ask
+	case t.is("CONSTRUCT"):
+		q.Type = Construct
+		if p.peek().is("{") {
+			p.next()
+			tpl, err := p.parseTriplesTemplate()
+			if err != nil {
+				return nil, err
+			}
+			q.Template = tpl
+		} else if !p.peek().is("WHERE") {
+			return nil, p.errorf(p.peek(), "expected construct template")
+		}
+	case t.is("DESCRIBE"):
+		return nil, p.errorf(t, "DESCRIBE queries are not supported")
+	default:
+		return nil, p.errorf(t, "expected query form, got %v", t)
+	}
+	p.accept("WHERE")
+	g, err := p.parseGroup()
+	if err != nil {
+		return nil, err
+	}
+	q.Where = g
+	if q.Type == Construct && q.Template == nil {
+		// CONSTRUCT WHERE { ... } short form
+		if len(g.Patterns) != 1 || len(g.Filters) != 0 {
+			return nil, errors.New("sparql: CONSTRUCT WHERE allows only a basic graph pattern")
+		}
+		bgp, ok := g.Patterns[0].(BGP)
+		if !ok {
+			return nil, errors.New("sparql: CONSTRUCT WHERE allows only a basic graph pattern")
+		}
+		q.Template = []Triple(bgp)
+	}
+	if err = p.parseModifiers(q); err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.typ != tokEOF {
+		return nil, p.errorf(t, "unexpected %v", t)
+	}
+	return q, nil
+}
+
+func (p *parser) parsePrologue() error {
+	for {
+		t := p.peek()
+		switch {
+		case t.is("BASE"):
+			p.next()
+			t = p.next()
+			if t.typ != tokIRI {
+				return p.errorf(t, "expected base IRI, got %v", t)
+			}
+			u, err := url.Parse(t.val)
+			if err != nil {
+				return err
+			}
+			p.base = u
+		case t.is("PREFIX"):
+			p.next()
+			t = p.next()
+			if t.typ != tokPName || !strings.HasSuffix(t.val, ":") {
+				return p.errorf(t, "expected prefix name, got %v", t)
+			}
+			pref := t.val
+			t = p.next()
+			if t.typ != tokIRI {
+				return p.errorf(t, "expected prefix IRI, got %v", t)
+			}
+			p.prefixes[pref] = string(p.resolve(t.val))
+		default:
+			return nil
+		}
+	}
+}
+
+func (p *parser) parseModifiers(q *Query) error {
+	for {
+		t := p.peek()
+		switch {
+		case t.is("LIMIT"), t.is("OFFSET"):
+			p.next()
+			v := p.next()
+			if v.typ != tokInteger {
+				return p.errorf(v, "expected integer, got %v", v)
+			}
+			n, err := strconv.Atoi(v.val)
+			if err != nil {
+				return err
+			}
+			if t.is("LIMIT") {
+				q.Limit = n
+			} else {
+				q.Offset = n
+			}
+		case t.is("ORDER"), t.is("GROUP"), t.is("HAVING"):
+			return p.errorf(t, "%s is not supported", strings.ToUpper(t.val))
+		default:
+			return nil
+		}
+	}
+}
+
+func (p *parser) resolve(iri string) quad.IRI {
+	if p.base == nil {
+		return quad.IRI(iri)
+	}
+	u, err := url.Parse(iri)
+	if err != nil {
+		return quad.IRI(iri)
+	}
+	return quad.IRI(p.base.ResolveReference(u).String())
+}
+
+func (p *parser) expandPName(t token) (quad.IRI, error) {
+	i := strings.Index(t.val, ":")
+	pref, local := t.val[:i+1], t.val[i+1:]
+	if ns, ok := p.prefixes[pref]; ok {
+		return quad.IRI(ns + local), nil
+	}
+	if full := voc.FullIRI(t.val); full != t.val {
+		return quad.IRI(full), nil
+	}
+	return "", p.errorf(t, "unknown prefix: %q", pref)
+}
+
+// parseGroup parses a group graph pattern, including braces.
+func (p *parser) parseGroup() (*Group, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	g := &Group{}
+	var bgp BGP
+	flush := func() {
+		if len(bgp) != 0 {
+			g.Patterns = append(g.Patterns, bgp)
+			bgp = nil
+		}
+	}
+	for {
+		t := p.peek()
+		switch {
+		case t.is("}"):
+			p.next()
+			flush()
+			return g, nil
+		case t.typ == tokEOF:
+			return nil, query.ErrParseMore
+		case t.is("."):
+			p.next()
+		case t.is("OPTIONAL"):
+			p.next()
+			flush()
+			sub, err := p.parseGroup()
+			if err != nil {
+				return nil, err
+			}
+			g.Patterns = append(g.Patterns, Optional{Group: sub})
+		case t.is("FILTER"):
+			p.next()
+			e, err := p.parseConstraint()
+			if err != nil {
+				return nil, err
+			}
+			g.Filters = append(g.Filters, e)
+		case t.is("{"):
+			flush()
+			sub, err := p.parseGroup()
+			if err != nil {
+				return nil, err
+			}
+			if !p.peek().is("UNION") {
+				g.Patterns = append(g.Patterns, sub)
+				continue
+			}
+			u := Union{sub}
+			for p.accept("UNION") {
+				sub, err = p.parseGroup()
+				if err != nil {
+					return nil, err
+				}
+				u = append(u, sub)
+			}
+			g.Patterns = append(g.Patterns, u)
+		case t.is("GRAPH"), t.is("MINUS"), t.is("BIND"), t.is("VALUES"), t.is("SERVICE"):
+			return nil, p.errorf(t, "%s is not supported", strings.ToUpper(t.val))
+		default:
+			tr, err := p.parseTriplesSameSubject()
+			if err != nil {
+				return nil, err
+			}
+			bgp = append(bgp, tr...)
+		}
+	}
+}
+
+// parseTriplesTemplate parses triples until the closing brace.
+func (p *parser) parseTriplesTemplate() ([]Triple, error) {
+	var out []Triple
+	for {
+		t := p.peek()
+		switch {
+		case t.is("}"):
+			p.next()
+			return out, nil
+		case t.typ == tokEOF:
+			return nil, query.ErrParseMore
+		case t.is("."):
+			p.next()
+		default:
+			tr, err := p.parseTriplesSameSubject()
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, tr...)
+		}
+	}
+}
+
+func (p *parser) parseTriplesSameSubject() ([]Triple, error) {
+	var out []Triple
+	if p.accept("[") {
+		s := p.newBNode()
+		if err := p.parsePropertyList(s, &out); err != nil {
+			return nil, err
+		}
+		if err := p.expect("]"); err != nil {
+			return nil, err
+		}
+		if p.peek().is(".") || p.peek().is("}") {
+			return out, nil
+		}
+		err := p.parsePropertyList(s, &out)
+		return out, err
+	}
+	s, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	err = p.parsePropertyList(s, &out)
+	return out, err
+}
+
+func (p *parser) parsePropertyList(s Term, out *[]Triple) error {
+	for {
+		var pred Term
+		if t := p.peek(); t.typ == tokIdent && t.val == "a" {
+			p.next()
+			pred = Term{Value: quad.IRI(rdfType)}
+		} else {
+			var err error
+			pred, err = p.parseTerm()
+			if err != nil {
+				return err
+			}
+			if v := pred.Value; v != nil {
+				if _, ok := v.(quad.IRI); !ok {
+					return fmt.Errorf("sparql: predicate must be an IRI or a variable, got %v", v)
+				}
+			}
+		}
+		for {
+			var o Term
+			if p.accept("[") {
+				o = p.newBNode()
+				if err := p.parsePropertyList(o, out); err != nil {
+					return err
+				}
+				if err := p.expect("]"); err != nil {
+					return err
+				}
+			} else {
+				var err error
+				o, err = p.parseTerm()
+				if err != nil {
+					return err
+				}
+			}
+			*out = append(*out, Triple{Subject: s, Predicate: pred, Object: o})
+			if !p.accept(",") {
+				break
+			}
+		}
+		if !p.accept(";") {
+			return nil
+		}
+		for p.accept(";") {
+		}
+		if t := p.peek(); t.is(".") || t.is("}") || t.is("]") {
+			return nil
+		}
+	}
+}
+
+const (
+	rdfType   = "http://www.w3.org/1999/02/22-rdf-syntax-ns#type"
+	xsdPrefix = "http://www.w3.org/2001/XMLSchema#"
+)
+
+// parseTerm parses a variable, IRI, blank node or a literal.
+func (p *parser) parseTerm() (Term, error) {
+	t := p.next()
+	switch t.typ {
+	case tokVar:
+		return Term{Var: t.val}, nil
+	case tokBNode:
+		// blank nodes in patterns act as non-distinguished variables
+		return Term{Var: "_:" + t.val}, nil
+	case tokAnon:
+		return p.newBNode(), nil
+	case tokIRI, tokPName:
+		iri, err := p.parseIRI(t)
+		if err != nil {
+			return Term{}, err
+		}
+		return Term{Value: iri}, nil
+	case tokString:
+		v, err := p.parseLiteral(t)
+		if err != nil {
+			return Term{}, err
+		}
+		return Term{Value: v}, nil
+	case tokInteger, tokDecimal, tokDouble:
+		v, err := numberValue(t)
+		if err != nil {
+			return Term{}, err
+		}
+		return Term{Value: v}, nil
+	case tokIdent:
+		switch strings.ToLower(t.val) {
+		case "true":
+			return Term{Value: quad.Bool(true)}, nil
+		case "false":
+			return Term{Value: quad.Bool(false)}, nil
+		}
+	case tokPunct:
+		if t.val == "+" || t.val == "-" {
+			n := p.next()
+			switch n.typ {
+			case tokInteger, tokDecimal, tokDouble:
+				n.val = t.val + n.val
+				v, err := numberValue(n)
+				if err != nil {
+					return Term{}, err
+				}
+				return Term{Value: v}, nil
+			}
+		}
+	}
+	return Term{}, p.errorf(t, "unexpected %v", t)
+}
+
+func (p *parser) parseIRI(t token) (quad.IRI, error) {
+	if t.typ == tokPName {
+		return p.expandPName(t)
+	}
+	return p.resolve(t.val), nil
+}
+
+// parseLiteral parses an optional language tag or a data type following a string token.
+func (p *parser) parseLiteral(t token) (quad.Value, error) {
+	s := quad.String(t.val)
+	switch n := p.peek(); n.typ {
+	case tokLang:
+		p.next()
+		return quad.LangString{Value: s, Lang: n.val}, nil
+	case tokDataType:
+		p.next()
+		dt := p.next()
+		if dt.typ != tokIRI && dt.typ != tokPName {
+			return nil, p.errorf(dt, "expected data type IRI, got %v", dt)
+		}
+		iri, err := p.parseIRI(dt)
+		if err != nil {
+			return nil, err
+		}
+		ts := quad.TypedString{Value: s, Type: iri}
+		if v, err := ts.ParseValue(); err == nil {
+			return v, nil
+		}
+		return ts, nil
+	}
+	return s, nil
+}
+
+func numberValue(t token) (quad.Value, error) {
+	if t.typ == tokInteger {
+		v, err := strconv.ParseInt(t.val, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		return quad.Int(v), nil
+	}
+	v, err := strconv.ParseFloat(t.val, 64)
+	if err != nil {
+		return nil, err
+	}
+	return quad.Float(v), nil
+}
+
+// parseConstraint parses a FILTER constraint: either a bracketted expression or a function call.
+func (p *parser) parseConstraint() (Expr, error) {
+	if p.peek().is("(") {
+		p.next()
+		e, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		if err = p.expect(")"); err != nil {
+			return nil, err
+		}
+		return e, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parseExpr() (Expr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("||") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryExpr{Op: "||", Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (Expr, error) {
+	left, err := p.parseRelational()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("&&") {
+		right, err := p.parseRelational()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryExpr{Op: "&&", Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseRelational() (Expr, error) {
+	left, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range []string{"=", "!=", "<", "<=", ">", ">="} {
+		if p.accept(op) {
+			right, err := p.parseAdditive()
+			if err != nil {
+				return nil, err
+			}
+			return binaryExpr{Op: op, Left: left, Right: right}, nil
+		}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAdditive() (Expr, error) {
+	left, err := p.parseMultiplicative()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		var op string
+		if p.accept("+") {
+			op = "+"
+		} else if p.accept("-") {
+			op = "-"
+		} else {
+			return left, nil
+		}
+		right, err := p.parseMultiplicative()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryExpr{Op: op, Left: left, Right: right}
+	}
+}
+
+func (p *parser) parseMultiplicative() (Expr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		var op string
+		if p.accept("*") {
+			op = "*"
+		} else if p.accept("/") {
+			op = "/"
+		} else {
+			return left, nil
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryExpr{Op: op, Left: left, Right: right}
+	}
+}
+
+func (p *parser) parseUnary() (Expr, error) {
+	for _, op := range []string{"!", "-", "+"} {
+		if p.accept(op) {
+			e, err := p.parseUnary()
+			if err != nil {
+				return nil, err
+			}
+			return unaryExpr{Op: op, Expr: e}, nil
+		}
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (Expr, error) {
+	t := p.peek()
+	switch t.typ {
+	case tokPunct:
+		if t.val == "(" {
+			p.next()
+			e, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			if err = p.expect(")"); err != nil {
+				return nil, err
+			}
+			return e, nil
+		}
+	case tokVar:
+		p.next()
+		return varExpr(t.val), nil
+	case tokIdent:
+		name := strings.ToUpper(t.val)
+		if name == "TRUE" || name == "FALSE" {
+			p.next()
+			return valueExpr{Value: quad.Bool(name == "TRUE")}, nil
+		}
+		if _, ok := builtins[name]; !ok {
+			return nil, p.errorf(t, "unknown function: %s", t.val)
+		}
+		p.next()
+		args, err := p.parseArgs()
+		if err != nil {
+			return nil, err
+		}
+		return newCallExpr(name, args)
+	case tokIRI, tokPName:
+		p.next()
+		iri, err := p.parseIRI(t)
+		if err != nil {
+			return nil, err
+		}
+		if !p.peek().is("(") {
+			return valueExpr{Value: iri}, nil
+		}
+		args, err := p.parseArgs()
+		if err != nil {
+			return nil, err
+		}
+		return newCastExpr(iri, args)
+	}
+	// literals
+	term, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	if term.IsVar() {
+		return nil, p.errorf(t, "blank nodes are not allowed in expressions")
+	}
+	return valueExpr{Value: term.Value}, nil
+}
+
+func (p *parser) parseArgs() ([]Expr, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	var args []Expr
+	if p.accept(")") {
+		return args, nil
+	}
+	for {
+		e, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, e)
+		if p.accept(")") {
+			return args, nil
+		}
+		if err = p.expect(","); err != nil {
+			return nil, err
+		}
+	}
+}
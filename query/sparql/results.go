@@ -0,0 +1,449 @@
+package sparql
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/codelingo/cayley/quad"
+)
+
+// Variables returns names of the variables that will be present in the results of SELECT query.
+// For SELECT * queries it returns all the variables in order of their appearance in the query.
+func (q *Query) Variables() []string {
+	if len(q.Vars) != 0 {
+		return q.Vars
+	}
+	var (
+		out  []string
+		seen = make(map[string]struct{})
+	)
+	add := func(t Term) {
+		if !t.IsVar() || isHiddenVar(t.Var) {
+			return
+		}
+		if _, ok := seen[t.Var]; ok {
+			return
+		}
+		seen[t.Var] = struct{}{}
+		out = append(out, t.Var)
+	}
+	var walk func(g *Group)
+	walk = func(g *Group) {
+		for _, p := range g.Patterns {
+			switch p := p.(type) {
+			case BGP:
+				for _, t := range p {
+					add(t.Subject)
+					add(t.Predicate)
+					add(t.Object)
+				}
+			case *Group:
+				walk(p)
+			case Optional:
+				walk(p.Group)
+			case Union:
+				for _, g := range p {
+					walk(g)
+				}
+			}
+		}
+	}
+	if q.Where != nil {
+		walk(q.Where)
+	}
+	return out
+}
+
+// ResultsWriter writes results of SELECT or ASK query in one of SPARQL results formats.
+//
+// For SELECT queries WriteHead must be called before writing any rows.
+// Writer must be closed to flush results.
+type ResultsWriter interface {
+	WriteHead(vars []string) error
+	WriteRow(row map[string]quad.Value) error
+	WriteBool(v bool) error
+	Close() error
+}
+
+// ResultsFormat is a description of SPARQL query results format.
+type ResultsFormat struct {
+	Name   string
+	Ext    []string
+	Mime   []string
+	Writer func(io.Writer) ResultsWriter
+}
+
+var resultsFormats = []ResultsFormat{
+	{
+		Name: "json", Ext: []string{".srj"},
+		Mime:   []string{"application/sparql-results+json", "application/json"},
+		Writer: func(w io.Writer) ResultsWriter { return &jsonResults{w: bufio.NewWriter(w)} },
+	},
+	{
+		Name: "xml", Ext: []string{".srx"},
+		Mime:   []string{"application/sparql-results+xml", "application/xml"},
+		Writer: func(w io.Writer) ResultsWriter { return &xmlResults{w: bufio.NewWriter(w)} },
+	},
+	{
+		Name: "csv", Ext: []string{".csv"},
+		Mime:   []string{"text/csv"},
+		Writer: func(w io.Writer) ResultsWriter { return &csvResults{w: csv.NewWriter(w)} },
+	},
+	{
+		Name: "tsv", Ext: []string{".tsv"},
+		Mime:   []string{"text/tab-separated-values"},
+		Writer: func(w io.Writer) ResultsWriter { return &tsvResults{w: bufio.NewWriter(w)} },
+	},
+}
+
+// ResultsFormats returns a list of supported results formats.
+func ResultsFormats() []ResultsFormat {
+	return append([]ResultsFormat{}, resultsFormats...)
+}
+
+// ResultsFormatByName returns a results format with a given name. It returns nil if format is not supported.
+func ResultsFormatByName(name string) *ResultsFormat {
+	for _, f := range resultsFormats {
+		if f.Name == name {
+			return &f
+		}
+	}
+	return nil
+}
+
+// ResultsFormatByMime returns a results format for a given MIME type. It returns nil if format is not supported.
+func ResultsFormatByMime(mime string) *ResultsFormat {
+	if i := strings.Index(mime, ";"); i >= 0 {
+		mime = mime[:i]
+	}
+	mime = strings.TrimSpace(mime)
+	for _, f := range resultsFormats {
+		for _, m := range f.Mime {
+			if strings.EqualFold(m, mime) {
+				return &f
+			}
+		}
+	}
+	return nil
+}
+
+// rdfTerm is a decomposed representation of RDF term, as used by SPARQL results formats.
+type rdfTerm struct {
+	Type     string // uri, bnode or literal
+	Value    string
+	Lang     string
+	Datatype string
+}
+
+func toRDFTerm(v quad.Value) rdfTerm {
+	if ts, ok := v.(quad.TypedStringer); ok {
+		v = ts.TypedString()
+	}
+	switch v := v.(type) {
+	case quad.IRI:
+		return rdfTerm{Type: "uri", Value: string(v.Full())}
+	case quad.BNode:
+		return rdfTerm{Type: "bnode", Value: string(v)}
+	case quad.String:
+		return rdfTerm{Type: "literal", Value: string(v)}
+	case quad.LangString:
+		return rdfTerm{Type: "literal", Value: string(v.Value), Lang: v.Lang}
+	case quad.TypedString:
+		return rdfTerm{Type: "literal", Value: string(v.Value), Datatype: string(v.Type.Full())}
+	}
+	return rdfTerm{Type: "literal", Value: quad.StringOf(v)}
+}
+
+type jsonResults struct {
+	w    *bufio.Writer
+	vars []string
+	rows int
+	err  error
+}
+
+func (w *jsonResults) write(v interface{}) {
+	if w.err != nil {
+		return
+	}
+	var data []byte
+	switch v := v.(type) {
+	case string:
+		_, w.err = w.w.WriteString(v)
+		return
+	default:
+		data, w.err = json.Marshal(v)
+	}
+	if w.err == nil {
+		_, w.err = w.w.Write(data)
+	}
+}
+
+func (w *jsonResults) WriteHead(vars []string) error {
+	if vars == nil {
+		vars = []string{}
+	}
+	w.vars = vars
+	w.write(`{"head":{"vars":`)
+	w.write(vars)
+	w.write(`},"results":{"bindings":[`)
+	return w.err
+}
+
+func (w *jsonResults) WriteRow(row map[string]quad.Value) error {
+	type jsonTerm struct {
+		Type     string `json:"type"`
+		Value    string `json:"value"`
+		Lang     string `json:"xml:lang,omitempty"`
+		Datatype string `json:"datatype,omitempty"`
+	}
+	m := make(map[string]jsonTerm, len(row))
+	for k, v := range row {
+		if v == nil {
+			continue
+		}
+		t := toRDFTerm(v)
+		m[k] = jsonTerm{Type: t.Type, Value: t.Value, Lang: t.Lang, Datatype: t.Datatype}
+	}
+	if w.rows != 0 {
+		w.write(",")
+	}
+	w.rows++
+	w.write(m)
+	return w.err
+}
+
+func (w *jsonResults) WriteBool(v bool) error {
+	w.vars = nil
+	w.write(`{"head":{},"boolean":`)
+	w.write(v)
+	w.write("}\n")
+	return w.err
+}
+
+func (w *jsonResults) Close() error {
+	if w.vars != nil {
+		w.write("]}}\n")
+	}
+	if w.err != nil {
+		return w.err
+	}
+	return w.w.Flush()
+}
+
+const xmlResultsNS = "http://www.w3.org/2005/sparql-results#"
+
+type xmlResults struct {
+	w    *bufio.Writer
+	head bool
+	err  error
+}
+
+func (w *xmlResults) write(s string) {
+	if w.err == nil {
+		_, w.err = w.w.WriteString(s)
+	}
+}
+
+func (w *xmlResults) text(s string) {
+	if w.err == nil {
+		w.err = xml.EscapeText(w.w, []byte(s))
+	}
+}
+
+func (w *xmlResults) attr(s string) {
+	w.write(`"`)
+	w.text(s)
+	w.write(`"`)
+}
+
+func (w *xmlResults) WriteHead(vars []string) error {
+	w.head = true
+	w.write(xml.Header)
+	w.write(`<sparql xmlns="` + xmlResultsNS + `">` + "\n<head>\n")
+	for _, v := range vars {
+		w.write(`<variable name=`)
+		w.attr(v)
+		w.write("/>\n")
+	}
+	w.write("</head>\n<results>\n")
+	return w.err
+}
+
+func (w *xmlResults) WriteRow(row map[string]quad.Value) error {
+	w.write("<result>\n")
+	names := make([]string, 0, len(row))
+	for k := range row {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		v := row[k]
+		if v == nil {
+			continue
+		}
+		w.write(`<binding name=`)
+		w.attr(k)
+		w.write(">")
+		t := toRDFTerm(v)
+		switch t.Type {
+		case "uri":
+			w.write("<uri>")
+			w.text(t.Value)
+			w.write("</uri>")
+		case "bnode":
+			w.write("<bnode>")
+			w.text(t.Value)
+			w.write("</bnode>")
+		default:
+			w.write("<literal")
+			if t.Lang != "" {
+				w.write(` xml:lang=`)
+				w.attr(t.Lang)
+			} else if t.Datatype != "" {
+				w.write(` datatype=`)
+				w.attr(t.Datatype)
+			}
+			w.write(">")
+			w.text(t.Value)
+			w.write("</literal>")
+		}
+		w.write("</binding>\n")
+	}
+	w.write("</result>\n")
+	return w.err
+}
+
+func (w *xmlResults) WriteBool(v bool) error {
+	w.write(xml.Header)
+	w.write(`<sparql xmlns="` + xmlResultsNS + `">` + "\n<head/>\n<boolean>")
+	if v {
+		w.write("true")
+	} else {
+		w.write("false")
+	}
+	w.write("</boolean>\n</sparql>\n")
+	return w.err
+}
+
+func (w *xmlResults) Close() error {
+	if w.head {
+		w.write("</results>\n</sparql>\n")
+	}
+	if w.err != nil {
+		return w.err
+	}
+	return w.w.Flush()
+}
+
+// askVar is a column name used by tabular formats for the results of ASK query.
+const askVar = "_askResult"
+
+type csvResults struct {
+	w    *csv.Writer
+	vars []string
+}
+
+func (w *csvResults) WriteHead(vars []string) error {
+	w.vars = vars
+	return w.w.Write(vars)
+}
+
+func (w *csvResults) WriteRow(row map[string]quad.Value) error {
+	rec := make([]string, len(w.vars))
+	for i, name := range w.vars {
+		v := row[name]
+		if v == nil {
+			continue
+		}
+		t := toRDFTerm(v)
+		if t.Type == "bnode" {
+			rec[i] = "_:" + t.Value
+		} else {
+			rec[i] = t.Value
+		}
+	}
+	return w.w.Write(rec)
+}
+
+func (w *csvResults) WriteBool(v bool) error {
+	if err := w.w.Write([]string{askVar}); err != nil {
+		return err
+	}
+	s := "false"
+	if v {
+		s = "true"
+	}
+	return w.w.Write([]string{s})
+}
+
+func (w *csvResults) Close() error {
+	w.w.Flush()
+	return w.w.Error()
+}
+
+type tsvResults struct {
+	w    *bufio.Writer
+	vars []string
+	err  error
+}
+
+func (w *tsvResults) writeLine(fields []string) error {
+	if w.err == nil {
+		_, w.err = w.w.WriteString(strings.Join(fields, "\t") + "\n")
+	}
+	return w.err
+}
+
+func (w *tsvResults) WriteHead(vars []string) error {
+	w.vars = vars
+	head := make([]string, len(vars))
+	for i, v := range vars {
+		head[i] = "?" + v
+	}
+	return w.writeLine(head)
+}
+
+func (w *tsvResults) WriteRow(row map[string]quad.Value) error {
+	rec := make([]string, len(w.vars))
+	for i, name := range w.vars {
+		v := row[name]
+		if v == nil {
+			continue
+		}
+		switch t := v.(type) {
+		case quad.IRI:
+			v = t.Full()
+		case quad.TypedString:
+			t.Type = t.Type.Full()
+			v = t
+		case quad.TypedStringer:
+			ts := t.TypedString()
+			ts.Type = ts.Type.Full()
+			v = ts
+		}
+		// values are encoded in the same way as in N-Quads, which is a subset of Turtle syntax
+		rec[i] = v.String()
+	}
+	return w.writeLine(rec)
+}
+
+func (w *tsvResults) WriteBool(v bool) error {
+	s := "false"
+	if v {
+		s = "true"
+	}
+	w.writeLine([]string{"?" + askVar})
+	return w.writeLine([]string{s})
+}
+
+func (w *tsvResults) Close() error {
+	if w.err != nil {
+		return w.err
+	}
+	return w.w.Flush()
+}
@@ -0,0 +1,208 @@
+// Package sparql implements a subset of SPARQL 1.1 query language.
+//
+// Supported query forms are SELECT, ASK and CONSTRUCT with basic graph patterns,
+// OPTIONAL, UNION, FILTER and LIMIT/OFFSET solution modifiers.
+package sparql
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"golang.org/x/net/context"
+
+	"github.com/codelingo/cayley/graph"
+	"github.com/codelingo/cayley/quad"
+	"github.com/codelingo/cayley/query"
+)
+
+const Name = "sparql"
+
+func init() {
+	query.RegisterLanguage(query.Language{
+		Name: Name,
+		Session: func(qs graph.QuadStore) query.Session {
+			return NewSession(qs)
+		},
+		HTTP: func(qs graph.QuadStore) query.HTTP {
+			return NewSession(qs)
+		},
+		REPL: func(qs graph.QuadStore) query.REPLSession {
+			return NewSession(qs)
+		},
+	})
+}
+
+// AskResult is a result of ASK query.
+type AskResult bool
+
+func (r AskResult) Result() interface{} { return bool(r) }
+func (AskResult) Err() error            { return nil }
+
+// QuadResult is a single quad produced by CONSTRUCT query.
+type QuadResult quad.Quad
+
+func (r QuadResult) Result() interface{} { return quad.Quad(r) }
+func (QuadResult) Err() error            { return nil }
+
+type Session struct {
+	qs graph.QuadStore
+
+	// collated results for HTTP
+	typ   QueryType
+	rows  []map[string]interface{}
+	ask   *bool
+	quads []quad.Quad
+}
+
+func NewSession(qs graph.QuadStore) *Session {
+	return &Session{qs: qs}
+}
+
+func (s *Session) send(ctx context.Context, out chan query.Result, r query.Result) bool {
+	select {
+	case out <- r:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// Run parses and runs the query, calling fnc for each solution.
+//
+// Solutions are passed as maps from variable names to graph values for SELECT queries,
+// as a single bool value for ASK, and as quads for CONSTRUCT queries.
+func Run(ctx context.Context, qs graph.QuadStore, qu *Query, limit int, fnc func(query.Result) error) error {
+	switch qu.Type {
+	case Ask:
+		found := false
+		err := qu.iterate(ctx, qs, 1, func(binding) error {
+			found = true
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		return fnc(AskResult(found))
+	case Construct:
+		var (
+			seq  quad.Sequence
+			seen = make(map[quad.Quad]struct{})
+		)
+		n := 0
+		err := qu.iterate(ctx, qs, 0, func(b binding) error {
+			for _, q := range qu.construct(qs, b, &seq) {
+				if _, ok := seen[q]; ok {
+					continue
+				}
+				seen[q] = struct{}{}
+				if err := fnc(QuadResult(q)); err != nil {
+					return err
+				}
+				n++
+				if limit > 0 && n >= limit {
+					return errStop
+				}
+			}
+			return nil
+		})
+		if err == errStop {
+			err = nil
+		}
+		return err
+	}
+	return qu.iterate(ctx, qs, limit, func(b binding) error {
+		return fnc(query.TagMapResult(map[string]graph.Value(b)))
+	})
+}
+
+func (s *Session) Execute(ctx context.Context, input string, out chan query.Result, limit int) {
+	defer close(out)
+	qu, err := Parse(input)
+	if err != nil {
+		s.send(ctx, out, query.ErrorResult(err))
+		return
+	}
+	s.typ = qu.Type
+	err = Run(ctx, s.qs, qu, limit, func(r query.Result) error {
+		if !s.send(ctx, out, r) {
+			return ctx.Err()
+		}
+		return nil
+	})
+	if err != nil && err != ctx.Err() {
+		s.send(ctx, out, query.ErrorResult(err))
+	}
+}
+
+func (s *Session) FormatREPL(result query.Result) string {
+	switch r := result.Result().(type) {
+	case bool:
+		return fmt.Sprintf("=> %v\n", r)
+	case quad.Quad:
+		return r.NQuad() + "\n"
+	case map[string]graph.Value:
+		out := fmt.Sprintln("****")
+		keys := make([]string, 0, len(r))
+		for k := range r {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			out += fmt.Sprintf("%s : %s\n", k, s.qs.NameOf(r[k]))
+		}
+		return out
+	}
+	return ""
+}
+
+func (s *Session) ShapeOf(string) (interface{}, error) {
+	return nil, errors.New("sparql: query shape is not supported")
+}
+
+func quadValueToNative(v quad.Value) interface{} {
+	if v == nil {
+		return nil
+	}
+	out := v.Native()
+	if nv, ok := out.(quad.Value); ok && v == nv {
+		return quad.StringOf(v)
+	}
+	return out
+}
+
+func (s *Session) Collate(result query.Result) {
+	switch r := result.Result().(type) {
+	case bool:
+		s.ask = &r
+	case quad.Quad:
+		s.quads = append(s.quads, r)
+	case map[string]graph.Value:
+		m := make(map[string]interface{}, len(r))
+		for k, v := range r {
+			m[k] = quadValueToNative(s.qs.NameOf(v))
+		}
+		s.rows = append(s.rows, m)
+	}
+}
+
+func (s *Session) Results() (interface{}, error) {
+	defer s.Clear()
+	switch s.typ {
+	case Ask:
+		return s.ask != nil && *s.ask, nil
+	case Construct:
+		if s.quads == nil {
+			return []quad.Quad{}, nil
+		}
+		return s.quads, nil
+	}
+	if s.rows == nil {
+		return []map[string]interface{}{}, nil
+	}
+	return s.rows, nil
+}
+
+func (s *Session) Clear() {
+	s.rows, s.ask, s.quads = nil, nil, nil
+}
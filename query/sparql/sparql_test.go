@@ -0,0 +1,252 @@
+package sparql
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+
+	"github.com/codelingo/cayley/graph"
+	"github.com/codelingo/cayley/graph/memstore"
+	"github.com/codelingo/cayley/quad"
+	"github.com/codelingo/cayley/quad/nquads"
+	"github.com/codelingo/cayley/query"
+	"github.com/codelingo/cayley/writer"
+)
+
+func makeTestSession(t testing.TB) *Session {
+	f, err := os.Open("../../data/testdata.nq")
+	require.NoError(t, err)
+	defer f.Close()
+	quads, err := quad.ReadAll(nquads.NewReader(f, false))
+	require.NoError(t, err)
+
+	qs := memstore.New()
+	w, err := writer.NewSingleReplication(qs, nil)
+	require.NoError(t, err)
+	require.NoError(t, w.AddQuadSet(quads))
+	return NewSession(qs)
+}
+
+func runQuery(t testing.TB, qu string) interface{} {
+	s := makeTestSession(t)
+	c := make(chan query.Result, 5)
+	go s.Execute(context.TODO(), qu, c, -1)
+	for r := range c {
+		require.NoError(t, r.Err())
+		s.Collate(r)
+	}
+	out, err := s.Results()
+	require.NoError(t, err)
+	return out
+}
+
+type row map[string]interface{}
+
+func sortRows(rows []map[string]interface{}) []string {
+	out := make([]string, 0, len(rows))
+	for _, r := range rows {
+		out = append(out, fmt.Sprint(r))
+	}
+	sort.Strings(out)
+	return out
+}
+
+var testSelectQueries = []struct {
+	message string
+	query   string
+	expect  []row
+}{
+	{
+		message: "get followers of bob",
+		query:   `SELECT ?x WHERE { ?x <follows> <bob> }`,
+		expect: []row{
+			{"x": "<alice>"},
+			{"x": "<charlie>"},
+			{"x": "<dani>"},
+		},
+	},
+	{
+		message: "join patterns",
+		query: `SELECT ?x ?y WHERE {
+			?x <follows> ?y .
+			?y <status> "cool_person" .
+		}`,
+		expect: []row{
+			{"x": "<alice>", "y": "<bob>"},
+			{"x": "<charlie>", "y": "<bob>"},
+			{"x": "<charlie>", "y": "<dani>"},
+			{"x": "<dani>", "y": "<bob>"},
+			{"x": "<dani>", "y": "<greg>"},
+			{"x": "<fred>", "y": "<greg>"},
+		},
+	},
+	{
+		message: "use prefixes and distinct",
+		query: `PREFIX ex: <>
+			SELECT DISTINCT ?y WHERE { ?x ex:follows ?y ; ex:status "cool_person" }`,
+		expect: []row{
+			{"y": "<bob>"},
+			{"y": "<fred>"},
+			{"y": "<greg>"},
+		},
+	},
+	{
+		message: "optional status",
+		query: `SELECT * WHERE {
+			<charlie> <follows> ?x .
+			OPTIONAL { ?x <follows> ?y . ?y <status> "cool_person" }
+		}`,
+		expect: []row{
+			{"x": "<bob>"},
+			{"x": "<dani>", "y": "<bob>"},
+			{"x": "<dani>", "y": "<greg>"},
+		},
+	},
+	{
+		message: "union of patterns",
+		query: `SELECT ?x WHERE {
+			{ ?x <follows> <fred> } UNION { ?x <status> "smart_person" }
+		}`,
+		expect: []row{
+			{"x": "<bob>"},
+			{"x": "<emily>"},
+			{"x": "<emily>"},
+			{"x": "<greg>"},
+		},
+	},
+	{
+		message: "filter by regex",
+		query:   `SELECT ?x ?s WHERE { ?x <status> ?s FILTER regex(?s, "^smart") }`,
+		expect: []row{
+			{"x": "<emily>", "s": "smart_person"},
+			{"x": "<greg>", "s": "smart_person"},
+		},
+	},
+	{
+		message: "filter by comparison",
+		query:   `SELECT ?x WHERE { <dani> <follows> ?x FILTER (?x > <c>) }`,
+		expect: []row{
+			{"x": "<greg>"},
+		},
+	},
+	{
+		message: "filter unbound optional values",
+		query: `SELECT ?x WHERE {
+			?x <follows> ?y .
+			OPTIONAL { ?x <status> ?s }
+			FILTER (!bound(?s))
+		}`,
+		expect: []row{
+			{"x": "<alice>"},
+			{"x": "<charlie>"},
+			{"x": "<charlie>"},
+			{"x": "<fred>"},
+		},
+	},
+	{
+		message: "join patterns with a cycle",
+		query: `SELECT * WHERE {
+			?x <follows> ?y .
+			?y <follows> ?z .
+			?x <follows> ?z .
+		}`,
+		expect: []row{
+			{"x": "<charlie>", "y": "<dani>", "z": "<bob>"},
+		},
+	},
+	{
+		message: "use a predicate variable",
+		query:   `SELECT ?o WHERE { <predicates> <are> ?p . <alice> ?p ?o }`,
+		expect: []row{
+			{"o": "<bob>"},
+		},
+	},
+	{
+		message: "join unconnected patterns",
+		query:   `SELECT * WHERE { <alice> <follows> ?x . ?y <status> "smart_person" }`,
+		expect: []row{
+			{"x": "<bob>", "y": "<emily>"},
+			{"x": "<bob>", "y": "<greg>"},
+		},
+	},
+}
+
+func TestSelect(t *testing.T) {
+	for _, test := range testSelectQueries {
+		got, ok := runQuery(t, test.query).([]map[string]interface{})
+		require.True(t, ok, "Failed to %s", test.message)
+		exp := make([]map[string]interface{}, 0, len(test.expect))
+		for _, r := range test.expect {
+			exp = append(exp, r)
+		}
+		require.Equal(t, sortRows(exp), sortRows(got), "Failed to %s", test.message)
+	}
+}
+
+func TestLimit(t *testing.T) {
+	got := runQuery(t, `SELECT ?x ?y WHERE { ?x <follows> ?y } LIMIT 3 OFFSET 6`)
+	require.Len(t, got, 2)
+	got = runQuery(t, `SELECT ?x ?y WHERE { ?x <follows> ?y } LIMIT 3 OFFSET 2`)
+	require.Len(t, got, 3)
+}
+
+func TestAsk(t *testing.T) {
+	require.Equal(t, true, runQuery(t, `ASK { <alice> <follows> <bob> }`))
+	require.Equal(t, false, runQuery(t, `ASK { <bob> <follows> <alice> }`))
+}
+
+func TestConstruct(t *testing.T) {
+	got := runQuery(t, `CONSTRUCT { <fred> <followed_by> ?x } WHERE { ?x <follows> <fred> }`)
+	quads, ok := got.([]quad.Quad)
+	require.True(t, ok)
+	var lines []string
+	for _, q := range quads {
+		lines = append(lines, q.NQuad())
+	}
+	sort.Strings(lines)
+	require.Equal(t, []string{
+		quad.MakeIRI("fred", "followed_by", "bob", "").NQuad(),
+		quad.MakeIRI("fred", "followed_by", "emily", "").NQuad(),
+	}, lines)
+}
+
+func TestParseMore(t *testing.T) {
+	_, err := Parse(`SELECT ?x WHERE { ?x <follows> `)
+	require.Equal(t, query.ErrParseMore, err)
+}
+
+func TestSingleIterator(t *testing.T) {
+	s := makeTestSession(t)
+	e := graph.NewExplainer(false)
+	c := make(chan query.Result, 5)
+	go s.Execute(graph.WithExplainer(context.TODO(), e), `SELECT * WHERE {
+		<charlie> <follows> ?x .
+		OPTIONAL { ?x <follows> ?y . ?y <status> "cool_person" }
+	}`, c, -1)
+	for r := range c {
+		require.NoError(t, r.Err())
+	}
+	trees := e.Explain()
+	require.Len(t, trees, 1, "patterns should be compiled to a single iterator")
+	require.Contains(t, graph.FormatExplanation(trees), "optional")
+}
+
+func TestRegexLangString(t *testing.T) {
+	s := NewSession(memstore.New(
+		quad.Make(quad.IRI("alice"), quad.IRI("name"), quad.LangString{Value: "Alice", Lang: "en"}, nil),
+		quad.Make(quad.IRI("bob"), quad.IRI("name"), quad.String("Bob"), nil),
+	))
+	c := make(chan query.Result, 5)
+	go s.Execute(context.TODO(), `SELECT ?x WHERE { ?x <name> ?n FILTER regex(?n, "^A") }`, c, -1)
+	for r := range c {
+		require.NoError(t, r.Err())
+		s.Collate(r)
+	}
+	got, err := s.Results()
+	require.NoError(t, err)
+	require.Equal(t, []map[string]interface{}{{"x": "<alice>"}}, got)
+}
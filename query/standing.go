@@ -0,0 +1,356 @@
+// Copyright 2017 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package query
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"golang.org/x/net/context"
+
+	"github.com/codelingo/cayley/graph"
+	"github.com/codelingo/cayley/quad"
+)
+
+// Diff is a change in results of a standing query.
+type Diff struct {
+	Added   []interface{} `json:"added"`
+	Removed []interface{} `json:"removed"`
+}
+
+// Empty checks if the diff has no changes.
+func (d Diff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0
+}
+
+// Standing runs the query as a standing query and sends changes of its results to out
+// until the context is cancelled. The first diff contains all initial results.
+//
+// The query is evaluated against a quad store that records all lookups made by it.
+// After each write to the store (see graph.Subscribe), the query is evaluated again only if
+// any of the deltas touch the recorded lookups, and rows that were added or removed since the
+// last evaluation are sent to out. Rows are compared by their JSON representation.
+func Standing(ctx context.Context, qs graph.QuadStore, lang, qu string, out chan<- Diff) error {
+	l := GetLanguage(lang)
+	if l == nil || (l.HTTP == nil && l.Session == nil) {
+		return fmt.Errorf("query: standing queries are not supported for %q", lang)
+	}
+	deltas, cancel := graph.Subscribe(qs, graph.DeltaFilter{})
+	defer func() { cancel() }()
+	// resubscribe is called when the feed closed the subscription
+	resubscribe := func() error {
+		if err := cancel(); err != graph.ErrFeedDropped {
+			// the store was closed; subscribing again would create a new feed for it
+			return err
+		}
+		deltas, cancel = graph.Subscribe(qs, graph.DeltaFilter{})
+		return nil
+	}
+
+	var (
+		w     *watchStore
+		rows  = make(rowSet)
+		first = true
+	)
+	eval := func() error {
+		w = newWatchStore(qs)
+		res, err := evalRows(ctx, w, l, qu)
+		if err != nil {
+			return err
+		}
+		d := rows.update(res)
+		if d.Empty() && !first {
+			return nil
+		}
+		first = false
+		select {
+		case out <- d:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	if err := eval(); err != nil {
+		return err
+	}
+	for {
+		var dirty bool
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case batch, ok := <-deltas:
+			if !ok {
+				// subscription was dropped; some deltas might be lost, thus always re-evaluate
+				if err := resubscribe(); err != nil {
+					return err
+				}
+				dirty = true
+			} else {
+				dirty = w.touches(batch)
+			}
+		}
+		// coalesce all pending writes into a single evaluation
+	pending:
+		for {
+			select {
+			case batch, ok := <-deltas:
+				if !ok {
+					if err := resubscribe(); err != nil {
+						return err
+					}
+					dirty = true
+					break pending
+				}
+				dirty = dirty || w.touches(batch)
+			default:
+				break pending
+			}
+		}
+		if dirty {
+			if err := eval(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// evalRows runs the query and splits its results into rows.
+func evalRows(ctx context.Context, qs graph.QuadStore, l *Language, qu string) ([]interface{}, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	c := make(chan Result, 5)
+	if l.HTTP != nil {
+		ses := l.HTTP(qs)
+		go ses.Execute(ctx, qu, c, -1)
+		for r := range c {
+			if err := r.Err(); err != nil {
+				return nil, err
+			}
+			ses.Collate(r)
+		}
+		res, err := ses.Results()
+		if err != nil {
+			return nil, err
+		}
+		return resultRows(res), nil
+	}
+	ses := l.Session(qs)
+	go ses.Execute(ctx, qu, c, -1)
+	var rows []interface{}
+	for r := range c {
+		if err := r.Err(); err != nil {
+			return nil, err
+		}
+		rows = append(rows, resultRows(r.Result())...)
+	}
+	return rows, nil
+}
+
+// resultRows splits a query result into rows. Results with named fields (like GraphQL)
+// are split by field, and each row will contain a single field.
+func resultRows(v interface{}) []interface{} {
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case []interface{}:
+		return v
+	case map[string]interface{}:
+		var rows []interface{}
+		for k, fv := range v {
+			switch fv := fv.(type) {
+			case nil:
+			case []map[string]interface{}:
+				for _, o := range fv {
+					rows = append(rows, map[string]interface{}{k: o})
+				}
+			default:
+				rows = append(rows, map[string]interface{}{k: fv})
+			}
+		}
+		return rows
+	}
+	return []interface{}{v}
+}
+
+type rowCount struct {
+	row interface{}
+	n   int
+}
+
+// rowSet is a multiset of result rows indexed by their JSON representation.
+type rowSet map[string]*rowCount
+
+func rowKey(row interface{}) string {
+	data, err := json.Marshal(row)
+	if err != nil {
+		return fmt.Sprintf("%#v", row)
+	}
+	return string(data)
+}
+
+// update replaces rows in the set and returns the difference.
+func (s rowSet) update(rows []interface{}) Diff {
+	next := make(rowSet, len(rows))
+	for _, r := range rows {
+		k := rowKey(r)
+		if c := next[k]; c != nil {
+			c.n++
+		} else {
+			next[k] = &rowCount{row: r, n: 1}
+		}
+	}
+	var d Diff
+	for k, c := range next {
+		n := c.n
+		if p := s[k]; p != nil {
+			n -= p.n
+		}
+		for ; n > 0; n-- {
+			d.Added = append(d.Added, c.row)
+		}
+	}
+	for k, p := range s {
+		n := p.n
+		if c := next[k]; c != nil {
+			n -= c.n
+		}
+		for ; n > 0; n-- {
+			d.Removed = append(d.Removed, p.row)
+		}
+		delete(s, k)
+	}
+	for k, c := range next {
+		s[k] = c
+	}
+	return d
+}
+
+// watchStore is a quad store wrapper that records lookups made by a query.
+//
+// A new delta can change results of the query only if the query scanned quads
+// with a value of this delta in the same direction, or if it looked up a value
+// that did not exist in the store, or if it iterated over all nodes or quads.
+type watchStore struct {
+	graph.QuadStore
+
+	mu      sync.Mutex
+	all     bool
+	lookups [4]map[string]struct{} // scanned values by direction
+	missing map[string]struct{}
+}
+
+func newWatchStore(qs graph.QuadStore) *watchStore {
+	w := &watchStore{QuadStore: qs, missing: make(map[string]struct{})}
+	for i := range w.lookups {
+		w.lookups[i] = make(map[string]struct{})
+	}
+	return w
+}
+
+func (w *watchStore) setAll() {
+	w.mu.Lock()
+	w.all = true
+	w.mu.Unlock()
+}
+
+// touches checks if any of the deltas may change the results of the query.
+func (w *watchStore) touches(deltas []graph.Delta) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.all {
+		return true
+	}
+	for i := range deltas {
+		q := deltas[i].Quad
+		for _, d := range quad.Directions {
+			v := q.Get(d)
+			if v == nil {
+				continue
+			}
+			s := v.String()
+			if _, ok := w.missing[s]; ok {
+				return true
+			}
+			if _, ok := w.lookups[d-1][s]; ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (w *watchStore) ValueOf(v quad.Value) graph.Value {
+	out := w.QuadStore.ValueOf(v)
+	if out == nil && v != nil {
+		w.mu.Lock()
+		w.missing[v.String()] = struct{}{}
+		w.mu.Unlock()
+	}
+	return out
+}
+
+func (w *watchStore) QuadIterator(d quad.Direction, v graph.Value) graph.Iterator {
+	if d >= quad.Subject && d <= quad.Label {
+		if name := w.QuadStore.NameOf(v); name != nil {
+			w.mu.Lock()
+			w.lookups[d-1][name.String()] = struct{}{}
+			w.mu.Unlock()
+		} else {
+			w.setAll()
+		}
+	} else {
+		w.setAll()
+	}
+	return w.QuadStore.QuadIterator(d, v)
+}
+
+func (w *watchStore) NodesAllIterator() graph.Iterator {
+	return &watchAllIterator{Iterator: w.QuadStore.NodesAllIterator(), w: w}
+}
+
+func (w *watchStore) QuadsAllIterator() graph.Iterator {
+	return &watchAllIterator{Iterator: w.QuadStore.QuadsAllIterator(), w: w}
+}
+
+// OptimizeIterator skips optimizations of the underlying store, since they may
+// replace generic iterators with native ones, bypassing the lookups recording.
+func (w *watchStore) OptimizeIterator(it graph.Iterator) (graph.Iterator, bool) {
+	return it, false
+}
+
+// watchAllIterator marks the query as dependent on all deltas if it iterates over all nodes or quads.
+// Iterators that are only used to check values with Contains do not change the dependencies.
+type watchAllIterator struct {
+	graph.Iterator
+	w *watchStore
+}
+
+func (it *watchAllIterator) Next(ctx *graph.IterationContext) bool {
+	it.w.setAll()
+	return it.Iterator.Next(ctx)
+}
+
+func (it *watchAllIterator) Clone() graph.Iterator {
+	return &watchAllIterator{Iterator: it.Iterator.Clone(), w: it.w}
+}
+
+func (it *watchAllIterator) Optimize() (graph.Iterator, bool) {
+	nit, ok := it.Iterator.Optimize()
+	if !ok {
+		return it, false
+	}
+	return &watchAllIterator{Iterator: nit, w: it.w}, true
+}
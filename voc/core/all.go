@@ -2,6 +2,8 @@
 package core
 
 import (
+	_ "github.com/codelingo/cayley/voc/geo"
+	_ "github.com/codelingo/cayley/voc/owl"
 	_ "github.com/codelingo/cayley/voc/rdf"
 	_ "github.com/codelingo/cayley/voc/rdfs"
 	_ "github.com/codelingo/cayley/voc/schema"
@@ -0,0 +1,31 @@
+// Package geo contains constants of the GeoSPARQL vocabulary.
+package geo
+
+import "github.com/codelingo/cayley/voc"
+
+func init() {
+	voc.RegisterPrefix(Prefix, NS)
+}
+
+const (
+	NS     = `http://www.opengis.net/ont/geosparql#`
+	Prefix = `geo:`
+)
+
+const (
+	// Types
+
+	// The class of all geometry or spatial measures.
+	Geometry = Prefix + `Geometry`
+	// The class of spatial objects with geometries.
+	Feature = Prefix + `Feature`
+	// The datatype of Well-Known Text literals, as defined by Simple Features Access.
+	WKTLiteral = Prefix + `wktLiteral`
+
+	// Properties
+
+	// A spatial representation of a feature.
+	HasGeometry = Prefix + `hasGeometry`
+	// The WKT serialization of a geometry.
+	AsWKT = Prefix + `asWKT`
+)
@@ -0,0 +1,51 @@
+// Package owl contains constants of the Web Ontology Language (OWL)
+package owl
+
+import "github.com/codelingo/cayley/voc"
+
+func init() {
+	voc.RegisterPrefix(Prefix, NS)
+}
+
+const (
+	NS     = `http://www.w3.org/2002/07/owl#`
+	Prefix = `owl:`
+)
+
+const (
+	// Classes
+
+	// The class of OWL classes.
+	Class = Prefix + `Class`
+	// The class of OWL individuals.
+	Thing = Prefix + `Thing`
+	// This is the empty class.
+	Nothing = Prefix + `Nothing`
+	// The class of object properties.
+	ObjectProperty = Prefix + `ObjectProperty`
+	// The class of data properties.
+	DatatypeProperty = Prefix + `DatatypeProperty`
+	// The class of symmetric properties.
+	SymmetricProperty = Prefix + `SymmetricProperty`
+	// The class of transitive properties.
+	TransitiveProperty = Prefix + `TransitiveProperty`
+	// The class of functional properties.
+	FunctionalProperty = Prefix + `FunctionalProperty`
+	// The class of inverse-functional properties.
+	InverseFunctionalProperty = Prefix + `InverseFunctionalProperty`
+	// The class of ontologies.
+	Ontology = Prefix + `Ontology`
+
+	// Properties
+
+	// The property that determines that two given properties are inverse.
+	InverseOf = Prefix + `inverseOf`
+	// The property that determines that two given individuals are equal.
+	SameAs = Prefix + `sameAs`
+	// The property that determines that two given individuals are different.
+	DifferentFrom = Prefix + `differentFrom`
+	// The property that determines that two given classes are equivalent.
+	EquivalentClass = Prefix + `equivalentClass`
+	// The property that determines that two given properties are equivalent.
+	EquivalentProperty = Prefix + `equivalentProperty`
+)
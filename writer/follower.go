@@ -0,0 +1,200 @@
+// Copyright 2017 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package writer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/codelingo/cayley/clog"
+	"github.com/codelingo/cayley/graph"
+	"github.com/codelingo/cayley/quad"
+	"github.com/codelingo/cayley/quad/pquads"
+	"github.com/codelingo/cayley/quad/pquads/pio"
+)
+
+func init() {
+	graph.RegisterWriter("follower", NewFollowerReplication)
+}
+
+// ErrReadOnly is returned on writes to a follower.
+var ErrReadOnly = errors.New("replication: follower is read-only")
+
+// Follower is a read-only writer that applies deltas published by a leader.
+//
+// It connects to the leader in background, requests all deltas after the current
+// horizon of the quad store and applies them as they arrive. Connection is
+// re-established after errors, and after a restart the follower continues
+// from the horizon of the store.
+type Follower struct {
+	qs      graph.QuadStore
+	url     string
+	cli     *http.Client
+	retry   time.Duration
+	done    chan struct{}
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+	horizon int64         // last applied delta ID
+	notify  chan struct{} // closed when new deltas are applied
+}
+
+// NewFollowerReplication creates a follower writer. Options:
+//
+//	leader - address of the leader: "http://host:port" or "unix:/path/to/socket"
+//	retry  - delay before reconnecting to the leader, as a duration string (default "1s")
+func NewFollowerReplication(qs graph.QuadStore, opts graph.Options) (graph.QuadWriter, error) {
+	h := qs.Horizon()
+	if !h.IsSequential() {
+		return nil, errUniqueKeys
+	}
+	addr, ok, err := opts.StringKey("leader")
+	if err != nil {
+		return nil, err
+	} else if !ok || addr == "" {
+		return nil, errors.New("replication: leader address is not set")
+	}
+	f := &Follower{
+		qs:      qs,
+		url:     addr,
+		cli:     &http.Client{},
+		retry:   time.Second,
+		done:    make(chan struct{}),
+		horizon: h.Int(),
+		notify:  make(chan struct{}),
+	}
+	if strings.HasPrefix(addr, "unix:") {
+		path := strings.TrimPrefix(addr, "unix:")
+		f.url = "http://unix"
+		f.cli.Transport = &http.Transport{
+			Dial: func(_, _ string) (net.Conn, error) {
+				return net.Dial("unix", path)
+			},
+		}
+	}
+	if s, ok, err := opts.StringKey("retry"); err != nil {
+		return nil, err
+	} else if ok {
+		if f.retry, err = time.ParseDuration(s); err != nil {
+			return nil, err
+		}
+	}
+	f.wg.Add(1)
+	go f.run()
+	return f, nil
+}
+
+func (f *Follower) run() {
+	defer f.wg.Done()
+	for {
+		if err := f.sync(); err != nil {
+			clog.Errorf("replication: %v", err)
+		}
+		select {
+		case <-f.done:
+			return
+		case <-time.After(f.retry):
+		}
+	}
+}
+
+// sync requests deltas from the leader and applies them until the connection is closed.
+func (f *Follower) sync() error {
+	f.mu.Lock()
+	since := f.horizon
+	f.mu.Unlock()
+	req, err := http.NewRequest("GET", f.url+"?since="+strconv.FormatInt(since, 10), nil)
+	if err != nil {
+		return err
+	}
+	req.Cancel = f.done
+	resp, err := f.cli.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("leader returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	r := pio.NewReader(resp.Body, pquads.DefaultMaxSize)
+	for {
+		b, err := readBatch(r)
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		// batches may be resent after reconnecting
+		deltas := b.deltas()
+		err = f.qs.ApplyDeltas(deltas, graph.IgnoreOpts{IgnoreDup: true, IgnoreMissing: true})
+		if err != nil {
+			return err
+		}
+		graph.Publish(f.qs, deltas)
+		f.mu.Lock()
+		if id := b.last(); id > f.horizon {
+			f.horizon = id
+		}
+		close(f.notify)
+		f.notify = make(chan struct{})
+		f.mu.Unlock()
+	}
+}
+
+// Wait blocks until the follower applies all deltas up to a given ID or the timeout expires.
+// It returns false on timeout.
+func (f *Follower) Wait(id int64, timeout time.Duration) bool {
+	deadline := time.After(timeout)
+	for {
+		f.mu.Lock()
+		h, notify := f.horizon, f.notify
+		f.mu.Unlock()
+		if h >= id {
+			return true
+		}
+		select {
+		case <-notify:
+		case <-deadline:
+			return false
+		case <-f.done:
+			return false
+		}
+	}
+}
+
+func (f *Follower) AddQuad(quad.Quad) error                   { return ErrReadOnly }
+func (f *Follower) AddQuadSet([]quad.Quad) error              { return ErrReadOnly }
+func (f *Follower) RemoveQuad(quad.Quad) error                { return ErrReadOnly }
+func (f *Follower) RemoveNode(graph.Value) error              { return ErrReadOnly }
+func (f *Follower) ApplyTransaction(*graph.Transaction) error { return ErrReadOnly }
+
+func (f *Follower) Close() error {
+	select {
+	case <-f.done:
+		return nil
+	default:
+	}
+	close(f.done)
+	f.wg.Wait()
+	return nil
+}
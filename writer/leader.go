@@ -0,0 +1,371 @@
+// Copyright 2017 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package writer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/codelingo/cayley/clog"
+	"github.com/codelingo/cayley/graph"
+	"github.com/codelingo/cayley/quad/pquads"
+	"github.com/codelingo/cayley/quad/pquads/pio"
+)
+
+func init() {
+	graph.RegisterWriter("leader", NewLeaderReplication)
+}
+
+var errUniqueKeys = errors.New("replication: quad store with unique keys is not supported")
+
+// defaultLogSize is a default number of recent batches kept in the replication log.
+const defaultLogSize = 10000
+
+// Leader is a writer that applies deltas to a local quad store and publishes
+// them to followers.
+//
+// Each successfully applied batch of deltas is appended to an in-memory log,
+// and optionally to a write-ahead log file. Followers request all batches
+// after their horizon and then wait for new ones in the same response.
+//
+// Only a limited number of recent batches is kept. Followers that are behind
+// the oldest of them receive an error and must be resynced.
+type Leader struct {
+	*Single
+	qs graph.QuadStore
+
+	mu      sync.Mutex
+	id      graph.PrimaryKey
+	first   int64 // log contains all deltas after this ID
+	size    int   // maximal number of batches in the log
+	batches []batch
+	wal     *os.File
+	walw    pio.Writer
+	walPath string
+	walN    int           // number of batches in the log file
+	notify  chan struct{} // closed when new batches are added
+	closed  chan struct{}
+	lis     net.Listener
+}
+
+// NewLeaderReplication creates a leader writer. It accepts the same options as the "single" writer, and:
+//
+//	listen   - address for followers to connect to: "host:port" or "unix:/path/to/socket"
+//	wal      - path to a write-ahead log file that keeps the replication log between restarts
+//	log_size - number of recent batches kept in the replication log (10000 by default)
+func NewLeaderReplication(qs graph.QuadStore, opts graph.Options) (graph.QuadWriter, error) {
+	h := qs.Horizon()
+	if !h.IsSequential() {
+		return nil, errUniqueKeys
+	}
+	size, ok, err := opts.IntKey("log_size")
+	if err != nil {
+		return nil, err
+	} else if !ok {
+		size = defaultLogSize
+	} else if size <= 0 {
+		return nil, fmt.Errorf("replication: invalid log size: %d", size)
+	}
+	l := &Leader{
+		qs:     qs,
+		id:     graph.NewSequentialKey(h.Int()),
+		first:  h.Int(),
+		size:   size,
+		notify: make(chan struct{}),
+		closed: make(chan struct{}),
+	}
+	w, err := NewSingleReplication(logStore{QuadStore: qs, l: l}, opts)
+	if err != nil {
+		return nil, err
+	}
+	l.Single = w.(*Single)
+	l.Single.feed = qs
+	if path, ok, err := opts.StringKey("wal"); err != nil {
+		return nil, err
+	} else if ok {
+		if err = l.openWAL(path); err != nil {
+			return nil, err
+		}
+	}
+	if addr, ok, err := opts.StringKey("listen"); err != nil {
+		l.Close()
+		return nil, err
+	} else if ok {
+		if err = l.listen(addr); err != nil {
+			l.Close()
+			return nil, err
+		}
+	}
+	return l, nil
+}
+
+// openWAL loads existing batches from the log file and opens it for writing.
+func (l *Leader) openWAL(path string) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	r := pio.NewReader(f, pquads.DefaultMaxSize)
+	var batches []batch
+	for {
+		b, err := readBatch(r)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			f.Close()
+			return fmt.Errorf("replication: cannot read log %q: %v", path, err)
+		}
+		batches = append(batches, b)
+	}
+	if n := len(batches); n != 0 && batches[n-1].last() == l.first {
+		// log is up to date with the store
+		l.first = int64(batches[0][0].ID) - 1
+		l.batches = batches
+		l.walN = n
+		l.trim()
+	} else if n != 0 {
+		// store was modified without the log, or the log belongs to a different (or reset) store;
+		// new IDs start from the current horizon, so followers must catch up from it
+		clog.Warningf("replication: log %q does not match the quad store, truncating", path)
+		if err = f.Truncate(0); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	l.wal, l.walw, l.walPath = f, pio.NewWriter(f), path
+	return nil
+}
+
+// trim drops the oldest batches that do not fit into the log.
+func (l *Leader) trim() {
+	if n := len(l.batches) - l.size; n > 0 {
+		l.first = l.batches[n-1].last()
+		l.batches = l.batches[n:]
+	}
+}
+
+// compactWAL replaces the log file with a new one that contains only batches kept in memory.
+// The new file is written next to the old one and renamed over it, so the log is never lost.
+func (l *Leader) compactWAL() error {
+	tmp := l.walPath + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_RDWR|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	w := pio.NewWriter(f)
+	for _, b := range l.batches {
+		if err = writeBatch(w, b); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if err == nil {
+		err = os.Rename(tmp, l.walPath)
+	}
+	if err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	l.wal.Close()
+	l.wal, l.walw, l.walN = f, w, len(l.batches)
+	return nil
+}
+
+func (l *Leader) listen(addr string) error {
+	network := "tcp"
+	if strings.HasPrefix(addr, "unix:") {
+		network, addr = "unix", strings.TrimPrefix(addr, "unix:")
+		// remove a stale socket left by the previous process
+		os.Remove(addr)
+	}
+	lis, err := net.Listen(network, addr)
+	if err != nil {
+		return err
+	}
+	l.lis = lis
+	clog.Infof("replication: serving log on %s", lis.Addr())
+	go http.Serve(lis, l)
+	return nil
+}
+
+// Addr returns an address followers can connect to, or nil if leader is not listening.
+func (l *Leader) Addr() net.Addr {
+	if l.lis == nil {
+		return nil
+	}
+	return l.lis.Addr()
+}
+
+// logStore applies deltas to the quad store and appends them to the replication log.
+type logStore struct {
+	graph.QuadStore
+	l *Leader
+}
+
+func (s logStore) ApplyDeltas(deltas []graph.Delta, opts graph.IgnoreOpts) error {
+	if len(deltas) == 0 {
+		return nil
+	}
+	l := s.l
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	select {
+	case <-l.closed:
+		return errors.New("replication: writer is closed")
+	default:
+	}
+	// IDs are assigned under the lock to keep the log ordered
+	for i := range deltas {
+		deltas[i].ID = l.id.Next()
+	}
+	err := s.QuadStore.ApplyDeltas(deltas, opts)
+	// failed writes and ignored deltas at the end of the batch do not move the horizon; reuse their IDs,
+	// so followers will not skip them after catching up to the horizon of a restarted leader
+	h := s.QuadStore.Horizon()
+	l.id = graph.NewSequentialKey(h.Int())
+	if err != nil {
+		return err
+	}
+	n := len(deltas)
+	for n > 0 && deltas[n-1].ID.Int() > h.Int() {
+		n--
+	}
+	if n == 0 {
+		return nil
+	}
+	b := makeBatch(deltas[:n])
+	if l.walw != nil {
+		if err := writeBatch(l.walw, b); err != nil {
+			clog.Errorf("replication: cannot write to log: %v", err)
+			return err
+		}
+		l.walN++
+	}
+	l.batches = append(l.batches, b)
+	l.trim()
+	// the file is rewritten only when it has twice as many batches as the log, to amortize the cost
+	if l.walw != nil && l.walN >= 2*l.size {
+		if err := l.compactWAL(); err != nil {
+			// the old file is still valid, try again after the next batch
+			clog.Errorf("replication: cannot compact log: %v", err)
+		}
+	}
+	close(l.notify)
+	l.notify = make(chan struct{})
+	return nil
+}
+
+// after returns all logged deltas with IDs greater than since.
+func (l *Leader) after(since int64) []batch {
+	i := sort.Search(len(l.batches), func(i int) bool {
+		return l.batches[i].last() > since
+	})
+	if i == len(l.batches) {
+		return nil
+	}
+	out := append([]batch{}, l.batches[i:]...)
+	b := out[0]
+	j := sort.Search(len(b), func(j int) bool {
+		return int64(b[j].ID) > since
+	})
+	out[0] = b[j:]
+	return out
+}
+
+// ServeHTTP streams the replication log to a follower, starting after the ID given in the "since" parameter.
+// Response is kept open and new batches are sent as they are applied.
+func (l *Leader) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var since int64
+	if s := r.FormValue("since"); s != "" {
+		var err error
+		if since, err = strconv.ParseInt(s, 10, 64); err != nil {
+			http.Error(w, "invalid since parameter: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	l.mu.Lock()
+	first := l.first
+	l.mu.Unlock()
+	if since < first {
+		http.Error(w, fmt.Sprintf("log starts after %d, requested %d", first, since), http.StatusGone)
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.WriteHeader(http.StatusOK)
+	var gone <-chan bool
+	if cn, ok := w.(http.CloseNotifier); ok {
+		gone = cn.CloseNotify()
+	}
+	flusher, _ := w.(http.Flusher)
+	pw := pio.NewWriter(w)
+	for {
+		l.mu.Lock()
+		if since < l.first {
+			// follower fell behind the log; it will get an error after reconnecting
+			l.mu.Unlock()
+			return
+		}
+		batches, notify := l.after(since), l.notify
+		l.mu.Unlock()
+		for _, b := range batches {
+			if err := writeBatch(pw, b); err != nil {
+				return
+			}
+			since = b.last()
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		select {
+		case <-notify:
+		case <-gone:
+			return
+		case <-l.closed:
+			return
+		}
+	}
+}
+
+func (l *Leader) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	select {
+	case <-l.closed:
+		return nil
+	default:
+	}
+	close(l.closed)
+	var err error
+	if l.lis != nil {
+		err = l.lis.Close()
+	}
+	if l.wal != nil {
+		if err2 := l.wal.Close(); err == nil {
+			err = err2
+		}
+	}
+	return err
+}
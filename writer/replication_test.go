@@ -0,0 +1,204 @@
+package writer
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/codelingo/cayley/graph"
+	"github.com/codelingo/cayley/graph/memstore"
+	"github.com/codelingo/cayley/quad"
+)
+
+func allQuads(t testing.TB, qs graph.QuadStore) []string {
+	it := qs.QuadsAllIterator()
+	defer it.Close()
+	var out []string
+	for it.Next(nil) {
+		out = append(out, qs.Quad(it.Result()).NQuad())
+	}
+	require.NoError(t, it.Err())
+	sort.Strings(out)
+	return out
+}
+
+func newLeader(t testing.TB, qs graph.QuadStore, opts graph.Options) *Leader {
+	w, err := NewLeaderReplication(qs, opts)
+	require.NoError(t, err)
+	return w.(*Leader)
+}
+
+func newFollower(t testing.TB, qs graph.QuadStore, addr string) *Follower {
+	w, err := NewFollowerReplication(qs, graph.Options{"leader": addr, "retry": "10ms"})
+	require.NoError(t, err)
+	return w.(*Follower)
+}
+
+func waitSync(t testing.TB, f *Follower, leader graph.QuadStore) {
+	h := leader.Horizon()
+	require.True(t, f.Wait(h.Int(), 5*time.Second), "follower did not catch up to %d", h.Int())
+	require.Equal(t, allQuads(t, leader), allQuads(t, f.qs))
+}
+
+func TestReplication(t *testing.T) {
+	lqs := memstore.New()
+	l := newLeader(t, lqs, graph.Options{"listen": "127.0.0.1:0"})
+	defer l.Close()
+	addr := "http://" + l.Addr().String()
+
+	// writes before the follower is started
+	err := l.AddQuadSet([]quad.Quad{
+		quad.MakeIRI("alice", "follows", "bob", ""),
+		quad.MakeIRI("bob", "follows", "charlie", ""),
+	})
+	require.NoError(t, err)
+
+	fqs := memstore.New()
+	f := newFollower(t, fqs, addr)
+	waitSync(t, f, lqs)
+
+	// live updates
+	require.NoError(t, l.RemoveQuad(quad.MakeIRI("alice", "follows", "bob", "")))
+	tx := graph.NewTransaction()
+	tx.AddQuad(quad.MakeIRI("charlie", "follows", "alice", "g"))
+	tx.AddQuad(quad.Make(quad.IRI("alice"), quad.IRI("age"), quad.Int(21), nil))
+	require.NoError(t, l.ApplyTransaction(tx))
+	waitSync(t, f, lqs)
+
+	require.Equal(t, ErrReadOnly, f.AddQuad(quad.MakeIRI("a", "b", "c", "")))
+
+	// restarted follower continues from its horizon
+	require.NoError(t, f.Close())
+	require.NoError(t, l.RemoveNode(lqs.ValueOf(quad.IRI("charlie"))))
+	f = newFollower(t, fqs, addr)
+	defer f.Close()
+	waitSync(t, f, lqs)
+}
+
+func TestReplicationWAL(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cayley_replication")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	opts := graph.Options{
+		"listen": "unix:" + filepath.Join(dir, "leader.sock"),
+		"wal":    filepath.Join(dir, "wal"),
+	}
+
+	lqs := memstore.New()
+	l := newLeader(t, lqs, opts)
+	require.NoError(t, l.AddQuad(quad.MakeIRI("alice", "follows", "bob", "")))
+	require.NoError(t, l.AddQuad(quad.MakeIRI("bob", "follows", "alice", "")))
+	require.NoError(t, l.Close())
+
+	// restarted leader serves the log from the file
+	l = newLeader(t, lqs, opts)
+	defer l.Close()
+	require.NoError(t, l.AddQuad(quad.MakeIRI("bob", "follows", "charlie", "")))
+
+	f := newFollower(t, memstore.New(), "unix:"+filepath.Join(dir, "leader.sock"))
+	defer f.Close()
+	waitSync(t, f, lqs)
+}
+
+func TestReplicationGone(t *testing.T) {
+	// quads written before the leader started are not in the log
+	qs := memstore.New()
+	w, err := NewSingleReplication(qs, nil)
+	require.NoError(t, err)
+	require.NoError(t, w.AddQuad(quad.MakeIRI("alice", "follows", "bob", "")))
+
+	l := newLeader(t, qs, nil)
+	defer l.Close()
+	srv := httptest.NewServer(l)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "?since=0")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusGone, resp.StatusCode)
+}
+
+func TestReplicationLogSize(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cayley_replication")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	opts := graph.Options{
+		"wal":      filepath.Join(dir, "wal"),
+		"log_size": 2,
+	}
+
+	lqs := memstore.New()
+	l := newLeader(t, lqs, opts)
+	srv := httptest.NewServer(l)
+	defer srv.Close()
+	for _, s := range []string{"a", "b", "c", "d", "e"} {
+		require.NoError(t, l.AddQuad(quad.MakeIRI(s, "follows", "bob", "")))
+	}
+	require.Len(t, l.batches, 2)
+	require.Equal(t, int64(3), l.first)
+	require.True(t, l.walN < 4, "log file was not compacted: %d batches", l.walN)
+
+	resp, err := http.Get(srv.URL + "?since=2")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusGone, resp.StatusCode)
+	require.NoError(t, l.Close())
+
+	// restarted leader keeps the same window
+	l = newLeader(t, lqs, opts)
+	defer l.Close()
+	require.Len(t, l.batches, 2)
+	require.Equal(t, int64(3), l.first)
+}
+
+func TestReplicationFailedWrite(t *testing.T) {
+	lqs := memstore.New()
+	l := newLeader(t, lqs, nil)
+	defer l.Close()
+	q := quad.MakeIRI("alice", "follows", "bob", "")
+	require.NoError(t, l.AddQuad(q))
+	require.Error(t, l.AddQuad(q))
+
+	// IDs of the failed write are reused
+	require.NoError(t, l.AddQuad(quad.MakeIRI("bob", "follows", "alice", "")))
+	require.Len(t, l.batches, 2)
+	h := lqs.Horizon()
+	require.Equal(t, h.Int(), l.batches[1].last())
+	require.Equal(t, l.batches[0].last()+1, l.batches[1].last())
+}
+
+func TestReplicationWALAhead(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cayley_replication")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	opts := graph.Options{"wal": filepath.Join(dir, "wal")}
+
+	l := newLeader(t, memstore.New(), opts)
+	require.NoError(t, l.AddQuad(quad.MakeIRI("alice", "follows", "bob", "")))
+	require.NoError(t, l.AddQuad(quad.MakeIRI("bob", "follows", "alice", "")))
+	require.NoError(t, l.Close())
+
+	// restarted on an empty store, the old log is ahead of it and must not be reused
+	lqs := memstore.New()
+	l = newLeader(t, lqs, opts)
+	defer l.Close()
+	require.Len(t, l.batches, 0)
+	require.Equal(t, int64(0), l.first)
+	require.NoError(t, l.AddQuad(quad.MakeIRI("bob", "follows", "charlie", "")))
+	require.Len(t, l.batches, 1)
+	h := lqs.Horizon()
+	require.Equal(t, h.Int(), l.batches[0].last())
+
+	srv := httptest.NewServer(l)
+	defer srv.Close()
+	f := newFollower(t, memstore.New(), srv.URL)
+	defer f.Close()
+	waitSync(t, f, lqs)
+}
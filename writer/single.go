@@ -15,6 +15,7 @@
 package writer
 
 import (
+	"sync"
 	"time"
 
 	"github.com/codelingo/cayley/graph"
@@ -26,8 +27,10 @@ func init() {
 }
 
 type Single struct {
+	mu         sync.Mutex // serializes writes, so ignored deltas are found in the same state of the store
 	currentID  graph.PrimaryKey
 	qs         graph.QuadStore
+	feed       graph.QuadStore // quad store to publish changes for; see graph.Publish
 	ignoreOpts graph.IgnoreOpts
 }
 
@@ -59,6 +62,7 @@ func NewSingleReplication(qs graph.QuadStore, opts graph.Options) (graph.QuadWri
 	return &Single{
 		currentID: qs.Horizon(),
 		qs:        qs,
+		feed:      qs,
 		ignoreOpts: graph.IgnoreOpts{
 			IgnoreDup:     ignoreDuplicate,
 			IgnoreMissing: ignoreMissing,
@@ -74,7 +78,7 @@ func (s *Single) AddQuad(q quad.Quad) error {
 		Action:    graph.Add,
 		Timestamp: time.Now(),
 	}
-	return s.qs.ApplyDeltas(deltas, s.ignoreOpts)
+	return s.apply(deltas, s.ignoreOpts)
 }
 
 func (s *Single) AddQuadSet(set []quad.Quad) error {
@@ -88,7 +92,7 @@ func (s *Single) AddQuadSet(set []quad.Quad) error {
 		}
 	}
 
-	return s.qs.ApplyDeltas(deltas, s.ignoreOpts)
+	return s.apply(deltas, s.ignoreOpts)
 }
 
 func (s *Single) RemoveQuad(q quad.Quad) error {
@@ -99,7 +103,7 @@ func (s *Single) RemoveQuad(q quad.Quad) error {
 		Action:    graph.Delete,
 		Timestamp: time.Now(),
 	}
-	return s.qs.ApplyDeltas(deltas, s.ignoreOpts)
+	return s.apply(deltas, s.ignoreOpts)
 }
 
 // RemoveNode removes all quads with the given value
@@ -118,7 +122,60 @@ func (s *Single) RemoveNode(v graph.Value) error {
 		}
 		it.Close()
 	}
-	return s.qs.ApplyDeltas(deltas, graph.IgnoreOpts{IgnoreMissing: true})
+	return s.apply(deltas, graph.IgnoreOpts{IgnoreMissing: true})
+}
+
+// apply writes deltas to the quad store and publishes them to the change feed.
+// Deltas ignored by the store are not published.
+func (s *Single) apply(deltas []graph.Delta, opts graph.IgnoreOpts) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var ignored []bool
+	if (opts.IgnoreDup || opts.IgnoreMissing) && graph.Subscribed(s.feed) {
+		// quad stores do not report which deltas they ignored, and not all of them
+		// keep delta IDs in the horizon, so check the quads before they are written;
+		// the writer lock is held, so no other write can change them in between
+		ignored = s.ignored(deltas, opts)
+	}
+	if err := s.qs.ApplyDeltas(deltas, opts); err != nil {
+		return err
+	}
+	if ignored != nil {
+		applied := make([]graph.Delta, 0, len(deltas))
+		for i := range deltas {
+			if !ignored[i] {
+				d := &deltas[i]
+				applied = append(applied, graph.Delta{ID: d.ID.Copy(), Quad: d.Quad, Action: d.Action, Timestamp: d.Timestamp})
+			}
+		}
+		deltas = applied
+	}
+	graph.Publish(s.feed, deltas)
+	return nil
+}
+
+// ignored finds deltas that will be ignored by the store: additions of existing quads and removals of missing ones.
+func (s *Single) ignored(deltas []graph.Delta, opts graph.IgnoreOpts) []bool {
+	out := make([]bool, len(deltas))
+	// quads that were added or removed by previous deltas in the batch
+	changed := make(map[string]bool)
+	for i := range deltas {
+		d := &deltas[i]
+		key := d.Quad.NQuad()
+		exists, ok := changed[key]
+		if !ok {
+			exists = graph.HasQuad(s.qs, d.Quad, false)
+		}
+		switch {
+		case d.Action == graph.Add && exists:
+			out[i] = opts.IgnoreDup
+		case d.Action == graph.Delete && !exists:
+			out[i] = opts.IgnoreMissing
+		default:
+			changed[key] = d.Action == graph.Add
+		}
+	}
+	return out
 }
 
 func (s *Single) Close() error {
@@ -132,5 +189,5 @@ func (s *Single) ApplyTransaction(t *graph.Transaction) error {
 		t.Deltas[i].ID = s.currentID.Next()
 		t.Deltas[i].Timestamp = ts
 	}
-	return s.qs.ApplyDeltas(t.Deltas, s.ignoreOpts)
+	return s.apply(t.Deltas, s.ignoreOpts)
 }
@@ -0,0 +1,69 @@
+package writer
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/codelingo/cayley/graph"
+	"github.com/codelingo/cayley/graph/memstore"
+	"github.com/codelingo/cayley/quad"
+)
+
+func TestSingleIgnoredNotPublished(t *testing.T) {
+	qs := memstore.New()
+	w, err := NewSingleReplication(qs, graph.Options{"ignore_duplicate": true, "ignore_missing": true})
+	require.NoError(t, err)
+	q1 := quad.MakeIRI("a", "follows", "b", "")
+	q2 := quad.MakeIRI("b", "follows", "c", "")
+	require.NoError(t, w.AddQuad(q1))
+
+	c, cancel := graph.Subscribe(qs, graph.DeltaFilter{})
+	defer cancel()
+	require.NoError(t, w.AddQuad(q1))
+	require.NoError(t, w.RemoveQuad(q2))
+	require.NoError(t, w.AddQuadSet([]quad.Quad{q1, q2, q2}))
+	require.NoError(t, w.RemoveQuad(q1))
+
+	batch := <-c
+	require.Len(t, batch, 1)
+	require.Equal(t, q2, batch[0].Quad)
+	require.Equal(t, graph.Add, batch[0].Action)
+	batch = <-c
+	require.Len(t, batch, 1)
+	require.Equal(t, q1, batch[0].Quad)
+	require.Equal(t, graph.Delete, batch[0].Action)
+	select {
+	case batch = <-c:
+		t.Fatalf("unexpected batch: %v", batch)
+	default:
+	}
+}
+
+func TestSingleIgnoredConcurrent(t *testing.T) {
+	qs := memstore.New()
+	w, err := NewSingleReplication(qs, graph.Options{"ignore_duplicate": true})
+	require.NoError(t, err)
+	c, cancel := graph.Subscribe(qs, graph.DeltaFilter{})
+	defer cancel()
+
+	q := quad.MakeIRI("a", "follows", "b", "")
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			require.NoError(t, w.AddQuad(q))
+		}()
+	}
+	wg.Wait()
+
+	batch := <-c
+	require.Len(t, batch, 1)
+	select {
+	case batch = <-c:
+		t.Fatalf("duplicate was published: %v", batch)
+	default:
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright 2017 The Cayley Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package writer
+
+import (
+	"io"
+	"time"
+
+	"github.com/codelingo/cayley/graph"
+	"github.com/codelingo/cayley/graph/proto"
+	"github.com/codelingo/cayley/quad/pquads"
+	"github.com/codelingo/cayley/quad/pquads/pio"
+)
+
+// Replication log format.
+//
+// A log is a sequence of batches. Each batch is a list of varint-delimited LogDelta messages
+// terminated by an empty message. Batches are applied atomically by followers.
+
+// batch is a list of deltas applied to the store in a single call.
+type batch []proto.LogDelta
+
+// last returns an ID of the last delta in the batch.
+func (b batch) last() int64 {
+	return int64(b[len(b)-1].ID)
+}
+
+func makeBatch(deltas []graph.Delta) batch {
+	b := make(batch, 0, len(deltas))
+	for i := range deltas {
+		d := &deltas[i]
+		b = append(b, proto.LogDelta{
+			ID:        uint64(d.ID.Int()),
+			Quad:      pquads.MakeQuad(d.Quad),
+			Action:    int32(d.Action),
+			Timestamp: d.Timestamp.UnixNano(),
+		})
+	}
+	return b
+}
+
+func (b batch) deltas() []graph.Delta {
+	out := make([]graph.Delta, len(b))
+	for i, d := range b {
+		out[i] = graph.Delta{
+			ID:        graph.NewSequentialKey(int64(d.ID)),
+			Quad:      d.Quad.ToNative(),
+			Action:    graph.Procedure(d.Action),
+			Timestamp: time.Unix(0, d.Timestamp),
+		}
+	}
+	return out
+}
+
+func writeBatch(w pio.Writer, b batch) error {
+	for i := range b {
+		if _, err := w.WriteMsg(&b[i]); err != nil {
+			return err
+		}
+	}
+	_, err := w.WriteMsg(&proto.LogDelta{})
+	return err
+}
+
+// readBatch reads the next batch from the log. It returns io.EOF if there are no more batches,
+// and io.ErrUnexpectedEOF if the last batch is incomplete.
+func readBatch(r pio.Reader) (batch, error) {
+	var b batch
+	for {
+		var d proto.LogDelta
+		if err := r.ReadMsg(&d); err == io.EOF && len(b) != 0 {
+			return nil, io.ErrUnexpectedEOF
+		} else if err != nil {
+			return nil, err
+		}
+		if d.ID == 0 && d.Quad == nil {
+			if len(b) == 0 {
+				continue
+			}
+			return b, nil
+		}
+		b = append(b, d)
+	}
+}